@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkbench"
+	"github.com/spf13/cobra"
+)
+
+type benchCMD struct {
+	ctx    *WuKongIMContext
+	opts   *wkbench.Options
+	output string // 压测结果保存的文件
+}
+
+func newBenchCMD(ctx *WuKongIMContext) *benchCMD {
+	return &benchCMD{
+		ctx:  ctx,
+		opts: wkbench.NewOptions(),
+	}
+}
+
+func (b *benchCMD) CMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "run a load test against a WuKongIM server",
+		RunE:  b.run,
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&b.opts.TCPAddr, "addr", b.opts.TCPAddr, "long connection address, tcp://ip:port or ws://ip:port")
+	flags.StringVar(&b.opts.APIURL, "api", b.opts.APIURL, "http api address")
+	flags.StringVar(&b.opts.APIToken, "token", b.opts.APIToken, "manager token of the http api")
+	flags.StringVar(&b.opts.UIDPrefix, "uid-prefix", b.opts.UIDPrefix, "uid prefix of the simulated users")
+	flags.IntVar(&b.opts.Users, "users", b.opts.Users, "number of simulated users")
+	flags.IntVar(&b.opts.Groups, "groups", b.opts.Groups, "number of groups")
+	flags.IntVar(&b.opts.GroupSize, "group-size", b.opts.GroupSize, "number of members per group")
+	flags.BoolVar(&b.opts.GroupLarge, "group-large", b.opts.GroupLarge, "create groups as large groups")
+	flags.IntVar(&b.opts.Rate, "rate", b.opts.Rate, "messages sent per second by all users")
+	flags.DurationVar(&b.opts.Duration, "duration", b.opts.Duration, "how long to send messages")
+	flags.Float64Var(&b.opts.PersonRatio, "person-ratio", b.opts.PersonRatio, "ratio of person messages (0-1), the rest are group messages")
+	flags.IntVar(&b.opts.PayloadSize, "payload-size", b.opts.PayloadSize, "payload size in bytes")
+	flags.IntVar(&b.opts.Concurrency, "concurrency", b.opts.Concurrency, "max messages waiting for sendack")
+	flags.DurationVar(&b.opts.RecvWait, "recv-wait", b.opts.RecvWait, "max time to wait for receivers after sending")
+	flags.StringVarP(&b.output, "output", "o", "", "save the results to a json file")
+	return cmd
+}
+
+func (b *benchCMD) run(cmd *cobra.Command, args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	report, err := wkbench.New(b.opts).Run(ctx)
+	if err != nil {
+		return err
+	}
+	report.Print(os.Stdout)
+	if b.output != "" {
+		if err := report.Save(b.output); err != nil {
+			return err
+		}
+		fmt.Println("results saved to", b.output)
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path"
 	"path/filepath"
 	"strconv"
@@ -137,7 +138,24 @@ func cmdRun() error {
 			}
 		}
 
-		select {}
+		// 收到排空信号后排空节点，排空结束后退出
+		drainC := make(chan os.Signal, 1)
+		if len(drainSignals) > 0 {
+			signal.Notify(drainC, drainSignals...)
+		}
+		for {
+			select {
+			case <-drainC:
+				wklog.Info("receive drain signal, start draining")
+				if err := s.Drain(); err != nil {
+					wklog.Error("drain error", zap.Error(err))
+				}
+			case <-s.DrainDone():
+				wklog.Info("drain done, stop server")
+				s.StopNoErr()
+				return nil
+			}
+		}
 
 	}
 	return nil
@@ -258,6 +276,7 @@ func addCommand(cmd CMD) {
 func Execute() {
 	ctx := &WuKongIMContext{}
 	addCommand(newStopCMD(ctx))
+	addCommand(newBenchCMD(ctx))
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package cmd
+
+import "os"
+
+// drainSignals windows不支持SIGUSR1，只能通过api触发排空
+var drainSignals = []os.Signal{}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -95,12 +96,18 @@ func (ch *ChannelAPI) channelCreateOrUpdate(c *wkhttp.Context) {
 
 	// channelInfo := wkstore.NewChannelInfo(req.ChannelID, req.ChannelType)
 	channelInfo := req.ToChannelInfo()
-	err = ch.addOrUpdateChannel(channelInfo)
+	existChannel, err := ch.addOrUpdateChannel(channelInfo)
 	if err != nil && err != wkdb.ErrNotFound {
 		ch.Error("创建或更新频道失败", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
 		c.ResponseError(errors.New("创建或更新频道失败"))
 		return
 	}
+	err = ch.reconfigureChannelIfNeed(channelInfo, existChannel)
+	if err != nil {
+		ch.Error("重新配置频道副本失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("重新配置频道副本失败！"))
+		return
+	}
 	err = ch.s.store.RemoveAllSubscriber(req.ChannelID, req.ChannelType)
 	if err != nil {
 		ch.Error("移除所有订阅者失败！", zap.Error(err))
@@ -124,6 +131,14 @@ func (ch *ChannelAPI) channelCreateOrUpdate(c *wkhttp.Context) {
 			c.ResponseError(err)
 			return
 		}
+		if channelInfo.Large {
+			// 超大群的最近会话不会随消息推送，需要先创建好
+			err = ch.addSubscriberConversations(req.ChannelID, req.ChannelType, req.Subscribers)
+			if err != nil {
+				c.ResponseError(err)
+				return
+			}
+		}
 	}
 
 	channelKey := wkutil.ChannelToKey(req.ChannelID, req.ChannelType)
@@ -131,6 +146,18 @@ func (ch *ChannelAPI) channelCreateOrUpdate(c *wkhttp.Context) {
 	if cacheChannel != nil {
 		cacheChannel.info = channelInfo
 	}
+	// 订阅者已重置，重新生成接收者标签
+	err = ch.updateReceiverTag(req.ChannelID, req.ChannelType, nil, nil, true)
+	if err != nil {
+		ch.Error("创建接收者标签失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+
+	ch.triggerChannelEvent(req.ChannelInfoReq, existChannel)
+	if len(req.Subscribers) > 0 {
+		ch.triggerMembersEvent(EventSubscriberAdd, req.ChannelID, req.ChannelType, req.Subscribers, true)
+	}
 
 	c.ResponseOK()
 }
@@ -144,6 +171,10 @@ func (ch *ChannelAPI) updateOrAddChannelInfo(c *wkhttp.Context) {
 		c.ResponseError(errors.New("数据格式有误！"))
 		return
 	}
+	if err := req.checkReplicaSetting(); err != nil {
+		c.ResponseError(err)
+		return
+	}
 
 	if ch.s.opts.ClusterOn() {
 		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
@@ -161,17 +192,33 @@ func (ch *ChannelAPI) updateOrAddChannelInfo(c *wkhttp.Context) {
 	}
 
 	channelInfo := req.ToChannelInfo()
-	err = ch.addOrUpdateChannel(channelInfo)
+	existChannel, err := ch.addOrUpdateChannel(channelInfo)
 	if err != nil {
 		ch.Error("添加或更新频道信息失败！", zap.Error(err))
 		c.ResponseError(errors.New("添加或更新频道信息失败！"))
 		return
 	}
+	err = ch.reconfigureChannelIfNeed(channelInfo, existChannel)
+	if err != nil {
+		ch.Error("重新配置频道副本失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("重新配置频道副本失败！"))
+		return
+	}
 	channelKey := wkutil.ChannelToKey(req.ChannelID, req.ChannelType)
 	cacheChannel := ch.s.channelReactor.reactorSub(channelKey).channel(channelKey)
 	if cacheChannel != nil {
 		cacheChannel.info = channelInfo
 	}
+	if existChannel.Large != channelInfo.Large {
+		// 超大群模式变化，重新生成接收者标签
+		err = ch.updateReceiverTag(req.ChannelID, req.ChannelType, nil, nil, true)
+		if err != nil {
+			ch.Error("创建接收者标签失败！", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+	}
+	ch.triggerChannelEvent(req, existChannel)
 	c.ResponseOK()
 }
 
@@ -236,6 +283,10 @@ func (ch *ChannelAPI) addSubscriber(c *wkhttp.Context) {
 		c.ResponseError(errors.New("添加频道失败！"))
 		return
 	}
+	if !exist {
+		ch.triggerChannelEvent(ChannelInfoReq{ChannelID: req.ChannelId, ChannelType: req.ChannelType}, wkdb.ChannelInfo{})
+	}
+	ch.triggerMembersEvent(EventSubscriberAdd, req.ChannelId, req.ChannelType, req.Subscribers, req.Reset == 1)
 	c.ResponseOK()
 }
 
@@ -258,22 +309,21 @@ func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 			existSubscribers = append(existSubscribers, member.Uid)
 		}
 	}
+	existSubscriberMap := make(map[string]struct{}, len(existSubscribers)+len(req.Subscribers))
+	for _, subscriber := range existSubscribers {
+		existSubscriberMap[subscriber] = struct{}{}
+	}
 	newSubscribers := make([]string, 0, len(req.Subscribers))
 	for _, subscriber := range req.Subscribers {
 		if strings.TrimSpace(subscriber) == "" {
 			continue
 		}
-		if !wkutil.ArrayContains(existSubscribers, subscriber) {
+		if _, ok := existSubscriberMap[subscriber]; !ok {
+			existSubscriberMap[subscriber] = struct{}{}
 			newSubscribers = append(newSubscribers, subscriber)
 		}
 	}
 	if len(newSubscribers) > 0 {
-		lastMsgSeq, err := ch.s.store.GetLastMsgSeq(req.ChannelId, req.ChannelType)
-		if err != nil {
-			ch.Error("获取最大消息序号失败！", zap.Error(err), zap.String("channelID", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
-			return err
-		}
-
 		// 添加订阅者
 		members := make([]wkdb.Member, 0, len(newSubscribers))
 		createdAt := time.Now()
@@ -292,36 +342,75 @@ func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 		}
 
 		// 添加或更新订阅者的最近会话最新消息序号
-		for _, subscriber := range newSubscribers {
-			createdAt := time.Now()
-			updatedAt := time.Now()
-			err = ch.s.store.AddOrUpdateConversations(subscriber, []wkdb.Conversation{
-				{
-					Id:           ch.s.store.NextPrimaryKey(),
-					Uid:          subscriber,
-					ChannelId:    req.ChannelId,
-					ChannelType:  req.ChannelType,
-					Type:         wkdb.ConversationTypeChat,
-					UnreadCount:  0,
-					ReadToMsgSeq: lastMsgSeq,
-					CreatedAt:    &createdAt,
-					UpdatedAt:    &updatedAt,
-				},
-			})
-			if err != nil {
-				ch.Error("添加或更新最近会话失败！", zap.Error(err), zap.String("uid", subscriber), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
-				return err
-			}
+		err = ch.addSubscriberConversations(req.ChannelId, req.ChannelType, newSubscribers)
+		if err != nil {
+			return err
 		}
-
 	}
-	channelKey := wkutil.ChannelToKey(req.ChannelId, req.ChannelType)
-	channel := ch.s.channelReactor.reactorSub(channelKey).channel(channelKey)
-	if channel != nil {
+	if req.Reset == 1 {
 		// 重新生成接收者标签
-		_, err = channel.makeReceiverTag()
+		err = ch.updateReceiverTag(req.ChannelId, req.ChannelType, nil, nil, true)
+	} else {
+		err = ch.updateReceiverTag(req.ChannelId, req.ChannelType, newSubscribers, nil, false)
+	}
+	if err != nil {
+		ch.Error("创建接收者标签失败！", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// addSubscriberConversations 为新的订阅者创建最近会话，已读位置为频道当前最新的消息序号
+func (ch *ChannelAPI) addSubscriberConversations(channelId string, channelType uint8, uids []string) error {
+	lastMsgSeq, err := ch.s.store.GetLastMsgSeq(channelId, channelType)
+	if err != nil {
+		ch.Error("获取最大消息序号失败！", zap.Error(err), zap.String("channelID", channelId), zap.Uint8("channelType", channelType))
+		return err
+	}
+	now := time.Now()
+	conversations := make([]wkdb.Conversation, 0, len(uids))
+	for _, uid := range uids {
+		createdAt := now
+		updatedAt := now
+		conversations = append(conversations, wkdb.Conversation{
+			Uid:          uid,
+			ChannelId:    channelId,
+			ChannelType:  channelType,
+			Type:         wkdb.ConversationTypeChat,
+			UnreadCount:  0,
+			ReadToMsgSeq: lastMsgSeq,
+			CreatedAt:    &createdAt,
+			UpdatedAt:    &updatedAt,
+		})
+	}
+	// 按订阅者所在的槽批量提案，避免每个订阅者单独提案
+	err = ch.s.store.AddOrUpdateUserConversations(conversations)
+	if err != nil {
+		ch.Error("添加或更新最近会话失败！", zap.Error(err), zap.Int("uids", len(uids)), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		return err
+	}
+	return nil
+}
+
+// updateReceiverTag 更新频道和对应命令频道的接收者标签（如果频道在内存中）
+// reset为true时重新生成标签，否则只增量添加或移除订阅者
+func (ch *ChannelAPI) updateReceiverTag(channelId string, channelType uint8, addUids []string, removeUids []string, reset bool) error {
+	channelKeys := []string{
+		wkutil.ChannelToKey(channelId, channelType),
+		wkutil.ChannelToKey(ch.s.opts.OrginalConvertCmdChannel(channelId), channelType),
+	}
+	for _, channelKey := range channelKeys {
+		channel := ch.s.channelReactor.reactorSub(channelKey).channel(channelKey)
+		if channel == nil {
+			continue
+		}
+		var err error
+		if reset {
+			_, err = channel.makeReceiverTag()
+		} else if len(addUids) > 0 || len(removeUids) > 0 {
+			_, err = channel.updateReceiverTag(addUids, removeUids)
+		}
 		if err != nil {
-			ch.Error("创建接收者标签失败！", zap.Error(err))
 			return err
 		}
 	}
@@ -364,17 +453,14 @@ func (ch *ChannelAPI) removeSubscriber(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	ch.triggerMembersEvent(EventSubscriberRemove, req.ChannelID, req.ChannelType, req.Subscribers, false)
 
-	channelKey := wkutil.ChannelToKey(req.ChannelID, req.ChannelType)
-	channel := ch.s.channelReactor.reactorSub(channelKey).channel(channelKey)
-	if channel != nil {
-		// 重新生成接收者标签
-		_, err = channel.makeReceiverTag()
-		if err != nil {
-			ch.Error("创建接收者标签失败！", zap.Error(err))
-			c.ResponseError(err)
-			return
-		}
+	// 只移除订阅者，不需要重新加载全部订阅者
+	err = ch.updateReceiverTag(req.ChannelID, req.ChannelType, nil, req.Subscribers, false)
+	if err != nil {
+		ch.Error("创建接收者标签失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
 	}
 
 	c.ResponseOK()
@@ -429,6 +515,7 @@ func (ch *ChannelAPI) blacklistAdd(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	ch.triggerMembersEvent(EventDenylistAdd, req.ChannelID, req.ChannelType, req.UIDs, false)
 
 	c.ResponseOK()
 }
@@ -487,6 +574,7 @@ func (ch *ChannelAPI) blacklistSet(c *wkhttp.Context) {
 			return
 		}
 	}
+	ch.triggerMembersEvent(EventDenylistSet, req.ChannelID, req.ChannelType, req.UIDs, true)
 
 	c.ResponseOK()
 }
@@ -524,6 +612,7 @@ func (ch *ChannelAPI) blacklistRemove(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	ch.triggerMembersEvent(EventDenylistRemove, req.ChannelID, req.ChannelType, req.UIDs, false)
 
 	c.ResponseOK()
 }
@@ -555,12 +644,35 @@ func (ch *ChannelAPI) channelDelete(c *wkhttp.Context) {
 		}
 	}
 
+	subscribers, err := ch.s.store.GetSubscribers(req.ChannelID, req.ChannelType)
+	if err != nil {
+		ch.Error("获取频道订阅者失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(err)
+		return
+	}
+
 	err = ch.s.store.DeleteChannelAndClearMessages(req.ChannelID, req.ChannelType)
 	if err != nil {
 		c.ResponseError(err)
 		return
 	}
 
+	// 频道的消息都被删除了，清空订阅者在此频道的未读数
+	uids := make([]string, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		uids = append(uids, subscriber.Uid)
+	}
+	ch.s.conversationManager.RemoveUnreadOfUsers(req.ChannelID, req.ChannelType, uids, nil, true)
+	ch.s.webhook.TriggerEvent(&Event{
+		Event:       EventChannelDelete,
+		ChannelType: req.ChannelType,
+		Key:         wkutil.ChannelToKey(req.ChannelID, req.ChannelType),
+		Data: ChannelEventData{
+			ChannelID:   req.ChannelID,
+			ChannelType: req.ChannelType,
+		},
+	})
+
 	c.ResponseOK()
 }
 
@@ -616,6 +728,7 @@ func (ch *ChannelAPI) whitelistAdd(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	ch.triggerMembersEvent(EventAllowlistAdd, req.ChannelID, req.ChannelType, req.UIDs, false)
 
 	c.ResponseOK()
 }
@@ -671,6 +784,7 @@ func (ch *ChannelAPI) whitelistSet(c *wkhttp.Context) {
 			return
 		}
 	}
+	ch.triggerMembersEvent(EventAllowlistSet, req.ChannelID, req.ChannelType, req.UIDs, true)
 
 	c.ResponseOK()
 }
@@ -709,6 +823,7 @@ func (ch *ChannelAPI) whitelistRemove(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	ch.triggerMembersEvent(EventAllowlistRemove, req.ChannelID, req.ChannelType, req.UIDs, false)
 
 	c.ResponseOK()
 }
@@ -803,8 +918,10 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 	if req.ChannelType == wkproto.ChannelTypePerson {
 		fakeChannelID = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
 	}
+	readRole := readRoleLeader
 	if ch.s.opts.ClusterOn() {
-		leaderInfo, err := ch.s.cluster.LeaderOfChannelForRead(fakeChannelID, req.ChannelType) // 获取频道的领导节点
+		// 开启从副本读取时，由同步及时的副本返回，否则由领导返回
+		readNodeId, role, err := ch.s.syncReadNode(fakeChannelID, req.ChannelType, c.GetHeader(HeaderFollowerRead) != "")
 		if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
 			ch.Info("频道集群从未初始化，返回空消息.", zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
 			c.JSON(http.StatusOK, emptySyncMessageResp)
@@ -815,13 +932,22 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 			c.ResponseError(errors.New("获取频道所在节点失败！"))
 			return
 		}
-		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
 
-		if !leaderIsSelf {
-			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
-			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		if readNodeId != ch.s.opts.Cluster.NodeId {
+			readNode, err := ch.s.cluster.NodeInfoById(readNodeId)
+			if err != nil {
+				ch.Error("获取节点信息失败！", zap.Error(err), zap.Uint64("nodeId", readNodeId))
+				c.ResponseError(errors.New("获取节点信息失败！"))
+				return
+			}
+			if role == readRoleFollower {
+				c.Request.Header.Set(HeaderFollowerRead, "1")
+			}
+			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", readNode.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", readNode.ApiServerAddr, c.Request.URL.Path), bodyBytes)
 			return
 		}
+		readRole = role
 	}
 	if req.StartMessageSeq == 0 && req.EndMessageSeq == 0 {
 		messages, err = ch.s.store.LoadLastMsgs(fakeChannelID, req.ChannelType, limit)
@@ -865,6 +991,7 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 			}
 		}
 	}
+	ch.s.setReadHeader(c, readRole)
 	c.JSON(http.StatusOK, syncMessageResp{
 		StartMessageSeq: req.StartMessageSeq,
 		EndMessageSeq:   req.EndMessageSeq,
@@ -910,22 +1037,83 @@ func (ch *ChannelAPI) getChannelMaxMessageSeq(c *wkhttp.Context) {
 	})
 }
 
-func (ch *ChannelAPI) addOrUpdateChannel(channelInfo wkdb.ChannelInfo) error {
+// addOrUpdateChannel 添加或更新频道，返回更新前的频道信息（频道不存在则为空）
+func (ch *ChannelAPI) addOrUpdateChannel(channelInfo wkdb.ChannelInfo) (wkdb.ChannelInfo, error) {
 	existChannel, err := ch.s.store.GetChannel(channelInfo.ChannelId, channelInfo.ChannelType)
 	if err != nil && err != wkdb.ErrNotFound {
-		return err
+		return existChannel, err
 	}
 
 	if wkdb.IsEmptyChannelInfo(existChannel) {
 		err = ch.s.store.AddChannelInfo(channelInfo)
 		if err != nil {
-			return err
+			return existChannel, err
 		}
 	} else {
 		err = ch.s.store.UpdateChannelInfo(channelInfo)
 		if err != nil {
-			return err
+			return existChannel, err
 		}
 	}
-	return nil
+	return existChannel, nil
+}
+
+// reconfigureChannelIfNeed 频道的副本数量或确认方式发生变化时，重新配置频道的副本
+func (ch *ChannelAPI) reconfigureChannelIfNeed(channelInfo wkdb.ChannelInfo, existChannel wkdb.ChannelInfo) error {
+	if !ch.s.opts.ClusterOn() {
+		return nil
+	}
+	if channelInfo.ReplicaCount == existChannel.ReplicaCount && channelInfo.AckMode == existChannel.AckMode {
+		return nil
+	}
+	timeoutCtx, cancel := context.WithTimeout(ch.s.ctx, ch.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	return ch.s.clusterServer.ReconfigureChannel(timeoutCtx, channelInfo.ChannelId, channelInfo.ChannelType)
+}
+
+// triggerChannelEvent 触发频道创建或更新事件，个人频道封禁状态变化时触发用户封禁事件
+func (ch *ChannelAPI) triggerChannelEvent(req ChannelInfoReq, existChannel wkdb.ChannelInfo) {
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		if existChannel.Ban != (req.Ban == 1) {
+			ch.s.webhook.TriggerEvent(&Event{
+				Event: EventUserBan,
+				Key:   req.ChannelID,
+				Data: UserBanEventData{
+					UID: req.ChannelID,
+					Ban: req.Ban,
+				},
+			})
+		}
+	}
+	event := EventChannelUpdate
+	if wkdb.IsEmptyChannelInfo(existChannel) {
+		event = EventChannelCreate
+	}
+	ch.s.webhook.TriggerEvent(&Event{
+		Event:       event,
+		ChannelType: req.ChannelType,
+		Key:         wkutil.ChannelToKey(req.ChannelID, req.ChannelType),
+		Data: ChannelEventData{
+			ChannelID:   req.ChannelID,
+			ChannelType: req.ChannelType,
+			Large:       req.Large,
+			Ban:         req.Ban,
+			Disband:     req.Disband,
+		},
+	})
+}
+
+// triggerMembersEvent 触发订阅者、黑名单、白名单变更事件
+func (ch *ChannelAPI) triggerMembersEvent(event string, channelId string, channelType uint8, uids []string, reset bool) {
+	ch.s.webhook.TriggerEvent(&Event{
+		Event:       event,
+		ChannelType: channelType,
+		Key:         wkutil.ChannelToKey(channelId, channelType),
+		Data: ChannelMembersEventData{
+			ChannelID:   channelId,
+			ChannelType: channelType,
+			UIDs:        uids,
+			Reset:       wkutil.BoolToInt(reset),
+		},
+	})
 }
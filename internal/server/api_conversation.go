@@ -39,6 +39,7 @@ func (s *ConversationAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/conversations/clearUnread", s.clearConversationUnread) // 清空会话未读数量
 	r.POST("/conversations/setUnread", s.setConversationUnread)     // 设置会话未读数量
 	r.POST("/conversations/delete", s.deleteConversation)           // 删除会话
+	r.POST("/conversations/extra/set", s.setConversationExtra)      // 设置会话扩展属性（置顶、免打扰、草稿等）
 	r.POST("/conversation/sync", s.syncUserConversation)            // 同步会话
 	r.POST("/conversation/syncMessages", s.syncRecentMessages)      // 同步会话最近消息
 }
@@ -117,46 +118,51 @@ func (s *ConversationAPI) clearConversationUnread(c *wkhttp.Context) {
 
 	}
 
-	conversation, err := s.s.store.GetConversation(req.UID, fakeChannelId, req.ChannelType)
-	if err != nil && err != wkdb.ErrNotFound {
-		s.Error("Failed to query conversation", zap.Error(err))
-		c.ResponseError(err)
-		return
-	}
-	if wkdb.IsEmptyConversation(conversation) {
-		createdAt := time.Now()
-		updatedAt := time.Now()
-		conversation = wkdb.Conversation{
-			Uid:         req.UID,
-			ChannelId:   fakeChannelId,
-			ChannelType: req.ChannelType,
-			CreatedAt:   &createdAt,
-			UpdatedAt:   &updatedAt,
+	// 和缓存的保存互斥，防止缓存里的旧未读数覆盖清空后的值
+	err = s.s.conversationManager.UpdateConversation(req.UID, fakeChannelId, req.ChannelType, func() error {
+		conversation, err := s.s.store.GetConversation(req.UID, fakeChannelId, req.ChannelType)
+		if err != nil && err != wkdb.ErrNotFound {
+			s.Error("Failed to query conversation", zap.Error(err))
+			return err
+		}
+		if wkdb.IsEmptyConversation(conversation) {
+			createdAt := time.Now()
+			updatedAt := time.Now()
+			conversation = wkdb.Conversation{
+				Uid:         req.UID,
+				ChannelId:   fakeChannelId,
+				ChannelType: req.ChannelType,
+				CreatedAt:   &createdAt,
+				UpdatedAt:   &updatedAt,
+			}
 		}
-	}
 
-	// 获取此频道最新的消息
-	msgSeq, err := s.s.store.GetLastMsgSeq(fakeChannelId, req.ChannelType)
-	if err != nil {
-		s.Error("Failed to query last message", zap.Error(err))
-		c.ResponseError(err)
-		return
-	}
+		// 获取此频道最新的消息
+		msgSeq, err := s.s.store.GetLastMsgSeq(fakeChannelId, req.ChannelType)
+		if err != nil {
+			s.Error("Failed to query last message", zap.Error(err))
+			return err
+		}
 
-	if conversation.ReadToMsgSeq < msgSeq {
-		conversation.ReadToMsgSeq = msgSeq
+		if conversation.ReadToMsgSeq < msgSeq {
+			conversation.ReadToMsgSeq = msgSeq
 
-	}
+		}
+		conversation.UnreadCount = 0
+		conversation.Mention = &wkdb.ConversationMention{} // 清除@提醒
 
-	err = s.s.store.AddOrUpdateConversations(req.UID, []wkdb.Conversation{conversation})
+		err = s.s.store.AddOrUpdateConversations(req.UID, []wkdb.Conversation{conversation})
+		if err != nil {
+			s.Error("Failed to add conversation", zap.Error(err))
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		s.Error("Failed to add conversation", zap.Error(err))
 		c.ResponseError(err)
 		return
 	}
 
-	s.s.conversationManager.DeleteUserConversationFromCache(req.UID, fakeChannelId, req.ChannelType)
-
 	c.ResponseOK()
 }
 
@@ -204,58 +210,153 @@ func (s *ConversationAPI) setConversationUnread(c *wkhttp.Context) {
 		fakeChannelId = GetFakeChannelIDWith(req.UID, req.ChannelID)
 
 	}
-	// 获取此频道最新的消息
-	msgSeq, err := s.s.store.GetLastMsgSeq(fakeChannelId, req.ChannelType)
+	// 和缓存的保存互斥，防止缓存里的旧未读数覆盖设置后的值
+	err = s.s.conversationManager.UpdateConversation(req.UID, fakeChannelId, req.ChannelType, func() error {
+		// 获取此频道最新的消息
+		msgSeq, err := s.s.store.GetLastMsgSeq(fakeChannelId, req.ChannelType)
+		if err != nil {
+			s.Error("Failed to query last message", zap.Error(err))
+			return err
+		}
+
+		conversation, err := s.s.store.GetConversation(req.UID, fakeChannelId, req.ChannelType)
+		if err != nil && err != wkdb.ErrNotFound {
+			s.Error("Failed to query conversation", zap.Error(err))
+			return err
+		}
+
+		if wkdb.IsEmptyConversation(conversation) {
+			createdAt := time.Now()
+			updatedAt := time.Now()
+			conversation = wkdb.Conversation{
+				Uid:         req.UID,
+				ChannelId:   fakeChannelId,
+				ChannelType: req.ChannelType,
+				CreatedAt:   &createdAt,
+				UpdatedAt:   &updatedAt,
+			}
+
+		}
+
+		var unread uint32 = 0
+		var readedMsgSeq uint64 = msgSeq
+
+		if uint64(req.Unread) > msgSeq {
+			unread = 1
+			readedMsgSeq = msgSeq - 1
+		} else if req.Unread > 0 {
+			unread = uint32(req.Unread)
+			readedMsgSeq = msgSeq - uint64(req.Unread)
+		}
+
+		conversation.ReadToMsgSeq = readedMsgSeq
+		conversation.UnreadCount = unread
+
+		err = s.s.store.AddOrUpdateConversations(req.UID, []wkdb.Conversation{conversation})
+		if err != nil {
+			s.Error("Failed to add conversation", zap.Error(err))
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		s.Error("Failed to query last message", zap.Error(err))
 		c.ResponseError(err)
 		return
 	}
 
-	conversation, err := s.s.store.GetConversation(req.UID, fakeChannelId, req.ChannelType)
-	if err != nil && err != wkdb.ErrNotFound {
-		s.Error("Failed to query conversation", zap.Error(err))
+	c.ResponseOK()
+}
+
+// 设置会话扩展属性
+func (s *ConversationAPI) setConversationExtra(c *wkhttp.Context) {
+	var req setConversationExtraReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		s.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
 		c.ResponseError(err)
 		return
 	}
 
-	if wkdb.IsEmptyConversation(conversation) {
-		createdAt := time.Now()
-		updatedAt := time.Now()
-		conversation = wkdb.Conversation{
-			Uid:         req.UID,
-			ChannelId:   fakeChannelId,
-			ChannelType: req.ChannelType,
-			CreatedAt:   &createdAt,
-			UpdatedAt:   &updatedAt,
+	if s.s.opts.ClusterOn() {
+		leaderInfo, err := s.s.cluster.SlotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
+		if err != nil {
+			s.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
 		}
+		leaderIsSelf := leaderInfo.Id == s.s.opts.Cluster.NodeId
+		if !leaderIsSelf {
+			s.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
 
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.UID, req.ChannelID)
 	}
 
-	var unread uint32 = 0
-	var readedMsgSeq uint64 = msgSeq
+	// 和缓存的保存互斥，防止缓存里的旧会话覆盖修改后的扩展数据
+	err = s.s.conversationManager.UpdateConversation(req.UID, fakeChannelId, req.ChannelType, func() error {
+		conversation, err := s.s.store.GetConversation(req.UID, fakeChannelId, req.ChannelType)
+		if err != nil && err != wkdb.ErrNotFound {
+			s.Error("Failed to query conversation", zap.Error(err))
+			return err
+		}
+		if wkdb.IsEmptyConversation(conversation) {
+			createdAt := time.Now()
+			updatedAt := time.Now()
+			conversation = wkdb.Conversation{
+				Uid:         req.UID,
+				ChannelId:   fakeChannelId,
+				ChannelType: req.ChannelType,
+				CreatedAt:   &createdAt,
+				UpdatedAt:   &updatedAt,
+			}
+		}
+		conversation.Extra = req.merge(conversation.Extra)
 
-	if uint64(req.Unread) > msgSeq {
-		unread = 1
-		readedMsgSeq = msgSeq - 1
-	} else if req.Unread > 0 {
-		unread = uint32(req.Unread)
-		readedMsgSeq = msgSeq - uint64(req.Unread)
+		err = s.s.store.AddOrUpdateConversations(req.UID, []wkdb.Conversation{conversation})
+		if err != nil {
+			s.Error("Failed to add conversation", zap.Error(err))
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		c.ResponseError(err)
+		return
 	}
 
-	conversation.ReadToMsgSeq = readedMsgSeq
-	conversation.UnreadCount = unread
-
-	err = s.s.store.AddOrUpdateConversations(req.UID, []wkdb.Conversation{conversation})
+	// 获取写入后的版本号
+	conversation, err := s.s.store.GetConversation(req.UID, fakeChannelId, req.ChannelType)
 	if err != nil {
-		s.Error("Failed to add conversation", zap.Error(err))
+		s.Error("Failed to query conversation", zap.Error(err))
 		c.ResponseError(err)
 		return
 	}
+	extraResp := newConversationExtraResp(conversation.Extra)
 
-	s.s.conversationManager.DeleteUserConversationFromCache(req.UID, fakeChannelId, req.ChannelType)
+	if req.Mute != nil { // 投递时使用的是缓存的免打扰设置
+		s.s.conversationManager.mutes.notifyChanged(fakeChannelId, req.ChannelType, req.UID)
+	}
 
-	c.ResponseOK()
+	// 通知用户的其他设备
+	err = s.s.sendCMDToUser(req.UID, CMDConversationExtraUpdate, map[string]interface{}{
+		"channel_id":   req.ChannelID,
+		"channel_type": req.ChannelType,
+		"extra":        extraResp,
+	})
+	if err != nil {
+		s.Warn("发送会话扩展属性更新命令失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+	}
+
+	c.ResponseOKWithData(extraResp)
 }
 
 func (s *ConversationAPI) deleteConversation(c *wkhttp.Context) {
@@ -301,15 +402,28 @@ func (s *ConversationAPI) deleteConversation(c *wkhttp.Context) {
 
 	s.s.conversationManager.DeleteUserConversationFromCache(req.UID, fakeChannelId, req.ChannelType)
 
+	s.s.webhook.TriggerEvent(&Event{
+		Event:       EventConversationDelete,
+		ChannelType: req.ChannelType,
+		Key:         req.UID,
+		Data: ConversationDeleteEventData{
+			UID:         req.UID,
+			ChannelID:   req.ChannelID,
+			ChannelType: req.ChannelType,
+		},
+	})
+
 	c.ResponseOK()
 }
 
 func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 	var req struct {
 		UID         string `json:"uid"`
-		Version     int64  `json:"version"`       // 当前客户端的会话最大版本号(客户端最新会话的时间戳)
+		Version     int64  `json:"version"`       // 当前客户端的会话最大版本号，增量同步时为上次同步返回的会话版本号，全量同步时为客户端最新会话的时间戳
 		LastMsgSeqs string `json:"last_msg_seqs"` // 客户端所有会话的最后一条消息序列号 格式： channelID:channelType:last_msg_seq|channelID:channelType:last_msg_seq
 		MsgCount    int64  `json:"msg_count"`     // 每个会话消息数量
+		Incremental int    `json:"incremental"`   // 1：按会话版本号增量同步，version为上次同步返回的版本号
+		Limit       int    `json:"limit"`         // 增量同步每次返回的最大会话数量
 	}
 	bodyBytes, err := BindJSON(&req, c)
 	if err != nil {
@@ -332,6 +446,17 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 		return
 	}
 
+	if req.Incremental == 1 {
+		resp, err := s.syncUserConversationIncr(req.UID, uint64(req.Version), req.LastMsgSeqs, int(req.MsgCount), req.Limit)
+		if err != nil {
+			s.Error("增量同步会话失败！", zap.Error(err), zap.String("uid", req.UID))
+			c.ResponseError(err)
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
 	var (
 		channelLastMsgMap        = s.getChannelLastMsgSeqMap(req.LastMsgSeqs) // 获取频道对应的最后一条消息的messageSeq
 		channelRecentMessageReqs = make([]*channelRecentMessageReq, 0, len(channelLastMsgMap))
@@ -361,6 +486,8 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 				if cacheConversation.ReadToMsgSeq > conversation.ReadToMsgSeq {
 					conversations[i].ReadToMsgSeq = cacheConversation.ReadToMsgSeq
 				}
+				conversations[i].UnreadCount = cacheConversation.UnreadCount // 缓存里的未读数是最新的
+				conversations[i].Mention = conversation.Mention.Merge(cacheConversation.Mention)
 				exist = true
 				break
 			}
@@ -423,14 +550,12 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 						resp.LastMsgSeq = uint32(lastMsg.MessageSeq)
 						resp.LastClientMsgNo = lastMsg.ClientMsgNo
 						resp.Timestamp = int64(lastMsg.Timestamp)
-						if lastMsg.MessageSeq > uint64(resp.ReadedToMsgSeq) {
-							resp.Unread = int(lastMsg.MessageSeq - uint64(resp.ReadedToMsgSeq))
-						}
 
 						resp.Version = time.Unix(int64(lastMsg.Timestamp), 0).UnixNano()
 					}
 
 					resp.Recents = channelRecentMessage.Messages
+					resp.applyLarge(channelRecentMessage)
 					break
 				}
 			}
@@ -450,6 +575,223 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 	c.JSON(http.StatusOK, resps)
 }
 
+// syncUserConversationIncr 增量同步版本号大于version的会话（包括已删除的会话）
+// version为0、大于服务端的版本号（比如服务端数据被重置）或者需要的删除记录已经被清理时返回全部会话
+func (s *ConversationAPI) syncUserConversationIncr(uid string, version uint64, lastMsgSeqs string, msgCount int, limit int) (*syncUserConversationIncrResp, error) {
+	if limit <= 0 || limit > s.s.opts.Conversation.UserMaxCount {
+		limit = s.s.opts.Conversation.UserMaxCount
+	}
+	currentVersion, err := s.s.store.GetConversationVersion(uid)
+	if err != nil {
+		return nil, err
+	}
+	tombstoneFloor, err := s.s.store.GetConversationTombstoneFloor(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &syncUserConversationIncrResp{
+		Conversations: make([]*syncUserConversationResp, 0),
+	}
+	var changes []conversationChange
+	if version == 0 || version > currentVersion || version < tombstoneFloor {
+		conversations, err := s.s.store.GetLastConversations(uid, wkdb.ConversationTypeChat, 0, s.s.opts.Conversation.UserMaxCount)
+		if err != nil && err != wkdb.ErrNotFound {
+			return nil, err
+		}
+		for _, conversation := range conversations {
+			changes = append(changes, conversationChange{conversation: conversation})
+		}
+		result.Version = currentVersion
+		result.Reset = wkutil.BoolToInt(version > 0)
+	} else {
+		conversations, err := s.s.store.GetConversationsByVersion(uid, version, limit)
+		if err != nil {
+			return nil, err
+		}
+		tombstones, err := s.s.store.GetConversationTombstones(uid, version, limit)
+		if err != nil {
+			return nil, err
+		}
+		var more bool
+		changes, result.Version, more = mergeConversationChanges(conversations, tombstones, version, limit)
+		result.More = wkutil.BoolToInt(more)
+	}
+
+	// 缓存里还没保存的会话和有新消息的会话，最后一页才返回
+	if result.More == 0 {
+		cacheConversations := s.s.conversationManager.GetUserConversationFromCache(uid, wkdb.ConversationTypeChat)
+		for _, cacheConversation := range cacheConversations {
+			exist := false
+			for i, change := range changes {
+				if !change.deleted && change.conversation.ChannelId == cacheConversation.ChannelId && change.conversation.ChannelType == cacheConversation.ChannelType {
+					if cacheConversation.ReadToMsgSeq > change.conversation.ReadToMsgSeq {
+						changes[i].conversation.ReadToMsgSeq = cacheConversation.ReadToMsgSeq
+					}
+					changes[i].conversation.UnreadCount = cacheConversation.UnreadCount // 缓存里的未读数是最新的
+					changes[i].conversation.Mention = change.conversation.Mention.Merge(cacheConversation.Mention)
+					exist = true
+					break
+				}
+			}
+			if !exist {
+				changes = append(changes, conversationChange{conversation: cacheConversation, onlyCache: true})
+			}
+		}
+	}
+
+	channelLastMsgMap := s.getChannelLastMsgSeqMap(lastMsgSeqs)
+	channelRecentMessageReqs := make([]*channelRecentMessageReq, 0, len(changes))
+	for _, change := range changes {
+		if change.deleted {
+			continue
+		}
+		realChannelId := newSyncUserConversationResp(change.conversation).ChannelId
+		msgSeq := channelLastMsgMap[fmt.Sprintf("%s-%d", realChannelId, change.conversation.ChannelType)]
+		if msgSeq != 0 {
+			msgSeq = msgSeq + 1 // 如果客户端传递了messageSeq，则需要获取这个messageSeq之后的消息
+		}
+		channelRecentMessageReqs = append(channelRecentMessageReqs, &channelRecentMessageReq{
+			ChannelId:   realChannelId,
+			ChannelType: change.conversation.ChannelType,
+			LastMsgSeq:  msgSeq,
+		})
+	}
+
+	// 超大群有新消息时会话版本号不变，最后一页时拉取客户端已有的群会话里超大群的新消息
+	if result.More == 0 && msgCount > 0 {
+		for channelKey, msgSeq := range channelLastMsgMap {
+			channelId, channelType := splitChannelLastMsgSeqKey(channelKey)
+			if channelType == wkproto.ChannelTypePerson || channelType == 0 {
+				continue
+			}
+			exist := false
+			for _, change := range changes {
+				if change.conversation.ChannelId == channelId && change.conversation.ChannelType == channelType {
+					exist = true
+					break
+				}
+			}
+			if exist {
+				continue
+			}
+			conversation, err := s.s.store.GetConversation(uid, channelId, channelType)
+			if err != nil {
+				if err == wkdb.ErrNotFound {
+					continue
+				}
+				return nil, err
+			}
+			changes = append(changes, conversationChange{conversation: conversation, onlyCache: true})
+			channelRecentMessageReqs = append(channelRecentMessageReqs, &channelRecentMessageReq{
+				ChannelId:   channelId,
+				ChannelType: channelType,
+				LastMsgSeq:  msgSeq + 1,
+				OnlyLarge:   1,
+			})
+		}
+	}
+
+	var channelRecentMessages []*channelRecentMessage
+	if msgCount > 0 {
+		channelRecentMessages, err = s.s.getRecentMessagesForCluster(uid, msgCount, channelRecentMessageReqs, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, change := range changes {
+		conversation := change.conversation
+		if conversation.ChannelType == wkproto.ChannelTypePerson && conversation.ChannelId == s.s.opts.SystemUID { // 系统消息不返回
+			continue
+		}
+		resp := newSyncUserConversationResp(conversation)
+		if change.deleted {
+			resp.Deleted = 1
+			result.Conversations = append(result.Conversations, resp)
+			continue
+		}
+		for _, channelRecentMessage := range channelRecentMessages {
+			if resp.ChannelId == channelRecentMessage.ChannelId && conversation.ChannelType == channelRecentMessage.ChannelType {
+				if len(channelRecentMessage.Messages) > 0 {
+					lastMsg := channelRecentMessage.Messages[0]
+					resp.LastMsgSeq = uint32(lastMsg.MessageSeq)
+					resp.LastClientMsgNo = lastMsg.ClientMsgNo
+					resp.Timestamp = int64(lastMsg.Timestamp)
+				}
+				resp.Recents = channelRecentMessage.Messages
+				resp.applyLarge(channelRecentMessage)
+				break
+			}
+		}
+		resp.Version = int64(conversation.Version)
+
+		// 只在缓存里的会话，客户端已经有最新消息的不返回
+		if change.onlyCache && msgCount > 0 && len(resp.Recents) == 0 {
+			continue
+		}
+		result.Conversations = append(result.Conversations, resp)
+	}
+	return result, nil
+}
+
+type conversationChange struct {
+	conversation wkdb.Conversation
+	deleted      bool // 已删除的会话
+	onlyCache    bool // 只在缓存里的会话（版本号没有变化，但是可能有新消息）
+}
+
+// mergeConversationChanges 合并变更的会话和已删除的会话，按版本号升序取前limit个，返回下次同步的版本号和是否还有更多变更
+func mergeConversationChanges(conversations []wkdb.Conversation, tombstones []wkdb.ConversationTombstone, version uint64, limit int) ([]conversationChange, uint64, bool) {
+	changes := make([]conversationChange, 0, len(conversations)+len(tombstones))
+	for _, conversation := range conversations {
+		changes = append(changes, conversationChange{conversation: conversation})
+	}
+	for _, tombstone := range tombstones {
+		changes = append(changes, conversationChange{
+			conversation: wkdb.Conversation{
+				Uid:         tombstone.Uid,
+				ChannelId:   tombstone.ChannelId,
+				ChannelType: tombstone.ChannelType,
+				Version:     tombstone.Version,
+			},
+			deleted: true,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].conversation.Version < changes[j].conversation.Version
+	})
+
+	// 任意一边取满了，后面可能还有更多变更
+	more := len(conversations) >= limit || len(tombstones) >= limit || len(changes) > limit
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+	if len(changes) > 0 {
+		version = changes[len(changes)-1].conversation.Version
+	}
+
+	// CMD类型的会话不返回，但是版本号需要跳过
+	result := changes[:0]
+	for _, change := range changes {
+		if !change.deleted && change.conversation.Type != wkdb.ConversationTypeChat {
+			continue
+		}
+		result = append(result, change)
+	}
+	return result, version, more
+}
+
+// splitChannelLastMsgSeqKey 拆分getChannelLastMsgSeqMap返回的key
+func splitChannelLastMsgSeqKey(key string) (string, uint8) {
+	idx := strings.LastIndex(key, "-")
+	if idx < 0 {
+		return key, 0
+	}
+	channelType, _ := strconv.Atoi(key[idx+1:])
+	return key[:idx], uint8(channelType)
+}
+
 func (s *ConversationAPI) getChannelLastMsgSeqMap(lastMsgSeqs string) map[string]uint64 {
 	channelLastMsgSeqStrList := strings.Split(lastMsgSeqs, "|")
 	channelLastMsgMap := map[string]uint64{} // 频道对应的messageSeq
@@ -488,9 +830,31 @@ func (s *ConversationAPI) syncRecentMessages(c *wkhttp.Context) {
 		c.ResponseError(errors.New("获取最近消息失败！"))
 		return
 	}
+	if s.s.opts.ClusterOn() {
+		s.s.setReadHeader(c, s.s.recentMessagesReadRole(req.UID, req.Channels))
+	}
 	c.JSON(http.StatusOK, channelRecentMessages)
 }
 
+// recentMessagesReadRole 本节点读取最近消息的副本角色，只要有一个频道是从追随者读取的就返回follower
+func (s *Server) recentMessagesReadRole(uid string, channels []*channelRecentMessageReq) string {
+	for _, channel := range channels {
+		fakeChannelId := channel.ChannelId
+		if channel.ChannelType == wkproto.ChannelTypePerson {
+			fakeChannelId = GetFakeChannelIDWith(uid, channel.ChannelId)
+		}
+		state, ok, err := s.clusterServer.ChannelReadState(fakeChannelId, channel.ChannelType)
+		if err != nil {
+			s.Warn("获取频道副本读状态失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", channel.ChannelType))
+			continue
+		}
+		if ok && !state.IsLeader {
+			return readRoleFollower
+		}
+	}
+	return readRoleLeader
+}
+
 func (s *Server) getRecentMessagesForCluster(uid string, msgCount int, channels []*channelRecentMessageReq, orderByLast bool) ([]*channelRecentMessage, error) {
 	if len(channels) == 0 {
 		return nil, nil
@@ -506,6 +870,10 @@ func (s *Server) getRecentMessagesForCluster(uid string, msgCount int, channels
 		if channelRecentMsgReq.ChannelType == wkproto.ChannelTypePerson {
 			fakeChannelId = GetFakeChannelIDWith(uid, channelRecentMsgReq.ChannelId)
 		}
+		if s.followerReadable(fakeChannelId, channelRecentMsgReq.ChannelType) { // 本节点是同步及时的副本，直接从本地读取
+			localPeerChannelRecentMessageReqs = append(localPeerChannelRecentMessageReqs, channelRecentMsgReq)
+			continue
+		}
 		leaderInfo, err := s.cluster.LeaderOfChannelForRead(fakeChannelId, channelRecentMsgReq.ChannelType) // 获取频道的领导节点
 		if err != nil {
 			s.Warn("getRecentMessagesForCluster: 获取频道所在节点失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", channelRecentMsgReq.ChannelType))
@@ -613,6 +981,28 @@ func (s *Server) getRecentMessages(uid string, msgCount int, channels []*channel
 			if channel.ChannelType == wkproto.ChannelTypePerson {
 				fakeChannelID = GetFakeChannelIDWith(uid, channel.ChannelId)
 			}
+			var (
+				large         bool
+				channelMsgSeq uint64
+			)
+			if channel.ChannelType != wkproto.ChannelTypePerson {
+				channelInfo, err := s.store.GetChannel(channel.ChannelId, channel.ChannelType)
+				if err != nil {
+					s.Error("查询频道信息失败！", zap.Error(err), zap.String("channelId", channel.ChannelId), zap.Uint8("channelType", channel.ChannelType))
+					return nil, err
+				}
+				large = channelInfo.Large
+			}
+			if channel.OnlyLarge == 1 && !large {
+				continue
+			}
+			if large {
+				channelMsgSeq, err = s.store.GetLastMsgSeq(fakeChannelID, channel.ChannelType)
+				if err != nil {
+					s.Error("获取最大消息序号失败！", zap.Error(err), zap.String("channelId", channel.ChannelId), zap.Uint8("channelType", channel.ChannelType))
+					return nil, err
+				}
+			}
 			msgSeq := channel.LastMsgSeq
 			messageResps := MessageRespSlice{}
 			if orderByLast {
@@ -653,6 +1043,8 @@ func (s *Server) getRecentMessages(uid string, msgCount int, channels []*channel
 				ChannelId:   channel.ChannelId,
 				ChannelType: channel.ChannelType,
 				Messages:    messageResps,
+				Large:       wkutil.BoolToInt(large),
+				LastMsgSeq:  channelMsgSeq,
 			})
 		}
 	}
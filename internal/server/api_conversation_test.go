@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/client"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	"github.com/stretchr/testify/assert"
 )
@@ -66,3 +67,98 @@ func TestSyncUserConversation(t *testing.T) {
 	assert.Equal(t, "u1", conversations[0].ChannelId)
 	assert.Equal(t, 1, conversations[0].Unread)
 }
+
+func TestMergeConversationChanges(t *testing.T) {
+	conversations := []wkdb.Conversation{
+		{ChannelId: "g1", ChannelType: 2, Type: wkdb.ConversationTypeChat, Version: 2},
+		{ChannelId: "cmd", ChannelType: 2, Type: wkdb.ConversationTypeCMD, Version: 3},
+		{ChannelId: "g2", ChannelType: 2, Type: wkdb.ConversationTypeChat, Version: 5},
+	}
+	tombstones := []wkdb.ConversationTombstone{
+		{ChannelId: "g3", ChannelType: 2, Version: 4},
+	}
+
+	// 按版本号合并，CMD会话不返回但是版本号会跳过
+	changes, version, more := mergeConversationChanges(conversations, tombstones, 1, 10)
+	assert.False(t, more)
+	assert.Equal(t, uint64(5), version)
+	assert.Equal(t, 3, len(changes))
+	assert.Equal(t, "g1", changes[0].conversation.ChannelId)
+	assert.Equal(t, "g3", changes[1].conversation.ChannelId)
+	assert.True(t, changes[1].deleted)
+	assert.Equal(t, "g2", changes[2].conversation.ChannelId)
+
+	// 分页
+	changes, version, more = mergeConversationChanges(conversations[:2], tombstones, 1, 2)
+	assert.True(t, more)
+	assert.Equal(t, uint64(3), version)
+	assert.Equal(t, 1, len(changes))
+
+	// 没有变更时版本号不变
+	changes, version, more = mergeConversationChanges(nil, nil, 7, 10)
+	assert.False(t, more)
+	assert.Equal(t, uint64(7), version)
+	assert.Equal(t, 0, len(changes))
+}
+
+func TestSetConversationExtraReqMerge(t *testing.T) {
+	pin := 1
+	draft := "hello"
+	req := setConversationExtraReq{
+		Pin:   &pin,
+		Draft: &draft,
+	}
+	old := &wkdb.ConversationExtra{Mute: true, Draft: "old", Version: 3}
+	extra := req.merge(old)
+	assert.True(t, extra.Pin)
+	assert.True(t, extra.Mute) // 没传的字段保持不变
+	assert.Equal(t, "hello", extra.Draft)
+	assert.Equal(t, "old", old.Draft) // 不修改原来的扩展属性
+
+	extra = req.merge(nil)
+	assert.True(t, extra.Pin)
+	assert.False(t, extra.Mute)
+}
+
+func TestMessageMention(t *testing.T) {
+	mention := parseMessageMention([]byte(`{"type":1,"content":"hi","mention":{"uids":["u1","u2"]}}`))
+	assert.NotNil(t, mention)
+	assert.True(t, mention.IsMentioned("u1"))
+	assert.False(t, mention.IsMentioned("u3"))
+
+	assert.Nil(t, parseMessageMention([]byte(`{"type":1,"content":"hi"}`)))
+	assert.Nil(t, parseMessageMention([]byte(`not json "mention"`)))
+
+	payload, err := setPayloadMention([]byte(`{"type":1,"content":"hi"}`), &MessageMention{All: 1})
+	assert.NoError(t, err)
+	mention = parseMessageMention(payload)
+	assert.True(t, mention.IsMentioned("u3"))
+
+	_, err = setPayloadMention([]byte(`hello`), &MessageMention{All: 1})
+	assert.Error(t, err)
+
+	// 免打扰的用户被@时仍然推送离线
+	mutedUids := map[string]struct{}{"u1": {}, "u2": {}}
+	uids := excludeMutedUids([]string{"u1", "u2", "u3"}, mutedUids, &MessageMention{Uids: []string{"u2"}})
+	assert.Equal(t, []string{"u2", "u3"}, uids)
+	uids = excludeMutedUids([]string{"u1", "u2", "u3"}, mutedUids, nil)
+	assert.Equal(t, []string{"u3"}, uids)
+}
+
+func TestSyncUserConversationRespApplyLarge(t *testing.T) {
+	resp := &syncUserConversationResp{Unread: 0, ReadedToMsgSeq: 10}
+	resp.applyLarge(&channelRecentMessage{LastMsgSeq: 15})
+	assert.Equal(t, 0, resp.Unread) // 不是超大群不处理
+
+	resp.applyLarge(&channelRecentMessage{Large: 1, LastMsgSeq: 15})
+	assert.Equal(t, 1, resp.Large)
+	assert.Equal(t, 5, resp.Unread)
+
+	resp = &syncUserConversationResp{Unread: 3, ReadedToMsgSeq: 20}
+	resp.applyLarge(&channelRecentMessage{Large: 1, LastMsgSeq: 15})
+	assert.Equal(t, 0, resp.Unread)
+
+	channelId, channelType := splitChannelLastMsgSeqKey("g-1-2")
+	assert.Equal(t, "g-1", channelId)
+	assert.Equal(t, uint8(2), channelType)
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"go.uber.org/zap"
+)
+
+// DrainAPI 节点排空
+type DrainAPI struct {
+	s *Server
+	wklog.Log
+}
+
+// NewDrainAPI NewDrainAPI
+func NewDrainAPI(s *Server) *DrainAPI {
+	return &DrainAPI{
+		s:   s,
+		Log: wklog.NewWKLog("DrainAPI"),
+	}
+}
+
+// Route Route
+func (d *DrainAPI) Route(r *wkhttp.WKHttp) {
+	r.POST("/drain", d.drain)      // 开始排空当前节点
+	r.GET("/drain", d.drainStatus) // 获取当前节点的排空进度
+}
+
+func (d *DrainAPI) drain(c *wkhttp.Context) {
+	err := d.s.Drain()
+	if err != nil {
+		d.Error("排空节点失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, d.s.drainManager.getStatus())
+}
+
+func (d *DrainAPI) drainStatus(c *wkhttp.Context) {
+	c.JSON(http.StatusOK, d.s.drainManager.getStatus())
+}
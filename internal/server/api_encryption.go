@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/WuKongIM/WuKongIM/pkg/network"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// EncryptionAPI 消息静态加密
+type EncryptionAPI struct {
+	s *Server
+	wklog.Log
+}
+
+// NewEncryptionAPI NewEncryptionAPI
+func NewEncryptionAPI(s *Server) *EncryptionAPI {
+	return &EncryptionAPI{
+		s:   s,
+		Log: wklog.NewWKLog("EncryptionAPI"),
+	}
+}
+
+// Route Route
+func (e *EncryptionAPI) Route(r *wkhttp.WKHttp) {
+	r.GET("/encryption/status", e.status)  // 获取所有节点的加密状态（local=1 只获取当前节点）
+	r.POST("/encryption/rotate", e.rotate) // 轮换所有节点的数据密钥，已有消息在后台重新加密（local=1 只轮换当前节点）
+}
+
+type nodeEncryptionStatus struct {
+	NodeId uint64 `json:"node_id"`
+	wkdb.EncryptionStatus
+}
+
+func (e *EncryptionAPI) localStatus() (nodeEncryptionStatus, error) {
+	status, err := e.s.store.EncryptionStatus()
+	if err != nil {
+		return nodeEncryptionStatus{}, err
+	}
+	return nodeEncryptionStatus{NodeId: e.s.opts.Cluster.NodeId, EncryptionStatus: status}, nil
+}
+
+func (e *EncryptionAPI) status(c *wkhttp.Context) {
+	if c.Query("local") == "1" {
+		status, err := e.localStatus()
+		if err != nil {
+			e.Error("获取加密状态失败！", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+		c.JSON(http.StatusOK, status)
+		return
+	}
+	statuses := make([]nodeEncryptionStatus, 0)
+	var mu sync.Mutex
+	err := e.forEachNode(func(apiServerAddr string, local bool) error {
+		var status nodeEncryptionStatus
+		var err error
+		if local {
+			status, err = e.localStatus()
+			if err != nil {
+				return err
+			}
+		} else {
+			resp, err := network.Get(fmt.Sprintf("%s/encryption/status", apiServerAddr), map[string]string{"local": "1"}, nil)
+			if err != nil {
+				return err
+			}
+			if err = handlerIMError(resp); err != nil {
+				return err
+			}
+			if err = wkutil.ReadJSONByByte([]byte(resp.Body), &status); err != nil {
+				return err
+			}
+		}
+		mu.Lock()
+		statuses = append(statuses, status)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		e.Error("获取加密状态失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, statuses)
+}
+
+func (e *EncryptionAPI) rotate(c *wkhttp.Context) {
+	if !e.s.opts.Encryption.On {
+		c.ResponseError(errors.New("未开启静态加密"))
+		return
+	}
+	if c.Query("local") == "1" {
+		if err := e.s.store.RotateDataKeys(); err != nil {
+			e.Error("轮换数据密钥失败！", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+		c.ResponseOK()
+		return
+	}
+	err := e.forEachNode(func(apiServerAddr string, local bool) error {
+		if local {
+			return e.s.store.RotateDataKeys()
+		}
+		resp, err := network.Post(fmt.Sprintf("%s/encryption/rotate?local=1", apiServerAddr), nil, nil)
+		if err != nil {
+			return err
+		}
+		return handlerIMError(resp)
+	})
+	if err != nil {
+		e.Error("轮换数据密钥失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// forEachNode 并发请求所有节点
+func (e *EncryptionAPI) forEachNode(f func(apiServerAddr string, local bool) error) error {
+	if !e.s.opts.ClusterOn() {
+		return f("", true)
+	}
+	requestGroup, _ := errgroup.WithContext(context.Background())
+	for _, node := range e.s.clusterServer.GetConfig().Nodes {
+		if node.Id == e.s.opts.Cluster.NodeId {
+			requestGroup.Go(func() error {
+				return f("", true)
+			})
+			continue
+		}
+		if node.ApiServerAddr == "" {
+			continue
+		}
+		apiServerAddr := node.ApiServerAddr
+		requestGroup.Go(func() error {
+			return f(apiServerAddr, false)
+		})
+	}
+	return requestGroup.Wait()
+}
@@ -51,6 +51,10 @@ func (m *MessageAPI) Route(r *wkhttp.WKHttp) {
 
 	r.POST("/message", m.searchMessage) // 搜索单条消息
 
+	r.POST("/message/reaction/add", m.addReaction)       // 添加消息回应
+	r.POST("/message/reaction/remove", m.removeReaction) // 取消消息回应
+	r.POST("/message/reaction/sync", m.syncReactions)    // 同步频道的消息回应变更
+
 }
 
 func (m *MessageAPI) send(c *wkhttp.Context) {
@@ -65,6 +69,15 @@ func (m *MessageAPI) send(c *wkhttp.Context) {
 		return
 	}
 
+	if req.Mention != nil {
+		payload, err := setPayloadMention(req.Payload, req.Mention)
+		if err != nil {
+			c.ResponseError(err)
+			return
+		}
+		req.Payload = payload
+	}
+
 	if strings.TrimSpace(req.FromUID) == "" {
 		req.FromUID = m.s.opts.SystemUID
 	}
@@ -174,6 +187,65 @@ func (m *MessageAPI) sendMessageToChannel(req MessageSendReq, channelId string,
 	return messageId, nil
 }
 
+const (
+	// CMDConversationExtraUpdate 会话扩展属性更新
+	CMDConversationExtraUpdate = "conversationExtraUpdate"
+	// CMDMessageReactionUpdate 消息回应更新
+	CMDMessageReactionUpdate = "messageReactionUpdate"
+)
+
+// sendCMDToUser 以系统账号给用户的所有设备发送命令消息（离线设备上线后通过/message/sync同步）
+func (s *Server) sendCMDToUser(uid string, cmd string, param interface{}) error {
+	fakeChannelId := s.opts.OrginalConvertCmdChannel(GetFakeChannelIDWith(s.opts.SystemUID, uid))
+	channel := s.channelReactor.loadOrCreateChannel(fakeChannelId, wkproto.ChannelTypePerson)
+	if channel == nil {
+		return errors.New("频道信息不存在！")
+	}
+	payload := []byte(wkutil.ToJSON(map[string]interface{}{
+		"type":  99, // 命令消息的正文类型
+		"cmd":   cmd,
+		"param": param,
+	}))
+	_, err := channel.proposeSend(context.Background(), s.opts.SystemUID, s.opts.SystemUID, 0, s.opts.Cluster.NodeId, false, &wkproto.SendPacket{
+		Framer: wkproto.Framer{
+			SyncOnce: true,
+		},
+		ClientMsgNo: fmt.Sprintf("%s0", wkutil.GenUUID()),
+		ChannelID:   uid,
+		ChannelType: wkproto.ChannelTypePerson,
+		Payload:     payload,
+	})
+	return err
+}
+
+// sendCMDToChannel 给频道的在线订阅者发送命令消息（不存储）
+func (s *Server) sendCMDToChannel(fromUid string, channelId string, channelType uint8, cmd string, param interface{}) error {
+	fakeChannelId := channelId
+	if channelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(fromUid, channelId)
+	}
+	channel := s.channelReactor.loadOrCreateChannel(s.opts.OrginalConvertCmdChannel(fakeChannelId), channelType)
+	if channel == nil {
+		return errors.New("频道信息不存在！")
+	}
+	payload := []byte(wkutil.ToJSON(map[string]interface{}{
+		"type":  99, // 命令消息的正文类型
+		"cmd":   cmd,
+		"param": param,
+	}))
+	_, err := channel.proposeSend(context.Background(), fromUid, fromUid, 0, s.opts.Cluster.NodeId, false, &wkproto.SendPacket{
+		Framer: wkproto.Framer{
+			SyncOnce:  true,
+			NoPersist: true,
+		},
+		ClientMsgNo: fmt.Sprintf("%s0", wkutil.GenUUID()),
+		ChannelID:   channelId,
+		ChannelType: channelType,
+		Payload:     payload,
+	})
+	return err
+}
+
 func (m *MessageAPI) sendBatch(c *wkhttp.Context) {
 	var req struct {
 		Header      MessageHeader `json:"header"`      // 消息头
@@ -638,3 +710,209 @@ func (m *MessageAPI) searchMessage(c *wkhttp.Context) {
 	resp.from(messages[0], m.s)
 	c.JSON(http.StatusOK, resp)
 }
+
+func (m *MessageAPI) addReaction(c *wkhttp.Context) {
+	m.updateReaction(c, false)
+}
+
+func (m *MessageAPI) removeReaction(c *wkhttp.Context) {
+	m.updateReaction(c, true)
+}
+
+// updateReaction 添加或取消消息回应
+func (m *MessageAPI) updateReaction(c *wkhttp.Context, remove bool) {
+	var req messageReactionReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.UID, req.ChannelID)
+	}
+
+	if m.s.opts.ClusterOn() {
+		leaderInfo, err := m.s.cluster.SlotLeaderOfChannel(fakeChannelId, req.ChannelType) // 获取频道的槽领导节点
+		if err != nil {
+			m.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		leaderIsSelf := leaderInfo.Id == m.s.opts.Cluster.NodeId
+		if !leaderIsSelf {
+			m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	// 只能回应频道里存在的消息
+	msgExist, err := m.s.messageExist(fakeChannelId, req.ChannelType, req.MessageSeq)
+	if err != nil {
+		m.Error("查询消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(errors.New("查询消息失败！"))
+		return
+	}
+	if !msgExist.Exist || (req.MessageID != 0 && req.MessageID != msgExist.MessageId) {
+		c.ResponseError(errors.New("消息不存在！"))
+		return
+	}
+
+	// 个人频道的频道id由回应用户生成，其他频道需要是订阅者才能回应
+	if req.ChannelType != wkproto.ChannelTypePerson && !m.s.systemUIDManager.SystemUID(req.UID) {
+		isSubscriber, err := m.s.store.ExistSubscriber(fakeChannelId, req.ChannelType, req.UID)
+		if err != nil {
+			m.Error("查询订阅者失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("channelId", fakeChannelId))
+			c.ResponseError(err)
+			return
+		}
+		if !isSubscriber {
+			c.ResponseError(errors.New("用户不是频道的订阅者！"))
+			return
+		}
+	}
+
+	now := time.Now()
+	err = m.s.store.AddOrUpdateReactions(fakeChannelId, req.ChannelType, []wkdb.Reaction{
+		{
+			MessageId:  msgExist.MessageId,
+			MessageSeq: req.MessageSeq,
+			Uid:        req.UID,
+			Emoji:      req.Emoji,
+			IsDeleted:  remove,
+			CreatedAt:  &now,
+			UpdatedAt:  &now,
+		},
+	})
+	if err != nil {
+		m.Error("保存消息回应失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("channelId", fakeChannelId), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(err)
+		return
+	}
+
+	summary, err := m.getReactionSummary(fakeChannelId, req.ChannelType, req.MessageSeq)
+	if err != nil {
+		m.Error("获取消息回应失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(err)
+		return
+	}
+	version, err := m.s.store.GetReactionVersion(fakeChannelId, req.ChannelType)
+	if err != nil {
+		m.Error("获取消息回应版本号失败！", zap.Error(err), zap.String("channelId", fakeChannelId))
+		c.ResponseError(err)
+		return
+	}
+	resp := newMessageReactionResp(msgExist.MessageId, summary, nil)
+
+	// 通知频道的在线订阅者
+	err = m.s.sendCMDToChannel(req.UID, req.ChannelID, req.ChannelType, CMDMessageReactionUpdate, map[string]interface{}{
+		"channel_id":   req.ChannelID,
+		"channel_type": req.ChannelType,
+		"version":      version,
+		"message":      resp,
+	})
+	if err != nil {
+		m.Warn("发送消息回应更新命令失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+	}
+
+	c.ResponseOKWithData(resp)
+}
+
+// syncReactions 同步频道版本号之后有回应变更的消息
+func (m *MessageAPI) syncReactions(c *wkhttp.Context) {
+	var req syncReactionReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
+	}
+
+	if m.s.opts.ClusterOn() {
+		leaderInfo, err := m.s.cluster.SlotLeaderOfChannel(fakeChannelId, req.ChannelType) // 获取频道的槽领导节点
+		if err != nil {
+			m.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		leaderIsSelf := leaderInfo.Id == m.s.opts.Cluster.NodeId
+		if !leaderIsSelf {
+			m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	limit := req.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = 1000
+	}
+
+	changes, err := m.s.store.GetReactionsByVersion(fakeChannelId, req.ChannelType, req.Version, limit)
+	if err != nil {
+		m.Error("获取消息回应变更失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("version", req.Version))
+		c.ResponseError(err)
+		return
+	}
+
+	resp := &syncReactionResp{
+		Version:  req.Version,
+		More:     wkutil.BoolToInt(len(changes) >= limit),
+		Messages: make([]*messageReactionResp, 0),
+	}
+	// 同一条消息的多次变更只返回消息的最新回应
+	exists := make(map[uint64]struct{})
+	for _, change := range changes {
+		if change.Version > resp.Version {
+			resp.Version = change.Version
+		}
+		if _, ok := exists[change.MessageSeq]; ok {
+			continue
+		}
+		exists[change.MessageSeq] = struct{}{}
+
+		summary, err := m.getReactionSummary(fakeChannelId, req.ChannelType, change.MessageSeq)
+		if err != nil {
+			m.Error("获取消息回应失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("messageSeq", change.MessageSeq))
+			c.ResponseError(err)
+			return
+		}
+		userReactions, err := m.s.store.GetUserReactions(fakeChannelId, req.ChannelType, change.MessageSeq, req.LoginUID)
+		if err != nil {
+			m.Error("获取用户的消息回应失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("messageSeq", change.MessageSeq))
+			c.ResponseError(err)
+			return
+		}
+		resp.Messages = append(resp.Messages, newMessageReactionResp(change.MessageId, summary, userReactions))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// getReactionSummary 获取消息的回应汇总，消息没有回应时返回空的汇总
+func (m *MessageAPI) getReactionSummary(channelId string, channelType uint8, messageSeq uint64) (wkdb.ReactionSummary, error) {
+	summary, err := m.s.store.GetReactionSummary(channelId, channelType, messageSeq)
+	if err != nil {
+		if err != wkdb.ErrNotFound {
+			return wkdb.EmptyReactionSummary, err
+		}
+		summary = wkdb.ReactionSummary{ChannelId: channelId, ChannelType: channelType}
+	}
+	summary.MessageSeq = messageSeq
+	return summary, nil
+}
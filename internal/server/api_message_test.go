@@ -0,0 +1,52 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMessageReactionResp(t *testing.T) {
+	t1 := time.Now()
+	t2 := t1.Add(time.Second)
+	summary := wkdb.ReactionSummary{
+		MessageId:  1001,
+		MessageSeq: 1,
+		Emojis: []wkdb.ReactionEmojiSummary{
+			{Emoji: "😄", Count: 1, Uids: []string{"u3"}, CreatedAt: t2.UnixNano()},
+			{Emoji: "👍", Count: 2, Uids: []string{"u2", "u1"}, CreatedAt: t1.UnixNano()},
+		},
+	}
+	userReactions := []wkdb.Reaction{
+		{MessageId: 1001, MessageSeq: 1, Uid: "u1", Emoji: "👍", CreatedAt: &t1},
+	}
+
+	resp := newMessageReactionResp(0, summary, userReactions)
+	assert.Equal(t, uint64(1001), resp.MessageID)
+	assert.Equal(t, uint64(1), resp.MessageSeq)
+	assert.Equal(t, 2, len(resp.Reactions))
+
+	// 表情按第一次回应的时间排序
+	assert.Equal(t, "👍", resp.Reactions[0].Emoji)
+	assert.Equal(t, 2, resp.Reactions[0].Count)
+	assert.Equal(t, []string{"u2", "u1"}, resp.Reactions[0].Uids)
+	assert.Equal(t, 1, resp.Reactions[0].Reacted)
+
+	assert.Equal(t, "😄", resp.Reactions[1].Emoji)
+	assert.Equal(t, 1, resp.Reactions[1].Count)
+	assert.Equal(t, 0, resp.Reactions[1].Reacted)
+}
+
+func TestMessageReactionReqCheck(t *testing.T) {
+	req := messageReactionReq{UID: "u1", ChannelID: "g1", ChannelType: 2, MessageSeq: 1, Emoji: "👍"}
+	assert.NoError(t, req.Check())
+
+	req.MessageSeq = 0
+	assert.Error(t, req.Check())
+
+	req.MessageSeq = 1
+	req.Emoji = ""
+	assert.Error(t, req.Check())
+}
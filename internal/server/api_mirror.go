@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/WuKongIM/WuKongIM/pkg/network"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// MirrorAPI 跨集群镜像
+type MirrorAPI struct {
+	s *Server
+	wklog.Log
+}
+
+// NewMirrorAPI NewMirrorAPI
+func NewMirrorAPI(s *Server) *MirrorAPI {
+	return &MirrorAPI{
+		s:   s,
+		Log: wklog.NewWKLog("MirrorAPI"),
+	}
+}
+
+// Route Route
+func (m *MirrorAPI) Route(r *wkhttp.WKHttp) {
+	r.GET("/mirror/status", m.status)    // 获取所有节点的镜像状态（local=1 只获取当前节点）
+	r.POST("/mirror/promote", m.promote) // 将备集群提升为主集群（local=1 只提升当前节点）
+}
+
+func (m *MirrorAPI) status(c *wkhttp.Context) {
+	if c.Query("local") == "1" {
+		c.JSON(http.StatusOK, m.s.mirrorManager.getStatus())
+		return
+	}
+	statuses := make([]mirrorStatus, 0)
+	var mu sync.Mutex
+	err := m.forEachNode(func(apiServerAddr string, local bool) error {
+		var status mirrorStatus
+		if local {
+			status = m.s.mirrorManager.getStatus()
+		} else {
+			resp, err := network.Get(fmt.Sprintf("%s/mirror/status", apiServerAddr), map[string]string{"local": "1"}, nil)
+			if err != nil {
+				return err
+			}
+			if err = handlerIMError(resp); err != nil {
+				return err
+			}
+			if err = wkutil.ReadJSONByByte([]byte(resp.Body), &status); err != nil {
+				return err
+			}
+		}
+		mu.Lock()
+		statuses = append(statuses, status)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		m.Error("获取镜像状态失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, statuses)
+}
+
+func (m *MirrorAPI) promote(c *wkhttp.Context) {
+	if c.Query("local") == "1" {
+		if err := m.s.mirrorManager.promote(); err != nil {
+			m.Error("提升备集群失败！", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+		c.ResponseOK()
+		return
+	}
+	err := m.forEachNode(func(apiServerAddr string, local bool) error {
+		if local {
+			return m.s.mirrorManager.promote()
+		}
+		resp, err := network.Post(fmt.Sprintf("%s/mirror/promote?local=1", apiServerAddr), nil, nil)
+		if err != nil {
+			return err
+		}
+		return handlerIMError(resp)
+	})
+	if err != nil {
+		m.Error("提升备集群失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// forEachNode 并发请求所有节点
+func (m *MirrorAPI) forEachNode(f func(apiServerAddr string, local bool) error) error {
+	if !m.s.opts.ClusterOn() {
+		return f("", true)
+	}
+	requestGroup, _ := errgroup.WithContext(context.Background())
+	for _, node := range m.s.clusterServer.GetConfig().Nodes {
+		if node.Id == m.s.opts.Cluster.NodeId {
+			requestGroup.Go(func() error {
+				return f("", true)
+			})
+			continue
+		}
+		if node.ApiServerAddr == "" {
+			continue
+		}
+		apiServerAddr := node.ApiServerAddr
+		requestGroup.Go(func() error {
+			return f(apiServerAddr, false)
+		})
+	}
+	return requestGroup.Wait()
+}
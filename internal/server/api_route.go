@@ -2,6 +2,8 @@ package server
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
@@ -10,7 +12,7 @@ import (
 	"go.uber.org/zap"
 )
 
-// 这个主要为了模拟proxy模式。
+// RouteAPI 用户连接地址路由
 type RouteAPI struct {
 	s *Server
 	wklog.Log
@@ -32,13 +34,28 @@ func (a *RouteAPI) Route(r *wkhttp.WKHttp) {
 
 // 路由用户的IM连接地址
 func (a *RouteAPI) routeUserIMAddr(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	nodes := a.route(uid)
+	primary := nodes[0]
 	c.JSON(http.StatusOK, gin.H{
-		"tcp_addr": a.s.opts.External.TCPAddr,
-		"ws_addr":  a.s.opts.External.WSAddr,
-		"wss_addr": a.s.opts.External.WSSAddr,
+		"node_id":   primary.NodeId,
+		"tcp_addr":  primary.TCPAddr,
+		"ws_addr":   primary.WSAddr,
+		"wss_addr":  primary.WSSAddr,
+		"fallbacks": newRouteAddrResps(nodes[1:]),
 	})
 }
 
+// route 路由用户，至少返回一个节点
+func (a *RouteAPI) route(uid string) []*routeNode {
+	nodes := a.s.routeManager.route(uid)
+	if len(nodes) == 0 {
+		a.Warn("没有可路由的节点，返回当前节点", zap.String("uid", uid))
+		nodes = []*routeNode{a.s.routeManager.localNode()}
+	}
+	return nodes
+}
+
 // 批量获取用户所在节点地址
 func (a *RouteAPI) routeUserIMAddrOfBatch(c *wkhttp.Context) {
 	var uids []string
@@ -48,19 +65,67 @@ func (a *RouteAPI) routeUserIMAddrOfBatch(c *wkhttp.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, []userAddrResp{
-		{
-			UIDs:    uids,
-			TCPAddr: a.s.opts.External.TCPAddr,
-			WSAddr:  a.s.opts.External.WSAddr,
-			WSSAddr: a.s.opts.External.WSSAddr,
-		},
-	})
+	// 按完整的路由节点列表（首选节点和备选节点）分组，同一组的用户备选地址相同
+	resps := make([]*userAddrResp, 0)
+	respMap := make(map[string]*userAddrResp)
+	for _, uid := range uids {
+		nodes := a.route(uid)
+		key := routeNodesKey(nodes)
+		resp := respMap[key]
+		if resp == nil {
+			primary := nodes[0]
+			resp = &userAddrResp{
+				NodeId:    primary.NodeId,
+				TCPAddr:   primary.TCPAddr,
+				WSAddr:    primary.WSAddr,
+				WSSAddr:   primary.WSSAddr,
+				Fallbacks: newRouteAddrResps(nodes[1:]),
+			}
+			respMap[key] = resp
+			resps = append(resps, resp)
+		}
+		resp.UIDs = append(resp.UIDs, uid)
+	}
+	c.JSON(http.StatusOK, resps)
 }
 
 type userAddrResp struct {
-	TCPAddr string   `json:"tcp_addr"`
-	WSAddr  string   `json:"ws_addr"`
-	WSSAddr string   `json:"wss_addr"`
-	UIDs    []string `json:"uids"`
+	NodeId    uint64           `json:"node_id"`
+	TCPAddr   string           `json:"tcp_addr"`
+	WSAddr    string           `json:"ws_addr"`
+	WSSAddr   string           `json:"wss_addr"`
+	UIDs      []string         `json:"uids"`
+	Fallbacks []*routeAddrResp `json:"fallbacks"` // 备选地址（按优先级排序）
+}
+
+type routeAddrResp struct {
+	NodeId  uint64 `json:"node_id"`
+	TCPAddr string `json:"tcp_addr"`
+	WSAddr  string `json:"ws_addr"`
+	WSSAddr string `json:"wss_addr"`
+}
+
+func newRouteAddrResps(nodes []*routeNode) []*routeAddrResp {
+	resps := make([]*routeAddrResp, 0, len(nodes))
+	for _, node := range nodes {
+		resps = append(resps, &routeAddrResp{
+			NodeId:  node.NodeId,
+			TCPAddr: node.TCPAddr,
+			WSAddr:  node.WSAddr,
+			WSSAddr: node.WSSAddr,
+		})
+	}
+	return resps
+}
+
+// routeNodesKey 路由节点列表的唯一标识（按顺序拼接节点id）
+func routeNodesKey(nodes []*routeNode) string {
+	var b strings.Builder
+	for i, node := range nodes {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(strconv.FormatUint(node.NodeId, 10))
+	}
+	return b.String()
 }
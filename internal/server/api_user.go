@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -39,6 +40,7 @@ func (u *UserAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/user/token", u.updateToken)                  // 更新用户token
 	r.POST("/user/device_quit", u.deviceQuit)             // 强制设备退出
 	r.POST("/user/onlinestatus", u.getOnlineStatus)       // 获取用户在线状态
+	r.POST("/user/badge", u.getBadge)                     // 获取用户所有会话的未读总数（角标）
 	r.POST("/user/systemuids_add", u.systemUidsAdd)       // 添加系统uid
 	r.POST("/user/systemuids_remove", u.systemUidsRemove) // 移除系统uid
 	r.GET("/user/systemuids", u.getSystemUids)            // 获取系统uid
@@ -46,6 +48,11 @@ func (u *UserAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/user/systemuids_add_to_cache", u.systemUidsAddToCache)           // 仅仅添加系统账号至缓存
 	r.POST("/user/systemuids_remove_from_cache", u.systemUidsRemoveFromCache) // 仅仅从缓存中移除系统账号
 
+	r.POST("/user/data/export", u.dataExport)           // 提交用户数据导出任务
+	r.POST("/user/data/erase", u.dataErase)             // 提交用户数据擦除任务
+	r.GET("/user/data/job", u.dataJob)                  // 获取用户数据任务进度
+	r.GET("/user/data/job/download", u.dataJobDownload) // 下载用户数据导出文件
+
 }
 
 // 强制设备退出
@@ -82,6 +89,14 @@ func (u *UserAPI) deviceQuit(c *wkhttp.Context) {
 	} else {
 		_ = u.quitUserDevice(req.UID, wkproto.DeviceFlag(req.DeviceFlag))
 	}
+	u.s.webhook.TriggerEvent(&Event{
+		Event: EventUserDeviceQuit,
+		Key:   req.UID,
+		Data: UserDeviceEventData{
+			UID:        req.UID,
+			DeviceFlag: req.DeviceFlag,
+		},
+	})
 
 	c.ResponseOK()
 
@@ -158,6 +173,50 @@ func (u *UserAPI) getOnlineStatus(c *wkhttp.Context) {
 	c.JSON(http.StatusOK, conns)
 }
 
+// 获取用户角标
+func (u *UserAPI) getBadge(c *wkhttp.Context) {
+	var req struct {
+		UID         string `json:"uid"`
+		ExcludeMute *int   `json:"exclude_mute"` // 1：不统计免打扰的会话（默认） 0：统计免打扰的会话
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+
+	if u.s.opts.ClusterOn() {
+		leaderInfo, err := u.s.cluster.SlotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
+		if err != nil {
+			u.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		if leaderInfo.Id != u.s.opts.Cluster.NodeId {
+			u.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	excludeMute := req.ExcludeMute == nil || *req.ExcludeMute == 1
+	badge, err := u.s.conversationManager.GetUserBadge(req.UID, excludeMute)
+	if err != nil {
+		u.Error("获取用户角标失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, userBadgeResp{
+		UID:   req.UID,
+		Badge: badge,
+	})
+}
+
 func (u *UserAPI) getOnlineConnsForCluster(uids []string) ([]*OnlinestatusResp, error) {
 	uidInPeerMap := make(map[uint64][]string)
 	localUids := make([]string, 0)
@@ -380,6 +439,16 @@ func (u *UserAPI) updateToken(c *wkhttp.Context) {
 		}
 	}
 
+	u.s.webhook.TriggerEvent(&Event{
+		Event: EventUserTokenUpdate,
+		Key:   req.UID,
+		Data: UserDeviceEventData{
+			UID:         req.UID,
+			DeviceFlag:  int(req.DeviceFlag),
+			DeviceLevel: uint8(req.DeviceLevel),
+		},
+	})
+
 	// // 创建或更新个人频道
 	// err = u.s.channelManager.CreateOrUpdatePersonChannel(req.UID)
 	// if err != nil {
@@ -645,3 +714,81 @@ type OnlinestatusResp struct {
 	DeviceFlag uint8  `json:"device_flag"` // 设备标记 0. APP 1.web
 	Online     int    `json:"online"`      // 是否在线
 }
+
+// 提交用户数据导出任务
+func (u *UserAPI) dataExport(c *wkhttp.Context) {
+	u.submitDataJob(c, userDataJobTypeExport)
+}
+
+// 提交用户数据擦除任务
+func (u *UserAPI) dataErase(c *wkhttp.Context) {
+	u.submitDataJob(c, userDataJobTypeErase)
+}
+
+func (u *UserAPI) submitDataJob(c *wkhttp.Context, tp userDataJobType) {
+	var req struct {
+		UID string `json:"uid"`
+	}
+	if _, err := BindJSON(&req, c); err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	job := u.s.userDataManager.submit(tp, req.UID)
+	c.JSON(http.StatusOK, job)
+}
+
+// 获取用户数据任务进度
+func (u *UserAPI) dataJob(c *wkhttp.Context) {
+	jobId := c.Query("job_id")
+	if u.forwardDataJob(c, jobId) {
+		return
+	}
+	job := u.s.userDataManager.getJob(jobId)
+	if job == nil {
+		c.ResponseError(errors.New("任务不存在！"))
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// 下载用户数据导出文件
+func (u *UserAPI) dataJobDownload(c *wkhttp.Context) {
+	jobId := c.Query("job_id")
+	if u.forwardDataJob(c, jobId) {
+		return
+	}
+	job := u.s.userDataManager.getJob(jobId)
+	if job == nil || job.Type != userDataJobTypeExport {
+		c.ResponseError(errors.New("任务不存在！"))
+		return
+	}
+	if job.Status != userDataJobStatusDone {
+		c.ResponseError(errors.New("任务未完成！"))
+		return
+	}
+	c.FileAttachment(u.s.userDataManager.exportFile(jobId), fmt.Sprintf("%s-%s.jsonl", job.Uid, jobId))
+}
+
+// forwardDataJob 任务不在本节点则转发到任务所在节点
+func (u *UserAPI) forwardDataJob(c *wkhttp.Context, jobId string) bool {
+	if !u.s.opts.ClusterOn() {
+		return false
+	}
+	nodeId, ok := u.s.userDataManager.jobNodeId(jobId)
+	if !ok || nodeId == u.s.opts.Cluster.NodeId {
+		return false
+	}
+	nodeInfo, err := u.s.cluster.NodeInfoById(nodeId)
+	if err != nil {
+		u.Error("获取任务所在节点失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+		c.ResponseError(errors.New("获取任务所在节点失败！"))
+		return true
+	}
+	c.Forward(fmt.Sprintf("%s%s?%s", nodeInfo.ApiServerAddr, c.Request.URL.Path, c.Request.URL.RawQuery))
+	return true
+}
@@ -0,0 +1,411 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/network"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// WebhookAPI webhook死信管理
+// 死信只保存在产生它的节点上，不指定node_id时会请求所有节点并合并结果（local=1 只处理当前节点）
+type WebhookAPI struct {
+	s *Server
+	wklog.Log
+}
+
+// NewWebhookAPI NewWebhookAPI
+func NewWebhookAPI(s *Server) *WebhookAPI {
+	return &WebhookAPI{
+		s:   s,
+		Log: wklog.NewWKLog("WebhookAPI"),
+	}
+}
+
+// Route Route
+func (w *WebhookAPI) Route(r *wkhttp.WKHttp) {
+	r.GET("/webhook/deadletters", w.deadLetters)              // 死信列表
+	r.GET("/webhook/deadletters/:id", w.deadLetter)           // 死信详情
+	r.POST("/webhook/deadletters/replay", w.deadLetterReplay) // 重放死信
+	r.POST("/webhook/deadletters/purge", w.deadLetterPurge)   // 清除死信
+}
+
+func (w *WebhookAPI) deadLetters(c *wkhttp.Context) {
+	if w.forward(c) {
+		return
+	}
+	startId, _ := strconv.ParseUint(c.Query("start_id"), 10, 64)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 {
+		limit = 100
+	}
+	resps := make([]*webhookDeadLetterResp, 0)
+	var mu sync.Mutex
+	err := w.forEachNode(c, func(apiServerAddr string, local bool) error {
+		var nodeResps []*webhookDeadLetterResp
+		if local {
+			deadLetters, err := w.s.store.GetWebhookDeadLetters(startId, limit)
+			if err != nil {
+				return err
+			}
+			for _, deadLetter := range deadLetters {
+				nodeResps = append(nodeResps, w.newResp(deadLetter, false))
+			}
+		} else {
+			resp, err := network.Get(fmt.Sprintf("%s/webhook/deadletters", apiServerAddr), map[string]string{
+				"local":    "1",
+				"start_id": strconv.FormatUint(startId, 10),
+				"limit":    strconv.Itoa(limit),
+			}, nil)
+			if err != nil {
+				return err
+			}
+			if err = handlerIMError(resp); err != nil {
+				return err
+			}
+			if err = wkutil.ReadJSONByByte([]byte(resp.Body), &nodeResps); err != nil {
+				return err
+			}
+		}
+		mu.Lock()
+		resps = append(resps, nodeResps...)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		w.Error("获取死信列表失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	// 死信id全局唯一且递增，按id合并各节点的结果
+	sort.Slice(resps, func(i, j int) bool {
+		return resps[i].Id < resps[j].Id
+	})
+	if len(resps) > limit {
+		resps = resps[:limit]
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+func (w *WebhookAPI) deadLetter(c *wkhttp.Context) {
+	if w.forward(c) {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.ResponseError(errors.New("id格式有误！"))
+		return
+	}
+	var (
+		result *webhookDeadLetterResp
+		mu     sync.Mutex
+	)
+	err = w.forEachNode(c, func(apiServerAddr string, local bool) error {
+		var nodeResp *webhookDeadLetterResp
+		if local {
+			deadLetter, err := w.s.store.GetWebhookDeadLetter(id)
+			if err != nil {
+				if err == wkdb.ErrNotFound {
+					return nil
+				}
+				return err
+			}
+			nodeResp = w.newResp(deadLetter, true)
+		} else {
+			resp, err := network.Get(fmt.Sprintf("%s/webhook/deadletters/%d", apiServerAddr, id), map[string]string{"local": "1"}, nil)
+			if err != nil {
+				return err
+			}
+			if resp.StatusCode == http.StatusNotFound {
+				return nil
+			}
+			if err = handlerIMError(resp); err != nil {
+				return err
+			}
+			nodeResp = &webhookDeadLetterResp{}
+			if err = wkutil.ReadJSONByByte([]byte(resp.Body), nodeResp); err != nil {
+				return err
+			}
+		}
+		mu.Lock()
+		result = nodeResp
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		w.Error("获取死信失败！", zap.Error(err), zap.Uint64("id", id))
+		c.ResponseError(err)
+		return
+	}
+	if result == nil {
+		if c.Query("local") == "1" {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.ResponseError(errors.New("死信不存在！"))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// 重放死信，重放成功的死信会被删除，all=true表示重放所有死信（遇到失败则停止）
+func (w *WebhookAPI) deadLetterReplay(c *wkhttp.Context) {
+	if w.forward(c) {
+		return
+	}
+	var req webhookDeadLetterReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		w.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if !w.s.opts.WebhookOn() {
+		c.ResponseError(errors.New("没有配置webhook！"))
+		return
+	}
+
+	result := webhookDeadLetterReplayResp{}
+	var mu sync.Mutex
+	err = w.forEachNode(c, func(apiServerAddr string, local bool) error {
+		var nodeResult webhookDeadLetterReplayResp
+		if local {
+			var err error
+			if nodeResult, err = w.replayLocal(req); err != nil {
+				return err
+			}
+		} else {
+			resp, err := network.Post(fmt.Sprintf("%s/webhook/deadletters/replay?local=1", apiServerAddr), bodyBytes, nil)
+			if err != nil {
+				return err
+			}
+			if err = handlerIMError(resp); err != nil {
+				return err
+			}
+			if err = wkutil.ReadJSONByByte([]byte(resp.Body), &nodeResult); err != nil {
+				return err
+			}
+		}
+		mu.Lock()
+		result.Replayed += nodeResult.Replayed
+		result.Failed = append(result.Failed, nodeResult.Failed...)
+		if nodeResult.Error != "" {
+			result.Error = nodeResult.Error
+		}
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		w.Error("重放死信失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// replayLocal 重放当前节点的死信（不在当前节点的id会被忽略）
+func (w *WebhookAPI) replayLocal(req webhookDeadLetterReq) (webhookDeadLetterReplayResp, error) {
+	resp := webhookDeadLetterReplayResp{}
+	if req.All {
+		var startId uint64
+		for {
+			deadLetters, err := w.s.store.GetWebhookDeadLetters(startId, 100)
+			if err != nil {
+				return resp, err
+			}
+			if len(deadLetters) == 0 {
+				break
+			}
+			for _, deadLetter := range deadLetters {
+				if err := w.s.webhook.replayDeadLetter(deadLetter); err != nil {
+					w.Warn("重放死信失败！", zap.Error(err), zap.Uint64("id", deadLetter.Id))
+					resp.Failed = append(resp.Failed, deadLetter.Id)
+					resp.Error = err.Error()
+					return resp, nil
+				}
+				resp.Replayed++
+			}
+			startId = deadLetters[len(deadLetters)-1].Id
+		}
+		return resp, nil
+	}
+
+	for _, id := range req.Ids {
+		deadLetter, err := w.s.store.GetWebhookDeadLetter(id)
+		if err != nil {
+			if err == wkdb.ErrNotFound {
+				continue
+			}
+			return resp, err
+		}
+		if err := w.s.webhook.replayDeadLetter(deadLetter); err != nil {
+			w.Warn("重放死信失败！", zap.Error(err), zap.Uint64("id", id))
+			resp.Failed = append(resp.Failed, id)
+			resp.Error = err.Error()
+			continue
+		}
+		resp.Replayed++
+	}
+	return resp, nil
+}
+
+// 清除死信，all=true表示清除所有死信
+func (w *WebhookAPI) deadLetterPurge(c *wkhttp.Context) {
+	if w.forward(c) {
+		return
+	}
+	var req webhookDeadLetterReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		w.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	err = w.forEachNode(c, func(apiServerAddr string, local bool) error {
+		if local {
+			if req.All {
+				return w.s.store.RemoveAllWebhookDeadLetters()
+			}
+			return w.s.store.RemoveWebhookDeadLetters(req.Ids)
+		}
+		resp, err := network.Post(fmt.Sprintf("%s/webhook/deadletters/purge?local=1", apiServerAddr), bodyBytes, nil)
+		if err != nil {
+			return err
+		}
+		return handlerIMError(resp)
+	})
+	if err != nil {
+		w.Error("清除死信失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// forEachNode 并发请求所有节点，local=1时只处理当前节点
+func (w *WebhookAPI) forEachNode(c *wkhttp.Context, f func(apiServerAddr string, local bool) error) error {
+	if !w.s.opts.ClusterOn() || c.Query("local") == "1" {
+		return f("", true)
+	}
+	requestGroup, _ := errgroup.WithContext(context.Background())
+	for _, node := range w.s.clusterServer.GetConfig().Nodes {
+		if node.Id == w.s.opts.Cluster.NodeId {
+			requestGroup.Go(func() error {
+				return f("", true)
+			})
+			continue
+		}
+		if node.ApiServerAddr == "" {
+			continue
+		}
+		apiServerAddr := node.ApiServerAddr
+		requestGroup.Go(func() error {
+			return f(apiServerAddr, false)
+		})
+	}
+	return requestGroup.Wait()
+}
+
+// forward 请求的不是本节点的死信则转发到指定节点
+func (w *WebhookAPI) forward(c *wkhttp.Context) bool {
+	if !w.s.opts.ClusterOn() {
+		return false
+	}
+	nodeIdStr := c.Query("node_id")
+	if nodeIdStr == "" {
+		return false
+	}
+	nodeId, err := strconv.ParseUint(nodeIdStr, 10, 64)
+	if err != nil {
+		c.ResponseError(errors.New("node_id格式有误！"))
+		return true
+	}
+	if nodeId == w.s.opts.Cluster.NodeId {
+		return false
+	}
+	nodeInfo, err := w.s.cluster.NodeInfoById(nodeId)
+	if err != nil {
+		w.Error("获取节点信息失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+		c.ResponseError(errors.New("获取节点信息失败！"))
+		return true
+	}
+	// 转发到指定节点后只处理该节点的死信
+	query := c.Request.URL.Query()
+	query.Del("node_id")
+	query.Set("local", "1")
+	c.Forward(fmt.Sprintf("%s%s?%s", nodeInfo.ApiServerAddr, c.Request.URL.Path, query.Encode()))
+	return true
+}
+
+type webhookDeadLetterReq struct {
+	Ids []uint64 `json:"ids"`
+	All bool     `json:"all"`
+}
+
+func (r webhookDeadLetterReq) check() error {
+	if len(r.Ids) == 0 && !r.All {
+		return errors.New("ids不能为空！")
+	}
+	return nil
+}
+
+type webhookDeadLetterResp struct {
+	NodeId     uint64          `json:"node_id"` // 死信所在节点
+	Id         uint64          `json:"id"`
+	IdStr      string          `json:"id_str"`
+	Event      string          `json:"event"`
+	MessageIds []int64         `json:"message_ids,omitempty"`
+	RetryCount int             `json:"retry_count"`
+	Error      string          `json:"error"`
+	CreatedAt  string          `json:"created_at"`
+	Data       json.RawMessage `json:"data,omitempty"`
+}
+
+func (w *WebhookAPI) newResp(d wkdb.WebhookDeadLetter, withData bool) *webhookDeadLetterResp {
+	resp := newWebhookDeadLetterResp(d, withData)
+	resp.NodeId = w.s.opts.Cluster.NodeId
+	return resp
+}
+
+func newWebhookDeadLetterResp(d wkdb.WebhookDeadLetter, withData bool) *webhookDeadLetterResp {
+	resp := &webhookDeadLetterResp{
+		Id:         d.Id,
+		IdStr:      strconv.FormatUint(d.Id, 10),
+		Event:      d.Event,
+		MessageIds: d.MessageIds,
+		RetryCount: d.RetryCount,
+		Error:      d.Error,
+		CreatedAt:  d.CreatedAt.Format(time.DateTime),
+	}
+	if withData {
+		resp.Data = d.Data
+	}
+	return resp
+}
+
+type webhookDeadLetterReplayResp struct {
+	Replayed int      `json:"replayed"`         // 重放成功的数量
+	Failed   []uint64 `json:"failed,omitempty"` // 重放失败的死信id
+	Error    string   `json:"error,omitempty"`  // 最后一次失败的原因
+}
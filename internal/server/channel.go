@@ -438,10 +438,17 @@ type ready struct {
 func (c *channel) makeReceiverTag() (*tag, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.makeReceiverTagNotLock()
+}
+
+func (c *channel) makeReceiverTagNotLock() (*tag, error) {
 
 	c.Debug("makeReceiverTag", zap.String("channelId", c.channelId), zap.Uint8("channelType", c.channelType))
 
-	var subscribers []string
+	var (
+		subscribers []string
+		large       bool // 是否是超大群
+	)
 
 	// 根据频道类型获取订阅者列表
 	if c.channelType == wkproto.ChannelTypePerson {
@@ -464,42 +471,68 @@ func (c *channel) makeReceiverTag() (*tag, error) {
 	} else {
 		// 处理非个人频道
 		realChannelId := c.channelId
-		if c.r.s.opts.IsCmdChannel(c.channelId) {
+		isCmdChannel := c.r.s.opts.IsCmdChannel(c.channelId)
+		if isCmdChannel {
 			realChannelId = c.r.opts.CmdChannelConvertOrginalChannel(c.channelId)
 		}
+		channelInfo, err := c.r.s.store.GetChannel(realChannelId, c.channelType)
+		if err != nil {
+			return nil, err
+		}
+		if !isCmdChannel {
+			c.info = channelInfo
+		}
+		large = channelInfo.Large
+
 		members, err := c.r.s.store.GetSubscribers(realChannelId, c.channelType)
 		if err != nil {
 			return nil, err
 		}
+		subscribers = make([]string, 0, len(members))
 		for _, member := range members {
 			subscribers = append(subscribers, member.Uid)
 		}
 	}
 
 	// 将订阅者按所在节点分组
-	var nodeUserList = make([]*nodeUsers, 0, 20)
-	for _, subscriber := range subscribers {
-		leaderInfo, err := c.r.s.cluster.SlotLeaderOfChannel(subscriber, wkproto.ChannelTypePerson)
-		if err != nil {
-			c.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", subscriber), zap.Uint8("channelType", wkproto.ChannelTypePerson))
-			return nil, err
-		}
-		exist := false
-		for _, nodeUser := range nodeUserList {
-			if nodeUser.nodeId == leaderInfo.Id {
-				nodeUser.uids = append(nodeUser.uids, subscriber)
-				exist = true
-				break
-			}
-		}
-		if !exist {
-			nodeUserList = append(nodeUserList, &nodeUsers{
-				nodeId: leaderInfo.Id,
-				uids:   []string{subscriber},
-			})
+	nodeUserList, err := groupUsersByNode(subscribers, c.slotLeaderIdOfUser)
+	if err != nil {
+		return nil, err
+	}
+	return c.replaceReceiverTagNotLock(nodeUserList, large), nil
+}
+
+// updateReceiverTag 增量更新接收者标签（只计算新增订阅者所在的节点，超大群添加或移除订阅者时不需要重新加载全部订阅者）
+func (c *channel) updateReceiverTag(addUids []string, removeUids []string) (*tag, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var oldTag *tag
+	if c.receiverTagKey.Load() != "" {
+		oldTag = c.r.s.tagManager.getReceiverTag(c.receiverTagKey.Load())
+	}
+	if oldTag == nil { // 标签不存在则全量创建
+		return c.makeReceiverTagNotLock()
+	}
+
+	addUsers, err := groupUsersByNode(addUids, c.slotLeaderIdOfUser)
+	if err != nil {
+		return nil, err
+	}
+	var removeUidMap map[string]struct{}
+	if len(removeUids) > 0 {
+		removeUidMap = make(map[string]struct{}, len(removeUids))
+		for _, uid := range removeUids {
+			removeUidMap[uid] = struct{}{}
 		}
 	}
+	// 生成新的标签（旧标签可能正在被投递使用，不能直接修改）
+	return c.replaceReceiverTagNotLock(mergeNodeUsers(oldTag.users, addUsers, removeUidMap), oldTag.large), nil
+}
 
+// replaceReceiverTagNotLock 用新的接收者替换频道当前的接收者标签
+// 每次都生成新的tag key，这样其他节点缓存的旧标签会失效并重新获取
+func (c *channel) replaceReceiverTagNotLock(users []*nodeUsers, large bool) *tag {
 	// 释放旧的接收者标签（如果存在）
 	if c.receiverTagKey.Load() != "" {
 		c.r.s.tagManager.releaseReceiverTag(c.receiverTagKey.Load())
@@ -507,9 +540,112 @@ func (c *channel) makeReceiverTag() (*tag, error) {
 
 	// 创建新的接收者标签
 	receiverTagKey := wkutil.GenUUID()
-	newTag := c.r.s.tagManager.addOrUpdateReceiverTag(receiverTagKey, nodeUserList)
+	newTag := c.r.s.tagManager.addOrUpdateReceiverTag(receiverTagKey, users, large)
 	newTag.ref.Inc() // 增加标签引用计数
 	c.receiverTagKey.Store(receiverTagKey)
+	return newTag
+}
+
+func (c *channel) slotLeaderIdOfUser(uid string) (uint64, error) {
+	leaderId, err := c.r.s.cluster.SlotLeaderIdOfChannel(uid, wkproto.ChannelTypePerson)
+	if err != nil {
+		c.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", uid), zap.Uint8("channelType", wkproto.ChannelTypePerson))
+		return 0, err
+	}
+	return leaderId, nil
+}
+
+// groupUsersByNode 将用户按所在的节点分组
+func groupUsersByNode(uids []string, leaderIdOf func(uid string) (uint64, error)) ([]*nodeUsers, error) {
+	nodeUserList := make([]*nodeUsers, 0, 20)
+	nodeUserMap := make(map[uint64]*nodeUsers)
+	for _, uid := range uids {
+		leaderId, err := leaderIdOf(uid)
+		if err != nil {
+			return nil, err
+		}
+		nodeUser := nodeUserMap[leaderId]
+		if nodeUser == nil {
+			nodeUser = &nodeUsers{
+				nodeId: leaderId,
+			}
+			nodeUserMap[leaderId] = nodeUser
+			nodeUserList = append(nodeUserList, nodeUser)
+		}
+		nodeUser.uids = append(nodeUser.uids, uid)
+	}
+	return nodeUserList, nil
+}
 
-	return newTag, nil
+// mergeNodeUsers 在users的基础上添加addUsers并去掉removeUids里的用户，返回新的列表（不修改users）
+func mergeNodeUsers(users []*nodeUsers, addUsers []*nodeUsers, removeUids map[string]struct{}) []*nodeUsers {
+	newUsers := make([]*nodeUsers, 0, len(users)+len(addUsers))
+	for _, nodeUser := range users {
+		var add *nodeUsers
+		for _, addUser := range addUsers {
+			if addUser.nodeId == nodeUser.nodeId {
+				add = addUser
+				break
+			}
+		}
+		// 新增的用户（去掉已经存在的）
+		var addUids map[string]struct{}
+		if add != nil {
+			addUids = make(map[string]struct{}, len(add.uids))
+			for _, uid := range add.uids {
+				addUids[uid] = struct{}{}
+			}
+		}
+		uids := make([]string, 0, len(nodeUser.uids)+len(addUids))
+		for _, uid := range nodeUser.uids {
+			if len(addUids) > 0 {
+				delete(addUids, uid)
+			}
+			if len(removeUids) > 0 {
+				if _, ok := removeUids[uid]; ok {
+					continue
+				}
+			}
+			uids = append(uids, uid)
+		}
+		if add != nil {
+			for _, uid := range add.uids {
+				if _, ok := addUids[uid]; !ok {
+					continue
+				}
+				delete(addUids, uid)
+				if _, ok := removeUids[uid]; ok {
+					continue
+				}
+				uids = append(uids, uid)
+			}
+		}
+		if len(uids) > 0 {
+			newUsers = append(newUsers, &nodeUsers{nodeId: nodeUser.nodeId, uids: uids})
+		}
+	}
+	// 新节点上的用户
+	for _, addUser := range addUsers {
+		exist := false
+		for _, nodeUser := range users {
+			if nodeUser.nodeId == addUser.nodeId {
+				exist = true
+				break
+			}
+		}
+		if exist {
+			continue
+		}
+		uids := make([]string, 0, len(addUser.uids))
+		for _, uid := range addUser.uids {
+			if _, ok := removeUids[uid]; ok {
+				continue
+			}
+			uids = append(uids, uid)
+		}
+		if len(uids) > 0 {
+			newUsers = append(newUsers, &nodeUsers{nodeId: addUser.nodeId, uids: uids})
+		}
+	}
+	return newUsers
 }
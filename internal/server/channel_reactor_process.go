@@ -714,7 +714,9 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 			}
 		}
 
-		if r.opts.WebhookOn() {
+		webhookOn := r.opts.WebhookOn() && r.opts.Webhook.Filter.Allow(EventMsgNotify, req.ch.channelType)
+		eventSinkOn := r.opts.EventSinkEventOn(EventMsgNotify, req.ch.channelType)
+		if webhookOn || eventSinkOn {
 			// 赋值messageeq
 			for i, msg := range messages {
 				for _, cmsg := range req.messages {
@@ -725,7 +727,9 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 					}
 				}
 			}
+		}
 
+		if webhookOn {
 			notifyQueueSpans := make([]trace.Span, 0, len(messages))
 			for _, msg := range messages {
 				for _, reactorMsg := range req.messages {
@@ -750,6 +754,18 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 				span.End()
 			}
 		}
+		if eventSinkOn && reason == ReasonSuccess {
+			// 事件写入成功后才返回发送结果，写入失败时阻塞重试，保证频道内事件的顺序
+			err := r.s.eventSinkManager.appendMessagesWithRetry(messages)
+			if err != nil {
+				r.Error("appendMessagesWithRetry error", zap.Error(err))
+				reason = ReasonError
+			}
+		}
+		if reason == ReasonSuccess && len(sotreMessages) > 0 {
+			// 通知镜像到备集群
+			r.s.mirrorManager.notifyMessages()
+		}
 		// 返回存储结果
 		r.respStoreResult(req, reason)
 
@@ -990,6 +1006,7 @@ type deliverReq struct {
 	channelKey  string
 	tagKey      string
 	messages    []ReactorChannelMessage
+	large       bool // 是否是超大群（由接收者标签决定，不参与编码）
 }
 
 // =================================== 关闭请求 ===================================
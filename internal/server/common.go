@@ -335,4 +335,5 @@ type ConnKey string
 
 const (
 	ConnKeyParseProxyProto = "parseProxyProto" // 解析代理协议
+	ConnKeyCounted         = "counted"         // 连接是否已计入连接数（被拒绝的连接不计入）
 )
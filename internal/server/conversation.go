@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
@@ -24,6 +25,7 @@ type ConversationManager struct {
 	s *Server
 
 	workers []*conversationWorker
+	mutes   *muteCache // 用户对频道的免打扰设置缓存
 
 	deadlock.RWMutex
 }
@@ -34,6 +36,7 @@ func NewConversationManager(s *Server) *ConversationManager {
 		Log:     wklog.NewWKLog("ConversationManager"),
 		stopper: syncutil.NewStopper(),
 		s:       s,
+		mutes:   newMuteCache(s),
 	}
 
 	return cm
@@ -68,6 +71,19 @@ func (c *ConversationManager) Push(fakeChannelId string, channelType uint8, uids
 		worker.getOrCreateUserConversation(message.FromUid).updateOrAddConversation(fakeChannelId, channelType, message.MessageSeq)
 	}
 
+	// 解析消息的@信息
+	mentions := make([]*MessageMention, len(messages))
+	hasMention := false
+	for i, message := range messages {
+		if message.SendPacket.NoPersist || message.SendPacket.SyncOnce {
+			continue
+		}
+		mentions[i] = parseMessageMention(message.SendPacket.Payload)
+		if mentions[i] != nil {
+			hasMention = true
+		}
+	}
+
 	// 处理接受者的最近会话
 	for _, uid := range uids {
 
@@ -90,31 +106,72 @@ func (c *ConversationManager) Push(fakeChannelId string, channelType uint8, uids
 				channelConversation := userConversation.addConversationIfNotExist(existConversation.Id, fakeChannelId, channelType, uint32(existConversation.ReadToMsgSeq))
 				if channelConversation != nil { // 如果db中存在会话，则不需要更新
 					channelConversation.NeedUpdate = false
+					channelConversation.UnreadCount = existConversation.UnreadCount
 				}
 			} else {
-				userConversation.addConversationIfNotExist(0, fakeChannelId, channelType, 0) // 只有缓存中不存在的时候才添加
+				if userConversation.addConversationIfNotExist(0, fakeChannelId, channelType, 0) != nil { // 只有缓存中不存在的时候才添加
+					userConversation.appendWal(fakeChannelId, channelType)
+				}
+			}
+		}
+
+		// 累加未读数（自己发的、不存储的、命令消息和不显示红点的消息不计入）
+		var unread uint32
+		for _, message := range messages {
+			if message.FromUid == uid || !message.SendPacket.RedDot || message.SendPacket.NoPersist || message.SendPacket.SyncOnce {
+				continue
+			}
+			unread++
+		}
+		if unread > 0 {
+			userConversation.addUnread(fakeChannelId, channelType, unread)
+		}
+
+		if hasMention {
+			for i, message := range messages {
+				if message.FromUid == uid || !mentions[i].IsMentioned(uid) {
+					continue
+				}
+				userConversation.addMention(fakeChannelId, channelType, message.MessageSeq, mentions[i].All == 1)
 			}
 		}
 
 	}
 
+	c.flushWal()
 }
 
 func (c *ConversationManager) Start() error {
 
 	c.workers = make([]*conversationWorker, c.s.opts.Conversation.WorkerCount)
 	for i := 0; i < c.s.opts.Conversation.WorkerCount; i++ {
-		cw := newConversationWorker(i, c.s)
-		c.workers[i] = cw
-		err := cw.start()
+		c.workers[i] = newConversationWorker(i, c.s)
+	}
+
+	var walFiles []string
+	if c.s.opts.Conversation.WalOn {
+		var err error
+		walFiles, err = c.recoverFromWal()
 		if err != nil {
-			c.Error("start conversation worker err", zap.Error(err))
+			c.Error("recover conversation from wal err", zap.Error(err))
 			return err
 		}
 	}
 
 	c.recoverFromFile()
 
+	if c.s.opts.Conversation.WalOn {
+		c.rewriteWal(walFiles)
+	}
+
+	for _, cw := range c.workers {
+		err := cw.start()
+		if err != nil {
+			c.Error("start conversation worker err", zap.Error(err))
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -122,12 +179,86 @@ func (c *ConversationManager) Stop() {
 
 	for _, w := range c.workers {
 		w.stop()
+		if w.wal != nil {
+			w.wal.close()
+		}
 	}
 
-	c.saveToFile()
+	err := c.saveToFile()
+	if err != nil {
+		return
+	}
+	// 缓存已经保存到文件，预写日志不再需要
+	if c.s.opts.Conversation.WalOn {
+		c.removeWalFiles()
+	}
+}
+
+func (c *ConversationManager) walDir() string {
+	return path.Join(c.s.opts.DataDir, "conversation", "wal")
+}
+
+// recoverFromWal 重放预写日志，返回重放过的日志文件
+func (c *ConversationManager) recoverFromWal() ([]string, error) {
+	start := time.Now()
+	files, err := conversationWalFiles(c.walDir())
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	total := 0
+	for _, file := range files {
+		count, err := readConversationWalFile(file, c.applyWalEntry)
+		total += count
+		if err != nil {
+			// 进程崩溃时最后一条日志可能只写了一半，忽略后面的数据
+			c.Warn("read conversation wal incomplete", zap.Error(err), zap.String("file", file), zap.Int("count", count))
+		}
+	}
+	cost := time.Since(start)
+	trace.GlobalTrace.Metrics.App().ConversationWalRecoverDurationSet(cost.Milliseconds())
+	c.Info("recover conversation from wal", zap.Int("files", len(files)), zap.Int("entries", total), zap.Duration("cost", cost))
+	return files, nil
+}
+
+func (c *ConversationManager) applyWalEntry(entry *conversationWalEntry) {
+	c.worker(entry.uid).getOrCreateUserConversation(entry.uid).applyWalEntry(entry)
+}
+
+// rewriteWal 将恢复后还未保存的缓存写入新的日志，然后删除旧的日志
+func (c *ConversationManager) rewriteWal(oldFiles []string) {
+	for _, w := range c.workers {
+		w.rewriteWal()
+	}
+	for _, file := range oldFiles {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			c.Error("remove conversation wal err", zap.Error(err), zap.String("file", file))
+		}
+	}
 }
 
-func (c *ConversationManager) saveToFile() {
+func (c *ConversationManager) removeWalFiles() {
+	files, err := conversationWalFiles(c.walDir())
+	if err != nil {
+		c.Error("get conversation wal files err", zap.Error(err))
+		return
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			c.Error("remove conversation wal err", zap.Error(err), zap.String("file", file))
+		}
+	}
+}
+
+func (c *ConversationManager) flushWal() {
+	for _, w := range c.workers {
+		w.flushWal()
+	}
+}
+
+func (c *ConversationManager) saveToFile() error {
 	c.Lock()
 	defer c.Unlock()
 
@@ -135,7 +266,7 @@ func (c *ConversationManager) saveToFile() {
 	err := os.MkdirAll(conversationDir, 0755)
 	if err != nil {
 		c.Error("mkdir conversation dir err", zap.Error(err))
-		return
+		return err
 	}
 
 	jsonMap := make(map[string][]*channelConversation)
@@ -145,13 +276,14 @@ func (c *ConversationManager) saveToFile() {
 		}
 	}
 	if len(jsonMap) == 0 {
-		return
+		return nil
 	}
 
 	err = os.WriteFile(path.Join(conversationDir, "conversation.json"), []byte(wkutil.ToJSON(jsonMap)), 0644)
 	if err != nil {
 		c.Error("write conversation file err", zap.Error(err))
 	}
+	return err
 }
 
 func (c *ConversationManager) recoverFromFile() {
@@ -213,12 +345,100 @@ func (c *ConversationManager) GetUserConversationFromCache(uid string, conversat
 
 }
 
+// UpdateConversation 修改用户数据库里的会话并删除缓存，和缓存的保存互斥
+func (c *ConversationManager) UpdateConversation(uid string, channelId string, channelType uint8, f func() error) error {
+	worker := c.worker(uid)
+	worker.proposeMu.Lock()
+	defer worker.proposeMu.Unlock()
+	if err := f(); err != nil {
+		return err
+	}
+	c.DeleteUserConversationFromCache(uid, channelId, channelType)
+	return nil
+}
+
+// RemoveUnread 消息被删除后扣减用户会话的未读数，all为true表示频道的消息全部被删除，本节点需要是用户所在槽的领导节点
+func (c *ConversationManager) RemoveUnread(uid string, fakeChannelId string, channelType uint8, messages []unreadMessage, all bool) error {
+	worker := c.worker(uid)
+	worker.proposeMu.Lock()
+	defer worker.proposeMu.Unlock()
+
+	// 缓存里的未读数是最新的，修改缓存等待保存即可
+	if userConversation := worker.getUserConversation(uid); userConversation != nil {
+		if userConversation.removeUnread(fakeChannelId, channelType, messages, all) {
+			worker.flushWal()
+			return nil
+		}
+	}
+
+	conversation, err := c.s.store.GetConversation(uid, fakeChannelId, channelType)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	count := conversation.UnreadCount
+	if !all {
+		count = countUnreadMessages(uid, messages, conversation.ReadToMsgSeq)
+	}
+	if count == 0 || conversation.UnreadCount == 0 {
+		return nil
+	}
+	if count > conversation.UnreadCount {
+		count = conversation.UnreadCount
+	}
+	conversation.UnreadCount -= count
+	return c.s.store.AddOrUpdateConversations(uid, []wkdb.Conversation{conversation})
+}
+
 func (c *ConversationManager) DeleteUserConversationFromCache(uid string, channelId string, channelType uint8) {
-	userconversation := c.worker(uid).getUserConversation(uid)
+	worker := c.worker(uid)
+	userconversation := worker.getUserConversation(uid)
 	if userconversation == nil {
 		return
 	}
 	userconversation.deleteConversation(channelId, channelType)
+	worker.flushWal()
+}
+
+// GetUserBadge 获取用户所有聊天会话的未读总数
+func (c *ConversationManager) GetUserBadge(uid string, excludeMute bool) (int, error) {
+	conversations, err := c.s.store.GetConversationsByType(uid, wkdb.ConversationTypeChat)
+	if err != nil && err != wkdb.ErrNotFound {
+		return 0, err
+	}
+	unreadMap := make(map[string]uint32, len(conversations))
+	mutedMap := make(map[string]bool)
+	for _, conversation := range conversations {
+		channelKey := wkutil.ChannelToKey(conversation.ChannelId, conversation.ChannelType)
+		unreadMap[channelKey] = conversation.UnreadCount
+		if conversation.Extra != nil && conversation.Extra.Mute {
+			mutedMap[channelKey] = true
+		}
+	}
+	// 缓存里的未读数是最新的
+	for _, conversation := range c.GetUserConversationFromCache(uid, wkdb.ConversationTypeChat) {
+		unreadMap[wkutil.ChannelToKey(conversation.ChannelId, conversation.ChannelType)] = conversation.UnreadCount
+	}
+
+	systemChannelKey := wkutil.ChannelToKey(GetFakeChannelIDWith(uid, c.s.opts.SystemUID), wkproto.ChannelTypePerson)
+	badge := 0
+	for channelKey, unread := range unreadMap {
+		if channelKey == systemChannelKey { // 与系统账号的会话不计入
+			continue
+		}
+		if excludeMute && mutedMap[channelKey] {
+			continue
+		}
+		badge += int(unread)
+	}
+	return badge, nil
+}
+
+// GetMutedUids 获取对此频道设置了免打扰的用户
+func (c *ConversationManager) GetMutedUids(fakeChannelId string, channelType uint8, uids []string) map[string]struct{} {
+	return c.mutes.mutedUids(fakeChannelId, channelType, uids)
 }
 
 // func (c *ConversationManager) existConversationInCache(uid string, channelId string, channelType uint8) bool {
@@ -236,17 +456,25 @@ type conversationWorker struct {
 	wklog.Log
 	index   int
 	stopper *syncutil.Stopper
+	wal     *conversationWal // 预写日志，未开启时为nil
+
+	// proposeMu 保存缓存期间持有，直接修改数据库里会话的操作也要持有，防止缓存里的旧未读数覆盖新写入的值
+	proposeMu sync.Mutex
 
 	sync.RWMutex
 }
 
 func newConversationWorker(i int, s *Server) *conversationWorker {
-	return &conversationWorker{
+	cw := &conversationWorker{
 		s:       s,
 		Log:     wklog.NewWKLog(fmt.Sprintf("conversationWorker[%d]", i)),
 		index:   i,
 		stopper: syncutil.NewStopper(),
 	}
+	if s.opts.Conversation.WalOn {
+		cw.wal = newConversationWal(path.Join(s.opts.DataDir, "conversation", "wal"), i, s.opts.Conversation.WalSyncInterval)
+	}
+	return cw
 }
 
 func (c *conversationWorker) start() error {
@@ -262,10 +490,20 @@ func (c *conversationWorker) loopPropose() {
 
 	tk := time.NewTicker(time.Minute * 5)
 
+	// 配置了刷盘间隔时定时刷盘，保证没有新的变更时已写入的日志也能落盘
+	var syncC <-chan time.Time
+	if c.wal != nil && c.s.opts.Conversation.WalSyncInterval > 0 {
+		syncTk := time.NewTicker(c.s.opts.Conversation.WalSyncInterval)
+		defer syncTk.Stop()
+		syncC = syncTk.C
+	}
+
 	for {
 		select {
 		case <-tk.C:
 			c.propose()
+		case <-syncC:
+			c.flushWal()
 		case <-c.stopper.ShouldStop():
 			return
 		}
@@ -274,6 +512,15 @@ func (c *conversationWorker) loopPropose() {
 }
 
 func (c *conversationWorker) propose() {
+	c.proposeMu.Lock()
+	defer c.proposeMu.Unlock()
+
+	// 封存当前的日志分段，分段里的变更全部保存成功后才能删除
+	var walSegments []*conversationWalSegment
+	if c.wal != nil {
+		walSegments = c.wal.seal()
+	}
+	success := true
 
 	c.Lock()
 	tmpUserConversations := make([]*userConversation, len(c.userConversations))
@@ -285,6 +532,7 @@ func (c *conversationWorker) propose() {
 	for _, cc := range tmpUserConversations {
 
 		var conversations []wkdb.Conversation
+		mentions := make(map[int]*wkdb.ConversationMention) // 缓存里新增的@提醒，key为conversations的下标
 
 		cc.Lock()
 		for _, conversation := range cc.conversations {
@@ -306,16 +554,32 @@ func (c *conversationWorker) propose() {
 					ChannelId:    conversation.ChannelId,
 					ChannelType:  conversation.ChannelType,
 					ReadToMsgSeq: uint64(conversation.ReadedMsgSeq),
+					UnreadCount:  conversation.UnreadCount,
 					CreatedAt:    &createdAt,
 					UpdatedAt:    &updatedAt,
 				})
+				if mention := conversation.mention(); mention != nil {
+					mentions[len(conversations)-1] = mention
+				}
 			}
 		}
 		cc.Unlock()
+
+		// @提醒需要累加到数据库已有的提醒上
+		for i, mention := range mentions {
+			existConversation, err := c.s.store.DB().GetConversation(cc.uid, conversations[i].ChannelId, conversations[i].ChannelType)
+			if err != nil && err != wkdb.ErrNotFound {
+				c.Error("get conversation err", zap.Error(err), zap.String("uid", cc.uid), zap.String("channelId", conversations[i].ChannelId))
+				existConversation = wkdb.EmptyConversation
+			}
+			conversations[i].Mention = existConversation.Mention.Merge(mention)
+		}
+
 		if len(conversations) > 0 {
 			err := c.s.store.AddOrUpdateConversations(cc.uid, conversations)
 			if err != nil {
 				c.Error("add or update conversations err", zap.Error(err))
+				success = false
 
 				// 如果更新失败，则需要重新更新
 				cc.Lock()
@@ -323,10 +587,59 @@ func (c *conversationWorker) propose() {
 					conversation.NeedUpdate = true
 				}
 				cc.Unlock()
+			} else if len(mentions) > 0 {
+				// 已经保存的@提醒从缓存中去掉
+				cc.Lock()
+				for i, mention := range mentions {
+					conversation := cc.getConversationNotLock(conversations[i].ChannelId, conversations[i].ChannelType)
+					if conversation != nil {
+						conversation.removeMention(mention.Count)
+						cc.appendWalNotLock(conversation)
+					}
+				}
+				cc.Unlock()
 			}
 
 		}
 	}
+
+	if c.wal == nil {
+		return
+	}
+	c.flushWal()
+	if success && len(walSegments) > 0 {
+		if err := c.wal.checkpoint(walSegments); err != nil {
+			c.Error("checkpoint conversation wal err", zap.Error(err))
+		}
+	}
+}
+
+func (c *conversationWorker) flushWal() {
+	if c.wal == nil {
+		return
+	}
+	if err := c.wal.flush(); err != nil {
+		c.Error("flush conversation wal err", zap.Error(err))
+	}
+}
+
+// rewriteWal 将还未保存的缓存全部写入新的日志分段
+func (c *conversationWorker) rewriteWal() {
+	c.Lock()
+	tmpUserConversations := make([]*userConversation, len(c.userConversations))
+	copy(tmpUserConversations, c.userConversations)
+	c.Unlock()
+
+	for _, cc := range tmpUserConversations {
+		cc.Lock()
+		for _, conversation := range cc.conversations {
+			if conversation.NeedUpdate {
+				cc.appendWalNotLock(conversation)
+			}
+		}
+		cc.Unlock()
+	}
+	c.wal.seal()
 }
 
 func (c *conversationWorker) getOrCreateUserConversation(uid string) *userConversation {
@@ -339,6 +652,7 @@ func (c *conversationWorker) getOrCreateUserConversation(uid string) *userConver
 	}
 
 	cc := newUserConversation(uid, c.s)
+	cc.wal = c.wal
 	c.userConversations = append(c.userConversations, cc)
 	return cc
 }
@@ -361,6 +675,7 @@ type userConversation struct {
 	uid           string
 	conversations []*channelConversation
 	s             *Server
+	wal           *conversationWal
 	deadlock.RWMutex
 }
 
@@ -415,6 +730,8 @@ func (c *userConversation) getConversationsByType(conversationType wkdb.Conversa
 				ChannelId:    s.ChannelId,
 				ChannelType:  s.ChannelType,
 				ReadToMsgSeq: uint64(s.ReadedMsgSeq),
+				UnreadCount:  s.UnreadCount,
+				Mention:      s.mention(),
 				CreatedAt:    &s.CreatedAt,
 				UpdatedAt:    &s.UpdatedAt,
 			})
@@ -431,11 +748,56 @@ func (c *userConversation) deleteConversation(channelId string, channelType uint
 	for i, s := range c.conversations {
 		if s.ChannelId == channelId && s.ChannelType == channelType {
 			c.conversations = append(c.conversations[:i], c.conversations[i+1:]...)
+			if c.wal != nil {
+				if err := c.wal.append(newConversationWalDeleteEntry(c.uid, channelId, channelType)); err != nil {
+					c.wal.Error("append wal err", zap.Error(err), zap.String("uid", c.uid))
+				}
+			}
 			return
 		}
 	}
 }
 
+// appendWal 将会话的最新数据写入预写日志
+func (c *userConversation) appendWal(channelId string, channelType uint8) {
+	c.RLock()
+	defer c.RUnlock()
+	conversation := c.getConversationNotLock(channelId, channelType)
+	if conversation == nil {
+		return
+	}
+	c.appendWalNotLock(conversation)
+}
+
+func (c *userConversation) appendWalNotLock(conversation *channelConversation) {
+	if c.wal == nil {
+		return
+	}
+	if err := c.wal.append(newConversationWalUpdateEntry(c.uid, conversation)); err != nil {
+		c.wal.Error("append wal err", zap.Error(err), zap.String("uid", c.uid), zap.String("channelId", conversation.ChannelId))
+	}
+}
+
+// applyWalEntry 重放预写日志的条目
+func (c *userConversation) applyWalEntry(entry *conversationWalEntry) {
+	c.Lock()
+	defer c.Unlock()
+
+	channelId, channelType := entry.conversation.ChannelId, entry.conversation.ChannelType
+	for i, s := range c.conversations {
+		if s.ChannelId == channelId && s.ChannelType == channelType {
+			c.conversations = append(c.conversations[:i], c.conversations[i+1:]...)
+			break
+		}
+	}
+	if entry.entryType != conversationWalEntryUpdate {
+		return
+	}
+	conversation := entry.conversation
+	conversation.NeedUpdate = true
+	c.conversations = append(c.conversations, &conversation)
+}
+
 func (c *userConversation) getConversationNotLock(channelId string, channelType uint8) *channelConversation {
 
 	for _, s := range c.conversations {
@@ -468,6 +830,13 @@ func (c *userConversation) updateOrAddConversation(channelId string, channelType
 			conversation.ReadedMsgSeq = readedMsgSeq
 			conversation.NeedUpdate = true
 		}
+		if conversation.UnreadCount > 0 { // 自己发了消息，说明已经读过了
+			conversation.UnreadCount = 0
+			conversation.NeedUpdate = true
+		}
+		if conversation.NeedUpdate {
+			c.appendWalNotLock(conversation)
+		}
 		return
 	}
 
@@ -478,7 +847,7 @@ func (c *userConversation) updateOrAddConversation(channelId string, channelType
 		conversationType = wkdb.ConversationTypeChat
 	}
 
-	c.conversations = append(c.conversations, &channelConversation{
+	conversation = &channelConversation{
 		ChannelId:        channelId,
 		ChannelType:      channelType,
 		ReadedMsgSeq:     readedMsgSeq,
@@ -486,7 +855,66 @@ func (c *userConversation) updateOrAddConversation(channelId string, channelType
 		NeedUpdate:       true,
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
-	})
+	}
+	c.conversations = append(c.conversations, conversation)
+	c.appendWalNotLock(conversation)
+}
+
+// addUnread 累加会话的未读数
+func (c *userConversation) addUnread(channelId string, channelType uint8, count uint32) {
+	c.Lock()
+	defer c.Unlock()
+
+	conversation := c.getConversationNotLock(channelId, channelType)
+	if conversation == nil {
+		return
+	}
+	conversation.UnreadCount += count
+	conversation.NeedUpdate = true
+	c.appendWalNotLock(conversation)
+}
+
+// removeUnread 扣减缓存里会话的未读数，会话不在缓存里返回false
+func (c *userConversation) removeUnread(channelId string, channelType uint8, messages []unreadMessage, all bool) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	conversation := c.getConversationNotLock(channelId, channelType)
+	if conversation == nil {
+		return false
+	}
+	count := conversation.UnreadCount
+	if !all {
+		count = countUnreadMessages(c.uid, messages, uint64(conversation.ReadedMsgSeq))
+	}
+	if count > conversation.UnreadCount {
+		count = conversation.UnreadCount
+	}
+	if count == 0 {
+		return true
+	}
+	conversation.UnreadCount -= count
+	conversation.NeedUpdate = true
+	c.appendWalNotLock(conversation)
+	return true
+}
+
+// addMention 记录用户在会话里被@
+func (c *userConversation) addMention(channelId string, channelType uint8, messageSeq uint32, all bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	conversation := c.getConversationNotLock(channelId, channelType)
+	if conversation == nil {
+		return
+	}
+	conversation.MentionCount++
+	if messageSeq >= conversation.MentionMsgSeq {
+		conversation.MentionMsgSeq = messageSeq
+		conversation.MentionAll = all
+	}
+	conversation.NeedUpdate = true
+	c.appendWalNotLock(conversation)
 }
 
 func (c *userConversation) addConversationNotLock(conversationId uint64, channelId string, channelType uint8, readedMsgSeq uint32) *channelConversation {
@@ -522,4 +950,31 @@ type channelConversation struct {
 	ConversationType wkdb.ConversationType `json:"conversation_type"`
 	CreatedAt        time.Time             `json:"created_at"`
 	UpdatedAt        time.Time             `json:"updated_at"`
+	UnreadCount      uint32                `json:"unread_count,omitempty"`    // 未读数（缓存里的为最新值）
+	MentionCount     uint32                `json:"mention_count,omitempty"`   // 还未保存的@数量
+	MentionMsgSeq    uint32                `json:"mention_msg_seq,omitempty"` // 最后一条@的消息序号
+	MentionAll       bool                  `json:"mention_all,omitempty"`     // 最后一条@是否是@所有人
+}
+
+// mention 还未保存的@提醒
+func (c *channelConversation) mention() *wkdb.ConversationMention {
+	if c.MentionCount == 0 {
+		return nil
+	}
+	return &wkdb.ConversationMention{
+		Count:  c.MentionCount,
+		MsgSeq: uint64(c.MentionMsgSeq),
+		All:    c.MentionAll,
+	}
+}
+
+// removeMention 去掉已经保存的@数量
+func (c *channelConversation) removeMention(count uint32) {
+	if c.MentionCount > count {
+		c.MentionCount -= count
+		return
+	}
+	c.MentionCount = 0
+	c.MentionMsgSeq = 0
+	c.MentionAll = false
 }
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	muteCacheChannelSize = 1000            // 最多缓存多少个频道的免打扰设置
+	muteCacheTTL         = time.Minute * 5 // 失效通知丢失时，最多这么久后重新查询
+)
+
+// muteCache 缓存用户对频道的免打扰设置，投递离线消息时判断是否推送
+// 用户的会话保存在用户所在槽的领导节点上，缓存未命中时按领导节点批量查询，设置免打扰后通知所有节点失效
+type muteCache struct {
+	s        *Server
+	mu       sync.Mutex
+	channels *lru.Cache[string, *channelMutes]
+	wklog.Log
+}
+
+type channelMutes struct {
+	mu   sync.Mutex
+	uids map[string]muteState
+}
+
+type muteState struct {
+	muted    bool
+	expireAt time.Time
+}
+
+// muteReq 节点间查询免打扰用户和通知失效的请求
+type muteReq struct {
+	ChannelId   string   `json:"channel_id"`
+	ChannelType uint8    `json:"channel_type"`
+	Uids        []string `json:"uids"`
+}
+
+func newMuteCache(s *Server) *muteCache {
+	channels, _ := lru.New[string, *channelMutes](muteCacheChannelSize)
+	return &muteCache{
+		s:        s,
+		channels: channels,
+		Log:      wklog.NewWKLog("muteCache"),
+	}
+}
+
+// mutedUids 获取uids里对此频道设置了免打扰的用户
+func (m *muteCache) mutedUids(fakeChannelId string, channelType uint8, uids []string) map[string]struct{} {
+	mutes := m.channelMutes(wkutil.ChannelToKey(fakeChannelId, channelType), true)
+
+	now := time.Now()
+	var misses []string
+	var mutedUids map[string]struct{}
+	mutes.mu.Lock()
+	for _, uid := range uids {
+		state, ok := mutes.uids[uid]
+		if !ok || now.After(state.expireAt) {
+			misses = append(misses, uid)
+			continue
+		}
+		if state.muted {
+			if mutedUids == nil {
+				mutedUids = make(map[string]struct{})
+			}
+			mutedUids[uid] = struct{}{}
+		}
+	}
+	mutes.mu.Unlock()
+	if len(misses) == 0 {
+		return mutedUids
+	}
+
+	loaded, loadedUids := m.load(fakeChannelId, channelType, misses)
+	expireAt := now.Add(muteCacheTTL)
+	mutes.mu.Lock()
+	for _, uid := range loadedUids {
+		_, muted := loaded[uid]
+		mutes.uids[uid] = muteState{muted: muted, expireAt: expireAt}
+	}
+	mutes.mu.Unlock()
+	for uid := range loaded {
+		if mutedUids == nil {
+			mutedUids = make(map[string]struct{})
+		}
+		mutedUids[uid] = struct{}{}
+	}
+	return mutedUids
+}
+
+// invalidate 删除用户对频道的免打扰缓存
+func (m *muteCache) invalidate(fakeChannelId string, channelType uint8, uids []string) {
+	mutes := m.channelMutes(wkutil.ChannelToKey(fakeChannelId, channelType), false)
+	if mutes == nil {
+		return
+	}
+	mutes.mu.Lock()
+	for _, uid := range uids {
+		delete(mutes.uids, uid)
+	}
+	mutes.mu.Unlock()
+}
+
+// notifyChanged 用户修改了免打扰设置，让所有节点的缓存失效
+func (m *muteCache) notifyChanged(fakeChannelId string, channelType uint8, uid string) {
+	m.invalidate(fakeChannelId, channelType, []string{uid})
+	if !m.s.opts.ClusterOn() {
+		return
+	}
+	req := &muteReq{ChannelId: fakeChannelId, ChannelType: channelType, Uids: []string{uid}}
+	for _, node := range m.s.clusterServer.GetConfig().Nodes {
+		if node.Id == m.s.opts.Cluster.NodeId || !node.Online {
+			continue
+		}
+		nodeId := node.Id
+		go func() {
+			if err := m.s.requestNodeJSON(nodeId, "/wk/muteInvalidate", req, nil); err != nil {
+				m.Warn("通知节点免打扰缓存失效失败！", zap.Error(err), zap.Uint64("nodeId", nodeId), zap.String("uid", uid))
+			}
+		}()
+	}
+}
+
+func (m *muteCache) channelMutes(channelKey string, create bool) *channelMutes {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mutes, ok := m.channels.Get(channelKey)
+	if ok || !create {
+		return mutes
+	}
+	mutes = &channelMutes{uids: make(map[string]muteState)}
+	m.channels.Add(channelKey, mutes)
+	return mutes
+}
+
+// load 从用户所在槽的领导节点查询免打扰设置，返回免打扰的用户和查询成功的用户
+func (m *muteCache) load(fakeChannelId string, channelType uint8, uids []string) (map[string]struct{}, []string) {
+	nodeUids := make(map[uint64][]string)
+	for _, uid := range uids {
+		nodeId := m.s.opts.Cluster.NodeId
+		if m.s.opts.ClusterOn() {
+			leaderId, err := m.s.cluster.SlotLeaderIdOfChannel(uid, wkproto.ChannelTypePerson)
+			if err != nil {
+				m.Warn("获取用户所在槽的领导节点失败！", zap.Error(err), zap.String("uid", uid))
+				continue
+			}
+			nodeId = leaderId
+		}
+		nodeUids[nodeId] = append(nodeUids[nodeId], uid)
+	}
+
+	mutedUids := make(map[string]struct{})
+	loadedUids := make([]string, 0, len(uids))
+	for nodeId, uids := range nodeUids {
+		var muted []string
+		if nodeId == m.s.opts.Cluster.NodeId {
+			muted = m.localMutedUids(fakeChannelId, channelType, uids)
+		} else {
+			req := &muteReq{ChannelId: fakeChannelId, ChannelType: channelType, Uids: uids}
+			if err := m.s.requestNodeJSON(nodeId, "/wk/mutedUids", req, &muted); err != nil {
+				m.Warn("查询节点的免打扰用户失败！", zap.Error(err), zap.Uint64("nodeId", nodeId), zap.String("channelId", fakeChannelId))
+				continue
+			}
+		}
+		for _, uid := range muted {
+			mutedUids[uid] = struct{}{}
+		}
+		loadedUids = append(loadedUids, uids...)
+	}
+	return mutedUids, loadedUids
+}
+
+// localMutedUids 从本节点读取免打扰的用户，本节点需要是这些用户所在槽的领导节点
+func (m *muteCache) localMutedUids(fakeChannelId string, channelType uint8, uids []string) []string {
+	var muted []string
+	for _, uid := range uids {
+		conversation, err := m.s.store.GetConversation(uid, fakeChannelId, channelType)
+		if err != nil && err != wkdb.ErrNotFound {
+			m.Error("get conversation err", zap.Error(err), zap.String("uid", uid), zap.String("fakeChannelId", fakeChannelId), zap.Uint8("channelType", channelType))
+			continue
+		}
+		if conversation.Extra != nil && conversation.Extra.Mute {
+			muted = append(muted, uid)
+		}
+	}
+	return muted
+}
+
+// handleMutedUids 查询本节点上免打扰的用户
+func (m *muteCache) handleMutedUids(c *wkserver.Context) {
+	var req muteReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	muted := m.localMutedUids(req.ChannelId, req.ChannelType, req.Uids)
+	if muted == nil {
+		muted = make([]string, 0)
+	}
+	c.Write([]byte(wkutil.ToJSON(muted)))
+}
+
+// handleMuteInvalidate 其他节点通知免打扰缓存失效
+func (m *muteCache) handleMuteInvalidate(c *wkserver.Context) {
+	var req muteReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	m.invalidate(req.ChannelId, req.ChannelType, req.Uids)
+	c.WriteOk()
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMuteCache(t *testing.T) {
+	m := newMuteCache(&Server{opts: NewOptions()})
+	mutes := m.channelMutes(wkutil.ChannelToKey("g1", 2), true)
+	expireAt := time.Now().Add(time.Minute)
+	mutes.uids["u1"] = muteState{muted: true, expireAt: expireAt}
+	mutes.uids["u2"] = muteState{muted: false, expireAt: expireAt}
+
+	// 命中缓存不需要查询
+	muted := m.mutedUids("g1", 2, []string{"u1", "u2"})
+	assert.Equal(t, map[string]struct{}{"u1": {}}, muted)
+
+	// 失效后缓存里不再有该用户
+	m.invalidate("g1", 2, []string{"u1"})
+	_, ok := mutes.uids["u1"]
+	assert.False(t, ok)
+	_, ok = mutes.uids["u2"]
+	assert.True(t, ok)
+}
@@ -1,7 +1,9 @@
 package server
 
 import (
+	"os"
 	"testing"
+	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
@@ -39,3 +41,221 @@ func TestConversationGet(t *testing.T) {
 	assert.Equal(t, uint64(0), conversations2[0].ReadToMsgSeq)
 
 }
+
+func TestConversationMention(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.Start()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.Stop()
+	}()
+
+	s.conversationManager.Push("g1", 2, []string{"u1", "u2", "u3"}, []ReactorChannelMessage{
+		{
+			FromUid:    "u1",
+			MessageSeq: 1,
+			SendPacket: &wkproto.SendPacket{Payload: []byte(`{"content":"hi @u2","mention":{"uids":["u2"]}}`)},
+		},
+		{
+			FromUid:    "u1",
+			MessageSeq: 2,
+			SendPacket: &wkproto.SendPacket{Payload: []byte(`{"content":"hi all","mention":{"all":1}}`)},
+		},
+	})
+
+	// 发送者自己不记录@
+	conversations := s.conversationManager.GetUserConversationFromCache("u1", wkdb.ConversationTypeChat)
+	assert.Equal(t, 1, len(conversations))
+	assert.Nil(t, conversations[0].Mention)
+
+	conversations = s.conversationManager.GetUserConversationFromCache("u2", wkdb.ConversationTypeChat)
+	assert.Equal(t, 1, len(conversations))
+	assert.Equal(t, uint32(2), conversations[0].Mention.Count)
+	assert.Equal(t, uint64(2), conversations[0].Mention.MsgSeq)
+	assert.True(t, conversations[0].Mention.All)
+
+	conversations = s.conversationManager.GetUserConversationFromCache("u3", wkdb.ConversationTypeChat)
+	assert.Equal(t, 1, len(conversations))
+	assert.Equal(t, uint32(1), conversations[0].Mention.Count)
+}
+
+func TestConversationUnread(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.Start()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.Stop()
+	}()
+
+	s.conversationManager.Push("g1", 2, []string{"u1", "u2"}, []ReactorChannelMessage{
+		{
+			FromUid:    "u1",
+			MessageSeq: 1,
+			SendPacket: &wkproto.SendPacket{Framer: wkproto.Framer{RedDot: true}},
+		},
+		{
+			FromUid:    "u1",
+			MessageSeq: 2,
+			SendPacket: &wkproto.SendPacket{Framer: wkproto.Framer{RedDot: false}}, // 不显示红点
+		},
+		{
+			FromUid:    "u1",
+			MessageSeq: 3,
+			SendPacket: &wkproto.SendPacket{Framer: wkproto.Framer{RedDot: true, NoPersist: true}}, // 不存储
+		},
+		{
+			FromUid:    "u1",
+			MessageSeq: 4,
+			SendPacket: &wkproto.SendPacket{Framer: wkproto.Framer{RedDot: true}},
+		},
+	})
+	s.conversationManager.Push("g2", 2, []string{"u2"}, []ReactorChannelMessage{
+		{
+			FromUid:    "u3",
+			MessageSeq: 1,
+			SendPacket: &wkproto.SendPacket{Framer: wkproto.Framer{RedDot: true}},
+		},
+	})
+
+	// 自己发的消息不计入未读
+	conversations := s.conversationManager.GetUserConversationFromCache("u1", wkdb.ConversationTypeChat)
+	assert.Equal(t, 1, len(conversations))
+	assert.Equal(t, uint32(0), conversations[0].UnreadCount)
+
+	badge, err := s.conversationManager.GetUserBadge("u2", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, badge)
+
+	// u2在g1里发了消息，g1的未读清零
+	s.conversationManager.Push("g1", 2, []string{"u1", "u2"}, []ReactorChannelMessage{
+		{
+			FromUid:    "u2",
+			MessageSeq: 5,
+			SendPacket: &wkproto.SendPacket{Framer: wkproto.Framer{RedDot: true}},
+		},
+	})
+	badge, err = s.conversationManager.GetUserBadge("u2", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, badge)
+}
+
+func TestConversationRemoveUnread(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.Start()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.Stop()
+	}()
+
+	s.conversationManager.Push("g1", 2, []string{"u1", "u2"}, []ReactorChannelMessage{
+		{FromUid: "u1", MessageSeq: 1, SendPacket: &wkproto.SendPacket{Framer: wkproto.Framer{RedDot: true}}},
+		{FromUid: "u1", MessageSeq: 2, SendPacket: &wkproto.SendPacket{Framer: wkproto.Framer{RedDot: true}}},
+		{FromUid: "u1", MessageSeq: 3, SendPacket: &wkproto.SendPacket{Framer: wkproto.Framer{RedDot: true}}},
+	})
+
+	// 删除缓存里会话的一条未读消息
+	s.conversationManager.removeUnreadLocal("g1", 2, []string{"u1", "u2"}, []unreadMessage{{MessageSeq: 2, FromUid: "u1"}}, false)
+	badge, err := s.conversationManager.GetUserBadge("u2", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, badge)
+
+	// 保存到数据库后再删除
+	s.conversationManager.worker("u2").propose()
+	s.conversationManager.DeleteUserConversationFromCache("u2", "g1", 2)
+	s.conversationManager.removeUnreadLocal("g1", 2, []string{"u2"}, []unreadMessage{{MessageSeq: 3, FromUid: "u1"}}, false)
+	badge, err = s.conversationManager.GetUserBadge("u2", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, badge)
+
+	// 频道消息全部删除
+	s.conversationManager.removeUnreadLocal("g1", 2, []string{"u2"}, nil, true)
+	badge, err = s.conversationManager.GetUserBadge("u2", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, badge)
+}
+
+func TestConversationWalRecover(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.Start()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.Stop()
+	}()
+
+	s.conversationManager.Push("u1@u2", 1, []string{"u1", "u2"}, []ReactorChannelMessage{
+		{
+			FromUid:    "u1",
+			MessageSeq: 100,
+			SendPacket: &wkproto.SendPacket{Framer: wkproto.Framer{RedDot: true}},
+		},
+		{
+			FromUid:    "u1",
+			MessageSeq: 102,
+			SendPacket: &wkproto.SendPacket{Framer: wkproto.Framer{RedDot: true}},
+		},
+	})
+
+	// 模拟崩溃：缓存没有保存，用新的管理者重放日志
+	cm := NewConversationManager(s)
+	cm.workers = make([]*conversationWorker, s.opts.Conversation.WorkerCount)
+	for i := range cm.workers {
+		cm.workers[i] = newConversationWorker(i, s)
+	}
+	files, err := cm.recoverFromWal()
+	assert.NoError(t, err)
+	assert.NotEqual(t, 0, len(files))
+
+	conversations1 := cm.GetUserConversationFromCache("u1", wkdb.ConversationTypeChat)
+	assert.Equal(t, 1, len(conversations1))
+	assert.Equal(t, uint64(102), conversations1[0].ReadToMsgSeq)
+
+	conversations2 := cm.GetUserConversationFromCache("u2", wkdb.ConversationTypeChat)
+	assert.Equal(t, 1, len(conversations2))
+	assert.Equal(t, uint32(2), conversations2[0].UnreadCount)
+
+	// 写了一半的日志条目会被忽略
+	var total int
+	for _, file := range files {
+		count, err := readConversationWalFile(file, func(entry *conversationWalEntry) {})
+		assert.NoError(t, err)
+		total += count
+	}
+	f, err := os.OpenFile(files[len(files)-1], os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 100, 1, 2})
+	assert.NoError(t, err)
+	_ = f.Close()
+
+	var count int
+	for _, file := range files {
+		n, _ := readConversationWalFile(file, func(entry *conversationWalEntry) {})
+		count += n
+	}
+	assert.Equal(t, total, count)
+}
+
+func TestConversationWalSyncInterval(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.Start()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.Stop()
+	}()
+
+	w := newConversationWal(t.TempDir(), 0, time.Hour)
+	err = w.append(newConversationWalUpdateEntry("u1", &channelConversation{ChannelId: "g1", ChannelType: 2}))
+	assert.NoError(t, err)
+
+	// 第一次flush刷盘
+	err = w.flush()
+	assert.NoError(t, err)
+	lastSync := w.lastSync
+	assert.False(t, lastSync.IsZero())
+
+	// 间隔内的flush只写入文件
+	err = w.append(newConversationWalUpdateEntry("u1", &channelConversation{ChannelId: "g2", ChannelType: 2}))
+	assert.NoError(t, err)
+	err = w.flush()
+	assert.NoError(t, err)
+	assert.Equal(t, lastSync, w.lastSync)
+}
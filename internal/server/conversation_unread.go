@@ -0,0 +1,100 @@
+package server
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// unreadMessage 计入未读数的消息
+type unreadMessage struct {
+	MessageSeq uint64 `json:"message_seq"`
+	FromUid    string `json:"from_uid"`
+}
+
+// removeUnreadReq 节点间扣减未读数的请求
+type removeUnreadReq struct {
+	ChannelId   string          `json:"channel_id"`
+	ChannelType uint8           `json:"channel_type"`
+	Uids        []string        `json:"uids"`
+	Messages    []unreadMessage `json:"messages,omitempty"`
+	All         bool            `json:"all,omitempty"` // 频道的消息全部被删除
+}
+
+// newUnreadMessages 过滤出计入未读数的消息（和累加未读数的规则一致）
+func newUnreadMessages(messages []wkdb.Message) []unreadMessage {
+	unreadMessages := make([]unreadMessage, 0, len(messages))
+	for _, message := range messages {
+		if !message.RedDot || message.NoPersist || message.SyncOnce {
+			continue
+		}
+		unreadMessages = append(unreadMessages, unreadMessage{
+			MessageSeq: uint64(message.MessageSeq),
+			FromUid:    message.FromUID,
+		})
+	}
+	return unreadMessages
+}
+
+// countUnreadMessages 统计messages里用户还没读的消息数量
+func countUnreadMessages(uid string, messages []unreadMessage, readToMsgSeq uint64) uint32 {
+	var count uint32
+	for _, message := range messages {
+		if message.FromUid == uid || message.MessageSeq <= readToMsgSeq {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// RemoveUnreadOfUsers 消息被删除后扣减用户的未读数，请求会发到用户所在槽的领导节点执行
+func (c *ConversationManager) RemoveUnreadOfUsers(fakeChannelId string, channelType uint8, uids []string, messages []unreadMessage, all bool) {
+	if !all && len(messages) == 0 {
+		return
+	}
+	nodeUids := make(map[uint64][]string)
+	for _, uid := range uids {
+		nodeId := c.s.opts.Cluster.NodeId
+		if c.s.opts.ClusterOn() {
+			leaderId, err := c.s.cluster.SlotLeaderIdOfChannel(uid, wkproto.ChannelTypePerson)
+			if err != nil {
+				c.Warn("获取用户所在槽的领导节点失败！", zap.Error(err), zap.String("uid", uid))
+				continue
+			}
+			nodeId = leaderId
+		}
+		nodeUids[nodeId] = append(nodeUids[nodeId], uid)
+	}
+	for nodeId, uids := range nodeUids {
+		if nodeId == c.s.opts.Cluster.NodeId {
+			c.removeUnreadLocal(fakeChannelId, channelType, uids, messages, all)
+			continue
+		}
+		req := &removeUnreadReq{ChannelId: fakeChannelId, ChannelType: channelType, Uids: uids, Messages: messages, All: all}
+		if err := c.s.requestNodeJSON(nodeId, "/wk/removeUnread", req, nil); err != nil {
+			c.Warn("请求节点扣减未读数失败！", zap.Error(err), zap.Uint64("nodeId", nodeId), zap.String("channelId", fakeChannelId))
+		}
+	}
+}
+
+func (c *ConversationManager) removeUnreadLocal(fakeChannelId string, channelType uint8, uids []string, messages []unreadMessage, all bool) {
+	for _, uid := range uids {
+		if err := c.RemoveUnread(uid, fakeChannelId, channelType, messages, all); err != nil {
+			c.Warn("扣减会话未读数失败！", zap.Error(err), zap.String("uid", uid), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", channelType))
+		}
+	}
+}
+
+// handleRemoveUnread 其他节点请求扣减本节点上用户的未读数
+func (c *ConversationManager) handleRemoveUnread(ctx *wkserver.Context) {
+	var req removeUnreadReq
+	if err := wkutil.ReadJSONByByte(ctx.Body(), &req); err != nil {
+		ctx.WriteErr(err)
+		return
+	}
+	c.removeUnreadLocal(req.ChannelId, req.ChannelType, req.Uids, req.Messages, req.All)
+	ctx.WriteOk()
+}
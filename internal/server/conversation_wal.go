@@ -0,0 +1,350 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+const (
+	conversationWalEntryUpdate uint8 = 1 // 会话缓存更新（记录的是更新后的完整数据）
+	conversationWalEntryDelete uint8 = 2 // 会话从缓存中删除
+
+	conversationWalHeaderSize = 8 // 数据长度(4) + crc32(4)
+)
+
+var errConversationWalCorrupt = errors.New("conversation wal entry corrupt")
+
+// conversationWalEntry 最近会话预写日志的条目
+type conversationWalEntry struct {
+	entryType    uint8
+	uid          string
+	conversation channelConversation
+}
+
+func newConversationWalUpdateEntry(uid string, conversation *channelConversation) *conversationWalEntry {
+	return &conversationWalEntry{
+		entryType:    conversationWalEntryUpdate,
+		uid:          uid,
+		conversation: *conversation,
+	}
+}
+
+func newConversationWalDeleteEntry(uid string, channelId string, channelType uint8) *conversationWalEntry {
+	return &conversationWalEntry{
+		entryType: conversationWalEntryDelete,
+		uid:       uid,
+		conversation: channelConversation{
+			ChannelId:   channelId,
+			ChannelType: channelType,
+		},
+	}
+}
+
+func (e *conversationWalEntry) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint8(e.entryType)
+	enc.WriteString(e.uid)
+	enc.WriteString(e.conversation.ChannelId)
+	enc.WriteUint8(e.conversation.ChannelType)
+	if e.entryType == conversationWalEntryUpdate {
+		enc.WriteUint64(e.conversation.Id)
+		enc.WriteUint32(e.conversation.ReadedMsgSeq)
+		enc.WriteUint8(uint8(e.conversation.ConversationType))
+		enc.WriteInt64(e.conversation.CreatedAt.UnixNano())
+		enc.WriteInt64(e.conversation.UpdatedAt.UnixNano())
+		enc.WriteUint32(e.conversation.UnreadCount)
+		enc.WriteUint32(e.conversation.MentionCount)
+		enc.WriteUint32(e.conversation.MentionMsgSeq)
+		enc.WriteUint8(boolToUint8(e.conversation.MentionAll))
+	}
+	return enc.Bytes()
+}
+
+func (e *conversationWalEntry) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if e.entryType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if e.uid, err = dec.String(); err != nil {
+		return err
+	}
+	if e.conversation.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if e.conversation.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if e.entryType != conversationWalEntryUpdate {
+		return nil
+	}
+	if e.conversation.Id, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if e.conversation.ReadedMsgSeq, err = dec.Uint32(); err != nil {
+		return err
+	}
+	var conversationType uint8
+	if conversationType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	e.conversation.ConversationType = wkdb.ConversationType(conversationType)
+	var createdAt, updatedAt int64
+	if createdAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	if updatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	e.conversation.CreatedAt = time.Unix(0, createdAt)
+	e.conversation.UpdatedAt = time.Unix(0, updatedAt)
+	if e.conversation.UnreadCount, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if e.conversation.MentionCount, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if e.conversation.MentionMsgSeq, err = dec.Uint32(); err != nil {
+		return err
+	}
+	var mentionAll uint8
+	if mentionAll, err = dec.Uint8(); err != nil {
+		return err
+	}
+	e.conversation.MentionAll = mentionAll == 1
+	return nil
+}
+
+func boolToUint8(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// conversationWalSegment 预写日志的一个分段文件
+type conversationWalSegment struct {
+	path    string
+	entries int64
+}
+
+// conversationWal 最近会话缓存的预写日志（每个conversationWorker一个）
+// 缓存变更后追加日志，缓存保存到数据库后删除已经保存的分段，启动时重放还未保存的日志
+type conversationWal struct {
+	dir     string
+	worker  int
+	file    *os.File
+	writer  *bufio.Writer
+	current *conversationWalSegment
+	sealed  []*conversationWalSegment
+	closed  bool
+
+	syncInterval time.Duration // 刷盘的最小间隔，0表示每次flush都刷盘
+	lastSync     time.Time
+	wklog.Log
+	sync.Mutex
+}
+
+func newConversationWal(dir string, worker int, syncInterval time.Duration) *conversationWal {
+	return &conversationWal{
+		dir:          dir,
+		worker:       worker,
+		syncInterval: syncInterval,
+		Log:          wklog.NewWKLog(fmt.Sprintf("conversationWal[%d]", worker)),
+	}
+}
+
+// append 追加日志（写入缓冲区，调用flush后才写入文件）
+func (w *conversationWal) append(entries ...*conversationWalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		return nil
+	}
+	if w.current == nil {
+		if err := w.openSegment(); err != nil {
+			return err
+		}
+	}
+
+	header := make([]byte, conversationWalHeaderSize)
+	for _, entry := range entries {
+		data := entry.Marshal()
+		binary.BigEndian.PutUint32(header[0:4], uint32(len(data)))
+		binary.BigEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(data))
+		if _, err := w.writer.Write(header); err != nil {
+			return err
+		}
+		if _, err := w.writer.Write(data); err != nil {
+			return err
+		}
+	}
+	w.current.entries += int64(len(entries))
+	trace.GlobalTrace.Metrics.App().ConversationWalLagAdd(int64(len(entries)))
+	return nil
+}
+
+// flush 将缓冲区的日志写入文件并刷盘，配置了刷盘间隔时距离上次刷盘不到间隔只写入文件
+func (w *conversationWal) flush() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.writer == nil {
+		return nil
+	}
+	if err := w.writer.Flush(); err != nil {
+		return err
+	}
+	if w.syncInterval > 0 && time.Since(w.lastSync) < w.syncInterval {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.lastSync = time.Now()
+	return nil
+}
+
+func (w *conversationWal) openSegment() error {
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return err
+	}
+	p := path.Join(w.dir, fmt.Sprintf("%020d-%d.wal", time.Now().UnixNano(), w.worker))
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = f
+	w.writer = bufio.NewWriter(f)
+	w.current = &conversationWalSegment{path: p}
+	return nil
+}
+
+// closeSegmentNotLock 将当前分段写入磁盘并封存
+func (w *conversationWal) closeSegmentNotLock() {
+	if w.current == nil {
+		return
+	}
+	if err := w.writer.Flush(); err != nil {
+		w.Error("flush wal err", zap.Error(err), zap.String("path", w.current.path))
+	}
+	if err := w.file.Sync(); err != nil {
+		w.Error("sync wal err", zap.Error(err), zap.String("path", w.current.path))
+	}
+	_ = w.file.Close()
+	w.sealed = append(w.sealed, w.current)
+	w.file = nil
+	w.writer = nil
+	w.current = nil
+}
+
+// seal 封存当前分段，返回所有已封存的分段（这些分段里的变更都已经在缓存里了）
+func (w *conversationWal) seal() []*conversationWalSegment {
+	w.Lock()
+	defer w.Unlock()
+	w.closeSegmentNotLock()
+	segments := make([]*conversationWalSegment, len(w.sealed))
+	copy(segments, w.sealed)
+	return segments
+}
+
+// checkpoint 分段里的变更已经保存到数据库，删除分段
+func (w *conversationWal) checkpoint(segments []*conversationWalSegment) error {
+	w.Lock()
+	defer w.Unlock()
+	var err error
+	for _, segment := range segments {
+		if rerr := os.Remove(segment.path); rerr != nil && !os.IsNotExist(rerr) {
+			err = rerr
+			continue
+		}
+		trace.GlobalTrace.Metrics.App().ConversationWalLagAdd(-segment.entries)
+		for i, s := range w.sealed {
+			if s == segment {
+				w.sealed = append(w.sealed[:i], w.sealed[i+1:]...)
+				break
+			}
+		}
+	}
+	return err
+}
+
+func (w *conversationWal) close() {
+	w.Lock()
+	defer w.Unlock()
+	w.closed = true
+	w.closeSegmentNotLock()
+}
+
+// conversationWalFiles 按写入顺序返回目录下的所有日志文件
+func conversationWalFiles(dir string) ([]string, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	files := make([]string, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ".wal") {
+			continue
+		}
+		files = append(files, dirEntry.Name())
+	}
+	sort.Strings(files) // 文件名以创建时间开头
+	for i, file := range files {
+		files[i] = path.Join(dir, file)
+	}
+	return files, nil
+}
+
+// readConversationWalFile 读取日志文件，文件末尾写了一半的条目会被忽略
+func readConversationWalFile(p string, fn func(entry *conversationWalEntry)) (int, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for len(data) > 0 {
+		if len(data) < conversationWalHeaderSize {
+			return count, io.ErrUnexpectedEOF
+		}
+		size := binary.BigEndian.Uint32(data[0:4])
+		checksum := binary.BigEndian.Uint32(data[4:8])
+		if uint64(len(data)-conversationWalHeaderSize) < uint64(size) {
+			return count, io.ErrUnexpectedEOF
+		}
+		entryData := data[conversationWalHeaderSize : conversationWalHeaderSize+int(size)]
+		if crc32.ChecksumIEEE(entryData) != checksum {
+			return count, errConversationWalCorrupt
+		}
+		entry := &conversationWalEntry{}
+		if err := entry.Unmarshal(entryData); err != nil {
+			return count, errConversationWalCorrupt
+		}
+		fn(entry)
+		count++
+		data = data[conversationWalHeaderSize+int(size):]
+	}
+	return count, nil
+}
@@ -193,19 +193,28 @@ func (d *deliverr) handleDeliverReq(req *deliverReq) {
 					uids:   tagResp.uids,
 					nodeId: d.dm.s.opts.Cluster.NodeId,
 				},
-			})
+			}, tagResp.large)
 		}
 
 	}
+	req.large = tg.large
 
 	// ================== 投递消息 ==================
 	for _, nodeUser := range tg.users {
 		if d.dm.s.opts.Cluster.NodeId == nodeUser.nodeId { // 只投递本节点的
-			// 更新最近会话
-			d.dm.s.conversationManager.Push(req.channelId, req.channelType, nodeUser.uids, req.messages)
-
-			// 投递消息
-			d.deliver(req, nodeUser.uids)
+			if req.large {
+				// 超大群的最近会话由接收者拉取，只更新发送者的最近会话
+				d.dm.s.conversationManager.Push(req.channelId, req.channelType, messageSenders(nodeUser.uids, req.messages), req.messages)
+
+				// 只投递在线用户
+				d.deliver(req, d.onlineUids(nodeUser.uids))
+			} else {
+				// 更新最近会话
+				d.dm.s.conversationManager.Push(req.channelId, req.channelType, nodeUser.uids, req.messages)
+
+				// 投递消息
+				d.deliver(req, nodeUser.uids)
+			}
 
 		} else { // 非本节点的转发给对应节点去投递
 			d.Debug("forward deliverReq to node", zap.Uint64("nodeId", nodeUser.nodeId), zap.String("tagKey", req.tagKey), zap.Strings("uids", nodeUser.uids), zap.String("channelId", req.channelId), zap.Uint8("channelType", req.channelType))
@@ -354,12 +363,61 @@ func (d *deliverr) deliver(req *deliverReq, uids []string) {
 		}
 	}
 
-	if len(offlineUids) > 0 { // 有离线用户，发送webhook
+	if len(offlineUids) > 0 && !req.large { // 有离线用户，发送webhook（超大群不推送离线）
+		// 免打扰的用户不推送离线（被@的除外）
+		mutedUids := d.dm.s.conversationManager.GetMutedUids(req.channelId, req.channelType, offlineUids)
 		for _, message := range req.messages {
+			toUids := offlineUids
+			if len(mutedUids) > 0 {
+				var mention *MessageMention
+				if d.dm.s.opts.Conversation.MentionIgnoreMute {
+					mention = parseMessageMention(message.SendPacket.Payload)
+				}
+				toUids = excludeMutedUids(offlineUids, mutedUids, mention)
+			}
+			if len(toUids) == 0 {
+				continue
+			}
+			d.dm.s.webhook.notifyOfflineMsg(message, toUids)
+		}
+	}
+}
 
-			d.dm.s.webhook.notifyOfflineMsg(message, offlineUids)
+// onlineUids 返回在线的用户
+func (d *deliverr) onlineUids(uids []string) []string {
+	online := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		if d.dm.s.userReactor.getUser(uid) != nil {
+			online = append(online, uid)
+		}
+	}
+	return online
+}
+
+// messageSenders 返回uids里发送了消息的用户
+func messageSenders(uids []string, messages []ReactorChannelMessage) []string {
+	var senders []string
+	for _, uid := range uids {
+		for _, message := range messages {
+			if message.FromUid == uid {
+				senders = append(senders, uid)
+				break
+			}
+		}
+	}
+	return senders
+}
+
+// excludeMutedUids 去掉免打扰的用户，被@的用户保留
+func excludeMutedUids(uids []string, mutedUids map[string]struct{}, mention *MessageMention) []string {
+	result := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		if _, ok := mutedUids[uid]; ok && !mention.IsMentioned(uid) {
+			continue
 		}
+		result = append(result, uid)
 	}
+	return result
 }
 
 // 加密消息
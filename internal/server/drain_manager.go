@@ -0,0 +1,228 @@
+package server
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/lni/goutils/syncutil"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+var ErrNodeDraining = errors.New("node is draining")
+
+type drainState string
+
+const (
+	drainStateNone      drainState = "none"      // 未排空
+	drainStateDraining  drainState = "draining"  // 排空中
+	drainStateDone      drainState = "done"      // 排空完成
+	drainStateTimeout   drainState = "timeout"   // 排空超时
+	drainStateCancelled drainState = "cancelled" // 服务已停止
+)
+
+// drainStatus 排空进度
+type drainStatus struct {
+	State          drainState `json:"state"`
+	StartedAt      int64      `json:"started_at"`      // 开始时间（秒）
+	Disconnected   int        `json:"disconnected"`    // 已断开的连接数
+	SlotLeaders    int        `json:"slot_leaders"`    // 剩余的槽领导数量
+	ChannelLeaders int        `json:"channel_leaders"` // 剩余的频道领导数量
+	ConnCount      int        `json:"conn_count"`      // 剩余的连接数
+}
+
+// drainManager 节点排空，迁出领导和客户端连接后再退出，用于滚动升级
+type drainManager struct {
+	s        *Server
+	draining atomic.Bool // 是否处于排空状态（排空超时后仍然保持，不再接受新连接）
+	running  atomic.Bool // 排空流程是否在执行
+	mu       sync.RWMutex
+	status   drainStatus
+	doneC    chan struct{}
+	stopper  *syncutil.Stopper
+	wklog.Log
+}
+
+func newDrainManager(s *Server) *drainManager {
+	return &drainManager{
+		s:       s,
+		status:  drainStatus{State: drainStateNone},
+		doneC:   make(chan struct{}),
+		stopper: syncutil.NewStopper(),
+		Log:     wklog.NewWKLog("drainManager"),
+	}
+}
+
+func (d *drainManager) stop() {
+	d.stopper.Stop()
+}
+
+func (d *drainManager) isDraining() bool {
+	return d.draining.Load()
+}
+
+// drain 开始排空（排空进行中重复调用会被忽略，排空超时后再次调用会重新尝试迁移领导）
+func (d *drainManager) drain() error {
+	if d.getStatus().State == drainStateDone {
+		return nil
+	}
+	if !d.running.CompareAndSwap(false, true) {
+		return nil
+	}
+	if !d.draining.Load() && d.s.opts.ClusterOn() {
+		err := d.s.clusterServer.StartDrain()
+		if err != nil {
+			d.running.Store(false)
+			return err
+		}
+	}
+	d.draining.Store(true)
+	d.mu.Lock()
+	d.status = drainStatus{State: drainStateDraining, StartedAt: time.Now().Unix()}
+	d.mu.Unlock()
+
+	d.Info("开始排空节点", zap.Uint64("nodeId", d.s.opts.Cluster.NodeId))
+	d.stopper.RunWorker(d.loop)
+	return nil
+}
+
+// done 排空成功后关闭（排空超时不会关闭，节点继续运行直到领导全部迁出）
+func (d *drainManager) done() <-chan struct{} {
+	return d.doneC
+}
+
+func (d *drainManager) getStatus() drainStatus {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.status
+}
+
+func (d *drainManager) loop() {
+	deadline := time.Now().Add(d.s.opts.Drain.Timeout)
+
+	// 先发起领导迁移，迁移过程中断开客户端连接
+	d.transferLeaders()
+
+	if !d.disconnectConns(deadline) {
+		d.finish(drainStateCancelled)
+		return
+	}
+
+	tk := time.NewTicker(time.Second * 2)
+	defer tk.Stop()
+	for {
+		if d.updateLeaderCount() == 0 {
+			d.finish(drainStateDone)
+			return
+		}
+		if time.Now().After(deadline) {
+			d.Error("排空超时，仍然有未迁移的领导，节点继续运行，可以再次发起排空", zap.Any("status", d.getStatus()))
+			d.finish(drainStateTimeout)
+			return
+		}
+		select {
+		case <-tk.C:
+			d.transferLeaders()
+		case <-d.stopper.ShouldStop():
+			d.finish(drainStateCancelled)
+			return
+		}
+	}
+}
+
+func (d *drainManager) finish(state drainState) {
+	d.mu.Lock()
+	d.status.State = state
+	d.status.ConnCount = d.s.engine.ConnCount()
+	d.mu.Unlock()
+	d.running.Store(false)
+	d.Info("节点排空结束", zap.String("state", string(state)))
+	if state == drainStateDone { // 只有领导全部迁出才允许退出
+		close(d.doneC)
+	}
+}
+
+func (d *drainManager) transferLeaders() {
+	if !d.s.opts.ClusterOn() {
+		return
+	}
+	err := d.s.clusterServer.TransferLeaders()
+	if err != nil {
+		d.Warn("迁移领导失败", zap.Error(err))
+	}
+}
+
+// updateLeaderCount 更新并返回剩余的领导数量
+func (d *drainManager) updateLeaderCount() int {
+	if !d.s.opts.ClusterOn() {
+		return 0
+	}
+	slotCount, channelCount := d.s.clusterServer.LeaderCount()
+	d.mu.Lock()
+	d.status.SlotLeaders = slotCount
+	d.status.ChannelLeaders = channelCount
+	d.status.ConnCount = d.s.engine.ConnCount()
+	d.mu.Unlock()
+	return slotCount + channelCount
+}
+
+// disconnectConns 分批断开客户端连接，并告知客户端可以重连的地址
+func (d *drainManager) disconnectConns(deadline time.Time) bool {
+	conns := d.s.userReactor.getRealConnContexts()
+	d.Info("断开客户端连接", zap.Int("connCount", len(conns)))
+
+	batchSize := d.s.opts.Drain.BatchSize
+	if batchSize <= 0 {
+		batchSize = len(conns)
+	}
+	for i := 0; i < len(conns); i += batchSize {
+		end := i + batchSize
+		if end > len(conns) {
+			end = len(conns)
+		}
+		for _, conn := range conns[i:end] {
+			_ = d.s.userReactor.writePacket(conn, &wkproto.DisconnectPacket{
+				ReasonCode: wkproto.ReasonConnectKick,
+				Reason:     d.redirectHint(conn.uid),
+			})
+			c := conn
+			d.s.timingWheel.AfterFunc(time.Second*2, func() {
+				c.close()
+			})
+		}
+		d.mu.Lock()
+		d.status.Disconnected += end - i
+		d.mu.Unlock()
+
+		if end >= len(conns) || time.Now().After(deadline) {
+			break
+		}
+		select {
+		case <-time.After(d.s.opts.Drain.BatchInterval):
+		case <-d.stopper.ShouldStop():
+			return false
+		}
+	}
+	return true
+}
+
+// redirectHint 客户端重连地址提示，格式：{"redirect":[{"node_id":2,"tcp_addr":"...","ws_addr":"...","wss_addr":"..."}]}
+func (d *drainManager) redirectHint(uid string) string {
+	nodes := make([]*routeNode, 0)
+	for _, node := range d.s.routeManager.route(uid) {
+		if node.NodeId == d.s.opts.Cluster.NodeId {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 0 {
+		return ""
+	}
+	return wkutil.ToJSON(map[string]interface{}{
+		"redirect": newRouteAddrResps(nodes),
+	})
+}
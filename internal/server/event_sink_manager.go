@@ -0,0 +1,327 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/eventsink"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/lni/goutils/syncutil"
+	"go.uber.org/zap"
+)
+
+// eventSinkManager 将事件投递到消息中间件
+// 事件先写入本地队列，每个投递目标按自己的进度（checkpoint）顺序投递，确认后才更新进度，所以是至少一次投递
+type eventSinkManager struct {
+	s     *Server
+	sinks []eventsink.EventSink
+
+	appendMu sync.Mutex // 保证事件id的分配顺序和写入顺序一致
+
+	checkpointMu sync.Mutex
+	checkpoints  map[string]uint64 // 每个投递目标的进度
+
+	notifyCs []chan struct{} // 每个投递目标一个，有新事件时通知
+	stopper  *syncutil.Stopper
+	wklog.Log
+}
+
+func newEventSinkManager(s *Server) *eventSinkManager {
+	opts := s.opts.EventSink
+	sinks := make([]eventsink.EventSink, 0)
+	if opts.NATS.On {
+		sinks = append(sinks, eventsink.NewNatsSink(eventsink.NatsOptions{
+			URL:        opts.NATS.URL,
+			Stream:     opts.NATS.Stream,
+			Subject:    opts.NATS.Subject,
+			Partitions: opts.Partitions,
+		}))
+	}
+	if opts.Kafka.On {
+		sinks = append(sinks, eventsink.NewKafkaSink(eventsink.KafkaOptions{
+			Brokers: opts.Kafka.Brokers,
+			Topic:   opts.Kafka.Topic,
+		}))
+	}
+	if opts.Redis.On {
+		sinks = append(sinks, eventsink.NewRedisSink(eventsink.RedisOptions{
+			Addr:       opts.Redis.Addr,
+			Password:   opts.Redis.Password,
+			DB:         opts.Redis.DB,
+			Stream:     opts.Redis.Stream,
+			Partitions: opts.Partitions,
+			MaxLen:     opts.Redis.MaxLen,
+		}))
+	}
+	notifyCs := make([]chan struct{}, 0, len(sinks))
+	for range sinks {
+		notifyCs = append(notifyCs, make(chan struct{}, 1))
+	}
+	return &eventSinkManager{
+		s:           s,
+		sinks:       sinks,
+		checkpoints: make(map[string]uint64),
+		notifyCs:    notifyCs,
+		stopper:     syncutil.NewStopper(),
+		Log:         wklog.NewWKLog("eventSinkManager"),
+	}
+}
+
+func (e *eventSinkManager) start() error {
+	if len(e.sinks) == 0 {
+		return nil
+	}
+	for _, sink := range e.sinks {
+		checkpoint, err := e.s.store.GetEventSinkCheckpoint(sink.Name())
+		if err != nil {
+			return err
+		}
+		e.checkpoints[sink.Name()] = checkpoint
+	}
+	for i, sink := range e.sinks {
+		sk, notifyC := sink, e.notifyCs[i]
+		e.stopper.RunWorker(func() {
+			e.loop(sk, notifyC)
+		})
+	}
+	e.stopper.RunWorker(e.loopGC)
+	return nil
+}
+
+func (e *eventSinkManager) stop() {
+	e.stopper.Stop()
+	for _, sink := range e.sinks {
+		sink.Stop()
+	}
+}
+
+// appendMessages 追加消息通知事件，每条消息一个事件，分区键为消息所在的频道
+func (e *eventSinkManager) appendMessages(messages []wkdb.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	events := make([]wkdb.SinkEvent, 0, len(messages))
+	for _, msg := range messages {
+		resp := &MessageResp{}
+		resp.from(msg, e.s)
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return err
+		}
+		events = append(events, wkdb.SinkEvent{
+			Type:      EventMsgNotify,
+			Key:       wkutil.ChannelToKey(msg.ChannelID, msg.ChannelType),
+			Timestamp: time.Now().UnixMilli(),
+			Data:      data,
+		})
+	}
+	return e.append(events)
+}
+
+// appendMessagesWithRetry 追加消息通知事件，失败后阻塞重试直到成功或服务停止
+// 在频道的存储流程里同步调用，事件写入后才返回发送结果，所以同一个频道的事件按消息顺序写入，
+// 服务停止时返回错误，发送方收不到成功的回执会重发消息
+func (e *eventSinkManager) appendMessagesWithRetry(messages []wkdb.Message) error {
+	retryCount := 0
+	for {
+		err := e.appendMessages(messages)
+		if err == nil {
+			return nil
+		}
+		retryCount++
+		e.Warn("追加消息通知事件失败，稍后重试！", zap.Error(err), zap.Int("count", len(messages)), zap.Int("retryCount", retryCount))
+		if !e.sleep(e.retryBackoff(retryCount)) {
+			return err
+		}
+	}
+}
+
+// appendEvent 追加webhook事件
+func (e *eventSinkManager) appendEvent(event *Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	key := event.Key
+	if key == "" {
+		key = event.Event
+	}
+	return e.append([]wkdb.SinkEvent{{
+		Type:      event.Event,
+		Key:       key,
+		Timestamp: time.Now().UnixMilli(),
+		Data:      data,
+	}})
+}
+
+func (e *eventSinkManager) append(events []wkdb.SinkEvent) error {
+	e.appendMu.Lock()
+	for i := range events {
+		events[i].Id = e.s.store.NextPrimaryKey()
+	}
+	err := e.s.store.AppendEventSinkEvents(events)
+	e.appendMu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, notifyC := range e.notifyCs {
+		select {
+		case notifyC <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// loop 按进度顺序投递事件，投递失败后从原进度重试
+func (e *eventSinkManager) loop(sink eventsink.EventSink, notifyC chan struct{}) {
+	retryCount := 0
+	for {
+		err := sink.Start()
+		if err == nil {
+			break
+		}
+		retryCount++
+		e.Warn("连接消息中间件失败！", zap.Error(err), zap.String("sink", sink.Name()))
+		if !e.sleep(e.retryBackoff(retryCount)) {
+			return
+		}
+	}
+	e.Info("开始投递事件", zap.String("sink", sink.Name()))
+
+	opts := e.s.opts.EventSink
+	retryCount = 0
+	for {
+		checkpoint := e.checkpoint(sink.Name())
+		events, err := e.s.store.GetEventSinkEvents(checkpoint, opts.BatchSize)
+		if err != nil {
+			e.Error("获取待投递的事件失败！", zap.Error(err), zap.String("sink", sink.Name()))
+			if !e.sleep(opts.RetryBackoff) {
+				return
+			}
+			continue
+		}
+		if len(events) == 0 {
+			if !e.wait(notifyC, opts.Interval) {
+				return
+			}
+			continue
+		}
+		sinkEvents := make([]eventsink.Event, 0, len(events))
+		for _, event := range events {
+			sinkEvents = append(sinkEvents, eventsink.Event{
+				Id:        event.Id,
+				Type:      event.Type,
+				Key:       event.Key,
+				Timestamp: event.Timestamp,
+				Data:      event.Data,
+			})
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		err = sink.Publish(ctx, sinkEvents)
+		cancel()
+		if err != nil {
+			retryCount++
+			e.Warn("投递事件失败！", zap.Error(err), zap.String("sink", sink.Name()), zap.Int("retryCount", retryCount))
+			if !e.sleep(e.retryBackoff(retryCount)) {
+				return
+			}
+			continue
+		}
+		retryCount = 0
+		lastId := events[len(events)-1].Id
+		err = e.s.store.SetEventSinkCheckpoint(sink.Name(), lastId)
+		if err != nil { // 进度没保存成功，重启后会重复投递
+			e.Warn("保存投递进度失败！", zap.Error(err), zap.String("sink", sink.Name()))
+		}
+		e.setCheckpoint(sink.Name(), lastId)
+	}
+}
+
+// loopGC 删除所有投递目标都已确认的事件
+func (e *eventSinkManager) loopGC() {
+	tk := time.NewTicker(time.Minute)
+	defer tk.Stop()
+	var lastGCId uint64
+	for {
+		select {
+		case <-tk.C:
+			minId := e.minCheckpoint()
+			if minId <= lastGCId {
+				continue
+			}
+			err := e.s.store.RemoveEventSinkEvents(minId)
+			if err != nil {
+				e.Warn("删除已投递的事件失败！", zap.Error(err))
+				continue
+			}
+			lastGCId = minId
+		case <-e.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+func (e *eventSinkManager) checkpoint(name string) uint64 {
+	e.checkpointMu.Lock()
+	defer e.checkpointMu.Unlock()
+	return e.checkpoints[name]
+}
+
+func (e *eventSinkManager) setCheckpoint(name string, id uint64) {
+	e.checkpointMu.Lock()
+	defer e.checkpointMu.Unlock()
+	e.checkpoints[name] = id
+}
+
+func (e *eventSinkManager) minCheckpoint() uint64 {
+	e.checkpointMu.Lock()
+	defer e.checkpointMu.Unlock()
+	var minId uint64
+	first := true
+	for _, id := range e.checkpoints {
+		if first || id < minId {
+			minId = id
+			first = false
+		}
+	}
+	return minId
+}
+
+// wait 等待新事件或者超时，服务停止返回false
+func (e *eventSinkManager) wait(notifyC chan struct{}, d time.Duration) bool {
+	select {
+	case <-notifyC:
+		return true
+	case <-time.After(d):
+		return true
+	case <-e.stopper.ShouldStop():
+		return false
+	}
+}
+
+func (e *eventSinkManager) sleep(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-e.stopper.ShouldStop():
+		return false
+	}
+}
+
+// retryBackoff 第retryCount次失败后的重试间隔
+func (e *eventSinkManager) retryBackoff(retryCount int) time.Duration {
+	backoff := e.s.opts.EventSink.RetryBackoff
+	maxBackoff := e.s.opts.EventSink.RetryBackoffMax
+	for i := 1; i < retryCount; i++ {
+		backoff *= 2
+		if maxBackoff > 0 && backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return backoff
+}
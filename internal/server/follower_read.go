@@ -0,0 +1,85 @@
+package server
+
+import (
+	"math/rand"
+	"strconv"
+	"time"
+
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderReadNode     = "X-WuKongIM-Read-Node"     // 返回数据的节点ID
+	HeaderReadRole     = "X-WuKongIM-Read-Role"     // 返回数据的副本角色 leader 或 follower
+	HeaderFollowerRead = "X-WuKongIM-Follower-Read" // 已经转发给副本的读请求，副本不满足条件时只能转发给领导
+)
+
+const (
+	readRoleLeader   = "leader"
+	readRoleFollower = "follower"
+)
+
+// syncReadNode 消息同步（读请求）的处理节点
+// 开启从副本读取后，本节点是同步及时的副本则直接读取，否则随机转发给其他副本，副本也不满足条件时再转发给领导
+// forwarded 表示请求已经从其他节点转发过来，不再转发给其他副本
+func (s *Server) syncReadNode(channelId string, channelType uint8, forwarded bool) (uint64, string, error) {
+	cfg, err := s.clusterServer.ChannelClusterConfigForRead(channelId, channelType)
+	if err != nil {
+		return 0, "", err
+	}
+	if cfg.LeaderId == 0 {
+		return 0, "", cluster.ErrNotLeader
+	}
+	nodeId := s.opts.Cluster.NodeId
+	if cfg.LeaderId == nodeId || !s.opts.Cluster.FollowerReadOn {
+		return cfg.LeaderId, readRoleLeader, nil
+	}
+
+	if s.followerReadable(channelId, channelType) {
+		return nodeId, readRoleFollower, nil
+	}
+
+	if !forwarded {
+		candidates := make([]uint64, 0, len(cfg.Replicas))
+		for _, replicaId := range cfg.Replicas {
+			if replicaId == nodeId || replicaId == cfg.LeaderId {
+				continue
+			}
+			if !s.cluster.NodeIsOnline(replicaId) {
+				continue
+			}
+			candidates = append(candidates, replicaId)
+		}
+		if len(candidates) > 0 {
+			return candidates[rand.Intn(len(candidates))], readRoleFollower, nil
+		}
+	}
+	return cfg.LeaderId, readRoleLeader, nil
+}
+
+// followerReadable 本节点作为频道的追随者，数据是否足够新可以直接读取
+func (s *Server) followerReadable(channelId string, channelType uint8) bool {
+	if !s.opts.Cluster.FollowerReadOn {
+		return false
+	}
+	state, ok, err := s.clusterServer.ChannelReadState(channelId, channelType)
+	if err != nil {
+		s.Warn("获取频道副本读状态失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		return false
+	}
+	if !ok || state.IsLeader || state.LastLeaderContact.IsZero() {
+		return false
+	}
+	if time.Since(state.LastLeaderContact) > s.opts.Cluster.FollowerReadMaxLagTime {
+		return false
+	}
+	return state.LagIndex() <= s.opts.Cluster.FollowerReadMaxLagIndex
+}
+
+// setReadHeader 设置返回数据的节点信息
+func (s *Server) setReadHeader(c *wkhttp.Context, role string) {
+	c.Header(HeaderReadNode, strconv.FormatUint(s.opts.Cluster.NodeId, 10))
+	c.Header(HeaderReadRole, role)
+}
@@ -0,0 +1,822 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterstore"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/client"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/lni/goutils/syncutil"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrMirrorStandbyReadOnly = errors.New("mirror standby is read only")
+	ErrMirrorNotStandby      = errors.New("mirror is not standby")
+)
+
+const (
+	mirrorConnAuthKey       = "mirrorAuthed"
+	mirrorCheckpointPromote = "promoted"
+)
+
+func mirrorSlotCheckpointName(slotId uint32) string {
+	return fmt.Sprintf("slot:%d", slotId)
+}
+
+func mirrorChannelCheckpointName(channelId string, channelType uint8) string {
+	return fmt.Sprintf("msg:%s", wkutil.ChannelToKey(channelId, channelType))
+}
+
+// mirrorManager 跨集群镜像
+// 主集群：槽领导将已应用的槽日志（用户、频道、订阅者、会话等）、频道领导将已存储的消息异步发送到备集群
+// 备集群：接收镜像数据并在本集群提案，按槽和频道记录已应用的进度，主集群断线重连或领导切换后从备集群的进度继续发送
+type mirrorManager struct {
+	s        *Server
+	promoted atomic.Bool
+
+	// ---------- primary ----------
+	client          *client.Client
+	connected       atomic.Bool
+	notifyC         chan struct{}
+	slotCheckpoints map[uint32]uint64 // 备集群的槽进度缓存，只在发送协程内访问
+
+	channelCursor wkdb.MirrorChannel // 遍历等待镜像的频道的位置，只在发送协程内访问
+
+	statusMu sync.RWMutex
+	status   mirrorStatus
+
+	// ---------- standby ----------
+	server  *wkserver.Server
+	applyMu sync.Mutex
+
+	stopper *syncutil.Stopper
+	wklog.Log
+}
+
+func newMirrorManager(s *Server) *mirrorManager {
+	return &mirrorManager{
+		s:               s,
+		notifyC:         make(chan struct{}, 1),
+		slotCheckpoints: make(map[uint32]uint64),
+		status: mirrorStatus{
+			NodeId: s.opts.Cluster.NodeId,
+			Role:   s.opts.Mirror.Role,
+		},
+		stopper: syncutil.NewStopper(),
+		Log:     wklog.NewWKLog("mirrorManager"),
+	}
+}
+
+func (m *mirrorManager) start() error {
+	if !m.s.opts.Mirror.On {
+		return nil
+	}
+	promoted, err := m.s.store.GetMirrorCheckpoint(mirrorCheckpointPromote)
+	if err != nil {
+		return err
+	}
+	m.promoted.Store(promoted == 1)
+
+	switch m.s.opts.Mirror.Role {
+	case MirrorRoleStandby:
+		if m.promoted.Load() { // 已提升为主集群，不再接收镜像数据
+			return nil
+		}
+		m.server = wkserver.New(m.s.opts.Mirror.Addr)
+		m.server.Route("/conn", m.handleConn)
+		m.server.Route("/mirror/checkpoints", m.handleCheckpoints)
+		m.server.Route("/mirror/slot", m.handleSlot)
+		m.server.Route("/mirror/messages", m.handleMessages)
+		return m.server.Start()
+	case MirrorRolePrimary:
+		m.client = client.New(
+			strings.ReplaceAll(m.s.opts.Mirror.Target, "tcp://", ""),
+			client.WithUID(fmt.Sprintf("mirror-%d", m.s.opts.Cluster.NodeId)),
+			client.WithToken(m.s.opts.Mirror.Token),
+			client.WithRequestTimeout(time.Second*10),
+			client.WithOnConnectStatus(func(status client.ConnectStatus) {
+				m.connected.Store(status == client.CONNECTED)
+			}),
+		)
+		m.client.Start()
+		m.stopper.RunWorker(m.loopPrimary)
+	}
+	return nil
+}
+
+func (m *mirrorManager) stop() {
+	m.stopper.Stop()
+	if m.client != nil {
+		_ = m.client.Close()
+	}
+	if m.server != nil {
+		m.server.Stop()
+	}
+}
+
+// readOnly 未提升的备集群只读，不接受客户端连接和发送消息
+func (m *mirrorManager) readOnly() bool {
+	opts := m.s.opts.Mirror
+	return opts.On && opts.Role == MirrorRoleStandby && !m.promoted.Load()
+}
+
+// mirrorWritePaths 写数据的接口，未提升的备集群的数据只来自主集群的镜像，不允许调用
+var mirrorWritePaths = map[string]struct{}{
+	"/user/token":                 {},
+	"/user/device_quit":           {},
+	"/user/systemuids_add":        {},
+	"/user/systemuids_remove":     {},
+	"/user/data/erase":            {},
+	"/channel":                    {},
+	"/channel/info":               {},
+	"/channel/delete":             {},
+	"/channel/subscriber_add":     {},
+	"/channel/subscriber_remove":  {},
+	"/channel/blacklist_add":      {},
+	"/channel/blacklist_set":      {},
+	"/channel/blacklist_remove":   {},
+	"/channel/whitelist_add":      {},
+	"/channel/whitelist_set":      {},
+	"/channel/whitelist_remove":   {},
+	"/conversations/clearUnread":  {},
+	"/conversations/setUnread":    {},
+	"/conversations/delete":       {},
+	"/conversations/extra/set":    {},
+	"/message/send":               {},
+	"/message/sendbatch":          {},
+	"/message/sync":               {},
+	"/message/syncack":            {},
+	"/message/reaction/add":       {},
+	"/message/reaction/remove":    {},
+	"/webhook/deadletters/replay": {},
+}
+
+// readOnlyMiddleware 未提升的备集群拒绝所有写接口
+func (m *mirrorManager) readOnlyMiddleware() wkhttp.HandlerFunc {
+	return func(c *wkhttp.Context) {
+		if !m.readOnly() {
+			c.Next()
+			return
+		}
+		if _, ok := mirrorWritePaths[c.Request.URL.Path]; ok {
+			c.ResponseError(ErrMirrorStandbyReadOnly)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// notifyMessages 频道有新消息存储，通知发送协程（primary）
+// 频道的镜像标记由存储在追加消息的同一个批次里写入（每个副本都有），这里只是让发送协程尽快发送
+func (m *mirrorManager) notifyMessages() {
+	opts := m.s.opts.Mirror
+	if !opts.On || opts.Role != MirrorRolePrimary {
+		return
+	}
+	select {
+	case m.notifyC <- struct{}{}:
+	default:
+	}
+}
+
+// promote 将备集群提升为主集群，提升后不再接收镜像数据并开始接受写入
+func (m *mirrorManager) promote() error {
+	if m.s.opts.Mirror.Role != MirrorRoleStandby {
+		return ErrMirrorNotStandby
+	}
+	if m.promoted.Load() {
+		return nil
+	}
+	// 先停止接收，避免提升后还有镜像数据写入
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+	if m.server != nil {
+		m.server.Stop()
+		m.server = nil
+	}
+	err := m.s.store.SetMirrorCheckpoint(mirrorCheckpointPromote, 1)
+	if err != nil {
+		return err
+	}
+	m.promoted.Store(true)
+	m.Info("备集群已提升为主集群", zap.Uint64("nodeId", m.s.opts.Cluster.NodeId))
+	return nil
+}
+
+func (m *mirrorManager) getStatus() mirrorStatus {
+	m.statusMu.RLock()
+	status := m.status
+	status.Slots = append([]*mirrorSlotStatus(nil), m.status.Slots...)
+	m.statusMu.RUnlock()
+
+	status.Promoted = m.promoted.Load()
+	status.Connected = m.connected.Load()
+	return status
+}
+
+// ================================== primary ==================================
+
+func (m *mirrorManager) loopPrimary() {
+	opts := m.s.opts.Mirror
+	errBackoff := opts.Interval
+	tk := time.NewTicker(opts.Interval)
+	defer tk.Stop()
+	for {
+		hasMore, err := m.syncOnce()
+		if err != nil {
+			m.Warn("镜像数据到备集群失败！", zap.Error(err), zap.String("target", opts.Target))
+			m.statusMu.Lock()
+			m.status.LastError = err.Error()
+			m.status.LastErrorAt = time.Now().UnixMilli()
+			m.statusMu.Unlock()
+
+			select {
+			case <-time.After(errBackoff):
+			case <-m.stopper.ShouldStop():
+				return
+			}
+			if errBackoff < time.Second*30 {
+				errBackoff *= 2
+			}
+			continue
+		}
+		errBackoff = opts.Interval
+		if hasMore {
+			select {
+			case <-m.stopper.ShouldStop():
+				return
+			default:
+			}
+			continue
+		}
+		select {
+		case <-tk.C:
+		case <-m.notifyC:
+		case <-m.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+func (m *mirrorManager) syncOnce() (bool, error) {
+	if !m.connected.Load() {
+		return false, nil
+	}
+	slotHasMore, err := m.syncSlots()
+	if err != nil {
+		return false, err
+	}
+	channelHasMore, err := m.syncChannels()
+	if err != nil {
+		return false, err
+	}
+	return slotHasMore || channelHasMore, nil
+}
+
+// syncSlots 将当前节点领导的槽已应用的日志发送到备集群
+func (m *mirrorManager) syncSlots() (bool, error) {
+	cfg := m.s.clusterServer.GetConfig()
+	leaderSlotIds := make([]uint32, 0)
+	for _, slot := range cfg.Slots {
+		if slot.Leader == m.s.opts.Cluster.NodeId {
+			leaderSlotIds = append(leaderSlotIds, slot.Id)
+		}
+	}
+
+	// 不再领导的槽清除进度缓存，重新成为领导后从备集群获取最新进度
+	unknownNames := make([]string, 0)
+	for slotId := range m.slotCheckpoints {
+		if !wkutil.ArrayContainsUint32(leaderSlotIds, slotId) {
+			delete(m.slotCheckpoints, slotId)
+		}
+	}
+	for _, slotId := range leaderSlotIds {
+		if _, ok := m.slotCheckpoints[slotId]; !ok {
+			unknownNames = append(unknownNames, mirrorSlotCheckpointName(slotId))
+		}
+	}
+	if len(unknownNames) > 0 {
+		checkpoints, err := m.requestCheckpoints(unknownNames)
+		if err != nil {
+			return false, err
+		}
+		for _, slotId := range leaderSlotIds {
+			if _, ok := m.slotCheckpoints[slotId]; !ok {
+				m.slotCheckpoints[slotId] = checkpoints[mirrorSlotCheckpointName(slotId)]
+			}
+		}
+	}
+
+	batchSize := uint64(m.s.opts.Mirror.BatchSize)
+	hasMore := false
+	slotStatuses := make([]*mirrorSlotStatus, 0, len(leaderSlotIds))
+	var slotLag uint64
+	defer func() {
+		m.statusMu.Lock()
+		m.status.Slots = slotStatuses
+		m.status.SlotLag = slotLag
+		m.statusMu.Unlock()
+	}()
+	for _, slotId := range leaderSlotIds {
+		appliedIndex, err := m.s.clusterServer.SlotAppliedIndex(slotId)
+		if err != nil {
+			return false, err
+		}
+		checkpoint := m.slotCheckpoints[slotId]
+		if appliedIndex > checkpoint {
+			endIndex := appliedIndex
+			if endIndex-checkpoint > batchSize {
+				endIndex = checkpoint + batchSize
+				hasMore = true
+			}
+			logs, err := m.s.clusterServer.SlotLogs(slotId, checkpoint+1, endIndex+1, 0)
+			if err != nil {
+				return false, err
+			}
+			if len(logs) > 0 {
+				req := &mirrorSlotReq{
+					SlotId:    slotId,
+					SlotCount: cfg.SlotCount,
+					Logs:      make([]mirrorLog, 0, len(logs)),
+				}
+				for _, lg := range logs {
+					req.Logs = append(req.Logs, mirrorLog{Index: lg.Index, Data: mirrorSlotLogData(lg.Data)})
+				}
+				err = m.requestSlot(req)
+				if err != nil {
+					delete(m.slotCheckpoints, slotId) // 重新获取备集群的进度
+					return false, err
+				}
+				checkpoint = logs[len(logs)-1].Index
+				m.slotCheckpoints[slotId] = checkpoint
+				m.syncSuccess()
+			} else {
+				m.Warn("槽日志不存在，无法镜像", zap.Uint32("slotId", slotId), zap.Uint64("checkpoint", checkpoint), zap.Uint64("appliedIndex", appliedIndex))
+			}
+		}
+		lag := appliedIndex - min(checkpoint, appliedIndex)
+		slotLag += lag
+		slotStatuses = append(slotStatuses, &mirrorSlotStatus{
+			SlotId:       slotId,
+			AppliedIndex: appliedIndex,
+			Checkpoint:   checkpoint,
+			Lag:          lag,
+		})
+	}
+	return hasMore, nil
+}
+
+// mirrorSlotLogData 备集群需要应用的槽日志数据，频道分布式配置是集群自身的，不需要镜像
+func mirrorSlotLogData(data []byte) []byte {
+	if len(data) == 0 {
+		return nil
+	}
+	cmd := &clusterstore.CMD{}
+	if err := cmd.Unmarshal(data); err != nil {
+		return data
+	}
+	switch cmd.CmdType {
+	case clusterstore.CMDChannelClusterConfigSave, clusterstore.CMDChannelClusterConfigDelete:
+		return nil
+	}
+	return data
+}
+
+// syncChannels 将当前节点领导的频道的新消息发送到备集群
+// 频道的每个副本都有镜像标记，只有领导发送，其他副本在备集群的进度追上后移除标记，领导切换后由新的领导继续发送
+func (m *mirrorManager) syncChannels() (bool, error) {
+	batchSize := m.s.opts.Mirror.BatchSize
+	channels, err := m.s.store.GetMirrorChannels(m.channelCursor.ChannelId, m.channelCursor.ChannelType, batchSize)
+	if err != nil {
+		return false, err
+	}
+	// 分页遍历所有标记，避免非领导的频道一直占用前面的批次
+	hasMore := len(channels) >= batchSize
+	if hasMore {
+		m.channelCursor = channels[len(channels)-1]
+	} else {
+		m.channelCursor = wkdb.MirrorChannel{}
+	}
+	m.statusMu.Lock()
+	m.status.PendingChannels = len(channels)
+	m.statusMu.Unlock()
+	if len(channels) == 0 {
+		m.statusMu.Lock()
+		m.status.MessageLag = 0
+		m.statusMu.Unlock()
+		return false, nil
+	}
+
+	names := make([]string, 0, len(channels))
+	for _, ch := range channels {
+		names = append(names, mirrorChannelCheckpointName(ch.ChannelId, ch.ChannelType))
+	}
+	checkpoints, err := m.requestCheckpoints(names)
+	if err != nil {
+		return false, err
+	}
+
+	var messageLag uint64
+	for _, ch := range channels {
+		checkpoint := checkpoints[mirrorChannelCheckpointName(ch.ChannelId, ch.ChannelType)]
+		if checkpoint >= ch.MessageSeq {
+			if err = m.s.store.RemoveMirrorChannel(ch.ChannelId, ch.ChannelType, checkpoint); err != nil {
+				return false, err
+			}
+			continue
+		}
+		if !m.isChannelLeader(ch.ChannelId, ch.ChannelType) {
+			continue
+		}
+		messageLag += ch.MessageSeq - checkpoint
+		messages, err := m.s.store.LoadNextRangeMsgs(ch.ChannelId, ch.ChannelType, checkpoint+1, 0, batchSize)
+		if err != nil {
+			return false, err
+		}
+		if len(messages) == 0 { // 本节点已没有这些消息（比如已不是频道的副本）
+			m.Warn("频道消息不存在，无法镜像", zap.String("channelId", ch.ChannelId), zap.Uint8("channelType", ch.ChannelType), zap.Uint64("checkpoint", checkpoint), zap.Uint64("messageSeq", ch.MessageSeq))
+			if err = m.s.store.RemoveMirrorChannel(ch.ChannelId, ch.ChannelType, ch.MessageSeq); err != nil {
+				return false, err
+			}
+			continue
+		}
+		err = m.requestMessages(&mirrorMessagesReq{
+			ChannelId:   ch.ChannelId,
+			ChannelType: ch.ChannelType,
+			Messages:    messages,
+		})
+		if err != nil {
+			return false, err
+		}
+		m.syncSuccess()
+		lastSeq := uint64(messages[len(messages)-1].MessageSeq)
+		messageLag -= min(lastSeq-checkpoint, messageLag)
+		if lastSeq >= ch.MessageSeq {
+			if err = m.s.store.RemoveMirrorChannel(ch.ChannelId, ch.ChannelType, lastSeq); err != nil {
+				return false, err
+			}
+		} else {
+			hasMore = true
+		}
+	}
+	m.statusMu.Lock()
+	m.status.MessageLag = messageLag
+	m.statusMu.Unlock()
+	return hasMore, nil
+}
+
+// isChannelLeader 当前节点是否是频道的领导
+func (m *mirrorManager) isChannelLeader(channelId string, channelType uint8) bool {
+	if !m.s.opts.ClusterOn() {
+		return true
+	}
+	leader, err := m.s.cluster.LeaderOfChannelForRead(channelId, channelType)
+	if err != nil {
+		m.Debug("获取频道领导失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		return false
+	}
+	return leader.Id == m.s.opts.Cluster.NodeId
+}
+
+func (m *mirrorManager) syncSuccess() {
+	m.statusMu.Lock()
+	m.status.LastSyncAt = time.Now().UnixMilli()
+	m.status.LastError = ""
+	m.statusMu.Unlock()
+}
+
+func (m *mirrorManager) requestCheckpoints(names []string) (map[string]uint64, error) {
+	resp, err := m.request("/mirror/checkpoints", []byte(wkutil.ToJSON(names)))
+	if err != nil {
+		return nil, err
+	}
+	checkpoints := make(map[string]uint64)
+	if err = wkutil.ReadJSONByByte(resp.Body, &checkpoints); err != nil {
+		return nil, err
+	}
+	return checkpoints, nil
+}
+
+func (m *mirrorManager) requestSlot(req *mirrorSlotReq) error {
+	data, err := req.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = m.request("/mirror/slot", data)
+	return err
+}
+
+func (m *mirrorManager) requestMessages(req *mirrorMessagesReq) error {
+	data, err := req.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = m.request("/mirror/messages", data)
+	return err
+}
+
+func (m *mirrorManager) request(path string, body []byte) (*proto.Response, error) {
+	resp, err := m.client.RequestWithContext(m.s.ctx, path, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("请求备集群[%s]失败！[%s]", path, string(resp.Body))
+	}
+	return resp, nil
+}
+
+// ================================== standby ==================================
+
+func (m *mirrorManager) handleConn(c *wkserver.Context) {
+	req := c.ConnReq()
+	if req == nil {
+		return
+	}
+	if req.Token != m.s.opts.Mirror.Token {
+		m.Warn("镜像连接的令牌错误！", zap.String("uid", req.Uid))
+		c.WriteConnack(&proto.Connack{
+			Id:     req.Id,
+			Status: proto.Status_ERROR,
+		})
+		_ = c.Conn().Close()
+		return
+	}
+	c.Conn().SetValue(mirrorConnAuthKey, true)
+	c.WriteConnack(&proto.Connack{
+		Id:     req.Id,
+		Status: proto.Status_OK,
+	})
+}
+
+func (m *mirrorManager) authed(c *wkserver.Context) bool {
+	if c.Conn().Value(mirrorConnAuthKey) == nil {
+		c.WriteErr(errors.New("unauthorized"))
+		return false
+	}
+	return true
+}
+
+func (m *mirrorManager) handleCheckpoints(c *wkserver.Context) {
+	if !m.authed(c) {
+		return
+	}
+	var names []string
+	if err := wkutil.ReadJSONByByte(c.Body(), &names); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	checkpoints, err := m.getCheckpoints(names)
+	if err != nil {
+		m.Error("获取镜像进度失败！", zap.Error(err), zap.Strings("names", names))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(checkpoints)))
+}
+
+// getCheckpoints 镜像进度随槽复制，从进度所在槽的领导节点读取
+func (m *mirrorManager) getCheckpoints(names []string) (map[string]uint64, error) {
+	nodeNames := make(map[uint64][]string)
+	for _, name := range names {
+		slotId, err := m.checkpointSlotId(name)
+		if err != nil {
+			return nil, err
+		}
+		leader, err := m.s.cluster.SlotLeaderNodeInfo(slotId)
+		if err != nil {
+			return nil, err
+		}
+		nodeNames[leader.Id] = append(nodeNames[leader.Id], name)
+	}
+	checkpoints := make(map[string]uint64, len(names))
+	for nodeId, names := range nodeNames {
+		var nodeCheckpoints map[string]uint64
+		var err error
+		if nodeId == m.s.opts.Cluster.NodeId {
+			nodeCheckpoints, err = m.localCheckpoints(names)
+		} else {
+			err = m.s.requestNodeJSON(nodeId, "/wk/mirrorCheckpoints", names, &nodeCheckpoints)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for name, checkpoint := range nodeCheckpoints {
+			checkpoints[name] = checkpoint
+		}
+	}
+	return checkpoints, nil
+}
+
+func (m *mirrorManager) localCheckpoints(names []string) (map[string]uint64, error) {
+	checkpoints := make(map[string]uint64, len(names))
+	for _, name := range names {
+		checkpoint, err := m.s.store.GetMirrorCheckpoint(name)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints[name] = checkpoint
+	}
+	return checkpoints, nil
+}
+
+// checkpointSlotId 槽日志的进度保存在对应的槽，频道消息的进度保存在频道所在的槽
+func (m *mirrorManager) checkpointSlotId(name string) (uint32, error) {
+	if strings.HasPrefix(name, "slot:") {
+		slotId, err := strconv.ParseUint(strings.TrimPrefix(name, "slot:"), 10, 32)
+		if err != nil {
+			return 0, err
+		}
+		return uint32(slotId), nil
+	}
+	if strings.HasPrefix(name, "msg:") {
+		parts := strings.SplitN(strings.TrimPrefix(name, "msg:"), "&", 2)
+		if len(parts) == 2 {
+			return m.s.cluster.GetSlotId(parts[1]), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid mirror checkpoint name: %s", name)
+}
+
+// handleLocalCheckpoints 其他节点读取本节点领导的槽里的镜像进度
+func (m *mirrorManager) handleLocalCheckpoints(c *wkserver.Context) {
+	var names []string
+	if err := wkutil.ReadJSONByByte(c.Body(), &names); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	checkpoints, err := m.localCheckpoints(names)
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(checkpoints)))
+}
+
+func (m *mirrorManager) handleSlot(c *wkserver.Context) {
+	if !m.authed(c) {
+		return
+	}
+	req := &mirrorSlotReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	if err := m.applySlot(req); err != nil {
+		m.Error("应用镜像的槽日志失败！", zap.Error(err), zap.Uint32("slotId", req.SlotId))
+		c.WriteErr(err)
+		return
+	}
+	c.WriteOk()
+}
+
+// applySlot 备集群将日志和镜像进度在同一个提案里应用到同一个槽，已应用的日志跳过
+func (m *mirrorManager) applySlot(req *mirrorSlotReq) error {
+	slotCount := m.s.clusterServer.GetConfig().SlotCount
+	if req.SlotCount != slotCount {
+		return fmt.Errorf("slot count not match, primary: %d standby: %d", req.SlotCount, slotCount)
+	}
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+	if m.promoted.Load() {
+		return ErrMirrorNotStandby
+	}
+
+	name := mirrorSlotCheckpointName(req.SlotId)
+	checkpoints, err := m.getCheckpoints([]string{name})
+	if err != nil {
+		return err
+	}
+	checkpoint := checkpoints[name]
+	logs := make([]clusterstore.MirrorLog, 0, len(req.Logs))
+	for _, lg := range req.Logs {
+		if lg.Index <= checkpoint {
+			continue
+		}
+		if lg.Index != checkpoint+1 {
+			return fmt.Errorf("slot log index not continuous, checkpoint: %d index: %d", checkpoint, lg.Index)
+		}
+		logs = append(logs, clusterstore.MirrorLog{Index: lg.Index, Data: lg.Data})
+		checkpoint = lg.Index
+	}
+	if err = m.s.store.ProposeMirrorSlotLogs(req.SlotId, name, logs); err != nil {
+		return err
+	}
+	m.receiveSuccess()
+	return nil
+}
+
+func (m *mirrorManager) handleMessages(c *wkserver.Context) {
+	if !m.authed(c) {
+		return
+	}
+	req := &mirrorMessagesReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	if err := m.applyMessages(req); err != nil {
+		m.Error("应用镜像的消息失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.WriteErr(err)
+		return
+	}
+	c.WriteOk()
+}
+
+// applyMessages 备集群追加主集群的消息，备集群的消息只来自镜像，消息序号和主集群一致，已追加的消息（序号小于等于频道最新序号）跳过
+func (m *mirrorManager) applyMessages(req *mirrorMessagesReq) error {
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+	if m.promoted.Load() {
+		return ErrMirrorNotStandby
+	}
+	if len(req.Messages) == 0 {
+		return nil
+	}
+
+	lastSeq, err := m.channelLastMsgSeq(req.ChannelId, req.ChannelType)
+	if err != nil {
+		return err
+	}
+	messages := make([]wkdb.Message, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		if uint64(msg.MessageSeq) > lastSeq {
+			messages = append(messages, msg)
+		}
+	}
+	if len(messages) > 0 {
+		if uint64(messages[0].MessageSeq) != lastSeq+1 {
+			return fmt.Errorf("message seq not continuous, lastSeq: %d messageSeq: %d", lastSeq, messages[0].MessageSeq)
+		}
+		timeoutCtx, cancel := context.WithTimeout(m.s.ctx, time.Second*10)
+		defer cancel()
+		_, err = m.s.store.AppendMessages(timeoutCtx, req.ChannelId, req.ChannelType, messages)
+		if err != nil {
+			return err
+		}
+		lastSeq = uint64(messages[len(messages)-1].MessageSeq)
+	}
+	slotId := m.s.cluster.GetSlotId(req.ChannelId)
+	if err = m.s.store.SaveMirrorCheckpoint(slotId, mirrorChannelCheckpointName(req.ChannelId, req.ChannelType), lastSeq); err != nil {
+		return err
+	}
+	m.receiveSuccess()
+	return nil
+}
+
+// mirrorChannelReq 节点间查询频道最新消息序号的请求
+type mirrorChannelReq struct {
+	ChannelId   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+}
+
+// channelLastMsgSeq 从频道的领导节点获取频道最新的消息序号
+func (m *mirrorManager) channelLastMsgSeq(channelId string, channelType uint8) (uint64, error) {
+	timeoutCtx, cancel := context.WithTimeout(m.s.ctx, time.Second*10)
+	defer cancel()
+	leader, err := m.s.cluster.LeaderOfChannel(timeoutCtx, channelId, channelType)
+	if err != nil {
+		return 0, err
+	}
+	if leader.Id == m.s.opts.Cluster.NodeId {
+		return m.s.store.GetLastMsgSeq(channelId, channelType)
+	}
+	var lastSeq uint64
+	err = m.s.requestNodeJSON(leader.Id, "/wk/mirrorChannelLastSeq", &mirrorChannelReq{ChannelId: channelId, ChannelType: channelType}, &lastSeq)
+	return lastSeq, err
+}
+
+// handleChannelLastSeq 其他节点获取本节点领导的频道最新的消息序号
+func (m *mirrorManager) handleChannelLastSeq(c *wkserver.Context) {
+	var req mirrorChannelReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	lastSeq, err := m.s.store.GetLastMsgSeq(req.ChannelId, req.ChannelType)
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(lastSeq)))
+}
+
+func (m *mirrorManager) receiveSuccess() {
+	m.statusMu.Lock()
+	m.status.LastSyncAt = time.Now().UnixMilli()
+	m.statusMu.Unlock()
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirrorReqCodec(t *testing.T) {
+	slotReq := &mirrorSlotReq{
+		SlotId:    3,
+		SlotCount: 64,
+		Logs: []mirrorLog{
+			{Index: 10, Data: []byte("cmd1")},
+			{Index: 11},
+			{Index: 12, Data: []byte("cmd3")},
+		},
+	}
+	data, err := slotReq.Marshal()
+	assert.NoError(t, err)
+	slotReq2 := &mirrorSlotReq{}
+	err = slotReq2.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, slotReq, slotReq2)
+
+	msgReq := &mirrorMessagesReq{
+		ChannelId:   "g1",
+		ChannelType: 2,
+		Messages: []wkdb.Message{
+			{RecvPacket: wkproto.RecvPacket{MessageID: 1, MessageSeq: 1, ChannelID: "g1", ChannelType: 2, FromUID: "u1", Payload: []byte("hello")}},
+			{RecvPacket: wkproto.RecvPacket{MessageID: 2, MessageSeq: 2, ChannelID: "g1", ChannelType: 2, FromUID: "u2", Payload: []byte("world")}},
+		},
+	}
+	data, err = msgReq.Marshal()
+	assert.NoError(t, err)
+	msgReq2 := &mirrorMessagesReq{}
+	err = msgReq2.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "g1", msgReq2.ChannelId)
+	assert.Equal(t, uint8(2), msgReq2.ChannelType)
+	assert.Equal(t, 2, len(msgReq2.Messages))
+	assert.Equal(t, uint32(2), msgReq2.Messages[1].MessageSeq)
+	assert.Equal(t, "world", string(msgReq2.Messages[1].Payload))
+}
+
+func TestMirrorReadOnlyMiddleware(t *testing.T) {
+	s := NewTestServer(t,
+		WithDemoOn(false),
+		WithMirrorOn(true),
+		WithMirrorRole(MirrorRoleStandby),
+		WithMirrorAddr("tcp://0.0.0.0:11120"),
+		WithMirrorToken("token"),
+	)
+	r := wkhttp.New()
+	r.Use(s.mirrorManager.readOnlyMiddleware())
+	r.POST("/channel/subscriber_add", func(c *wkhttp.Context) { c.ResponseOK() })
+	r.POST("/conversation/sync", func(c *wkhttp.Context) { c.ResponseOK() })
+
+	request := func(path string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, nil)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 未提升的备集群拒绝写接口，读接口不受影响
+	assert.Equal(t, http.StatusBadRequest, request("/channel/subscriber_add"))
+	assert.Equal(t, http.StatusOK, request("/conversation/sync"))
+
+	s.mirrorManager.promoted.Store(true)
+	assert.Equal(t, http.StatusOK, request("/channel/subscriber_add"))
+}
+
+// 两个进程内的集群，主集群的消息和频道数据镜像到备集群，备集群提升后可写
+func TestMirror(t *testing.T) {
+	standby := NewTestServer(t,
+		WithDemoOn(false),
+		WithWSAddr("ws://0.0.0.0:5220"),
+		WithManagerAddr("0.0.0.0:5320"),
+		WithAddr("tcp://0.0.0.0:5120"),
+		WithHTTPAddr("0.0.0.0:5002"),
+		WithClusterAddr("tcp://0.0.0.0:11111"),
+		WithMirrorOn(true),
+		WithMirrorRole(MirrorRoleStandby),
+		WithMirrorAddr("tcp://0.0.0.0:11120"),
+		WithMirrorToken("token"),
+	)
+	primary := NewTestServer(t,
+		WithDemoOn(false),
+		WithMirrorOn(true),
+		WithMirrorRole(MirrorRolePrimary),
+		WithMirrorTarget("tcp://127.0.0.1:11120"),
+		WithMirrorToken("token"),
+	)
+	err := standby.Start()
+	assert.Nil(t, err)
+	defer standby.StopNoErr()
+	err = primary.Start()
+	assert.Nil(t, err)
+	defer primary.StopNoErr()
+
+	MustWaitClusterReady(standby, primary)
+
+	// 备集群只读
+	assert.True(t, standby.mirrorManager.readOnly())
+	assert.False(t, primary.mirrorManager.readOnly())
+
+	err = primary.store.AddChannelInfo(wkdb.ChannelInfo{ChannelId: "g1", ChannelType: wkproto.ChannelTypeGroup})
+	assert.Nil(t, err)
+
+	cli := client.New(primary.opts.External.TCPAddr, client.WithUID("test1"))
+	err = cli.Connect()
+	assert.Nil(t, err)
+	defer cli.Close()
+	err = cli.SendMessage(client.NewChannel("test2", wkproto.ChannelTypePerson), []byte("hello"))
+	assert.Nil(t, err)
+
+	channelId := GetFakeChannelIDWith("test1", "test2")
+	assert.Eventually(t, func() bool {
+		exist, err := standby.store.ExistChannel("g1", wkproto.ChannelTypeGroup)
+		if err != nil || !exist {
+			return false
+		}
+		messages, err := standby.store.LoadNextRangeMsgs(channelId, wkproto.ChannelTypePerson, 1, 0, 10)
+		return err == nil && len(messages) == 1 && string(messages[0].Payload) == "hello"
+	}, time.Second*20, time.Millisecond*100)
+
+	assert.Eventually(t, func() bool {
+		status := primary.mirrorManager.getStatus()
+		return status.Connected && status.SlotLag == 0 && status.PendingChannels == 0
+	}, time.Second*10, time.Millisecond*100)
+
+	// 提升备集群
+	err = standby.mirrorManager.promote()
+	assert.Nil(t, err)
+	assert.False(t, standby.mirrorManager.readOnly())
+	assert.True(t, standby.mirrorManager.getStatus().Promoted)
+
+	promoted, err := standby.store.GetMirrorCheckpoint(mirrorCheckpointPromote)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), promoted)
+}
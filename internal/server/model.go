@@ -1,8 +1,11 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -631,6 +634,94 @@ func (req clearConversationUnreadReq) Check() error {
 	return nil
 }
 
+// setConversationExtraReq 设置会话扩展属性，为nil的字段不修改
+type setConversationExtraReq struct {
+	UID         string  `json:"uid"`
+	ChannelID   string  `json:"channel_id"`
+	ChannelType uint8   `json:"channel_type"`
+	Pin         *int    `json:"pin"`    // 1：置顶 0：取消置顶
+	Mute        *int    `json:"mute"`   // 1：免打扰 0：取消免打扰
+	Draft       *string `json:"draft"`  // 草稿
+	Custom      *string `json:"custom"` // 业务自定义的扩展数据
+}
+
+func (req setConversationExtraReq) Check() error {
+	if req.UID == "" {
+		return errors.New("uid cannot be empty")
+	}
+	if req.ChannelID == "" || req.ChannelType == 0 {
+		return errors.New("channel_id or channel_type cannot be empty")
+	}
+	if req.Pin == nil && req.Mute == nil && req.Draft == nil && req.Custom == nil {
+		return errors.New("pin, mute, draft or custom cannot all be empty")
+	}
+	return nil
+}
+
+// merge 合并到已有的扩展属性
+func (req setConversationExtraReq) merge(extra *wkdb.ConversationExtra) *wkdb.ConversationExtra {
+	newExtra := &wkdb.ConversationExtra{}
+	if extra != nil {
+		*newExtra = *extra
+	}
+	if req.Pin != nil {
+		newExtra.Pin = wkutil.IntToBool(*req.Pin)
+	}
+	if req.Mute != nil {
+		newExtra.Mute = wkutil.IntToBool(*req.Mute)
+	}
+	if req.Draft != nil {
+		newExtra.Draft = *req.Draft
+	}
+	if req.Custom != nil {
+		newExtra.Custom = *req.Custom
+	}
+	return newExtra
+}
+
+type conversationExtraResp struct {
+	Pin     int    `json:"pin"`              // 1：置顶
+	Mute    int    `json:"mute"`             // 1：免打扰
+	Draft   string `json:"draft,omitempty"`  // 草稿
+	Custom  string `json:"custom,omitempty"` // 业务自定义的扩展数据
+	Version uint64 `json:"version"`          // 扩展属性的版本号
+}
+
+type userBadgeResp struct {
+	UID   string `json:"uid"`
+	Badge int    `json:"badge"` // 所有会话的未读总数
+}
+
+type conversationMentionResp struct {
+	Count      int    `json:"count"`       // 未读的@数量
+	MessageSeq uint64 `json:"message_seq"` // 最后一条@我的消息序号
+	All        int    `json:"all"`         // 1：最后一条@是@所有人
+}
+
+func newConversationMentionResp(mention *wkdb.ConversationMention) *conversationMentionResp {
+	if mention == nil || mention.Count == 0 {
+		return nil
+	}
+	return &conversationMentionResp{
+		Count:      int(mention.Count),
+		MessageSeq: mention.MsgSeq,
+		All:        wkutil.BoolToInt(mention.All),
+	}
+}
+
+func newConversationExtraResp(extra *wkdb.ConversationExtra) *conversationExtraResp {
+	if extra == nil {
+		return nil
+	}
+	return &conversationExtraResp{
+		Pin:     wkutil.BoolToInt(extra.Pin),
+		Mute:    wkutil.BoolToInt(extra.Mute),
+		Draft:   extra.Draft,
+		Custom:  extra.Custom,
+		Version: extra.Version,
+	}
+}
+
 type deleteChannelReq struct {
 	UID         string `json:"uid"`
 	ChannelID   string `json:"channel_id"`
@@ -648,16 +739,28 @@ func (req deleteChannelReq) Check() error {
 }
 
 type syncUserConversationResp struct {
-	ChannelId       string         `json:"channel_id"`         // 频道ID
-	ChannelType     uint8          `json:"channel_type"`       // 频道类型
-	Unread          int            `json:"unread"`             // 未读消息
-	Timestamp       int64          `json:"timestamp"`          // 最后一次会话时间
-	LastMsgSeq      uint32         `json:"last_msg_seq"`       // 最后一条消息seq
-	LastClientMsgNo string         `json:"last_client_msg_no"` // 最后一次消息客户端编号
-	OffsetMsgSeq    int64          `json:"offset_msg_seq"`     // 偏移位的消息seq
-	ReadedToMsgSeq  uint32         `json:"readed_to_msg_seq"`  // 已读至的消息seq
-	Version         int64          `json:"version"`            // 数据版本
-	Recents         []*MessageResp `json:"recents"`            // 最近N条消息
+	ChannelId       string                   `json:"channel_id"`         // 频道ID
+	ChannelType     uint8                    `json:"channel_type"`       // 频道类型
+	Unread          int                      `json:"unread"`             // 未读消息
+	Timestamp       int64                    `json:"timestamp"`          // 最后一次会话时间
+	LastMsgSeq      uint32                   `json:"last_msg_seq"`       // 最后一条消息seq
+	LastClientMsgNo string                   `json:"last_client_msg_no"` // 最后一次消息客户端编号
+	OffsetMsgSeq    int64                    `json:"offset_msg_seq"`     // 偏移位的消息seq
+	ReadedToMsgSeq  uint32                   `json:"readed_to_msg_seq"`  // 已读至的消息seq
+	Version         int64                    `json:"version"`            // 数据版本
+	Recents         []*MessageResp           `json:"recents"`            // 最近N条消息
+	Deleted         int                      `json:"deleted,omitempty"`  // 1：会话已删除（增量同步时返回）
+	Extra           *conversationExtraResp   `json:"extra,omitempty"`    // 会话扩展属性（置顶、免打扰、草稿等）
+	Mention         *conversationMentionResp `json:"mention,omitempty"`  // 未读的@提醒
+	Large           int                      `json:"large,omitempty"`    // 1：超大群（未读数根据频道最新消息序号计算）
+}
+
+// syncUserConversationIncrResp 会话增量同步结果
+type syncUserConversationIncrResp struct {
+	Version       uint64                      `json:"version"`       // 下次同步使用的版本号
+	More          int                         `json:"more"`          // 1：还有更多变更，需要用version继续同步
+	Reset         int                         `json:"reset"`         // 1：客户端的版本号无效，返回的是全部会话，客户端需要清空本地会话
+	Conversations []*syncUserConversationResp `json:"conversations"` // 变更的会话
 }
 
 func newSyncUserConversationResp(conversation wkdb.Conversation) *syncUserConversationResp {
@@ -675,6 +778,21 @@ func newSyncUserConversationResp(conversation wkdb.Conversation) *syncUserConver
 		ChannelType:    conversation.ChannelType,
 		Unread:         int(conversation.UnreadCount),
 		ReadedToMsgSeq: uint32(conversation.ReadToMsgSeq),
+		Extra:          newConversationExtraResp(conversation.Extra),
+		Mention:        newConversationMentionResp(conversation.Mention),
+	}
+}
+
+// applyLarge 超大群的最近会话不随消息推送，未读数由频道最新消息序号和已读位置计算
+func (r *syncUserConversationResp) applyLarge(recent *channelRecentMessage) {
+	if recent.Large != 1 {
+		return
+	}
+	r.Large = 1
+	if recent.LastMsgSeq > uint64(r.ReadedToMsgSeq) {
+		r.Unread = int(recent.LastMsgSeq - uint64(r.ReadedToMsgSeq))
+	} else {
+		r.Unread = 0
 	}
 }
 
@@ -682,12 +800,15 @@ type channelRecentMessageReq struct {
 	ChannelId   string `json:"channel_id"`
 	ChannelType uint8  `json:"channel_type"`
 	LastMsgSeq  uint64 `json:"last_msg_seq"`
+	OnlyLarge   int    `json:"only_large,omitempty"` // 1：频道是超大群才返回
 }
 
 type channelRecentMessage struct {
 	ChannelId   string         `json:"channel_id"`
 	ChannelType uint8          `json:"channel_type"`
 	Messages    []*MessageResp `json:"messages"`
+	Large       int            `json:"large,omitempty"`        // 1：超大群
+	LastMsgSeq  uint64         `json:"last_msg_seq,omitempty"` // 超大群的最新消息序号
 }
 
 type MessageRespSlice []*MessageResp
@@ -715,7 +836,7 @@ func (r ChannelCreateReq) Check() error {
 	if IsSpecialChar(r.ChannelID) {
 		return errors.New("频道ID不能包含特殊字符！")
 	}
-	return nil
+	return r.checkReplicaSetting()
 }
 
 type subscriberAddReq struct {
@@ -873,33 +994,48 @@ type ChannelInfoReq struct {
 	Large       int    `json:"large"`        // 是否是超大群
 	Ban         int    `json:"ban"`          // 是否封禁频道（封禁后此频道所有人都将不能发消息，除了系统账号）
 	Disband     int    `json:"disband"`      // 是否解散频道
+	// 副本数量（0.使用集群默认的副本数量）
+	ReplicaCount uint16 `json:"replica_count"`
+	// 副本确认方式（0.使用集群默认 1.领导写入即提交 2.大多数副本确认 3.所有副本确认）
+	AckMode uint8 `json:"ack_mode"`
+}
+
+// checkReplicaSetting 检查副本设置
+func (c ChannelInfoReq) checkReplicaSetting() error {
+	if c.AckMode > uint8(wkdb.ChannelAckModeAll) {
+		return errors.New("副本确认方式错误！")
+	}
+	return nil
 }
 
 func (c ChannelInfoReq) ToChannelInfo() wkdb.ChannelInfo {
 	createdAt := time.Now()
 	updatedAt := time.Now()
 	return wkdb.ChannelInfo{
-		ChannelId:   c.ChannelID,
-		ChannelType: c.ChannelType,
-		Large:       c.Large == 1,
-		Ban:         c.Ban == 1,
-		Disband:     c.Disband == 1,
-		CreatedAt:   &createdAt,
-		UpdatedAt:   &updatedAt,
+		ChannelId:    c.ChannelID,
+		ChannelType:  c.ChannelType,
+		Large:        c.Large == 1,
+		Ban:          c.Ban == 1,
+		Disband:      c.Disband == 1,
+		ReplicaCount: c.ReplicaCount,
+		AckMode:      wkdb.ChannelAckMode(c.AckMode),
+		CreatedAt:    &createdAt,
+		UpdatedAt:    &updatedAt,
 	}
 }
 
 // MessageSendReq 消息发送请求
 type MessageSendReq struct {
-	Header      MessageHeader `json:"header"`        // 消息头
-	ClientMsgNo string        `json:"client_msg_no"` // 客户端消息编号（相同编号，客户端只会显示一条）
-	StreamNo    string        `json:"stream_no"`     // 消息流编号
-	FromUID     string        `json:"from_uid"`      // 发送者UID
-	ChannelID   string        `json:"channel_id"`    // 频道ID
-	ChannelType uint8         `json:"channel_type"`  // 频道类型
-	Expire      uint32        `json:"expire"`        // 消息过期时间
-	Subscribers []string      `json:"subscribers"`   // 订阅者 如果此字段有值，表示消息只发给指定的订阅者
-	Payload     []byte        `json:"payload"`       // 消息内容
+	Header      MessageHeader   `json:"header"`        // 消息头
+	ClientMsgNo string          `json:"client_msg_no"` // 客户端消息编号（相同编号，客户端只会显示一条）
+	StreamNo    string          `json:"stream_no"`     // 消息流编号
+	FromUID     string          `json:"from_uid"`      // 发送者UID
+	ChannelID   string          `json:"channel_id"`    // 频道ID
+	ChannelType uint8           `json:"channel_type"`  // 频道类型
+	Expire      uint32          `json:"expire"`        // 消息过期时间
+	Subscribers []string        `json:"subscribers"`   // 订阅者 如果此字段有值，表示消息只发给指定的订阅者
+	Payload     []byte          `json:"payload"`       // 消息内容
+	Mention     *MessageMention `json:"mention"`       // @信息，会写入payload的mention字段（payload需要是json对象）
 }
 
 // Check 检查输入
@@ -910,6 +1046,59 @@ func (m MessageSendReq) Check() error {
 	return nil
 }
 
+// MessageMention 消息的@信息，客户端发送的消息通过payload的mention字段携带
+type MessageMention struct {
+	All  int      `json:"all,omitempty"`  // 1：@所有人
+	Uids []string `json:"uids,omitempty"` // 被@的用户
+}
+
+// IsMentioned 用户是否被@
+func (m *MessageMention) IsMentioned(uid string) bool {
+	if m == nil {
+		return false
+	}
+	if m.All == 1 {
+		return true
+	}
+	for _, u := range m.Uids {
+		if u == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMessageMention 解析payload里的@信息，没有@返回nil
+func parseMessageMention(payload []byte) *MessageMention {
+	if len(payload) == 0 || !bytes.Contains(payload, []byte(`"mention"`)) {
+		return nil
+	}
+	var content struct {
+		Mention *MessageMention `json:"mention"`
+	}
+	if err := json.Unmarshal(payload, &content); err != nil {
+		return nil
+	}
+	if content.Mention == nil || (content.Mention.All != 1 && len(content.Mention.Uids) == 0) {
+		return nil
+	}
+	return content.Mention
+}
+
+// setPayloadMention 将@信息写入payload的mention字段
+func setPayloadMention(payload []byte, mention *MessageMention) ([]byte, error) {
+	var content map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &content); err != nil || content == nil {
+		return nil, errors.New("设置了mention，payload必须是json对象！")
+	}
+	mentionData, err := json.Marshal(mention)
+	if err != nil {
+		return nil, err
+	}
+	content["mention"] = mentionData
+	return json.Marshal(content)
+}
+
 type allowSendReq struct {
 	From string `json:"from"` // 发送者
 	To   string `json:"to"`   // 接收者
@@ -934,3 +1123,249 @@ func (a *allowSendReq) Marshal() ([]byte, error) {
 	enc.WriteString(a.To)
 	return enc.Bytes(), nil
 }
+
+type messageReactionReq struct {
+	UID         string `json:"uid"` // 回应的用户
+	ChannelID   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	MessageID   uint64 `json:"message_id"`
+	MessageSeq  uint64 `json:"message_seq"`
+	Emoji       string `json:"emoji"` // 回应的表情
+}
+
+func (r messageReactionReq) Check() error {
+	if strings.TrimSpace(r.UID) == "" {
+		return errors.New("uid不能为空！")
+	}
+	if strings.TrimSpace(r.ChannelID) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if r.ChannelType == 0 {
+		return errors.New("channel_type不能为0！")
+	}
+	if r.MessageSeq == 0 {
+		return errors.New("message_seq不能为0！")
+	}
+	if strings.TrimSpace(r.Emoji) == "" {
+		return errors.New("emoji不能为空！")
+	}
+	if len(r.Emoji) > 64 {
+		return errors.New("emoji太长！")
+	}
+	return nil
+}
+
+type syncReactionReq struct {
+	LoginUID    string `json:"login_uid"` // 当前登录用户的uid
+	ChannelID   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	Version     uint64 `json:"version"` // 客户端已同步到的回应版本号
+	Limit       int    `json:"limit"`   // 每次同步的回应变更数量
+}
+
+func (r syncReactionReq) Check() error {
+	if strings.TrimSpace(r.LoginUID) == "" {
+		return errors.New("login_uid不能为空！")
+	}
+	if strings.TrimSpace(r.ChannelID) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if r.ChannelType == 0 {
+		return errors.New("channel_type不能为0！")
+	}
+	return nil
+}
+
+type reactionSummaryResp struct {
+	Emoji   string   `json:"emoji"`
+	Count   int      `json:"count"`             // 回应人数
+	Uids    []string `json:"uids"`              // 最近回应的用户
+	Reacted int      `json:"reacted,omitempty"` // 当前登录用户是否回应了此表情
+}
+
+type messageReactionResp struct {
+	MessageID    uint64                 `json:"message_id"`
+	MessageIDStr string                 `json:"message_idstr"`
+	MessageSeq   uint64                 `json:"message_seq"`
+	Reactions    []*reactionSummaryResp `json:"reactions"`
+}
+
+type syncReactionResp struct {
+	Version  uint64                 `json:"version"` // 客户端下次同步时传入
+	More     int                    `json:"more"`
+	Messages []*messageReactionResp `json:"messages"`
+}
+
+// newMessageReactionResp 根据消息的回应汇总生成响应，表情按第一次回应的时间排序，userReactions为当前登录用户的回应
+func newMessageReactionResp(messageId uint64, summary wkdb.ReactionSummary, userReactions []wkdb.Reaction) *messageReactionResp {
+	if messageId == 0 {
+		messageId = summary.MessageId
+	}
+	resp := &messageReactionResp{
+		MessageID:    messageId,
+		MessageIDStr: strconv.FormatUint(messageId, 10),
+		MessageSeq:   summary.MessageSeq,
+		Reactions:    make([]*reactionSummaryResp, 0, len(summary.Emojis)),
+	}
+	reacted := make(map[string]struct{}, len(userReactions))
+	for _, reaction := range userReactions {
+		reacted[reaction.Emoji] = struct{}{}
+	}
+	emojis := append([]wkdb.ReactionEmojiSummary(nil), summary.Emojis...)
+	sort.SliceStable(emojis, func(i, j int) bool {
+		return emojis[i].CreatedAt < emojis[j].CreatedAt
+	})
+	for _, emoji := range emojis {
+		reactionResp := &reactionSummaryResp{
+			Emoji: emoji.Emoji,
+			Count: int(emoji.Count),
+			Uids:  emoji.Uids,
+		}
+		if _, ok := reacted[emoji.Emoji]; ok {
+			reactionResp.Reacted = 1
+		}
+		resp.Reactions = append(resp.Reactions, reactionResp)
+	}
+	return resp
+}
+
+// mirrorLog 镜像到备集群的槽日志
+type mirrorLog struct {
+	Index uint64
+	Data  []byte // 为空表示备集群不需要应用的日志（比如频道分布式配置），只推进进度
+}
+
+// mirrorSlotReq 主集群镜像到备集群的槽日志
+type mirrorSlotReq struct {
+	SlotId    uint32
+	SlotCount uint32 // 主集群的槽数量，主备集群的槽数量必须一致
+	Logs      []mirrorLog
+}
+
+func (m *mirrorSlotReq) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(m.SlotId)
+	enc.WriteUint32(m.SlotCount)
+	enc.WriteUint32(uint32(len(m.Logs)))
+	for _, lg := range m.Logs {
+		enc.WriteUint64(lg.Index)
+		enc.WriteUint32(uint32(len(lg.Data)))
+		enc.WriteBytes(lg.Data)
+	}
+	return enc.Bytes(), nil
+}
+
+func (m *mirrorSlotReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if m.SlotId, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if m.SlotCount, err = dec.Uint32(); err != nil {
+		return err
+	}
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	m.Logs = make([]mirrorLog, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var lg mirrorLog
+		if lg.Index, err = dec.Uint64(); err != nil {
+			return err
+		}
+		size, err := dec.Uint32()
+		if err != nil {
+			return err
+		}
+		data, err := dec.Bytes(int(size))
+		if err != nil {
+			return err
+		}
+		if len(data) > 0 {
+			lg.Data = append([]byte(nil), data...)
+		}
+		m.Logs = append(m.Logs, lg)
+	}
+	return nil
+}
+
+// mirrorMessagesReq 主集群镜像到备集群的频道消息
+type mirrorMessagesReq struct {
+	ChannelId   string
+	ChannelType uint8
+	Messages    []wkdb.Message // 按消息序号从小到大排列
+}
+
+func (m *mirrorMessagesReq) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(m.ChannelId)
+	enc.WriteUint8(m.ChannelType)
+	enc.WriteUint32(uint32(len(m.Messages)))
+	for _, msg := range m.Messages {
+		data, err := msg.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		enc.WriteUint32(uint32(len(data)))
+		enc.WriteBytes(data)
+	}
+	return enc.Bytes(), nil
+}
+
+func (m *mirrorMessagesReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if m.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if m.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	m.Messages = make([]wkdb.Message, 0, count)
+	for i := uint32(0); i < count; i++ {
+		size, err := dec.Uint32()
+		if err != nil {
+			return err
+		}
+		msgData, err := dec.Bytes(int(size))
+		if err != nil {
+			return err
+		}
+		var msg wkdb.Message
+		if err = msg.Unmarshal(append([]byte(nil), msgData...)); err != nil {
+			return err
+		}
+		m.Messages = append(m.Messages, msg)
+	}
+	return nil
+}
+
+// mirrorStatus 跨集群镜像状态
+type mirrorStatus struct {
+	NodeId          uint64              `json:"node_id"`
+	Role            MirrorRole          `json:"role"`
+	Promoted        bool                `json:"promoted"`                // 备集群是否已提升为主集群
+	Connected       bool                `json:"connected"`               // 是否已连接备集群（primary）
+	Slots           []*mirrorSlotStatus `json:"slots,omitempty"`         // 当前节点领导的槽的镜像进度（primary）
+	SlotLag         uint64              `json:"slot_lag"`                // 槽日志落后的数量（primary）
+	PendingChannels int                 `json:"pending_channels"`        // 等待镜像的频道数量（primary）
+	MessageLag      uint64              `json:"message_lag"`             // 最近一轮同步时消息落后的数量（primary）
+	LastSyncAt      int64               `json:"last_sync_at"`            // 最后一次成功发送（primary）或接收（standby）镜像数据的时间（毫秒）
+	LastError       string              `json:"last_error,omitempty"`    // 最后一次同步的错误
+	LastErrorAt     int64               `json:"last_error_at,omitempty"` // 最后一次同步错误的时间（毫秒）
+}
+
+// mirrorSlotStatus 槽的镜像进度
+type mirrorSlotStatus struct {
+	SlotId       uint32 `json:"slot_id"`
+	AppliedIndex uint64 `json:"applied_index"` // 主集群已应用的日志索引
+	Checkpoint   uint64 `json:"checkpoint"`    // 备集群已应用的日志索引
+	Lag          uint64 `json:"lag"`           // 落后的日志数量
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/auth"
 	"github.com/WuKongIM/WuKongIM/pkg/auth/resource"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wknet"
 	"github.com/WuKongIM/crypto/tls"
 	"github.com/pkg/errors"
 	"github.com/sasha-s/go-deadlock"
@@ -48,6 +49,28 @@ const (
 	RoleProxy   Role = "proxy"
 )
 
+type MirrorRole string
+
+const (
+	MirrorRolePrimary MirrorRole = "primary" // 主集群，将数据镜像到备集群
+	MirrorRoleStandby MirrorRole = "standby" // 备集群，接收主集群的镜像数据，提升前只读
+)
+
+type TieredBackend string
+
+const (
+	TieredBackendFile TieredBackend = "file" // 文件系统（本地目录或挂载的网络存储）
+	TieredBackendS3   TieredBackend = "s3"   // S3兼容存储（AWS S3、MinIO等）
+)
+
+type RouteStrategy string
+
+const (
+	RouteStrategyLeastConn  RouteStrategy = "leastConn"  // 连接数最少（按权重）的节点
+	RouteStrategySlotLeader RouteStrategy = "slotLeader" // 用户所在槽的领导节点
+	RouteStrategyHash       RouteStrategy = "hash"       // 按用户uid加权一致性哈希
+)
+
 type Options struct {
 	vp          *viper.Viper // 内部配置对象
 	Mode        Mode         // 模式 debug 测试 release 正式 bench 压力测试
@@ -63,6 +86,7 @@ type Options struct {
 		CertFile string // 证书文件
 		KeyFile  string // 私钥文件
 	}
+	WSCompression wknet.WSCompressionOptions // websocket permessage-deflate压缩配置
 
 	Logger struct {
 		Dir     string // 日志存储目录
@@ -102,7 +126,20 @@ type Options struct {
 		GRPCAddr                    string        //  webhook的grpc地址 如果此地址有值 则不会再调用HttpAddr配置的地址,格式为 ip:port
 		MsgNotifyEventPushInterval  time.Duration // 消息通知事件推送间隔，默认500毫秒发起一次推送
 		MsgNotifyEventCountPerPush  int           // 每次webhook消息通知事件推送消息数量限制 默认一次请求最多推送100条
-		MsgNotifyEventRetryMaxCount int           // 消息通知事件消息推送失败最大重试次数 默认为5次，超过将丢弃
+		MsgNotifyEventRetryMaxCount int           // 消息通知事件消息推送失败最大重试次数 默认为5次，超过将移到死信里
+		RetryBackoff                time.Duration // 推送失败后的重试间隔，每次失败翻倍
+		RetryBackoffMax             time.Duration // 推送失败后的最大重试间隔
+		Secrets                     []string      // 签名密钥，配置后每个请求都会带上HMAC-SHA256签名，轮换密钥时可同时配置新旧两个密钥
+		TLS                         struct {      // webhook客户端的tls配置
+			On                 bool   // grpc是否使用tls连接（http通过https地址开启tls）
+			CAFile             string // 校验webhook服务端证书的CA证书，不填使用系统CA
+			CertFile           string // 客户端证书，配置后进行双向认证
+			KeyFile            string // 客户端证书私钥
+			ServerName         string // 校验服务端证书的域名，不填使用地址里的域名
+			InsecureSkipVerify bool   // 不校验服务端证书（仅测试使用）
+		}
+		Filter      WebhookFilter       // 上面配置的webhook接收方订阅的事件
+		Subscribers []WebhookSubscriber // 额外的webhook接收方，每个接收方可以订阅不同的事件
 	}
 	Datasource struct { // 数据源配置，不填写则使用自身数据存储逻辑，如果填写则使用第三方数据源，数据格式请查看文档
 		Addr          string // 数据源地址
@@ -118,6 +155,9 @@ type Options struct {
 		SavePoolSize       int           // 保存最近会话协程池大小
 		WorkerCount        int           // 处理最近会话工作者数量
 		WorkerScanInterval time.Duration // 处理最近会话扫描间隔
+		MentionIgnoreMute  bool          // 被@的用户即使设置了免打扰也推送离线
+		WalOn              bool          // 是否开启最近会话缓存的预写日志（进程异常退出后可以恢复还未保存的最近会话）
+		WalSyncInterval    time.Duration // 预写日志刷盘（fsync）的最小间隔，0表示每次写入都刷盘，大于0时机器掉电最多丢失这段时间内的变更
 
 	}
 	ManagerToken   string // 管理者的token
@@ -178,6 +218,23 @@ type Options struct {
 		SlotReactorSubCount    int // 槽reactor sub的数量
 
 		PongMaxTick int // 节点超过多少tick没有回应心跳就认为是掉线
+
+		GossipAddr          string   // gossip监听地址 例如：0.0.0.0:7946，为空则不开启gossip
+		GossipAdvertiseAddr string   // gossip可访问地址
+		GossipSeeds         []string // gossip种子节点地址，开启后可以不配置seed和initNodes
+		GossipBootstrap     bool     // 通过gossip没有发现已有集群时由本节点初始化集群（集群内只需一个节点开启）
+
+		Zone   string            // 节点所在可用区，槽和频道的副本会尽量分散在不同的可用区
+		Rack   string            // 节点所在机架，同一可用区内的副本会尽量分散在不同的机架
+		Labels map[string]string // 节点标签
+
+		FollowerReadOn          bool          // 是否开启从副本读取消息（消息同步接口），关闭则都从频道领导读取
+		FollowerReadMaxLagIndex uint64        // 副本已提交的日志落后领导已提交日志的最大条数，超过则回退到领导读取
+		FollowerReadMaxLagTime  time.Duration // 副本距离最后一次收到领导消息的最大时间，超过则回退到领导读取
+
+		ConsistencyCheckInterval time.Duration // 后台校验副本一致性的间隔，0表示不开启
+		ConsistencyCheckMaxLogs  uint64        // 每次校验最近多少条已提交日志，0表示校验全部日志
+		ConsistencyAutoRepair    bool          // 后台校验发现频道追随者与领导不一致时是否自动修复
 	}
 
 	Trace struct {
@@ -212,6 +269,88 @@ type Options struct {
 		// DeliverWorkerCountPerNode int    // 每个节点投递协程数量
 	}
 
+	Route struct {
+		Strategy        RouteStrategy // 用户连接地址的路由策略
+		Weight          int           // 当前节点的路由权重，权重越大分配的连接越多，0表示不参与路由
+		Sticky          bool          // 是否粘性分配，同一个用户在粘性有效期内尽量分配到同一个节点
+		StickyExpire    time.Duration // 粘性分配的有效期
+		FallbackCount   int           // 返回的备选地址数量
+		RefreshInterval time.Duration // 节点路由信息的刷新间隔
+	}
+
+	Drain struct {
+		BatchSize     int           // 每批断开的连接数量
+		BatchInterval time.Duration // 每批断开连接的间隔
+		Timeout       time.Duration // 排空超时时间，超时后仍有领导未迁出则排空失败，节点继续运行
+	}
+
+	EventSink struct { // 事件投递到消息中间件（至少一次投递，相同频道的事件保证顺序）
+		BatchSize       int           // 每次投递的事件数量
+		Interval        time.Duration // 没有新事件时的检查间隔
+		RetryBackoff    time.Duration // 投递失败后的重试间隔，每次失败翻倍
+		RetryBackoffMax time.Duration // 投递失败后的最大重试间隔
+		Partitions      int           // 分区数量（nats的主题、redis的stream按分区拆分，kafka使用主题自身的分区）
+		Filter          WebhookFilter // 投递的事件
+		NATS            struct {
+			On      bool
+			URL     string // nats地址
+			Stream  string // JetStream的stream名称，不存在时自动创建
+			Subject string // 主题前缀，事件投递到 subject.分区 上
+		}
+		Kafka struct {
+			On      bool
+			Brokers []string // kafka地址
+			Topic   string   // 主题
+		}
+		Redis struct {
+			On       bool
+			Addr     string // redis地址
+			Password string
+			DB       int
+			Stream   string // stream名称前缀，事件投递到 stream:分区 上
+			MaxLen   int64  // 每个stream保留的最大长度，0表示不限制
+		}
+	}
+
+	Mirror struct { // 跨集群镜像（主集群异步将已提交的消息和槽数据复制到其他区域的备集群）
+		On        bool
+		Role      MirrorRole    // primary: 主集群 standby: 备集群
+		Addr      string        // 接收镜像数据的监听地址（standby有效）例如 tcp://0.0.0.0:11120
+		Target    string        // 备集群接收镜像数据的地址（primary有效）例如 tcp://xx.xx.xx.xx:11120
+		Token     string        // 主备集群之间的认证令牌
+		BatchSize int           // 每次镜像的日志或消息数量
+		Interval  time.Duration // 没有新数据时的检查间隔
+	}
+
+	Tiered struct { // 分层存储（频道中较旧的消息封存成压缩段下沉到对象存储，本地只保留热数据；每个副本各自上传段，对象存储占用为副本数量倍）
+		On      bool
+		Backend TieredBackend // file: 文件系统 s3: S3兼容存储
+		Dir     string        // 段文件目录（backend为file有效），默认为 dataDir/cold
+		S3      struct {      // backend为s3有效
+			Endpoint  string // 例如 http://127.0.0.1:9000
+			Region    string
+			Bucket    string
+			AccessKey string
+			SecretKey string
+		}
+		SegmentSize  int           // 每个段包含的消息序号数量
+		KeepMessages uint64        // 每个频道最新的多少条消息始终保留在本地
+		ColdAfter    time.Duration // 消息超过多久才下沉，0表示不限制
+		CacheSize    int           // 本地缓存的段数量
+		Interval     time.Duration // 下沉检查间隔
+	}
+
+	Encryption struct { // 静态加密（消息内容使用每个分区的数据密钥加密后保存，数据密钥由主密钥包装）
+		On            bool
+		MasterKeyFile string   // 主密钥文件，每行 id:base64(32字节密钥)，最后一行为当前主密钥
+		Plugin        struct { // KMS插件，设置了endpoint则通过插件包装数据密钥，不使用主密钥文件
+			Endpoint string
+			Token    string
+		}
+		ReencryptInterval  time.Duration // 后台重新加密的间隔
+		ReencryptBatchSize int           // 每个分区每次重新加密的消息数量
+	}
+
 	Db struct {
 		ShardNum     int // 频道db分片数量
 		SlotShardNum int // 槽db分片数量
@@ -271,6 +410,7 @@ func NewOptions(op ...Option) *Options {
 		Addr:                "tcp://0.0.0.0:5100",
 		WSAddr:              "ws://0.0.0.0:5200",
 		WSSAddr:             "",
+		WSCompression:       wknet.NewWSCompressionOptions(),
 		ConnIdleTime:        time.Minute * 3,
 		UserMsgQueueMaxSize: 0,
 		TmpChannel: struct {
@@ -309,6 +449,9 @@ func NewOptions(op ...Option) *Options {
 			SavePoolSize       int
 			WorkerCount        int
 			WorkerScanInterval time.Duration
+			MentionIgnoreMute  bool
+			WalOn              bool
+			WalSyncInterval    time.Duration
 		}{
 			On:                 true,
 			CacheExpire:        time.Hour * 24 * 1, // 1天过期
@@ -319,6 +462,8 @@ func NewOptions(op ...Option) *Options {
 			SavePoolSize:       100,
 			WorkerCount:        10,
 			WorkerScanInterval: time.Minute * 5,
+			MentionIgnoreMute:  true,
+			WalOn:              true,
 		},
 		DeliveryMsgPoolSize: 10240,
 		EventPoolSize:       1024,
@@ -339,10 +484,25 @@ func NewOptions(op ...Option) *Options {
 			MsgNotifyEventPushInterval  time.Duration
 			MsgNotifyEventCountPerPush  int
 			MsgNotifyEventRetryMaxCount int
+			RetryBackoff                time.Duration
+			RetryBackoffMax             time.Duration
+			Secrets                     []string
+			TLS                         struct {
+				On                 bool
+				CAFile             string
+				CertFile           string
+				KeyFile            string
+				ServerName         string
+				InsecureSkipVerify bool
+			}
+			Filter      WebhookFilter
+			Subscribers []WebhookSubscriber
 		}{
 			MsgNotifyEventPushInterval:  time.Millisecond * 500,
 			MsgNotifyEventCountPerPush:  100,
 			MsgNotifyEventRetryMaxCount: 5,
+			RetryBackoff:                time.Second,
+			RetryBackoffMax:             time.Minute,
 		},
 		Manager: struct {
 			On   bool
@@ -359,38 +519,55 @@ func NewOptions(op ...Option) *Options {
 			Addr: "0.0.0.0:5172",
 		},
 		Cluster: struct {
-			NodeId                 uint64
-			Addr                   string
-			ServerAddr             string
-			APIUrl                 string
-			ReqTimeout             time.Duration
-			Role                   Role
-			Seed                   string
-			SlotReplicaCount       int
-			ChannelReplicaCount    int
-			SlotCount              int
-			InitNodes              []*Node
-			TickInterval           time.Duration
-			HeartbeatIntervalTick  int
-			ElectionIntervalTick   int
-			ChannelReactorSubCount int
-			SlotReactorSubCount    int
-			PongMaxTick            int
+			NodeId                  uint64
+			Addr                    string
+			ServerAddr              string
+			APIUrl                  string
+			ReqTimeout              time.Duration
+			Role                    Role
+			Seed                    string
+			SlotReplicaCount        int
+			ChannelReplicaCount     int
+			SlotCount               int
+			InitNodes               []*Node
+			TickInterval            time.Duration
+			HeartbeatIntervalTick   int
+			ElectionIntervalTick    int
+			ChannelReactorSubCount  int
+			SlotReactorSubCount     int
+			PongMaxTick             int
+			GossipAddr              string
+			GossipAdvertiseAddr     string
+			GossipSeeds             []string
+			GossipBootstrap         bool
+			Zone                    string
+			Rack                    string
+			Labels                  map[string]string
+			FollowerReadOn          bool
+			FollowerReadMaxLagIndex uint64
+			FollowerReadMaxLagTime  time.Duration
+
+			ConsistencyCheckInterval time.Duration
+			ConsistencyCheckMaxLogs  uint64
+			ConsistencyAutoRepair    bool
 		}{
-			NodeId:                 1001,
-			Addr:                   "tcp://0.0.0.0:11110",
-			ServerAddr:             "",
-			ReqTimeout:             time.Second * 10,
-			Role:                   RoleReplica,
-			SlotCount:              64,
-			SlotReplicaCount:       3,
-			ChannelReplicaCount:    3,
-			TickInterval:           time.Millisecond * 150,
-			HeartbeatIntervalTick:  1,
-			ElectionIntervalTick:   10,
-			ChannelReactorSubCount: 64,
-			SlotReactorSubCount:    64,
-			PongMaxTick:            30,
+			NodeId:                  1001,
+			Addr:                    "tcp://0.0.0.0:11110",
+			ServerAddr:              "",
+			ReqTimeout:              time.Second * 10,
+			Role:                    RoleReplica,
+			SlotCount:               64,
+			SlotReplicaCount:        3,
+			ChannelReplicaCount:     3,
+			TickInterval:            time.Millisecond * 150,
+			HeartbeatIntervalTick:   1,
+			ElectionIntervalTick:    10,
+			ChannelReactorSubCount:  64,
+			SlotReactorSubCount:     64,
+			PongMaxTick:             30,
+			FollowerReadMaxLagIndex: 100,
+			FollowerReadMaxLagTime:  time.Second * 3,
+			ConsistencyCheckMaxLogs: 10000,
 		},
 		Trace: struct {
 			Endpoint         string
@@ -442,6 +619,143 @@ func NewOptions(op ...Option) *Options {
 			MaxDeliverSizePerNode: 1024 * 1024 * 5,
 			// DeliverWorkerCountPerNode: 10,
 		},
+		Route: struct {
+			Strategy        RouteStrategy
+			Weight          int
+			Sticky          bool
+			StickyExpire    time.Duration
+			FallbackCount   int
+			RefreshInterval time.Duration
+		}{
+			Strategy:        RouteStrategyLeastConn,
+			Weight:          100,
+			Sticky:          true,
+			StickyExpire:    time.Minute * 10,
+			FallbackCount:   2,
+			RefreshInterval: time.Second * 5,
+		},
+		Drain: struct {
+			BatchSize     int
+			BatchInterval time.Duration
+			Timeout       time.Duration
+		}{
+			BatchSize:     500,
+			BatchInterval: time.Second,
+			Timeout:       time.Minute * 5,
+		},
+		EventSink: struct {
+			BatchSize       int
+			Interval        time.Duration
+			RetryBackoff    time.Duration
+			RetryBackoffMax time.Duration
+			Partitions      int
+			Filter          WebhookFilter
+			NATS            struct {
+				On      bool
+				URL     string
+				Stream  string
+				Subject string
+			}
+			Kafka struct {
+				On      bool
+				Brokers []string
+				Topic   string
+			}
+			Redis struct {
+				On       bool
+				Addr     string
+				Password string
+				DB       int
+				Stream   string
+				MaxLen   int64
+			}
+		}{
+			BatchSize:       100,
+			Interval:        time.Millisecond * 500,
+			RetryBackoff:    time.Second,
+			RetryBackoffMax: time.Minute,
+			Partitions:      16,
+			NATS: struct {
+				On      bool
+				URL     string
+				Stream  string
+				Subject string
+			}{
+				URL:     "nats://127.0.0.1:4222",
+				Stream:  "WUKONGIM_EVENTS",
+				Subject: "wukongim.events",
+			},
+			Kafka: struct {
+				On      bool
+				Brokers []string
+				Topic   string
+			}{
+				Brokers: []string{"127.0.0.1:9092"},
+				Topic:   "wukongim-events",
+			},
+			Redis: struct {
+				On       bool
+				Addr     string
+				Password string
+				DB       int
+				Stream   string
+				MaxLen   int64
+			}{
+				Addr:   "127.0.0.1:6379",
+				Stream: "wukongim:events",
+			},
+		},
+		Mirror: struct {
+			On        bool
+			Role      MirrorRole
+			Addr      string
+			Target    string
+			Token     string
+			BatchSize int
+			Interval  time.Duration
+		}{
+			Role:      MirrorRolePrimary,
+			Addr:      "tcp://0.0.0.0:11120",
+			BatchSize: 100,
+			Interval:  time.Millisecond * 500,
+		},
+		Tiered: struct {
+			On      bool
+			Backend TieredBackend
+			Dir     string
+			S3      struct {
+				Endpoint  string
+				Region    string
+				Bucket    string
+				AccessKey string
+				SecretKey string
+			}
+			SegmentSize  int
+			KeepMessages uint64
+			ColdAfter    time.Duration
+			CacheSize    int
+			Interval     time.Duration
+		}{
+			Backend:      TieredBackendFile,
+			SegmentSize:  10000,
+			KeepMessages: 100000,
+			ColdAfter:    time.Hour * 24 * 30,
+			CacheSize:    32,
+			Interval:     time.Minute * 10,
+		},
+		Encryption: struct {
+			On            bool
+			MasterKeyFile string
+			Plugin        struct {
+				Endpoint string
+				Token    string
+			}
+			ReencryptInterval  time.Duration
+			ReencryptBatchSize int
+		}{
+			ReencryptInterval:  time.Second * 10,
+			ReencryptBatchSize: 1000,
+		},
 		Db: struct {
 			ShardNum     int
 			SlotShardNum int
@@ -528,6 +842,14 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.WSSConfig.CertFile = o.getString("wssConfig.certFile", o.WSSConfig.CertFile)
 	o.WSSConfig.KeyFile = o.getString("wssConfig.keyFile", o.WSSConfig.KeyFile)
 
+	o.WSCompression.On = o.getBool("wsCompression.on", o.WSCompression.On)
+	o.WSCompression.Level = o.getInt("wsCompression.level", o.WSCompression.Level)
+	o.WSCompression.MinSize = o.getInt("wsCompression.minSize", o.WSCompression.MinSize)
+	o.WSCompression.ServerNoContextTakeover = o.getBool("wsCompression.serverNoContextTakeover", o.WSCompression.ServerNoContextTakeover)
+	o.WSCompression.ClientNoContextTakeover = o.getBool("wsCompression.clientNoContextTakeover", o.WSCompression.ClientNoContextTakeover)
+	o.WSCompression.MaxMemory = o.getInt("wsCompression.maxMemory", o.WSCompression.MaxMemory)
+	o.WSCompression.MaxMessageSize = o.getInt("wsCompression.maxMessageSize", o.WSCompression.MaxMessageSize)
+
 	o.Channel.CacheCount = o.getInt("channel.cacheCount", o.Channel.CacheCount)
 	o.Channel.CreateIfNoExist = o.getBool("channel.createIfNoExist", o.Channel.CreateIfNoExist)
 	o.Channel.SubscriberCompressOfCount = o.getInt("channel.subscriberCompressOfCount", o.Channel.SubscriberCompressOfCount)
@@ -548,6 +870,33 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Webhook.MsgNotifyEventRetryMaxCount = o.getInt("webhook.msgNotifyEventRetryMaxCount", o.Webhook.MsgNotifyEventRetryMaxCount)
 	o.Webhook.MsgNotifyEventCountPerPush = o.getInt("webhook.msgNotifyEventCountPerPush", o.Webhook.MsgNotifyEventCountPerPush)
 	o.Webhook.MsgNotifyEventPushInterval = o.getDuration("webhook.msgNotifyEventPushInterval", o.Webhook.MsgNotifyEventPushInterval)
+	o.Webhook.RetryBackoff = o.getDuration("webhook.retryBackoff", o.Webhook.RetryBackoff)
+	o.Webhook.RetryBackoffMax = o.getDuration("webhook.retryBackoffMax", o.Webhook.RetryBackoffMax)
+	if secrets := o.getStringSlice("webhook.secrets"); len(secrets) > 0 {
+		o.Webhook.Secrets = secrets
+	}
+	o.Webhook.TLS.On = o.getBool("webhook.tls.on", o.Webhook.TLS.On)
+	o.Webhook.TLS.CAFile = o.getString("webhook.tls.caFile", o.Webhook.TLS.CAFile)
+	o.Webhook.TLS.CertFile = o.getString("webhook.tls.certFile", o.Webhook.TLS.CertFile)
+	o.Webhook.TLS.KeyFile = o.getString("webhook.tls.keyFile", o.Webhook.TLS.KeyFile)
+	o.Webhook.TLS.ServerName = o.getString("webhook.tls.serverName", o.Webhook.TLS.ServerName)
+	o.Webhook.TLS.InsecureSkipVerify = o.getBool("webhook.tls.insecureSkipVerify", o.Webhook.TLS.InsecureSkipVerify)
+	if events := o.getStringSlice("webhook.events"); len(events) > 0 {
+		o.Webhook.Filter.Events = events
+	}
+	if channelTypes := cast.ToIntSlice(o.vp.Get("webhook.channelTypes")); len(channelTypes) > 0 {
+		o.Webhook.Filter.ChannelTypes = make([]uint8, 0, len(channelTypes))
+		for _, channelType := range channelTypes {
+			o.Webhook.Filter.ChannelTypes = append(o.Webhook.Filter.ChannelTypes, uint8(channelType))
+		}
+	}
+	if o.vp.IsSet("webhook.subscribers") {
+		var subscribers []WebhookSubscriber
+		if err := o.vp.UnmarshalKey("webhook.subscribers", &subscribers); err != nil {
+			panic(err)
+		}
+		o.Webhook.Subscribers = subscribers
+	}
 
 	o.EventPoolSize = o.getInt("eventPoolSize", o.EventPoolSize)
 	o.DeliveryMsgPoolSize = o.getInt("deliveryMsgPoolSize", o.DeliveryMsgPoolSize)
@@ -575,6 +924,9 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Conversation.SavePoolSize = o.getInt("conversation.savePoolSize", o.Conversation.SavePoolSize)
 	o.Conversation.WorkerCount = o.getInt("conversation.workerNum", o.Conversation.WorkerCount)
 	o.Conversation.WorkerScanInterval = o.getDuration("conversation.workerScanInterval", o.Conversation.WorkerScanInterval)
+	o.Conversation.MentionIgnoreMute = o.getBool("conversation.mentionIgnoreMute", o.Conversation.MentionIgnoreMute)
+	o.Conversation.WalOn = o.getBool("conversation.walOn", o.Conversation.WalOn)
+	o.Conversation.WalSyncInterval = o.getDuration("conversation.walSyncInterval", o.Conversation.WalSyncInterval)
 
 	if o.WSSConfig.CertFile != "" && o.WSSConfig.KeyFile != "" {
 		certificate, err := tls.LoadX509KeyPair(o.WSSConfig.CertFile, o.WSSConfig.KeyFile)
@@ -654,6 +1006,23 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Cluster.ChannelReplicaCount = o.getInt("cluster.channelReplicaCount", o.Cluster.ChannelReplicaCount)
 	o.Cluster.ServerAddr = o.getString("cluster.serverAddr", o.Cluster.ServerAddr)
 	o.Cluster.PongMaxTick = o.getInt("cluster.pongMaxTick", o.Cluster.PongMaxTick)
+	o.Cluster.GossipAddr = o.getString("cluster.gossipAddr", o.Cluster.GossipAddr)
+	o.Cluster.GossipAdvertiseAddr = o.getString("cluster.gossipAdvertiseAddr", o.Cluster.GossipAdvertiseAddr)
+	if gossipSeeds := o.getStringSlice("cluster.gossipSeeds"); len(gossipSeeds) > 0 {
+		o.Cluster.GossipSeeds = gossipSeeds
+	}
+	o.Cluster.GossipBootstrap = o.getBool("cluster.gossipBootstrap", o.Cluster.GossipBootstrap)
+	o.Cluster.Zone = o.getString("cluster.zone", o.Cluster.Zone)
+	o.Cluster.Rack = o.getString("cluster.rack", o.Cluster.Rack)
+	if labels := o.vp.GetStringMapString("cluster.labels"); len(labels) > 0 {
+		o.Cluster.Labels = labels
+	}
+	o.Cluster.FollowerReadOn = o.getBool("cluster.followerReadOn", o.Cluster.FollowerReadOn)
+	o.Cluster.FollowerReadMaxLagIndex = o.getUint64("cluster.followerReadMaxLagIndex", o.Cluster.FollowerReadMaxLagIndex)
+	o.Cluster.FollowerReadMaxLagTime = o.getDuration("cluster.followerReadMaxLagTime", o.Cluster.FollowerReadMaxLagTime)
+	o.Cluster.ConsistencyCheckInterval = o.getDuration("cluster.consistencyCheckInterval", o.Cluster.ConsistencyCheckInterval)
+	o.Cluster.ConsistencyCheckMaxLogs = o.getUint64("cluster.consistencyCheckMaxLogs", o.Cluster.ConsistencyCheckMaxLogs)
+	o.Cluster.ConsistencyAutoRepair = o.getBool("cluster.consistencyAutoRepair", o.Cluster.ConsistencyAutoRepair)
 
 	o.Cluster.ReqTimeout = o.getDuration("cluster.reqTimeout", o.Cluster.ReqTimeout)
 	o.Cluster.Seed = o.getString("cluster.seed", o.Cluster.Seed)
@@ -702,6 +1071,85 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	// o.Deliver.DeliverWorkerCountPerNode = o.getInt("deliver.deliverWorkerCountPerNode", o.Deliver.DeliverWorkerCountPerNode)
 	o.Deliver.MaxDeliverSizePerNode = o.getUint64("deliver.maxDeliverSizePerNode", o.Deliver.MaxDeliverSizePerNode)
 
+	// =================== route ===================
+	o.Route.Strategy = RouteStrategy(o.getString("route.strategy", string(o.Route.Strategy)))
+	o.Route.Weight = o.getInt("route.weight", o.Route.Weight)
+	o.Route.Sticky = o.getBool("route.sticky", o.Route.Sticky)
+	o.Route.StickyExpire = o.getDuration("route.stickyExpire", o.Route.StickyExpire)
+	o.Route.FallbackCount = o.getInt("route.fallbackCount", o.Route.FallbackCount)
+	o.Route.RefreshInterval = o.getDuration("route.refreshInterval", o.Route.RefreshInterval)
+
+	// =================== drain ===================
+	o.Drain.BatchSize = o.getInt("drain.batchSize", o.Drain.BatchSize)
+	o.Drain.BatchInterval = o.getDuration("drain.batchInterval", o.Drain.BatchInterval)
+	o.Drain.Timeout = o.getDuration("drain.timeout", o.Drain.Timeout)
+
+	// =================== eventSink ===================
+	o.EventSink.BatchSize = o.getInt("eventSink.batchSize", o.EventSink.BatchSize)
+	o.EventSink.Interval = o.getDuration("eventSink.interval", o.EventSink.Interval)
+	o.EventSink.RetryBackoff = o.getDuration("eventSink.retryBackoff", o.EventSink.RetryBackoff)
+	o.EventSink.RetryBackoffMax = o.getDuration("eventSink.retryBackoffMax", o.EventSink.RetryBackoffMax)
+	o.EventSink.Partitions = o.getInt("eventSink.partitions", o.EventSink.Partitions)
+	if events := o.getStringSlice("eventSink.events"); len(events) > 0 {
+		o.EventSink.Filter.Events = events
+	}
+	if channelTypes := cast.ToIntSlice(o.vp.Get("eventSink.channelTypes")); len(channelTypes) > 0 {
+		o.EventSink.Filter.ChannelTypes = make([]uint8, 0, len(channelTypes))
+		for _, channelType := range channelTypes {
+			o.EventSink.Filter.ChannelTypes = append(o.EventSink.Filter.ChannelTypes, uint8(channelType))
+		}
+	}
+	o.EventSink.NATS.On = o.getBool("eventSink.nats.on", o.EventSink.NATS.On)
+	o.EventSink.NATS.URL = o.getString("eventSink.nats.url", o.EventSink.NATS.URL)
+	o.EventSink.NATS.Stream = o.getString("eventSink.nats.stream", o.EventSink.NATS.Stream)
+	o.EventSink.NATS.Subject = o.getString("eventSink.nats.subject", o.EventSink.NATS.Subject)
+	o.EventSink.Kafka.On = o.getBool("eventSink.kafka.on", o.EventSink.Kafka.On)
+	if brokers := o.getStringSlice("eventSink.kafka.brokers"); len(brokers) > 0 {
+		o.EventSink.Kafka.Brokers = brokers
+	}
+	o.EventSink.Kafka.Topic = o.getString("eventSink.kafka.topic", o.EventSink.Kafka.Topic)
+	o.EventSink.Redis.On = o.getBool("eventSink.redis.on", o.EventSink.Redis.On)
+	o.EventSink.Redis.Addr = o.getString("eventSink.redis.addr", o.EventSink.Redis.Addr)
+	o.EventSink.Redis.Password = o.getString("eventSink.redis.password", o.EventSink.Redis.Password)
+	o.EventSink.Redis.DB = o.getInt("eventSink.redis.db", o.EventSink.Redis.DB)
+	o.EventSink.Redis.Stream = o.getString("eventSink.redis.stream", o.EventSink.Redis.Stream)
+	o.EventSink.Redis.MaxLen = o.getInt64("eventSink.redis.maxLen", o.EventSink.Redis.MaxLen)
+
+	// =================== mirror ===================
+	o.Mirror.On = o.getBool("mirror.on", o.Mirror.On)
+	o.Mirror.Role = MirrorRole(o.getString("mirror.role", string(o.Mirror.Role)))
+	o.Mirror.Addr = o.getString("mirror.addr", o.Mirror.Addr)
+	o.Mirror.Target = o.getString("mirror.target", o.Mirror.Target)
+	o.Mirror.Token = o.getString("mirror.token", o.Mirror.Token)
+	o.Mirror.BatchSize = o.getInt("mirror.batchSize", o.Mirror.BatchSize)
+	o.Mirror.Interval = o.getDuration("mirror.interval", o.Mirror.Interval)
+
+	// =================== tiered ===================
+	o.Tiered.On = o.getBool("tiered.on", o.Tiered.On)
+	o.Tiered.Backend = TieredBackend(o.getString("tiered.backend", string(o.Tiered.Backend)))
+	o.Tiered.Dir = o.getString("tiered.dir", o.Tiered.Dir)
+	if strings.TrimSpace(o.Tiered.Dir) == "" {
+		o.Tiered.Dir = filepath.Join(o.DataDir, "cold")
+	}
+	o.Tiered.S3.Endpoint = o.getString("tiered.s3.endpoint", o.Tiered.S3.Endpoint)
+	o.Tiered.S3.Region = o.getString("tiered.s3.region", o.Tiered.S3.Region)
+	o.Tiered.S3.Bucket = o.getString("tiered.s3.bucket", o.Tiered.S3.Bucket)
+	o.Tiered.S3.AccessKey = o.getString("tiered.s3.accessKey", o.Tiered.S3.AccessKey)
+	o.Tiered.S3.SecretKey = o.getString("tiered.s3.secretKey", o.Tiered.S3.SecretKey)
+	o.Tiered.SegmentSize = o.getInt("tiered.segmentSize", o.Tiered.SegmentSize)
+	o.Tiered.KeepMessages = o.getUint64("tiered.keepMessages", o.Tiered.KeepMessages)
+	o.Tiered.ColdAfter = o.getDuration("tiered.coldAfter", o.Tiered.ColdAfter)
+	o.Tiered.CacheSize = o.getInt("tiered.cacheSize", o.Tiered.CacheSize)
+	o.Tiered.Interval = o.getDuration("tiered.interval", o.Tiered.Interval)
+
+	// =================== encryption ===================
+	o.Encryption.On = o.getBool("encryption.on", o.Encryption.On)
+	o.Encryption.MasterKeyFile = o.getString("encryption.masterKeyFile", o.Encryption.MasterKeyFile)
+	o.Encryption.Plugin.Endpoint = o.getString("encryption.plugin.endpoint", o.Encryption.Plugin.Endpoint)
+	o.Encryption.Plugin.Token = o.getString("encryption.plugin.token", o.Encryption.Plugin.Token)
+	o.Encryption.ReencryptInterval = o.getDuration("encryption.reencryptInterval", o.Encryption.ReencryptInterval)
+	o.Encryption.ReencryptBatchSize = o.getInt("encryption.reencryptBatchSize", o.Encryption.ReencryptBatchSize)
+
 	// =================== reactor ===================
 	o.Reactor.ChannelSubCount = o.getInt("reactor.channelSubCount", o.Reactor.ChannelSubCount)
 	o.Reactor.ChannelProcessIntervalTick = o.getInt("reactor.channelProcessIntervalTick", o.Reactor.ChannelProcessIntervalTick)
@@ -874,6 +1322,57 @@ func (o *Options) Check() error {
 	if o.Cluster.NodeId == 0 {
 		return errors.New("cluster.nodeId must be set")
 	}
+	if o.Cluster.FollowerReadOn && o.Cluster.FollowerReadMaxLagTime <= 0 {
+		return errors.New("cluster.followerReadMaxLagTime must be greater than 0")
+	}
+	if o.Cluster.ConsistencyCheckInterval < 0 {
+		return errors.New("cluster.consistencyCheckInterval must not be negative")
+	}
+	if len(o.Webhook.Secrets) > 2 {
+		return errors.New("webhook.secrets supports at most 2 secrets")
+	}
+	if (o.Webhook.TLS.CertFile == "") != (o.Webhook.TLS.KeyFile == "") {
+		return errors.New("webhook.tls.certFile and webhook.tls.keyFile must be set together")
+	}
+	if _, err := newWebhookTLSConfig(o); err != nil {
+		return fmt.Errorf("webhook.tls is invalid: %w", err)
+	}
+	if o.EventSink.BatchSize <= 0 {
+		return errors.New("eventSink.batchSize must be greater than 0")
+	}
+	if o.Mirror.On {
+		if o.Mirror.Role != MirrorRolePrimary && o.Mirror.Role != MirrorRoleStandby {
+			return fmt.Errorf("mirror.role must be %s or %s", MirrorRolePrimary, MirrorRoleStandby)
+		}
+		if o.Mirror.Role == MirrorRolePrimary && o.Mirror.Target == "" {
+			return errors.New("mirror.target must be set when mirror.role is primary")
+		}
+		if o.Mirror.BatchSize <= 0 {
+			return errors.New("mirror.batchSize must be greater than 0")
+		}
+	}
+	if o.Tiered.On {
+		switch o.Tiered.Backend {
+		case TieredBackendFile:
+		case TieredBackendS3:
+			if o.Tiered.S3.Endpoint == "" || o.Tiered.S3.Bucket == "" {
+				return errors.New("tiered.s3.endpoint and tiered.s3.bucket must be set when tiered.backend is s3")
+			}
+		default:
+			return fmt.Errorf("tiered.backend must be %s or %s", TieredBackendFile, TieredBackendS3)
+		}
+		if o.Tiered.SegmentSize <= 0 {
+			return errors.New("tiered.segmentSize must be greater than 0")
+		}
+	}
+	if o.Encryption.On {
+		if o.Encryption.MasterKeyFile == "" && o.Encryption.Plugin.Endpoint == "" {
+			return errors.New("encryption.masterKeyFile or encryption.plugin.endpoint must be set when encryption is on")
+		}
+		if o.Encryption.ReencryptBatchSize <= 0 {
+			return errors.New("encryption.reencryptBatchSize must be greater than 0")
+		}
+	}
 
 	return nil
 }
@@ -991,6 +1490,27 @@ func (o *Options) WebhookOn() bool {
 	return strings.TrimSpace(o.Webhook.HTTPAddr) != "" || o.WebhookGRPCOn()
 }
 
+// WebhookEventOn 配置的webhook接收方是否订阅了指定事件
+func (o *Options) WebhookEventOn(event string) bool {
+	return o.WebhookOn() && o.Webhook.Filter.Allow(event, 0)
+}
+
+// EventSinkOn 是否开启了事件投递到消息中间件
+func (o *Options) EventSinkOn() bool {
+	return o.EventSink.NATS.On || o.EventSink.Kafka.On || o.EventSink.Redis.On
+}
+
+// EventSinkEventOn 是否需要投递指定事件到消息中间件
+func (o *Options) EventSinkEventOn(event string, channelType uint8) bool {
+	return o.EventSinkOn() && o.EventSink.Filter.Allow(event, channelType)
+}
+
+// WebhookTLSConfigured 是否配置了webhook客户端的证书
+func (o *Options) WebhookTLSConfigured() bool {
+	tlsOpts := o.Webhook.TLS
+	return tlsOpts.CAFile != "" || tlsOpts.CertFile != "" || tlsOpts.ServerName != "" || tlsOpts.InsecureSkipVerify
+}
+
 // WebhookGRPCOn 是否配置了webhook grpc地址
 func (o *Options) WebhookGRPCOn() bool {
 	return strings.TrimSpace(o.Webhook.GRPCAddr) != ""
@@ -1358,6 +1878,12 @@ func WithConversationSavePoolSize(savePoolSize int) Option {
 	}
 }
 
+func WithConversationWalSyncInterval(walSyncInterval time.Duration) Option {
+	return func(opts *Options) {
+		opts.Conversation.WalSyncInterval = walSyncInterval
+	}
+}
+
 func WithMessageRetryInterval(interval time.Duration) Option {
 	return func(opts *Options) {
 		opts.MessageRetry.Interval = interval
@@ -1509,6 +2035,158 @@ func WithClusterPongMaxTick(pongMaxTick int) Option {
 	}
 }
 
+func WithClusterGossipAddr(addr string) Option {
+	return func(opts *Options) {
+		opts.Cluster.GossipAddr = addr
+	}
+}
+
+func WithClusterGossipSeeds(seeds []string) Option {
+	return func(opts *Options) {
+		opts.Cluster.GossipSeeds = seeds
+	}
+}
+
+func WithClusterGossipBootstrap(bootstrap bool) Option {
+	return func(opts *Options) {
+		opts.Cluster.GossipBootstrap = bootstrap
+	}
+}
+
+func WithClusterZone(zone string) Option {
+	return func(opts *Options) {
+		opts.Cluster.Zone = zone
+	}
+}
+
+func WithClusterRack(rack string) Option {
+	return func(opts *Options) {
+		opts.Cluster.Rack = rack
+	}
+}
+
+func WithClusterLabels(labels map[string]string) Option {
+	return func(opts *Options) {
+		opts.Cluster.Labels = labels
+	}
+}
+
+func WithClusterFollowerReadOn(on bool) Option {
+	return func(opts *Options) {
+		opts.Cluster.FollowerReadOn = on
+	}
+}
+
+func WithClusterFollowerReadMaxLag(maxLagIndex uint64, maxLagTime time.Duration) Option {
+	return func(opts *Options) {
+		opts.Cluster.FollowerReadMaxLagIndex = maxLagIndex
+		opts.Cluster.FollowerReadMaxLagTime = maxLagTime
+	}
+}
+
+func WithClusterConsistencyCheck(interval time.Duration, maxLogs uint64, autoRepair bool) Option {
+	return func(opts *Options) {
+		opts.Cluster.ConsistencyCheckInterval = interval
+		opts.Cluster.ConsistencyCheckMaxLogs = maxLogs
+		opts.Cluster.ConsistencyAutoRepair = autoRepair
+	}
+}
+
+func WithMirrorOn(on bool) Option {
+	return func(opts *Options) {
+		opts.Mirror.On = on
+	}
+}
+
+func WithMirrorRole(role MirrorRole) Option {
+	return func(opts *Options) {
+		opts.Mirror.Role = role
+	}
+}
+
+func WithMirrorAddr(addr string) Option {
+	return func(opts *Options) {
+		opts.Mirror.Addr = addr
+	}
+}
+
+func WithMirrorTarget(target string) Option {
+	return func(opts *Options) {
+		opts.Mirror.Target = target
+	}
+}
+
+func WithMirrorToken(token string) Option {
+	return func(opts *Options) {
+		opts.Mirror.Token = token
+	}
+}
+
+func WithTieredOn(on bool) Option {
+	return func(opts *Options) {
+		opts.Tiered.On = on
+	}
+}
+
+func WithTieredBackend(backend TieredBackend) Option {
+	return func(opts *Options) {
+		opts.Tiered.Backend = backend
+	}
+}
+
+func WithTieredDir(dir string) Option {
+	return func(opts *Options) {
+		opts.Tiered.Dir = dir
+	}
+}
+
+func WithTieredS3(endpoint, region, bucket, accessKey, secretKey string) Option {
+	return func(opts *Options) {
+		opts.Tiered.S3.Endpoint = endpoint
+		opts.Tiered.S3.Region = region
+		opts.Tiered.S3.Bucket = bucket
+		opts.Tiered.S3.AccessKey = accessKey
+		opts.Tiered.S3.SecretKey = secretKey
+	}
+}
+
+func WithTieredSegmentSize(size int) Option {
+	return func(opts *Options) {
+		opts.Tiered.SegmentSize = size
+	}
+}
+
+func WithTieredKeepMessages(keep uint64) Option {
+	return func(opts *Options) {
+		opts.Tiered.KeepMessages = keep
+	}
+}
+
+func WithTieredColdAfter(d time.Duration) Option {
+	return func(opts *Options) {
+		opts.Tiered.ColdAfter = d
+	}
+}
+
+func WithEncryptionOn(on bool) Option {
+	return func(opts *Options) {
+		opts.Encryption.On = on
+	}
+}
+
+func WithEncryptionMasterKeyFile(file string) Option {
+	return func(opts *Options) {
+		opts.Encryption.MasterKeyFile = file
+	}
+}
+
+func WithEncryptionPlugin(endpoint, token string) Option {
+	return func(opts *Options) {
+		opts.Encryption.Plugin.Endpoint = endpoint
+		opts.Encryption.Plugin.Token = token
+	}
+}
+
 func WithTraceEndpoint(endpoint string) Option {
 	return func(opts *Options) {
 		opts.Trace.Endpoint = endpoint
@@ -0,0 +1,320 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/lni/goutils/syncutil"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// routeNode 节点的路由信息
+type routeNode struct {
+	NodeId    uint64 `json:"node_id"`
+	TCPAddr   string `json:"tcp_addr"`
+	WSAddr    string `json:"ws_addr"`
+	WSSAddr   string `json:"wss_addr"`
+	ConnCount int64  `json:"conn_count"` // 当前连接数
+	Weight    int    `json:"weight"`     // 路由权重
+	Role      Role   `json:"role"`       // 节点角色
+
+	updatedAt time.Time
+}
+
+// score 负载分数，越小越优先
+func (r *routeNode) score() float64 {
+	return float64(r.ConnCount+1) / float64(r.Weight)
+}
+
+type routeSticky struct {
+	nodeId   uint64
+	expireAt time.Time
+}
+
+// routeManager 用户连接地址路由管理
+type routeManager struct {
+	s       *Server
+	mu      sync.RWMutex
+	nodes   map[uint64]*routeNode // 各个节点的路由信息
+	pending map[uint64]int64      // 上次刷新后分配到各个节点的连接数（叠加在节点负载上）
+	sticky  *lru.Cache[string, routeSticky]
+	stopper *syncutil.Stopper
+	wklog.Log
+}
+
+func newRouteManager(s *Server) *routeManager {
+	r := &routeManager{
+		s:       s,
+		nodes:   make(map[uint64]*routeNode),
+		pending: make(map[uint64]int64),
+		stopper: syncutil.NewStopper(),
+		Log:     wklog.NewWKLog("routeManager"),
+	}
+	var err error
+	r.sticky, err = lru.New[string, routeSticky](100000)
+	if err != nil {
+		r.Panic("new sticky cache failed", zap.Error(err))
+	}
+	return r
+}
+
+func (r *routeManager) start() error {
+	r.stopper.RunWorker(r.loop)
+	return nil
+}
+
+func (r *routeManager) stop() {
+	r.stopper.Stop()
+}
+
+func (r *routeManager) loop() {
+	r.refresh()
+	tk := time.NewTicker(r.s.opts.Route.RefreshInterval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-tk.C:
+			r.refresh()
+		case <-r.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+// localNode 当前节点的路由信息
+func (r *routeManager) localNode() *routeNode {
+	weight := r.s.opts.Route.Weight
+	if r.s.drainManager.isDraining() { // 排空中的节点不参与路由
+		weight = 0
+	}
+	return &routeNode{
+		NodeId:    r.s.opts.Cluster.NodeId,
+		TCPAddr:   r.s.opts.External.TCPAddr,
+		WSAddr:    r.s.opts.External.WSAddr,
+		WSSAddr:   r.s.opts.External.WSSAddr,
+		ConnCount: int64(r.s.engine.ConnCount()),
+		Weight:    weight,
+		Role:      r.s.opts.Cluster.Role,
+		updatedAt: time.Now(),
+	}
+}
+
+// refresh 刷新各个节点的路由信息
+func (r *routeManager) refresh() {
+	local := r.localNode()
+	r.mu.Lock()
+	r.nodes[local.NodeId] = local
+	// 刷新后的负载已包含之前分配的连接
+	r.pending = make(map[uint64]int64)
+	r.mu.Unlock()
+
+	if !r.s.opts.ClusterOn() {
+		return
+	}
+
+	// 通过gossip传播当前节点负载
+	r.s.clusterServer.SetLoad(uint64(local.ConnCount))
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), r.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	requestGroup, _ := errgroup.WithContext(timeoutCtx)
+	for _, node := range r.s.clusterServer.GetConfig().Nodes {
+		if node.Id == r.s.opts.Cluster.NodeId || !node.Online || node.Role != pb.NodeRole_NodeRoleReplica {
+			continue
+		}
+		requestGroup.Go(func(n *pb.Node) func() error {
+			return func() error {
+				routeNode, err := r.requestRouteNode(n)
+				if err != nil {
+					r.Debug("获取节点路由信息失败！", zap.Error(err), zap.Uint64("nodeId", n.Id))
+					return nil
+				}
+				routeNode.updatedAt = time.Now()
+				r.mu.Lock()
+				r.nodes[n.Id] = routeNode
+				r.mu.Unlock()
+				return nil
+			}
+		}(node))
+	}
+	_ = requestGroup.Wait()
+}
+
+// requestRouteNode 通过节点间通信获取节点的路由信息
+func (r *routeManager) requestRouteNode(node *pb.Node) (*routeNode, error) {
+	timeoutCtx, cancel := context.WithTimeout(r.s.ctx, r.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := r.s.cluster.RequestWithContext(timeoutCtx, node.Id, "/wk/routeNode", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("获取节点路由信息请求状态错误！[%d]", resp.Status)
+	}
+	routeNode := &routeNode{}
+	err = wkutil.ReadJSONByByte(resp.Body, routeNode)
+	if err != nil {
+		return nil, err
+	}
+	return routeNode, nil
+}
+
+// handleRouteNode 返回当前节点的路由信息（节点内部调用）
+func (r *routeManager) handleRouteNode(c *wkserver.Context) {
+	c.Write([]byte(wkutil.ToJSON(r.localNode())))
+}
+
+// candidates 可分配连接的健康节点（按负载从低到高排序）
+func (r *routeManager) candidates() []*routeNode {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.s.opts.ClusterOn() {
+		local := r.nodes[r.s.opts.Cluster.NodeId]
+		if local == nil {
+			return []*routeNode{r.localNode()}
+		}
+		cp := *local
+		cp.ConnCount += r.pending[cp.NodeId]
+		return []*routeNode{&cp}
+	}
+
+	// gossip里的负载比定时刷新的更及时
+	gossipLoads := make(map[uint64]uint64)
+	for _, m := range r.s.clusterServer.GossipMembers() {
+		if m.Meta != nil {
+			gossipLoads[m.NodeID] = m.Meta.Load
+		}
+	}
+
+	expire := r.s.opts.Route.RefreshInterval * 3
+	now := time.Now()
+	nodes := make([]*routeNode, 0)
+	for _, node := range r.s.clusterServer.GetConfig().Nodes {
+		if !node.Online || node.Status != pb.NodeStatus_NodeStatusJoined || node.Role != pb.NodeRole_NodeRoleReplica {
+			continue
+		}
+		routeNode := r.nodes[node.Id]
+		if !routable(routeNode) {
+			continue
+		}
+		if node.Id != r.s.opts.Cluster.NodeId && now.Sub(routeNode.updatedAt) > expire { // 长时间没有刷新成功，认为节点不健康
+			continue
+		}
+		cp := *routeNode
+		if load, ok := gossipLoads[node.Id]; ok && node.Id != r.s.opts.Cluster.NodeId {
+			cp.ConnCount = int64(load)
+		}
+		cp.ConnCount += r.pending[node.Id]
+		nodes = append(nodes, &cp)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].score() < nodes[j].score()
+	})
+	return nodes
+}
+
+// route 路由用户的连接地址，返回按优先级排序的节点列表（第一个为首选，其余为备选）
+func (r *routeManager) route(uid string) []*routeNode {
+	nodes := r.candidates()
+	if len(nodes) <= 1 {
+		return nodes
+	}
+
+	var primary *routeNode
+	switch r.s.opts.Route.Strategy {
+	case RouteStrategySlotLeader:
+		leaderId, err := r.s.cluster.SlotLeaderIdOfChannel(uid, wkproto.ChannelTypePerson)
+		if err != nil {
+			r.Warn("获取用户所在槽领导失败！", zap.Error(err), zap.String("uid", uid))
+		} else {
+			primary = findRouteNode(nodes, leaderId)
+		}
+	case RouteStrategyHash:
+		sortByRendezvousHash(uid, nodes)
+		primary = nodes[0]
+	default:
+		if r.s.opts.Route.Sticky {
+			if sticky, ok := r.sticky.Get(uid); ok && time.Now().Before(sticky.expireAt) {
+				primary = findRouteNode(nodes, sticky.nodeId)
+			}
+		}
+		if primary == nil {
+			primary = nodes[0]
+			r.assigned(primary.NodeId)
+		}
+		if r.s.opts.Route.Sticky {
+			r.sticky.Add(uid, routeSticky{
+				nodeId:   primary.NodeId,
+				expireAt: time.Now().Add(r.s.opts.Route.StickyExpire),
+			})
+		}
+	}
+	if primary == nil {
+		primary = nodes[0]
+	}
+
+	results := make([]*routeNode, 0, r.s.opts.Route.FallbackCount+1)
+	results = append(results, primary)
+	for _, node := range nodes {
+		if len(results) > r.s.opts.Route.FallbackCount {
+			break
+		}
+		if node.NodeId == primary.NodeId {
+			continue
+		}
+		results = append(results, node)
+	}
+	return results
+}
+
+// assigned 预估分配后的连接数，避免刷新间隔内所有用户都被分配到同一个节点
+func (r *routeManager) assigned(nodeId uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending[nodeId]++
+}
+
+// routable 节点是否可以分配给客户端（只分配副本节点）
+func routable(node *routeNode) bool {
+	if node == nil || node.Weight <= 0 || node.TCPAddr == "" {
+		return false
+	}
+	return node.Role == "" || node.Role == RoleReplica
+}
+
+func findRouteNode(nodes []*routeNode, nodeId uint64) *routeNode {
+	for _, node := range nodes {
+		if node.NodeId == nodeId {
+			return node
+		}
+	}
+	return nil
+}
+
+// sortByRendezvousHash 按加权的最高随机权重哈希排序（同一个uid在节点不变的情况下结果稳定）
+func sortByRendezvousHash(uid string, nodes []*routeNode) {
+	scores := make(map[uint64]float64, len(nodes))
+	for _, node := range nodes {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(fmt.Sprintf("%s@%d", uid, node.NodeId)))
+		u := (float64(h.Sum64()>>11) + 0.5) / float64(uint64(1)<<53) // (0,1)
+		scores[node.NodeId] = -float64(node.Weight) / math.Log(u)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return scores[nodes[i].NodeId] > scores[nodes[j].NodeId]
+	})
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortByRendezvousHash(t *testing.T) {
+	newNodes := func() []*routeNode {
+		return []*routeNode{
+			{NodeId: 1, Weight: 100},
+			{NodeId: 2, Weight: 100},
+			{NodeId: 3, Weight: 300},
+		}
+	}
+
+	// 同一个uid结果稳定
+	nodes1 := newNodes()
+	sortByRendezvousHash("u1", nodes1)
+	nodes2 := newNodes()
+	sortByRendezvousHash("u1", nodes2)
+	for i := range nodes1 {
+		assert.Equal(t, nodes1[i].NodeId, nodes2[i].NodeId)
+	}
+
+	// 权重大的节点分配的用户更多
+	counts := make(map[uint64]int)
+	for i := 0; i < 10000; i++ {
+		nodes := newNodes()
+		sortByRendezvousHash(fmt.Sprintf("u%d", i), nodes)
+		counts[nodes[0].NodeId]++
+	}
+	assert.Greater(t, counts[3], counts[1])
+	assert.Greater(t, counts[3], counts[2])
+}
+
+func TestRoutable(t *testing.T) {
+	assert.True(t, routable(&routeNode{NodeId: 1, TCPAddr: "127.0.0.1:5100", Weight: 100, Role: RoleReplica}))
+	assert.False(t, routable(&routeNode{NodeId: 1, TCPAddr: "127.0.0.1:5100", Weight: 100, Role: RoleProxy}))
+	assert.False(t, routable(&routeNode{NodeId: 1, TCPAddr: "127.0.0.1:5100", Weight: 0, Role: RoleReplica}))
+	assert.False(t, routable(&routeNode{NodeId: 1, Weight: 100, Role: RoleReplica}))
+	assert.False(t, routable(nil))
+}
@@ -16,6 +16,8 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterstore"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
+	"github.com/WuKongIM/WuKongIM/pkg/kms"
+	"github.com/WuKongIM/WuKongIM/pkg/objstore"
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
@@ -61,6 +63,12 @@ type Server struct {
 
 	conversationManager *ConversationManager // 会话管理
 
+	userDataManager  *userDataManager  // 用户数据导出与擦除
+	routeManager     *routeManager     // 用户连接地址路由
+	drainManager     *drainManager     // 节点排空
+	mirrorManager    *mirrorManager    // 跨集群镜像
+	eventSinkManager *eventSinkManager // 事件投递到消息中间件
+
 	migrateTask *MigrateTask // 迁移任务
 }
 
@@ -108,6 +116,20 @@ func New(opts *Options) *Server {
 	storeOpts.IsCmdChannel = opts.IsCmdChannel
 	storeOpts.Db.ShardNum = s.opts.Db.ShardNum
 	storeOpts.Db.MemTableSize = s.opts.Db.MemTableSize
+	if s.opts.Tiered.On {
+		storeOpts.Tiered.ColdStore = s.newColdStore()
+		storeOpts.Tiered.SegmentSize = s.opts.Tiered.SegmentSize
+		storeOpts.Tiered.KeepMessages = s.opts.Tiered.KeepMessages
+		storeOpts.Tiered.ColdAfter = s.opts.Tiered.ColdAfter
+		storeOpts.Tiered.CacheSize = s.opts.Tiered.CacheSize
+		storeOpts.Tiered.Interval = s.opts.Tiered.Interval
+	}
+	if s.opts.Encryption.On {
+		storeOpts.Encryption.MasterKey = s.newMasterKey()
+		storeOpts.Encryption.ReencryptInterval = s.opts.Encryption.ReencryptInterval
+		storeOpts.Encryption.ReencryptBatchSize = s.opts.Encryption.ReencryptBatchSize
+	}
+	storeOpts.MirrorOn = s.opts.Mirror.On && s.opts.Mirror.Role == MirrorRolePrimary
 	s.store = clusterstore.NewStore(storeOpts)
 
 	// 初始化tag管理
@@ -119,6 +141,7 @@ func New(opts *Options) *Server {
 		wknet.WithWSAddr(s.opts.WSAddr),
 		wknet.WithWSSAddr(s.opts.WSSAddr),
 		wknet.WithWSTLSConfig(s.opts.WSTLSConfig),
+		wknet.WithWSCompression(s.opts.WSCompression),
 		wknet.WithOnReadBytes(func(n int) {
 			trace.GlobalTrace.Metrics.System().ExtranetIncomingAdd(int64(n))
 		}),
@@ -134,6 +157,11 @@ func New(opts *Options) *Server {
 	s.apiServer = NewAPIServer(s)                     // api服务
 	s.managerServer = NewManagerServer(s)             // 管理者的api服务
 	s.retryManager = newRetryManager(s)               // 消息重试管理
+	s.userDataManager = newUserDataManager(s)         // 用户数据导出与擦除
+	s.drainManager = newDrainManager(s)               // 节点排空
+	s.mirrorManager = newMirrorManager(s)             // 跨集群镜像
+	s.eventSinkManager = newEventSinkManager(s)       // 事件投递到消息中间件
+	s.routeManager = newRouteManager(s)               // 用户连接地址路由
 	s.conversationManager = NewConversationManager(s) // 会话管理
 	s.migrateTask = NewMigrateTask(s)                 // 迁移任务
 
@@ -179,6 +207,16 @@ func New(opts *Options) *Server {
 			cluster.WithSlotReactorSubCount(s.opts.Cluster.SlotReactorSubCount),
 			cluster.WithPongMaxTick(s.opts.Cluster.PongMaxTick),
 			cluster.WithAuth(s.opts.Auth),
+			cluster.WithGossipAddr(s.opts.Cluster.GossipAddr),
+			cluster.WithGossipAdvertiseAddr(s.opts.Cluster.GossipAdvertiseAddr),
+			cluster.WithGossipSeeds(s.opts.Cluster.GossipSeeds),
+			cluster.WithGossipBootstrap(s.opts.Cluster.GossipBootstrap),
+			cluster.WithZone(s.opts.Cluster.Zone),
+			cluster.WithRack(s.opts.Cluster.Rack),
+			cluster.WithLabels(s.opts.Cluster.Labels),
+			cluster.WithConsistencyCheckInterval(s.opts.Cluster.ConsistencyCheckInterval),
+			cluster.WithConsistencyCheckMaxLogs(s.opts.Cluster.ConsistencyCheckMaxLogs),
+			cluster.WithConsistencyAutoRepair(s.opts.Cluster.ConsistencyAutoRepair),
 		),
 
 		// cluster.WithOnChannelMetaApply(func(channelID string, channelType uint8, logs []replica.Log) error {
@@ -306,6 +344,26 @@ func (s *Server) Start() error {
 		return err
 	}
 
+	err = s.routeManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.eventSinkManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.mirrorManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.userDataManager.start()
+	if err != nil {
+		return err
+	}
+
 	s.webhook.Start()
 
 	// 判断是否开启迁移任务
@@ -327,9 +385,14 @@ func (s *Server) Stop() error {
 
 	s.cancel()
 
+	s.drainManager.stop()
 	s.deliverManager.stop()
 
 	s.retryManager.stop()
+	s.userDataManager.stop()
+	s.routeManager.stop()
+	s.eventSinkManager.stop()
+	s.mirrorManager.stop()
 	s.conversationManager.Stop()
 	s.cluster.Stop()
 	s.apiServer.Stop()
@@ -361,6 +424,16 @@ func (s *Server) Stop() error {
 	return nil
 }
 
+// Drain 排空当前节点（迁出领导和客户端连接），排空结束后DrainDone会被关闭
+func (s *Server) Drain() error {
+	return s.drainManager.drain()
+}
+
+// DrainDone 排空结束
+func (s *Server) DrainDone() <-chan struct{} {
+	return s.drainManager.done()
+}
+
 // 等待分布式就绪
 func (s *Server) MustWaitClusterReady() {
 	s.cluster.MustWaitClusterReady()
@@ -390,8 +463,46 @@ func (s *Server) getSlotId(v string) uint32 {
 	return s.cluster.GetSlotId(v)
 }
 
+// newColdStore 分层存储的对象存储
+func (s *Server) newColdStore() objstore.Store {
+	if s.opts.Tiered.Backend == TieredBackendS3 {
+		return objstore.NewS3Store(objstore.S3Options{
+			Endpoint:  s.opts.Tiered.S3.Endpoint,
+			Region:    s.opts.Tiered.S3.Region,
+			Bucket:    s.opts.Tiered.S3.Bucket,
+			AccessKey: s.opts.Tiered.S3.AccessKey,
+			SecretKey: s.opts.Tiered.S3.SecretKey,
+		})
+	}
+	return objstore.NewFileStore(s.opts.Tiered.Dir)
+}
+
+// newMasterKey 静态加密的主密钥
+func (s *Server) newMasterKey() kms.MasterKey {
+	if s.opts.Encryption.Plugin.Endpoint != "" {
+		return kms.NewPluginMasterKey(s.opts.Encryption.Plugin.Endpoint, s.opts.Encryption.Plugin.Token, 0)
+	}
+	masterKey, err := kms.NewFileMasterKey(s.opts.Encryption.MasterKeyFile)
+	if err != nil {
+		s.Panic("load master key error", zap.Error(err))
+	}
+	return masterKey
+}
+
 func (s *Server) onConnect(conn wknet.Conn) error {
 	conn.SetMaxIdle(time.Second * 2) // 在认证之前，连接最多空闲2秒
+
+	if s.drainManager.isDraining() { // 排空中不再接受新连接
+		_ = conn.Close()
+		return ErrNodeDraining
+	}
+
+	if s.mirrorManager.readOnly() { // 未提升的备集群不接受客户端连接
+		_ = conn.Close()
+		return ErrMirrorStandbyReadOnly
+	}
+
+	conn.SetValue(ConnKeyCounted, true)
 	s.trace.Metrics.App().ConnCountAdd(1)
 
 	if conn.InboundBuffer().BoundBufferSize() == 0 {
@@ -432,7 +543,9 @@ func (s *Server) onConnect(conn wknet.Conn) error {
 // }
 
 func (s *Server) onClose(conn wknet.Conn) {
-	s.trace.Metrics.App().ConnCountAdd(-1)
+	if conn.Value(ConnKeyCounted) != nil {
+		s.trace.Metrics.App().ConnCountAdd(-1)
+	}
 	connCtxObj := conn.Context()
 	if connCtxObj != nil {
 		connCtx := connCtxObj.(*connContext)
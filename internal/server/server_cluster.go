@@ -3,8 +3,10 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
@@ -56,6 +58,26 @@ func (s *Server) setClusterRoutes() {
 	// 是否允许发送消息
 	s.cluster.Route("/wk/allowSend", s.handleAllowSend)
 
+	// 获取节点的路由信息
+	s.cluster.Route("/wk/routeNode", s.routeManager.handleRouteNode)
+
+	// 用户数据导出与擦除（节点内部调用）
+	s.cluster.Route("/wk/userDataExport", s.userDataManager.handleLocalExport)
+	s.cluster.Route("/wk/userDataChannels", s.userDataManager.handleLocalChannels)
+	s.cluster.Route("/wk/userDataKick", s.userDataManager.handleLocalKick)
+	s.cluster.Route("/wk/userDataErase", s.userDataManager.handleLocalErase)
+
+	// 免打扰设置（查询用户所在槽领导节点上的设置，修改后通知缓存失效）
+	s.cluster.Route("/wk/mutedUids", s.conversationManager.mutes.handleMutedUids)
+	s.cluster.Route("/wk/muteInvalidate", s.conversationManager.mutes.handleMuteInvalidate)
+	// 消息删除后扣减未读数（在用户所在槽的领导节点上执行）
+	s.cluster.Route("/wk/removeUnread", s.conversationManager.handleRemoveUnread)
+	// 查询本节点保存的消息（消息回应校验消息是否存在）
+	s.cluster.Route("/wk/messageExist", s.handleMessageExist)
+	// 跨集群镜像（备集群的镜像进度和频道最新消息序号在对应的领导节点上读取）
+	s.cluster.Route("/wk/mirrorCheckpoints", s.mirrorManager.handleLocalCheckpoints)
+	s.cluster.Route("/wk/mirrorChannelLastSeq", s.mirrorManager.handleChannelLastSeq)
+
 }
 
 func (s *Server) handleChannelForward(c *wkserver.Context) {
@@ -358,6 +380,7 @@ func (s *Server) getNodeUidsByTag(c *wkserver.Context) {
 	var resp = &tagResp{
 		tagKey: tag.key,
 		uids:   uids,
+		large:  tag.large,
 	}
 	c.Write(resp.Marshal())
 
@@ -479,3 +502,78 @@ func (s *Server) handleAllowSend(c *wkserver.Context) {
 	}
 	c.WriteErrorAndStatus(errors.New("not allow send"), proto.Status(reasonCode))
 }
+
+// messageExistReq 查询消息是否存在的请求
+type messageExistReq struct {
+	ChannelId   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	MessageSeq  uint64 `json:"message_seq"`
+}
+
+type messageExistResp struct {
+	Exist     bool   `json:"exist"`
+	MessageId uint64 `json:"message_id"`
+}
+
+func (s *Server) handleMessageExist(c *wkserver.Context) {
+	var req messageExistReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	resp, err := s.localMessageExist(req.ChannelId, req.ChannelType, req.MessageSeq)
+	if err != nil {
+		s.Error("handleMessageExist: load message failed", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint64("messageSeq", req.MessageSeq))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(resp)))
+}
+
+func (s *Server) localMessageExist(channelId string, channelType uint8, messageSeq uint64) (*messageExistResp, error) {
+	msg, err := s.store.LoadMsg(channelId, channelType, messageSeq)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			return &messageExistResp{}, nil
+		}
+		return nil, err
+	}
+	return &messageExistResp{Exist: true, MessageId: uint64(msg.MessageID)}, nil
+}
+
+// messageExist 从保存频道消息的节点查询消息是否存在
+func (s *Server) messageExist(channelId string, channelType uint8, messageSeq uint64) (*messageExistResp, error) {
+	if !s.opts.ClusterOn() {
+		return s.localMessageExist(channelId, channelType, messageSeq)
+	}
+	leader, err := s.cluster.LeaderOfChannelForRead(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	if leader.Id == s.opts.Cluster.NodeId {
+		return s.localMessageExist(channelId, channelType, messageSeq)
+	}
+	resp := &messageExistResp{}
+	err = s.requestNodeJSON(leader.Id, "/wk/messageExist", &messageExistReq{ChannelId: channelId, ChannelType: channelType, MessageSeq: messageSeq}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// requestNodeJSON 通过节点间通信请求其他节点，请求和响应都是json
+func (s *Server) requestNodeJSON(nodeId uint64, path string, req interface{}, result interface{}) error {
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, nodeId, path, []byte(wkutil.ToJSON(req)))
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.Status_OK {
+		return fmt.Errorf("request %s status error: %d %s", path, resp.Status, string(resp.Body))
+	}
+	if result == nil {
+		return nil
+	}
+	return wkutil.ReadJSONByByte(resp.Body, result)
+}
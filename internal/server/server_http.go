@@ -58,6 +58,8 @@ func (s *APIServer) Start() {
 	s.r.Use(wkhttp.CORSMiddleware())
 	// 带宽流量计算中间件
 	s.r.Use(bandwidthMiddleware())
+	// 未提升的备集群只读
+	s.r.Use(s.s.mirrorManager.readOnlyMiddleware())
 
 	s.setRoutes()
 	go func() {
@@ -110,6 +112,22 @@ func (s *APIServer) setRoutes() {
 	routeapi := NewRouteAPI(s.s)
 	routeapi.Route(s.r)
 
+	// 节点排空api
+	drainapi := NewDrainAPI(s.s)
+	drainapi.Route(s.r)
+
+	// 跨集群镜像api
+	mirrorapi := NewMirrorAPI(s.s)
+	mirrorapi.Route(s.r)
+
+	// 静态加密api
+	encryptionapi := NewEncryptionAPI(s.s)
+	encryptionapi.Route(s.r)
+
+	// webhook死信api
+	webhookapi := NewWebhookAPI(s.s)
+	webhookapi.Route(s.r)
+
 	// 分布式api
 	clusterServer, ok := s.s.cluster.(*cluster.Server)
 	if ok {
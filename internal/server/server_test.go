@@ -61,6 +61,89 @@ func TestSingleSendMessage(t *testing.T) {
 
 }
 
+// 测试客户端SDK（websocket、发送回执、断线消息同步）
+func TestClientSDK(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitClusterReady() // 等待服务准备好
+
+	cli1 := client.New(s.opts.External.TCPAddr, client.WithUID("test1"))
+	err = cli1.Connect()
+	assert.Nil(t, err)
+	defer cli1.Close()
+
+	// websocket连接
+	cli2 := client.New(s.opts.External.WSAddr, client.WithUID("test2"))
+	recvC := cli2.RecvChan()
+	err = cli2.Connect()
+	assert.Nil(t, err)
+	defer cli2.Close()
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	sendack, err := cli1.SendMessageContext(timeoutCtx, client.NewChannel("test2", wkproto.ChannelTypePerson), []byte("hello"))
+	assert.Nil(t, err)
+	assert.True(t, sendack.MessageID > 0)
+	assert.Equal(t, uint32(1), sendack.MessageSeq)
+
+	select {
+	case recv := <-recvC:
+		assert.Equal(t, "hello", string(recv.Payload))
+		assert.Equal(t, "test1", recv.ChannelID)
+	case <-time.After(time.Second * 5):
+		t.Fatal("recv message timeout")
+	}
+
+	// 离线期间的消息通过api同步
+	for i := 0; i < 2; i++ {
+		_, err = cli1.SendMessageContext(timeoutCtx, client.NewChannel("test3", wkproto.ChannelTypePerson), []byte("offline"))
+		assert.Nil(t, err)
+	}
+	cli3 := client.New(s.opts.External.TCPAddr, client.WithUID("test3"), client.WithAPIURL(s.opts.External.APIUrl))
+	recvC = cli3.RecvChan()
+	err = cli3.Connect()
+	assert.Nil(t, err)
+	defer cli3.Close()
+
+	cli3.SyncMissedMessages()
+	assert.Equal(t, 2, len(recvC))
+	recv := <-recvC
+	assert.Equal(t, "offline", string(recv.Payload))
+	assert.Equal(t, uint32(1), recv.MessageSeq)
+}
+
+// 测试单节点排空
+func TestSingleDrain(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Mode = TestMode
+	err := s.Start()
+	assert.Nil(t, err)
+	defer s.StopNoErr()
+
+	s.MustWaitClusterReady() // 等待服务准备好
+
+	cli := client.New(s.opts.External.TCPAddr, client.WithUID("test1"))
+	err = cli.Connect()
+	assert.Nil(t, err)
+
+	err = s.Drain()
+	assert.Nil(t, err)
+
+	select {
+	case <-s.DrainDone():
+	case <-time.After(time.Second * 10):
+		t.Fatal("drain timeout")
+	}
+	status := s.drainManager.getStatus()
+	assert.Equal(t, drainStateDone, status.State)
+	assert.Equal(t, 1, status.Disconnected)
+	assert.Equal(t, 0, s.routeManager.localNode().Weight)
+}
+
 func TestClusterSendMessage(t *testing.T) {
 	s1, s2 := NewTestClusterServerTwoNode(t)
 	err := s1.Start()
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
@@ -79,7 +80,8 @@ func (t *tagManager) stop() {
 }
 
 // 添加频道接受者tag
-func (t *tagManager) addOrUpdateReceiverTag(key string, users []*nodeUsers) *tag {
+// large: 是否是超大群（超大群只投递在线用户并且不更新接收者的最近会话）
+func (t *tagManager) addOrUpdateReceiverTag(key string, users []*nodeUsers, large bool) *tag {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -87,6 +89,7 @@ func (t *tagManager) addOrUpdateReceiverTag(key string, users []*nodeUsers) *tag
 	for _, tag := range t.tags {
 		if tag.key == key {
 			tag.users = users
+			tag.large = large
 			existTag = tag
 			break
 		}
@@ -96,6 +99,7 @@ func (t *tagManager) addOrUpdateReceiverTag(key string, users []*nodeUsers) *tag
 		existTag = &tag{
 			key:       key,
 			users:     users,
+			large:     large,
 			createdAt: time.Now(),
 		}
 		t.tags = append(t.tags, existTag)
@@ -168,6 +172,7 @@ func (t *tagReq) Unmarshal(data []byte) error {
 type tagResp struct {
 	tagKey string
 	uids   []string
+	large  bool // 是否是超大群
 }
 
 func (t *tagResp) Marshal() []byte {
@@ -180,6 +185,7 @@ func (t *tagResp) Marshal() []byte {
 	for _, uid := range t.uids {
 		enc.WriteString(uid)
 	}
+	enc.WriteUint8(wkutil.BoolToUint8(t.large))
 
 	return enc.Bytes()
 }
@@ -205,12 +211,20 @@ func (t *tagResp) Unmarshal(data []byte) error {
 		}
 		t.uids = append(t.uids, uid)
 	}
+	if dec.Len() > 0 { // 兼容旧版本节点
+		var large uint8
+		if large, err = dec.Uint8(); err != nil {
+			return err
+		}
+		t.large = large == 1
+	}
 	return nil
 }
 
 type tag struct {
 	key       string
 	users     []*nodeUsers
+	large     bool         // 是否是超大群
 	ref       atomic.Int32 // 引用计数
 	createdAt time.Time    // 创建时间
 }
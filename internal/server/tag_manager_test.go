@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testLeaderIdOf 模拟3个节点的槽分布
+func testLeaderIdOf(uid string) (uint64, error) {
+	return uint64(crc32.ChecksumIEEE([]byte(uid))%3) + 1, nil
+}
+
+func testUids(start, count int) []string {
+	uids := make([]string, 0, count)
+	for i := start; i < start+count; i++ {
+		uids = append(uids, fmt.Sprintf("u%d", i))
+	}
+	return uids
+}
+
+func TestMergeNodeUsers(t *testing.T) {
+	users, err := groupUsersByNode(testUids(0, 100), testLeaderIdOf)
+	assert.NoError(t, err)
+
+	u0NodeId, _ := testLeaderIdOf("u0")
+	addUsers := []*nodeUsers{
+		{nodeId: u0NodeId, uids: []string{"u0", "new1"}}, // u0已经存在
+		{nodeId: 4, uids: []string{"new2", "u1"}},
+	}
+	newUsers := mergeNodeUsers(users, addUsers, map[string]struct{}{"u1": {}, "u2": {}})
+
+	uidNodes := map[string]uint64{}
+	count := 0
+	for _, nodeUser := range newUsers {
+		for _, uid := range nodeUser.uids {
+			uidNodes[uid] = nodeUser.nodeId
+			count++
+		}
+	}
+	assert.Equal(t, len(uidNodes), count) // 没有重复的用户
+	assert.Equal(t, u0NodeId, uidNodes["new1"])
+	assert.Equal(t, uint64(4), uidNodes["new2"])
+	_, ok := uidNodes["u1"]
+	assert.False(t, ok)
+	_, ok = uidNodes["u2"]
+	assert.False(t, ok)
+	assert.Equal(t, 100, count) // 100 - 2 + 2
+
+	// 不修改原来的列表
+	total := 0
+	for _, nodeUser := range users {
+		total += len(nodeUser.uids)
+	}
+	assert.Equal(t, 100, total)
+}
+
+func TestTagRespLarge(t *testing.T) {
+	resp := &tagResp{tagKey: "t1", uids: []string{"u1", "u2"}, large: true}
+	newResp := &tagResp{}
+	err := newResp.Unmarshal(resp.Marshal())
+	assert.NoError(t, err)
+	assert.Equal(t, resp, newResp)
+
+	// 旧版本节点没有large字段
+	data := resp.Marshal()
+	newResp = &tagResp{}
+	err = newResp.Unmarshal(data[:len(data)-1])
+	assert.NoError(t, err)
+	assert.False(t, newResp.large)
+	assert.Equal(t, []string{"u1", "u2"}, newResp.uids)
+}
+
+func BenchmarkMakeReceiverTag(b *testing.B) {
+	for _, count := range []int{100000, 1000000} {
+		uids := testUids(0, count)
+		b.Run(fmt.Sprintf("members-%d", count), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := groupUsersByNode(uids, testLeaderIdOf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUpdateReceiverTag(b *testing.B) {
+	for _, count := range []int{100000, 1000000} {
+		users, err := groupUsersByNode(testUids(0, count), testLeaderIdOf)
+		if err != nil {
+			b.Fatal(err)
+		}
+		addUids := testUids(count, 100)
+		removeUids := map[string]struct{}{}
+		for _, uid := range testUids(0, 100) {
+			removeUids[uid] = struct{}{}
+		}
+		b.Run(fmt.Sprintf("members-%d", count), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				addUsers, err := groupUsersByNode(addUids, testLeaderIdOf)
+				if err != nil {
+					b.Fatal(err)
+				}
+				mergeNodeUsers(users, addUsers, removeUids)
+			}
+		})
+	}
+}
@@ -0,0 +1,689 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type userDataJobType string
+
+const (
+	userDataJobTypeExport userDataJobType = "export" // 导出
+	userDataJobTypeErase  userDataJobType = "erase"  // 擦除
+)
+
+type userDataJobStatus string
+
+const (
+	userDataJobStatusPending userDataJobStatus = "pending" // 等待执行
+	userDataJobStatusRunning userDataJobStatus = "running" // 执行中
+	userDataJobStatusDone    userDataJobStatus = "done"    // 已完成
+	userDataJobStatusFailed  userDataJobStatus = "failed"  // 失败
+)
+
+// 用户数据记录类型
+const (
+	userDataRecordUser         = "user"
+	userDataRecordDevice       = "device"
+	userDataRecordConversation = "conversation"
+	userDataRecordSubscriber   = "subscriber"
+	userDataRecordDenylist     = "denylist"
+	userDataRecordAllowlist    = "allowlist"
+	userDataRecordMessage      = "message"
+)
+
+// userDataRecord 导出的一条用户数据
+type userDataRecord struct {
+	Type string      `json:"type"` // 记录类型
+	Key  string      `json:"key"`  // 记录唯一标识（用于多节点去重）
+	Data interface{} `json:"data"` // 记录内容
+}
+
+// userDataJob 用户数据任务
+type userDataJob struct {
+	Id           string            `json:"job_id"`
+	Type         userDataJobType   `json:"type"`
+	Uid          string            `json:"uid"`
+	Status       userDataJobStatus `json:"status"`
+	TotalStep    int               `json:"total_step"`             // 总步骤数
+	FinishedStep int               `json:"finished_step"`          // 已完成步骤数
+	RecordCount  int               `json:"record_count"`           // 导出的记录数
+	Blocked      bool              `json:"blocked,omitempty"`      // 擦除前是否已封禁用户
+	PrevBanned   bool              `json:"prev_banned,omitempty"`  // 擦除前用户原来的封禁状态
+	ErasedNodes  []uint64          `json:"erased_nodes,omitempty"` // 已擦除消息的节点
+	Error        string            `json:"error,omitempty"`
+	CreatedAt    int64             `json:"created_at"`
+	FinishedAt   int64             `json:"finished_at,omitempty"`
+}
+
+// userDataManager 用户数据导出与擦除管理
+type userDataManager struct {
+	s      *Server
+	jobs   map[string]*userDataJob
+	mu     sync.RWMutex
+	seq    atomic.Uint64
+	stopC  chan struct{}
+	wg     sync.WaitGroup
+	dir    string
+	nodeId uint64
+	wklog.Log
+}
+
+func newUserDataManager(s *Server) *userDataManager {
+	return &userDataManager{
+		s:      s,
+		jobs:   make(map[string]*userDataJob),
+		stopC:  make(chan struct{}),
+		dir:    path.Join(s.opts.DataDir, "userdata"),
+		nodeId: s.opts.Cluster.NodeId,
+		Log:    wklog.NewWKLog("userDataManager"),
+	}
+}
+
+// start 加载持久化的任务，重启前未完成的擦除任务继续执行（擦除是幂等的），未完成的导出任务标记为失败
+func (u *userDataManager) start() error {
+	if err := os.MkdirAll(u.jobDir(), 0755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(u.jobDir())
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(path.Join(u.jobDir(), entry.Name()))
+		if err != nil {
+			return err
+		}
+		job := &userDataJob{}
+		if err = wkutil.ReadJSONByByte(data, job); err != nil {
+			u.Warn("用户数据任务文件格式有误！", zap.Error(err), zap.String("file", entry.Name()))
+			continue
+		}
+		strs := strings.Split(job.Id, "-")
+		if len(strs) == 2 {
+			if seq := wkutil.ParseUint64(strs[1]); seq > u.seq.Load() {
+				u.seq.Store(seq)
+			}
+		}
+		u.jobs[job.Id] = job
+		if job.Status != userDataJobStatusPending && job.Status != userDataJobStatusRunning {
+			continue
+		}
+		if job.Type == userDataJobTypeErase {
+			u.Info("继续执行未完成的用户数据擦除任务", zap.String("jobId", job.Id), zap.String("uid", job.Uid))
+			u.runAsync(job)
+			continue
+		}
+		job.Status = userDataJobStatusFailed
+		job.Error = "interrupted by node restart"
+		job.FinishedAt = time.Now().Unix()
+		u.save(job)
+	}
+	return nil
+}
+
+func (u *userDataManager) stop() {
+	close(u.stopC)
+	u.wg.Wait()
+}
+
+func (u *userDataManager) jobDir() string {
+	return path.Join(u.dir, "jobs")
+}
+
+// save 持久化任务状态（需要在持有锁或任务未并发修改时调用）
+func (u *userDataManager) save(job *userDataJob) {
+	tmp := path.Join(u.jobDir(), fmt.Sprintf("%s.json.tmp", job.Id))
+	if err := os.WriteFile(tmp, []byte(wkutil.ToJSON(job)), 0644); err != nil {
+		u.Warn("保存用户数据任务失败！", zap.Error(err), zap.String("jobId", job.Id))
+		return
+	}
+	if err := os.Rename(tmp, path.Join(u.jobDir(), fmt.Sprintf("%s.json", job.Id))); err != nil {
+		u.Warn("保存用户数据任务失败！", zap.Error(err), zap.String("jobId", job.Id))
+	}
+}
+
+// submit 提交任务
+func (u *userDataManager) submit(tp userDataJobType, uid string) *userDataJob {
+	job := &userDataJob{
+		Id:        fmt.Sprintf("%d-%d", u.nodeId, u.seq.Add(1)),
+		Type:      tp,
+		Uid:       uid,
+		Status:    userDataJobStatusPending,
+		CreatedAt: time.Now().Unix(),
+	}
+	u.mu.Lock()
+	u.jobs[job.Id] = job
+	u.save(job)
+	u.mu.Unlock()
+
+	u.runAsync(job)
+	return u.snapshot(job)
+}
+
+func (u *userDataManager) runAsync(job *userDataJob) {
+	u.wg.Add(1)
+	go func() {
+		defer u.wg.Done()
+		u.run(job)
+	}()
+}
+
+// getJob 获取任务（返回副本）
+func (u *userDataManager) getJob(jobId string) *userDataJob {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	job := u.jobs[jobId]
+	if job == nil {
+		return nil
+	}
+	cp := *job
+	return &cp
+}
+
+// jobNodeId 任务所在节点
+func (u *userDataManager) jobNodeId(jobId string) (uint64, bool) {
+	strs := strings.Split(jobId, "-")
+	if len(strs) != 2 {
+		return 0, false
+	}
+	nodeId := wkutil.ParseUint64(strs[0])
+	if nodeId == 0 && u.s.opts.ClusterOn() {
+		return 0, false
+	}
+	return nodeId, true
+}
+
+func (u *userDataManager) exportFile(jobId string) string {
+	return path.Join(u.dir, fmt.Sprintf("%s.jsonl", jobId))
+}
+
+func (u *userDataManager) snapshot(job *userDataJob) *userDataJob {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	cp := *job
+	return &cp
+}
+
+func (u *userDataManager) update(job *userDataJob, f func(j *userDataJob)) {
+	u.mu.Lock()
+	f(job)
+	u.save(job)
+	u.mu.Unlock()
+}
+
+func (u *userDataManager) run(job *userDataJob) {
+	u.update(job, func(j *userDataJob) {
+		j.Status = userDataJobStatusRunning
+	})
+	var err error
+	switch job.Type {
+	case userDataJobTypeExport:
+		err = u.runExport(job)
+	case userDataJobTypeErase:
+		err = u.runErase(job)
+	default:
+		err = fmt.Errorf("unknown job type: %s", job.Type)
+	}
+	if err != nil && u.stopped() {
+		// 节点停止导致的中断保持执行中状态，重启后继续执行
+		return
+	}
+	u.update(job, func(j *userDataJob) {
+		j.FinishedAt = time.Now().Unix()
+		if err != nil {
+			j.Status = userDataJobStatusFailed
+			j.Error = err.Error()
+		} else {
+			j.Status = userDataJobStatusDone
+		}
+	})
+	if err != nil {
+		u.Error("用户数据任务执行失败！", zap.Error(err), zap.String("jobId", job.Id), zap.String("type", string(job.Type)), zap.String("uid", job.Uid))
+	} else {
+		u.Info("用户数据任务执行完成", zap.String("jobId", job.Id), zap.String("type", string(job.Type)), zap.String("uid", job.Uid))
+	}
+}
+
+// runExport 从所有节点收集用户数据并写入jsonl文件
+func (u *userDataManager) runExport(job *userDataJob) error {
+	nodes := u.targetNodes()
+	u.update(job, func(j *userDataJob) {
+		j.TotalStep = len(nodes) + 1
+	})
+
+	if err := os.MkdirAll(u.dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(u.exportFile(job.Id))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	written := make(map[string]struct{})
+	for _, node := range nodes {
+		if u.stopped() {
+			return errors.New("user data manager stopped")
+		}
+		var records []*userDataRecord
+		if node == nil {
+			records, err = u.localExport(job.Uid)
+		} else {
+			err = u.requestNode(node, "/wk/userDataExport", job.Uid, &records)
+		}
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if _, ok := written[record.Key]; ok {
+				continue
+			}
+			written[record.Key] = struct{}{}
+			if _, err = w.WriteString(wkutil.ToJSON(record) + "\n"); err != nil {
+				return err
+			}
+		}
+		u.update(job, func(j *userDataJob) {
+			j.FinishedStep++
+			j.RecordCount = len(written)
+		})
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	u.update(job, func(j *userDataJob) {
+		j.FinishedStep++
+	})
+	return nil
+}
+
+// runErase 擦除用户数据
+// 1. 封禁用户并踢掉用户的连接，擦除期间用户不能再连接和发送消息
+// 2. 移除用户在各频道的订阅者、黑名单、白名单
+// 3. 删除用户、设备和会话（通过分布式存储提交）
+// 4. 在每个节点上擦除用户发送的消息，记录已擦除的节点，重启后跳过
+// 5. 恢复用户原来的封禁状态
+func (u *userDataManager) runErase(job *userDataJob) error {
+	nodes := u.targetNodes()
+	eraseNodes := u.allNodes()
+	u.update(job, func(j *userDataJob) {
+		j.TotalStep = len(nodes) + len(eraseNodes) + 2
+		j.FinishedStep = 0
+	})
+
+	if !job.Blocked {
+		banned, err := u.banUser(job.Uid, true)
+		if err != nil {
+			return err
+		}
+		u.update(job, func(j *userDataJob) {
+			j.Blocked = true
+			j.PrevBanned = banned
+		})
+	}
+	// 踢掉用户的连接（用户连接在用户所在槽的领导节点上）
+	if err := u.kickUser(job.Uid); err != nil {
+		return err
+	}
+	u.update(job, func(j *userDataJob) {
+		j.FinishedStep++
+	})
+
+	// 收集用户所在频道
+	memberOfChannels := make(map[string]wkdb.MemberOfChannel)
+	for _, node := range nodes {
+		var members []wkdb.MemberOfChannel
+		var err error
+		if node == nil {
+			members, err = u.s.store.GetChannelsByMember(job.Uid)
+		} else {
+			err = u.requestNode(node, "/wk/userDataChannels", job.Uid, &members)
+		}
+		if err != nil {
+			return err
+		}
+		for _, m := range members {
+			key := wkutil.ChannelToKey(m.ChannelId, m.ChannelType)
+			exist := memberOfChannels[key]
+			exist.ChannelId = m.ChannelId
+			exist.ChannelType = m.ChannelType
+			exist.Subscriber = exist.Subscriber || m.Subscriber
+			exist.Denylist = exist.Denylist || m.Denylist
+			exist.Allowlist = exist.Allowlist || m.Allowlist
+			memberOfChannels[key] = exist
+		}
+		u.update(job, func(j *userDataJob) {
+			j.FinishedStep++
+		})
+	}
+
+	for channelKey, m := range memberOfChannels {
+		if u.stopped() {
+			return errors.New("user data manager stopped")
+		}
+		if m.Subscriber {
+			if err := u.s.store.RemoveSubscribers(m.ChannelId, m.ChannelType, []string{job.Uid}); err != nil {
+				return err
+			}
+			channel := u.s.channelReactor.reactorSub(channelKey).channel(channelKey)
+			if channel != nil {
+				// 从接收者标签中移除用户
+				if _, err := channel.updateReceiverTag(nil, []string{job.Uid}); err != nil {
+					u.Warn("创建接收者标签失败！", zap.Error(err), zap.String("channelKey", channelKey))
+				}
+			}
+		}
+		if m.Denylist {
+			if err := u.s.store.RemoveDenylist(m.ChannelId, m.ChannelType, []string{job.Uid}); err != nil {
+				return err
+			}
+		}
+		if m.Allowlist {
+			if err := u.s.store.RemoveAllowlist(m.ChannelId, m.ChannelType, []string{job.Uid}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 删除用户、设备和会话
+	if err := u.s.store.DeleteUser(job.Uid); err != nil {
+		return err
+	}
+	u.update(job, func(j *userDataJob) {
+		j.FinishedStep++
+	})
+
+	// 擦除用户发送的消息（消息存储在频道副本所在的节点上，每个节点擦除一次本地的消息）
+	for _, node := range eraseNodes {
+		nodeId := u.nodeId
+		if node != nil {
+			nodeId = node.Id
+		}
+		if !u.erasedNode(job, nodeId) {
+			if err := u.eraseNodeMessages(node, job.Uid); err != nil {
+				return err
+			}
+		}
+		u.update(job, func(j *userDataJob) {
+			if !wkutil.ArrayContainsUint64(j.ErasedNodes, nodeId) {
+				j.ErasedNodes = append(j.ErasedNodes, nodeId)
+			}
+			j.FinishedStep++
+		})
+	}
+
+	// 擦除完成后再解除封禁，之后用户新发送的消息不属于本次擦除的范围
+	if !job.PrevBanned {
+		if _, err := u.banUser(job.Uid, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// banUser 设置用户个人频道的封禁状态，返回设置之前是否已经被封禁
+func (u *userDataManager) banUser(uid string, ban bool) (bool, error) {
+	channelInfo, err := u.s.store.GetChannel(uid, wkproto.ChannelTypePerson)
+	if err != nil && err != wkdb.ErrNotFound {
+		return false, err
+	}
+	if wkdb.IsEmptyChannelInfo(channelInfo) {
+		if !ban {
+			return false, nil
+		}
+		return false, u.s.store.AddChannelInfo(wkdb.ChannelInfo{
+			ChannelId:   uid,
+			ChannelType: wkproto.ChannelTypePerson,
+			Ban:         true,
+		})
+	}
+	banned := channelInfo.Ban
+	if banned == ban {
+		return banned, nil
+	}
+	channelInfo.Ban = ban
+	return banned, u.s.store.UpdateChannelInfo(channelInfo)
+}
+
+func (u *userDataManager) kickUser(uid string) error {
+	leaderInfo, err := u.s.cluster.SlotLeaderOfChannel(uid, wkproto.ChannelTypePerson)
+	if err != nil {
+		return err
+	}
+	if leaderInfo.Id == u.s.opts.Cluster.NodeId {
+		u.localKick(uid)
+		return nil
+	}
+	return u.requestNode(leaderInfo, "/wk/userDataKick", uid, nil)
+}
+
+// erasedNode 节点上的消息是否已擦除
+func (u *userDataManager) erasedNode(job *userDataJob, nodeId uint64) bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return wkutil.ArrayContainsUint64(job.ErasedNodes, nodeId)
+}
+
+// eraseNodeMessages 擦除节点上用户发送的消息，节点不可用时等待重试直到成功
+func (u *userDataManager) eraseNodeMessages(node *pb.Node, uid string) error {
+	for {
+		var err error
+		if node == nil {
+			_, err = u.localEraseMessages(uid)
+		} else {
+			err = u.requestNode(node, "/wk/userDataErase", uid, nil)
+		}
+		if err == nil {
+			return nil
+		}
+		u.Warn("擦除节点上的用户消息失败，稍后重试！", zap.Error(err), zap.String("uid", uid))
+		select {
+		case <-time.After(time.Second * 5):
+		case <-u.stopC:
+			return errors.New("user data manager stopped")
+		}
+	}
+}
+
+// allNodes 集群内的所有节点（包括离线节点），nil表示本节点
+func (u *userDataManager) allNodes() []*pb.Node {
+	nodes := []*pb.Node{nil}
+	if !u.s.opts.ClusterOn() {
+		return nodes
+	}
+	for _, node := range u.s.clusterServer.GetConfig().Nodes {
+		if node.Id == u.s.opts.Cluster.NodeId {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// targetNodes 需要执行的节点，nil表示本节点
+func (u *userDataManager) targetNodes() []*pb.Node {
+	nodes := []*pb.Node{nil}
+	if !u.s.opts.ClusterOn() {
+		return nodes
+	}
+	for _, node := range u.s.clusterServer.GetConfig().Nodes {
+		if node.Id == u.s.opts.Cluster.NodeId {
+			continue
+		}
+		if !node.Online {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func (u *userDataManager) stopped() bool {
+	select {
+	case <-u.stopC:
+		return true
+	default:
+		return false
+	}
+}
+
+// requestNode 通过节点间通信请求其他节点（不经过对外的api）
+func (u *userDataManager) requestNode(node *pb.Node, p string, uid string, result interface{}) error {
+	timeoutCtx, cancel := context.WithTimeout(u.s.ctx, u.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := u.s.cluster.RequestWithContext(timeoutCtx, node.Id, p, []byte(uid))
+	if err != nil {
+		u.Error("请求节点用户数据失败！", zap.Error(err), zap.Uint64("nodeId", node.Id), zap.String("path", p))
+		return err
+	}
+	if resp.Status != proto.Status_OK {
+		return fmt.Errorf("请求节点用户数据状态错误！[%d] %s", resp.Status, string(resp.Body))
+	}
+	if result == nil {
+		return nil
+	}
+	return wkutil.ReadJSONByByte(resp.Body, result)
+}
+
+// localExport 导出本节点上的用户数据
+func (u *userDataManager) localExport(uid string) ([]*userDataRecord, error) {
+	records := make([]*userDataRecord, 0)
+
+	user, err := u.s.store.GetUser(uid)
+	if err != nil && err != wkdb.ErrNotFound {
+		return nil, err
+	}
+	if err == nil && !wkdb.IsEmptyUser(user) {
+		records = append(records, &userDataRecord{Type: userDataRecordUser, Key: fmt.Sprintf("user:%s", uid), Data: user})
+	}
+
+	devices, err := u.s.store.GetDevices(uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		records = append(records, &userDataRecord{Type: userDataRecordDevice, Key: fmt.Sprintf("device:%d", device.DeviceFlag), Data: device})
+	}
+
+	conversations, err := u.s.store.GetConversations(uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, conversation := range conversations {
+		records = append(records, &userDataRecord{Type: userDataRecordConversation, Key: fmt.Sprintf("conversation:%s", wkutil.ChannelToKey(conversation.ChannelId, conversation.ChannelType)), Data: conversation})
+	}
+
+	members, err := u.s.store.GetChannelsByMember(uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		channelKey := wkutil.ChannelToKey(m.ChannelId, m.ChannelType)
+		if m.Subscriber {
+			records = append(records, &userDataRecord{Type: userDataRecordSubscriber, Key: fmt.Sprintf("subscriber:%s", channelKey), Data: m})
+		}
+		if m.Denylist {
+			records = append(records, &userDataRecord{Type: userDataRecordDenylist, Key: fmt.Sprintf("denylist:%s", channelKey), Data: m})
+		}
+		if m.Allowlist {
+			records = append(records, &userDataRecord{Type: userDataRecordAllowlist, Key: fmt.Sprintf("allowlist:%s", channelKey), Data: m})
+		}
+	}
+
+	messages, err := u.s.store.GetMessagesByFromUid(uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		resp := &MessageResp{}
+		resp.from(message, u.s)
+		records = append(records, &userDataRecord{Type: userDataRecordMessage, Key: fmt.Sprintf("message:%d", message.MessageID), Data: resp})
+	}
+	return records, nil
+}
+
+// localKick 踢掉本节点上用户的连接
+func (u *userDataManager) localKick(uid string) {
+	conns := u.s.userReactor.getConnContexts(uid)
+	for _, conn := range conns {
+		_ = u.s.userReactor.writePacket(conn, &wkproto.DisconnectPacket{
+			ReasonCode: wkproto.ReasonConnectKick,
+		})
+		c := conn
+		u.s.timingWheel.AfterFunc(time.Second*2, func() {
+			c.close()
+		})
+	}
+}
+
+// localEraseMessages 擦除本节点上用户发送的消息
+func (u *userDataManager) localEraseMessages(uid string) (int, error) {
+	count, err := u.s.store.EraseMessagesByFromUid(uid)
+	if err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		u.Info("擦除用户消息", zap.String("uid", uid), zap.Int("count", count))
+	}
+	return count, nil
+}
+
+// handleLocalExport 导出本节点的用户数据（节点内部调用）
+func (u *userDataManager) handleLocalExport(c *wkserver.Context) {
+	records, err := u.localExport(string(c.Body()))
+	if err != nil {
+		u.Error("导出用户数据失败！", zap.Error(err), zap.String("uid", string(c.Body())))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(records)))
+}
+
+// handleLocalChannels 获取本节点上用户所在的频道（节点内部调用）
+func (u *userDataManager) handleLocalChannels(c *wkserver.Context) {
+	members, err := u.s.store.GetChannelsByMember(string(c.Body()))
+	if err != nil {
+		u.Error("获取用户所在频道失败！", zap.Error(err), zap.String("uid", string(c.Body())))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(members)))
+}
+
+// handleLocalKick 踢掉本节点上用户的连接（节点内部调用）
+func (u *userDataManager) handleLocalKick(c *wkserver.Context) {
+	u.localKick(string(c.Body()))
+	c.WriteOk()
+}
+
+// handleLocalErase 擦除本节点上用户发送的消息（节点内部调用）
+func (u *userDataManager) handleLocalErase(c *wkserver.Context) {
+	if _, err := u.localEraseMessages(string(c.Body())); err != nil {
+		u.Error("擦除用户消息失败！", zap.Error(err), zap.String("uid", string(c.Body())))
+		c.WriteErr(err)
+		return
+	}
+	c.WriteOk()
+}
@@ -146,6 +146,15 @@ func (u *userReactor) getConnContextCountByDeviceFlag(uid string, deviceFlag wkp
 	return len(u.getConnContextByDeviceFlag(uid, deviceFlag))
 }
 
+// 获取本节点上的所有真实连接
+func (u *userReactor) getRealConnContexts() []*connContext {
+	var conns []*connContext
+	for _, sub := range u.subs {
+		conns = append(conns, sub.getRealConnContexts()...)
+	}
+	return conns
+}
+
 func (u *userReactor) getConnContextCount(uid string) int {
 	return u.reactorSub(uid).getConnContextCount(uid)
 }
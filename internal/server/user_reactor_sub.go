@@ -342,6 +342,20 @@ func (u *userReactorSub) getConnContextByDeviceFlag(uid string, deviceFlag wkpro
 	return conns
 }
 
+// 获取本节点上的所有真实连接
+func (u *userReactorSub) getRealConnContexts() []*connContext {
+	var conns []*connContext
+	u.users.iter(func(uh *userHandler) bool {
+		for _, c := range uh.getConns() {
+			if c.isRealConn {
+				conns = append(conns, c)
+			}
+		}
+		return true
+	})
+	return conns
+}
+
 // func (u *userReactorSub) removeConnContext(uid string, deviceId string) {
 // 	u.mu.Lock()
 // 	defer u.mu.Unlock()
@@ -4,15 +4,19 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
+	"os"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/grpcpool"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
@@ -21,8 +25,10 @@ import (
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
 )
 
 type webhook struct {
@@ -46,9 +52,19 @@ func newWebhook(s *Server) *webhook {
 	var (
 		webhookGRPCPool *grpcpool.Pool
 	)
+	log := wklog.NewWKLog("Webhook")
+	// 证书配置已经在Options.Check中校验过，这里出错说明证书文件在启动过程中被改动了
+	tlsConfig, err := newWebhookTLSConfig(s.opts)
+	if err != nil {
+		log.Error("load webhook tls config failed", zap.Error(err))
+	}
 	if s.opts.WebhookGRPCOn() {
+		transportCredentials := insecure.NewCredentials()
+		if s.opts.Webhook.TLS.On {
+			transportCredentials = credentials.NewTLS(tlsConfig)
+		}
 		webhookGRPCPool, err = grpcpool.New(func() (*grpc.ClientConn, error) {
-			return grpc.Dial(s.opts.Webhook.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			return grpc.Dial(s.opts.Webhook.GRPCAddr, grpc.WithTransportCredentials(transportCredentials), grpc.WithKeepaliveParams(keepalive.ClientParameters{
 				Time:    5 * time.Minute, // send pings every 5 minute if there is no activity
 				Timeout: 2 * time.Second, // wait 1 second for ping ack before considering the connection dead
 			}))
@@ -60,7 +76,7 @@ func newWebhook(s *Server) *webhook {
 	}
 	return &webhook{
 		s:                s,
-		Log:              wklog.NewWKLog("Webhook"),
+		Log:              log,
 		eventPool:        eventPool,
 		webhookGRPCPool:  webhookGRPCPool,
 		onlinestatusList: make([]string, 0),
@@ -71,6 +87,7 @@ func newWebhook(s *Server) *webhook {
 					Timeout:   5 * time.Second,
 					KeepAlive: 5 * time.Second,
 				}).DialContext,
+				TLSClientConfig:       tlsConfig,
 				ForceAttemptHTTP2:     true,
 				MaxIdleConns:          200,
 				MaxIdleConnsPerHost:   200,
@@ -83,6 +100,38 @@ func newWebhook(s *Server) *webhook {
 	}
 }
 
+// newWebhookTLSConfig webhook客户端的tls配置，配置了客户端证书时进行双向认证
+func newWebhookTLSConfig(opts *Options) (*tls.Config, error) {
+	tlsOpts := opts.Webhook.TLS
+	if !tlsOpts.On && !opts.WebhookTLSConfigured() {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         tlsOpts.ServerName,
+		InsecureSkipVerify: tlsOpts.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if tlsOpts.CAFile != "" {
+		caData, err := os.ReadFile(tlsOpts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("webhook ca file is invalid: %s", tlsOpts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if tlsOpts.CertFile != "" && tlsOpts.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(tlsOpts.CertFile, tlsOpts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
 func (w *webhook) Start() {
 	go w.notifyQueueLoop()
 	go w.loopOnlineStatus()
@@ -94,6 +143,9 @@ func (w *webhook) Stop() {
 
 // Online 用户设备上线通知
 func (w *webhook) Online(uid string, deviceFlag wkproto.DeviceFlag, connId int64, deviceOnlineCount int, totalOnlineCount int) {
+	if !w.s.opts.WebhookEventOn(EventOnlineStatus) {
+		return
+	}
 	w.onlinestatusLock.Lock()
 	defer w.onlinestatusLock.Unlock()
 	online := 1
@@ -103,6 +155,9 @@ func (w *webhook) Online(uid string, deviceFlag wkproto.DeviceFlag, connId int64
 }
 
 func (w *webhook) Offline(uid string, deviceFlag wkproto.DeviceFlag, connId int64, deviceOnlineCount int, totalOnlineCount int) {
+	if !w.s.opts.WebhookEventOn(EventOnlineStatus) {
+		return
+	}
 	w.onlinestatusLock.Lock()
 	defer w.onlinestatusLock.Unlock()
 	online := 0
@@ -112,9 +167,22 @@ func (w *webhook) Offline(uid string, deviceFlag wkproto.DeviceFlag, connId int6
 	w.Debug("User offline", zap.String("uid", uid), zap.String("deviceFlag", deviceFlag.String()))
 }
 
-// TriggerEvent 触发事件
+// TriggerEvent 触发事件，事件会推送给订阅了此事件的接收方
 func (w *webhook) TriggerEvent(event *Event) {
-	if !w.s.opts.WebhookOn() { // 没设置webhook直接忽略
+	if w.s.opts.EventSinkEventOn(event.Event, event.ChannelType) {
+		err := w.s.eventSinkManager.appendEvent(event)
+		if err != nil {
+			w.Error("追加投递到消息中间件的事件失败！", zap.Error(err), zap.String("event", event.Event))
+		}
+	}
+	mainOn := w.s.opts.WebhookOn() && w.s.opts.Webhook.Filter.Allow(event.Event, event.ChannelType)
+	subscribers := make([]string, 0)
+	for _, subscriber := range w.s.opts.Webhook.Subscribers {
+		if subscriber.Allow(event.Event, event.ChannelType) {
+			subscribers = append(subscribers, subscriber.HTTPAddr)
+		}
+	}
+	if !mainOn && len(subscribers) == 0 { // 没有接收方直接忽略
 		return
 	}
 	err := w.eventPool.Submit(func() {
@@ -123,17 +191,20 @@ func (w *webhook) TriggerEvent(event *Event) {
 			w.Error("webhook的event数据不能json化！", zap.Error(err))
 			return
 		}
+		eventId := strconv.FormatUint(w.s.store.NextPrimaryKey(), 10)
 
-		if w.s.opts.WebhookGRPCOn() {
-			err = w.sendWebhookForGRPC(event.Event, jsonData)
-		} else {
-			err = w.sendWebhookForHttp(event.Event, jsonData)
+		if mainOn {
+			err = w.sendWebhook(event.Event, eventId, jsonData)
+			if err != nil {
+				w.Error("请求webhook失败！", zap.Error(err), zap.String("event", event.Event))
+			}
 		}
-		if err != nil {
-			w.Error("请求webhook失败！", zap.Error(err), zap.String("event", event.Event))
-			return
+		for _, addr := range subscribers {
+			err = w.sendWebhookForHttpAddr(addr, event.Event, eventId, jsonData)
+			if err != nil {
+				w.Error("请求webhook订阅者失败！", zap.Error(err), zap.String("event", event.Event), zap.String("addr", addr))
+			}
 		}
-
 	})
 	if err != nil {
 		w.Error("提交事件失败", zap.Error(err))
@@ -159,7 +230,9 @@ func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []stri
 	}
 	// 推送离线到上层应用
 	w.TriggerEvent(&Event{
-		Event: EventMsgOffline,
+		Event:       EventMsgOffline,
+		ChannelType: msg.SendPacket.ChannelType,
+		Key:         wkutil.ChannelToKey(msg.SendPacket.ChannelID, msg.SendPacket.ChannelType),
 		Data: MessageOfflineNotify{
 			MessageResp: MessageResp{
 				Header: MessageHeader{
@@ -192,7 +265,10 @@ func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []stri
 func (w *webhook) notifyQueueLoop() {
 	errorSleepTime := time.Second * 1 // 发生错误后sleep时间
 	ticker := time.NewTicker(w.s.opts.Webhook.MsgNotifyEventPushInterval)
-	errMessageIDMap := make(map[int64]int) // 记录错误的消息ID value为错误次数
+	var (
+		eventId  string  // 当前批次的事件id，重试时复用，方便接收方去重
+		batchIDs []int64 // 当前批次的消息id
+	)
 	if w.s.opts.WebhookOn() {
 		for {
 			messages, err := w.s.store.GetMessagesOfNotifyQueue(w.s.opts.Webhook.MsgNotifyEventCountPerPush)
@@ -202,6 +278,21 @@ func (w *webhook) notifyQueueLoop() {
 				continue
 			}
 			if len(messages) > 0 {
+				// 重试时只发送上一批的消息，保证同一个事件id对应的内容不变
+				if eventId != "" {
+					if len(messages) >= len(batchIDs) && hasMessageIDs(messages[:len(batchIDs)], batchIDs) {
+						messages = messages[:len(batchIDs)]
+					} else {
+						eventId = ""
+					}
+				}
+				if eventId == "" {
+					eventId = strconv.FormatUint(w.s.store.NextPrimaryKey(), 10)
+					batchIDs = batchIDs[:0]
+					for _, msg := range messages {
+						batchIDs = append(batchIDs, msg.MessageID)
+					}
+				}
 				messageResps := make([]*MessageResp, 0, len(messages))
 				for _, msg := range messages {
 					resp := &MessageResp{}
@@ -215,42 +306,20 @@ func (w *webhook) notifyQueueLoop() {
 					continue
 				}
 
-				if w.s.opts.WebhookGRPCOn() {
-					err = w.sendWebhookForGRPC(EventMsgNotify, messageData)
-				} else {
-					err = w.sendWebhookForHttp(EventMsgNotify, messageData)
-				}
+				err = w.sendWebhook(EventMsgNotify, eventId, messageData)
 				if err != nil {
 					w.Error("请求所有消息通知webhook失败！", zap.Error(err))
-					errMessageIDs := make([]int64, 0, len(messages))
-					for _, message := range messages {
-						errCount := errMessageIDMap[message.MessageID]
-						errCount++
-						errMessageIDMap[message.MessageID] = errCount
-						if errCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
-							errMessageIDs = append(errMessageIDs, message.MessageID)
-						}
+					retryCount := w.handleNotifyFail(messageResps, err)
+					if !w.sleepBackoff(retryCount) {
+						return
 					}
-					if len(errMessageIDs) > 0 {
-						w.Error("消息通知失败超过最大次数！", zap.Int64s("messageIDs", errMessageIDs))
-						err = w.s.store.RemoveMessagesOfNotifyQueue(errMessageIDs)
-						if err != nil {
-							w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", errMessageIDs))
-						}
-						for _, errMessageID := range errMessageIDs {
-							delete(errMessageIDMap, errMessageID)
-						}
-					}
-					time.Sleep(errorSleepTime) // 如果报错就休息下
 					continue
 				}
 
+				eventId = ""
 				messageIDs := make([]int64, 0, len(messages))
 				for _, message := range messages {
-					messageID := message.MessageID
-					messageIDs = append(messageIDs, messageID)
-
-					delete(errMessageIDMap, messageID)
+					messageIDs = append(messageIDs, message.MessageID)
 				}
 				err = w.s.store.RemoveMessagesOfNotifyQueue(messageIDs)
 				if err != nil {
@@ -269,17 +338,117 @@ func (w *webhook) notifyQueueLoop() {
 	}
 }
 
+// hasMessageIDs 消息的id是否和ids一一对应
+func hasMessageIDs(messages []wkdb.Message, ids []int64) bool {
+	if len(messages) != len(ids) {
+		return false
+	}
+	for i, msg := range messages {
+		if msg.MessageID != ids[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// handleNotifyFail 记录消息的失败次数，超过最大重试次数的消息移到死信里，返回剩余消息的最大失败次数
+func (w *webhook) handleNotifyFail(messageResps []*MessageResp, sendErr error) int {
+	messageIDs := make([]int64, 0, len(messageResps))
+	for _, resp := range messageResps {
+		messageIDs = append(messageIDs, resp.MessageId)
+	}
+	retryCounts, err := w.s.store.IncMessageNotifyRetryCount(messageIDs)
+	if err != nil {
+		w.Warn("记录消息通知失败次数失败！", zap.Error(err), zap.Int64s("messageIDs", messageIDs))
+		return 1
+	}
+
+	maxRetryCount := 0
+	deadResps := make([]*MessageResp, 0)
+	deadMessageIDs := make([]int64, 0)
+	for _, resp := range messageResps {
+		retryCount := retryCounts[resp.MessageId]
+		if retryCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
+			deadResps = append(deadResps, resp)
+			deadMessageIDs = append(deadMessageIDs, resp.MessageId)
+			continue
+		}
+		if retryCount > maxRetryCount {
+			maxRetryCount = retryCount
+		}
+	}
+	if len(deadMessageIDs) == 0 {
+		return maxRetryCount
+	}
+
+	w.Error("消息通知失败超过最大次数，移到死信里！", zap.Int64s("messageIDs", deadMessageIDs))
+	data, err := json.Marshal(deadResps)
+	if err != nil {
+		w.Error("死信数据不能json化！", zap.Error(err))
+		return maxRetryCount
+	}
+	err = w.s.store.MoveMessagesOfNotifyQueueToDeadLetter(deadMessageIDs, wkdb.WebhookDeadLetter{
+		Id:         w.s.store.NextPrimaryKey(),
+		Event:      EventMsgNotify,
+		MessageIds: deadMessageIDs,
+		RetryCount: w.s.opts.Webhook.MsgNotifyEventRetryMaxCount,
+		Error:      sendErr.Error(),
+		CreatedAt:  time.Now(),
+		Data:       data,
+	})
+	if err != nil {
+		w.Warn("移动消息到死信失败！", zap.Error(err), zap.Int64s("messageIDs", deadMessageIDs))
+	}
+	return maxRetryCount
+}
+
+// sleepBackoff 按失败次数指数退避，服务停止返回false
+func (w *webhook) sleepBackoff(retryCount int) bool {
+	select {
+	case <-time.After(w.retryBackoff(retryCount)):
+		return true
+	case <-w.stoped:
+		return false
+	}
+}
+
+// retryBackoff 第retryCount次失败后的重试间隔
+func (w *webhook) retryBackoff(retryCount int) time.Duration {
+	backoff := w.s.opts.Webhook.RetryBackoff
+	maxBackoff := w.s.opts.Webhook.RetryBackoffMax
+	for i := 1; i < retryCount; i++ {
+		backoff *= 2
+		if maxBackoff > 0 && backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return backoff
+}
+
+// replayDeadLetter 重新投递死信，投递成功后删除（事件id使用死信id）
+func (w *webhook) replayDeadLetter(deadLetter wkdb.WebhookDeadLetter) error {
+	err := w.sendWebhook(deadLetter.Event, strconv.FormatUint(deadLetter.Id, 10), deadLetter.Data)
+	if err != nil {
+		return err
+	}
+	return w.s.store.RemoveWebhookDeadLetters([]uint64{deadLetter.Id})
+}
+
 func (w *webhook) loopOnlineStatus() {
 	if !w.s.opts.WebhookOn() {
 		return
 	}
 	opLen := 0    // 最后一次操作在线状态数组的长度
 	errCount := 0 // webhook请求失败重试次数
+	eventId := "" // 当前批次的事件id，重试时复用
 	for {
 		if opLen == 0 {
 			w.onlinestatusLock.Lock()
 			opLen = len(w.onlinestatusList)
 			w.onlinestatusLock.Unlock()
+			if opLen > 0 {
+				eventId = strconv.FormatUint(w.s.store.NextPrimaryKey(), 10)
+			}
 		}
 		if opLen == 0 {
 			time.Sleep(time.Second * 2) // 没有数据就休息2秒
@@ -295,11 +464,7 @@ func (w *webhook) loopOnlineStatus() {
 			continue
 		}
 
-		if w.s.opts.WebhookGRPCOn() {
-			err = w.sendWebhookForGRPC(EventOnlineStatus, jsonData)
-		} else {
-			err = w.sendWebhookForHttp(EventOnlineStatus, jsonData)
-		}
+		err = w.sendWebhook(EventOnlineStatus, eventId, jsonData)
 		if err != nil {
 			errCount++
 			w.Error("请求在线状态webhook失败！", zap.Error(err))
@@ -326,26 +491,63 @@ func (w *webhook) loopOnlineStatus() {
 	}
 }
 
-func (w *webhook) sendWebhookForHttp(event string, data []byte) error {
-	eventURL := fmt.Sprintf("%s?event=%s", w.s.opts.Webhook.HTTPAddr, event)
+// sendWebhook 发送webhook事件，eventId为空时生成一个新的事件id
+func (w *webhook) sendWebhook(event string, eventId string, data []byte) error {
+	if eventId == "" {
+		eventId = strconv.FormatUint(w.s.store.NextPrimaryKey(), 10)
+	}
+	if w.s.opts.WebhookGRPCOn() {
+		return w.sendWebhookForGRPC(event, eventId, data)
+	}
+	return w.sendWebhookForHttp(event, eventId, data)
+}
+
+// signHeaders 事件的签名头，没有配置密钥时只返回事件id
+func (w *webhook) signHeaders(eventId string, data []byte) map[string]string {
+	headers := map[string]string{
+		wkhook.HeaderEventId: eventId,
+	}
+	if len(w.s.opts.Webhook.Secrets) == 0 {
+		return headers
+	}
+	timestamp := time.Now().Unix()
+	headers[wkhook.HeaderTimestamp] = strconv.FormatInt(timestamp, 10)
+	headers[wkhook.HeaderSignature] = wkhook.SignatureHeader(w.s.opts.Webhook.Secrets, timestamp, eventId, data)
+	return headers
+}
+
+func (w *webhook) sendWebhookForHttp(event string, eventId string, data []byte) error {
+	return w.sendWebhookForHttpAddr(w.s.opts.Webhook.HTTPAddr, event, eventId, data)
+}
+
+func (w *webhook) sendWebhookForHttpAddr(addr string, event string, eventId string, data []byte) error {
+	eventURL := fmt.Sprintf("%s?event=%s", addr, event)
 	startTime := time.Now().UnixNano() / 1000 / 1000
 	w.Debug("webhook开始请求", zap.String("eventURL", eventURL))
-	resp, err := w.httpClient.Post(eventURL, "application/json", bytes.NewBuffer(data))
+	req, err := http.NewRequest(http.MethodPost, eventURL, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.signHeaders(eventId, data) {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.httpClient.Do(req)
 	w.Debug("webhook请求结束 耗时", zap.Int64("mill", time.Now().UnixNano()/1000/1000-startTime))
 	if err != nil {
-		w.Warn("调用第三方消息通知失败！", zap.String("Webhook", w.s.opts.Webhook.HTTPAddr), zap.Error(err))
+		w.Warn("调用第三方消息通知失败！", zap.String("Webhook", addr), zap.Error(err))
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		w.Warn("第三方消息通知接口返回状态错误！", zap.Int("status", resp.StatusCode), zap.String("Webhook", w.s.opts.Webhook.HTTPAddr))
+		w.Warn("第三方消息通知接口返回状态错误！", zap.Int("status", resp.StatusCode), zap.String("Webhook", addr))
 		return errors.New("第三方消息通知接口返回状态错误！")
 	}
 	return nil
 }
 
-func (w *webhook) sendWebhookForGRPC(event string, data []byte) error {
+func (w *webhook) sendWebhookForGRPC(event string, eventId string, data []byte) error {
 
 	startNow := time.Now()
 	startTime := startNow.UnixNano() / 1000 / 1000
@@ -366,6 +568,8 @@ func (w *webhook) sendWebhookForGRPC(event string, data []byte) error {
 
 	sendCtx, sendCancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer sendCancel()
+	md := metadata.New(w.signHeaders(eventId, data))
+	sendCtx = metadata.NewOutgoingContext(sendCtx, md)
 	resp, err := cli.SendWebhook(sendCtx, &wkhook.EventReq{
 		Event: event,
 		Data:  data,
@@ -392,8 +596,10 @@ const (
 
 // Event Event
 type Event struct {
-	Event string      `json:"event"` // 事件标示
-	Data  interface{} `json:"data"`  // 事件数据
+	Event       string      `json:"event"` // 事件标示
+	Data        interface{} `json:"data"`  // 事件数据
+	ChannelType uint8       `json:"-"`     // 事件相关的频道类型，用于按频道类型过滤，0表示与频道无关
+	Key         string      `json:"-"`     // 投递到消息中间件的分区键，相同分区键的事件保证顺序，为空时使用事件类型
 }
 
 func (e *Event) String() string {
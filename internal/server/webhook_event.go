@@ -0,0 +1,123 @@
+package server
+
+import (
+	"strings"
+)
+
+// 频道、订阅者、黑白名单、用户和会话相关的事件，在数据写入（分布式下为提交）成功后触发
+const (
+	// EventChannelCreate 创建频道
+	EventChannelCreate = "channel.create"
+	// EventChannelUpdate 更新频道
+	EventChannelUpdate = "channel.update"
+	// EventChannelDelete 删除频道
+	EventChannelDelete = "channel.delete"
+	// EventSubscriberAdd 添加订阅者
+	EventSubscriberAdd = "channel.subscriber.add"
+	// EventSubscriberRemove 移除订阅者
+	EventSubscriberRemove = "channel.subscriber.remove"
+	// EventDenylistAdd 添加黑名单
+	EventDenylistAdd = "channel.denylist.add"
+	// EventDenylistSet 设置黑名单（覆盖原来的黑名单）
+	EventDenylistSet = "channel.denylist.set"
+	// EventDenylistRemove 移除黑名单
+	EventDenylistRemove = "channel.denylist.remove"
+	// EventAllowlistAdd 添加白名单
+	EventAllowlistAdd = "channel.allowlist.add"
+	// EventAllowlistSet 设置白名单（覆盖原来的白名单）
+	EventAllowlistSet = "channel.allowlist.set"
+	// EventAllowlistRemove 移除白名单
+	EventAllowlistRemove = "channel.allowlist.remove"
+	// EventUserBan 用户封禁或解封
+	EventUserBan = "user.ban"
+	// EventUserTokenUpdate 用户token更新
+	EventUserTokenUpdate = "user.token.update"
+	// EventUserDeviceQuit 用户设备被强制退出
+	EventUserDeviceQuit = "user.device.quit"
+	// EventConversationDelete 删除最近会话
+	EventConversationDelete = "conversation.delete"
+)
+
+// WebhookFilter webhook接收方订阅的事件
+type WebhookFilter struct {
+	Events       []string `mapstructure:"events"`       // 订阅的事件，支持通配 例如：channel.* ，为空表示订阅所有事件
+	ChannelTypes []uint8  `mapstructure:"channelTypes"` // 订阅的频道类型，为空表示所有频道类型（与频道无关的事件不受此限制）
+}
+
+// Allow 事件是否需要推送给接收方，channelType为0表示事件与频道无关
+func (f WebhookFilter) Allow(event string, channelType uint8) bool {
+	if len(f.Events) > 0 {
+		matched := false
+		for _, e := range f.Events {
+			if webhookEventMatch(e, event) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if channelType == 0 || len(f.ChannelTypes) == 0 {
+		return true
+	}
+	for _, ct := range f.ChannelTypes {
+		if ct == channelType {
+			return true
+		}
+	}
+	return false
+}
+
+func webhookEventMatch(pattern string, event string) bool {
+	if pattern == "*" || pattern == event {
+		return true
+	}
+	if strings.HasSuffix(pattern, ".*") {
+		return strings.HasPrefix(event, pattern[:len(pattern)-1])
+	}
+	return false
+}
+
+// WebhookSubscriber 额外的webhook接收方（只支持http），只接收订阅的事件，不接收消息通知（msg.notify）和在线状态事件
+type WebhookSubscriber struct {
+	HTTPAddr      string `mapstructure:"httpAddr"` // 接收地址
+	WebhookFilter `mapstructure:",squash"`
+}
+
+// ChannelEventData 频道创建、更新、删除事件的数据
+type ChannelEventData struct {
+	ChannelID   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	Large       int    `json:"large,omitempty"`
+	Ban         int    `json:"ban,omitempty"`
+	Disband     int    `json:"disband,omitempty"`
+}
+
+// ChannelMembersEventData 订阅者、黑名单、白名单变更事件的数据
+type ChannelMembersEventData struct {
+	ChannelID   string   `json:"channel_id"`
+	ChannelType uint8    `json:"channel_type"`
+	UIDs        []string `json:"uids"`
+	Reset       int      `json:"reset,omitempty"` // 是否先清空了原来的成员
+}
+
+// UserBanEventData 用户封禁事件的数据
+type UserBanEventData struct {
+	UID string `json:"uid"`
+	Ban int    `json:"ban"` // 1.封禁 0.解封
+}
+
+// UserDeviceEventData 用户设备相关事件的数据（不包含token）
+type UserDeviceEventData struct {
+	UID         string `json:"uid"`
+	DeviceFlag  int    `json:"device_flag"`            // -1表示所有设备
+	DeviceLevel uint8  `json:"device_level,omitempty"` // 设备等级
+}
+
+// ConversationDeleteEventData 删除最近会话事件的数据
+type ConversationDeleteEventData struct {
+	UID         string `json:"uid"`
+	ChannelID   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSignedMTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, clientCert := writeTestClientCert(t, dir)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	secrets := []string{"new-secret", "old-secret"}
+	received := make(chan struct{}, 1)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		// 接收方只有旧密钥也能校验通过
+		err := wkhook.Verify(secrets[1:], r.Header.Get(wkhook.HeaderSignature), r.Header.Get(wkhook.HeaderEventId), data, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, "1001", r.Header.Get(wkhook.HeaderEventId))
+		assert.Equal(t, EventMsgNotify, r.URL.Query().Get("event"))
+		received <- struct{}{}
+	}))
+	srv.TLS = &tls.Config{
+		ClientCAs:  clientCAs,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0644)
+	assert.NoError(t, err)
+
+	opts := NewOptions()
+	opts.Webhook.HTTPAddr = srv.URL
+	opts.Webhook.Secrets = secrets
+	opts.Webhook.TLS.CAFile = caFile
+	opts.Webhook.TLS.CertFile = certFile
+	opts.Webhook.TLS.KeyFile = keyFile
+	w := newWebhook(&Server{opts: opts})
+
+	err = w.sendWebhook(EventMsgNotify, "1001", []byte(`[{"message_id":1}]`))
+	assert.NoError(t, err)
+	<-received
+
+	// 没有客户端证书，服务端拒绝握手
+	opts.Webhook.TLS.CertFile = ""
+	opts.Webhook.TLS.KeyFile = ""
+	w = newWebhook(&Server{opts: opts})
+	err = w.sendWebhook(EventMsgNotify, "1002", []byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestWebhookTLSCheck(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	err := os.WriteFile(caFile, []byte("invalid"), 0644)
+	assert.NoError(t, err)
+
+	opts := NewOptions()
+	opts.Cluster.NodeId = 1
+	opts.Webhook.TLS.CAFile = caFile
+	assert.Error(t, opts.Check())
+
+	opts.Webhook.TLS.CAFile = ""
+	assert.NoError(t, opts.Check())
+}
+
+// writeTestClientCert 生成自签名的客户端证书
+func writeTestClientCert(t *testing.T, dir string) (certFile string, keyFile string, cert *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "wukongim-webhook-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client.key")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return
+}
+
+func TestWebhookFilter(t *testing.T) {
+	f := WebhookFilter{}
+	assert.True(t, f.Allow(EventChannelCreate, 2))
+
+	f = WebhookFilter{Events: []string{"channel.*", EventUserBan}, ChannelTypes: []uint8{2}}
+	assert.True(t, f.Allow(EventChannelCreate, 2))
+	assert.True(t, f.Allow(EventDenylistAdd, 2))
+	assert.False(t, f.Allow(EventChannelCreate, 1))
+	assert.True(t, f.Allow(EventUserBan, 0)) // 与频道无关的事件不受频道类型限制
+	assert.False(t, f.Allow(EventUserTokenUpdate, 0))
+	assert.False(t, f.Allow(EventMsgNotify, 2))
+}
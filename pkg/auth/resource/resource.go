@@ -12,6 +12,7 @@ var ClusterChannel = channel{
 	Migrate: "clusterchannelMigrate", // 迁移频道
 	Start:   "clusterchannelStart",   // 启动频道
 	Stop:    "clusterchannelStop",    // 停止频道
+	Repair:  "clusterchannelRepair",  // 修复不一致的频道副本
 }
 
 type slot struct {
@@ -22,6 +23,7 @@ type channel struct {
 	Migrate Id
 	Start   Id
 	Stop    Id
+	Repair  Id
 }
 
 var All Id = "*"
@@ -1,12 +1,14 @@
 package client
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"net"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
@@ -59,6 +61,18 @@ type Client struct {
 	onRecv    OnRecv
 	onSendack OnSendack
 
+	// 事件通道（调用对应的方法后才会创建）
+	recvC    chan *wkproto.RecvPacket
+	sendackC chan *wkproto.SendackPacket
+	statusC  chan Status
+	eventMu  sync.RWMutex
+
+	pendingSendacks map[uint64]chan *wkproto.SendackPacket // 等待发送回执的消息（key为clientSeq）
+	pendingMu       sync.Mutex
+
+	channelSeqs *channelSeqs // 频道收到的最大消息序号
+	httpClient  *http.Client
+
 	err error
 
 	lastSendMsgTime time.Time // 最后发送消息时间
@@ -86,6 +100,9 @@ func New(addr string, opt ...Option) *Client {
 			buf: make([]byte, opts.DefaultBufSize),
 			off: -1,
 		},
+		pendingSendacks: make(map[uint64]chan *wkproto.SendackPacket),
+		channelSeqs:     newChannelSeqs(),
+		httpClient:      &http.Client{Timeout: opts.Timeout * 5},
 	}
 
 	return c
@@ -115,6 +132,9 @@ func (c *Client) Connect() error {
 		go c.doReconnect()
 		err = nil
 	}
+	if err == nil && c.status == CONNECTED {
+		c.emitStatus(CONNECTED)
+	}
 	return err
 
 }
@@ -128,6 +148,49 @@ func (c *Client) SetOnSendack(onSendack OnSendack) {
 	c.onSendack = onSendack
 }
 
+// RecvChan 收到的消息（通道满了的消息不回复recvack，服务端会重新投递）
+func (c *Client) RecvChan() <-chan *wkproto.RecvPacket {
+	c.eventMu.Lock()
+	defer c.eventMu.Unlock()
+	if c.recvC == nil {
+		c.recvC = make(chan *wkproto.RecvPacket, c.opts.EventBufSize)
+	}
+	return c.recvC
+}
+
+// SendackChan 收到的发送回执
+func (c *Client) SendackChan() <-chan *wkproto.SendackPacket {
+	c.eventMu.Lock()
+	defer c.eventMu.Unlock()
+	if c.sendackC == nil {
+		c.sendackC = make(chan *wkproto.SendackPacket, c.opts.EventBufSize)
+	}
+	return c.sendackC
+}
+
+// StatusChan 连接状态变化（CONNECTED、RECONNECTING、CLOSED）
+func (c *Client) StatusChan() <-chan Status {
+	c.eventMu.Lock()
+	defer c.eventMu.Unlock()
+	if c.statusC == nil {
+		c.statusC = make(chan Status, c.opts.EventBufSize)
+	}
+	return c.statusC
+}
+
+func (c *Client) emitStatus(status Status) {
+	c.eventMu.RLock()
+	defer c.eventMu.RUnlock()
+	if c.statusC == nil {
+		return
+	}
+	select {
+	case c.statusC <- status:
+	default:
+		c.Warn("状态事件通道已满，丢弃事件", zap.String("status", status.String()))
+	}
+}
+
 func (c *Client) close(status Status, err error) {
 	c.mu.Lock()
 
@@ -167,6 +230,9 @@ func (c *Client) close(status Status, err error) {
 	c.status = status
 
 	c.mu.Unlock()
+
+	c.failPendingSendacks()
+	c.emitStatus(status)
 }
 
 func (c *Client) isClosed() bool {
@@ -307,9 +373,16 @@ func (c *Client) doReconnect() {
 
 		c.mu.Unlock()
 
+		c.emitStatus(CONNECTED)
+
 		// Make sure to flush everything
 		c.Flush()
 
+		// 同步断线期间的消息
+		if c.opts.APIURL != "" {
+			go c.SyncMissedMessages()
+		}
+
 		return
 	}
 	if c.err == nil {
@@ -423,6 +496,7 @@ func (c *Client) processOpErr(err error) {
 		c.writer.switchToPending()
 		go c.doReconnect()
 		c.mu.Unlock()
+		c.emitStatus(RECONNECTING)
 		return
 	}
 	c.status = DISCONNECTED
@@ -539,34 +613,92 @@ func (c *Client) handlePacket(frame wkproto.Frame) error {
 }
 
 func (c *Client) handleSendackPacket(packet *wkproto.SendackPacket) {
+	c.pendingMu.Lock()
+	ch := c.pendingSendacks[packet.ClientSeq]
+	delete(c.pendingSendacks, packet.ClientSeq)
+	c.pendingMu.Unlock()
+	if ch != nil {
+		ch <- packet
+	}
+
 	if c.onSendack != nil {
 		c.onSendack(packet)
 	}
+
+	c.eventMu.RLock()
+	if c.sendackC != nil {
+		select {
+		case c.sendackC <- packet:
+		default:
+			c.Warn("发送回执事件通道已满，丢弃事件", zap.Uint64("clientSeq", packet.ClientSeq))
+		}
+	}
+	c.eventMu.RUnlock()
 }
 
 // 处理接受包
 func (c *Client) handleRecvPacket(packet *wkproto.RecvPacket) {
-	var err error
-	var payload []byte
-	if c.onRecv != nil {
-		if !packet.Setting.IsSet(wkproto.SettingNoEncrypt) {
-			payload, err = wkutil.AesDecryptPkcs7Base64(packet.Payload, []byte(c.aesKey), []byte(c.salt))
-			if err != nil {
-				c.Panic("解密消息payload失败！", zap.Error(err), zap.String("payload", string(packet.Payload)), zap.String("aesKey", c.aesKey), zap.String("salt", c.salt))
-			}
-			packet.Payload = payload
+	if !packet.Setting.IsSet(wkproto.SettingNoEncrypt) {
+		payload, err := wkutil.AesDecryptPkcs7Base64(packet.Payload, []byte(c.aesKey), []byte(c.salt))
+		if err != nil {
+			c.Panic("解密消息payload失败！", zap.Error(err), zap.String("payload", string(packet.Payload)), zap.String("aesKey", c.aesKey), zap.String("salt", c.salt))
 		}
-		err = c.onRecv(packet)
+		packet.Payload = payload
+	}
+	if !c.dispatchRecvPacket(packet, true) {
+		return
 	}
-	if err == nil {
-		c.sendPacket(&wkproto.RecvackPacket{
+	if c.opts.AutoRecvack {
+		err := c.sendPacket(&wkproto.RecvackPacket{
 			Framer:     packet.Framer,
 			MessageID:  packet.MessageID,
 			MessageSeq: packet.MessageSeq,
 		})
+		if err != nil {
+			c.Warn("发送recvack失败！", zap.Error(err), zap.Int64("messageId", packet.MessageID))
+		}
 	}
 }
 
+// dispatchRecvPacket 将消息交给回调和事件通道，返回消息是否处理成功（重复的消息也算成功）
+func (c *Client) dispatchRecvPacket(packet *wkproto.RecvPacket, live bool) bool {
+	key := channelKey{channelID: packet.ChannelID, channelType: packet.ChannelType}
+	if !packet.NoPersist && !c.channelSeqs.accept(key, uint64(packet.MessageSeq)) {
+		return true // 重复的消息
+	}
+	if c.onRecv != nil {
+		if err := c.onRecv(packet); err != nil {
+			c.rejectRecvPacket(key, packet)
+			return false
+		}
+	}
+	c.eventMu.RLock()
+	recvC := c.recvC
+	c.eventMu.RUnlock()
+	if recvC != nil {
+		if live {
+			select {
+			case recvC <- packet:
+			default:
+				c.Warn("消息事件通道已满，等待服务端重新投递", zap.Int64("messageId", packet.MessageID))
+				c.rejectRecvPacket(key, packet)
+				return false
+			}
+		} else {
+			recvC <- packet // 同步的消息没有重试，必须投递
+		}
+	}
+	return true
+}
+
+// rejectRecvPacket 消息处理失败，服务端重新投递时不能当作重复的消息丢弃
+func (c *Client) rejectRecvPacket(key channelKey, packet *wkproto.RecvPacket) {
+	if packet.NoPersist {
+		return
+	}
+	c.channelSeqs.reject(key, uint64(packet.MessageSeq))
+}
+
 func (c *Client) handlePong() {
 	var ch chan struct{}
 	c.mu.Lock()
@@ -582,6 +714,62 @@ func (c *Client) handlePong() {
 }
 
 func (c *Client) SendMessage(channel *Channel, payload []byte, opt ...SendOption) error {
+	packet, err := c.newSendPacket(channel, payload, opt...)
+	if err != nil {
+		return err
+	}
+	c.lastSendMsgTime = time.Now()
+	return c.appendPacket(packet)
+}
+
+// SendMessageContext 发送消息并等待发送回执（包含服务端生成的消息ID和消息序号）
+func (c *Client) SendMessageContext(ctx context.Context, channel *Channel, payload []byte, opt ...SendOption) (*wkproto.SendackPacket, error) {
+	packet, err := c.newSendPacket(channel, payload, opt...)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan *wkproto.SendackPacket, 1)
+	c.pendingMu.Lock()
+	c.pendingSendacks[packet.ClientSeq] = ch
+	c.pendingMu.Unlock()
+
+	c.lastSendMsgTime = time.Now()
+	if err = c.appendPacket(packet); err != nil {
+		c.removePendingSendack(packet.ClientSeq)
+		return nil, err
+	}
+	select {
+	case sendack, ok := <-ch:
+		if !ok {
+			return nil, ErrConnectionClosed
+		}
+		if sendack.ReasonCode != wkproto.ReasonSuccess {
+			return sendack, fmt.Errorf("wukongim send message failed: %s", sendack.ReasonCode.String())
+		}
+		return sendack, nil
+	case <-ctx.Done():
+		c.removePendingSendack(packet.ClientSeq)
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Client) removePendingSendack(clientSeq uint64) {
+	c.pendingMu.Lock()
+	delete(c.pendingSendacks, clientSeq)
+	c.pendingMu.Unlock()
+}
+
+// failPendingSendacks 连接关闭后不会再收到发送回执
+func (c *Client) failPendingSendacks() {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	for clientSeq, ch := range c.pendingSendacks {
+		close(ch)
+		delete(c.pendingSendacks, clientSeq)
+	}
+}
+
+func (c *Client) newSendPacket(channel *Channel, payload []byte, opt ...SendOption) (*wkproto.SendPacket, error) {
 	opts := NewSendOptions()
 	if len(opt) > 0 {
 		for _, op := range opt {
@@ -596,7 +784,7 @@ func (c *Client) SendMessage(channel *Channel, payload []byte, opt ...SendOption
 		newPayload, err = wkutil.AesEncryptPkcs7Base64(payload, []byte(c.aesKey), []byte(c.salt))
 		if err != nil {
 			c.Error("加密消息payload失败！", zap.Error(err), zap.String("aesKey", c.aesKey), zap.String("salt", c.salt))
-			return err
+			return nil, err
 		}
 	} else {
 		setting.Set(wkproto.SettingNoEncrypt)
@@ -628,12 +816,11 @@ func (c *Client) SendMessage(channel *Channel, payload []byte, opt ...SendOption
 		actMsgKey, err := wkutil.AesEncryptPkcs7Base64([]byte(signStr), []byte(c.aesKey), []byte(c.salt))
 		if err != nil {
 			c.Error("加密数据失败！", zap.Error(err))
-			return err
+			return nil, err
 		}
 		packet.MsgKey = wkutil.MD5(string(actMsgKey))
 	}
-	c.lastSendMsgTime = time.Now()
-	return c.appendPacket(packet)
+	return packet, nil
 }
 func (c *Client) Close() {
 	c.close(CLOSED, nil)
@@ -752,7 +939,15 @@ func (c *Client) appendPacket(packet wkproto.Frame) error {
 
 func (c *Client) createConn() (net.Conn, error) {
 	network, address, _ := parseAddr(c.addr)
-	conn, err := net.DialTimeout(network, address, c.opts.Timeout)
+	var (
+		conn net.Conn
+		err  error
+	)
+	if network == "ws" || network == "wss" {
+		conn, err = dialWebsocket(c.addr, c.opts.Timeout, c.opts.TLSConfig)
+	} else {
+		conn, err = net.DialTimeout(network, address, c.opts.Timeout)
+	}
 	if err != nil {
 		return nil, err
 	}
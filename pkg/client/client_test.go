@@ -1,7 +1,16 @@
 package client
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestSendMessage(t *testing.T) {
@@ -10,3 +19,146 @@ func TestSendMessage(t *testing.T) {
 	// assert.NoError(t, err)
 	// c.Disconnect()
 }
+
+func TestChannelSeqsAccept(t *testing.T) {
+	seqs := newChannelSeqs()
+	key := channelKey{channelID: "g1", channelType: 2}
+	assert.True(t, seqs.accept(key, 1))
+	assert.True(t, seqs.accept(key, 2))
+	assert.False(t, seqs.accept(key, 2)) // 重复
+	assert.True(t, seqs.accept(key, 0))  // 没有序号的消息不去重
+
+	// 同步期间实时消息和同步的消息交替到达
+	lastSeqs := seqs.startSync()
+	assert.Equal(t, uint64(2), lastSeqs[key])
+	assert.True(t, seqs.accept(key, 10)) // 实时推送
+	assert.True(t, seqs.accept(key, 3))  // 同步
+	assert.False(t, seqs.accept(key, 10))
+	assert.False(t, seqs.accept(key, 2))
+	seqs.endSync()
+	assert.False(t, seqs.accept(key, 5))
+	assert.True(t, seqs.accept(key, 11))
+}
+
+func TestChannelSeqsReject(t *testing.T) {
+	seqs := newChannelSeqs()
+	key := channelKey{channelID: "g1", channelType: 2}
+	assert.True(t, seqs.accept(key, 1))
+	seqs.reject(key, 1)
+	assert.True(t, seqs.accept(key, 2))
+	assert.True(t, seqs.accept(key, 1)) // 处理失败的消息重新投递
+	assert.False(t, seqs.accept(key, 1))
+
+	// 同步期间处理失败
+	seqs.startSync()
+	assert.True(t, seqs.accept(key, 3))
+	seqs.reject(key, 3)
+	assert.True(t, seqs.accept(key, 3))
+	assert.False(t, seqs.accept(key, 3))
+	seqs.endSync()
+}
+
+func TestDispatchRecvPacketEventChanFull(t *testing.T) {
+	c := New("tcp://127.0.0.1:0", WithUID("u1"), WithEventBufSize(1))
+	recvC := c.RecvChan()
+
+	newPacket := func(seq uint32) *wkproto.RecvPacket {
+		return &wkproto.RecvPacket{ChannelID: "g1", ChannelType: 2, MessageSeq: seq}
+	}
+	assert.True(t, c.dispatchRecvPacket(newPacket(1), true))
+	assert.False(t, c.dispatchRecvPacket(newPacket(2), true)) // 事件通道已满
+
+	<-recvC
+	// 服务端重新投递的消息不能当作重复的消息丢弃
+	assert.True(t, c.dispatchRecvPacket(newPacket(2), true))
+	assert.Equal(t, 1, len(recvC))
+	recv := <-recvC
+	assert.Equal(t, uint32(2), recv.MessageSeq)
+
+	// 已经投递成功的消息再次收到时丢弃
+	assert.True(t, c.dispatchRecvPacket(newPacket(2), true))
+	assert.Equal(t, 0, len(recvC))
+}
+
+func TestSyncMissedMessages(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/conversation/sync", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		assert.Equal(t, "g1:2:1", req["last_msg_seqs"])
+		_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"channel_id": "g1", "channel_type": 2, "last_msg_seq": 3},
+			{"channel_id": "u2", "channel_type": 1, "last_msg_seq": 5, "readed_to_msg_seq": 4},
+		})
+	})
+	mux.HandleFunc("/channel/messagesync", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		start := uint64(req["start_message_seq"].(float64))
+		var messages []map[string]interface{}
+		if req["channel_id"] == "g1" {
+			for seq := start; seq <= 3; seq++ {
+				messages = append(messages, map[string]interface{}{"message_seq": seq, "channel_id": "g1", "channel_type": 2, "payload": []byte("hello")})
+			}
+		} else {
+			assert.Equal(t, uint64(5), start)
+			messages = append(messages, map[string]interface{}{"message_seq": 5, "channel_id": "u2", "channel_type": 1, "payload": []byte("hi")})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"more": 0, "messages": messages})
+	})
+	apiServer := httptest.NewServer(mux)
+	defer apiServer.Close()
+
+	c := New("tcp://127.0.0.1:0", WithUID("u1"), WithAPIURL(apiServer.URL))
+	c.channelSeqs.accept(channelKey{channelID: "g1", channelType: 2}, 1)
+	recvC := c.RecvChan()
+	c.SyncMissedMessages()
+
+	assert.Equal(t, 3, len(recvC))
+	recv := <-recvC
+	assert.Equal(t, "g1", recv.ChannelID)
+	assert.Equal(t, uint32(2), recv.MessageSeq)
+	assert.Equal(t, "hello", string(recv.Payload))
+	assert.True(t, recv.Setting.IsSet(wkproto.SettingNoEncrypt))
+	<-recvC
+	recv = <-recvC
+	assert.Equal(t, "u2", recv.ChannelID)
+	assert.Equal(t, uint32(5), recv.MessageSeq)
+}
+
+func TestWsConn(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			// 拆成两条消息返回
+			_ = conn.WriteMessage(websocket.BinaryMessage, data[:1])
+			_ = conn.WriteMessage(websocket.BinaryMessage, data[1:])
+		}
+	}))
+	defer server.Close()
+
+	conn, err := dialWebsocket("ws"+strings.TrimPrefix(server.URL, "http"), time.Second, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hello"))
+	assert.NoError(t, err)
+
+	buf := make([]byte, 0, 5)
+	tmp := make([]byte, 5)
+	for len(buf) < 5 {
+		n, err := conn.Read(tmp)
+		assert.NoError(t, err)
+		buf = append(buf, tmp[:n]...)
+	}
+	assert.Equal(t, "hello", string(buf))
+}
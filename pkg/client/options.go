@@ -1,6 +1,8 @@
 package client
 
 import (
+	"crypto/tls"
+	"strings"
 	"time"
 
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
@@ -37,6 +39,17 @@ type Options struct {
 	// ReconnectWait sets the time to backoff after attempting a reconnect
 	// to a server that we were already connected to previously.
 	ReconnectWait time.Duration
+
+	TLSConfig *tls.Config // wss连接使用的tls配置
+
+	AutoRecvack bool // 收到消息后是否自动回复recvack
+
+	APIURL   string // IM的http api地址，例如：http://127.0.0.1:5001 （设置后重连成功会通过api同步断线期间的消息）
+	APIToken string // 调用api的token（服务端开启了ManagerToken时需要）
+
+	SyncLimit int // 重连后每次同步频道消息的数量
+
+	EventBufSize int // 事件通道的缓冲大小
 }
 
 // NewOptions 创建默认配置
@@ -51,6 +64,9 @@ func NewOptions() *Options {
 		MaxPingCount:     2,
 		ReconnectJitter:  100 * time.Millisecond,
 		ReconnectWait:    2 * time.Second,
+		AutoRecvack:      true,
+		SyncLimit:        100,
+		EventBufSize:     1024,
 	}
 }
 
@@ -89,6 +105,62 @@ func WithAutoReconn(autoReconn bool) Option {
 	}
 }
 
+// WithDefaultBufSize 连接读写缓冲区的大小（同时创建大量客户端时可以调小）
+func WithDefaultBufSize(size int) Option {
+	return func(opts *Options) error {
+		opts.DefaultBufSize = size
+		return nil
+	}
+}
+
+// WithTLSConfig wss连接使用的tls配置
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(opts *Options) error {
+		opts.TLSConfig = tlsConfig
+		return nil
+	}
+}
+
+// WithAutoRecvack 收到消息后是否自动回复recvack
+func WithAutoRecvack(autoRecvack bool) Option {
+	return func(opts *Options) error {
+		opts.AutoRecvack = autoRecvack
+		return nil
+	}
+}
+
+// WithAPIURL IM的http api地址，设置后重连成功会同步断线期间的消息
+func WithAPIURL(apiURL string) Option {
+	return func(opts *Options) error {
+		opts.APIURL = strings.TrimSuffix(apiURL, "/")
+		return nil
+	}
+}
+
+// WithAPIToken 调用api的token
+func WithAPIToken(token string) Option {
+	return func(opts *Options) error {
+		opts.APIToken = token
+		return nil
+	}
+}
+
+// WithSyncLimit 重连后每次同步频道消息的数量
+func WithSyncLimit(limit int) Option {
+	return func(opts *Options) error {
+		opts.SyncLimit = limit
+		return nil
+	}
+}
+
+// WithEventBufSize 事件通道的缓冲大小
+func WithEventBufSize(size int) Option {
+	return func(opts *Options) error {
+		opts.EventBufSize = size
+		return nil
+	}
+}
+
 // SendOptions SendOptions
 type SendOptions struct {
 	NoPersist   bool // 是否不存储 默认 false
@@ -0,0 +1,275 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+type channelKey struct {
+	channelID   string
+	channelType uint8
+}
+
+// channelSeqs 记录每个频道收到的最大消息序号，用于重连后补齐断线期间的消息和去重
+type channelSeqs struct {
+	last   map[channelKey]uint64
+	floor  map[channelKey]uint64              // 同步期间的起始序号（小于等于这个序号的消息已经收到过）
+	seen   map[channelKey]map[uint64]struct{} // 同步期间收到的消息（同步和实时推送的消息可能重复）
+	failed map[channelKey]map[uint64]struct{} // 处理失败等待重新投递的消息
+	mu     sync.Mutex
+}
+
+func newChannelSeqs() *channelSeqs {
+	return &channelSeqs{
+		last:   make(map[channelKey]uint64),
+		failed: make(map[channelKey]map[uint64]struct{}),
+	}
+}
+
+// accept 消息是否需要处理（重复的消息返回false）
+func (c *channelSeqs) accept(key channelKey, seq uint64) bool {
+	if seq == 0 { // 不存储的消息没有序号
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if seqs := c.failed[key]; seqs != nil {
+		if _, ok := seqs[seq]; ok { // 之前处理失败的消息重新投递
+			delete(seqs, seq)
+			if len(seqs) == 0 {
+				delete(c.failed, key)
+			}
+			if c.seen != nil {
+				c.markSeen(key, seq)
+			}
+			return true
+		}
+	}
+	if c.seen != nil {
+		if seq <= c.floor[key] {
+			return false
+		}
+		if _, ok := c.seen[key][seq]; ok {
+			return false
+		}
+		c.markSeen(key, seq)
+	} else if seq <= c.last[key] {
+		return false
+	}
+	if seq > c.last[key] {
+		c.last[key] = seq
+	}
+	return true
+}
+
+// reject 消息处理失败，重新投递时需要再次处理
+func (c *channelSeqs) reject(key channelKey, seq uint64) {
+	if seq == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seen != nil {
+		if seqs := c.seen[key]; seqs != nil {
+			delete(seqs, seq)
+		}
+	}
+	seqs := c.failed[key]
+	if seqs == nil {
+		seqs = make(map[uint64]struct{})
+		c.failed[key] = seqs
+	}
+	seqs[seq] = struct{}{}
+}
+
+func (c *channelSeqs) markSeen(key channelKey, seq uint64) {
+	seqs := c.seen[key]
+	if seqs == nil {
+		seqs = make(map[uint64]struct{})
+		c.seen[key] = seqs
+	}
+	seqs[seq] = struct{}{}
+}
+
+// startSync 开始同步，返回每个频道已经收到的最大序号
+func (c *channelSeqs) startSync() map[channelKey]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.floor = make(map[channelKey]uint64, len(c.last))
+	for key, seq := range c.last {
+		c.floor[key] = seq
+	}
+	c.seen = make(map[channelKey]map[uint64]struct{})
+	floor := make(map[channelKey]uint64, len(c.floor))
+	for key, seq := range c.floor {
+		floor[key] = seq
+	}
+	return floor
+}
+
+func (c *channelSeqs) endSync() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.floor = nil
+	c.seen = nil
+}
+
+// formatLastMsgSeqs 格式化成会话同步接口的last_msg_seqs参数
+func formatLastMsgSeqs(seqs map[channelKey]uint64) string {
+	items := make([]string, 0, len(seqs))
+	for key, seq := range seqs {
+		items = append(items, fmt.Sprintf("%s:%d:%d", key.channelID, key.channelType, seq))
+	}
+	return strings.Join(items, "|")
+}
+
+type syncConversation struct {
+	ChannelID      string `json:"channel_id"`
+	ChannelType    uint8  `json:"channel_type"`
+	LastMsgSeq     uint64 `json:"last_msg_seq"`
+	ReadedToMsgSeq uint64 `json:"readed_to_msg_seq"`
+}
+
+type syncMessageResp struct {
+	More     int            `json:"more"`
+	Messages []*syncMessage `json:"messages"`
+}
+
+type syncMessage struct {
+	Header struct {
+		NoPersist int `json:"no_persist"`
+		RedDot    int `json:"red_dot"`
+		SyncOnce  int `json:"sync_once"`
+	} `json:"header"`
+	Setting     uint8  `json:"setting"`
+	MessageID   int64  `json:"message_id"`
+	ClientMsgNo string `json:"client_msg_no"`
+	StreamNo    string `json:"stream_no"`
+	MessageSeq  uint64 `json:"message_seq"`
+	FromUID     string `json:"from_uid"`
+	ChannelID   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	Topic       string `json:"topic"`
+	Expire      uint32 `json:"expire"`
+	Timestamp   int32  `json:"timestamp"`
+	Payload     []byte `json:"payload"`
+}
+
+func (m *syncMessage) toRecvPacket(channelID string) *wkproto.RecvPacket {
+	setting := wkproto.Setting(m.Setting)
+	setting.Set(wkproto.SettingNoEncrypt) // 通过api同步的消息是明文
+	return &wkproto.RecvPacket{
+		Framer: wkproto.Framer{
+			NoPersist: m.Header.NoPersist == 1,
+			RedDot:    m.Header.RedDot == 1,
+			SyncOnce:  m.Header.SyncOnce == 1,
+		},
+		Setting:     setting,
+		MessageID:   m.MessageID,
+		MessageSeq:  uint32(m.MessageSeq),
+		ClientMsgNo: m.ClientMsgNo,
+		StreamNo:    m.StreamNo,
+		FromUID:     m.FromUID,
+		ChannelID:   channelID,
+		ChannelType: m.ChannelType,
+		Topic:       m.Topic,
+		Expire:      m.Expire,
+		Timestamp:   m.Timestamp,
+		Payload:     m.Payload,
+	}
+}
+
+// SyncMissedMessages 通过api同步断线期间的消息（设置了APIURL时重连成功后会自动调用）
+// 没有收到过消息的频道从会话的已读位置开始同步
+func (c *Client) SyncMissedMessages() {
+	lastSeqs := c.channelSeqs.startSync()
+	defer c.channelSeqs.endSync()
+
+	var conversations []*syncConversation
+	err := c.postAPI("/conversation/sync", map[string]interface{}{
+		"uid":           c.opts.UID,
+		"last_msg_seqs": formatLastMsgSeqs(lastSeqs),
+		"msg_count":     1,
+	}, &conversations)
+	if err != nil {
+		c.Error("同步最近会话失败！", zap.Error(err))
+		return
+	}
+	for _, conversation := range conversations {
+		key := channelKey{channelID: conversation.ChannelID, channelType: conversation.ChannelType}
+		startSeq, ok := lastSeqs[key]
+		if !ok { // 没有收到过的频道从已读位置开始同步
+			startSeq = conversation.ReadedToMsgSeq
+		}
+		if conversation.LastMsgSeq <= startSeq {
+			continue
+		}
+		if err := c.syncChannelMessages(key, startSeq+1); err != nil {
+			c.Error("同步频道消息失败！", zap.Error(err), zap.String("channelID", key.channelID), zap.Uint8("channelType", key.channelType))
+		}
+	}
+}
+
+// syncChannelMessages 同步频道序号从startSeq开始的消息
+func (c *Client) syncChannelMessages(key channelKey, startSeq uint64) error {
+	for !c.isClosed() {
+		var resp syncMessageResp
+		err := c.postAPI("/channel/messagesync", map[string]interface{}{
+			"login_uid":         c.opts.UID,
+			"channel_id":        key.channelID,
+			"channel_type":      key.channelType,
+			"start_message_seq": startSeq,
+			"limit":             c.opts.SyncLimit,
+			"pull_mode":         1, // 向上拉取（拉取更新的消息）
+		}, &resp)
+		if err != nil {
+			return err
+		}
+		for _, message := range resp.Messages {
+			c.dispatchRecvPacket(message.toRecvPacket(key.channelID), false)
+			if message.MessageSeq >= startSeq {
+				startSeq = message.MessageSeq + 1
+			}
+		}
+		if resp.More != 1 || len(resp.Messages) == 0 {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (c *Client) postAPI(path string, req interface{}, resp interface{}) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, c.opts.APIURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.opts.APIToken != "" {
+		httpReq.Header.Set("token", c.opts.APIToken)
+	}
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("请求%s失败！状态码：%d 内容：%s", path, httpResp.StatusCode, string(body))
+	}
+	return json.Unmarshal(body, resp)
+}
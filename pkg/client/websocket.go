@@ -0,0 +1,88 @@
+package client
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsConn 将websocket连接包装成net.Conn（数据以二进制消息传输，读取时拼接成字节流）
+type wsConn struct {
+	conn   *websocket.Conn
+	reader io.Reader // 当前正在读取的消息
+	wmu    sync.Mutex
+}
+
+func dialWebsocket(addr string, timeout time.Duration, tlsConfig *tls.Config) (net.Conn, error) {
+	dialer := websocket.Dialer{
+		HandshakeTimeout: timeout,
+		TLSClientConfig:  tlsConfig,
+		Proxy:            websocket.DefaultDialer.Proxy,
+	}
+	conn, _, err := dialer.Dial(addr, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &wsConn{conn: conn}, nil
+}
+
+func (w *wsConn) Read(b []byte) (int, error) {
+	for {
+		if w.reader == nil {
+			_, reader, err := w.conn.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			w.reader = reader
+		}
+		n, err := w.reader.Read(b)
+		if err == io.EOF { // 当前消息读完了，继续读下一条
+			w.reader = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (w *wsConn) Write(b []byte) (int, error) {
+	w.wmu.Lock()
+	defer w.wmu.Unlock()
+	if err := w.conn.WriteMessage(websocket.BinaryMessage, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *wsConn) Close() error {
+	return w.conn.Close()
+}
+
+func (w *wsConn) LocalAddr() net.Addr {
+	return w.conn.LocalAddr()
+}
+
+func (w *wsConn) RemoteAddr() net.Addr {
+	return w.conn.RemoteAddr()
+}
+
+func (w *wsConn) SetDeadline(t time.Time) error {
+	if err := w.conn.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return w.conn.SetWriteDeadline(t)
+}
+
+func (w *wsConn) SetReadDeadline(t time.Time) error {
+	return w.conn.SetReadDeadline(t)
+}
+
+func (w *wsConn) SetWriteDeadline(t time.Time) error {
+	return w.conn.SetWriteDeadline(t)
+}
@@ -21,6 +21,7 @@ const (
 	CMDTypeSlotMigrate                       // 槽迁移
 	CMDTypeSlotUpdate                        // 槽更新
 	CMDTypeNodeStatusChange                  // 节点状态改变
+	CMDTypeNodeLabelsChange                  // 节点标签（可用区、机架等）变更
 
 )
 
@@ -48,6 +49,8 @@ func (c CMDType) String() string {
 		return "CMDTypeSlotUpdate"
 	case CMDTypeNodeStatusChange:
 		return "CMDTypeNodeStatusChange"
+	case CMDTypeNodeLabelsChange:
+		return "CMDTypeNodeLabelsChange"
 	}
 	return "CMDTypeUnknown"
 }
@@ -169,6 +172,18 @@ func (c *CMD) CMDContent() (string, error) {
 			"nodeId": nodeId,
 			"status": status,
 		}), nil
+	case CMDTypeNodeLabelsChange:
+		node := &pb.Node{}
+		err := node.Unmarshal(c.Data)
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"nodeId": node.Id,
+			"zone":   node.Zone,
+			"rack":   node.Rack,
+			"labels": node.Labels,
+		}), nil
 	}
 
 	return "", nil
@@ -276,6 +276,19 @@ func (c *Config) updateNodeStatus(nodeId uint64, status pb.NodeStatus) {
 	}
 }
 
+func (c *Config) updateNodeLabels(nodeId uint64, zone, rack string, labels map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, node := range c.cfg.Nodes {
+		if node.Id == nodeId {
+			node.Zone = zone
+			node.Rack = rack
+			node.Labels = labels
+			return
+		}
+	}
+}
+
 func (c *Config) config() *pb.Config {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
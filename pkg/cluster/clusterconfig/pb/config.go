@@ -145,6 +145,22 @@ func (n *Node) Equal(v *Node) bool {
 	if n.ClusterAddr != v.ClusterAddr {
 		return false
 	}
+	return n.LabelsEqual(v.Zone, v.Rack, v.Labels)
+}
+
+// LabelsEqual 节点的可用区、机架和标签是否与给定的一致
+func (n *Node) LabelsEqual(zone, rack string, labels map[string]string) bool {
+	if n.Zone != zone || n.Rack != rack {
+		return false
+	}
+	if len(n.Labels) != len(labels) {
+		return false
+	}
+	for k, v := range n.Labels {
+		if lv, ok := labels[k]; !ok || lv != v {
+			return false
+		}
+	}
 	return true
 }
 
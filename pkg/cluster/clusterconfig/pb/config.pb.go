@@ -73,6 +73,7 @@ const (
 	NodeStatus_NodeStatusWillJoin NodeStatus = 1 // 将要加入
 	NodeStatus_NodeStatusJoining  NodeStatus = 2 // 加入中
 	NodeStatus_NodeStatusJoined   NodeStatus = 3 // 加入完成
+	NodeStatus_NodeStatusDraining NodeStatus = 4 // 排空中（准备下线）
 )
 
 // Enum value maps for NodeStatus.
@@ -82,12 +83,14 @@ var (
 		1: "NodeStatusWillJoin",
 		2: "NodeStatusJoining",
 		3: "NodeStatusJoined",
+		4: "NodeStatusDraining",
 	}
 	NodeStatus_value = map[string]int32{
 		"NodeStatusUnkown":   0,
 		"NodeStatusWillJoin": 1,
 		"NodeStatusJoining":  2,
 		"NodeStatusJoined":   3,
+		"NodeStatusDraining": 4,
 	}
 )
 
@@ -395,13 +398,16 @@ type Node struct {
 	Join          bool   `protobuf:"varint,4,opt,name=join,proto3" json:"join,omitempty"`                  // 是否是加入集群的节点，false表示初始节点 true表示后面新加入的节点
 	// repeated SlotMigrate exports = 5; // 正在迁出的槽位
 	// repeated SlotMigrate imports = 6; // 正在迁入的槽位
-	Online       bool       `protobuf:"varint,5,opt,name=online,proto3" json:"online,omitempty"`                     // 是否在线
-	OfflineCount uint32     `protobuf:"varint,6,opt,name=offlineCount,proto3" json:"offlineCount,omitempty"`         // 离线次数
-	LastOffline  int64      `protobuf:"varint,7,opt,name=lastOffline,proto3" json:"lastOffline,omitempty"`           // 最后一次离线时间
-	AllowVote    bool       `protobuf:"varint,8,opt,name=allowVote,proto3" json:"allowVote,omitempty"`               // 节点是否允许投票
-	Role         NodeRole   `protobuf:"varint,9,opt,name=role,proto3,enum=pb.NodeRole" json:"role,omitempty"`        // 节点角色
-	Status       NodeStatus `protobuf:"varint,10,opt,name=status,proto3,enum=pb.NodeStatus" json:"status,omitempty"` // 节点状态
-	CreatedAt    int64      `protobuf:"varint,11,opt,name=createdAt,proto3" json:"createdAt,omitempty"`              // 创建时间
+	Online       bool              `protobuf:"varint,5,opt,name=online,proto3" json:"online,omitempty"`                                                                                         // 是否在线
+	OfflineCount uint32            `protobuf:"varint,6,opt,name=offlineCount,proto3" json:"offlineCount,omitempty"`                                                                             // 离线次数
+	LastOffline  int64             `protobuf:"varint,7,opt,name=lastOffline,proto3" json:"lastOffline,omitempty"`                                                                               // 最后一次离线时间
+	AllowVote    bool              `protobuf:"varint,8,opt,name=allowVote,proto3" json:"allowVote,omitempty"`                                                                                   // 节点是否允许投票
+	Role         NodeRole          `protobuf:"varint,9,opt,name=role,proto3,enum=pb.NodeRole" json:"role,omitempty"`                                                                            // 节点角色
+	Status       NodeStatus        `protobuf:"varint,10,opt,name=status,proto3,enum=pb.NodeStatus" json:"status,omitempty"`                                                                     // 节点状态
+	CreatedAt    int64             `protobuf:"varint,11,opt,name=createdAt,proto3" json:"createdAt,omitempty"`                                                                                  // 创建时间
+	Zone         string            `protobuf:"bytes,12,opt,name=zone,proto3" json:"zone,omitempty"`                                                                                             // 节点所在可用区
+	Rack         string            `protobuf:"bytes,13,opt,name=rack,proto3" json:"rack,omitempty"`                                                                                             // 节点所在机架
+	Labels       map[string]string `protobuf:"bytes,14,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"` // 节点标签
 }
 
 func (x *Node) Reset() {
@@ -513,6 +519,27 @@ func (x *Node) GetCreatedAt() int64 {
 	return 0
 }
 
+func (x *Node) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *Node) GetRack() string {
+	if x != nil {
+		return x.Rack
+	}
+	return ""
+}
+
+func (x *Node) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
 type Slot struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -761,7 +788,7 @@ var file_pkg_cluster_clusterconfig_pb_config_proto_rawDesc = []byte{
 	0x08, 0x2e, 0x70, 0x62, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x05, 0x6e, 0x6f, 0x64, 0x65, 0x73,
 	0x12, 0x1e, 0x0a, 0x05, 0x73, 0x6c, 0x6f, 0x74, 0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0b, 0x32,
 	0x08, 0x2e, 0x70, 0x62, 0x2e, 0x53, 0x6c, 0x6f, 0x74, 0x52, 0x05, 0x73, 0x6c, 0x6f, 0x74, 0x73,
-	0x22, 0xd6, 0x02, 0x0a, 0x04, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x22, 0xe7, 0x03, 0x0a, 0x04, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
 	0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x69, 0x64, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6c, 0x75,
 	0x73, 0x74, 0x65, 0x72, 0x41, 0x64, 0x64, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b,
 	0x63, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x41, 0x64, 0x64, 0x72, 0x12, 0x24, 0x0a, 0x0d, 0x61,
@@ -782,60 +809,71 @@ var file_pkg_cluster_clusterconfig_pb_config_proto_rawDesc = []byte{
 	0x01, 0x28, 0x0e, 0x32, 0x0e, 0x2e, 0x70, 0x62, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x74, 0x61,
 	0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x63,
 	0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09,
-	0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x22, 0x86, 0x02, 0x0a, 0x04, 0x53, 0x6c,
-	0x6f, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02,
-	0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x04, 0x52, 0x06, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65,
-	0x72, 0x6d, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x74, 0x65, 0x72, 0x6d, 0x12, 0x1a,
-	0x0a, 0x08, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x04,
-	0x52, 0x08, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x65,
-	0x61, 0x72, 0x6e, 0x65, 0x72, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x04, 0x52, 0x08, 0x6c, 0x65,
-	0x61, 0x72, 0x6e, 0x65, 0x72, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x6d, 0x69, 0x67, 0x72, 0x61, 0x74,
-	0x65, 0x46, 0x72, 0x6f, 0x6d, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x6d, 0x69, 0x67,
-	0x72, 0x61, 0x74, 0x65, 0x46, 0x72, 0x6f, 0x6d, 0x12, 0x1c, 0x0a, 0x09, 0x6d, 0x69, 0x67, 0x72,
-	0x61, 0x74, 0x65, 0x54, 0x6f, 0x18, 0x08, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x6d, 0x69, 0x67,
-	0x72, 0x61, 0x74, 0x65, 0x54, 0x6f, 0x12, 0x22, 0x0a, 0x0c, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74,
-	0x4c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x09, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x65, 0x78,
-	0x70, 0x65, 0x63, 0x74, 0x4c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x26, 0x0a, 0x06, 0x73, 0x74,
-	0x61, 0x74, 0x75, 0x73, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0e, 0x2e, 0x70, 0x62, 0x2e,
-	0x53, 0x6c, 0x6f, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74,
-	0x75, 0x73, 0x22, 0x31, 0x0a, 0x0b, 0x53, 0x6c, 0x6f, 0x74, 0x4d, 0x69, 0x67, 0x72, 0x61, 0x74,
-	0x65, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52,
-	0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x04, 0x52, 0x02, 0x74, 0x6f, 0x22, 0x52, 0x0a, 0x07, 0x4c, 0x65, 0x61, 0x72, 0x6e, 0x65, 0x72,
-	0x12, 0x1c, 0x0a, 0x09, 0x6c, 0x65, 0x61, 0x72, 0x6e, 0x65, 0x72, 0x49, 0x64, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x04, 0x52, 0x09, 0x6c, 0x65, 0x61, 0x72, 0x6e, 0x65, 0x72, 0x49, 0x64, 0x12, 0x29,
-	0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x11,
-	0x2e, 0x70, 0x62, 0x2e, 0x4c, 0x65, 0x61, 0x72, 0x6e, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74, 0x75,
-	0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x2a, 0x32, 0x0a, 0x08, 0x4e, 0x6f, 0x64,
-	0x65, 0x52, 0x6f, 0x6c, 0x65, 0x12, 0x13, 0x0a, 0x0f, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x6f, 0x6c,
-	0x65, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x10, 0x00, 0x12, 0x11, 0x0a, 0x0d, 0x4e, 0x6f,
-	0x64, 0x65, 0x52, 0x6f, 0x6c, 0x65, 0x50, 0x72, 0x6f, 0x78, 0x79, 0x10, 0x01, 0x2a, 0x67, 0x0a,
-	0x0a, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x10, 0x4e,
-	0x6f, 0x64, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x55, 0x6e, 0x6b, 0x6f, 0x77, 0x6e, 0x10,
-	0x00, 0x12, 0x16, 0x0a, 0x12, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x57,
-	0x69, 0x6c, 0x6c, 0x4a, 0x6f, 0x69, 0x6e, 0x10, 0x01, 0x12, 0x15, 0x0a, 0x11, 0x4e, 0x6f, 0x64,
-	0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x4a, 0x6f, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x10, 0x02,
-	0x12, 0x14, 0x0a, 0x10, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x4a, 0x6f,
-	0x69, 0x6e, 0x65, 0x64, 0x10, 0x03, 0x2a, 0x6e, 0x0a, 0x0d, 0x4d, 0x69, 0x67, 0x72, 0x61, 0x74,
-	0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x17, 0x0a, 0x13, 0x4d, 0x69, 0x67, 0x72, 0x61,
-	0x74, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x55, 0x6e, 0x6b, 0x6f, 0x77, 0x6e, 0x10, 0x00,
-	0x12, 0x15, 0x0a, 0x11, 0x4d, 0x69, 0x67, 0x72, 0x61, 0x74, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75,
-	0x73, 0x57, 0x69, 0x6c, 0x6c, 0x10, 0x01, 0x12, 0x16, 0x0a, 0x12, 0x4d, 0x69, 0x67, 0x72, 0x61,
-	0x74, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x44, 0x6f, 0x69, 0x6e, 0x67, 0x10, 0x02, 0x12,
-	0x15, 0x0a, 0x11, 0x4d, 0x69, 0x67, 0x72, 0x61, 0x74, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
-	0x44, 0x6f, 0x6e, 0x65, 0x10, 0x03, 0x2a, 0x59, 0x0a, 0x0a, 0x53, 0x6c, 0x6f, 0x74, 0x53, 0x74,
-	0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x10, 0x53, 0x6c, 0x6f, 0x74, 0x53, 0x74, 0x61, 0x74,
-	0x75, 0x73, 0x4e, 0x6f, 0x72, 0x6d, 0x61, 0x6c, 0x10, 0x00, 0x12, 0x17, 0x0a, 0x13, 0x53, 0x6c,
-	0x6f, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x43, 0x61, 0x6e, 0x64, 0x69, 0x64, 0x61, 0x74,
-	0x65, 0x10, 0x01, 0x12, 0x1c, 0x0a, 0x18, 0x53, 0x6c, 0x6f, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75,
-	0x73, 0x4c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x10,
-	0x02, 0x2a, 0x45, 0x0a, 0x0d, 0x4c, 0x65, 0x61, 0x72, 0x6e, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74,
-	0x75, 0x73, 0x12, 0x19, 0x0a, 0x15, 0x4c, 0x65, 0x61, 0x72, 0x6e, 0x65, 0x72, 0x53, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x4c, 0x65, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x10, 0x00, 0x12, 0x19, 0x0a,
-	0x15, 0x4c, 0x65, 0x61, 0x72, 0x6e, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x47, 0x72,
-	0x61, 0x64, 0x75, 0x61, 0x74, 0x65, 0x10, 0x01, 0x42, 0x07, 0x5a, 0x05, 0x2e, 0x2f, 0x3b, 0x70,
-	0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x7a, 0x6f, 0x6e,
+	0x65, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x7a, 0x6f, 0x6e, 0x65, 0x12, 0x12, 0x0a,
+	0x04, 0x72, 0x61, 0x63, 0x6b, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x61, 0x63,
+	0x6b, 0x12, 0x2c, 0x0a, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x18, 0x0e, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x14, 0x2e, 0x70, 0x62, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x2e, 0x4c, 0x61, 0x62, 0x65,
+	0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x1a,
+	0x39, 0x0a, 0x0b, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10,
+	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
+	0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x86, 0x02, 0x0a, 0x04, 0x53,
+	0x6c, 0x6f, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x06, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x74,
+	0x65, 0x72, 0x6d, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x74, 0x65, 0x72, 0x6d, 0x12,
+	0x1a, 0x0a, 0x08, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28,
+	0x04, 0x52, 0x08, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x6c,
+	0x65, 0x61, 0x72, 0x6e, 0x65, 0x72, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x04, 0x52, 0x08, 0x6c,
+	0x65, 0x61, 0x72, 0x6e, 0x65, 0x72, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x6d, 0x69, 0x67, 0x72, 0x61,
+	0x74, 0x65, 0x46, 0x72, 0x6f, 0x6d, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x6d, 0x69,
+	0x67, 0x72, 0x61, 0x74, 0x65, 0x46, 0x72, 0x6f, 0x6d, 0x12, 0x1c, 0x0a, 0x09, 0x6d, 0x69, 0x67,
+	0x72, 0x61, 0x74, 0x65, 0x54, 0x6f, 0x18, 0x08, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x6d, 0x69,
+	0x67, 0x72, 0x61, 0x74, 0x65, 0x54, 0x6f, 0x12, 0x22, 0x0a, 0x0c, 0x65, 0x78, 0x70, 0x65, 0x63,
+	0x74, 0x4c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x09, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x65,
+	0x78, 0x70, 0x65, 0x63, 0x74, 0x4c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x26, 0x0a, 0x06, 0x73,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0e, 0x2e, 0x70, 0x62,
+	0x2e, 0x53, 0x6c, 0x6f, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x22, 0x31, 0x0a, 0x0b, 0x53, 0x6c, 0x6f, 0x74, 0x4d, 0x69, 0x67, 0x72, 0x61,
+	0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x02, 0x74, 0x6f, 0x22, 0x52, 0x0a, 0x07, 0x4c, 0x65, 0x61, 0x72, 0x6e, 0x65,
+	0x72, 0x12, 0x1c, 0x0a, 0x09, 0x6c, 0x65, 0x61, 0x72, 0x6e, 0x65, 0x72, 0x49, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x6c, 0x65, 0x61, 0x72, 0x6e, 0x65, 0x72, 0x49, 0x64, 0x12,
+	0x29, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x11, 0x2e, 0x70, 0x62, 0x2e, 0x4c, 0x65, 0x61, 0x72, 0x6e, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x2a, 0x32, 0x0a, 0x08, 0x4e, 0x6f,
+	0x64, 0x65, 0x52, 0x6f, 0x6c, 0x65, 0x12, 0x13, 0x0a, 0x0f, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x6f,
+	0x6c, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x10, 0x00, 0x12, 0x11, 0x0a, 0x0d, 0x4e,
+	0x6f, 0x64, 0x65, 0x52, 0x6f, 0x6c, 0x65, 0x50, 0x72, 0x6f, 0x78, 0x79, 0x10, 0x01, 0x2a, 0x7f,
+	0x0a, 0x0a, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x10,
+	0x4e, 0x6f, 0x64, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x55, 0x6e, 0x6b, 0x6f, 0x77, 0x6e,
+	0x10, 0x00, 0x12, 0x16, 0x0a, 0x12, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x57, 0x69, 0x6c, 0x6c, 0x4a, 0x6f, 0x69, 0x6e, 0x10, 0x01, 0x12, 0x15, 0x0a, 0x11, 0x4e, 0x6f,
+	0x64, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x4a, 0x6f, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x10,
+	0x02, 0x12, 0x14, 0x0a, 0x10, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x4a,
+	0x6f, 0x69, 0x6e, 0x65, 0x64, 0x10, 0x03, 0x12, 0x16, 0x0a, 0x12, 0x4e, 0x6f, 0x64, 0x65, 0x53,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x44, 0x72, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x10, 0x04, 0x2a,
+	0x6e, 0x0a, 0x0d, 0x4d, 0x69, 0x67, 0x72, 0x61, 0x74, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x12, 0x17, 0x0a, 0x13, 0x4d, 0x69, 0x67, 0x72, 0x61, 0x74, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x55, 0x6e, 0x6b, 0x6f, 0x77, 0x6e, 0x10, 0x00, 0x12, 0x15, 0x0a, 0x11, 0x4d, 0x69, 0x67,
+	0x72, 0x61, 0x74, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x57, 0x69, 0x6c, 0x6c, 0x10, 0x01,
+	0x12, 0x16, 0x0a, 0x12, 0x4d, 0x69, 0x67, 0x72, 0x61, 0x74, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x44, 0x6f, 0x69, 0x6e, 0x67, 0x10, 0x02, 0x12, 0x15, 0x0a, 0x11, 0x4d, 0x69, 0x67, 0x72,
+	0x61, 0x74, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x44, 0x6f, 0x6e, 0x65, 0x10, 0x03, 0x2a,
+	0x59, 0x0a, 0x0a, 0x53, 0x6c, 0x6f, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a,
+	0x10, 0x53, 0x6c, 0x6f, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x4e, 0x6f, 0x72, 0x6d, 0x61,
+	0x6c, 0x10, 0x00, 0x12, 0x17, 0x0a, 0x13, 0x53, 0x6c, 0x6f, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x43, 0x61, 0x6e, 0x64, 0x69, 0x64, 0x61, 0x74, 0x65, 0x10, 0x01, 0x12, 0x1c, 0x0a, 0x18,
+	0x53, 0x6c, 0x6f, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x4c, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x10, 0x02, 0x2a, 0x45, 0x0a, 0x0d, 0x4c, 0x65,
+	0x61, 0x72, 0x6e, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x19, 0x0a, 0x15, 0x4c,
+	0x65, 0x61, 0x72, 0x6e, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x4c, 0x65, 0x61, 0x72,
+	0x6e, 0x69, 0x6e, 0x67, 0x10, 0x00, 0x12, 0x19, 0x0a, 0x15, 0x4c, 0x65, 0x61, 0x72, 0x6e, 0x65,
+	0x72, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x47, 0x72, 0x61, 0x64, 0x75, 0x61, 0x74, 0x65, 0x10,
+	0x01, 0x42, 0x07, 0x5a, 0x05, 0x2e, 0x2f, 0x3b, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
 }
 
 var (
@@ -851,7 +889,7 @@ func file_pkg_cluster_clusterconfig_pb_config_proto_rawDescGZIP() []byte {
 }
 
 var file_pkg_cluster_clusterconfig_pb_config_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
-var file_pkg_cluster_clusterconfig_pb_config_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_pkg_cluster_clusterconfig_pb_config_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_pkg_cluster_clusterconfig_pb_config_proto_goTypes = []interface{}{
 	(NodeRole)(0),       // 0: pb.NodeRole
 	(NodeStatus)(0),     // 1: pb.NodeStatus
@@ -863,19 +901,21 @@ var file_pkg_cluster_clusterconfig_pb_config_proto_goTypes = []interface{}{
 	(*Slot)(nil),        // 7: pb.Slot
 	(*SlotMigrate)(nil), // 8: pb.SlotMigrate
 	(*Learner)(nil),     // 9: pb.Learner
+	nil,                 // 10: pb.Node.LabelsEntry
 }
 var file_pkg_cluster_clusterconfig_pb_config_proto_depIdxs = []int32{
-	6, // 0: pb.Config.nodes:type_name -> pb.Node
-	7, // 1: pb.Config.slots:type_name -> pb.Slot
-	0, // 2: pb.Node.role:type_name -> pb.NodeRole
-	1, // 3: pb.Node.status:type_name -> pb.NodeStatus
-	3, // 4: pb.Slot.status:type_name -> pb.SlotStatus
-	4, // 5: pb.Learner.status:type_name -> pb.LearnerStatus
-	6, // [6:6] is the sub-list for method output_type
-	6, // [6:6] is the sub-list for method input_type
-	6, // [6:6] is the sub-list for extension type_name
-	6, // [6:6] is the sub-list for extension extendee
-	0, // [0:6] is the sub-list for field type_name
+	6,  // 0: pb.Config.nodes:type_name -> pb.Node
+	7,  // 1: pb.Config.slots:type_name -> pb.Slot
+	0,  // 2: pb.Node.role:type_name -> pb.NodeRole
+	1,  // 3: pb.Node.status:type_name -> pb.NodeStatus
+	10, // 4: pb.Node.labels:type_name -> pb.Node.LabelsEntry
+	3,  // 5: pb.Slot.status:type_name -> pb.SlotStatus
+	4,  // 6: pb.Learner.status:type_name -> pb.LearnerStatus
+	7,  // [7:7] is the sub-list for method output_type
+	7,  // [7:7] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_pkg_cluster_clusterconfig_pb_config_proto_init() }
@@ -951,7 +991,7 @@ func file_pkg_cluster_clusterconfig_pb_config_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_pkg_cluster_clusterconfig_pb_config_proto_rawDesc,
 			NumEnums:      5,
-			NumMessages:   5,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
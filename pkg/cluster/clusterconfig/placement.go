@@ -0,0 +1,168 @@
+package clusterconfig
+
+import (
+	"sort"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+)
+
+// PickReplicas 从nodes中挑选count个副本节点，副本尽量分散在不同的可用区，同一可用区内尽量分散在不同的机架
+// preset为必须包含的节点（比如频道领导），offset为候选节点的起始位置，用于让副本在节点间轮流分布
+// 节点都没有设置可用区和机架时，结果与按offset轮询挑选一致
+func PickReplicas(nodes []*pb.Node, count int, preset []uint64, offset int) []uint64 {
+	replicas := make([]uint64, 0, count)
+	zoneCountMap := make(map[string]int) // 每个可用区已选的副本数量
+	rackCountMap := make(map[string]int) // 每个机架已选的副本数量
+	nodeMap := make(map[uint64]*pb.Node, len(nodes))
+	for _, node := range nodes {
+		nodeMap[node.Id] = node
+	}
+
+	add := func(nodeId uint64) {
+		replicas = append(replicas, nodeId)
+		if node := nodeMap[nodeId]; node != nil {
+			zoneCountMap[node.Zone]++
+			rackCountMap[rackKey(node)]++
+		}
+	}
+	for _, nodeId := range preset {
+		if len(replicas) >= count {
+			break
+		}
+		if !wkutil.ArrayContainsUint64(replicas, nodeId) {
+			add(nodeId)
+		}
+	}
+
+	candidates := make([]*pb.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if !wkutil.ArrayContainsUint64(replicas, node.Id) {
+			candidates = append(candidates, node)
+		}
+	}
+	if len(candidates) > 0 && offset > 0 {
+		offset = offset % len(candidates)
+		candidates = append(candidates[offset:], candidates[:offset]...)
+	}
+
+	for len(replicas) < count && len(candidates) > 0 {
+		best := 0
+		for i := 1; i < len(candidates); i++ {
+			c, b := candidates[i], candidates[best]
+			if zoneCountMap[c.Zone] != zoneCountMap[b.Zone] {
+				if zoneCountMap[c.Zone] < zoneCountMap[b.Zone] {
+					best = i
+				}
+				continue
+			}
+			if rackCountMap[rackKey(c)] < rackCountMap[rackKey(b)] {
+				best = i
+			}
+		}
+		add(candidates[best].Id)
+		candidates = append(candidates[:best], candidates[best+1:]...)
+	}
+	return replicas
+}
+
+// ReplicaZones 副本所在的可用区（去重并排序，不包含没有设置可用区的节点）
+func ReplicaZones(nodes []*pb.Node, replicas []uint64) []string {
+	zoneMap := make(map[string]struct{})
+	for _, node := range nodes {
+		if node.Zone != "" && wkutil.ArrayContainsUint64(replicas, node.Id) {
+			zoneMap[node.Zone] = struct{}{}
+		}
+	}
+	return sortedZones(zoneMap)
+}
+
+// ExpectZoneCount replicaCount个副本至少应该分布在多少个可用区
+// 集群节点分布在少于两个可用区时没有约束，返回0
+func ExpectZoneCount(nodes []*pb.Node, replicaCount int) int {
+	zoneMap := make(map[string]struct{})
+	for _, node := range nodes {
+		if node.Zone != "" {
+			zoneMap[node.Zone] = struct{}{}
+		}
+	}
+	if len(zoneMap) < 2 {
+		return 0
+	}
+	if replicaCount < len(zoneMap) {
+		return replicaCount
+	}
+	return len(zoneMap)
+}
+
+// PlacementSatisfied 副本分布是否满足可用区约束
+func PlacementSatisfied(nodes []*pb.Node, replicas []uint64) bool {
+	return len(ReplicaZones(nodes, replicas)) >= ExpectZoneCount(nodes, len(replicas))
+}
+
+// PlacementRepair 为不满足可用区约束的副本挑选一次迁移
+// from为需要迁出的副本（不会是领导，优先选择副本最多的可用区或者没有设置可用区的副本），to为副本还没有覆盖的可用区中负载最小的节点
+// load为节点当前的负载（比如槽数量），不需要迁移或找不到合适的节点时返回0, 0
+func PlacementRepair(nodes []*pb.Node, replicas []uint64, leaderId uint64, load func(nodeId uint64) int) (from uint64, to uint64) {
+	if PlacementSatisfied(nodes, replicas) {
+		return 0, 0
+	}
+	nodeMap := make(map[uint64]*pb.Node, len(nodes))
+	for _, node := range nodes {
+		nodeMap[node.Id] = node
+	}
+	zoneCountMap := make(map[string]int)
+	for _, replicaId := range replicas {
+		if node := nodeMap[replicaId]; node != nil && node.Zone != "" {
+			zoneCountMap[node.Zone]++
+		}
+	}
+
+	fromZoneCount := 1
+	for _, replicaId := range replicas {
+		if replicaId == leaderId {
+			continue
+		}
+		node := nodeMap[replicaId]
+		if node == nil || node.Zone == "" { // 没有可用区的副本优先迁出
+			from = replicaId
+			break
+		}
+		if zoneCountMap[node.Zone] > fromZoneCount {
+			from = replicaId
+			fromZoneCount = zoneCountMap[node.Zone]
+		}
+	}
+	if from == 0 {
+		return 0, 0
+	}
+
+	toLoad := 0
+	for _, node := range nodes {
+		if node.Zone == "" || zoneCountMap[node.Zone] > 0 || wkutil.ArrayContainsUint64(replicas, node.Id) {
+			continue
+		}
+		nodeLoad := load(node.Id)
+		if to == 0 || nodeLoad < toLoad {
+			to = node.Id
+			toLoad = nodeLoad
+		}
+	}
+	if to == 0 {
+		return 0, 0
+	}
+	return from, to
+}
+
+func rackKey(node *pb.Node) string {
+	return node.Zone + "/" + node.Rack
+}
+
+func sortedZones(zoneMap map[string]struct{}) []string {
+	zones := make([]string, 0, len(zoneMap))
+	for zone := range zoneMap {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones
+}
@@ -0,0 +1,63 @@
+package clusterconfig_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func testZoneNodes() []*pb.Node {
+	return []*pb.Node{
+		{Id: 1, Zone: "a", Rack: "r1"},
+		{Id: 2, Zone: "a", Rack: "r1"},
+		{Id: 3, Zone: "a", Rack: "r2"},
+		{Id: 4, Zone: "b", Rack: "r1"},
+		{Id: 5, Zone: "b", Rack: "r2"},
+		{Id: 6, Zone: "c", Rack: "r1"},
+	}
+}
+
+func TestPickReplicas(t *testing.T) {
+	nodes := testZoneNodes()
+	for offset := 0; offset < len(nodes); offset++ {
+		replicas := clusterconfig.PickReplicas(nodes, 3, nil, offset)
+		assert.Len(t, replicas, 3)
+		assert.Equal(t, []string{"a", "b", "c"}, clusterconfig.ReplicaZones(nodes, replicas))
+	}
+
+	// 必须包含的节点
+	replicas := clusterconfig.PickReplicas(nodes, 3, []uint64{1}, 0)
+	assert.Equal(t, uint64(1), replicas[0])
+	assert.True(t, clusterconfig.PlacementSatisfied(nodes, replicas))
+
+	// 可用区不够时同一可用区内分散到不同机架
+	replicas = clusterconfig.PickReplicas(nodes, 5, []uint64{1}, 0)
+	assert.Contains(t, replicas, uint64(3))
+	assert.NotContains(t, replicas, uint64(2))
+
+	// 没有可用区时按offset轮询
+	plainNodes := []*pb.Node{{Id: 1}, {Id: 2}, {Id: 3}, {Id: 4}}
+	assert.Equal(t, []uint64{2, 3, 4}, clusterconfig.PickReplicas(plainNodes, 3, nil, 1))
+	assert.Equal(t, []uint64{4, 1, 2}, clusterconfig.PickReplicas(plainNodes, 3, nil, 3))
+}
+
+func TestPlacementRepair(t *testing.T) {
+	nodes := testZoneNodes()
+
+	assert.Equal(t, 0, clusterconfig.ExpectZoneCount([]*pb.Node{{Id: 1, Zone: "a"}, {Id: 2}}, 3))
+	assert.Equal(t, 2, clusterconfig.ExpectZoneCount(nodes, 2))
+	assert.Equal(t, 3, clusterconfig.ExpectZoneCount(nodes, 5))
+
+	replicas := []uint64{1, 2, 4}
+	assert.False(t, clusterconfig.PlacementSatisfied(nodes, replicas))
+
+	from, to := clusterconfig.PlacementRepair(nodes, replicas, 1, func(nodeId uint64) int { return 0 })
+	assert.Equal(t, uint64(2), from) // 领导不迁出
+	assert.Equal(t, uint64(6), to)
+
+	from, to = clusterconfig.PlacementRepair(nodes, []uint64{1, 4, 6}, 1, func(nodeId uint64) int { return 0 })
+	assert.Equal(t, uint64(0), from)
+	assert.Equal(t, uint64(0), to)
+}
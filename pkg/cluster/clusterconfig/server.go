@@ -468,6 +468,11 @@ func (s *Server) send(m reactor.Message) {
 // 	}
 // }
 
+// SetSeed 设置种子节点（需要在Start之前调用）
+func (s *Server) SetSeed(seed string) {
+	s.opts.Seed = seed
+}
+
 // 是否需要加入集群
 func (s *Server) needJoin() bool {
 	if strings.TrimSpace(s.opts.Seed) == "" {
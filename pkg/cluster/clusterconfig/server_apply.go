@@ -69,6 +69,8 @@ func (s *Server) handleCmd(cmd *CMD) error {
 		return s.handleSlotUpdate(cmd)
 	case CMDTypeNodeStatusChange: // 节点状态改变
 		return s.handleNodeStatusChange(cmd)
+	case CMDTypeNodeLabelsChange: // 节点标签变更
+		return s.handleNodeLabelsChange(cmd)
 	}
 	return nil
 }
@@ -180,3 +182,14 @@ func (s *Server) handleNodeStatusChange(cmd *CMD) error {
 	s.cfg.updateNodeStatus(nodeId, status)
 	return nil
 }
+
+func (s *Server) handleNodeLabelsChange(cmd *CMD) error {
+	node := &pb.Node{}
+	err := node.Unmarshal(cmd.Data)
+	if err != nil {
+		s.Error("unmarshal node labels err", zap.Error(err))
+		return err
+	}
+	s.cfg.updateNodeLabels(node.Id, node.Zone, node.Rack, node.Labels)
+	return nil
+}
@@ -64,6 +64,33 @@ func (s *Server) ProposeApiServerAddr(nodeId uint64, apiServerAddr string) error
 	return nil
 }
 
+// ProposeNodeLabels 提案节点标签（可用区、机架等）变更
+func (s *Server) ProposeNodeLabels(nodeId uint64, zone, rack string, labels map[string]string) error {
+	data, err := (&pb.Node{Id: nodeId, Zone: zone, Rack: rack, Labels: labels}).Marshal()
+	if err != nil {
+		return err
+	}
+
+	cmd := NewCMD(CMDTypeNodeLabelsChange, data)
+	cmdBytes, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+
+	err = s.proposeAndWait([]replica.Log{
+		{
+			Id:   uint64(s.cfgGenId.Generate().Int64()),
+			Data: cmdBytes,
+		},
+	})
+	if err != nil {
+		s.Error("ProposeNodeLabels failed", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
 // ProposeJoin 提案节点加入
 func (s *Server) ProposeJoin(node *pb.Node) error {
 
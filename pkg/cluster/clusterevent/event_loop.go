@@ -4,6 +4,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	"go.uber.org/zap"
@@ -23,6 +24,8 @@ func (s *Server) loop() {
 			s.tick()
 		case nodeId := <-s.pongC:
 			s.pongTickMap[nodeId] = 0
+		case l := <-s.livenessC:
+			s.handleNodeLiveness(l)
 		case <-s.stopper.ShouldStop():
 			return
 		}
@@ -59,6 +62,13 @@ func (s *Server) checkClusterConfig() error {
 			return err
 		}
 
+		// 检查槽副本的可用区分布
+		err = s.handleSlotPlacement()
+		if err != nil {
+			s.Error("handleSlotPlacement failed", zap.Error(err))
+			return err
+		}
+
 	}
 
 	// ================== 处理槽领导选举 ==================
@@ -106,6 +116,23 @@ func (s *Server) tick() {
 
 }
 
+// 根据gossip探测的存活状态调整pong tick，由tick统一提案节点上下线
+// gossip存活不代表节点的分布式通讯正常，所以存活事件不重置pong tick，只有收到pong才重置
+func (s *Server) handleNodeLiveness(l nodeLiveness) {
+	if !s.IsLeader() || l.nodeId == s.opts.NodeId {
+		return
+	}
+	tk := s.pongTickMap[l.nodeId]
+	switch l.liveness {
+	case NodeLivenessSuspect: // 被怀疑的节点只剩一半的心跳容忍时间
+		if half := s.opts.PongMaxTick / 2; tk < half {
+			s.pongTickMap[l.nodeId] = half
+		}
+	case NodeLivenessDead:
+		s.pongTickMap[l.nodeId] = s.opts.PongMaxTick
+	}
+}
+
 func (s *Server) handleClusterConfigInit() error {
 
 	cfg := &pb.Config{
@@ -118,20 +145,22 @@ func (s *Server) handleClusterConfigInit() error {
 
 	var replicas []uint64
 	for nodeId, addr := range s.opts.InitNodes {
-		apiAddr := ""
-		if nodeId == s.opts.NodeId {
-			apiAddr = s.opts.ApiServerAddr
-		}
-		nodes = append(nodes, &pb.Node{
-			Id:            nodeId,
-			ClusterAddr:   addr,
-			ApiServerAddr: apiAddr,
-			Online:        true,
-			AllowVote:     true,
-			Role:          pb.NodeRole_NodeRoleReplica,
-			Status:        pb.NodeStatus_NodeStatusJoined,
-			CreatedAt:     time.Now().Unix(),
-		})
+		node := &pb.Node{
+			Id:          nodeId,
+			ClusterAddr: addr,
+			Online:      true,
+			AllowVote:   true,
+			Role:        pb.NodeRole_NodeRoleReplica,
+			Status:      pb.NodeStatus_NodeStatusJoined,
+			CreatedAt:   time.Now().Unix(),
+		}
+		if nodeId == s.opts.NodeId { // 其他节点的可用区等信息由节点自己提案
+			node.ApiServerAddr = s.opts.ApiServerAddr
+			node.Zone = s.opts.Zone
+			node.Rack = s.opts.Rack
+			node.Labels = s.opts.Labels
+		}
+		nodes = append(nodes, node)
 		replicas = append(replicas, nodeId)
 	}
 	cfg.Nodes = nodes
@@ -146,11 +175,7 @@ func (s *Server) handleClusterConfigInit() error {
 			if len(replicas) <= int(replicaCount) {
 				slot.Replicas = replicas
 			} else {
-				slot.Replicas = make([]uint64, 0, replicaCount)
-				for i := uint32(0); i < replicaCount; i++ {
-					idx := (offset + int(i)) % len(replicas)
-					slot.Replicas = append(slot.Replicas, replicas[idx])
-				}
+				slot.Replicas = clusterconfig.PickReplicas(nodes, int(replicaCount), nil, offset)
 			}
 			offset++
 			// 随机选举一个领导者
@@ -201,6 +226,15 @@ func (s *Server) handleCompareLocalClusterConfig() error {
 		}
 	}
 
+	// 如果配置里自己节点的可用区、机架或标签与本地配置不同，则提案配置
+	if localNode := s.cfgServer.Node(s.opts.NodeId); localNode != nil && !localNode.LabelsEqual(s.opts.Zone, s.opts.Rack, s.opts.Labels) {
+		err := s.cfgServer.ProposeNodeLabels(s.opts.NodeId, s.opts.Zone, s.opts.Rack, s.opts.Labels)
+		if err != nil {
+			s.Error("ProposeNodeLabels failed", zap.Error(err))
+			return err
+		}
+	}
+
 	if s.IsLeader() {
 		// 节点在线状态改变
 		err := s.handleNodeOnlineStatusChange()
@@ -354,6 +388,18 @@ func (s *Server) handleNodeJoining() error {
 
 	voteNodes := s.cfgServer.AllowVoteNodes()
 
+	// 副本从from迁到新节点后，副本覆盖的可用区不能减少
+	keepZoneSpread := func(slot *pb.Slot, from uint64) bool {
+		newReplicas := make([]uint64, 0, len(slot.Replicas))
+		for _, replicaId := range slot.Replicas {
+			if replicaId != from {
+				newReplicas = append(newReplicas, replicaId)
+			}
+		}
+		newReplicas = append(newReplicas, joiningNode.Id)
+		return len(clusterconfig.ReplicaZones(voteNodes, newReplicas)) >= len(clusterconfig.ReplicaZones(voteNodes, slot.Replicas))
+	}
+
 	if uint32(len(firstSlot.Replicas)) < s.cfgServer.SlotReplicaCount() { // 如果当前槽的副本数量小于配置的副本数量，则可以将新节点直接加入到学习节点中
 		for _, slot := range slots {
 			newSlot := slot.Clone()
@@ -428,7 +474,7 @@ func (s *Server) handleNodeJoining() error {
 
 				// ------------------- 分配槽领导 -------------------
 				allocSlotLeader := false // 是否已经分配完槽领导
-				if fromSlotCount > 0 && fromSlotLeaderCount > 0 && slot.Leader == node.Id {
+				if fromSlotCount > 0 && fromSlotLeaderCount > 0 && slot.Leader == node.Id && keepZoneSpread(slot, node.Id) {
 
 					allocSlotLeader = true
 					newSlot := slot.Clone()
@@ -446,7 +492,7 @@ func (s *Server) handleNodeJoining() error {
 
 				// ------------------- 分配槽副本 -------------------
 				if fromSlotCount > 0 && !allocSlotLeader {
-					if wkutil.ArrayContainsUint64(slot.Replicas, node.Id) {
+					if wkutil.ArrayContainsUint64(slot.Replicas, node.Id) && keepZoneSpread(slot, node.Id) {
 						newSlot := slot.Clone()
 						newSlot.MigrateFrom = node.Id
 						newSlot.MigrateTo = joiningNode.Id
@@ -496,6 +542,9 @@ func (s *Server) handleNodeOnlineChange(nodeId uint64, online bool) error {
 	if online { // 节点上线
 
 		s.Info("节点上线", zap.Uint64("nodeId", nodeId))
+		if node := s.cfgServer.Node(nodeId); node != nil && node.Status == pb.NodeStatus_NodeStatusDraining { // 排空中的节点不迁入槽领导
+			return nil
+		}
 		slots := s.cfgServer.Slots()
 
 		onlineNodeCount := s.cfgServer.AllowVoteAndJoinedOnlineNodeCount()
@@ -577,6 +626,11 @@ func (s *Server) handleNodeOnlineChange(nodeId uint64, online bool) error {
 					continue
 				}
 
+				// 排空中的节点不作为新的领导
+				if n := s.cfgServer.Node(nId); n != nil && n.Status == pb.NodeStatus_NodeStatusDraining {
+					continue
+				}
+
 				if currentNodeSlotLeaderCount <= 0 {
 					break
 				}
@@ -660,3 +714,58 @@ func (s *Server) handleSlotLeaderAutoBalance() error {
 	}
 	return nil
 }
+
+// 槽副本不满足可用区约束时（比如初始化时还不知道其他节点的可用区），将副本迁移到还没有覆盖的可用区
+func (s *Server) handleSlotPlacement() error {
+	cfg := s.cfgServer.Config()
+
+	// 有未加入或离线的节点，或者有槽正在迁移，则不调整副本分布
+	for _, node := range cfg.Nodes {
+		if node.Status != pb.NodeStatus_NodeStatusJoined || !node.Online {
+			return nil
+		}
+	}
+	for _, slot := range cfg.Slots {
+		if slot.MigrateFrom != 0 || slot.MigrateTo != 0 {
+			return nil
+		}
+		if slot.Status == pb.SlotStatus_SlotStatusCandidate {
+			return nil
+		}
+	}
+
+	nodes := s.cfgServer.AllowVoteAndJoinedNodes()
+	nodeSlotCountMap := make(map[uint64]int) // 每个节点的槽数量
+	for _, slot := range cfg.Slots {
+		for _, replicaId := range slot.Replicas {
+			nodeSlotCountMap[replicaId]++
+		}
+	}
+
+	var newSlots []*pb.Slot
+	for _, slot := range cfg.Slots {
+		from, to := clusterconfig.PlacementRepair(nodes, slot.Replicas, slot.Leader, func(nodeId uint64) int {
+			return nodeSlotCountMap[nodeId]
+		})
+		if from == 0 || to == 0 {
+			continue
+		}
+		newSlot := slot.Clone()
+		newSlot.MigrateFrom = from
+		newSlot.MigrateTo = to
+		newSlot.Learners = append(newSlot.Learners, to)
+		newSlots = append(newSlots, newSlot)
+		nodeSlotCountMap[from]--
+		nodeSlotCountMap[to]++
+	}
+	if len(newSlots) == 0 {
+		return nil
+	}
+	s.Info("槽副本不满足可用区约束，开始迁移", zap.Int("slotCount", len(newSlots)))
+	err := s.ProposeSlots(newSlots)
+	if err != nil {
+		s.Error("handleSlotPlacement failed,ProposeSlots failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
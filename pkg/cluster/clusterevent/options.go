@@ -17,6 +17,9 @@ type Options struct {
 	ChannelMaxReplicaCount uint32 // 每个频道最大副本数量
 	ConfigDir              string
 	ApiServerAddr          string                       // api服务地址
+	Zone                   string                       // 节点所在可用区
+	Rack                   string                       // 节点所在机架
+	Labels                 map[string]string            // 节点标签
 	OnClusterConfigChange  func(cfg *pb.Config)         // 分布式配置改变
 	OnSlotElection         func(slots []*pb.Slot) error // 槽位选举
 	Send                   func(m reactor.Message)      // 发送消息
@@ -105,6 +108,24 @@ func WithApiServerAddr(apiServerAddr string) Option {
 	}
 }
 
+func WithZone(zone string) Option {
+	return func(o *Options) {
+		o.Zone = zone
+	}
+}
+
+func WithRack(rack string) Option {
+	return func(o *Options) {
+		o.Rack = rack
+	}
+}
+
+func WithLabels(labels map[string]string) Option {
+	return func(o *Options) {
+		o.Labels = labels
+	}
+}
+
 func WithCluster(cluster icluster.Cluster) Option {
 	return func(o *Options) {
 		o.Cluster = cluster
@@ -36,6 +36,22 @@ type Server struct {
 	stopped atomic.Bool
 
 	pongC chan uint64
+
+	livenessC chan nodeLiveness // 节点存活状态（由gossip上报）
+}
+
+// NodeLiveness 节点存活状态
+type NodeLiveness int
+
+const (
+	NodeLivenessAlive   NodeLiveness = iota // 存活
+	NodeLivenessSuspect                     // 被怀疑故障
+	NodeLivenessDead                        // 故障
+)
+
+type nodeLiveness struct {
+	nodeId   uint64
+	liveness NodeLiveness
 }
 
 func New(opts *Options) *Server {
@@ -57,6 +73,7 @@ func New(opts *Options) *Server {
 		stopper:       syncutil.NewStopper(),
 		pongTickMap:   make(map[uint64]int),
 		pongC:         make(chan uint64, 100),
+		livenessC:     make(chan nodeLiveness, 100),
 	}
 
 	s.cfgServer = clusterconfig.New(clusterconfig.NewOptions(
@@ -122,6 +139,22 @@ func (s *Server) AddMessage(m reactor.Message) {
 	}
 }
 
+// SetSeed 设置种子节点（需要在Start之前调用）
+func (s *Server) SetSeed(seed string) {
+	s.opts.Seed = seed
+	s.cfgServer.SetSeed(seed)
+}
+
+// NodeLivenessChange 外部（gossip）探测到节点存活状态改变
+// 领导节点会根据此状态加快节点上下线的提案
+func (s *Server) NodeLivenessChange(nodeId uint64, liveness NodeLiveness) {
+	select {
+	case s.livenessC <- nodeLiveness{nodeId: nodeId, liveness: liveness}:
+	default:
+		s.Warn("livenessC is full, ignore", zap.Uint64("nodeId", nodeId))
+	}
+}
+
 func (s *Server) loadLocalConfig() error {
 	clusterCfgPath := s.localCfgPath
 	var err error
@@ -201,11 +234,21 @@ func (s *Server) ProposeJoin(node *pb.Node) error {
 	return s.cfgServer.ProposeJoin(node)
 }
 
+func (s *Server) ProposeNodeLabels(nodeId uint64, zone, rack string, labels map[string]string) error {
+	return s.cfgServer.ProposeNodeLabels(nodeId, zone, rack, labels)
+}
+
 func (s *Server) ProposeMigrateSlot(slotId uint32, fromNodeId, toNodeId uint64) error {
 
 	return s.cfgServer.ProposeMigrateSlot(slotId, fromNodeId, toNodeId)
 }
 
+// ProposeNodeStatus 提案节点状态
+func (s *Server) ProposeNodeStatus(nodeId uint64, status pb.NodeStatus) error {
+
+	return s.cfgServer.ProposeNodeStatus(nodeId, status)
+}
+
 func (s *Server) ProposeSlots(slots []*pb.Slot) error {
 
 	return s.cfgServer.ProposeSlots(slots)
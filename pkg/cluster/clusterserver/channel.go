@@ -104,6 +104,7 @@ func (c *channel) switchConfig(cfg wkdb.ChannelClusterConfig) error {
 		Leader:      cfg.LeaderId,
 		Role:        role,
 		Term:        cfg.Term,
+		AckMode:     replica.AckMode(cfg.AckMode),
 	}
 
 	c.s.channelManager.channelReactor.Step(c.key, replica.Message{
@@ -130,6 +131,12 @@ func (c *channel) leaderId() uint64 {
 	return c.cfg.LeaderId
 }
 
+func (c *channel) config() wkdb.ChannelClusterConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cfg
+}
+
 func (c *channel) term() uint32 {
 	c.mu.Lock()
 	defer c.mu.Unlock()
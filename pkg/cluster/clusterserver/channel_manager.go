@@ -129,6 +129,7 @@ func (c *channelManager) GetConfig(req reactor.ConfigReq) (reactor.ConfigResp, e
 			Term:        clusterCfg.Term,
 			Role:        role,
 			Version:     clusterCfg.ConfVersion,
+			AckMode:     replica.AckMode(clusterCfg.AckMode),
 		},
 	}, nil
 
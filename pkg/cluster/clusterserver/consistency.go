@@ -0,0 +1,529 @@
+package cluster
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/reactor"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// 副本一致性校验
+// 领导把最近的已提交日志按段计算校验和，每个副本对相同的日志段计算校验和后返回给领导对比，
+// 第一个校验和不一致的日志段就是副本和领导开始分歧的位置。
+// 频道的日志就是存储在wkdb里的消息，所以频道日志一致即已应用的状态一致；
+// 槽的已应用状态额外对比槽内频道分布式配置、用户、频道、频道成员和最近会话的校验和（已应用下标相同时才对比）。
+// 这些数据在wkdb里没有按槽存储，计算时需要扫描全表，所以只在校验时计算。
+
+const (
+	consistencyTypeSlot    = "slot"
+	consistencyTypeChannel = "channel"
+)
+
+// checksumLogs 计算日志的校验和（包含日志下标、任期和内容）
+func checksumLogs(logs []replica.Log) uint32 {
+	h := crc32.NewIEEE()
+	buf := make([]byte, 12)
+	for _, log := range logs {
+		binary.BigEndian.PutUint64(buf, log.Index)
+		binary.BigEndian.PutUint32(buf[8:], log.Term)
+		_, _ = h.Write(buf)
+		_, _ = h.Write(log.Data)
+	}
+	return h.Sum32()
+}
+
+// segmentChecksums 按段计算[startIndex,endIndex]之间日志的校验和
+func segmentChecksums(storage IShardLogStorage, shardNo string, startIndex, endIndex, segmentSize uint64) ([]LogSegmentChecksum, error) {
+	if startIndex == 0 {
+		startIndex = 1
+	}
+	if endIndex < startIndex {
+		return nil, nil
+	}
+	if segmentSize == 0 {
+		segmentSize = endIndex - startIndex + 1
+	}
+	segments := make([]LogSegmentChecksum, 0, (endIndex-startIndex)/segmentSize+1)
+	for segStart := startIndex; segStart <= endIndex; segStart += segmentSize {
+		segEnd := min(segStart+segmentSize-1, endIndex)
+		logs, err := storage.Logs(shardNo, segStart, segEnd+1, 0)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, LogSegmentChecksum{
+			StartIndex: segStart,
+			EndIndex:   segEnd,
+			Count:      uint32(len(logs)),
+			Checksum:   checksumLogs(logs),
+		})
+	}
+	return segments, nil
+}
+
+// shardStorage 分区对应的日志存储
+func (s *Server) shardStorage(req *ShardChecksumReq) (IShardLogStorage, string, error) {
+	switch req.ShardType {
+	case ShardTypeSlot:
+		return s.opts.SlotLogStorage, SlotIdToKey(req.SlotId), nil
+	case ShardTypeChannel:
+		return s.opts.MessageLogStorage, wkutil.ChannelToKey(req.ChannelId, req.ChannelType), nil
+	}
+	return nil, "", ErrUnknownShardType
+}
+
+// localShardChecksum 计算本节点副本的日志校验和，只计算本副本已提交的日志
+func (s *Server) localShardChecksum(req *ShardChecksumReq) (*ShardChecksumResp, error) {
+	storage, shardNo, err := s.shardStorage(req)
+	if err != nil {
+		return nil, err
+	}
+	lastIndex, err := storage.LastIndex(shardNo)
+	if err != nil {
+		return nil, err
+	}
+	appliedIndex, err := storage.AppliedIndex(shardNo)
+	if err != nil {
+		return nil, err
+	}
+	resp := &ShardChecksumResp{
+		NodeId:       s.opts.NodeId,
+		LastIndex:    lastIndex,
+		AppliedIndex: appliedIndex,
+	}
+
+	switch req.ShardType {
+	case ShardTypeSlot:
+		resp.CommittedIndex = appliedIndex
+		if st := s.slotManager.get(req.SlotId); st != nil {
+			resp.CommittedIndex = max(appliedIndex, st.rc.CommittedIndex())
+		}
+		resp.StateChecksum, err = s.slotStateChecksum(req.SlotId)
+		if err != nil {
+			return nil, err
+		}
+	case ShardTypeChannel:
+		// 频道没有运行时没有正在同步的日志，存储的日志都认为是已提交的
+		resp.CommittedIndex = lastIndex
+		if handler := s.channelManager.get(req.ChannelId, req.ChannelType); handler != nil {
+			resp.CommittedIndex = handler.(*channel).rc.CommittedIndex()
+		}
+	}
+	resp.CommittedIndex = min(resp.CommittedIndex, lastIndex)
+
+	if req.EndIndex == 0 {
+		return resp, nil
+	}
+	endIndex := min(req.EndIndex, resp.CommittedIndex)
+	resp.Segments, err = segmentChecksums(storage, shardNo, req.StartIndex, endIndex, req.SegmentSize)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// slotStateChecksum 槽内已应用状态的校验和（只包含通过槽日志复制的字段）
+func (s *Server) slotStateChecksum(slotId uint32) (uint32, error) {
+	cfgs, err := s.opts.ChannelClusterStorage.GetWithSlotId(slotId)
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(cfgs, func(i, j int) bool {
+		if cfgs[i].ChannelId == cfgs[j].ChannelId {
+			return cfgs[i].ChannelType < cfgs[j].ChannelType
+		}
+		return cfgs[i].ChannelId < cfgs[j].ChannelId
+	})
+	h := crc32.NewIEEE()
+	for _, cfg := range cfgs {
+		_, _ = h.Write(channelClusterConfigStateBytes(cfg))
+	}
+	if s.opts.DB != nil {
+		// 用户、频道、频道成员和最近会话
+		dataChecksum, err := s.opts.DB.SlotStateChecksum(slotId, s.getSlotId)
+		if err != nil {
+			return 0, err
+		}
+		_, _ = h.Write(binary.BigEndian.AppendUint32(nil, dataChecksum))
+	}
+	return h.Sum32(), nil
+}
+
+func channelClusterConfigStateBytes(cfg wkdb.ChannelClusterConfig) []byte {
+	data := make([]byte, 0, len(cfg.ChannelId)+64+8*(len(cfg.Replicas)+len(cfg.Learners)))
+	data = append(data, cfg.ChannelId...)
+	data = append(data, cfg.ChannelType, uint8(cfg.AckMode), uint8(cfg.Status))
+	data = binary.BigEndian.AppendUint16(data, cfg.ReplicaMaxCount)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(cfg.Replicas)))
+	for _, replicaId := range cfg.Replicas {
+		data = binary.BigEndian.AppendUint64(data, replicaId)
+	}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(cfg.Learners)))
+	for _, learnerId := range cfg.Learners {
+		data = binary.BigEndian.AppendUint64(data, learnerId)
+	}
+	data = binary.BigEndian.AppendUint64(data, cfg.LeaderId)
+	data = binary.BigEndian.AppendUint32(data, cfg.Term)
+	data = binary.BigEndian.AppendUint64(data, cfg.MigrateFrom)
+	data = binary.BigEndian.AppendUint64(data, cfg.MigrateTo)
+	data = binary.BigEndian.AppendUint64(data, cfg.ConfVersion)
+	return data
+}
+
+// VerifySlot 校验槽所有副本和领导是否一致（需要在槽领导节点调用）
+func (s *Server) VerifySlot(ctx context.Context, slotId uint32) (*ConsistencyReport, error) {
+	st := s.clusterEventServer.Slot(slotId)
+	if st == nil {
+		return nil, ErrSlotNotFound
+	}
+	if st.Leader != s.opts.NodeId {
+		return nil, ErrNotIsLeader
+	}
+	report := &ConsistencyReport{
+		Type:     consistencyTypeSlot,
+		SlotId:   slotId,
+		LeaderId: st.Leader,
+	}
+	req := &ShardChecksumReq{
+		ShardType: ShardTypeSlot,
+		SlotId:    slotId,
+	}
+	err := s.verifyShard(ctx, report, req, st.Replicas, st.Learners, false)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// VerifyChannel 校验频道所有副本和领导是否一致（需要在频道领导节点调用）
+// repair为true时，日志不一致的追随者会截断到分歧位置，然后重新从领导同步
+func (s *Server) VerifyChannel(ctx context.Context, channelId string, channelType uint8, repair bool) (*ConsistencyReport, error) {
+	cfg, err := s.loadOnlyChannelClusterConfig(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	if wkdb.IsEmptyChannelClusterConfig(cfg) {
+		return nil, ErrChannelClusterConfigNotFound
+	}
+	if cfg.LeaderId != s.opts.NodeId {
+		return nil, ErrNotIsLeader
+	}
+	report := &ConsistencyReport{
+		Type:        consistencyTypeChannel,
+		SlotId:      s.getSlotId(channelId),
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		LeaderId:    cfg.LeaderId,
+	}
+	req := &ShardChecksumReq{
+		ShardType:   ShardTypeChannel,
+		ChannelId:   channelId,
+		ChannelType: channelType,
+	}
+	err = s.verifyShard(ctx, report, req, cfg.Replicas, cfg.Learners, repair)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *Server) verifyShard(ctx context.Context, report *ConsistencyReport, req *ShardChecksumReq, replicas []uint64, learners []uint64, repair bool) error {
+	kind := trace.ClusterKindChannel
+	if req.ShardType == ShardTypeSlot {
+		kind = trace.ClusterKindSlot
+	}
+	trace.GlobalTrace.Metrics.Cluster().ConsistencyCheckCountAdd(kind, 1)
+
+	leaderInfo, err := s.localShardChecksum(req)
+	if err != nil {
+		return err
+	}
+	// 只校验领导最近的已提交日志
+	endIndex := leaderInfo.CommittedIndex
+	startIndex := uint64(1)
+	if s.opts.ConsistencyCheckMaxLogs > 0 && endIndex > s.opts.ConsistencyCheckMaxLogs {
+		startIndex = endIndex - s.opts.ConsistencyCheckMaxLogs + 1
+	}
+	req.StartIndex = startIndex
+	req.EndIndex = endIndex
+	req.SegmentSize = s.opts.ConsistencyCheckSegmentSize
+	report.StartIndex = startIndex
+	report.EndIndex = endIndex
+	report.CheckedAt = time.Now().Unix()
+	report.Consistent = true
+
+	var leaderResp *ShardChecksumResp
+	if endIndex > 0 {
+		leaderResp, err = s.localShardChecksum(req)
+		if err != nil {
+			return err
+		}
+	} else {
+		leaderResp = leaderInfo
+	}
+	report.Replicas = append(report.Replicas, &ReplicaConsistency{
+		NodeId:       s.opts.NodeId,
+		Role:         "leader",
+		LastIndex:    leaderResp.LastIndex,
+		AppliedIndex: leaderResp.AppliedIndex,
+	})
+
+	replicaIds := make([]uint64, 0, len(replicas)+len(learners))
+	replicaIds = append(replicaIds, replicas...)
+	replicaIds = append(replicaIds, learners...)
+	for _, replicaId := range replicaIds {
+		if replicaId == s.opts.NodeId {
+			continue
+		}
+		result := &ReplicaConsistency{
+			NodeId: replicaId,
+			Role:   "follower",
+		}
+		if wkutil.ArrayContainsUint64(learners, replicaId) {
+			result.Role = "learner"
+		}
+		report.Replicas = append(report.Replicas, result)
+
+		if !s.NodeIsOnline(replicaId) {
+			result.Error = "node offline"
+			continue
+		}
+		if endIndex == 0 {
+			continue
+		}
+		resp, err := s.nodeManager.requestShardChecksum(ctx, replicaId, req)
+		if err != nil {
+			s.Warn("request shard checksum failed", zap.Error(err), zap.Uint64("replicaId", replicaId), zap.String("shard", report.shardName()))
+			result.Error = err.Error()
+			continue
+		}
+		result.LastIndex = resp.LastIndex
+		result.AppliedIndex = resp.AppliedIndex
+
+		divergeIndex, err := s.compareSegments(req, leaderResp.Segments, resp.Segments)
+		if err != nil {
+			return err
+		}
+		if divergeIndex > 0 {
+			result.Diverged = true
+			result.DivergeIndex = divergeIndex
+		}
+		if req.ShardType == ShardTypeSlot && resp.AppliedIndex == leaderResp.AppliedIndex && resp.StateChecksum != leaderResp.StateChecksum {
+			result.StateDiverged = true
+		}
+		if !result.Diverged && !result.StateDiverged {
+			continue
+		}
+
+		report.Consistent = false
+		trace.GlobalTrace.Metrics.Cluster().ConsistencyDivergenceCountAdd(kind, 1)
+		s.Warn("replica diverged from leader", zap.String("shard", report.shardName()), zap.Uint64("replicaId", replicaId), zap.Uint64("divergeIndex", divergeIndex), zap.Bool("stateDiverged", result.StateDiverged))
+
+		if repair && result.Diverged && req.ShardType == ShardTypeChannel {
+			err = s.nodeManager.requestChannelRepair(ctx, replicaId, &ChannelRepairReq{
+				ChannelId:     req.ChannelId,
+				ChannelType:   req.ChannelType,
+				TruncateIndex: divergeIndex,
+			})
+			if err != nil {
+				s.Warn("request channel repair failed", zap.Error(err), zap.String("shard", report.shardName()), zap.Uint64("replicaId", replicaId))
+				result.Error = err.Error()
+				continue
+			}
+			result.Repaired = true
+			trace.GlobalTrace.Metrics.Cluster().ConsistencyRepairCountAdd(kind, 1)
+			s.Info("repair diverged replica", zap.String("shard", report.shardName()), zap.Uint64("replicaId", replicaId), zap.Uint64("truncateIndex", divergeIndex))
+		}
+	}
+	s.saveConsistencyReport(report)
+	return nil
+}
+
+// compareSegments 对比领导和副本的分段校验和，返回第一个不一致的日志段的开始下标，0表示一致
+// 副本最后一段可能因为副本提交的日志较少而不完整，这时领导按副本的范围重新计算
+func (s *Server) compareSegments(req *ShardChecksumReq, leaderSegs []LogSegmentChecksum, replicaSegs []LogSegmentChecksum) (uint64, error) {
+	for i, seg := range replicaSegs {
+		var leaderSeg LogSegmentChecksum
+		if i < len(leaderSegs) && leaderSegs[i].StartIndex == seg.StartIndex && leaderSegs[i].EndIndex == seg.EndIndex {
+			leaderSeg = leaderSegs[i]
+		} else {
+			storage, shardNo, err := s.shardStorage(req)
+			if err != nil {
+				return 0, err
+			}
+			segs, err := segmentChecksums(storage, shardNo, seg.StartIndex, seg.EndIndex, 0)
+			if err != nil {
+				return 0, err
+			}
+			if len(segs) > 0 {
+				leaderSeg = segs[0]
+			}
+		}
+		if leaderSeg.Count != seg.Count || leaderSeg.Checksum != seg.Checksum {
+			return seg.StartIndex, nil
+		}
+	}
+	return 0, nil
+}
+
+// repairChannelReplica 追随者截断从truncateIndex开始的日志，重新加载频道后从领导同步
+func (s *Server) repairChannelReplica(from uint64, req *ChannelRepairReq) error {
+	if req.TruncateIndex == 0 {
+		return errors.New("truncate index can not be 0")
+	}
+	err := s.truncateChannelReplica(from, req)
+	if err != nil {
+		return err
+	}
+	_, err = s.loadOrCreateChannel(s.cancelCtx, req.ChannelId, req.ChannelType)
+	return err
+}
+
+func (s *Server) truncateChannelReplica(from uint64, req *ChannelRepairReq) error {
+	s.channelKeyLock.Lock(req.ChannelId)
+	defer s.channelKeyLock.Unlock(req.ChannelId)
+
+	leaderId := uint64(0)
+	handler := s.channelManager.get(req.ChannelId, req.ChannelType)
+	if handler != nil {
+		leaderId = handler.LeaderId()
+	} else {
+		cfg, err := s.loadOnlyChannelClusterConfig(req.ChannelId, req.ChannelType)
+		if err != nil {
+			return err
+		}
+		leaderId = cfg.LeaderId
+	}
+	if leaderId == s.opts.NodeId {
+		return errors.New("leader replica can not be repaired")
+	}
+	if leaderId != from {
+		return fmt.Errorf("repair request not from leader, leader:%d from:%d", leaderId, from)
+	}
+	if handler != nil {
+		s.channelManager.remove(handler.(*channel))
+	}
+
+	err := truncateShardLogs(s.opts.MessageLogStorage, wkutil.ChannelToKey(req.ChannelId, req.ChannelType), req.TruncateIndex)
+	if err != nil {
+		return err
+	}
+	s.Info("truncate diverged channel replica", zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType), zap.Uint64("truncateIndex", req.TruncateIndex))
+	return nil
+}
+
+// truncateShardLogs 截断从truncateIndex开始的日志，已应用下标超过截断位置时一起回退
+func truncateShardLogs(storage IShardLogStorage, shardNo string, truncateIndex uint64) error {
+	// 截断位置前一条日志的任期，比它大的领导任期记录作废，重新同步时会从领导获取
+	var term uint32
+	if truncateIndex > 1 {
+		logs, err := storage.Logs(shardNo, truncateIndex-1, truncateIndex, 0)
+		if err != nil {
+			return err
+		}
+		if len(logs) > 0 {
+			term = logs[0].Term
+		}
+	}
+	// 先回退已应用下标，有的存储不允许截断已应用的日志
+	appliedIndex, err := storage.AppliedIndex(shardNo)
+	if err != nil {
+		return err
+	}
+	if appliedIndex >= truncateIndex {
+		err = storage.SetAppliedIndex(shardNo, truncateIndex-1)
+		if err != nil {
+			return err
+		}
+	}
+	err = storage.TruncateLogTo(shardNo, truncateIndex)
+	if err != nil {
+		return err
+	}
+	return storage.DeleteLeaderTermStartIndexGreaterThanTerm(shardNo, term)
+}
+
+// saveConsistencyReport 保存最近一次发现不一致的报告，再次校验一致后删除
+func (s *Server) saveConsistencyReport(report *ConsistencyReport) {
+	s.consistencyReportsLock.Lock()
+	defer s.consistencyReportsLock.Unlock()
+	if report.Consistent {
+		delete(s.consistencyReports, report.shardName())
+		return
+	}
+	s.consistencyReports[report.shardName()] = report
+}
+
+// ConsistencyDivergences 本节点作为领导校验发现的不一致的槽和频道
+func (s *Server) ConsistencyDivergences() []*ConsistencyReport {
+	s.consistencyReportsLock.RLock()
+	defer s.consistencyReportsLock.RUnlock()
+	reports := make([]*ConsistencyReport, 0, len(s.consistencyReports))
+	for _, report := range s.consistencyReports {
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].shardName() < reports[j].shardName()
+	})
+	return reports
+}
+
+// consistencyCheckLoop 后台定时校验本节点作为领导的槽和频道
+func (s *Server) consistencyCheckLoop() {
+	tk := time.NewTicker(s.opts.ConsistencyCheckInterval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-tk.C:
+			s.checkConsistency()
+		case <-s.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+func (s *Server) checkConsistency() {
+	for _, st := range s.clusterEventServer.Slots() {
+		if st.Leader != s.opts.NodeId {
+			continue
+		}
+		if _, err := s.VerifySlot(s.cancelCtx, st.Id); err != nil {
+			s.Warn("verify slot failed", zap.Error(err), zap.Uint32("slotId", st.Id))
+		}
+		if s.stopped.Load() {
+			return
+		}
+	}
+
+	channels := make([]*channel, 0)
+	s.channelManager.channelReactor.IteratorHandler(func(h reactor.IHandler) bool {
+		if h.LeaderId() == s.opts.NodeId {
+			channels = append(channels, h.(*channel))
+		}
+		return true
+	})
+	for _, ch := range channels {
+		if _, err := s.VerifyChannel(s.cancelCtx, ch.channelId, ch.channelType, s.opts.ConsistencyAutoRepair); err != nil {
+			s.Warn("verify channel failed", zap.Error(err), zap.String("channelId", ch.channelId), zap.Uint8("channelType", ch.channelType))
+		}
+		if s.stopped.Load() {
+			return
+		}
+	}
+}
+
+func (c *ConsistencyReport) shardName() string {
+	if c.Type == consistencyTypeSlot {
+		return fmt.Sprintf("slot:%d", c.SlotId)
+	}
+	return fmt.Sprintf("channel:%s", wkutil.ChannelToKey(c.ChannelId, c.ChannelType))
+}
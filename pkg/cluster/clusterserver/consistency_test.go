@@ -0,0 +1,150 @@
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestShardStorage(t *testing.T) *PebbleShardLogStorage {
+	storage := NewPebbleShardLogStorage(t.TempDir(), 1)
+	err := storage.Open()
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = storage.Close()
+	})
+	return storage
+}
+
+// appendTestLogs 追加[startIndex,endIndex]的日志，内容由任期和下标决定
+func appendTestLogs(t *testing.T, storage IShardLogStorage, shardNo string, startIndex, endIndex uint64, term uint32) {
+	logs := make([]replica.Log, 0, endIndex-startIndex+1)
+	for i := startIndex; i <= endIndex; i++ {
+		logs = append(logs, replica.Log{
+			Id:    i,
+			Index: i,
+			Term:  term,
+			Data:  []byte(fmt.Sprintf("term%d-log%d", term, i)),
+		})
+	}
+	err := storage.AppendLogs(shardNo, logs)
+	assert.NoError(t, err)
+}
+
+func TestSegmentChecksums(t *testing.T) {
+	storage := newTestShardStorage(t)
+	shardNo := wkutil.ChannelToKey("test", 1)
+	appendTestLogs(t, storage, shardNo, 1, 10, 1)
+
+	segs, err := segmentChecksums(storage, shardNo, 1, 10, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(segs))
+	assert.Equal(t, LogSegmentChecksum{StartIndex: 1, EndIndex: 4, Count: 4, Checksum: segs[0].Checksum}, segs[0])
+	assert.Equal(t, uint64(9), segs[2].StartIndex)
+	assert.Equal(t, uint64(10), segs[2].EndIndex)
+	assert.Equal(t, uint32(2), segs[2].Count)
+
+	logs, err := storage.Logs(shardNo, 5, 9, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, checksumLogs(logs), segs[1].Checksum)
+
+	// 不分段时整个范围是一段
+	segs, err = segmentChecksums(storage, shardNo, 0, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(segs))
+	assert.Equal(t, uint32(10), segs[0].Count)
+
+	segs, err = segmentChecksums(storage, shardNo, 5, 4, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(segs))
+}
+
+func TestCompareSegments(t *testing.T) {
+	leaderStorage := newTestShardStorage(t)
+	followerStorage := newTestShardStorage(t)
+	shardNo := wkutil.ChannelToKey("test", 2)
+	req := &ShardChecksumReq{ShardType: ShardTypeChannel, ChannelId: "test", ChannelType: 2}
+	s := &Server{opts: &Options{MessageLogStorage: leaderStorage}}
+
+	// 领导: 1-6任期1，7-10任期2；追随者: 1-6任期1，7-10是旧领导的任期1
+	appendTestLogs(t, leaderStorage, shardNo, 1, 6, 1)
+	appendTestLogs(t, leaderStorage, shardNo, 7, 10, 2)
+	appendTestLogs(t, followerStorage, shardNo, 1, 10, 1)
+
+	leaderSegs, err := segmentChecksums(leaderStorage, shardNo, 1, 10, 4)
+	assert.NoError(t, err)
+	followerSegs, err := segmentChecksums(followerStorage, shardNo, 1, 10, 4)
+	assert.NoError(t, err)
+	divergeIndex, err := s.compareSegments(req, leaderSegs, followerSegs)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), divergeIndex)
+
+	// 每条日志一段时能定位到具体的日志
+	leaderSegs, err = segmentChecksums(leaderStorage, shardNo, 1, 10, 1)
+	assert.NoError(t, err)
+	followerSegs, err = segmentChecksums(followerStorage, shardNo, 1, 10, 1)
+	assert.NoError(t, err)
+	divergeIndex, err = s.compareSegments(req, leaderSegs, followerSegs)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), divergeIndex)
+
+	// 追随者只提交到6，最后一段不完整，领导按追随者的范围重新计算
+	leaderSegs, err = segmentChecksums(leaderStorage, shardNo, 1, 10, 4)
+	assert.NoError(t, err)
+	followerSegs, err = segmentChecksums(followerStorage, shardNo, 1, 6, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), followerSegs[1].EndIndex)
+	divergeIndex, err = s.compareSegments(req, leaderSegs, followerSegs)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), divergeIndex)
+
+	// 不完整的最后一段里有分歧
+	followerStorage2 := newTestShardStorage(t)
+	appendTestLogs(t, followerStorage2, shardNo, 1, 5, 1)
+	appendTestLogs(t, followerStorage2, shardNo, 6, 6, 2)
+	followerSegs, err = segmentChecksums(followerStorage2, shardNo, 1, 6, 4)
+	assert.NoError(t, err)
+	divergeIndex, err = s.compareSegments(req, leaderSegs, followerSegs)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), divergeIndex)
+}
+
+func TestTruncateShardLogs(t *testing.T) {
+	storage := newTestShardStorage(t)
+	shardNo := wkutil.ChannelToKey("test", 2)
+	appendTestLogs(t, storage, shardNo, 1, 6, 1)
+	appendTestLogs(t, storage, shardNo, 7, 10, 3)
+	assert.NoError(t, storage.SetLeaderTermStartIndex(shardNo, 1, 1))
+	assert.NoError(t, storage.SetLeaderTermStartIndex(shardNo, 3, 7))
+	assert.NoError(t, storage.SetAppliedIndex(shardNo, 9))
+
+	err := truncateShardLogs(storage, shardNo, 7)
+	assert.NoError(t, err)
+
+	lastIndex, err := storage.LastIndex(shardNo)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), lastIndex)
+
+	logs, err := storage.Logs(shardNo, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(logs))
+
+	appliedIndex, err := storage.AppliedIndex(shardNo)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), appliedIndex)
+
+	lastTerm, err := storage.LeaderLastTerm(shardNo)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), lastTerm)
+
+	// 已应用下标在截断位置之前时保持不变
+	assert.NoError(t, storage.SetAppliedIndex(shardNo, 3))
+	err = truncateShardLogs(storage, shardNo, 5)
+	assert.NoError(t, err)
+	appliedIndex, err = storage.AppliedIndex(shardNo)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), appliedIndex)
+}
@@ -0,0 +1,214 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/reactor"
+	"github.com/WuKongIM/WuKongIM/pkg/network"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// StartDrain 开始排空当前节点
+// 节点状态变为排空中后，不会再被分配新的槽领导和频道副本，槽领导自动均衡也会暂停
+func (s *Server) StartDrain() error {
+	if s.draining.Load() {
+		return nil
+	}
+	err := s.clusterEventServer.ProposeNodeStatus(s.opts.NodeId, pb.NodeStatus_NodeStatusDraining)
+	if err != nil {
+		s.Error("propose node draining status failed", zap.Error(err))
+		return err
+	}
+	s.draining.Store(true)
+	s.Info("节点开始排空")
+	return nil
+}
+
+// IsDraining 当前节点是否在排空中
+func (s *Server) IsDraining() bool {
+	return s.draining.Load()
+}
+
+// LeaderCount 当前节点持有的槽领导和频道领导数量
+func (s *Server) LeaderCount() (slotCount int, channelCount int) {
+	for _, slot := range s.clusterEventServer.Slots() {
+		if slot.Leader == s.opts.NodeId {
+			slotCount++
+		}
+	}
+	s.channelManager.channelReactor.IteratorHandler(func(h reactor.IHandler) bool {
+		if h.LeaderId() == s.opts.NodeId {
+			channelCount++
+		}
+		return true
+	})
+	return
+}
+
+// TransferLeaders 将当前节点的槽领导和频道领导迁移到其他节点（迁移是异步完成的，需要通过LeaderCount确认）
+func (s *Server) TransferLeaders() error {
+	if !s.draining.Load() {
+		return errors.New("node is not draining")
+	}
+	err := s.transferSlotLeaders()
+	if err != nil {
+		return err
+	}
+	s.transferChannelLeaders()
+	return nil
+}
+
+// transferSlotLeaders 将当前节点的槽领导迁移到领导最少的在线副本上
+func (s *Server) transferSlotLeaders() error {
+	cfg := s.clusterEventServer.Config()
+
+	leaderCountMap := make(map[uint64]int) // 每个节点的槽领导数量
+	for _, slot := range cfg.Slots {
+		leaderCountMap[slot.Leader]++
+	}
+
+	newSlots := make([]*pb.Slot, 0)
+	for _, slot := range cfg.Slots {
+		if slot.Leader != s.opts.NodeId {
+			continue
+		}
+		if slot.MigrateFrom != 0 || slot.MigrateTo != 0 || slot.Status == pb.SlotStatus_SlotStatusCandidate { // 迁移中或选举中的槽等待下一轮
+			continue
+		}
+		var target uint64
+		for _, replicaId := range slot.Replicas {
+			if !s.drainTargetAllowed(replicaId) {
+				continue
+			}
+			if target == 0 || leaderCountMap[replicaId] < leaderCountMap[target] {
+				target = replicaId
+			}
+		}
+		if target == 0 {
+			s.Warn("no replica can take over slot leader", zap.Uint32("slotId", slot.Id), zap.Uint64s("replicas", slot.Replicas))
+			continue
+		}
+		leaderCountMap[target]++
+		newSlot := slot.Clone()
+		newSlot.MigrateFrom = s.opts.NodeId
+		newSlot.MigrateTo = target
+		newSlots = append(newSlots, newSlot)
+	}
+	if len(newSlots) == 0 {
+		return nil
+	}
+	s.Info("迁移槽领导", zap.Int("slotCount", len(newSlots)))
+	err := s.clusterEventServer.ProposeSlots(newSlots)
+	if err != nil {
+		s.Error("transferSlotLeaders: ProposeSlots failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// transferChannelLeaders 将当前节点的频道领导迁移到其他在线副本上
+func (s *Server) transferChannelLeaders() {
+	channels := make([]*channel, 0)
+	s.channelManager.channelReactor.IteratorHandler(func(h reactor.IHandler) bool {
+		if h.LeaderId() != s.opts.NodeId {
+			return true
+		}
+		if ch, ok := h.(*channel); ok {
+			channels = append(channels, ch)
+		}
+		return true
+	})
+	if len(channels) == 0 {
+		return
+	}
+	s.Info("迁移频道领导", zap.Int("channelCount", len(channels)))
+	for _, ch := range channels {
+		cfg := ch.config()
+		if cfg.MigrateFrom != 0 || cfg.MigrateTo != 0 { // 迁移中
+			continue
+		}
+		var target uint64
+		for _, replicaId := range cfg.Replicas {
+			if s.drainTargetAllowed(replicaId) {
+				target = replicaId
+				break
+			}
+		}
+		if target == 0 {
+			s.Warn("no replica can take over channel leader", zap.String("channelId", ch.channelId), zap.Uint8("channelType", ch.channelType), zap.Uint64s("replicas", cfg.Replicas))
+			continue
+		}
+		err := s.migrateChannelLeader(ch.channelId, ch.channelType, target)
+		if err != nil {
+			s.Warn("migrate channel leader failed", zap.Error(err), zap.String("channelId", ch.channelId), zap.Uint8("channelType", ch.channelType), zap.Uint64("target", target))
+		}
+	}
+}
+
+// migrateChannelLeader 迁移频道领导，频道迁移需要在槽领导上执行
+func (s *Server) migrateChannelLeader(channelId string, channelType uint8, to uint64) error {
+	slotLeaderId, err := s.SlotLeaderIdOfChannel(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	if slotLeaderId == s.opts.NodeId {
+		return s.migrateChannel(channelId, channelType, s.opts.NodeId, to)
+	}
+	node := s.clusterEventServer.Node(slotLeaderId)
+	if node == nil {
+		return fmt.Errorf("slot leader not found, nodeId:%d", slotLeaderId)
+	}
+	fullUrl := fmt.Sprintf("%s%s", node.ApiServerAddr, s.formatPath(fmt.Sprintf("/channels/%s/%d/migrate", channelId, channelType)))
+	resp, err := network.Post(fullUrl, []byte(wkutil.ToJSON(map[string]interface{}{
+		"migrate_from": s.opts.NodeId,
+		"migrate_to":   to,
+	})), nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("migrate channel failed, status:%d body:%s", resp.StatusCode, resp.Body)
+	}
+	return nil
+}
+
+// drainTargetAllowed 节点是否可以接收排空节点的领导
+func (s *Server) drainTargetAllowed(nodeId uint64) bool {
+	if nodeId == s.opts.NodeId {
+		return false
+	}
+	node := s.clusterEventServer.Node(nodeId)
+	return node != nil && node.Online && node.Status == pb.NodeStatus_NodeStatusJoined
+}
+
+// recoverFromDrain 节点排空后重启，需要将节点状态恢复为已加入
+func (s *Server) recoverFromDrain() {
+	tk := time.NewTicker(time.Second)
+	defer tk.Stop()
+	for {
+		select {
+		case <-tk.C:
+			if s.draining.Load() {
+				return
+			}
+			node := s.clusterEventServer.Node(s.opts.NodeId)
+			if node == nil || node.Status != pb.NodeStatus_NodeStatusDraining {
+				return
+			}
+			err := s.clusterEventServer.ProposeNodeStatus(s.opts.NodeId, pb.NodeStatus_NodeStatusJoined)
+			if err != nil {
+				s.Warn("recover node status from draining failed", zap.Error(err))
+				continue
+			}
+			s.Info("节点状态从排空中恢复为已加入")
+			return
+		case <-s.stopper.ShouldStop():
+			return
+		}
+	}
+}
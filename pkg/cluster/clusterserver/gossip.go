@@ -0,0 +1,144 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterevent"
+	"github.com/WuKongIM/WuKongIM/pkg/gossip"
+	"go.uber.org/zap"
+)
+
+func (s *Server) gossipOn() bool {
+	return strings.TrimSpace(s.opts.GossipAddr) != ""
+}
+
+func (s *Server) newGossipServer() *gossip.Server {
+	return gossip.NewServer(s.opts.NodeId, s.opts.GossipAddr,
+		gossip.WithSeed(s.opts.GossipSeeds),
+		gossip.WithAdvertiseAddr(s.opts.GossipAdvertiseAddr),
+		gossip.WithMeta(s.localGossipMeta(nil)),
+		gossip.WithOnNodeEvent(s.onGossipNodeEvent),
+	)
+}
+
+func (s *Server) localGossipMeta(cfg *pb.Config) gossip.NodeMeta {
+	clusterAddr := s.opts.ServerAddr
+	if clusterAddr == "" {
+		clusterAddr = s.opts.Addr
+	}
+	meta := gossip.NodeMeta{
+		ClusterAddr:   strings.ReplaceAll(clusterAddr, "tcp://", ""),
+		ApiServerAddr: s.opts.ApiServerAddr,
+		Role:          uint32(s.opts.Role),
+		Load:          s.gossipLoad.Load(),
+	}
+	if cfg != nil {
+		for _, node := range cfg.Nodes {
+			if node.Id == s.opts.NodeId {
+				meta.Joined = true
+				break
+			}
+		}
+	}
+	return meta
+}
+
+// discoverSeedByGossip 通过gossip发现已加入集群的节点作为种子节点
+// 只有开启了GossipBootstrap的节点在GossipJoinTimeout内没有发现已加入集群的节点时才会自己初始化集群，
+// 其他节点一直等待，避免同时启动的节点各自初始化出多个集群
+func (s *Server) discoverSeedByGossip() (string, error) {
+	if len(s.opts.GossipSeeds) == 0 {
+		s.Info("no gossip seeds, bootstrap cluster by self")
+		return "", nil
+	}
+	deadline := time.Now().Add(s.opts.GossipJoinTimeout)
+	for {
+		if err := s.gossipServer.Join(); err != nil {
+			s.Warn("join gossip failed", zap.Error(err))
+		} else {
+			for _, m := range s.gossipServer.Members() {
+				if m.NodeID == s.opts.NodeId || m.Meta == nil || !m.Meta.Joined {
+					continue
+				}
+				seed := fmt.Sprintf("%d@%s", m.NodeID, m.Meta.ClusterAddr)
+				s.Info("discover seed by gossip", zap.String("seed", seed))
+				return seed, nil
+			}
+		}
+		if s.opts.GossipBootstrap && time.Now().After(deadline) {
+			s.Info("no joined node found by gossip, bootstrap cluster by self")
+			return "", nil
+		}
+		select {
+		case <-time.After(time.Millisecond * 500):
+		case <-s.stopper.ShouldStop():
+			return "", errors.New("cluster server stopped")
+		}
+	}
+}
+
+// setSeed 设置通过gossip发现的种子节点（需要在集群事件服务启动前调用）
+func (s *Server) setSeed(seed string) error {
+	seedNodeId, seedAddr, err := seedNode(seed)
+	if err != nil {
+		return err
+	}
+	s.opts.Seed = seed
+	s.opts.InitNodes[seedNodeId] = seedAddr
+	s.clusterEventServer.SetSeed(seed)
+	return nil
+}
+
+// updateGossipMeta 集群配置改变后更新gossip元数据
+func (s *Server) updateGossipMeta(cfg *pb.Config) {
+	if s.gossipServer == nil {
+		return
+	}
+	meta := s.localGossipMeta(cfg)
+	old := s.gossipServer.Meta()
+	if old.Joined == meta.Joined && old.ApiServerAddr == meta.ApiServerAddr && old.ClusterAddr == meta.ClusterAddr && old.Load == meta.Load {
+		return
+	}
+	go s.gossipServer.SetMeta(meta) // SetMeta会等待广播完成，这里不阻塞配置处理
+}
+
+// SetLoad 设置当前节点负载，通过gossip传播给其他节点
+func (s *Server) SetLoad(load uint64) {
+	s.gossipLoad.Store(load)
+	if !s.gossipReady.Load() {
+		return
+	}
+	s.updateGossipMeta(s.clusterEventServer.Config())
+}
+
+// GossipMembers gossip成员，没有开启gossip返回nil
+func (s *Server) GossipMembers() []gossip.Member {
+	if s.gossipServer == nil {
+		return nil
+	}
+	return s.gossipServer.Members()
+}
+
+func (s *Server) onGossipNodeEvent(event gossip.NodeEvent) {
+	if !s.gossipReady.Load() || s.stopped.Load() || event.NodeID == 0 || event.NodeID == s.opts.NodeId {
+		return
+	}
+
+	// 修复节点的通讯地址（只处理集群配置内的节点）
+	if event.Meta != nil && event.Meta.ClusterAddr != "" && s.clusterEventServer.Node(event.NodeID) != nil {
+		s.addOrUpdateNode(event.NodeID, event.Meta.ClusterAddr)
+	}
+
+	switch event.EventType {
+	case gossip.NodeEventJoin, gossip.NodeEventAlive:
+		s.clusterEventServer.NodeLivenessChange(event.NodeID, clusterevent.NodeLivenessAlive)
+	case gossip.NodeEventSuspect:
+		s.clusterEventServer.NodeLivenessChange(event.NodeID, clusterevent.NodeLivenessSuspect)
+	case gossip.NodeEventLeave:
+		s.clusterEventServer.NodeLivenessChange(event.NodeID, clusterevent.NodeLivenessDead)
+	}
+}
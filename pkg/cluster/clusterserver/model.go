@@ -47,6 +47,7 @@ var (
 	ErrSlotLeaderNotFound           = errors.New("slot leader not found")
 	ErrEmptyRequest                 = errors.New("empty request")
 	ErrChannelClusterConfigNotFound = errors.New("channel cluster config not found")
+	ErrUnknownShardType             = errors.New("unknown shard type")
 )
 
 const (
@@ -649,6 +650,9 @@ type ClusterJoinReq struct {
 	NodeId     uint64
 	ServerAddr string
 	Role       pb.NodeRole
+	Zone       string            // 节点所在可用区
+	Rack       string            // 节点所在机架
+	Labels     map[string]string // 节点标签
 }
 
 func (c *ClusterJoinReq) Marshal() ([]byte, error) {
@@ -657,6 +661,13 @@ func (c *ClusterJoinReq) Marshal() ([]byte, error) {
 	enc.WriteUint64(c.NodeId)
 	enc.WriteString(c.ServerAddr)
 	enc.WriteUint32(uint32(c.Role))
+	enc.WriteString(c.Zone)
+	enc.WriteString(c.Rack)
+	enc.WriteUint16(uint16(len(c.Labels)))
+	for k, v := range c.Labels {
+		enc.WriteString(k)
+		enc.WriteString(v)
+	}
 	return enc.Bytes(), nil
 
 }
@@ -675,6 +686,33 @@ func (c *ClusterJoinReq) Unmarshal(data []byte) error {
 		return err
 	}
 	c.Role = pb.NodeRole(role)
+
+	if dec.Len() == 0 { // 旧版本节点没有可用区等信息
+		return nil
+	}
+	if c.Zone, err = dec.String(); err != nil {
+		return err
+	}
+	if c.Rack, err = dec.String(); err != nil {
+		return err
+	}
+	var labelsLen uint16
+	if labelsLen, err = dec.Uint16(); err != nil {
+		return err
+	}
+	if labelsLen > 0 {
+		c.Labels = make(map[string]string, labelsLen)
+		for i := uint16(0); i < labelsLen; i++ {
+			var k, v string
+			if k, err = dec.String(); err != nil {
+				return err
+			}
+			if v, err = dec.String(); err != nil {
+				return err
+			}
+			c.Labels[k] = v
+		}
+	}
 	return nil
 }
 
@@ -816,6 +854,23 @@ type NodeInfo struct {
 	ServerAddr string
 }
 
+// ChannelReadState 本节点频道副本的读状态，用于判断能否从副本读取消息
+type ChannelReadState struct {
+	LeaderId             uint64    // 频道领导ID
+	IsLeader             bool      // 本节点是否是频道领导
+	LeaderCommittedIndex uint64    // 最后一次从领导收到的已提交下标
+	CommittedIndex       uint64    // 本节点已提交的日志下标
+	LastLeaderContact    time.Time // 最后一次收到领导消息的时间
+}
+
+// LagIndex 本节点落后领导已提交日志的条数
+func (r ChannelReadState) LagIndex() uint64 {
+	if r.CommittedIndex >= r.LeaderCommittedIndex {
+		return 0
+	}
+	return r.LeaderCommittedIndex - r.CommittedIndex
+}
+
 type ChannelClusterStorage interface {
 	// 保存分布式配置
 	Save(clusterCfg wkdb.ChannelClusterConfig) error
@@ -1070,27 +1125,30 @@ func newMessageResp(m wkdb.Message) *messageResp {
 }
 
 type NodeConfig struct {
-	Id              uint64         `json:"id"`                          // 节点ID
-	IsLeader        int            `json:"is_leader,omitempty"`         // 是否是leader
-	Role            pb.NodeRole    `json:"role"`                        // 节点角色
-	ClusterAddr     string         `json:"cluster_addr"`                // 集群地址
-	ApiServerAddr   string         `json:"api_server_addr,omitempty"`   // API服务地址
-	Online          int            `json:"online,omitempty"`            // 是否在线
-	OfflineCount    int            `json:"offline_count,omitempty"`     // 下线次数
-	LastOffline     string         `json:"last_offline,omitempty"`      // 最后一次下线时间
-	AllowVote       int            `json:"allow_vote"`                  // 是否允许投票
-	SlotCount       int            `json:"slot_count,omitempty"`        // 槽位数量
-	Term            uint32         `json:"term,omitempty"`              // 任期
-	SlotLeaderCount int            `json:"slot_leader_count,omitempty"` // 槽位领导者数量
-	ExportCount     int            `json:"export_count,omitempty"`      // 迁出槽位数量
-	Exports         []*SlotMigrate `json:"exports,omitempty"`           // 迁移槽位
-	ImportCount     int            `json:"import_count,omitempty"`      // 迁入槽位数量
-	Imports         []*SlotMigrate `json:"imports,omitempty"`           // 迁入槽位
-	Uptime          string         `json:"uptime,omitempty"`            // 运行时间
-	AppVersion      string         `json:"app_version,omitempty"`       // 应用版本
-	ConfigVersion   uint64         `json:"config_version,omitempty"`    // 配置版本
-	Status          pb.NodeStatus  `json:"status,omitempty"`            // 状态
-	StatusFormat    string         `json:"status_format,omitempty"`     // 状态格式化
+	Id              uint64            `json:"id"`                          // 节点ID
+	IsLeader        int               `json:"is_leader,omitempty"`         // 是否是leader
+	Zone            string            `json:"zone,omitempty"`              // 可用区
+	Rack            string            `json:"rack,omitempty"`              // 机架
+	Labels          map[string]string `json:"labels,omitempty"`            // 标签
+	Role            pb.NodeRole       `json:"role"`                        // 节点角色
+	ClusterAddr     string            `json:"cluster_addr"`                // 集群地址
+	ApiServerAddr   string            `json:"api_server_addr,omitempty"`   // API服务地址
+	Online          int               `json:"online,omitempty"`            // 是否在线
+	OfflineCount    int               `json:"offline_count,omitempty"`     // 下线次数
+	LastOffline     string            `json:"last_offline,omitempty"`      // 最后一次下线时间
+	AllowVote       int               `json:"allow_vote"`                  // 是否允许投票
+	SlotCount       int               `json:"slot_count,omitempty"`        // 槽位数量
+	Term            uint32            `json:"term,omitempty"`              // 任期
+	SlotLeaderCount int               `json:"slot_leader_count,omitempty"` // 槽位领导者数量
+	ExportCount     int               `json:"export_count,omitempty"`      // 迁出槽位数量
+	Exports         []*SlotMigrate    `json:"exports,omitempty"`           // 迁移槽位
+	ImportCount     int               `json:"import_count,omitempty"`      // 迁入槽位数量
+	Imports         []*SlotMigrate    `json:"imports,omitempty"`           // 迁入槽位
+	Uptime          string            `json:"uptime,omitempty"`            // 运行时间
+	AppVersion      string            `json:"app_version,omitempty"`       // 应用版本
+	ConfigVersion   uint64            `json:"config_version,omitempty"`    // 配置版本
+	Status          pb.NodeStatus     `json:"status,omitempty"`            // 状态
+	StatusFormat    string            `json:"status_format,omitempty"`     // 状态格式化
 }
 
 func NewNodeConfigFromNode(n *pb.Node) *NodeConfig {
@@ -1107,12 +1165,17 @@ func NewNodeConfigFromNode(n *pb.Node) *NodeConfig {
 		status = "加入中"
 	} else if n.Status == pb.NodeStatus_NodeStatusWillJoin {
 		status = "将加入"
+	} else if n.Status == pb.NodeStatus_NodeStatusDraining {
+		status = "排空中"
 	}
 	return &NodeConfig{
 		Id:            n.Id,
 		Role:          n.Role,
 		ClusterAddr:   n.ClusterAddr,
 		ApiServerAddr: n.ApiServerAddr,
+		Zone:          n.Zone,
+		Rack:          n.Rack,
+		Labels:        n.Labels,
 		Online:        wkutil.BoolToInt(n.Online),
 		OfflineCount:  int(n.OfflineCount),
 		LastOffline:   lastOffline,
@@ -1468,3 +1531,213 @@ type LogRespTotal struct {
 	More    int        `json:"more"`    // 是否有更多
 	Logs    []*LogResp `json:"logs"`    // 日志信息
 }
+
+// 副本分布不满足可用区约束的槽或频道
+type placementViolationResp struct {
+	Type            string   `json:"type"`                   // slot或channel
+	SlotId          uint32   `json:"slot_id"`                // 槽id（频道为频道所在的槽）
+	ChannelId       string   `json:"channel_id,omitempty"`   // 频道id
+	ChannelType     uint8    `json:"channel_type,omitempty"` // 频道类型
+	Replicas        []uint64 `json:"replicas"`               // 副本节点
+	Zones           []string `json:"zones"`                  // 副本所在的可用区
+	ExpectZoneCount int      `json:"expect_zone_count"`      // 副本至少应该分布的可用区数量
+}
+
+type placementViolationRespTotal struct {
+	Total int                       `json:"total"` // 总数
+	Data  []*placementViolationResp `json:"data"`
+}
+
+// ShardChecksumReq 获取副本日志校验和的请求
+type ShardChecksumReq struct {
+	ShardType   ShardType // 分区类型（槽或频道）
+	SlotId      uint32    // 槽id（槽分区有效）
+	ChannelId   string    // 频道id（频道分区有效）
+	ChannelType uint8     // 频道类型（频道分区有效）
+	StartIndex  uint64    // 开始日志下标（包含）
+	EndIndex    uint64    // 结束日志下标（包含），0表示只返回日志信息不计算校验和
+	SegmentSize uint64    // 每段日志的数量
+}
+
+func (s *ShardChecksumReq) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint8(uint8(s.ShardType))
+	enc.WriteUint32(s.SlotId)
+	enc.WriteString(s.ChannelId)
+	enc.WriteUint8(s.ChannelType)
+	enc.WriteUint64(s.StartIndex)
+	enc.WriteUint64(s.EndIndex)
+	enc.WriteUint64(s.SegmentSize)
+	return enc.Bytes(), nil
+}
+
+func (s *ShardChecksumReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	var shardType uint8
+	if shardType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	s.ShardType = ShardType(shardType)
+	if s.SlotId, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if s.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if s.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if s.StartIndex, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if s.EndIndex, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if s.SegmentSize, err = dec.Uint64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// LogSegmentChecksum 一段日志的校验和
+type LogSegmentChecksum struct {
+	StartIndex uint64 `json:"start_index"` // 开始日志下标（包含）
+	EndIndex   uint64 `json:"end_index"`   // 结束日志下标（包含）
+	Count      uint32 `json:"count"`       // 实际读取到的日志数量
+	Checksum   uint32 `json:"checksum"`    // 校验和
+}
+
+// ShardChecksumResp 副本日志校验和
+type ShardChecksumResp struct {
+	NodeId         uint64
+	LastIndex      uint64               // 最后一条日志下标
+	CommittedIndex uint64               // 已提交的日志下标（只校验已提交的日志）
+	AppliedIndex   uint64               // 已应用的日志下标
+	StateChecksum  uint32               // 已应用状态的校验和（槽分区为槽内频道分布式配置的校验和）
+	Segments       []LogSegmentChecksum // 分段日志校验和
+}
+
+func (s *ShardChecksumResp) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(s.NodeId)
+	enc.WriteUint64(s.LastIndex)
+	enc.WriteUint64(s.CommittedIndex)
+	enc.WriteUint64(s.AppliedIndex)
+	enc.WriteUint32(s.StateChecksum)
+	enc.WriteUint32(uint32(len(s.Segments)))
+	for _, seg := range s.Segments {
+		enc.WriteUint64(seg.StartIndex)
+		enc.WriteUint64(seg.EndIndex)
+		enc.WriteUint32(seg.Count)
+		enc.WriteUint32(seg.Checksum)
+	}
+	return enc.Bytes(), nil
+}
+
+func (s *ShardChecksumResp) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if s.NodeId, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if s.LastIndex, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if s.CommittedIndex, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if s.AppliedIndex, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if s.StateChecksum, err = dec.Uint32(); err != nil {
+		return err
+	}
+	var segLen uint32
+	if segLen, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if segLen > 0 {
+		s.Segments = make([]LogSegmentChecksum, segLen)
+		for i := uint32(0); i < segLen; i++ {
+			if s.Segments[i].StartIndex, err = dec.Uint64(); err != nil {
+				return err
+			}
+			if s.Segments[i].EndIndex, err = dec.Uint64(); err != nil {
+				return err
+			}
+			if s.Segments[i].Count, err = dec.Uint32(); err != nil {
+				return err
+			}
+			if s.Segments[i].Checksum, err = dec.Uint32(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ChannelRepairReq 修复频道追随者副本的请求（截断到指定下标后重新从领导同步）
+type ChannelRepairReq struct {
+	ChannelId     string
+	ChannelType   uint8
+	TruncateIndex uint64 // 从这个下标开始截断（不保留该下标的日志）
+}
+
+func (c *ChannelRepairReq) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(c.ChannelId)
+	enc.WriteUint8(c.ChannelType)
+	enc.WriteUint64(c.TruncateIndex)
+	return enc.Bytes(), nil
+}
+
+func (c *ChannelRepairReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if c.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if c.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if c.TruncateIndex, err = dec.Uint64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReplicaConsistency 副本的一致性校验结果
+type ReplicaConsistency struct {
+	NodeId        uint64 `json:"node_id"`                 // 副本节点id
+	Role          string `json:"role"`                    // 副本角色 leader, follower, learner
+	LastIndex     uint64 `json:"last_index"`              // 最后一条日志下标
+	AppliedIndex  uint64 `json:"applied_index"`           // 已应用的日志下标
+	Diverged      bool   `json:"diverged"`                // 日志是否与领导不一致
+	DivergeIndex  uint64 `json:"diverge_index,omitempty"` // 第一个不一致的日志段的开始下标
+	StateDiverged bool   `json:"state_diverged"`          // 已应用状态是否与领导不一致
+	Repaired      bool   `json:"repaired"`                // 是否已修复
+	Error         string `json:"error,omitempty"`         // 校验失败的原因
+}
+
+// ConsistencyReport 槽或频道的副本一致性校验报告
+type ConsistencyReport struct {
+	Type        string                `json:"type"`                   // slot或channel
+	SlotId      uint32                `json:"slot_id,omitempty"`      // 槽id
+	ChannelId   string                `json:"channel_id,omitempty"`   // 频道id
+	ChannelType uint8                 `json:"channel_type,omitempty"` // 频道类型
+	LeaderId    uint64                `json:"leader_id"`              // 领导节点
+	StartIndex  uint64                `json:"start_index"`            // 校验的开始日志下标
+	EndIndex    uint64                `json:"end_index"`              // 校验的结束日志下标
+	Consistent  bool                  `json:"consistent"`             // 所有副本是否一致
+	Replicas    []*ReplicaConsistency `json:"replicas"`               // 各副本的校验结果
+	CheckedAt   int64                 `json:"checked_at"`             // 校验时间（秒）
+}
+
+type consistencyReportTotal struct {
+	Total int                  `json:"total"` // 总数
+	Data  []*ConsistencyReport `json:"data"`
+}
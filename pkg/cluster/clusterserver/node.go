@@ -268,6 +268,44 @@ func (n *node) requestClusterJoin(ctx context.Context, req *ClusterJoinReq) (*Cl
 	return clusterJoinResp, err
 }
 
+func (n *node) requestShardChecksum(ctx context.Context, req *ShardChecksumReq) (*ShardChecksumResp, error) {
+	data, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := n.client.RequestWithContext(ctx, "/shard/checksum", data)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		if len(resp.Body) > 0 {
+			return nil, errors.New(string(resp.Body))
+		}
+		return nil, fmt.Errorf("requestShardChecksum is failed, status:%d", resp.Status)
+	}
+	checksumResp := &ShardChecksumResp{}
+	err = checksumResp.Unmarshal(resp.Body)
+	return checksumResp, err
+}
+
+func (n *node) requestChannelRepair(ctx context.Context, req *ChannelRepairReq) error {
+	data, err := req.Marshal()
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.RequestWithContext(ctx, "/channel/repair", data)
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.Status_OK {
+		if len(resp.Body) > 0 {
+			return errors.New(string(resp.Body))
+		}
+		return fmt.Errorf("requestChannelRepair is failed, status:%d", resp.Status)
+	}
+	return nil
+}
+
 type sendQueue struct {
 	ch    chan *proto.Message
 	rl    *RateLimiter
@@ -84,3 +84,23 @@ func (n *nodeManager) requestClusterJoin(to uint64, req *ClusterJoinReq) (*Clust
 	defer cancel()
 	return node.requestClusterJoin(timeoutCtx, req)
 }
+
+func (n *nodeManager) requestShardChecksum(ctx context.Context, to uint64, req *ShardChecksumReq) (*ShardChecksumResp, error) {
+	node := n.node(to)
+	if node == nil {
+		return nil, fmt.Errorf("node[%d] not found", to)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, n.opts.ReqTimeout)
+	defer cancel()
+	return node.requestShardChecksum(timeoutCtx, req)
+}
+
+func (n *nodeManager) requestChannelRepair(ctx context.Context, to uint64, req *ChannelRepairReq) error {
+	node := n.node(to)
+	if node == nil {
+		return fmt.Errorf("node[%d] not found", to)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, n.opts.ReqTimeout)
+	defer cancel()
+	return node.requestChannelRepair(timeoutCtx, req)
+}
@@ -19,6 +19,12 @@ type Options struct {
 	ServerAddr    string      // 分布式可访问地址
 	ApiServerAddr string      // api服务地址
 	AppVersion    string      // 当前应用版本
+	// Zone 节点所在可用区，槽和频道的副本会尽量分散在不同的可用区
+	Zone string
+	// Rack 节点所在机架，同一可用区内的副本会尽量分散在不同的机架
+	Rack string
+	// Labels 节点标签
+	Labels map[string]string
 	// InitNodes 集群初始节点，key为节点id，value为节点内网通信地址
 	InitNodes map[uint64]string
 	// SlotCount 槽位数量
@@ -94,6 +100,26 @@ type Options struct {
 	PongMaxTick int // 节点超过多少tick没有回应心跳就认为是掉线
 
 	Auth auth.AuthConfig
+
+	// GossipAddr gossip监听地址 格式：ip:port，为空则不开启gossip
+	GossipAddr string
+	// GossipAdvertiseAddr gossip可访问地址 格式：ip:port
+	GossipAdvertiseAddr string
+	// GossipSeeds gossip种子节点地址 格式：ip:port，开启后无需配置Seed和InitNodes也能发现集群
+	GossipSeeds []string
+	// GossipBootstrap 通过gossip没有发现已加入集群的节点时，由本节点初始化集群（集群内只需一个节点开启）
+	GossipBootstrap bool
+	// GossipJoinTimeout 开启GossipBootstrap的节点等待发现已有集群的时间，超时后由本节点初始化集群
+	GossipJoinTimeout time.Duration
+
+	// ConsistencyCheckInterval 后台校验副本一致性的间隔，0表示不开启后台校验
+	ConsistencyCheckInterval time.Duration
+	// ConsistencyCheckMaxLogs 每次校验最近多少条日志，0表示校验全部日志
+	ConsistencyCheckMaxLogs uint64
+	// ConsistencyCheckSegmentSize 日志按多少条一段计算校验和
+	ConsistencyCheckSegmentSize uint64
+	// ConsistencyAutoRepair 发现频道追随者与领导不一致时是否自动修复（截断后重新从领导同步）
+	ConsistencyAutoRepair bool
 }
 
 func NewOptions(opt ...Option) *Options {
@@ -118,6 +144,9 @@ func NewOptions(opt ...Option) *Options {
 		LearnerMinLogGap:           100,
 		PageSize:                   20,
 
+		ConsistencyCheckMaxLogs:     10000,
+		ConsistencyCheckSegmentSize: 1000,
+
 		TickInterval:          150 * time.Millisecond,
 		HeartbeatIntervalTick: 1,
 		ElectionIntervalTick:  10,
@@ -126,6 +155,7 @@ func NewOptions(opt ...Option) *Options {
 		SlotReactorSubCount:    128,
 		PongMaxTick:            30,
 		SlotDbShardNum:         8,
+		GossipJoinTimeout:      10 * time.Second,
 	}
 	for _, o := range opt {
 		o(opts)
@@ -395,3 +425,75 @@ func WithAuth(auth auth.AuthConfig) Option {
 		o.Auth = auth
 	}
 }
+
+func WithGossipAddr(addr string) Option {
+	return func(o *Options) {
+		o.GossipAddr = addr
+	}
+}
+
+func WithGossipAdvertiseAddr(addr string) Option {
+	return func(o *Options) {
+		o.GossipAdvertiseAddr = addr
+	}
+}
+
+func WithGossipSeeds(seeds []string) Option {
+	return func(o *Options) {
+		o.GossipSeeds = seeds
+	}
+}
+
+func WithGossipBootstrap(bootstrap bool) Option {
+	return func(o *Options) {
+		o.GossipBootstrap = bootstrap
+	}
+}
+
+func WithGossipJoinTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.GossipJoinTimeout = timeout
+	}
+}
+
+func WithZone(zone string) Option {
+	return func(o *Options) {
+		o.Zone = zone
+	}
+}
+
+func WithRack(rack string) Option {
+	return func(o *Options) {
+		o.Rack = rack
+	}
+}
+
+func WithLabels(labels map[string]string) Option {
+	return func(o *Options) {
+		o.Labels = labels
+	}
+}
+
+func WithConsistencyCheckInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.ConsistencyCheckInterval = interval
+	}
+}
+
+func WithConsistencyCheckMaxLogs(maxLogs uint64) Option {
+	return func(o *Options) {
+		o.ConsistencyCheckMaxLogs = maxLogs
+	}
+}
+
+func WithConsistencyCheckSegmentSize(size uint64) Option {
+	return func(o *Options) {
+		o.ConsistencyCheckSegmentSize = size
+	}
+}
+
+func WithConsistencyAutoRepair(autoRepair bool) Option {
+	return func(o *Options) {
+		o.ConsistencyAutoRepair = autoRepair
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterevent"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/reactor"
+	"github.com/WuKongIM/WuKongIM/pkg/gossip"
 	"github.com/WuKongIM/WuKongIM/pkg/keylock"
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
@@ -60,6 +61,15 @@ type Server struct {
 	stopper *syncutil.Stopper
 
 	clusterCfgCache *lru.Cache[string, wkdb.ChannelClusterConfig]
+
+	gossipServer   *gossip.Server // gossip服务（用于节点发现和故障检测）
+	gossipReady    atomic.Bool    // gossip事件是否可以处理
+	gossipLoad     atomic.Uint64  // 当前节点负载
+	gossipDiscover bool           // 是否需要通过gossip发现集群
+	draining       atomic.Bool    // 当前节点是否在排空中
+
+	consistencyReports     map[string]*ConsistencyReport // 校验发现不一致的槽和频道
+	consistencyReportsLock sync.RWMutex
 }
 
 func New(opts *Options) *Server {
@@ -71,6 +81,8 @@ func New(opts *Options) *Server {
 		channelKeyLock: keylock.NewKeyLock(),
 		channelLoadMap: make(map[string]struct{}),
 		stopper:        syncutil.NewStopper(),
+
+		consistencyReports: make(map[string]*ConsistencyReport),
 	}
 	var err error
 	s.clusterCfgCache, err = lru.New[string, wkdb.ChannelClusterConfig](1000)
@@ -94,8 +106,18 @@ func New(opts *Options) *Server {
 
 	cfgDir := path.Join(opts.DataDir, "config")
 
+	if s.gossipOn() {
+		s.gossipServer = s.newGossipServer()
+		// 没有配置种子节点和初始节点，启动时通过gossip发现集群
+		s.gossipDiscover = strings.TrimSpace(s.opts.Seed) == "" && len(opts.InitNodes) == 0
+	}
+
 	initNodes := opts.InitNodes
 	if len(initNodes) == 0 {
+		if initNodes == nil {
+			initNodes = make(map[uint64]string)
+			opts.InitNodes = initNodes
+		}
 		if strings.TrimSpace(s.opts.Seed) != "" {
 			seedNodeID, seedAddr, err := seedNode(s.opts.Seed)
 			if err != nil {
@@ -124,6 +146,9 @@ func New(opts *Options) *Server {
 		clusterevent.WithSend(s.onSend),
 		clusterevent.WithConfigDir(cfgDir),
 		clusterevent.WithApiServerAddr(opts.ApiServerAddr),
+		clusterevent.WithZone(opts.Zone),
+		clusterevent.WithRack(opts.Rack),
+		clusterevent.WithLabels(opts.Labels),
 		clusterevent.WithCluster(s),
 		clusterevent.WithElectionIntervalTick(opts.ElectionIntervalTick),
 		clusterevent.WithHeartbeatIntervalTick(opts.HeartbeatIntervalTick),
@@ -171,6 +196,19 @@ func (s *Server) Start() error {
 
 	s.channelKeyLock.StartCleanLoop()
 
+	// 还没有加入过集群，通过gossip发现集群的种子节点
+	if s.gossipDiscover && len(s.clusterEventServer.Nodes()) == 0 {
+		seed, err := s.discoverSeedByGossip()
+		if err != nil {
+			return err
+		}
+		if seed != "" {
+			if err = s.setSeed(seed); err != nil {
+				return err
+			}
+		}
+	}
+
 	nodes := s.clusterEventServer.Nodes()
 	if len(nodes) > 0 {
 		for _, node := range nodes {
@@ -227,12 +265,29 @@ func (s *Server) Start() error {
 		return err
 	}
 
+	// gossip
+	if s.gossipServer != nil {
+		err = s.gossipServer.Start()
+		if err != nil {
+			return err
+		}
+		s.gossipReady.Store(true)
+	}
+
 	// 如果有新加入的节点 则执行加入逻辑
 	if s.needJoin() { // 需要加入集群
 		// s.clusterEventServer.SetIsPrepared(false) // 先将节点集群准备状态设置为false，等待加入集群后再设置为true
 		s.stopper.RunWorker(s.joinLoop)
 	}
 
+	// 排空后重启的节点恢复为已加入状态
+	s.stopper.RunWorker(s.recoverFromDrain)
+
+	// 后台校验副本一致性
+	if s.opts.ConsistencyCheckInterval > 0 {
+		s.stopper.RunWorker(s.consistencyCheckLoop)
+	}
+
 	return nil
 }
 
@@ -241,6 +296,9 @@ func (s *Server) Stop() {
 	s.stopped.Store(true)
 	s.cancelFnc()
 	s.stopper.Stop()
+	if s.gossipServer != nil {
+		s.gossipServer.Stop()
+	}
 	s.nodeManager.stop()
 	s.channelElectionManager.stop()
 	s.netServer.Stop()
@@ -521,6 +579,9 @@ func (s *Server) joinLoop() {
 		NodeId:     s.opts.NodeId,
 		ServerAddr: s.opts.ServerAddr,
 		Role:       s.opts.Role,
+		Zone:       s.opts.Zone,
+		Rack:       s.opts.Rack,
+		Labels:     s.opts.Labels,
 	}
 	for {
 		select {
@@ -14,6 +14,7 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/auth"
 	"github.com/WuKongIM/WuKongIM/pkg/auth/resource"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
 	"github.com/WuKongIM/WuKongIM/pkg/network"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
@@ -54,7 +55,13 @@ func (s *Server) ServerAPI(route *wkhttp.WKHttp, prefix string) {
 	route.GET(s.formatPath("/channels/:channel_id/:channel_type/replicas"), s.channelReplicas)         // 获取频道副本信息
 	route.GET(s.formatPath("/channels/:channel_id/:channel_type/localReplica"), s.channelLocalReplica) // 获取频道在本节点的副本信息
 
-	route.GET(s.formatPath("/logs"), s.clusterLogs) // 获取节点日志
+	route.GET(s.formatPath("/logs"), s.clusterLogs)                            // 获取节点日志
+	route.GET(s.formatPath("/placement/violations"), s.placementViolationsGet) // 获取副本分布不满足可用区约束的槽和频道
+
+	route.GET(s.formatPath("/slots/:id/consistency"), s.slotConsistencyGet)                             // 校验槽副本一致性
+	route.GET(s.formatPath("/channels/:channel_id/:channel_type/consistency"), s.channelConsistencyGet) // 校验频道副本一致性
+	route.POST(s.formatPath("/channels/:channel_id/:channel_type/repair"), s.channelRepair)             // 校验并修复不一致的频道副本
+	route.GET(s.formatPath("/consistency/divergences"), s.consistencyDivergencesGet)                    // 获取后台校验发现不一致的槽和频道
 
 }
 
@@ -1755,37 +1762,44 @@ func (s *Server) channelMigrate(c *wkhttp.Context) {
 		return
 	}
 
+	err = s.migrateChannel(channelId, channelType, req.MigrateFrom, req.MigrateTo)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+
+}
+
+// migrateChannel 迁移频道（需要在频道所属槽的领导节点上执行）
+func (s *Server) migrateChannel(channelId string, channelType uint8, migrateFrom, migrateTo uint64) error {
 	// 获取频道的分布式配置
 	clusterConfig, err := s.getChannelClusterConfig(channelId, channelType)
 	if err != nil {
 		s.Error("channelMigrate: getChannelClusterConfig error", zap.Error(err))
-		c.ResponseError(err)
-		return
+		return err
 	}
-	if !wkutil.ArrayContainsUint64(clusterConfig.Replicas, req.MigrateFrom) {
-		c.ResponseError(errors.New("MigrateFrom not in replicas"))
-		return
+	if !wkutil.ArrayContainsUint64(clusterConfig.Replicas, migrateFrom) {
+		return errors.New("MigrateFrom not in replicas")
 	}
 
-	if wkutil.ArrayContainsUint64(clusterConfig.Replicas, req.MigrateTo) && req.MigrateFrom != clusterConfig.LeaderId {
-		c.ResponseError(errors.New("transition between followers is not supported"))
-		return
+	if wkutil.ArrayContainsUint64(clusterConfig.Replicas, migrateTo) && migrateFrom != clusterConfig.LeaderId {
+		return errors.New("transition between followers is not supported")
 	}
 
 	newClusterConfig := clusterConfig.Clone()
 	if newClusterConfig.MigrateFrom != 0 || newClusterConfig.MigrateTo != 0 {
-		c.ResponseError(errors.New("migrate is in progress"))
-		return
+		return errors.New("migrate is in progress")
 	}
 
 	// 保存配置
-	newClusterConfig.MigrateFrom = req.MigrateFrom
-	newClusterConfig.MigrateTo = req.MigrateTo
+	newClusterConfig.MigrateFrom = migrateFrom
+	newClusterConfig.MigrateTo = migrateTo
 	newClusterConfig.ConfVersion = uint64(time.Now().UnixNano())
 
-	if !wkutil.ArrayContainsUint64(clusterConfig.Replicas, req.MigrateTo) {
+	if !wkutil.ArrayContainsUint64(clusterConfig.Replicas, migrateTo) {
 		// 将要目标节点加入学习者中
-		newClusterConfig.Learners = append(newClusterConfig.Learners, req.MigrateTo)
+		newClusterConfig.Learners = append(newClusterConfig.Learners, migrateTo)
 	}
 
 	timeoutCtx, cancel := context.WithTimeout(s.cancelCtx, s.opts.ReqTimeout)
@@ -1795,8 +1809,7 @@ func (s *Server) channelMigrate(c *wkhttp.Context) {
 	err = s.opts.ChannelClusterStorage.Propose(timeoutCtx, newClusterConfig)
 	if err != nil {
 		s.Error("channelMigrate: Save error", zap.Error(err))
-		c.ResponseError(err)
-		return
+		return err
 	}
 	s.clusterCfgCache.Add(wkutil.ChannelToKey(channelId, channelType), newClusterConfig)
 
@@ -1805,24 +1818,21 @@ func (s *Server) channelMigrate(c *wkhttp.Context) {
 		err = s.SendChannelClusterConfigUpdate(channelId, channelType, newClusterConfig.LeaderId)
 		if err != nil {
 			s.Error("channelMigrate: sendChannelClusterConfigUpdate error", zap.Error(err))
-			c.ResponseError(err)
-			return
+			return err
 		}
 	} else {
 		s.UpdateChannelClusterConfig(newClusterConfig)
 	}
 
 	// 如果目标节点不是当前节点，则发送最新配置给目标节点
-	if req.MigrateTo != s.opts.NodeId {
-		err = s.SendChannelClusterConfigUpdate(channelId, channelType, req.MigrateTo)
+	if migrateTo != s.opts.NodeId {
+		err = s.SendChannelClusterConfigUpdate(channelId, channelType, migrateTo)
 		if err != nil {
 			s.Error("channelMigrate: sendChannelClusterConfigUpdate error", zap.Error(err))
-			c.ResponseError(err)
-			return
+			return err
 		}
 	}
-	c.ResponseOK()
-
+	return nil
 }
 
 func (s *Server) channelClusterConfig(c *wkhttp.Context) {
@@ -2317,3 +2327,261 @@ func (s *Server) clusterLogs(c *wkhttp.Context) {
 		Logs:    resps,
 	})
 }
+
+func (s *Server) placementViolationsGet(c *wkhttp.Context) {
+	local := wkutil.ParseBool(c.Query("local")) // 只获取本节点作为槽领导的频道
+
+	nodes := s.clusterEventServer.AllowVoteAndJoinedNodes()
+	cfg := s.clusterEventServer.Config()
+
+	violations := make([]*placementViolationResp, 0)
+	if !local {
+		for _, slot := range cfg.Slots {
+			if clusterconfig.PlacementSatisfied(nodes, slot.Replicas) {
+				continue
+			}
+			violations = append(violations, &placementViolationResp{
+				Type:            "slot",
+				SlotId:          slot.Id,
+				Replicas:        slot.Replicas,
+				Zones:           clusterconfig.ReplicaZones(nodes, slot.Replicas),
+				ExpectZoneCount: clusterconfig.ExpectZoneCount(nodes, len(slot.Replicas)),
+			})
+		}
+	}
+
+	// 本节点作为槽领导的频道
+	for _, slot := range cfg.Slots {
+		if slot.Leader != s.opts.NodeId {
+			continue
+		}
+		channelCfgs, err := s.opts.ChannelClusterStorage.GetWithSlotId(slot.Id)
+		if err != nil {
+			s.Error("placementViolationsGet: GetWithSlotId error", zap.Error(err), zap.Uint32("slotId", slot.Id))
+			c.ResponseError(err)
+			return
+		}
+		for _, channelCfg := range channelCfgs {
+			if clusterconfig.PlacementSatisfied(nodes, channelCfg.Replicas) {
+				continue
+			}
+			violations = append(violations, &placementViolationResp{
+				Type:            "channel",
+				SlotId:          slot.Id,
+				ChannelId:       channelCfg.ChannelId,
+				ChannelType:     channelCfg.ChannelType,
+				Replicas:        channelCfg.Replicas,
+				Zones:           clusterconfig.ReplicaZones(nodes, channelCfg.Replicas),
+				ExpectZoneCount: clusterconfig.ExpectZoneCount(nodes, len(channelCfg.Replicas)),
+			})
+		}
+	}
+
+	if !local {
+		// 其他节点作为槽领导的频道
+		timeoutCtx, cancel := context.WithTimeout(s.cancelCtx, time.Second*10)
+		defer cancel()
+		requestGroup, _ := errgroup.WithContext(timeoutCtx)
+		violationsLock := sync.Mutex{}
+		for _, node := range s.clusterEventServer.Nodes() {
+			if node.Id == s.opts.NodeId || !node.Online {
+				continue
+			}
+			requestGroup.Go(func(nId uint64) func() error {
+				return func() error {
+					resp, err := s.requestPlacementViolations(nId, c.CopyRequestHeader(c.Request))
+					if err != nil {
+						return err
+					}
+					violationsLock.Lock()
+					violations = append(violations, resp.Data...)
+					violationsLock.Unlock()
+					return nil
+				}
+			}(node.Id))
+		}
+		err := requestGroup.Wait()
+		if err != nil {
+			s.Error("placementViolationsGet: request node failed", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, placementViolationRespTotal{
+		Total: len(violations),
+		Data:  violations,
+	})
+}
+
+func (s *Server) requestPlacementViolations(nodeId uint64, headers map[string]string) (*placementViolationRespTotal, error) {
+	node := s.clusterEventServer.Node(nodeId)
+	if node == nil {
+		s.Error("requestPlacementViolations failed, node not found", zap.Uint64("nodeId", nodeId))
+		return nil, errors.New("node not found")
+	}
+	fullUrl := fmt.Sprintf("%s%s", node.ApiServerAddr, s.formatPath("/placement/violations"))
+	resp, err := network.Get(fullUrl, map[string]string{"local": "1"}, headers)
+	if err != nil {
+		return nil, err
+	}
+	err = handlerIMError(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var violationsResp *placementViolationRespTotal
+	err = wkutil.ReadJSONByByte([]byte(resp.Body), &violationsResp)
+	if err != nil {
+		return nil, err
+	}
+	return violationsResp, nil
+}
+
+func (s *Server) slotConsistencyGet(c *wkhttp.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		s.Error("id parse error", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	slotId := uint32(id)
+
+	slot := s.clusterEventServer.Slot(slotId)
+	if slot == nil {
+		s.Error("slot not found", zap.Uint32("slotId", slotId))
+		c.ResponseError(ErrSlotNotFound)
+		return
+	}
+	if slot.Leader == 0 {
+		c.ResponseError(ErrNoLeader)
+		return
+	}
+	if slot.Leader != s.opts.NodeId {
+		leaderNode := s.clusterEventServer.Node(slot.Leader)
+		if leaderNode == nil {
+			c.ResponseError(ErrNodeNotFound)
+			return
+		}
+		c.Forward(fmt.Sprintf("%s%s", leaderNode.ApiServerAddr, c.Request.URL.Path))
+		return
+	}
+
+	report, err := s.VerifySlot(s.cancelCtx, slotId)
+	if err != nil {
+		s.Error("VerifySlot error", zap.Error(err), zap.Uint32("slotId", slotId))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (s *Server) channelConsistencyGet(c *wkhttp.Context) {
+	s.verifyChannelFromApi(c, false)
+}
+
+func (s *Server) channelRepair(c *wkhttp.Context) {
+	if !s.opts.Auth.HasPermissionWithContext(c, resource.ClusterChannel.Repair, auth.ActionWrite) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	s.verifyChannelFromApi(c, true)
+}
+
+// verifyChannelFromApi 在频道领导节点上校验频道副本，不是领导则转发给领导
+func (s *Server) verifyChannelFromApi(c *wkhttp.Context, repair bool) {
+	channelId := c.Param("channel_id")
+	channelType := wkutil.ParseUint8(c.Param("channel_type"))
+
+	cfg, err := s.loadOnlyChannelClusterConfig(channelId, channelType)
+	if err != nil {
+		s.Error("loadOnlyChannelClusterConfig error", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if cfg.LeaderId == 0 {
+		c.ResponseError(ErrNoLeader)
+		return
+	}
+	if cfg.LeaderId != s.opts.NodeId {
+		leaderNode := s.clusterEventServer.Node(cfg.LeaderId)
+		if leaderNode == nil {
+			c.ResponseError(ErrNodeNotFound)
+			return
+		}
+		c.Forward(fmt.Sprintf("%s%s", leaderNode.ApiServerAddr, c.Request.URL.Path))
+		return
+	}
+
+	report, err := s.VerifyChannel(s.cancelCtx, channelId, channelType, repair)
+	if err != nil {
+		s.Error("VerifyChannel error", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (s *Server) consistencyDivergencesGet(c *wkhttp.Context) {
+	local := wkutil.ParseBool(c.Query("local")) // 只获取本节点校验发现的
+
+	reports := s.ConsistencyDivergences()
+	if !local {
+		timeoutCtx, cancel := context.WithTimeout(s.cancelCtx, time.Second*10)
+		defer cancel()
+		requestGroup, _ := errgroup.WithContext(timeoutCtx)
+		reportsLock := sync.Mutex{}
+		for _, node := range s.clusterEventServer.Nodes() {
+			if node.Id == s.opts.NodeId || !node.Online {
+				continue
+			}
+			requestGroup.Go(func(nId uint64) func() error {
+				return func() error {
+					resp, err := s.requestConsistencyDivergences(nId, c.CopyRequestHeader(c.Request))
+					if err != nil {
+						return err
+					}
+					reportsLock.Lock()
+					reports = append(reports, resp.Data...)
+					reportsLock.Unlock()
+					return nil
+				}
+			}(node.Id))
+		}
+		err := requestGroup.Wait()
+		if err != nil {
+			s.Error("consistencyDivergencesGet: request node failed", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, consistencyReportTotal{
+		Total: len(reports),
+		Data:  reports,
+	})
+}
+
+func (s *Server) requestConsistencyDivergences(nodeId uint64, headers map[string]string) (*consistencyReportTotal, error) {
+	node := s.clusterEventServer.Node(nodeId)
+	if node == nil {
+		s.Error("requestConsistencyDivergences failed, node not found", zap.Uint64("nodeId", nodeId))
+		return nil, errors.New("node not found")
+	}
+	fullUrl := fmt.Sprintf("%s%s", node.ApiServerAddr, s.formatPath("/consistency/divergences"))
+	resp, err := network.Get(fullUrl, map[string]string{"local": "1"}, headers)
+	if err != nil {
+		return nil, err
+	}
+	err = handlerIMError(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var reportsResp *consistencyReportTotal
+	err = wkutil.ReadJSONByByte([]byte(resp.Body), &reportsResp)
+	if err != nil {
+		return nil, err
+	}
+	return reportsResp, nil
+}
@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/reactor"
@@ -43,6 +44,50 @@ func (s *Server) LeaderOfChannel(ctx context.Context, channelId string, channelT
 	return node, nil
 }
 
+// ReconfigureChannel 频道的副本数量或确认方式变更后重新配置频道（需要在频道所在槽的领导节点上调用）
+// 副本不足的加入学习者，学习者追上日志后再提升为副本，副本过多的直接移除
+func (s *Server) ReconfigureChannel(ctx context.Context, channelId string, channelType uint8) error {
+	cfg, changed, err := s.loadOrCreateChannelClusterConfig(ctx, channelId, channelType)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	if cfg.LeaderId == s.opts.NodeId {
+		s.UpdateChannelClusterConfig(cfg)
+		return nil
+	}
+	// 通知频道领导切换到新配置
+	return s.SendChannelClusterConfigUpdate(channelId, channelType, cfg.LeaderId)
+}
+
+// ChannelClusterConfigForRead 获取频道的分布式配置（不激活频道）
+func (s *Server) ChannelClusterConfigForRead(channelId string, channelType uint8) (wkdb.ChannelClusterConfig, error) {
+	return s.loadOnlyChannelClusterConfig(channelId, channelType)
+}
+
+// ChannelReadState 本节点上频道副本的读状态，频道没有在本节点激活或者本节点不是频道副本时返回false
+func (s *Server) ChannelReadState(channelId string, channelType uint8) (ChannelReadState, bool, error) {
+	handler := s.channelManager.get(channelId, channelType)
+	if handler == nil {
+		return ChannelReadState{}, false, nil
+	}
+	ch := handler.(*channel)
+	cfg := ch.config()
+	if !wkutil.ArrayContainsUint64(cfg.Replicas, s.opts.NodeId) { // 学习者还在追日志，不参与读
+		return ChannelReadState{}, false, nil
+	}
+	// 已存储但未提交的日志可能被领导截断，所以用本节点已提交的下标来计算落后条数
+	return ChannelReadState{
+		LeaderId:             cfg.LeaderId,
+		IsLeader:             cfg.LeaderId == s.opts.NodeId,
+		LeaderCommittedIndex: ch.rc.LeaderCommittedIndex(),
+		CommittedIndex:       ch.rc.CommittedIndex(),
+		LastLeaderContact:    ch.rc.LastLeaderContact(),
+	}, true, nil
+}
+
 func (s *Server) LeaderOfChannelForRead(channelId string, channelType uint8) (*pb.Node, error) {
 	cfg, err := s.loadOnlyChannelClusterConfig(channelId, channelType)
 	if err != nil {
@@ -228,6 +273,16 @@ func (s *Server) ProposeDataToSlot(ctx context.Context, slotId uint32, data []by
 	return results[0], nil
 }
 
+// SlotLogs 获取槽的日志 [startLogIndex, endLogIndex) limitSize 限制返回的日志大小 (字节) 0表示不限制
+func (s *Server) SlotLogs(slotId uint32, startLogIndex uint64, endLogIndex uint64, limitSize uint64) ([]replica.Log, error) {
+	return s.opts.SlotLogStorage.Logs(SlotIdToKey(slotId), startLogIndex, endLogIndex, limitSize)
+}
+
+// SlotAppliedIndex 获取槽已应用的日志索引
+func (s *Server) SlotAppliedIndex(slotId uint32) (uint64, error) {
+	return s.opts.SlotLogStorage.AppliedIndex(SlotIdToKey(slotId))
+}
+
 func (s *Server) MustWaitClusterReady() {
 	s.MustWaitAllSlotsReady()
 	s.MustWaitAllApiServerAddrReady()
@@ -372,6 +427,35 @@ func (s *Server) loadOrCreateChannelClusterConfigNoLock(ctx context.Context, cha
 		return wkdb.EmptyChannelClusterConfig, needProposeCfg, ErrEmptyChannelClusterConfig
 	}
 
+	// ================== 同步频道设置的副本数量和确认方式 ==================
+	replicaMaxCount, ackMode, err := s.channelReplicaSetting(channelId, channelType)
+	if err != nil {
+		s.Warn("get channel replica setting failed", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+	} else if clusterCfg.ReplicaMaxCount != replicaMaxCount || clusterCfg.AckMode != ackMode {
+		s.Info("loadOrCreateChannelClusterConfig: channel replica setting changed", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint16("oldReplicaMaxCount", clusterCfg.ReplicaMaxCount), zap.Uint16("replicaMaxCount", replicaMaxCount), zap.String("oldAckMode", clusterCfg.AckMode.String()), zap.String("ackMode", ackMode.String()))
+		clusterCfg.ReplicaMaxCount = replicaMaxCount
+		clusterCfg.AckMode = ackMode
+		clusterCfg.ConfVersion = uint64(time.Now().UnixNano())
+		needProposeCfg = true
+	}
+
+	// ================== 检查副本是否超过设置的数量 ==================
+	// 副本数量调小后，移除多余的副本（领导不移除），迁移中的不处理，等迁移完成后再移除
+	if len(clusterCfg.Learners) == 0 && clusterCfg.MigrateFrom == 0 && clusterCfg.MigrateTo == 0 && len(clusterCfg.Replicas) > int(clusterCfg.ReplicaMaxCount) {
+		replicaNodes := make([]*pb.Node, 0, len(clusterCfg.Replicas))
+		for _, node := range s.clusterEventServer.AllowVoteAndJoinedNodes() {
+			if wkutil.ArrayContainsUint64(clusterCfg.Replicas, node.Id) {
+				replicaNodes = append(replicaNodes, node)
+			}
+		}
+		// 保留的副本尽量分散在不同的可用区
+		newReplicas := clusterconfig.PickReplicas(replicaNodes, int(clusterCfg.ReplicaMaxCount), []uint64{clusterCfg.LeaderId}, 0)
+		s.Info("loadOrCreateChannelClusterConfig: remove replicas", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64s("replicas", clusterCfg.Replicas), zap.Uint64s("newReplicas", newReplicas))
+		clusterCfg.Replicas = newReplicas
+		clusterCfg.ConfVersion = uint64(time.Now().UnixNano())
+		needProposeCfg = true
+	}
+
 	// ================== 检查配置是否有新节点加入 ==================
 	// 如果当前节点是频道的领导者，但是副本数量小于设置的最大副本数量，则需要变更
 	allowVoteAndJoinedNodeCount := s.clusterEventServer.AllowVoteAndJoinedNodeCount() // 允许投票的节点数量
@@ -380,17 +464,10 @@ func (s *Server) loadOrCreateChannelClusterConfigNoLock(ctx context.Context, cha
 
 		s.Info("loadOrCreateChannelClusterConfig: need add new node to replicas", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Int("currentReplicaCount", currentReplicaCount), zap.Uint64s("replicas", clusterCfg.Replicas), zap.Uint16("replicaMaxCount", clusterCfg.ReplicaMaxCount), zap.Int("allowVoteAndJoinedNodeCount", allowVoteAndJoinedNodeCount))
 
-		nodes := s.clusterEventServer.AllowVoteAndJoinedNodes()
-		newReplicaIds := make([]uint64, 0, allowVoteAndJoinedNodeCount-len(clusterCfg.Replicas))
-		for _, node := range nodes {
-			if !wkutil.ArrayContainsUint64(clusterCfg.Replicas, node.Id) {
-				newReplicaIds = append(newReplicaIds, node.Id)
-			}
-		}
 		// 打乱顺序，防止每次都是相同的节点加入
-		rand.Shuffle(len(newReplicaIds), func(i, j int) {
-			newReplicaIds[i], newReplicaIds[j] = newReplicaIds[j], newReplicaIds[i]
-		})
+		nodes := s.shuffledAllowVoteAndJoinedNodes()
+		// 优先选择副本还没有覆盖的可用区里的节点
+		newReplicaIds := clusterconfig.PickReplicas(nodes, int(clusterCfg.ReplicaMaxCount), clusterCfg.Replicas, 0)[len(clusterCfg.Replicas):]
 
 		// 将新节点加入到学习者列表
 		for _, newReplicaId := range newReplicaIds {
@@ -402,6 +479,18 @@ func (s *Server) loadOrCreateChannelClusterConfigNoLock(ctx context.Context, cha
 			}
 		}
 		needProposeCfg = true
+	} else if len(clusterCfg.Learners) == 0 && clusterCfg.MigrateFrom == 0 && clusterCfg.MigrateTo == 0 {
+		// ================== 检查副本的可用区分布 ==================
+		from, to := clusterconfig.PlacementRepair(s.shuffledAllowVoteAndJoinedNodes(), clusterCfg.Replicas, clusterCfg.LeaderId, func(nodeId uint64) int {
+			return 0 // 节点已经打乱，取第一个满足的节点即可
+		})
+		if from != 0 && to != 0 {
+			s.Info("loadOrCreateChannelClusterConfig: replicas not spread across zones, migrate replica", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64s("replicas", clusterCfg.Replicas), zap.Uint64("from", from), zap.Uint64("to", to))
+			clusterCfg.MigrateFrom = from
+			clusterCfg.MigrateTo = to
+			clusterCfg.Learners = append(clusterCfg.Learners, to)
+			needProposeCfg = true
+		}
 	}
 
 	if needProposeCfg {
@@ -496,39 +585,53 @@ func (s *Server) createChannelClusterConfig(channelId string, channelType uint8)
 		return wkdb.EmptyChannelClusterConfig, ErrNoAllowVoteNode
 	}
 
+	replicaMaxCount, ackMode, err := s.channelReplicaSetting(channelId, channelType)
+	if err != nil {
+		return wkdb.EmptyChannelClusterConfig, err
+	}
+
 	createdAt := time.Now()
 	updatedAt := time.Now()
 	clusterConfig := wkdb.ChannelClusterConfig{
 		ChannelId:       channelId,
 		ChannelType:     channelType,
-		ReplicaMaxCount: uint16(s.opts.ChannelMaxReplicaCount),
+		ReplicaMaxCount: replicaMaxCount,
+		AckMode:         ackMode,
 		Term:            1,
 		LeaderId:        s.opts.NodeId,
 		CreatedAt:       &createdAt,
 		UpdatedAt:       &updatedAt,
 	}
-	replicaIds := make([]uint64, 0, s.opts.ChannelMaxReplicaCount)
-	replicaIds = append(replicaIds, s.opts.NodeId) // 默认当前节点是领导，所以加入到副本列表中
-
-	// 随机选择副本
-	newAllowVoteNodes := make([]*pb.Node, 0, len(allowVoteNodes))
-	newAllowVoteNodes = append(newAllowVoteNodes, allowVoteNodes...)
-	rand.Shuffle(len(newAllowVoteNodes), func(i, j int) {
-		newAllowVoteNodes[i], newAllowVoteNodes[j] = newAllowVoteNodes[j], newAllowVoteNodes[i]
-	})
-
-	for _, allowVoteNode := range newAllowVoteNodes {
-		if allowVoteNode.Id == s.opts.NodeId {
-			continue
-		}
-		if len(replicaIds) >= int(s.opts.ChannelMaxReplicaCount) {
-			break
-		}
-		replicaIds = append(replicaIds, allowVoteNode.Id)
+	// 随机选择副本，默认当前节点是领导，所以加入到副本列表中，其他副本尽量分散在不同的可用区
+	clusterConfig.Replicas = clusterconfig.PickReplicas(s.shuffledAllowVoteAndJoinedNodes(), int(replicaMaxCount), []uint64{s.opts.NodeId}, 0)
+	return clusterConfig, nil
+}
 
+// 频道设置的副本数量和确认方式，频道没有设置副本数量则使用默认的副本数量
+func (s *Server) channelReplicaSetting(channelId string, channelType uint8) (uint16, wkdb.ChannelAckMode, error) {
+	replicaMaxCount := uint16(s.opts.ChannelMaxReplicaCount)
+	if s.opts.DB == nil {
+		return replicaMaxCount, wkdb.ChannelAckModeDefault, nil
 	}
-	clusterConfig.Replicas = replicaIds
-	return clusterConfig, nil
+	channelInfo, err := s.opts.DB.GetChannel(channelId, channelType)
+	if err != nil {
+		return 0, wkdb.ChannelAckModeDefault, err
+	}
+	if channelInfo.ReplicaCount > 0 {
+		replicaMaxCount = channelInfo.ReplicaCount
+	}
+	return replicaMaxCount, channelInfo.AckMode, nil
+}
+
+// 打乱顺序的允许投票并且已经加入的节点
+func (s *Server) shuffledAllowVoteAndJoinedNodes() []*pb.Node {
+	allowVoteNodes := s.clusterEventServer.AllowVoteAndJoinedNodes()
+	nodes := make([]*pb.Node, 0, len(allowVoteNodes))
+	nodes = append(nodes, allowVoteNodes...)
+	rand.Shuffle(len(nodes), func(i, j int) {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	})
+	return nodes
 }
 
 // func (s *Server) updateClusterConfigIfNeed(clusterCfg wkdb.ChannelClusterConfig) (wkdb.ChannelClusterConfig, bool, error) {
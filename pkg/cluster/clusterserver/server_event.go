@@ -21,6 +21,7 @@ func (s *Server) onClusterConfigChange(cfg *pb.Config) {
 	if err != nil {
 		s.Error("handleClusterConfigChange failed", zap.Error(err))
 	}
+	s.updateGossipMeta(cfg)
 }
 
 // 处理槽选举
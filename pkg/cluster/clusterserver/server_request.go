@@ -34,6 +34,11 @@ func (s *Server) setRoutes() {
 
 	// 获取槽日志信息
 	s.netServer.Route("/slot/logInfo", s.handleSlotLogInfo)
+
+	// 获取副本的日志校验和
+	s.netServer.Route("/shard/checksum", s.handleShardChecksum)
+	// 修复与领导不一致的频道副本
+	s.netServer.Route("/channel/repair", s.handleChannelRepair)
 }
 
 func (s *Server) handleChannelLastLogInfo(c *wkserver.Context) {
@@ -293,6 +298,9 @@ func (s *Server) handleClusterJoin(c *wkserver.Context) {
 		AllowVote:   allowVote,
 		CreatedAt:   time.Now().Unix(),
 		Status:      pb.NodeStatus_NodeStatusWillJoin,
+		Zone:        req.Zone,
+		Rack:        req.Rack,
+		Labels:      req.Labels,
 	})
 	if err != nil {
 		s.Error("proposeJoin failed", zap.Error(err))
@@ -416,3 +424,47 @@ func (s *Server) handleSlotLogInfo(c *wkserver.Context) {
 	}
 	c.Write(data)
 }
+
+func (s *Server) handleShardChecksum(c *wkserver.Context) {
+	req := &ShardChecksumReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("unmarshal ShardChecksumReq failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	resp, err := s.localShardChecksum(req)
+	if err != nil {
+		s.Error("localShardChecksum failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	data, err := resp.Marshal()
+	if err != nil {
+		s.Error("marshal ShardChecksumResp failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	c.Write(data)
+}
+
+func (s *Server) handleChannelRepair(c *wkserver.Context) {
+	req := &ChannelRepairReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("unmarshal ChannelRepairReq failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	from, err := s.getFrom(c)
+	if err != nil {
+		s.Error("getFrom failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	err = s.repairChannelReplica(from, req)
+	if err != nil {
+		s.Error("repairChannelReplica failed", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.WriteErr(err)
+		return
+	}
+	c.WriteOk()
+}
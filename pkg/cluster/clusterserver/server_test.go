@@ -5,6 +5,7 @@ import (
 
 	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap/zapcore"
 )
@@ -63,3 +64,76 @@ func TestServer(t *testing.T) {
 	s3.MustWaitAllSlotsReady()
 
 }
+
+func TestClusterJoinReqLabels(t *testing.T) {
+	req := &cluster.ClusterJoinReq{
+		NodeId:     2,
+		ServerAddr: "127.0.0.1:10002",
+		Zone:       "zone-a",
+		Rack:       "rack-1",
+		Labels:     map[string]string{"disk": "ssd"},
+	}
+	data, err := req.Marshal()
+	assert.NoError(t, err)
+
+	newReq := &cluster.ClusterJoinReq{}
+	err = newReq.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, req, newReq)
+
+	// 旧版本节点没有可用区等信息
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(2)
+	enc.WriteString("127.0.0.1:10002")
+	enc.WriteUint32(0)
+	newReq = &cluster.ClusterJoinReq{}
+	err = newReq.Unmarshal(enc.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), newReq.NodeId)
+	assert.Equal(t, "", newReq.Zone)
+}
+
+func TestShardChecksumMarshal(t *testing.T) {
+	req := &cluster.ShardChecksumReq{
+		ShardType:   cluster.ShardTypeChannel,
+		ChannelId:   "test",
+		ChannelType: 2,
+		StartIndex:  1,
+		EndIndex:    2500,
+		SegmentSize: 1000,
+	}
+	data, err := req.Marshal()
+	assert.NoError(t, err)
+	newReq := &cluster.ShardChecksumReq{}
+	err = newReq.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, req, newReq)
+
+	resp := &cluster.ShardChecksumResp{
+		NodeId:         2,
+		LastIndex:      2600,
+		CommittedIndex: 2500,
+		AppliedIndex:   2500,
+		StateChecksum:  12345,
+		Segments: []cluster.LogSegmentChecksum{
+			{StartIndex: 1, EndIndex: 1000, Count: 1000, Checksum: 1},
+			{StartIndex: 1001, EndIndex: 2000, Count: 1000, Checksum: 2},
+			{StartIndex: 2001, EndIndex: 2500, Count: 500, Checksum: 3},
+		},
+	}
+	data, err = resp.Marshal()
+	assert.NoError(t, err)
+	newResp := &cluster.ShardChecksumResp{}
+	err = newResp.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, resp, newResp)
+}
+
+func TestChannelReadStateLagIndex(t *testing.T) {
+	state := cluster.ChannelReadState{LeaderCommittedIndex: 10, CommittedIndex: 7}
+	assert.Equal(t, uint64(3), state.LagIndex())
+
+	state.CommittedIndex = 12
+	assert.Equal(t, uint64(0), state.LagIndex())
+}
@@ -78,6 +78,16 @@ const (
 
 	// 批量更新最近会话
 	CMDBatchUpdateConversation
+	// 删除用户（包括用户的设备和最近会话）
+	CMDDeleteUser
+	// 添加或更新消息回应
+	CMDAddOrUpdateReactions
+	// 批量添加或更新多个用户的会话（同一个槽的用户）
+	CMDAddOrUpdateUserConversations
+	// 应用主集群镜像过来的槽日志并推进镜像进度（备集群）
+	CMDMirrorSlotLogs
+	// 保存镜像进度（备集群）
+	CMDSaveMirrorCheckpoint
 )
 
 func (c CMDType) Uint16() uint16 {
@@ -150,6 +160,16 @@ func (c CMDType) String() string {
 		return "CMDBatchUpdateConversation"
 	case CMDDeleteConversations:
 		return "CMDDeleteConversations"
+	case CMDDeleteUser:
+		return "CMDDeleteUser"
+	case CMDAddOrUpdateReactions:
+		return "CMDAddOrUpdateReactions"
+	case CMDAddOrUpdateUserConversations:
+		return "CMDAddOrUpdateUserConversations"
+	case CMDMirrorSlotLogs:
+		return "CMDMirrorSlotLogs"
+	case CMDSaveMirrorCheckpoint:
+		return "CMDSaveMirrorCheckpoint"
 	default:
 		return fmt.Sprintf("CMDUnknown[%d]", c)
 	}
@@ -434,6 +454,59 @@ func (c *CMD) CMDContent() (string, error) {
 		}
 		return wkutil.ToJSON(channelClusterConfig), nil
 
+	case CMDDeleteUser:
+		uid, err := c.DecodeCMDDeleteUser()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid": uid,
+		}), nil
+
+	case CMDAddOrUpdateReactions:
+		channelId, channelType, reactions, err := c.DecodeCMDAddOrUpdateReactions()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"reactions":   reactions,
+		}), nil
+
+	case CMDAddOrUpdateUserConversations:
+		conversations, err := c.DecodeCMDAddOrUpdateUserConversations()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"conversations": conversations,
+		}), nil
+
+	case CMDMirrorSlotLogs:
+		name, logs, err := c.DecodeCMDMirrorSlotLogs()
+		if err != nil {
+			return "", err
+		}
+		indexs := make([]uint64, 0, len(logs))
+		for _, lg := range logs {
+			indexs = append(indexs, lg.Index)
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"name":   name,
+			"indexs": indexs,
+		}), nil
+
+	case CMDSaveMirrorCheckpoint:
+		name, value, err := c.DecodeCMDSaveMirrorCheckpoint()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"name":  name,
+			"value": value,
+		}), nil
+
 	}
 
 	return "", nil
@@ -769,6 +842,10 @@ func EncodeChannelInfo(c wkdb.ChannelInfo, version CmdVersion) ([]byte, error) {
 	if version > 0 {
 		enc.WriteString(c.Webhook)
 	}
+	if version > 2 {
+		enc.WriteUint16(c.ReplicaCount)
+		enc.WriteUint8(uint8(c.AckMode))
+	}
 	return enc.Bytes(), nil
 }
 
@@ -824,6 +901,18 @@ func (c *CMD) DecodeChannelInfo() (wkdb.ChannelInfo, error) {
 		}
 	}
 
+	// 副本数量和确认方式是后追加的字段，老数据没有
+	if c.version > 0 && dec.Len() > 0 {
+		if channelInfo.ReplicaCount, err = dec.Uint16(); err != nil {
+			return channelInfo, err
+		}
+		var ackMode uint8
+		if ackMode, err = dec.Uint8(); err != nil {
+			return channelInfo, err
+		}
+		channelInfo.AckMode = wkdb.ChannelAckMode(ackMode)
+	}
+
 	return channelInfo, err
 }
 
@@ -1207,4 +1296,142 @@ func (c *CMD) DecodeCMDSystemUIDs() (uids []string, err error) {
 	return
 }
 
+func EncodeCMDDeleteUser(uid string) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDDeleteUser() (uid string, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	uid, err = decoder.String()
+	return
+}
+
+func EncodeCMDAddOrUpdateReactions(channelId string, channelType uint8, reactions []wkdb.Reaction) ([]byte, error) {
+	data, err := wkdb.ReactionSet(reactions).Marshal()
+	if err != nil {
+		return nil, err
+	}
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encoder.WriteBinary(data)
+	return encoder.Bytes(), nil
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateReactions() (channelId string, channelType uint8, reactions []wkdb.Reaction, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	var data []byte
+	if data, err = decoder.Binary(); err != nil {
+		return
+	}
+	var reactionSet wkdb.ReactionSet
+	if err = reactionSet.Unmarshal(data); err != nil {
+		return
+	}
+	reactions = reactionSet
+	return
+}
+
+// EncodeCMDAddOrUpdateUserConversations 多个用户的会话，会话里的Uid为会话所属的用户
+func EncodeCMDAddOrUpdateUserConversations(conversations []wkdb.Conversation) ([]byte, error) {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint32(uint32(len(conversations)))
+	for _, conversation := range conversations {
+		data, err := conversation.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		encoder.WriteBinary(data)
+	}
+	return encoder.Bytes(), nil
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateUserConversations() (conversations []wkdb.Conversation, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	for i := uint32(0); i < count; i++ {
+		var data []byte
+		if data, err = decoder.Binary(); err != nil {
+			return
+		}
+		var conversation wkdb.Conversation
+		if err = conversation.Unmarshal(data); err != nil {
+			return
+		}
+		conversations = append(conversations, conversation)
+	}
+	return
+}
+
+// MirrorLog 主集群镜像过来的槽日志
+type MirrorLog struct {
+	Index uint64 // 主集群的日志下标
+	Data  []byte // 为空表示不需要应用，只推进进度
+}
+
+func EncodeCMDMirrorSlotLogs(name string, logs []MirrorLog) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(name)
+	encoder.WriteUint32(uint32(len(logs)))
+	for _, lg := range logs {
+		encoder.WriteUint64(lg.Index)
+		encoder.WriteBinary(lg.Data)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDMirrorSlotLogs() (name string, logs []MirrorLog, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if name, err = decoder.String(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	for i := uint32(0); i < count; i++ {
+		var lg MirrorLog
+		if lg.Index, err = decoder.Uint64(); err != nil {
+			return
+		}
+		if lg.Data, err = decoder.Binary(); err != nil {
+			return
+		}
+		logs = append(logs, lg)
+	}
+	return
+}
+
+func EncodeCMDSaveMirrorCheckpoint(name string, value uint64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(name)
+	encoder.WriteUint64(value)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDSaveMirrorCheckpoint() (name string, value uint64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if name, err = decoder.String(); err != nil {
+		return
+	}
+	value, err = decoder.Uint64()
+	return
+}
+
 var ErrStoreStopped = fmt.Errorf("store stopped")
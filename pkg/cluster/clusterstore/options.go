@@ -1,7 +1,11 @@
 package clusterstore
 
 import (
+	"time"
+
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
+	"github.com/WuKongIM/WuKongIM/pkg/kms"
+	"github.com/WuKongIM/WuKongIM/pkg/objstore"
 )
 
 type Options struct {
@@ -18,6 +22,23 @@ type Options struct {
 		ShardNum     int // 分片数量
 		MemTableSize int // MemTable大小
 	}
+
+	Tiered struct { // 分层存储，ColdStore为nil表示不开启
+		ColdStore    objstore.Store
+		SegmentSize  int
+		KeepMessages uint64
+		ColdAfter    time.Duration
+		CacheSize    int
+		Interval     time.Duration
+	}
+
+	Encryption struct { // 静态加密，MasterKey为nil表示不开启
+		MasterKey          kms.MasterKey
+		ReencryptInterval  time.Duration
+		ReencryptBatchSize int
+	}
+
+	MirrorOn bool // 跨集群镜像的主集群，追加消息时标记频道等待镜像
 }
 
 func NewOptions(nodeID uint64, opts ...Option) *Options {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/WuKongIM/WuKongIM/pkg/keylock"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
@@ -21,6 +22,8 @@ type Store struct {
 
 	messageShardLogStorage *MessageShardLogStorage
 
+	mirrorMu sync.Mutex // 同一批次的日志并发应用，镜像进度的读写需要串行
+
 	stopper *syncutil.Stopper
 }
 
@@ -39,16 +42,35 @@ func NewStore(opts *Options) *Store {
 		s.Panic("create data dir err", zap.Error(err))
 	}
 
-	s.wdb = wkdb.NewWukongDB(
-		wkdb.NewOptions(
-			wkdb.WithIsCmdChannel(opts.IsCmdChannel),
-			wkdb.WithShardNum(opts.Db.ShardNum),
-			wkdb.WithDir(opts.DataDir),
-			wkdb.WithNodeId(opts.NodeID),
-			wkdb.WithMemTableSize(opts.Db.MemTableSize),
-			wkdb.WithSlotCount(int(opts.SlotCount)),
-		),
-	)
+	dbOpts := []wkdb.Option{
+		wkdb.WithIsCmdChannel(opts.IsCmdChannel),
+		wkdb.WithShardNum(opts.Db.ShardNum),
+		wkdb.WithDir(opts.DataDir),
+		wkdb.WithNodeId(opts.NodeID),
+		wkdb.WithMemTableSize(opts.Db.MemTableSize),
+		wkdb.WithSlotCount(int(opts.SlotCount)),
+	}
+	if opts.Tiered.ColdStore != nil {
+		dbOpts = append(dbOpts,
+			wkdb.WithColdStore(opts.Tiered.ColdStore),
+			wkdb.WithTieredSegmentSize(opts.Tiered.SegmentSize),
+			wkdb.WithTieredKeepMessages(opts.Tiered.KeepMessages),
+			wkdb.WithTieredColdAfter(opts.Tiered.ColdAfter),
+			wkdb.WithTieredCacheSize(opts.Tiered.CacheSize),
+			wkdb.WithTieredInterval(opts.Tiered.Interval),
+		)
+	}
+	if opts.Encryption.MasterKey != nil {
+		dbOpts = append(dbOpts,
+			wkdb.WithMasterKey(opts.Encryption.MasterKey),
+			wkdb.WithReencryptInterval(opts.Encryption.ReencryptInterval),
+			wkdb.WithReencryptBatchSize(opts.Encryption.ReencryptBatchSize),
+		)
+	}
+	if opts.MirrorOn {
+		dbOpts = append(dbOpts, wkdb.WithMirrorOn())
+	}
+	s.wdb = wkdb.NewWukongDB(wkdb.NewOptions(dbOpts...))
 
 	s.messageShardLogStorage = NewMessageShardLogStorage(s.wdb)
 	return s
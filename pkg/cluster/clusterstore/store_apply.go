@@ -102,6 +102,16 @@ func (s *Store) execCMD(cmd *CMD) error {
 		return s.handleSystemUIDsAdd(cmd)
 	case CMDSystemUIDsRemove: // 移除系统UID
 		return s.handleSystemUIDsRemove(cmd)
+	case CMDDeleteUser: // 删除用户
+		return s.handleDeleteUser(cmd)
+	case CMDAddOrUpdateReactions: // 添加或更新消息回应
+		return s.handleAddOrUpdateReactions(cmd)
+	case CMDAddOrUpdateUserConversations: // 批量添加或更新多个用户的会话
+		return s.handleAddOrUpdateUserConversations(cmd)
+	case CMDMirrorSlotLogs: // 应用镜像的槽日志
+		return s.handleMirrorSlotLogs(cmd)
+	case CMDSaveMirrorCheckpoint: // 保存镜像进度
+		return s.handleSaveMirrorCheckpoint(cmd)
 
 	}
 	return nil
@@ -327,3 +337,59 @@ func (s *Store) handleSystemUIDsRemove(cmd *CMD) error {
 	}
 	return s.wdb.RemoveSystemUids(uids)
 }
+
+func (s *Store) handleDeleteUser(cmd *CMD) error {
+	uid, err := cmd.DecodeCMDDeleteUser()
+	if err != nil {
+		return err
+	}
+	conversations, err := s.wdb.GetConversations(uid)
+	if err != nil {
+		return err
+	}
+	if len(conversations) > 0 {
+		channels := make([]wkdb.Channel, 0, len(conversations))
+		for _, conversation := range conversations {
+			channels = append(channels, wkdb.Channel{
+				ChannelId:   conversation.ChannelId,
+				ChannelType: conversation.ChannelType,
+			})
+		}
+		if err = s.wdb.DeleteConversations(uid, channels); err != nil {
+			return err
+		}
+	}
+	if err = s.wdb.DeleteDevices(uid); err != nil {
+		return err
+	}
+	return s.wdb.DeleteUser(uid)
+}
+
+func (s *Store) handleAddOrUpdateReactions(cmd *CMD) error {
+	channelId, channelType, reactions, err := cmd.DecodeCMDAddOrUpdateReactions()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateReactions(channelId, channelType, reactions)
+}
+
+func (s *Store) handleAddOrUpdateUserConversations(cmd *CMD) error {
+	conversations, err := cmd.DecodeCMDAddOrUpdateUserConversations()
+	if err != nil {
+		return err
+	}
+	uids := make([]string, 0)
+	userConversations := make(map[string][]wkdb.Conversation)
+	for _, conversation := range conversations {
+		if _, ok := userConversations[conversation.Uid]; !ok {
+			uids = append(uids, conversation.Uid)
+		}
+		userConversations[conversation.Uid] = append(userConversations[conversation.Uid], conversation)
+	}
+	for _, uid := range uids {
+		if err = s.wdb.AddOrUpdateConversations(uid, userConversations[uid]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -220,6 +220,11 @@ func (s *Store) RemoveAllowlist(channelId string, channelType uint8, uids []stri
 	return err
 }
 
+// GetChannelsByMember 获取本节点上用户所在的频道（订阅者、黑名单、白名单）
+func (s *Store) GetChannelsByMember(uid string) ([]wkdb.MemberOfChannel, error) {
+	return s.wdb.GetChannelsByMember(uid)
+}
+
 // 是否存在白名单
 func (s *Store) HasAllowlist(channelId string, channelType uint8) (bool, error) {
 	return s.wdb.HasAllowlist(channelId, channelType)
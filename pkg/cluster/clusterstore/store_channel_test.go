@@ -1,5 +1,47 @@
 package clusterstore_test
 
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterstore"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelInfoCMD(t *testing.T) {
+	channelInfo := wkdb.ChannelInfo{
+		ChannelId:    "g1",
+		ChannelType:  2,
+		Large:        true,
+		Webhook:      "http://127.0.0.1",
+		ReplicaCount: 5,
+		AckMode:      wkdb.ChannelAckModeAll,
+	}
+	decode := func(version clusterstore.CmdVersion) wkdb.ChannelInfo {
+		data, err := clusterstore.EncodeChannelInfo(channelInfo, version)
+		assert.NoError(t, err)
+		cmdData, err := clusterstore.NewCMDWithVersion(clusterstore.CMDAddChannelInfo, data, version).Marshal()
+		assert.NoError(t, err)
+		cmd := &clusterstore.CMD{}
+		err = cmd.Unmarshal(cmdData)
+		assert.NoError(t, err)
+		channelInfo2, err := cmd.DecodeChannelInfo()
+		assert.NoError(t, err)
+		return channelInfo2
+	}
+
+	channelInfo2 := decode(clusterstore.CmdVersionChannelInfo)
+	assert.Equal(t, channelInfo.Webhook, channelInfo2.Webhook)
+	assert.Equal(t, channelInfo.ReplicaCount, channelInfo2.ReplicaCount)
+	assert.Equal(t, channelInfo.AckMode, channelInfo2.AckMode)
+
+	// 老版本的数据没有副本设置
+	channelInfo2 = decode(2)
+	assert.Equal(t, channelInfo.Webhook, channelInfo2.Webhook)
+	assert.Equal(t, uint16(0), channelInfo2.ReplicaCount)
+	assert.Equal(t, wkdb.ChannelAckModeDefault, channelInfo2.AckMode)
+}
+
 // func TestAddSubscribers(t *testing.T) {
 // 	s1, t1, s2, t2, s3, t3 := newTestClusterServerGroupThree()
 // 	defer s1.Close()
@@ -478,3 +520,25 @@ package clusterstore_test
 // 	assert.NoError(t, err)
 // 	assert.Equal(t, 0, len(allowlist))
 // }
+
+func TestAddOrUpdateUserConversationsCMD(t *testing.T) {
+	conversations := []wkdb.Conversation{
+		{Id: 1, Uid: "u1", ChannelId: "g1", ChannelType: 2, ReadToMsgSeq: 10},
+		{Id: 2, Uid: "u2", ChannelId: "g1", ChannelType: 2, ReadToMsgSeq: 10},
+	}
+	data, err := clusterstore.EncodeCMDAddOrUpdateUserConversations(conversations)
+	assert.NoError(t, err)
+	cmdData, err := clusterstore.NewCMD(clusterstore.CMDAddOrUpdateUserConversations, data).Marshal()
+	assert.NoError(t, err)
+	cmd := &clusterstore.CMD{}
+	err = cmd.Unmarshal(cmdData)
+	assert.NoError(t, err)
+
+	conversations2, err := cmd.DecodeCMDAddOrUpdateUserConversations()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(conversations2))
+	assert.Equal(t, "u1", conversations2[0].Uid)
+	assert.Equal(t, "u2", conversations2[1].Uid)
+	assert.Equal(t, uint64(2), conversations2[1].Id)
+	assert.Equal(t, uint64(10), conversations2[1].ReadToMsgSeq)
+}
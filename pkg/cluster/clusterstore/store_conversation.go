@@ -2,8 +2,12 @@ package clusterstore
 
 import (
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"golang.org/x/sync/errgroup"
 )
 
+// userConversationsBatchSize 批量添加多个用户的会话时，每次提案的最大会话数量
+const userConversationsBatchSize = 500
+
 func (s *Store) AddOrUpdateConversations(uid string, conversations []wkdb.Conversation) error {
 	if len(conversations) == 0 {
 		return nil
@@ -27,6 +31,50 @@ func (s *Store) AddOrUpdateConversations(uid string, conversations []wkdb.Conver
 	return err
 }
 
+// AddOrUpdateUserConversations 批量添加或更新多个用户的会话（会话的Uid为所属用户），按用户所在的槽分组提案
+func (s *Store) AddOrUpdateUserConversations(conversations []wkdb.Conversation) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+	slotIds := make([]uint32, 0)
+	slotConversations := make(map[uint32][]wkdb.Conversation)
+	for _, c := range conversations {
+		if c.Id == 0 {
+			c.Id = s.NextPrimaryKey() // 如果id为0，生成一个新的id
+		}
+		slotId := s.opts.GetSlotId(c.Uid)
+		if _, ok := slotConversations[slotId]; !ok {
+			slotIds = append(slotIds, slotId)
+		}
+		slotConversations[slotId] = append(slotConversations[slotId], c)
+	}
+
+	requestGroup, _ := errgroup.WithContext(s.ctx)
+	requestGroup.SetLimit(20) // 同时提案的并发数
+	for _, slotId := range slotIds {
+		slotId := slotId
+		cs := slotConversations[slotId]
+		for len(cs) > 0 {
+			size := min(len(cs), userConversationsBatchSize)
+			batch := cs[:size]
+			cs = cs[size:]
+			requestGroup.Go(func() error {
+				data, err := EncodeCMDAddOrUpdateUserConversations(batch)
+				if err != nil {
+					return err
+				}
+				cmdData, err := NewCMD(CMDAddOrUpdateUserConversations, data).Marshal()
+				if err != nil {
+					return err
+				}
+				_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+				return err
+			})
+		}
+	}
+	return requestGroup.Wait()
+}
+
 func (s *Store) DeleteConversation(uid string, channelID string, channelType uint8) error {
 	data := EncodeCMDDeleteConversation(uid, channelID, channelType)
 	cmd := NewCMD(CMDDeleteConversation, data)
@@ -68,6 +116,22 @@ func (s *Store) GetLastConversations(uid string, tp wkdb.ConversationType, updat
 	return s.wdb.GetLastConversations(uid, tp, updatedAt, limit)
 }
 
+func (s *Store) GetConversationVersion(uid string) (uint64, error) {
+	return s.wdb.GetConversationVersion(uid)
+}
+
+func (s *Store) GetConversationsByVersion(uid string, version uint64, limit int) ([]wkdb.Conversation, error) {
+	return s.wdb.GetConversationsByVersion(uid, version, limit)
+}
+
+func (s *Store) GetConversationTombstones(uid string, version uint64, limit int) ([]wkdb.ConversationTombstone, error) {
+	return s.wdb.GetConversationTombstones(uid, version, limit)
+}
+
+func (s *Store) GetConversationTombstoneFloor(uid string) (uint64, error) {
+	return s.wdb.GetConversationTombstoneFloor(uid)
+}
+
 func (s *Store) GetChannelLastMessageSeq(channelId string, channelType uint8) (uint64, error) {
 	seq, _, err := s.wdb.GetChannelLastMessageSeq(channelId, channelType)
 	return seq, err
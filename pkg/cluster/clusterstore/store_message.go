@@ -75,6 +75,90 @@ func (s *Store) RemoveMessagesOfNotifyQueue(messageIDs []int64) error {
 	return s.wdb.RemoveMessagesOfNotifyQueue(messageIDs)
 }
 
+// IncMessageNotifyRetryCount 增加通知队列消息的重试次数（只存在本节点）
+func (s *Store) IncMessageNotifyRetryCount(messageIDs []int64) (map[int64]int, error) {
+	return s.wdb.IncMessageNotifyRetryCount(messageIDs)
+}
+
+// MoveMessagesOfNotifyQueueToDeadLetter 将通知队列的消息移到死信里（只存在本节点）
+func (s *Store) MoveMessagesOfNotifyQueueToDeadLetter(messageIDs []int64, deadLetter wkdb.WebhookDeadLetter) error {
+	return s.wdb.MoveMessagesOfNotifyQueueToDeadLetter(messageIDs, deadLetter)
+}
+
+func (s *Store) AddWebhookDeadLetter(deadLetter wkdb.WebhookDeadLetter) error {
+	return s.wdb.AddWebhookDeadLetter(deadLetter)
+}
+
+func (s *Store) GetWebhookDeadLetters(startId uint64, limit int) ([]wkdb.WebhookDeadLetter, error) {
+	return s.wdb.GetWebhookDeadLetters(startId, limit)
+}
+
+func (s *Store) GetWebhookDeadLetter(id uint64) (wkdb.WebhookDeadLetter, error) {
+	return s.wdb.GetWebhookDeadLetter(id)
+}
+
+func (s *Store) RemoveWebhookDeadLetters(ids []uint64) error {
+	return s.wdb.RemoveWebhookDeadLetters(ids)
+}
+
+func (s *Store) RemoveAllWebhookDeadLetters() error {
+	return s.wdb.RemoveAllWebhookDeadLetters()
+}
+
+func (s *Store) AppendEventSinkEvents(events []wkdb.SinkEvent) error {
+	return s.wdb.AppendEventSinkEvents(events)
+}
+
+func (s *Store) GetEventSinkEvents(startId uint64, limit int) ([]wkdb.SinkEvent, error) {
+	return s.wdb.GetEventSinkEvents(startId, limit)
+}
+
+func (s *Store) RemoveEventSinkEvents(endId uint64) error {
+	return s.wdb.RemoveEventSinkEvents(endId)
+}
+
+func (s *Store) GetEventSinkCheckpoint(sinkName string) (uint64, error) {
+	return s.wdb.GetEventSinkCheckpoint(sinkName)
+}
+
+func (s *Store) SetEventSinkCheckpoint(sinkName string, eventId uint64) error {
+	return s.wdb.SetEventSinkCheckpoint(sinkName, eventId)
+}
+
+func (s *Store) GetMirrorCheckpoint(name string) (uint64, error) {
+	return s.wdb.GetMirrorCheckpoint(name)
+}
+
+func (s *Store) SetMirrorCheckpoint(name string, value uint64) error {
+	return s.wdb.SetMirrorCheckpoint(name, value)
+}
+
+func (s *Store) MarkMirrorChannel(channelId string, channelType uint8, messageSeq uint64) error {
+	return s.wdb.MarkMirrorChannel(channelId, channelType, messageSeq)
+}
+
+func (s *Store) MarkMirrorChannels(channels []wkdb.MirrorChannel) error {
+	return s.wdb.MarkMirrorChannels(channels)
+}
+
+func (s *Store) GetMirrorChannels(startChannelId string, startChannelType uint8, limit int) ([]wkdb.MirrorChannel, error) {
+	return s.wdb.GetMirrorChannels(startChannelId, startChannelType, limit)
+}
+
+func (s *Store) RemoveMirrorChannel(channelId string, channelType uint8, messageSeq uint64) error {
+	return s.wdb.RemoveMirrorChannel(channelId, channelType, messageSeq)
+}
+
+// RotateDataKeys 轮换本节点的消息加密数据密钥
+func (s *Store) RotateDataKeys() error {
+	return s.wdb.RotateDataKeys()
+}
+
+// EncryptionStatus 本节点的静态加密状态
+func (s *Store) EncryptionStatus() (wkdb.EncryptionStatus, error) {
+	return s.wdb.EncryptionStatus()
+}
+
 func (s *Store) GetMessageShardLogStorage() *MessageShardLogStorage {
 	return s.messageShardLogStorage
 }
@@ -152,6 +236,17 @@ func (s *Store) SearchMessages(req wkdb.MessageSearchReq) ([]wkdb.Message, error
 	return s.wdb.SearchMessages(req)
 }
 
+// GetMessagesByFromUid 获取本节点上指定发送者的消息
+func (s *Store) GetMessagesByFromUid(fromUid string) ([]wkdb.Message, error) {
+	return s.wdb.GetMessagesByFromUid(fromUid)
+}
+
+// EraseMessagesByFromUid 擦除本节点上指定发送者的消息，返回擦除的消息数量
+// 消息存储在频道副本所在的节点上，需要在每个节点上分别调用
+func (s *Store) EraseMessagesByFromUid(fromUid string) (int, error) {
+	return s.wdb.EraseMessagesByFromUid(fromUid)
+}
+
 // 获取频道的槽id
 func (s *Store) getChannelSlotId(channelId string) uint32 {
 	return wkutil.GetSlotNum(int(s.opts.SlotCount), channelId)
@@ -0,0 +1,80 @@
+package clusterstore
+
+import "go.uber.org/zap"
+
+// ProposeMirrorSlotLogs 备集群将主集群镜像过来的槽日志提案到同一个槽，日志和镜像进度在同一个提案里应用
+func (s *Store) ProposeMirrorSlotLogs(slotId uint32, name string, logs []MirrorLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	cmdData, err := NewCMD(CMDMirrorSlotLogs, EncodeCMDMirrorSlotLogs(name, logs)).Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// SaveMirrorCheckpoint 保存镜像进度（随槽复制），进度只会变大
+func (s *Store) SaveMirrorCheckpoint(slotId uint32, name string, value uint64) error {
+	cmdData, err := NewCMD(CMDSaveMirrorCheckpoint, EncodeCMDSaveMirrorCheckpoint(name, value)).Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+func (s *Store) handleMirrorSlotLogs(cmd *CMD) error {
+	name, logs, err := cmd.DecodeCMDMirrorSlotLogs()
+	if err != nil {
+		return err
+	}
+	s.mirrorMu.Lock()
+	defer s.mirrorMu.Unlock()
+
+	checkpoint, err := s.wdb.GetMirrorCheckpoint(name)
+	if err != nil {
+		return err
+	}
+	applied := checkpoint
+	for _, lg := range logs {
+		if lg.Index <= applied { // 重复发送的日志跳过
+			continue
+		}
+		if len(lg.Data) > 0 {
+			mirrorCmd := &CMD{}
+			if err = mirrorCmd.Unmarshal(lg.Data); err != nil {
+				s.Error("unmarshal mirror cmd err", zap.Error(err), zap.String("name", name), zap.Uint64("index", lg.Index))
+				return err
+			}
+			if err = s.execCMD(mirrorCmd); err != nil {
+				s.Error("exec mirror cmd err", zap.Error(err), zap.String("name", name), zap.Uint64("index", lg.Index), zap.String("cmdType", mirrorCmd.CmdType.String()))
+				return err
+			}
+		}
+		applied = lg.Index
+	}
+	if applied == checkpoint {
+		return nil
+	}
+	return s.wdb.SetMirrorCheckpoint(name, applied)
+}
+
+func (s *Store) handleSaveMirrorCheckpoint(cmd *CMD) error {
+	name, value, err := cmd.DecodeCMDSaveMirrorCheckpoint()
+	if err != nil {
+		return err
+	}
+	s.mirrorMu.Lock()
+	defer s.mirrorMu.Unlock()
+
+	checkpoint, err := s.wdb.GetMirrorCheckpoint(name)
+	if err != nil {
+		return err
+	}
+	if value <= checkpoint {
+		return nil
+	}
+	return s.wdb.SetMirrorCheckpoint(name, value)
+}
@@ -0,0 +1,67 @@
+package clusterstore_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterstore"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirrorSlotLogsApply(t *testing.T) {
+	s := clusterstore.NewStore(clusterstore.NewOptions(1, clusterstore.WithDataDir(t.TempDir())))
+	err := s.Open()
+	assert.NoError(t, err)
+	defer s.Close()
+
+	conversationCmd := func(channelId string) []byte {
+		data, err := clusterstore.EncodeCMDAddOrUpdateConversations("u1", []wkdb.Conversation{{Id: 1, Uid: "u1", ChannelId: channelId, ChannelType: 2}})
+		assert.NoError(t, err)
+		cmdData, err := clusterstore.NewCMD(clusterstore.CMDAddOrUpdateConversations, data).Marshal()
+		assert.NoError(t, err)
+		return cmdData
+	}
+	mirrorCmd := func(logs []clusterstore.MirrorLog) []byte {
+		cmdData, err := clusterstore.NewCMD(clusterstore.CMDMirrorSlotLogs, clusterstore.EncodeCMDMirrorSlotLogs("slot:1", logs)).Marshal()
+		assert.NoError(t, err)
+		return cmdData
+	}
+
+	err = s.OnMetaApply(1, []replica.Log{{Index: 1, Data: mirrorCmd([]clusterstore.MirrorLog{
+		{Index: 10, Data: conversationCmd("g1")},
+		{Index: 11},
+	})}})
+	assert.NoError(t, err)
+
+	checkpoint, err := s.GetMirrorCheckpoint("slot:1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(11), checkpoint)
+	version, err := s.GetConversationVersion("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), version)
+
+	// 重复发送的日志跳过，只应用新的日志
+	err = s.OnMetaApply(1, []replica.Log{{Index: 2, Data: mirrorCmd([]clusterstore.MirrorLog{
+		{Index: 10, Data: conversationCmd("g1")},
+		{Index: 11},
+		{Index: 12, Data: conversationCmd("g2")},
+	})}})
+	assert.NoError(t, err)
+
+	checkpoint, err = s.GetMirrorCheckpoint("slot:1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(12), checkpoint)
+	version, err = s.GetConversationVersion("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), version)
+
+	// 镜像进度只会变大
+	cmdData, err := clusterstore.NewCMD(clusterstore.CMDSaveMirrorCheckpoint, clusterstore.EncodeCMDSaveMirrorCheckpoint("slot:1", 5)).Marshal()
+	assert.NoError(t, err)
+	err = s.OnMetaApply(1, []replica.Log{{Index: 3, Data: cmdData}})
+	assert.NoError(t, err)
+	checkpoint, err = s.GetMirrorCheckpoint("slot:1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(12), checkpoint)
+}
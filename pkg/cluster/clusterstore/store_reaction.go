@@ -0,0 +1,42 @@
+package clusterstore
+
+import "github.com/WuKongIM/WuKongIM/pkg/wkdb"
+
+// AddOrUpdateReactions 添加或更新消息回应（随频道所在的槽复制）
+func (s *Store) AddOrUpdateReactions(channelId string, channelType uint8, reactions []wkdb.Reaction) error {
+	if len(reactions) == 0 {
+		return nil
+	}
+	data, err := EncodeCMDAddOrUpdateReactions(channelId, channelType, reactions)
+	if err != nil {
+		return err
+	}
+	cmd := NewCMD(CMDAddOrUpdateReactions, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(channelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+func (s *Store) GetReactions(channelId string, channelType uint8, messageSeq uint64) ([]wkdb.Reaction, error) {
+	return s.wdb.GetReactions(channelId, channelType, messageSeq)
+}
+
+func (s *Store) GetReactionSummary(channelId string, channelType uint8, messageSeq uint64) (wkdb.ReactionSummary, error) {
+	return s.wdb.GetReactionSummary(channelId, channelType, messageSeq)
+}
+
+func (s *Store) GetUserReactions(channelId string, channelType uint8, messageSeq uint64, uid string) ([]wkdb.Reaction, error) {
+	return s.wdb.GetUserReactions(channelId, channelType, messageSeq, uid)
+}
+
+func (s *Store) GetReactionsByVersion(channelId string, channelType uint8, version uint64, limit int) ([]wkdb.Reaction, error) {
+	return s.wdb.GetReactionsByVersion(channelId, channelType, version, limit)
+}
+
+func (s *Store) GetReactionVersion(channelId string, channelType uint8) (uint64, error) {
+	return s.wdb.GetReactionVersion(channelId, channelType)
+}
@@ -37,6 +37,24 @@ func (s *Store) UpdateUser(u wkdb.User) error {
 	return err
 }
 
+// DeleteUser 删除用户，同时删除用户的设备和最近会话
+func (s *Store) DeleteUser(uid string) error {
+	data := EncodeCMDDeleteUser(uid)
+	cmd := NewCMD(CMDDeleteUser, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+func (s *Store) GetDevices(uid string) ([]wkdb.Device, error) {
+	return s.wdb.GetDevices(uid)
+}
+
 func (s *Store) UpdateDevice(d wkdb.Device) error {
 	data := EncodeCMDDevice(d)
 	cmd := NewCMD(CMDUpdateDevice, data)
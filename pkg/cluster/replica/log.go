@@ -2,6 +2,7 @@ package replica
 
 import (
 	"fmt"
+	"sync/atomic"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"go.uber.org/zap"
@@ -17,7 +18,8 @@ type replicaLog struct {
 	storagingIndex uint64 // 正在存储中的日志下标
 	storagedIndex  uint64 // 已存储的日志下标
 
-	committedIndex uint64 // 已提交的日志下标
+	committedIndex       uint64        // 已提交的日志下标
+	atomicCommittedIndex atomic.Uint64 // 已提交的日志下标（其他协程可读）
 
 	applyingIndex uint64 // 正在应用的日志下标
 
@@ -45,7 +47,7 @@ func newReplicaLog(opts *Options) *replicaLog {
 		rg.Panic("last index is less than applied index", zap.Uint64("lastIndex", opts.LastIndex), zap.Uint64("appliedIndex", opts.AppliedIndex))
 	}
 
-	rg.committedIndex = opts.AppliedIndex
+	rg.setCommittedIndex(opts.AppliedIndex)
 	rg.appliedIndex = opts.AppliedIndex
 	rg.applyingIndex = opts.AppliedIndex
 
@@ -62,7 +64,7 @@ func (r *replicaLog) updateLastIndex(lastIndex uint64) {
 	r.unstable.offsetInProgress = lastIndex + 1
 
 	if r.committedIndex > lastIndex {
-		r.committedIndex = lastIndex
+		r.setCommittedIndex(lastIndex)
 	}
 
 }
@@ -119,7 +121,12 @@ func (r *replicaLog) committedTo(index uint64) {
 	if index < r.committedIndex {
 		r.Panic("commit index less than committed index", zap.Uint64("commitIndex", index), zap.Uint64("committedIndex", r.committedIndex))
 	}
+	r.setCommittedIndex(index)
+}
+
+func (r *replicaLog) setCommittedIndex(index uint64) {
 	r.committedIndex = index
+	r.atomicCommittedIndex.Store(index)
 }
 
 func (r *replicaLog) applyingTo(index uint64) {
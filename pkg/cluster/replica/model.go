@@ -443,7 +443,8 @@ type Config struct {
 	Version     uint64   // 配置版本
 
 	// 不参与编码
-	Leader uint64 // 领导ID
+	Leader  uint64  // 领导ID
+	AckMode AckMode // 确认方式，AckModeDefault表示使用Options的配置
 }
 
 func NewConfig() *Config {
@@ -512,7 +513,7 @@ func (c *Config) Unmarshal(data []byte) error {
 }
 
 func (c *Config) String() string {
-	return fmt.Sprintf("Config{MigrateFrom:%d,MigrateTo:%d,Replicas:%v,Learners:%v,Role:%s,Term:%d,Version:%d,Leader:%d}", c.MigrateFrom, c.MigrateTo, c.Replicas, c.Learners, c.Role, c.Term, c.Version, c.Leader)
+	return fmt.Sprintf("Config{MigrateFrom:%d,MigrateTo:%d,Replicas:%v,Learners:%v,Role:%s,Term:%d,Version:%d,Leader:%d,AckMode:%d}", c.MigrateFrom, c.MigrateTo, c.Replicas, c.Learners, c.Role, c.Term, c.Version, c.Leader, c.AckMode)
 
 }
 
@@ -52,8 +52,10 @@ func NewOptions() *Options {
 type AckMode int
 
 const (
+	// AckModeDefault 未指定，使用Options里的确认方式
+	AckModeDefault AckMode = iota
 	// AckModeNone 不需要其他节点确认，只需要本节点确认
-	AckModeNone AckMode = iota
+	AckModeNone
 	// AckModeMajority 大多数节点确认
 	AckModeMajority
 	// AckModeAll 所有节点确认
@@ -2,6 +2,8 @@ package replica
 
 import (
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
@@ -49,6 +51,9 @@ type Replica struct {
 	voteFor                   uint64          // 投票给谁
 	votes                     map[uint64]bool // 投票记录
 
+	// -------------------- 从副本读取 --------------------
+	leaderCommittedIndex atomic.Uint64 // 最后一次从领导收到的已提交下标（其他协程可读）
+	leaderContactAt      atomic.Int64  // 最后一次收到领导消息的时间（纳秒）
 }
 
 func New(nodeId uint64, optList ...Option) *Replica {
@@ -223,6 +228,36 @@ func (r *Replica) Term() uint32 {
 	return r.term
 }
 
+// CommittedIndex 本副本已提交的日志下标，可以在其他协程调用
+func (r *Replica) CommittedIndex() uint64 {
+	return r.replicaLog.atomicCommittedIndex.Load()
+}
+
+// LeaderCommittedIndex 最后一次从领导收到的已提交下标，可以在其他协程调用
+func (r *Replica) LeaderCommittedIndex() uint64 {
+	return r.leaderCommittedIndex.Load()
+}
+
+// LastLeaderContact 追随者最后一次收到领导消息的时间，还没收到过或者不是追随者时返回零值，可以在其他协程调用
+func (r *Replica) LastLeaderContact() time.Time {
+	at := r.leaderContactAt.Load()
+	if at == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, at)
+}
+
+// 记录领导的提交下标和联系时间，日志冲突检查完成后本地日志才和领导一致
+func (r *Replica) updateLeaderContact(leaderCommittedIndex uint64) {
+	if r.status != StatusReady {
+		return
+	}
+	if leaderCommittedIndex > r.leaderCommittedIndex.Load() {
+		r.leaderCommittedIndex.Store(leaderCommittedIndex)
+	}
+	r.leaderContactAt.Store(time.Now().UnixNano())
+}
+
 func (r *Replica) switchConfig(cfg Config) {
 
 	if r.cfg.Version > cfg.Version {
@@ -350,6 +385,7 @@ func (r *Replica) becomeFollower(term uint32, leaderID uint64) {
 	r.term = term
 	r.leader = leaderID
 	r.role = RoleFollower
+	r.leaderContactAt.Store(0) // 领导变了，之前的同步状态不可用
 
 	r.Info("become follower", zap.Uint32("term", term), zap.Uint64("leader", leaderID))
 
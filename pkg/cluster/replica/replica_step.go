@@ -1,6 +1,7 @@
 package replica
 
 import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	"go.uber.org/zap"
 )
 
@@ -133,7 +134,7 @@ func (r *Replica) stepLeader(m Message) error {
 		if !r.appendLog(m.Logs...) {
 			return ErrProposalDropped
 		}
-		if r.isSingleNode() || r.opts.AckMode == AckModeNone { // 单机
+		if r.isSingleNode() || r.ackMode() == AckModeNone { // 单机或不需要确认
 			r.Debug("no ack", zap.Uint64("nodeId", r.nodeId), zap.Uint32("term", r.term), zap.Uint64("lastLogIndex", r.replicaLog.lastLogIndex), zap.Uint64("committedIndex", r.replicaLog.committedIndex))
 			r.updateLeaderCommittedIndex() // 更新领导的提交索引
 		}
@@ -248,6 +249,7 @@ func (r *Replica) stepFollower(m Message) error {
 		r.send(r.newPong(m.From))
 		// r.Debug("recv ping", zap.Uint64("nodeID", r.nodeID), zap.Uint32("term", m.Term), zap.Uint64("from", m.From), zap.Uint64("to", m.To), zap.Uint64("lastLogIndex", r.replicaLog.lastLogIndex), zap.Uint64("leaderCommittedIndex", m.CommittedIndex), zap.Uint64("committedIndex", r.replicaLog.committedIndex))
 		r.updateFollowCommittedIndex(m.CommittedIndex) // 更新提交索引
+		r.updateLeaderContact(m.CommittedIndex)
 	case MsgLogConflictCheckResp: // 日志冲突检查返回
 		if !m.Reject {
 			// r.Info("follower: truncate log to", zap.Uint64("leader", r.leader), zap.Uint32("term", r.term), zap.Uint64("index", m.Index), zap.Uint64("lastIndex", r.replicaLog.lastLogIndex))
@@ -290,6 +292,7 @@ func (r *Replica) stepFollower(m Message) error {
 			r.syncTick = 0
 		}
 		r.updateFollowCommittedIndex(m.CommittedIndex) // 更新提交索引
+		r.updateLeaderContact(m.CommittedIndex)
 
 	}
 	return nil
@@ -446,7 +449,7 @@ func (r *Replica) updateFollowCommittedIndex(leaderCommittedIndex uint64) {
 	}
 	newCommittedIndex := r.committedIndexForFollow(leaderCommittedIndex)
 	if newCommittedIndex > r.replicaLog.committedIndex {
-		r.replicaLog.committedIndex = newCommittedIndex
+		r.replicaLog.setCommittedIndex(newCommittedIndex)
 		r.Debug("update follow committed index", zap.Uint64("nodeId", r.nodeId), zap.Uint32("term", r.term), zap.Uint64("committedIndex", r.replicaLog.committedIndex))
 	}
 }
@@ -465,7 +468,7 @@ func (r *Replica) updateLeaderCommittedIndex() bool {
 	newCommitted := r.committedIndexForLeader() // 通过副本同步信息计算领导已提交下标
 	updated := false
 	if newCommitted > r.replicaLog.committedIndex {
-		r.replicaLog.committedIndex = newCommitted
+		r.replicaLog.setCommittedIndex(newCommitted)
 		updated = true
 		r.Debug("update leader committed index", zap.Uint64("lastIndex", r.replicaLog.lastLogIndex), zap.Uint32("term", r.term), zap.Uint64("committedIndex", r.replicaLog.committedIndex))
 	}
@@ -476,8 +479,15 @@ func (r *Replica) updateLeaderCommittedIndex() bool {
 func (r *Replica) committedIndexForLeader() uint64 {
 
 	committed := r.replicaLog.committedIndex
+	ackMode := r.ackMode()
 	quorum := r.quorum() // r.replicas 不包含本节点
-	if quorum <= 1 {     // 如果少于或等于一个节点，那么直接返回最后一条日志下标
+	switch ackMode {
+	case AckModeAll:
+		quorum = len(r.replicas) + 1
+	case AckModeNone:
+		quorum = 1
+	}
+	if quorum <= 1 { // 如果少于或等于一个节点，那么直接返回最后一条日志下标
 		return r.replicaLog.lastLogIndex
 	}
 
@@ -511,8 +521,12 @@ func (r *Replica) committedIndexForLeader() uint64 {
 		if maxLogIndex > r.replicaLog.lastLogIndex {
 			continue
 		}
-		for _, syncInfo := range r.lastSyncInfoMap {
-			if syncInfo.LastSyncIndex-1 >= maxLogIndex {
+		for nodeId, syncInfo := range r.lastSyncInfoMap {
+			if ackMode == AckModeAll && !wkutil.ArrayContainsUint64(r.replicas, nodeId) { // 所有节点确认时只统计副本，学习者不算
+				continue
+			}
+			if syncInfo.LastSyncIndex > maxLogIndex { // LastSyncIndex为下次要同步的下标，还没同步过的为0
+
 				count++
 			}
 			if count+1 >= quorum {
@@ -543,6 +557,14 @@ func (r *Replica) updateReplicSyncInfo(m Message) {
 	syncInfo.SyncTick = 0
 }
 
+// 当前生效的确认方式，配置里指定的优先
+func (r *Replica) ackMode() AckMode {
+	if r.cfg.AckMode != AckModeDefault {
+		return r.cfg.AckMode
+	}
+	return r.opts.AckMode
+}
+
 func (r *Replica) quorum() int {
 	return (len(r.replicas)+1)/2 + 1 //  r.replicas 不包含本节点
 }
@@ -521,3 +521,80 @@ func TestFollowerToLeader(t *testing.T) {
 	assert.True(t, hasMsg(rd.Messages, MsgSyncResp))
 	assert.True(t, hasMsg(rd.Messages, MsgFollowerToLeader))
 }
+
+// 测试不同确认方式下领导的提交
+func TestCommittedIndexWithAckMode(t *testing.T) {
+	newLeader := func(ackMode AckMode) *Replica {
+		r := New(1)
+		_ = r.Ready()
+		err := r.Step(Message{
+			MsgType: MsgInitResp,
+			Config: Config{
+				Role:     RoleLeader,
+				Term:     1,
+				Replicas: []uint64{1, 2, 3},
+				AckMode:  ackMode,
+			},
+		})
+		assert.NoError(t, err)
+		assert.True(t, r.isLeader())
+		r.replicaLog.appendLog(Log{Index: 1, Term: 1, Data: []byte("hello")})
+		return r
+	}
+
+	// 大多数确认，一个追随者同步后即可提交
+	r := newLeader(AckModeDefault)
+	r.lastSyncInfoMap[2].LastSyncIndex = 2
+	assert.Equal(t, uint64(1), r.committedIndexForLeader())
+
+	// 所有副本确认，需要所有追随者都同步后才能提交
+	r = newLeader(AckModeAll)
+	r.lastSyncInfoMap[2].LastSyncIndex = 2
+	assert.Equal(t, uint64(0), r.committedIndexForLeader())
+	r.lastSyncInfoMap[3].LastSyncIndex = 2
+	assert.Equal(t, uint64(1), r.committedIndexForLeader())
+
+	// 不需要确认，提案后直接提交
+	r = newLeader(AckModeNone)
+	err := r.Step(Message{
+		MsgType: MsgPropose,
+		Logs:    []Log{{Index: 2, Term: 1, Data: []byte("world")}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), r.replicaLog.committedIndex)
+	assert.Equal(t, uint64(2), r.CommittedIndex())
+}
+
+// 测试追随者记录领导的提交下标和联系时间
+func TestFollowerLeaderContact(t *testing.T) {
+	follower := New(2)
+	initReplica(follower, Config{
+		Role:   RoleFollower,
+		Term:   1,
+		Leader: 1,
+	}, t)
+	assert.True(t, follower.LastLeaderContact().IsZero())
+
+	err := follower.Step(Message{
+		MsgType:        MsgPing,
+		From:           1,
+		To:             2,
+		Term:           1,
+		CommittedIndex: 10,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), follower.LeaderCommittedIndex())
+	assert.False(t, follower.LastLeaderContact().IsZero())
+
+	// 领导变更后，之前的联系时间不可用，直到收到新领导的消息
+	follower.becomeFollower(2, 3)
+	assert.True(t, follower.LastLeaderContact().IsZero())
+	err = follower.Step(Message{
+		MsgType: MsgPing,
+		From:    3,
+		To:      2,
+		Term:    2,
+	})
+	assert.NoError(t, err)
+	assert.False(t, follower.LastLeaderContact().IsZero())
+}
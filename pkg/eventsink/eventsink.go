@@ -0,0 +1,45 @@
+package eventsink
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// 事件的元数据在消息头（kafka、nats）里的名称
+const (
+	HeaderEventId        = "WuKongIM-Event-Id"
+	HeaderEventType      = "WuKongIM-Event-Type"
+	HeaderEventKey       = "WuKongIM-Event-Key"
+	HeaderEventTimestamp = "WuKongIM-Event-Timestamp"
+)
+
+// Event 投递到消息中间件的事件
+type Event struct {
+	Id        uint64 // 事件id，单调递增，消费方可以用来去重（至少一次投递，可能会重复）
+	Type      string // 事件类型 例如：msg.notify、channel.create
+	Key       string // 分区键，相同分区键的事件保证顺序（一般为频道）
+	Timestamp int64  // 事件产生的时间（毫秒）
+	Data      []byte // 事件数据（json）
+}
+
+// EventSink 事件投递的目标
+type EventSink interface {
+	// Name 名称，用于保存投递进度，不能重复
+	Name() string
+	// Start 连接消息中间件
+	Start() error
+	// Stop 断开连接
+	Stop()
+	// Publish 按顺序投递一批事件，返回nil表示所有事件都已被消息中间件确认
+	Publish(ctx context.Context, events []Event) error
+}
+
+// Partition 分区键对应的分区
+func Partition(key string, partitions int) int {
+	if partitions <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(partitions))
+}
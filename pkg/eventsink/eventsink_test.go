@@ -0,0 +1,146 @@
+package eventsink
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/alicebob/miniredis/v2"
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func testEvents(n int) []Event {
+	events := make([]Event, 0, n)
+	for i := 0; i < n; i++ {
+		events = append(events, Event{
+			Id:        uint64(i + 1),
+			Type:      "msg.notify",
+			Key:       fmt.Sprintf("ch%d-2", i%2),
+			Timestamp: time.Now().UnixMilli(),
+			Data:      []byte(fmt.Sprintf(`{"seq":%d}`, i)),
+		})
+	}
+	return events
+}
+
+func TestPartition(t *testing.T) {
+	assert.Equal(t, 0, Partition("ch1-2", 0))
+	assert.Equal(t, 0, Partition("ch1-2", 1))
+	p := Partition("ch1-2", 8)
+	assert.True(t, p >= 0 && p < 8)
+	assert.Equal(t, p, Partition("ch1-2", 8))
+}
+
+func TestNatsSink(t *testing.T) {
+	srv, err := natsserver.NewServer(&natsserver.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	assert.NoError(t, err)
+	srv.Start()
+	defer srv.Shutdown()
+	assert.True(t, srv.ReadyForConnections(time.Second*5))
+
+	sink := NewNatsSink(NatsOptions{URL: srv.ClientURL(), Stream: "WUKONGIM", Subject: "wukongim.events", Partitions: 4})
+	err = sink.Start()
+	assert.NoError(t, err)
+	defer sink.Stop()
+
+	events := testEvents(10)
+	err = sink.Publish(context.Background(), events)
+	assert.NoError(t, err)
+
+	// 重复投递会被去重
+	err = sink.Publish(context.Background(), events[:3])
+	assert.NoError(t, err)
+
+	nc, err := nats.Connect(srv.ClientURL())
+	assert.NoError(t, err)
+	defer nc.Close()
+	js, err := nc.JetStream()
+	assert.NoError(t, err)
+	info, err := js.StreamInfo("WUKONGIM")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(len(events)), info.State.Msgs)
+
+	// 同一个分区键的事件按顺序投递
+	sub, err := js.SubscribeSync(sink.Subject(events[0].Key), nats.DeliverAll())
+	assert.NoError(t, err)
+	var last uint64
+	for i := 0; i < len(events)/2; i++ {
+		msg, err := sub.NextMsg(time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, events[0].Key, msg.Header.Get(HeaderEventKey))
+		id, _ := strconv.ParseUint(msg.Header.Get(nats.MsgIdHdr), 10, 64)
+		assert.Greater(t, id, last)
+		last = id
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	metadata := sarama.NewMockMetadataResponse(t).
+		SetBroker(broker.Addr(), broker.BrokerID()).
+		SetLeader("events", 0, broker.BrokerID())
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest":    metadata,
+		"ProduceRequest":     sarama.NewMockProduceResponse(t).SetVersion(3), // 默认配置下的ProduceRequest版本
+	})
+
+	sink := NewKafkaSink(KafkaOptions{Brokers: []string{broker.Addr()}, Topic: "events"})
+	err := sink.Start()
+	assert.NoError(t, err)
+	defer sink.Stop()
+
+	err = sink.Publish(context.Background(), testEvents(5))
+	assert.NoError(t, err)
+
+	// broker返回错误时投递失败
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest":    metadata,
+		"ProduceRequest":     sarama.NewMockProduceResponse(t).SetVersion(3).SetError("events", 0, sarama.ErrNotEnoughReplicas),
+	})
+	err = sink.Publish(context.Background(), testEvents(1))
+	assert.Error(t, err)
+}
+
+func TestRedisSink(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	sink := NewRedisSink(RedisOptions{Addr: mr.Addr(), Stream: "wukongim:events", Partitions: 4})
+	err := sink.Start()
+	assert.NoError(t, err)
+	defer sink.Stop()
+
+	events := testEvents(10)
+	err = sink.Publish(context.Background(), events)
+	assert.NoError(t, err)
+
+	entries, err := mr.Stream(sink.Stream(events[0].Key))
+	assert.NoError(t, err)
+	assert.Equal(t, len(events)/2, len(entries))
+	var last uint64
+	for _, entry := range entries {
+		values := entry.Values
+		assert.Equal(t, "key", values[4])
+		assert.Equal(t, events[0].Key, values[5])
+		id, _ := strconv.ParseUint(values[1], 10, 64)
+		assert.Greater(t, id, last)
+		last = id
+	}
+
+	mr.Close()
+	err = sink.Publish(context.Background(), events)
+	assert.Error(t, err)
+}
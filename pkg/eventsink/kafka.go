@@ -0,0 +1,70 @@
+package eventsink
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+type KafkaOptions struct {
+	Brokers []string // kafka地址
+	Topic   string   // 主题，事件按分区键hash到主题的分区上
+	Timeout time.Duration
+}
+
+// KafkaSink 投递事件到kafka，等待所有副本确认（acks=all）
+type KafkaSink struct {
+	opts     KafkaOptions
+	producer sarama.SyncProducer
+}
+
+func NewKafkaSink(opts KafkaOptions) *KafkaSink {
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second * 5
+	}
+	return &KafkaSink{opts: opts}
+}
+
+func (k *KafkaSink) Name() string {
+	return "kafka"
+}
+
+func (k *KafkaSink) Start() error {
+	cfg := sarama.NewConfig()
+	cfg.Producer.RequiredAcks = sarama.WaitForAll
+	cfg.Producer.Return.Successes = true
+	cfg.Producer.Partitioner = sarama.NewHashPartitioner
+	cfg.Producer.Timeout = k.opts.Timeout
+	cfg.Net.MaxOpenRequests = 1 // 保证同一个分区内的顺序
+	producer, err := sarama.NewSyncProducer(k.opts.Brokers, cfg)
+	if err != nil {
+		return err
+	}
+	k.producer = producer
+	return nil
+}
+
+func (k *KafkaSink) Stop() {
+	if k.producer != nil {
+		_ = k.producer.Close()
+	}
+}
+
+func (k *KafkaSink) Publish(ctx context.Context, events []Event) error {
+	msgs := make([]*sarama.ProducerMessage, 0, len(events))
+	for _, event := range events {
+		msgs = append(msgs, &sarama.ProducerMessage{
+			Topic: k.opts.Topic,
+			Key:   sarama.StringEncoder(event.Key),
+			Value: sarama.ByteEncoder(event.Data),
+			Headers: []sarama.RecordHeader{
+				{Key: []byte(HeaderEventId), Value: []byte(strconv.FormatUint(event.Id, 10))},
+				{Key: []byte(HeaderEventType), Value: []byte(event.Type)},
+				{Key: []byte(HeaderEventTimestamp), Value: []byte(strconv.FormatInt(event.Timestamp, 10))},
+			},
+		})
+	}
+	return k.producer.SendMessages(msgs)
+}
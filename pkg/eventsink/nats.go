@@ -0,0 +1,104 @@
+package eventsink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type NatsOptions struct {
+	URL        string // nats地址 例如：nats://127.0.0.1:4222
+	Stream     string // JetStream的stream名称，不存在时会自动创建
+	Subject    string // 主题前缀，事件投递到 Subject.分区 上
+	Partitions int    // 分区数量
+	Timeout    time.Duration
+}
+
+// NatsSink 投递事件到NATS JetStream，使用Nats-Msg-Id让服务端在去重窗口内去掉重复投递的事件
+type NatsSink struct {
+	opts NatsOptions
+	nc   *nats.Conn
+	js   nats.JetStreamContext
+}
+
+func NewNatsSink(opts NatsOptions) *NatsSink {
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second * 5
+	}
+	return &NatsSink{opts: opts}
+}
+
+func (n *NatsSink) Name() string {
+	return "nats"
+}
+
+func (n *NatsSink) Start() error {
+	nc, err := nats.Connect(n.opts.URL, nats.MaxReconnects(-1))
+	if err != nil {
+		return err
+	}
+	js, err := nc.JetStream(nats.MaxWait(n.opts.Timeout))
+	if err != nil {
+		nc.Close()
+		return err
+	}
+	if n.opts.Stream != "" {
+		_, err = js.StreamInfo(n.opts.Stream)
+		if errors.Is(err, nats.ErrStreamNotFound) {
+			_, err = js.AddStream(&nats.StreamConfig{
+				Name:     n.opts.Stream,
+				Subjects: []string{n.opts.Subject + ".>"},
+				Storage:  nats.FileStorage,
+			})
+		}
+		if err != nil {
+			nc.Close()
+			return err
+		}
+	}
+	n.nc = nc
+	n.js = js
+	return nil
+}
+
+func (n *NatsSink) Stop() {
+	if n.nc != nil {
+		n.nc.Close()
+	}
+}
+
+func (n *NatsSink) Publish(ctx context.Context, events []Event) error {
+	futures := make([]nats.PubAckFuture, 0, len(events))
+	for _, event := range events {
+		msg := nats.NewMsg(n.Subject(event.Key))
+		msg.Data = event.Data
+		msg.Header.Set(nats.MsgIdHdr, strconv.FormatUint(event.Id, 10))
+		msg.Header.Set(HeaderEventType, event.Type)
+		msg.Header.Set(HeaderEventKey, event.Key)
+		msg.Header.Set(HeaderEventTimestamp, strconv.FormatInt(event.Timestamp, 10))
+		future, err := n.js.PublishMsgAsync(msg)
+		if err != nil {
+			return err
+		}
+		futures = append(futures, future)
+	}
+	for _, future := range futures {
+		select {
+		case <-future.Ok():
+		case err := <-future.Err():
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Subject 分区键对应的主题
+func (n *NatsSink) Subject(key string) string {
+	return fmt.Sprintf("%s.%d", n.opts.Subject, Partition(key, n.opts.Partitions))
+}
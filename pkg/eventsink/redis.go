@@ -0,0 +1,83 @@
+package eventsink
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisOptions struct {
+	Addr       string // redis地址
+	Password   string
+	DB         int
+	Stream     string // stream名称前缀，事件写入到 Stream:分区 上
+	Partitions int    // 分区数量
+	MaxLen     int64  // 每个stream保留的最大长度（近似值），0表示不限制
+}
+
+// RedisSink 投递事件到redis stream
+type RedisSink struct {
+	opts   RedisOptions
+	client *redis.Client
+}
+
+func NewRedisSink(opts RedisOptions) *RedisSink {
+	return &RedisSink{opts: opts}
+}
+
+func (r *RedisSink) Name() string {
+	return "redis"
+}
+
+func (r *RedisSink) Start() error {
+	r.client = redis.NewClient(&redis.Options{
+		Addr:     r.opts.Addr,
+		Password: r.opts.Password,
+		DB:       r.opts.DB,
+	})
+	return r.client.Ping(context.Background()).Err()
+}
+
+func (r *RedisSink) Stop() {
+	if r.client != nil {
+		_ = r.client.Close()
+	}
+}
+
+func (r *RedisSink) Publish(ctx context.Context, events []Event) error {
+	pipe := r.client.Pipeline()
+	for _, event := range events {
+		args := &redis.XAddArgs{
+			Stream: r.Stream(event.Key),
+			Values: []interface{}{
+				"id", strconv.FormatUint(event.Id, 10),
+				"type", event.Type,
+				"key", event.Key,
+				"timestamp", event.Timestamp,
+				"data", event.Data,
+			},
+		}
+		if r.opts.MaxLen > 0 {
+			args.MaxLen = r.opts.MaxLen
+			args.Approx = true
+		}
+		pipe.XAdd(ctx, args)
+	}
+	cmds, err := pipe.Exec(ctx)
+	if err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		if cmd.Err() != nil {
+			return cmd.Err()
+		}
+	}
+	return nil
+}
+
+// Stream 分区键对应的stream
+func (r *RedisSink) Stream(key string) string {
+	return fmt.Sprintf("%s:%d", r.opts.Stream, Partition(key, r.opts.Partitions))
+}
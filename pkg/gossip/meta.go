@@ -4,41 +4,55 @@ import (
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 )
 
-// nodeMeta 数据的大小不能超过 memberlist.MetaMaxSize
-type nodeMeta struct {
-	version      uint32     // meta数据的版本号
-	dataVersion  uint64     // 当前节点数据的版本号
-	role         ServerRole // 当前节点的角色
-	currentEpoch uint32     // 当前选举周期
+// NodeMeta 节点元数据，通过gossip传播
+// 编码后的数据大小不能超过 memberlist.MetaMaxSize
+type NodeMeta struct {
+	Version       uint32 // meta数据的版本号
+	ClusterAddr   string // 节点之间通讯地址
+	ApiServerAddr string // 节点api地址
+	Role          uint32 // 节点角色
+	Joined        bool   // 是否已加入集群（拥有集群配置）
+	Load          uint64 // 节点负载（例如连接数）
 }
 
-func (n *nodeMeta) encode() []byte {
+func (n *NodeMeta) encode() []byte {
 	encode := wkproto.NewEncoder()
 	defer encode.End()
-	encode.WriteUint32(n.version)
-	encode.WriteUint64(n.dataVersion)
-	encode.WriteUint8(uint8(n.role))
-	encode.WriteUint32(n.currentEpoch)
+	encode.WriteUint32(n.Version)
+	encode.WriteString(n.ClusterAddr)
+	encode.WriteString(n.ApiServerAddr)
+	encode.WriteUint32(n.Role)
+	if n.Joined {
+		encode.WriteUint8(1)
+	} else {
+		encode.WriteUint8(0)
+	}
+	encode.WriteUint64(n.Load)
 	return encode.Bytes()
 }
 
-func decodeNodeMeta(data []byte) (*nodeMeta, error) {
-	n := &nodeMeta{}
+func decodeNodeMeta(data []byte) (*NodeMeta, error) {
+	n := &NodeMeta{}
 	decode := wkproto.NewDecoder(data)
 	var err error
-	if n.version, err = decode.Uint32(); err != nil {
+	if n.Version, err = decode.Uint32(); err != nil {
 		return nil, err
 	}
-	if n.dataVersion, err = decode.Uint64(); err != nil {
+	if n.ClusterAddr, err = decode.String(); err != nil {
 		return nil, err
 	}
-	role := uint8(0)
-	if role, err = decode.Uint8(); err != nil {
+	if n.ApiServerAddr, err = decode.String(); err != nil {
 		return nil, err
 	}
-	n.role = ServerRole(role)
-
-	if n.currentEpoch, err = decode.Uint32(); err != nil {
+	if n.Role, err = decode.Uint32(); err != nil {
+		return nil, err
+	}
+	var joined uint8
+	if joined, err = decode.Uint8(); err != nil {
+		return nil, err
+	}
+	n.Joined = joined == 1
+	if n.Load, err = decode.Uint64(); err != nil {
 		return nil, err
 	}
 	return n, nil
@@ -11,6 +11,7 @@ type Options struct {
 	Heartbeat      time.Duration
 	OnLeaderChange func(leaderID uint64)
 	OnNodeEvent    func(event NodeEvent) // 节点事件
+	Meta           NodeMeta              // 本节点的元数据
 }
 
 func NewOptions() *Options {
@@ -43,12 +44,26 @@ func WithOnNodeEvent(fnc func(event NodeEvent)) Option {
 	}
 }
 
+func WithAdvertiseAddr(addr string) Option {
+	return func(o *Options) {
+		o.AdvertiseAddr = addr
+	}
+}
+
+func WithMeta(meta NodeMeta) Option {
+	return func(o *Options) {
+		o.Meta = meta
+	}
+}
+
 type NodeEventType int
 
 const (
 	NodeEventJoin NodeEventType = iota
 	NodeEventLeave
 	NodeEventUpdate
+	NodeEventSuspect // 节点被怀疑故障
+	NodeEventAlive   // 节点从怀疑状态恢复
 )
 
 type NodeEvent struct {
@@ -56,4 +71,5 @@ type NodeEvent struct {
 	Addr      string        // 节点地址
 	EventType NodeEventType // 节点事件类型
 	State     NodeStateType // 节点状态
+	Meta      *NodeMeta     // 节点元数据（可能为nil）
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
@@ -19,6 +20,20 @@ type Server struct {
 	wklog.Log
 	opts    *Options
 	stopper *syncutil.Stopper
+
+	metaLock sync.RWMutex
+	meta     NodeMeta // 本节点的元数据
+
+	// 记录每个节点上一次观察到的状态，用于发现怀疑状态
+	stateMap map[uint64]NodeStateType
+}
+
+// Member gossip成员
+type Member struct {
+	NodeID uint64        // 节点ID
+	Addr   string        // gossip地址
+	State  NodeStateType // 节点状态
+	Meta   *NodeMeta     // 节点元数据（可能为nil）
 }
 
 func NewServer(nodeID uint64, listenAddr string, opts ...Option) *Server {
@@ -32,9 +47,11 @@ func NewServer(nodeID uint64, listenAddr string, opts ...Option) *Server {
 	}
 
 	s := &Server{
-		Log:     lg,
-		opts:    defaultOpts,
-		stopper: syncutil.NewStopper(),
+		Log:      lg,
+		opts:     defaultOpts,
+		stopper:  syncutil.NewStopper(),
+		meta:     defaultOpts.Meta,
+		stateMap: make(map[uint64]NodeStateType),
 	}
 
 	s.gossipServer = createMemberlist(s) // 创建gossip服务
@@ -49,6 +66,11 @@ func (s *Server) Start() error {
 		s.loopJoin()
 	})
 
+	// 监听节点状态
+	s.stopper.RunWorker(func() {
+		s.loopState()
+	})
+
 	return nil
 }
 
@@ -91,6 +113,98 @@ func (s *Server) MustWaitJoin(timeout time.Duration) {
 	}
 }
 
+// Join 同步加入种子节点
+func (s *Server) Join() error {
+	needJoins := s.getNeedJoin()
+	if len(needJoins) == 0 {
+		return nil
+	}
+	_, err := s.gossipServer.Join(needJoins)
+	return err
+}
+
+// SetMeta 更新本节点元数据并广播给其他节点（会等待广播完成）
+func (s *Server) SetMeta(meta NodeMeta) {
+	s.metaLock.Lock()
+	meta.Version = s.meta.Version + 1
+	s.meta = meta
+	s.metaLock.Unlock()
+
+	err := s.gossipServer.UpdateNode(time.Second * 5)
+	if err != nil {
+		s.Warn("UpdateNode failed!", zap.Error(err))
+	}
+}
+
+// Meta 本节点元数据
+func (s *Server) Meta() NodeMeta {
+	s.metaLock.RLock()
+	defer s.metaLock.RUnlock()
+	return s.meta
+}
+
+// Members 获取所有存活（包括被怀疑）的成员
+func (s *Server) Members() []Member {
+	nodes := s.gossipServer.Members()
+	members := make([]Member, 0, len(nodes))
+	for _, n := range nodes {
+		members = append(members, Member{
+			NodeID: nameToNodeID(n.Name),
+			Addr:   n.Address(),
+			State:  NodeStateType(n.State),
+			Meta:   nodeMetaOf(n),
+		})
+	}
+	return members
+}
+
+// loopState memberlist的EventDelegate不会通知怀疑状态，这里定时检查成员状态
+func (s *Server) loopState() {
+	tick := time.NewTicker(s.opts.Heartbeat)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			s.checkState()
+		case <-s.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+func (s *Server) checkState() {
+	for _, n := range s.gossipServer.Members() {
+		nodeID := nameToNodeID(n.Name)
+		if nodeID == s.opts.NodeID {
+			continue
+		}
+		state := NodeStateType(n.State)
+		oldState, ok := s.stateMap[nodeID]
+		s.stateMap[nodeID] = state
+		if !ok || oldState == state {
+			continue
+		}
+		var eventType NodeEventType
+		if state == StateSuspect {
+			eventType = NodeEventSuspect
+		} else if state == StateAlive && oldState == StateSuspect {
+			eventType = NodeEventAlive
+		} else {
+			continue
+		}
+		s.Info("node state change", zap.Uint64("nodeID", nodeID), zap.String("from", oldState.metricsString()), zap.String("to", state.metricsString()))
+		if s.opts.OnNodeEvent != nil {
+			s.opts.OnNodeEvent(NodeEvent{
+				NodeID:    nodeID,
+				Addr:      n.Address(),
+				EventType: eventType,
+				State:     state,
+				Meta:      nodeMetaOf(n),
+			})
+		}
+	}
+}
+
 func (s *Server) loopJoin() {
 
 	needJoins := s.getNeedJoin()
@@ -4,11 +4,18 @@ import (
 	"strconv"
 
 	"github.com/hashicorp/memberlist"
+	"go.uber.org/zap"
 )
 
 // -------------------- 以下是memberlist的Delegate接口实现 --------------------
 func (s *Server) NodeMeta(limit int) []byte {
-	return nil
+	meta := s.Meta()
+	data := meta.encode()
+	if len(data) > limit {
+		s.Warn("node meta is too large", zap.Int("size", len(data)), zap.Int("limit", limit))
+		return nil
+	}
+	return data
 }
 
 func (s *Server) NotifyMsg(msg []byte) {
@@ -35,6 +42,7 @@ func (s *Server) NotifyJoin(n *memberlist.Node) {
 			Addr:      n.Address(),
 			EventType: NodeEventJoin,
 			State:     NodeStateType(n.State),
+			Meta:      nodeMetaOf(n),
 		}
 		s.opts.OnNodeEvent(nodeEvent)
 	}
@@ -47,6 +55,7 @@ func (s *Server) NotifyLeave(n *memberlist.Node) {
 			Addr:      n.Address(),
 			EventType: NodeEventLeave,
 			State:     NodeStateType(n.State),
+			Meta:      nodeMetaOf(n),
 		}
 		s.opts.OnNodeEvent(nodeEvent)
 	}
@@ -59,11 +68,23 @@ func (s *Server) NotifyUpdate(n *memberlist.Node) {
 			Addr:      n.Address(),
 			EventType: NodeEventUpdate,
 			State:     NodeStateType(n.State),
+			Meta:      nodeMetaOf(n),
 		}
 		s.opts.OnNodeEvent(nodeEvent)
 	}
 }
 
+func nodeMetaOf(n *memberlist.Node) *NodeMeta {
+	if len(n.Meta) == 0 {
+		return nil
+	}
+	meta, err := decodeNodeMeta(n.Meta)
+	if err != nil {
+		return nil
+	}
+	return meta
+}
+
 func nameToNodeID(name string) uint64 {
 	nodeID, _ := strconv.ParseUint(name, 10, 64)
 	return nodeID
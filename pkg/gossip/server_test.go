@@ -28,3 +28,32 @@ func TestServerJoin(t *testing.T) {
 	wg.Wait()
 
 }
+
+func TestServerMeta(t *testing.T) {
+	s1 := gossip.NewServer(1, "127.0.0.1:11001", gossip.WithMeta(gossip.NodeMeta{
+		ClusterAddr:   "127.0.0.1:11110",
+		ApiServerAddr: "http://127.0.0.1:5001",
+		Joined:        true,
+	}))
+	err := s1.Start()
+	assert.NoError(t, err)
+	defer s1.Stop()
+
+	s2 := gossip.NewServer(2, "127.0.0.1:12001", gossip.WithSeed([]string{"127.0.0.1:11001"}))
+	err = s2.Join()
+	assert.NoError(t, err)
+	err = s2.Start()
+	assert.NoError(t, err)
+	defer s2.Stop()
+
+	var meta *gossip.NodeMeta
+	for _, m := range s2.Members() {
+		if m.NodeID == 1 {
+			meta = m.Meta
+		}
+	}
+	assert.NotNil(t, meta)
+	assert.Equal(t, "127.0.0.1:11110", meta.ClusterAddr)
+	assert.Equal(t, "http://127.0.0.1:5001", meta.ApiServerAddr)
+	assert.True(t, meta.Joined)
+}
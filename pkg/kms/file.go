@@ -0,0 +1,108 @@
+package kms
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+var _ MasterKey = (*FileMasterKey)(nil)
+
+// FileMasterKey 从文件加载的主密钥
+// 文件每行一个密钥，格式为 id:base64(32字节密钥)，#开头为注释，最后一行为当前密钥。
+// 轮换主密钥时在文件末尾追加新的密钥然后调用Reload，旧的密钥需要保留直到数据密钥都重新包装。
+type FileMasterKey struct {
+	path      string
+	mu        sync.RWMutex
+	keys      map[string][]byte
+	currentId string
+}
+
+func NewFileMasterKey(path string) (*FileMasterKey, error) {
+	f := &FileMasterKey{path: path}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload 重新加载密钥文件
+func (f *FileMasterKey) Reload() error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	keys := make(map[string][]byte)
+	var currentId string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, encoded, ok := strings.Cut(line, ":")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" {
+			return fmt.Errorf("master key file %s line %d: invalid format, expect id:base64key", f.path, lineNo)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return fmt.Errorf("master key file %s line %d: %w", f.path, lineNo, err)
+		}
+		if len(key) != 32 {
+			return fmt.Errorf("master key file %s line %d: key must be 32 bytes", f.path, lineNo)
+		}
+		if _, exist := keys[id]; exist {
+			return fmt.Errorf("master key file %s line %d: duplicate key id[%s]", f.path, lineNo, id)
+		}
+		keys[id] = key
+		currentId = id
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	if currentId == "" {
+		return fmt.Errorf("master key file %s has no key", f.path)
+	}
+	f.mu.Lock()
+	f.keys = keys
+	f.currentId = currentId
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *FileMasterKey) CurrentKeyId(ctx context.Context) (string, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.currentId, nil
+}
+
+func (f *FileMasterKey) Wrap(ctx context.Context, plaintext []byte) (string, []byte, error) {
+	f.mu.RLock()
+	keyId := f.currentId
+	key := f.keys[keyId]
+	f.mu.RUnlock()
+
+	wrapped, err := Seal(key, plaintext, []byte(keyId))
+	if err != nil {
+		return "", nil, err
+	}
+	return keyId, wrapped, nil
+}
+
+func (f *FileMasterKey) Unwrap(ctx context.Context, keyId string, wrapped []byte) ([]byte, error) {
+	f.mu.RLock()
+	key, ok := f.keys[keyId]
+	f.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyId)
+	}
+	return Open(key, wrapped, []byte(keyId))
+}
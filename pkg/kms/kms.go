@@ -0,0 +1,71 @@
+package kms
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// ErrKeyNotFound 主密钥不存在
+var ErrKeyNotFound = errors.New("master key not found")
+
+// MasterKey 主密钥，只用于包装（加密）数据密钥，不直接加密数据
+type MasterKey interface {
+	// CurrentKeyId 当前用于包装的主密钥id
+	CurrentKeyId(ctx context.Context) (string, error)
+	// Wrap 用当前主密钥包装数据密钥，返回使用的主密钥id
+	Wrap(ctx context.Context, plaintext []byte) (keyId string, wrapped []byte, err error)
+	// Unwrap 用指定的主密钥解开数据密钥
+	Unwrap(ctx context.Context, keyId string, wrapped []byte) ([]byte, error)
+}
+
+// Reloader 可以重新加载的主密钥（例如密钥文件追加了新的主密钥）
+type Reloader interface {
+	Reload() error
+}
+
+// Seal 使用AES-GCM加密，结果为 nonce + 密文
+func Seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := NewAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open 解密Seal的结果
+func Open(key, data, additionalData []byte) ([]byte, error) {
+	aead, err := NewAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additionalData)
+}
+
+// NewAEAD 创建AES-GCM，key长度为16、24或32
+func NewAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// GenerateKey 生成随机的256位密钥
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
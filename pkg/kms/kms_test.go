@@ -0,0 +1,122 @@
+package kms_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/kms"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeKeyFile(t *testing.T, path string, ids ...string) {
+	content := "# master keys\n"
+	for _, id := range ids {
+		key, err := kms.GenerateKey()
+		assert.NoError(t, err)
+		content += id + ":" + base64.StdEncoding.EncodeToString(key) + "\n"
+	}
+	err := os.WriteFile(path, []byte(content), 0600)
+	assert.NoError(t, err)
+}
+
+func TestFileMasterKey(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "master.key")
+	writeKeyFile(t, path, "k1")
+
+	mk, err := kms.NewFileMasterKey(path)
+	assert.NoError(t, err)
+
+	keyId, wrapped, err := mk.Wrap(ctx, []byte("data key"))
+	assert.NoError(t, err)
+	assert.Equal(t, "k1", keyId)
+
+	plaintext, err := mk.Unwrap(ctx, keyId, wrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data key"), plaintext)
+
+	// 包装时绑定了主密钥id
+	_, err = mk.Unwrap(ctx, "k2", wrapped)
+	assert.ErrorIs(t, err, kms.ErrKeyNotFound)
+
+	// 追加新的主密钥，旧的仍然可以解开
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	key, err := kms.GenerateKey()
+	assert.NoError(t, err)
+	data = append(data, []byte("k2:"+base64.StdEncoding.EncodeToString(key)+"\n")...)
+	assert.NoError(t, os.WriteFile(path, data, 0600))
+	assert.NoError(t, mk.Reload())
+
+	currentId, err := mk.CurrentKeyId(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "k2", currentId)
+
+	plaintext, err = mk.Unwrap(ctx, "k1", wrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data key"), plaintext)
+}
+
+func TestPluginMasterKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "master.key")
+	writeKeyFile(t, path, "kms-1")
+	backend, err := kms.NewFileMasterKey(path)
+	assert.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			KeyId      string `json:"key_id"`
+			Plaintext  []byte `json:"plaintext"`
+			Ciphertext []byte `json:"ciphertext"`
+		}
+		if r.Method == http.MethodPost {
+			_ = json.NewDecoder(r.Body).Decode(&req)
+		}
+		resp := map[string]interface{}{}
+		switch r.URL.Path {
+		case "/key":
+			resp["key_id"], _ = backend.CurrentKeyId(r.Context())
+		case "/wrap":
+			keyId, wrapped, _ := backend.Wrap(r.Context(), req.Plaintext)
+			resp["key_id"] = keyId
+			resp["ciphertext"] = wrapped
+		case "/unwrap":
+			plaintext, err := backend.Unwrap(r.Context(), req.KeyId, req.Ciphertext)
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			resp["plaintext"] = plaintext
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	mk := kms.NewPluginMasterKey(srv.URL, "secret", 0)
+
+	currentId, err := mk.CurrentKeyId(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "kms-1", currentId)
+
+	keyId, wrapped, err := mk.Wrap(ctx, []byte("data key"))
+	assert.NoError(t, err)
+	assert.Equal(t, "kms-1", keyId)
+
+	plaintext, err := mk.Unwrap(ctx, keyId, wrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data key"), plaintext)
+
+	_, err = mk.Unwrap(ctx, "unknown", wrapped)
+	assert.ErrorIs(t, err, kms.ErrKeyNotFound)
+}
@@ -0,0 +1,108 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var _ MasterKey = (*PluginMasterKey)(nil)
+
+// PluginMasterKey 通过HTTP插件访问外部KMS，主密钥不会离开KMS
+//
+//	GET  {endpoint}/key    返回 {"key_id": "..."}
+//	POST {endpoint}/wrap   请求 {"plaintext": base64}            返回 {"key_id": "...", "ciphertext": base64}
+//	POST {endpoint}/unwrap 请求 {"key_id": "...", "ciphertext": base64} 返回 {"plaintext": base64}
+type PluginMasterKey struct {
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+func NewPluginMasterKey(endpoint string, token string, timeout time.Duration) *PluginMasterKey {
+	if timeout <= 0 {
+		timeout = time.Second * 10
+	}
+	return &PluginMasterKey{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		token:    token,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+type pluginKeyReq struct {
+	KeyId      string `json:"key_id,omitempty"`
+	Plaintext  []byte `json:"plaintext,omitempty"`
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+}
+
+type pluginKeyResp struct {
+	KeyId      string `json:"key_id"`
+	Plaintext  []byte `json:"plaintext"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func (p *PluginMasterKey) CurrentKeyId(ctx context.Context) (string, error) {
+	resp, err := p.request(ctx, http.MethodGet, "/key", nil)
+	if err != nil {
+		return "", err
+	}
+	return resp.KeyId, nil
+}
+
+func (p *PluginMasterKey) Wrap(ctx context.Context, plaintext []byte) (string, []byte, error) {
+	resp, err := p.request(ctx, http.MethodPost, "/wrap", &pluginKeyReq{Plaintext: plaintext})
+	if err != nil {
+		return "", nil, err
+	}
+	return resp.KeyId, resp.Ciphertext, nil
+}
+
+func (p *PluginMasterKey) Unwrap(ctx context.Context, keyId string, wrapped []byte) ([]byte, error) {
+	resp, err := p.request(ctx, http.MethodPost, "/unwrap", &pluginKeyReq{KeyId: keyId, Ciphertext: wrapped})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
+
+func (p *PluginMasterKey) request(ctx context.Context, method, path string, body *pluginKeyReq) (*pluginKeyResp, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.endpoint+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && path == "/unwrap" {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, body.KeyId)
+	}
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("kms plugin %s failed, status: %d, body: %s", path, resp.StatusCode, string(data))
+	}
+	var result pluginKeyResp
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
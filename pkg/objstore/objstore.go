@@ -0,0 +1,19 @@
+package objstore
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("object not found")
+
+// Store 对象存储，用于存放冷数据（只写一次，不会修改）
+type Store interface {
+	// Put 上传对象，如果已存在则覆盖
+	Put(ctx context.Context, key string, data []byte) error
+	// Get 获取对象，不存在返回ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete 删除对象，不存在不返回错误
+	Delete(ctx context.Context, key string) error
+}
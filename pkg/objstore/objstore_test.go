@@ -0,0 +1,89 @@
+package objstore_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/objstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func testStore(t *testing.T, s objstore.Store) {
+	ctx := context.Background()
+
+	_, err := s.Get(ctx, "messages/1/100-200.seg")
+	assert.Equal(t, objstore.ErrNotFound, err)
+
+	err = s.Put(ctx, "messages/1/100-200.seg", []byte("hello"))
+	assert.NoError(t, err)
+
+	data, err := s.Get(ctx, "messages/1/100-200.seg")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), data)
+
+	err = s.Delete(ctx, "messages/1/100-200.seg")
+	assert.NoError(t, err)
+
+	_, err = s.Get(ctx, "messages/1/100-200.seg")
+	assert.Equal(t, objstore.ErrNotFound, err)
+
+	// 删除不存在的对象不报错
+	err = s.Delete(ctx, "messages/1/100-200.seg")
+	assert.NoError(t, err)
+}
+
+func TestFileStore(t *testing.T) {
+	s := objstore.NewFileStore(t.TempDir())
+	testStore(t, s)
+
+	err := s.Put(context.Background(), "../escape", []byte("x"))
+	assert.Error(t, err)
+}
+
+func TestS3Store(t *testing.T) {
+	var (
+		objects = map[string][]byte{}
+		mu      sync.Mutex
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") || r.Header.Get("x-amz-content-sha256") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, "/cold/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = data
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	s := objstore.NewS3Store(objstore.S3Options{
+		Endpoint:  srv.URL,
+		Bucket:    "cold",
+		AccessKey: "minio",
+		SecretKey: "minio123",
+	})
+	testStore(t, s)
+}
@@ -0,0 +1,159 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var _ Store = (*S3Store)(nil)
+
+// S3Options S3兼容存储的配置（AWS S3、MinIO等）
+type S3Options struct {
+	Endpoint  string        // 服务地址，例如 http://127.0.0.1:9000
+	Region    string        // 区域，默认us-east-1
+	Bucket    string        // 存储桶
+	AccessKey string        // 访问key
+	SecretKey string        // 访问密钥
+	Timeout   time.Duration // 请求超时时间
+}
+
+// S3Store S3兼容的对象存储，使用path-style地址和SigV4签名
+type S3Store struct {
+	opts   S3Options
+	client *http.Client
+}
+
+func NewS3Store(opts S3Options) *S3Store {
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second * 30
+	}
+	opts.Endpoint = strings.TrimSuffix(opts.Endpoint, "/")
+	return &S3Store{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+	}
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s.respError(http.MethodPut, key, resp)
+	}
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.respError(http.MethodGet, key, resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s.respError(http.MethodDelete, key, resp)
+	}
+	return nil
+}
+
+func (s *S3Store) respError(method, key string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 %s %s failed, status: %d, body: %s", method, key, resp.StatusCode, string(body))
+}
+
+func (s *S3Store) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	path := "/" + s.opts.Bucket + "/" + uriEncode(key, false)
+	req, err := http.NewRequestWithContext(ctx, method, s.opts.Endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, path, body, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+// sign 使用AWS Signature Version 4对请求签名
+func (s *S3Store) sign(req *http.Request, path string, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		"", // query
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := dateStamp + "/" + s.opts.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), dateStamp)
+	signingKey = hmacSHA256(signingKey, s.opts.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.opts.AccessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// uriEncode 按照S3的规则编码，encodeSlash为false时保留'/'
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -209,6 +209,11 @@ type IAppMetrics interface {
 	ConnackPacketBytesAdd(v int64)
 	// ConnackPacketCountAdd 连接应答包数量
 	ConnackPacketCountAdd(v int64)
+
+	// ConversationWalLagAdd 最近会话预写日志里还未保存到数据库的条数
+	ConversationWalLagAdd(v int64)
+	// ConversationWalRecoverDurationSet 启动时恢复最近会话预写日志的耗时（毫秒）
+	ConversationWalRecoverDurationSet(v int64)
 }
 
 // IClusterMetrics 分布式监控
@@ -358,4 +363,11 @@ type IClusterMetrics interface {
 
 	// ProposeFailedCountAdd 提案失败的次数
 	ProposeFailedCountAdd(kind ClusterKind, v int64)
+
+	// ConsistencyCheckCountAdd 副本一致性校验次数
+	ConsistencyCheckCountAdd(kind ClusterKind, v int64)
+	// ConsistencyDivergenceCountAdd 发现副本不一致的次数
+	ConsistencyDivergenceCountAdd(kind ClusterKind, v int64)
+	// ConsistencyRepairCountAdd 修复不一致副本的次数
+	ConsistencyRepairCountAdd(kind ClusterKind, v int64)
 }
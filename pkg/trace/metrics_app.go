@@ -33,6 +33,9 @@ type appMetrics struct {
 	connPacketCount    atomic.Int64
 	connackPacketBytes atomic.Int64
 	connackPacketCount atomic.Int64
+
+	conversationWalLag             atomic.Int64
+	conversationWalRecoverDuration atomic.Int64
 }
 
 func newAppMetrics(opts *Options) *appMetrics {
@@ -61,6 +64,8 @@ func newAppMetrics(opts *Options) *appMetrics {
 	connPacketCount := NewInt64ObservableCounter("app_conn_packet_count")
 	connackPacketBytes := NewInt64ObservableCounter("app_connack_packet_bytes")
 	connackPacketCount := NewInt64ObservableCounter("app_connack_packet_count")
+	conversationWalLag := NewInt64ObservableGauge("app_conversation_wal_lag")
+	conversationWalRecoverDuration := NewInt64ObservableGauge("app_conversation_wal_recover_duration")
 
 	RegisterCallback(func(ctx context.Context, obs metric.Observer) error {
 		obs.ObserveInt64(connCount, a.connCount.Load())
@@ -82,8 +87,10 @@ func newAppMetrics(opts *Options) *appMetrics {
 		obs.ObserveInt64(connPacketCount, a.connPacketCount.Load())
 		obs.ObserveInt64(connackPacketBytes, a.connackPacketBytes.Load())
 		obs.ObserveInt64(connackPacketCount, a.connackPacketCount.Load())
+		obs.ObserveInt64(conversationWalLag, a.conversationWalLag.Load())
+		obs.ObserveInt64(conversationWalRecoverDuration, a.conversationWalRecoverDuration.Load())
 		return nil
-	}, connCount, onlineUserCount, onlineDeviceCount, pingBytes, pingCount, pongBytes, pongCount, sendPacketBytes, sendPacketCount, sendackPacketBytes, sendackPacketCount, recvPacketBytes, recvPacketCount, recvackPacketBytes, recvackPacketCount, connPacketBytes, connPacketCount, connackPacketBytes, connackPacketCount)
+	}, connCount, onlineUserCount, onlineDeviceCount, pingBytes, pingCount, pongBytes, pongCount, sendPacketBytes, sendPacketCount, sendackPacketBytes, sendackPacketCount, recvPacketBytes, recvPacketCount, recvackPacketBytes, recvackPacketCount, connPacketBytes, connPacketCount, connackPacketBytes, connackPacketCount, conversationWalLag, conversationWalRecoverDuration)
 	var err error
 	a.messageLatency, err = meter.Int64Histogram("app_message_latency", metric.WithDescription("The latency of message processing in the app layer"), metric.WithUnit("ms"))
 	if err != nil {
@@ -170,3 +177,11 @@ func (a *appMetrics) ConnackPacketBytesAdd(v int64) {
 func (a *appMetrics) ConnackPacketCountAdd(v int64) {
 	a.connackPacketCount.Add(v)
 }
+
+func (a *appMetrics) ConversationWalLagAdd(v int64) {
+	a.conversationWalLag.Add(v)
+}
+
+func (a *appMetrics) ConversationWalRecoverDurationSet(v int64) {
+	a.conversationWalRecoverDuration.Store(v)
+}
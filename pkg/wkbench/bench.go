@@ -0,0 +1,396 @@
+package wkbench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/client"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+const (
+	sendTimeout    = time.Second * 10 // 等待发送回执的超时时间
+	sendTickPeriod = time.Millisecond * 10
+	clientBufSize  = 64 * 1024
+)
+
+// Bench 通过pkg/client模拟用户收发消息的压测工具
+type Bench struct {
+	opts       *Options
+	uids       []string
+	groups     []string
+	clients    []*client.Client
+	httpClient *http.Client
+
+	sent     atomic.Int64
+	acked    atomic.Int64
+	failed   atomic.Int64
+	received atomic.Int64
+
+	sendackLatency latencyRecorder
+	recvLatency    latencyRecorder
+
+	errors  map[string]int64
+	errorMu sync.Mutex
+
+	wg sync.WaitGroup
+	wklog.Log
+}
+
+// New 创建压测
+func New(opts *Options) *Bench {
+	return &Bench{
+		opts:       opts,
+		httpClient: &http.Client{Timeout: time.Second * 30},
+		errors:     make(map[string]int64),
+		Log:        wklog.NewWKLog("bench"),
+	}
+}
+
+func (b *Bench) check() error {
+	if b.opts.Users < 2 {
+		return errors.New("users must be greater than 1")
+	}
+	if b.opts.Rate <= 0 {
+		return errors.New("rate must be greater than 0")
+	}
+	if b.opts.Groups > 0 && b.opts.GroupSize < 2 {
+		return errors.New("group size must be greater than 1")
+	}
+	if b.opts.PersonRatio < 0 || b.opts.PersonRatio > 1 {
+		return errors.New("person ratio must be between 0 and 1")
+	}
+	if b.opts.Groups == 0 && b.opts.PersonRatio < 1 {
+		b.opts.PersonRatio = 1 // 没有群只发单聊消息
+	}
+	if b.opts.GroupSize > b.opts.Users {
+		b.opts.GroupSize = b.opts.Users
+	}
+	if b.opts.Concurrency <= 0 {
+		b.opts.Concurrency = 1
+	}
+	return nil
+}
+
+// Run 运行压测，ctx取消后提前结束发送
+func (b *Bench) Run(ctx context.Context) (*Report, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+	startedAt := time.Now()
+
+	b.uids = make([]string, 0, b.opts.Users)
+	for i := 0; i < b.opts.Users; i++ {
+		b.uids = append(b.uids, fmt.Sprintf("%s%d", b.opts.UIDPrefix, i))
+	}
+	b.Info("register users", zap.Int("users", len(b.uids)))
+	if err := b.registerUsers(); err != nil {
+		return nil, err
+	}
+	b.Info("create groups", zap.Int("groups", b.opts.Groups), zap.Int("groupSize", b.opts.GroupSize))
+	if err := b.createGroups(); err != nil {
+		return nil, err
+	}
+	b.Info("connect users", zap.Int("users", len(b.uids)))
+	if err := b.connect(); err != nil {
+		b.close()
+		return nil, err
+	}
+	defer b.close()
+
+	b.Info("start sending", zap.Int("rate", b.opts.Rate), zap.Duration("duration", b.opts.Duration))
+	sendStart := time.Now()
+	b.send(ctx)
+	sendDuration := time.Since(sendStart)
+	b.wg.Wait() // 等待所有发送回执
+
+	// 等待接收者收完消息
+	timer := time.NewTimer(b.opts.RecvWait)
+	defer timer.Stop()
+	tk := time.NewTicker(time.Millisecond * 100)
+	defer tk.Stop()
+	lastReceived := b.received.Load()
+	idle := 0
+	for idle < 10 { // 1秒没有收到新消息则认为已经收完
+		select {
+		case <-timer.C:
+			idle = 10
+		case <-ctx.Done():
+			idle = 10
+		case <-tk.C:
+			received := b.received.Load()
+			if received == lastReceived {
+				idle++
+			} else {
+				idle = 0
+			}
+			lastReceived = received
+		}
+	}
+	return b.report(startedAt, sendDuration), nil
+}
+
+func (b *Bench) token(uid string) string {
+	return "token-" + uid
+}
+
+func (b *Bench) registerUsers() error {
+	for _, uid := range b.uids {
+		err := b.post("/user/token", map[string]interface{}{
+			"uid":          uid,
+			"token":        b.token(uid),
+			"device_flag":  wkproto.APP,
+			"device_level": wkproto.DeviceLevelMaster,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupMembers 第i个群的成员（按顺序轮流取用户）
+func (b *Bench) groupMembers(i int) []string {
+	members := make([]string, 0, b.opts.GroupSize)
+	for m := 0; m < b.opts.GroupSize; m++ {
+		members = append(members, b.uids[(i*b.opts.GroupSize+m)%len(b.uids)])
+	}
+	return members
+}
+
+func (b *Bench) createGroups() error {
+	b.groups = make([]string, 0, b.opts.Groups)
+	large := 0
+	if b.opts.GroupLarge {
+		large = 1
+	}
+	for i := 0; i < b.opts.Groups; i++ {
+		groupId := fmt.Sprintf("%sg%d", b.opts.UIDPrefix, i)
+		err := b.post("/channel", map[string]interface{}{
+			"channel_id":   groupId,
+			"channel_type": wkproto.ChannelTypeGroup,
+			"large":        large,
+			"subscribers":  b.groupMembers(i),
+		})
+		if err != nil {
+			return err
+		}
+		b.groups = append(b.groups, groupId)
+	}
+	return nil
+}
+
+func (b *Bench) connect() error {
+	b.clients = make([]*client.Client, len(b.uids))
+	for i, uid := range b.uids {
+		cli := client.New(b.opts.TCPAddr, client.WithUID(uid), client.WithToken(b.token(uid)), client.WithDefaultBufSize(clientBufSize))
+		recvC := cli.RecvChan()
+		if err := cli.Connect(); err != nil {
+			return fmt.Errorf("connect %s failed: %w", uid, err)
+		}
+		b.clients[i] = cli
+		go b.loopRecv(recvC)
+	}
+	return nil
+}
+
+func (b *Bench) close() {
+	for _, cli := range b.clients {
+		if cli != nil {
+			cli.Close()
+		}
+	}
+}
+
+func (b *Bench) loopRecv(recvC <-chan *wkproto.RecvPacket) {
+	for recv := range recvC {
+		b.received.Inc()
+		if sentAt := parsePayloadTime(recv.Payload); sentAt > 0 {
+			b.recvLatency.record(time.Since(time.Unix(0, sentAt)))
+		}
+	}
+}
+
+// send 按设置的速率发送消息直到时间结束
+func (b *Bench) send(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, b.opts.Duration)
+	defer cancel()
+
+	sem := make(chan struct{}, b.opts.Concurrency)
+	start := time.Now()
+	tk := time.NewTicker(sendTickPeriod)
+	defer tk.Stop()
+	var sent int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tk.C:
+			expected := int64(time.Since(start).Seconds() * float64(b.opts.Rate))
+			for ; sent < expected; sent++ {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				b.wg.Add(1)
+				go func() {
+					defer func() {
+						<-sem
+						b.wg.Done()
+					}()
+					b.sendOne()
+				}()
+			}
+		}
+	}
+}
+
+func (b *Bench) sendOne() {
+	senderIdx := rand.Intn(len(b.clients))
+	var channel *client.Channel
+	if rand.Float64() < b.opts.PersonRatio {
+		receiverIdx := rand.Intn(len(b.uids) - 1)
+		if receiverIdx >= senderIdx {
+			receiverIdx++
+		}
+		channel = client.NewChannel(b.uids[receiverIdx], wkproto.ChannelTypePerson)
+	} else {
+		groupIdx := rand.Intn(len(b.groups))
+		members := b.groupMembers(groupIdx)
+		senderIdx = (groupIdx*b.opts.GroupSize + rand.Intn(len(members))) % len(b.uids)
+		channel = client.NewChannel(b.groups[groupIdx], wkproto.ChannelTypeGroup)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	sendAt := time.Now()
+	b.sent.Inc()
+	sendack, err := b.clients[senderIdx].SendMessageContext(ctx, channel, newPayload(sendAt, b.opts.PayloadSize))
+	if err != nil {
+		b.failed.Inc()
+		b.addError(sendErrorReason(sendack, err))
+		return
+	}
+	b.acked.Inc()
+	b.sendackLatency.record(time.Since(sendAt))
+}
+
+func (b *Bench) addError(reason string) {
+	b.errorMu.Lock()
+	b.errors[reason]++
+	b.errorMu.Unlock()
+}
+
+func sendErrorReason(sendack *wkproto.SendackPacket, err error) string {
+	if sendack != nil {
+		return sendack.ReasonCode.String()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	return err.Error()
+}
+
+func (b *Bench) report(startedAt time.Time, duration time.Duration) *Report {
+	seconds := duration.Seconds()
+	if seconds <= 0 {
+		seconds = 1
+	}
+	report := &Report{
+		StartedAt: startedAt,
+		Duration:  duration,
+		Options: ReportOptions{
+			TCPAddr:     b.opts.TCPAddr,
+			Users:       b.opts.Users,
+			Groups:      b.opts.Groups,
+			GroupSize:   b.opts.GroupSize,
+			GroupLarge:  b.opts.GroupLarge,
+			Rate:        b.opts.Rate,
+			Duration:    b.opts.Duration.String(),
+			PersonRatio: b.opts.PersonRatio,
+			PayloadSize: b.opts.PayloadSize,
+		},
+		Sent:           b.sent.Load(),
+		Acked:          b.acked.Load(),
+		Failed:         b.failed.Load(),
+		Received:       b.received.Load(),
+		SendackLatency: b.sendackLatency.stats(),
+		RecvLatency:    b.recvLatency.stats(),
+	}
+	report.SendRate = float64(report.Sent) / seconds
+	report.AckRate = float64(report.Acked) / seconds
+	report.RecvRate = float64(report.Received) / seconds
+	if report.Sent > 0 {
+		report.ErrorRate = float64(report.Failed) / float64(report.Sent)
+	}
+	b.errorMu.Lock()
+	report.Errors = make(map[string]int64, len(b.errors))
+	for reason, count := range b.errors {
+		report.Errors[reason] = count
+	}
+	b.errorMu.Unlock()
+	return report
+}
+
+func (b *Bench) post(path string, req interface{}) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(b.opts.APIURL, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if b.opts.APIToken != "" {
+		httpReq.Header.Set("token", b.opts.APIToken)
+	}
+	resp, err := b.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("request %s failed, status: %d body: %s", path, resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+type benchPayload struct {
+	Type    int    `json:"type"`
+	Content string `json:"content"`
+	SentAt  int64  `json:"bench_sent_at"` // 发送时间（纳秒）
+}
+
+// newPayload 生成大约size字节的消息内容，包含发送时间用于计算接收延迟
+func newPayload(sentAt time.Time, size int) []byte {
+	payload := benchPayload{Type: 1, SentAt: sentAt.UnixNano()}
+	data, _ := json.Marshal(payload)
+	if pad := size - len(data); pad > 0 {
+		payload.Content = strings.Repeat("x", pad)
+		data, _ = json.Marshal(payload)
+	}
+	return data
+}
+
+func parsePayloadTime(data []byte) int64 {
+	var payload benchPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return 0
+	}
+	return payload.SentAt
+}
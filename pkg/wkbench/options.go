@@ -0,0 +1,43 @@
+package wkbench
+
+import "time"
+
+// Options 压测配置
+type Options struct {
+	TCPAddr  string // 长连接地址 例如：tcp://127.0.0.1:5100 或 ws://127.0.0.1:5200
+	APIURL   string // http api地址 例如：http://127.0.0.1:5001
+	APIToken string // 调用api的token（服务端开启了ManagerToken时需要）
+
+	UIDPrefix string // 模拟用户的uid前缀
+	Users     int    // 模拟用户数量
+
+	Groups     int  // 群数量
+	GroupSize  int  // 每个群的成员数量
+	GroupLarge bool // 群是否是超大群
+
+	Rate        int           // 每秒发送的消息数量（所有用户合计）
+	Duration    time.Duration // 发送消息的持续时间
+	PersonRatio float64       // 单聊消息的比例（0-1），其余为群消息
+	PayloadSize int           // 消息内容大小（字节）
+	Concurrency int           // 同时等待发送回执的最大消息数量
+
+	RecvWait time.Duration // 发送结束后等待接收消息的时间
+}
+
+// NewOptions 创建默认配置
+func NewOptions() *Options {
+	return &Options{
+		TCPAddr:     "tcp://127.0.0.1:5100",
+		APIURL:      "http://127.0.0.1:5001",
+		UIDPrefix:   "bench",
+		Users:       100,
+		Groups:      10,
+		GroupSize:   50,
+		Rate:        100,
+		Duration:    time.Second * 30,
+		PersonRatio: 0.5,
+		PayloadSize: 64,
+		Concurrency: 1000,
+		RecvWait:    time.Second * 5,
+	}
+}
@@ -0,0 +1,146 @@
+package wkbench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Report 压测结果
+type Report struct {
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"` // 实际发送消息的时长
+	Options   ReportOptions `json:"options"`
+
+	Sent      int64   `json:"sent"`       // 发送的消息数量
+	Acked     int64   `json:"acked"`      // 收到成功发送回执的消息数量
+	Failed    int64   `json:"failed"`     // 发送失败的消息数量
+	Received  int64   `json:"received"`   // 收到的消息数量（群消息每个接收者算一次）
+	SendRate  float64 `json:"send_rate"`  // 每秒发送的消息数量
+	AckRate   float64 `json:"ack_rate"`   // 每秒成功发送的消息数量
+	RecvRate  float64 `json:"recv_rate"`  // 每秒收到的消息数量
+	ErrorRate float64 `json:"error_rate"` // 发送失败的比例
+
+	SendackLatency LatencyStats     `json:"sendack_latency"` // 发送到收到发送回执的延迟
+	RecvLatency    LatencyStats     `json:"recv_latency"`    // 发送到接收者收到消息的延迟
+	Errors         map[string]int64 `json:"errors"`          // 失败原因和数量
+}
+
+// ReportOptions 压测配置（写入结果，方便对比）
+type ReportOptions struct {
+	TCPAddr     string  `json:"tcp_addr"`
+	Users       int     `json:"users"`
+	Groups      int     `json:"groups"`
+	GroupSize   int     `json:"group_size"`
+	GroupLarge  bool    `json:"group_large"`
+	Rate        int     `json:"rate"`
+	Duration    string  `json:"duration"`
+	PersonRatio float64 `json:"person_ratio"`
+	PayloadSize int     `json:"payload_size"`
+}
+
+// LatencyStats 延迟统计（单位毫秒）
+type LatencyStats struct {
+	Count int64   `json:"count"`
+	Avg   float64 `json:"avg"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	P999  float64 `json:"p999"`
+	Max   float64 `json:"max"`
+}
+
+// latencyRecorder 记录延迟
+type latencyRecorder struct {
+	latencies []time.Duration
+	mu        sync.Mutex
+}
+
+func (l *latencyRecorder) record(d time.Duration) {
+	l.mu.Lock()
+	l.latencies = append(l.latencies, d)
+	l.mu.Unlock()
+}
+
+func (l *latencyRecorder) stats() LatencyStats {
+	l.mu.Lock()
+	latencies := make([]time.Duration, len(l.latencies))
+	copy(latencies, l.latencies)
+	l.mu.Unlock()
+
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	return LatencyStats{
+		Count: int64(len(latencies)),
+		Avg:   toMillisecond(total / time.Duration(len(latencies))),
+		P50:   toMillisecond(percentile(latencies, 0.5)),
+		P90:   toMillisecond(percentile(latencies, 0.9)),
+		P99:   toMillisecond(percentile(latencies, 0.99)),
+		P999:  toMillisecond(percentile(latencies, 0.999)),
+		Max:   toMillisecond(latencies[len(latencies)-1]),
+	}
+}
+
+// percentile 已排序的延迟的百分位数
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted))*p+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+func toMillisecond(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Print 输出可读的压测结果
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "duration: %s users: %d groups: %d group size: %d\n", r.Duration, r.Options.Users, r.Options.Groups, r.Options.GroupSize)
+	fmt.Fprintf(w, "sent: %d acked: %d failed: %d received: %d\n", r.Sent, r.Acked, r.Failed, r.Received)
+	fmt.Fprintf(w, "throughput: send %.1f/s ack %.1f/s recv %.1f/s error rate %.2f%%\n", r.SendRate, r.AckRate, r.RecvRate, r.ErrorRate*100)
+	printLatency(w, "send->sendack", r.SendackLatency)
+	printLatency(w, "send->recv", r.RecvLatency)
+	if len(r.Errors) > 0 {
+		reasons := make([]string, 0, len(r.Errors))
+		for reason := range r.Errors {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		fmt.Fprintln(w, "errors:")
+		for _, reason := range reasons {
+			fmt.Fprintf(w, "  %s: %d\n", reason, r.Errors[reason])
+		}
+	}
+}
+
+func printLatency(w io.Writer, name string, stats LatencyStats) {
+	fmt.Fprintf(w, "%-14s count: %d avg: %.2fms p50: %.2fms p90: %.2fms p99: %.2fms p999: %.2fms max: %.2fms\n", name, stats.Count, stats.Avg, stats.P50, stats.P90, stats.P99, stats.P999, stats.Max)
+}
+
+// Save 将压测结果保存为json文件
+func (r *Report) Save(file string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
@@ -0,0 +1,61 @@
+package wkbench
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyStats(t *testing.T) {
+	var recorder latencyRecorder
+	assert.Equal(t, LatencyStats{}, recorder.stats())
+
+	for i := 100; i >= 1; i-- {
+		recorder.record(time.Duration(i) * time.Millisecond)
+	}
+	stats := recorder.stats()
+	assert.Equal(t, int64(100), stats.Count)
+	assert.Equal(t, 50.0, stats.P50)
+	assert.Equal(t, 90.0, stats.P90)
+	assert.Equal(t, 99.0, stats.P99)
+	assert.Equal(t, 100.0, stats.P999)
+	assert.Equal(t, 100.0, stats.Max)
+	assert.InDelta(t, 50.5, stats.Avg, 0.001)
+}
+
+func TestPayload(t *testing.T) {
+	sentAt := time.Now()
+	payload := newPayload(sentAt, 200)
+	assert.Equal(t, 200, len(payload))
+	assert.Equal(t, sentAt.UnixNano(), parsePayloadTime(payload))
+	assert.Equal(t, int64(0), parsePayloadTime([]byte("hello")))
+}
+
+func TestReportSave(t *testing.T) {
+	b := New(NewOptions())
+	b.sent.Add(10)
+	b.acked.Add(8)
+	b.failed.Add(2)
+	b.addError(sendErrorReason(&wkproto.SendackPacket{ReasonCode: wkproto.ReasonSubscriberNotExist}, nil))
+	b.addError("timeout")
+	report := b.report(time.Now(), time.Second*2)
+	assert.Equal(t, 5.0, report.SendRate)
+	assert.Equal(t, 0.2, report.ErrorRate)
+	assert.Equal(t, 2, len(report.Errors))
+
+	file := path.Join(t.TempDir(), "bench.json")
+	err := report.Save(file)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	var saved Report
+	err = json.Unmarshal(data, &saved)
+	assert.NoError(t, err)
+	assert.Equal(t, report.Sent, saved.Sent)
+	assert.Equal(t, report.Errors, saved.Errors)
+}
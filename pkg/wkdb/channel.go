@@ -268,6 +268,49 @@ func (wk *wukongDB) SearchChannels(req ChannelSearchReq) ([]ChannelInfo, error)
 	return allChannelInfos, nil
 }
 
+func (wk *wukongDB) GetChannelsByMember(uid string) ([]MemberOfChannel, error) {
+
+	var channels []ChannelInfo
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewChannelInfoColumnKey(0, key.MinColumnKey),
+			UpperBound: key.NewChannelInfoColumnKey(math.MaxUint64, key.MaxColumnKey),
+		})
+		err := wk.iterChannelInfo(iter, func(channelInfo ChannelInfo) bool {
+			channels = append(channels, channelInfo)
+			return true
+		})
+		iter.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]MemberOfChannel, 0)
+	for _, channelInfo := range channels {
+		var (
+			member MemberOfChannel
+			err    error
+		)
+		if member.Subscriber, err = wk.ExistSubscriber(channelInfo.ChannelId, channelInfo.ChannelType, uid); err != nil {
+			return nil, err
+		}
+		if member.Denylist, err = wk.ExistDenylist(channelInfo.ChannelId, channelInfo.ChannelType, uid); err != nil {
+			return nil, err
+		}
+		if member.Allowlist, err = wk.ExistAllowlist(channelInfo.ChannelId, channelInfo.ChannelType, uid); err != nil {
+			return nil, err
+		}
+		if !member.Subscriber && !member.Denylist && !member.Allowlist {
+			continue
+		}
+		member.ChannelId = channelInfo.ChannelId
+		member.ChannelType = channelInfo.ChannelType
+		results = append(results, member)
+	}
+	return results, nil
+}
+
 func (wk *wukongDB) searchChannelsByIndex(req ChannelSearchReq, db *pebble.DB, iterFnc func(ch ChannelInfo) bool) (bool, error) {
 	var lowKey []byte
 	var highKey []byte
@@ -519,6 +562,18 @@ func (wk *wukongDB) writeChannelInfo(primaryKey uint64, channelInfo ChannelInfo,
 		return err
 	}
 
+	// replicaCount
+	replicaCountBytes := make([]byte, 2)
+	wk.endian.PutUint16(replicaCountBytes, channelInfo.ReplicaCount)
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.ReplicaCount), replicaCountBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// ackMode
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.AckMode), []byte{uint8(channelInfo.AckMode)}, wk.noSync); err != nil {
+		return err
+	}
+
 	// createdAt
 	if channelInfo.CreatedAt != nil {
 		ct := uint64(channelInfo.CreatedAt.UnixNano())
@@ -677,6 +732,10 @@ func (wk *wukongDB) iterChannelInfo(iter *pebble.Iterator, iterFnc func(channelI
 			preChannelInfo.AllowlistCount = int(wk.endian.Uint32(iter.Value()))
 		case key.TableChannelInfo.Column.DenylistCount:
 			preChannelInfo.DenylistCount = int(wk.endian.Uint32(iter.Value()))
+		case key.TableChannelInfo.Column.ReplicaCount:
+			preChannelInfo.ReplicaCount = wk.endian.Uint16(iter.Value())
+		case key.TableChannelInfo.Column.AckMode:
+			preChannelInfo.AckMode = ChannelAckMode(iter.Value()[0])
 		case key.TableChannelInfo.Column.CreatedAt:
 			tm := int64(wk.endian.Uint64(iter.Value()))
 			if tm > 0 {
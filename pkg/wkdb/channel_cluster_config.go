@@ -363,6 +363,11 @@ func (wk *wukongDB) writeChannelClusterConfig(primaryKey uint64, channelClusterC
 		return err
 	}
 
+	// ackMode
+	if err := w.Set(key.NewChannelClusterConfigColumnKey(primaryKey, key.TableChannelClusterConfig.Column.AckMode), []byte{uint8(channelClusterConfig.AckMode)}, wk.noSync); err != nil {
+		return err
+	}
+
 	// replicas
 	var replicasBytes = make([]byte, 8*len(channelClusterConfig.Replicas))
 	for i, replica := range channelClusterConfig.Replicas {
@@ -556,6 +561,8 @@ func (wk *wukongDB) iteratorChannelClusterConfig(iter *pebble.Iterator, iterFnc
 			preChannelClusterConfig.ChannelType = iter.Value()[0]
 		case key.TableChannelClusterConfig.Column.ReplicaMaxCount:
 			preChannelClusterConfig.ReplicaMaxCount = wk.endian.Uint16(iter.Value())
+		case key.TableChannelClusterConfig.Column.AckMode:
+			preChannelClusterConfig.AckMode = ChannelAckMode(iter.Value()[0])
 		case key.TableChannelClusterConfig.Column.Replicas:
 			replicas := make([]uint64, len(iter.Value())/8)
 			for i := 0; i < len(replicas); i++ {
@@ -57,6 +57,7 @@ func TestGetChannelClusterConfig(t *testing.T) {
 		ChannelId:       channelId,
 		ChannelType:     channelType,
 		ReplicaMaxCount: 3,
+		AckMode:         wkdb.ChannelAckModeNone,
 		Replicas:        []uint64{1, 2, 3},
 		LeaderId:        1001,
 		Term:            1,
@@ -73,6 +74,7 @@ func TestGetChannelClusterConfig(t *testing.T) {
 	assert.Equal(t, config.ChannelId, config2.ChannelId)
 	assert.Equal(t, config.ChannelType, config2.ChannelType)
 	assert.Equal(t, config.ReplicaMaxCount, config2.ReplicaMaxCount)
+	assert.Equal(t, config.AckMode, config2.AckMode)
 	assert.Equal(t, config.LeaderId, config2.LeaderId)
 	assert.Equal(t, config.Term, config2.Term)
 	assert.Equal(t, config.Replicas, config2.Replicas)
@@ -70,6 +70,8 @@ func TestUpdateChannel(t *testing.T) {
 	channelInfo.Ban = false
 	channelInfo.Large = false
 	channelInfo.Disband = false
+	channelInfo.ReplicaCount = 5
+	channelInfo.AckMode = wkdb.ChannelAckModeAll
 	channelInfo.UpdatedAt = &nw
 
 	err = d.UpdateChannel(channelInfo)
@@ -83,6 +85,8 @@ func TestUpdateChannel(t *testing.T) {
 	assert.Equal(t, channelInfo.Ban, channelInfo2.Ban)
 	assert.Equal(t, channelInfo.Large, channelInfo2.Large)
 	assert.Equal(t, channelInfo.Disband, channelInfo2.Disband)
+	assert.Equal(t, channelInfo.ReplicaCount, channelInfo2.ReplicaCount)
+	assert.Equal(t, channelInfo.AckMode, channelInfo2.AckMode)
 	assert.Equal(t, channelInfo.CreatedAt.Unix(), channelInfo2.CreatedAt.Unix())
 	assert.Equal(t, channelInfo.UpdatedAt.Unix(), channelInfo2.UpdatedAt.Unix())
 }
@@ -138,3 +142,47 @@ func TestDeleteChannel(t *testing.T) {
 	assert.NoError(t, err)
 	assert.False(t, exist)
 }
+
+func TestGetChannelsByMember(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+	nw := time.Now()
+	for _, channelId := range []string{"channel1", "channel2", "channel3"} {
+		_, err = d.AddChannel(wkdb.ChannelInfo{
+			ChannelId:   channelId,
+			ChannelType: 2,
+			CreatedAt:   &nw,
+			UpdatedAt:   &nw,
+		})
+		assert.NoError(t, err)
+	}
+
+	err = d.AddSubscribers("channel1", 2, []wkdb.Member{{Uid: "u1", CreatedAt: &nw, UpdatedAt: &nw}})
+	assert.NoError(t, err)
+	err = d.AddDenylist("channel2", 2, []wkdb.Member{{Uid: "u1", CreatedAt: &nw, UpdatedAt: &nw}})
+	assert.NoError(t, err)
+	err = d.AddSubscribers("channel3", 2, []wkdb.Member{{Uid: "u2", CreatedAt: &nw, UpdatedAt: &nw}})
+	assert.NoError(t, err)
+
+	members, err := d.GetChannelsByMember("u1")
+	assert.NoError(t, err)
+	assert.Len(t, members, 2)
+	for _, m := range members {
+		switch m.ChannelId {
+		case "channel1":
+			assert.True(t, m.Subscriber)
+			assert.False(t, m.Denylist)
+		case "channel2":
+			assert.False(t, m.Subscriber)
+			assert.True(t, m.Denylist)
+		default:
+			t.Fatalf("unexpected channel %s", m.ChannelId)
+		}
+	}
+}
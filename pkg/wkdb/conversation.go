@@ -5,14 +5,15 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	"github.com/cockroachdb/pebble"
 	"go.uber.org/zap"
 )
 
 func (wk *wukongDB) AddOrUpdateConversations(uid string, conversations []Conversation) error {
 
-	// wk.dblock.conversationLock.lock(uid)
-	// defer wk.dblock.conversationLock.unlock(uid)
+	wk.dblock.conversationLock.lock(uid)
+	defer wk.dblock.conversationLock.unlock(uid)
 	if wk.opts.EnableCost {
 		start := time.Now()
 		defer func() {
@@ -26,10 +27,20 @@ func (wk *wukongDB) AddOrUpdateConversations(uid string, conversations []Convers
 	batch := wk.shardDB(uid).NewBatch()
 	defer batch.Close()
 
+	version, err := wk.GetConversationVersion(uid)
+	if err != nil {
+		return err
+	}
+
+	pending := make(map[string]Conversation, len(conversations)) // 本批次已经写入的会话，同一个频道重复出现时以它为旧会话
 	for _, cn := range conversations {
-		oldConversation, err := wk.GetConversation(uid, cn.ChannelId, cn.ChannelType)
-		if err != nil && err != ErrNotFound {
-			return err
+		channelKey := wkutil.ChannelToKey(cn.ChannelId, cn.ChannelType)
+		oldConversation, ok := pending[channelKey]
+		if !ok {
+			oldConversation, err = wk.GetConversation(uid, cn.ChannelId, cn.ChannelType)
+			if err != nil && err != ErrNotFound {
+				return err
+			}
 		}
 
 		exist := !IsEmptyConversation(oldConversation)
@@ -48,9 +59,20 @@ func (wk *wukongDB) AddOrUpdateConversations(uid string, conversations []Convers
 			cn.CreatedAt = nil // 更新时不更新创建时间
 		}
 
+		version++
+		cn.Version = version
+		if cn.Extra != nil {
+			extra := *cn.Extra
+			extra.Version = version
+			cn.Extra = &extra
+		}
 		if err := wk.writeConversation(cn, batch); err != nil {
 			return err
 		}
+		pending[channelKey] = cn
+	}
+	if err := wk.writeConversationVersion(uid, version, batch); err != nil {
+		return err
 	}
 
 	// err := wk.IncConversationCount(createCount)
@@ -154,31 +176,149 @@ func (wk *wukongDB) getLastConversationIds(uid string, updatedAt uint64, limit i
 
 // DeleteConversation 删除最近会话
 func (wk *wukongDB) DeleteConversation(uid string, channelId string, channelType uint8) error {
+	return wk.DeleteConversations(uid, []Channel{{ChannelId: channelId, ChannelType: channelType}})
+}
+
+// DeleteConversations 批量删除最近会话
+func (wk *wukongDB) DeleteConversations(uid string, channels []Channel) error {
+	wk.dblock.conversationLock.lock(uid)
+	defer wk.dblock.conversationLock.unlock(uid)
 
 	batch := wk.shardDB(uid).NewBatch()
 	defer batch.Close()
 
-	err := wk.deleteConversation(uid, channelId, channelType, batch)
+	version, err := wk.GetConversationVersion(uid)
 	if err != nil {
 		return err
 	}
-
+	deleted := make(map[string]struct{}, len(channels))
+	for _, channel := range channels {
+		channelKey := wkutil.ChannelToKey(channel.ChannelId, channel.ChannelType)
+		if _, ok := deleted[channelKey]; ok { // 本批次已经删除过了
+			continue
+		}
+		ok, err := wk.deleteConversation(uid, channel.ChannelId, channel.ChannelType, version+1, batch)
+		if err != nil {
+			return err
+		}
+		if ok {
+			version++
+			deleted[channelKey] = struct{}{}
+		}
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+	if err := wk.writeConversationVersion(uid, version, batch); err != nil {
+		return err
+	}
+	if err := wk.gcConversationTombstones(uid, version, batch); err != nil {
+		return err
+	}
 	return batch.Commit(wk.sync)
+}
 
+// gcConversationTombstones 清理超出保留版本范围的已删除会话记录，并记录清理到的版本号
+func (wk *wukongDB) gcConversationTombstones(uid string, version uint64, w pebble.Writer) error {
+	retain := wk.opts.ConversationTombstoneRetain
+	if retain == 0 || version <= retain {
+		return nil
+	}
+	floor := version - retain
+	if err := w.DeleteRange(key.NewConversationTombstoneKey(uid, 0), key.NewConversationTombstoneKey(uid, floor+1), wk.noSync); err != nil {
+		return err
+	}
+	floorBytes := make([]byte, 8)
+	wk.endian.PutUint64(floorBytes, floor)
+	return w.Set(key.NewConversationTombstoneFloorKey(uid), floorBytes, wk.noSync)
 }
 
-// DeleteConversations 批量删除最近会话
-func (wk *wukongDB) DeleteConversations(uid string, channels []Channel) error {
-	batch := wk.shardDB(uid).NewBatch()
-	defer batch.Close()
+// GetConversationTombstoneFloor 获取已删除会话记录被清理到的版本号，版本号小于等于它的删除记录可能已经不存在了
+func (wk *wukongDB) GetConversationTombstoneFloor(uid string) (uint64, error) {
+	value, closer, err := wk.shardDB(uid).Get(key.NewConversationTombstoneFloorKey(uid))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	if len(value) < 8 {
+		return 0, nil
+	}
+	return wk.endian.Uint64(value), nil
+}
 
-	for _, channel := range channels {
-		err := wk.deleteConversation(uid, channel.ChannelId, channel.ChannelType, batch)
+// GetConversationVersion 获取用户会话的当前版本号
+func (wk *wukongDB) GetConversationVersion(uid string) (uint64, error) {
+	value, closer, err := wk.shardDB(uid).Get(key.NewConversationVersionKey(uid))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	if len(value) < 8 {
+		return 0, nil
+	}
+	return wk.endian.Uint64(value), nil
+}
+
+// GetConversationsByVersion 获取版本号大于version的会话，按版本号升序
+func (wk *wukongDB) GetConversationsByVersion(uid string, version uint64, limit int) ([]Conversation, error) {
+	db := wk.shardDB(uid)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewConversationSecondIndexKey(uid, key.TableConversation.SecondIndex.Version, version+1, 0),
+		UpperBound: key.NewConversationSecondIndexKey(uid, key.TableConversation.SecondIndex.Version, math.MaxUint64, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	conversations := make([]Conversation, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		id, _, _, err := key.ParseConversationSecondIndexKey(iter.Key())
 		if err != nil {
-			return err
+			return nil, err
+		}
+		conversation, err := wk.getConversation(uid, id)
+		if err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, conversation)
+		if limit > 0 && len(conversations) >= limit {
+			break
 		}
 	}
-	return batch.Commit(wk.sync)
+	return conversations, nil
+}
+
+// GetConversationTombstones 获取版本号大于version的已删除会话，按版本号升序
+func (wk *wukongDB) GetConversationTombstones(uid string, version uint64, limit int) ([]ConversationTombstone, error) {
+	db := wk.shardDB(uid)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewConversationTombstoneKey(uid, version+1),
+		UpperBound: key.NewConversationTombstoneKey(uid, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	tombstones := make([]ConversationTombstone, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var tombstone ConversationTombstone
+		if err := tombstone.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		tombstones = append(tombstones, tombstone)
+		if limit > 0 && len(tombstones) >= limit {
+			break
+		}
+	}
+	return tombstones, nil
+}
+
+func (wk *wukongDB) writeConversationVersion(uid string, version uint64, w pebble.Writer) error {
+	versionBytes := make([]byte, 8)
+	wk.endian.PutUint64(versionBytes, version)
+	return w.Set(key.NewConversationVersionKey(uid), versionBytes, wk.noSync)
 }
 
 func (wk *wukongDB) SearchConversation(req ConversationSearchReq) ([]Conversation, error) {
@@ -213,26 +353,42 @@ func (wk *wukongDB) SearchConversation(req ConversationSearchReq) ([]Conversatio
 	return conversations, nil
 }
 
-func (wk *wukongDB) deleteConversation(uid string, channelId string, channelType uint8, w pebble.Writer) error {
+// deleteConversation 删除会话并记录删除时的版本号，会话不存在返回false
+func (wk *wukongDB) deleteConversation(uid string, channelId string, channelType uint8, version uint64, w pebble.Writer) (bool, error) {
 	oldConversation, err := wk.GetConversation(uid, channelId, channelType)
 	if err != nil && err != ErrNotFound {
-		return err
+		return false, err
 	}
 	if IsEmptyConversation(oldConversation) {
-		return nil
+		return false, nil
 	}
 	// 删除索引
 	err = wk.deleteConversationIndex(oldConversation, w)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// 删除数据
 	err = w.DeleteRange(key.NewConversationColumnKey(uid, oldConversation.Id, key.MinColumnKey), key.NewConversationColumnKey(uid, oldConversation.Id, key.MaxColumnKey), wk.noSync)
 	if err != nil {
-		return err
+		return false, err
 	}
-	return nil
+
+	// 记录删除
+	tombstone := ConversationTombstone{
+		Uid:         uid,
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Version:     version,
+	}
+	data, err := tombstone.Marshal()
+	if err != nil {
+		return false, err
+	}
+	if err = w.Set(key.NewConversationTombstoneKey(uid, version), data, wk.noSync); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // GetConversation 获取指定用户的指定会话
@@ -430,6 +586,35 @@ func (wk *wukongDB) writeConversation(conversation Conversation, w pebble.Writer
 		}
 	}
 
+	// version
+	versionBytes := make([]byte, 8)
+	wk.endian.PutUint64(versionBytes, conversation.Version)
+	if err = w.Set(key.NewConversationColumnKey(uid, id, key.TableConversation.Column.Version), versionBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// extra（为nil时不修改）
+	if conversation.Extra != nil {
+		extraBytes, err := conversation.Extra.Marshal()
+		if err != nil {
+			return err
+		}
+		if err = w.Set(key.NewConversationColumnKey(uid, id, key.TableConversation.Column.Extra), extraBytes, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// mention（为nil时不修改）
+	if conversation.Mention != nil {
+		mentionBytes, err := conversation.Mention.Marshal()
+		if err != nil {
+			return err
+		}
+		if err = w.Set(key.NewConversationColumnKey(uid, id, key.TableConversation.Column.Mention), mentionBytes, wk.noSync); err != nil {
+			return err
+		}
+	}
+
 	// write index
 	if err = wk.writeConversationIndex(conversation, w); err != nil {
 		return err
@@ -467,6 +652,13 @@ func (wk *wukongDB) writeConversationIndex(conversation Conversation, w pebble.W
 		}
 	}
 
+	if conversation.Version > 0 {
+		// version second index
+		if err := w.Set(key.NewConversationSecondIndexKey(conversation.Uid, key.TableConversation.SecondIndex.Version, conversation.Version, conversation.Id), nil, wk.noSync); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -495,6 +687,13 @@ func (wk *wukongDB) deleteConversationIndex(conversation Conversation, w pebble.
 		}
 	}
 
+	if conversation.Version > 0 {
+		// version second index
+		if err := w.Delete(key.NewConversationSecondIndexKey(conversation.Uid, key.TableConversation.SecondIndex.Version, conversation.Version, conversation.Id), wk.noSync); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -551,6 +750,20 @@ func (wk *wukongDB) iterateConversation(iter *pebble.Iterator, iterFnc func(conv
 				t := time.Unix(tm/1e9, tm%1e9)
 				preConversation.UpdatedAt = &t
 			}
+		case key.TableConversation.Column.Version:
+			preConversation.Version = wk.endian.Uint64(iter.Value())
+		case key.TableConversation.Column.Extra:
+			extra := &ConversationExtra{}
+			if err := extra.Unmarshal(iter.Value()); err != nil {
+				return err
+			}
+			preConversation.Extra = extra
+		case key.TableConversation.Column.Mention:
+			mention := &ConversationMention{}
+			if err := mention.Unmarshal(iter.Value()); err != nil {
+				return err
+			}
+			preConversation.Mention = mention
 
 		}
 		hasData = true
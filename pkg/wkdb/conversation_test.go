@@ -1,6 +1,7 @@
 package wkdb_test
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -152,6 +153,7 @@ func TestDeleteConversation(t *testing.T) {
 
 	assert.Len(t, conversations2, 1)
 	conversations[1].Id = conversations2[0].Id
+	conversations[1].Version = conversations2[0].Version // 版本号写入时生成
 	assert.Equal(t, conversations[1], conversations2[0])
 }
 
@@ -195,3 +197,252 @@ func TestDeleteConversation(t *testing.T) {
 // 	assert.Equal(t, conversations[0], conversations2[0])
 // 	assert.Equal(t, conversations[1], conversations2[1])
 // }
+
+func TestConversationVersion(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	uid := "test1"
+	updatedAt := time.Now()
+	newConversation := func(id uint64, channelId string) wkdb.Conversation {
+		return wkdb.Conversation{
+			Id:          id,
+			Uid:         uid,
+			ChannelId:   channelId,
+			ChannelType: 2,
+			UpdatedAt:   &updatedAt,
+		}
+	}
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{newConversation(1, "g1"), newConversation(2, "g2"), newConversation(3, "g3")})
+	assert.NoError(t, err)
+
+	version, err := d.GetConversationVersion(uid)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), version)
+
+	// 更新会话会递增版本号
+	cn := newConversation(0, "g1")
+	cn.ReadToMsgSeq = 10
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{cn})
+	assert.NoError(t, err)
+
+	// 删除会话也会递增版本号
+	err = d.DeleteConversation(uid, "g2", 2)
+	assert.NoError(t, err)
+
+	// 删除不存在的会话不会改变版本号
+	err = d.DeleteConversation(uid, "g100", 2)
+	assert.NoError(t, err)
+
+	version, err = d.GetConversationVersion(uid)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), version)
+
+	conversations, err := d.GetConversationsByVersion(uid, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(conversations))
+	assert.Equal(t, "g3", conversations[0].ChannelId)
+	assert.Equal(t, uint64(3), conversations[0].Version)
+	assert.Equal(t, "g1", conversations[1].ChannelId)
+	assert.Equal(t, uint64(4), conversations[1].Version)
+	assert.Equal(t, uint64(10), conversations[1].ReadToMsgSeq)
+
+	conversations, err = d.GetConversationsByVersion(uid, 3, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(conversations))
+	assert.Equal(t, "g1", conversations[0].ChannelId)
+
+	conversations, err = d.GetConversationsByVersion(uid, 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(conversations))
+
+	tombstones, err := d.GetConversationTombstones(uid, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(tombstones))
+	assert.Equal(t, "g2", tombstones[0].ChannelId)
+	assert.Equal(t, uint64(5), tombstones[0].Version)
+
+	tombstones, err = d.GetConversationTombstones(uid, 5, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(tombstones))
+}
+
+func TestConversationDuplicateInBatch(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	uid := "test1"
+	updatedAt := time.Now()
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{
+		{Id: 1, Uid: uid, ChannelId: "g1", ChannelType: 2, UpdatedAt: &updatedAt, ReadToMsgSeq: 1},
+		{Id: 2, Uid: uid, ChannelId: "g1", ChannelType: 2, UpdatedAt: &updatedAt, ReadToMsgSeq: 2},
+	})
+	assert.NoError(t, err)
+
+	// 同一个频道只保留一条会话，版本索引只有最新的版本
+	conversations, err := d.GetConversationsByVersion(uid, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(conversations))
+	assert.Equal(t, uint64(1), conversations[0].Id)
+	assert.Equal(t, uint64(2), conversations[0].Version)
+	assert.Equal(t, uint64(2), conversations[0].ReadToMsgSeq)
+
+	conversations, err = d.GetConversations(uid)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(conversations))
+}
+
+func TestConversationTombstoneGC(t *testing.T) {
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(t.TempDir()), wkdb.WithShardNum(1), wkdb.WithConversationTombstoneRetain(2)))
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	uid := "test1"
+	updatedAt := time.Now()
+	for i := 1; i <= 3; i++ {
+		channelId := fmt.Sprintf("g%d", i)
+		err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{{Id: uint64(i), Uid: uid, ChannelId: channelId, ChannelType: 2, UpdatedAt: &updatedAt}})
+		assert.NoError(t, err)
+	}
+	// 版本号 4、5、6
+	for i := 1; i <= 3; i++ {
+		err = d.DeleteConversation(uid, fmt.Sprintf("g%d", i), 2)
+		assert.NoError(t, err)
+	}
+
+	floor, err := d.GetConversationTombstoneFloor(uid)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), floor)
+
+	tombstones, err := d.GetConversationTombstones(uid, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(tombstones))
+	assert.Equal(t, uint64(5), tombstones[0].Version)
+	assert.Equal(t, uint64(6), tombstones[1].Version)
+}
+
+func TestConversationExtra(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	uid := "test1"
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{
+		{
+			Id:          1,
+			Uid:         uid,
+			ChannelId:   "g1",
+			ChannelType: 2,
+			Extra: &wkdb.ConversationExtra{
+				Pin:    true,
+				Draft:  "hello",
+				Custom: `{"label":"work"}`,
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	conversation, err := d.GetConversation(uid, "g1", 2)
+	assert.NoError(t, err)
+	assert.NotNil(t, conversation.Extra)
+	assert.True(t, conversation.Extra.Pin)
+	assert.False(t, conversation.Extra.Mute)
+	assert.Equal(t, "hello", conversation.Extra.Draft)
+	assert.Equal(t, `{"label":"work"}`, conversation.Extra.Custom)
+	assert.Equal(t, uint64(1), conversation.Extra.Version)
+
+	// 不带扩展属性更新会话，扩展属性保持不变
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{
+		{
+			Uid:          uid,
+			ChannelId:    "g1",
+			ChannelType:  2,
+			ReadToMsgSeq: 10,
+		},
+	})
+	assert.NoError(t, err)
+
+	conversation, err = d.GetConversation(uid, "g1", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), conversation.ReadToMsgSeq)
+	assert.Equal(t, uint64(2), conversation.Version)
+	assert.NotNil(t, conversation.Extra)
+	assert.True(t, conversation.Extra.Pin)
+	assert.Equal(t, uint64(1), conversation.Extra.Version)
+
+	// 修改扩展属性
+	conversation.Extra = &wkdb.ConversationExtra{Mute: true}
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{conversation})
+	assert.NoError(t, err)
+
+	conversation, err = d.GetConversation(uid, "g1", 2)
+	assert.NoError(t, err)
+	assert.False(t, conversation.Extra.Pin)
+	assert.True(t, conversation.Extra.Mute)
+	assert.Equal(t, "", conversation.Extra.Draft)
+	assert.Equal(t, uint64(3), conversation.Extra.Version)
+}
+
+func TestConversationMention(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	uid := "test1"
+	mention := (*wkdb.ConversationMention)(nil).Merge(&wkdb.ConversationMention{Count: 1, MsgSeq: 5})
+	mention = mention.Merge(&wkdb.ConversationMention{Count: 2, MsgSeq: 8, All: true})
+	assert.Equal(t, uint32(3), mention.Count)
+	assert.Equal(t, uint64(8), mention.MsgSeq)
+	assert.True(t, mention.All)
+
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{
+		{Id: 1, Uid: uid, ChannelId: "g1", ChannelType: 2, Mention: mention},
+	})
+	assert.NoError(t, err)
+
+	// 不带@提醒更新会话，@提醒保持不变
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{
+		{Uid: uid, ChannelId: "g1", ChannelType: 2, ReadToMsgSeq: 2},
+	})
+	assert.NoError(t, err)
+
+	conversation, err := d.GetConversation(uid, "g1", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, *mention, *conversation.Mention)
+
+	// 清除@提醒
+	conversation.Mention = &wkdb.ConversationMention{}
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{conversation})
+	assert.NoError(t, err)
+
+	conversation, err = d.GetConversation(uid, "g1", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), conversation.Mention.Count)
+}
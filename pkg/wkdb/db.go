@@ -17,6 +17,8 @@ type DB interface {
 	ConversationDB
 	// 频道分布式配置
 	ChannelClusterConfigDB
+	// 槽状态校验
+	SlotStateDB
 	// 领导任期开始的第一条日志索引
 	LeaderTermSequenceDB
 	// 会话
@@ -25,6 +27,19 @@ type DB interface {
 	TotalDB
 	//	系统账号
 	SystemUidDB
+	// webhook重试和死信
+	WebhookDB
+	EventSinkDB
+	// 消息回应
+	ReactionDB
+	// 跨集群镜像
+	MirrorDB
+
+	// 分层存储
+	TieredDB
+
+	// 静态加密
+	EncryptionDB
 }
 
 type MessageDB interface {
@@ -69,11 +84,17 @@ type MessageDB interface {
 	// GetMessagesOfNotifyQueue 获取通知队列的消息
 	GetMessagesOfNotifyQueue(count int) ([]Message, error)
 
-	// RemoveMessagesOfNotifyQueue 移除通知队列的消息
+	// RemoveMessagesOfNotifyQueue 移除通知队列的消息（包括消息的重试次数）
 	RemoveMessagesOfNotifyQueue(messageIDs []int64) error
 
 	// 搜索消息
 	SearchMessages(req MessageSearchReq) ([]Message, error)
+
+	// GetMessagesByFromUid 获取本节点上指定发送者的所有消息
+	GetMessagesByFromUid(fromUid string) ([]Message, error)
+
+	// EraseMessagesByFromUid 擦除本节点上指定发送者的消息（清空payload和发送者），返回擦除的消息数量
+	EraseMessagesByFromUid(fromUid string) (int, error)
 }
 
 type DeviceDB interface {
@@ -91,6 +112,9 @@ type DeviceDB interface {
 
 	// UpdateDevice 更新设备
 	UpdateDevice(device Device) error
+
+	// DeleteDevices 删除用户的所有设备
+	DeleteDevices(uid string) error
 }
 
 type UserDB interface {
@@ -111,6 +135,9 @@ type UserDB interface {
 
 	// UpdateUser 更新用户
 	UpdateUser(u User) error
+
+	// DeleteUser 删除用户
+	DeleteUser(uid string) error
 }
 
 type ChannelDB interface {
@@ -182,6 +209,9 @@ type ChannelDB interface {
 
 	// SearchChannels 搜索频道
 	SearchChannels(req ChannelSearchReq) ([]ChannelInfo, error)
+
+	// GetChannelsByMember 获取本节点上用户作为订阅者、黑名单或白名单成员所在的频道
+	GetChannelsByMember(uid string) ([]MemberOfChannel, error)
 }
 
 type ConversationDB interface {
@@ -203,6 +233,18 @@ type ConversationDB interface {
 	// GetLastConversations 获取指定用户的最近会话
 	GetLastConversations(uid string, tp ConversationType, updatedAt uint64, limit int) ([]Conversation, error)
 
+	// GetConversationVersion 获取用户会话的当前版本号
+	GetConversationVersion(uid string) (uint64, error)
+
+	// GetConversationsByVersion 获取版本号大于version的会话，按版本号升序
+	GetConversationsByVersion(uid string, version uint64, limit int) ([]Conversation, error)
+
+	// GetConversationTombstones 获取版本号大于version的已删除会话，按版本号升序
+	GetConversationTombstones(uid string, version uint64, limit int) ([]ConversationTombstone, error)
+
+	// GetConversationTombstoneFloor 获取已删除会话记录被清理到的版本号，客户端的版本号小于它时需要全量同步
+	GetConversationTombstoneFloor(uid string) (uint64, error)
+
 	// GetConversation 获取指定用户的指定会话
 	GetConversation(uid string, channelId string, channelType uint8) (Conversation, error)
 
@@ -215,6 +257,11 @@ type ConversationDB interface {
 	SearchConversation(req ConversationSearchReq) ([]Conversation, error)
 }
 
+type SlotStateDB interface {
+	// SlotStateChecksum 计算槽内已应用的用户、频道、频道成员和最近会话的校验和，slotOf返回uid或频道id所在的槽
+	SlotStateChecksum(slotId uint32, slotOf func(v string) uint32) (uint32, error)
+}
+
 type ChannelClusterConfigDB interface {
 
 	// SaveChannelClusterConfig 保存频道的分布式配置
@@ -329,6 +376,53 @@ type TotalDB interface {
 	GetTotalChannelClusterConfigCount() (int, error)
 }
 
+type WebhookDB interface {
+	// IncMessageNotifyRetryCount 增加通知队列消息的重试次数，返回增加后的次数
+	IncMessageNotifyRetryCount(messageIDs []int64) (map[int64]int, error)
+	// GetMessageNotifyRetryCount 获取通知队列消息的重试次数
+	GetMessageNotifyRetryCount(messageID int64) (int, error)
+	// MoveMessagesOfNotifyQueueToDeadLetter 将通知队列的消息移到死信里
+	MoveMessagesOfNotifyQueueToDeadLetter(messageIDs []int64, deadLetter WebhookDeadLetter) error
+	// AddWebhookDeadLetter 添加死信
+	AddWebhookDeadLetter(deadLetter WebhookDeadLetter) error
+	// GetWebhookDeadLetters 获取死信列表 startId表示从此id之后开始获取（不包含startId）
+	GetWebhookDeadLetters(startId uint64, limit int) ([]WebhookDeadLetter, error)
+	// GetWebhookDeadLetter 获取指定id的死信
+	GetWebhookDeadLetter(id uint64) (WebhookDeadLetter, error)
+	// RemoveWebhookDeadLetters 移除死信
+	RemoveWebhookDeadLetters(ids []uint64) error
+	// RemoveAllWebhookDeadLetters 清空死信
+	RemoveAllWebhookDeadLetters() error
+}
+
+type EventSinkDB interface {
+	// AppendEventSinkEvents 追加待投递的事件，事件id需要递增
+	AppendEventSinkEvents(events []SinkEvent) error
+	// GetEventSinkEvents 获取待投递的事件 startId表示从此id之后开始获取（不包含startId）
+	GetEventSinkEvents(startId uint64, limit int) ([]SinkEvent, error)
+	// RemoveEventSinkEvents 移除id小于等于endId的事件
+	RemoveEventSinkEvents(endId uint64) error
+	// GetEventSinkCheckpoint 获取投递进度（最后一个已确认的事件id）
+	GetEventSinkCheckpoint(sinkName string) (uint64, error)
+	// SetEventSinkCheckpoint 保存投递进度
+	SetEventSinkCheckpoint(sinkName string, eventId uint64) error
+}
+
+type MirrorDB interface {
+	// GetMirrorCheckpoint 获取镜像进度
+	GetMirrorCheckpoint(name string) (uint64, error)
+	// SetMirrorCheckpoint 保存镜像进度
+	SetMirrorCheckpoint(name string, value uint64) error
+	// MarkMirrorChannel 标记频道有新消息需要镜像，messageSeq为需要镜像到的消息序号（只会变大）
+	MarkMirrorChannel(channelId string, channelType uint8, messageSeq uint64) error
+	// MarkMirrorChannels 批量标记频道有新消息需要镜像（同一个频道只能出现一次）
+	MarkMirrorChannels(channels []MirrorChannel) error
+	// GetMirrorChannels 获取等待镜像的频道，从指定频道之后（不包含）开始获取，startChannelId为空表示从头开始
+	GetMirrorChannels(startChannelId string, startChannelType uint8, limit int) ([]MirrorChannel, error)
+	// RemoveMirrorChannel 频道已镜像到messageSeq，如果期间没有新的标记则移除
+	RemoveMirrorChannel(channelId string, channelType uint8, messageSeq uint64) error
+}
+
 type SystemUidDB interface {
 	// AddSystemUids  添加系统账号的uid
 	AddSystemUids(uids []string) error
@@ -405,3 +499,32 @@ type ChannelClusterConfigSearchReq struct {
 	Pre             bool   // 是否向前搜索
 
 }
+
+type ReactionDB interface {
+	// AddOrUpdateReactions 添加或更新消息回应（取消回应时IsDeleted为true），每次变更频道的回应版本号递增
+	AddOrUpdateReactions(channelId string, channelType uint8, reactions []Reaction) error
+	// GetReactions 获取消息的所有回应（不包含已取消的）
+	GetReactions(channelId string, channelType uint8, messageSeq uint64) ([]Reaction, error)
+	// GetReactionSummary 获取消息的回应汇总（每个表情的回应数和最近回应的用户）
+	GetReactionSummary(channelId string, channelType uint8, messageSeq uint64) (ReactionSummary, error)
+	// GetUserReactions 获取用户对消息的回应（不包含已取消的）
+	GetUserReactions(channelId string, channelType uint8, messageSeq uint64, uid string) ([]Reaction, error)
+	// GetReactionsByVersion 获取版本号大于version的回应变更（包含已取消的），按版本号升序
+	GetReactionsByVersion(channelId string, channelType uint8, version uint64, limit int) ([]Reaction, error)
+	// GetReactionVersion 获取频道消息回应的当前版本号
+	GetReactionVersion(channelId string, channelType uint8) (uint64, error)
+}
+
+type TieredDB interface {
+	// OffloadChannelMessages 将频道较旧的消息下沉到对象存储，返回本次下沉的段数量
+	OffloadChannelMessages(channelId string, channelType uint8) (int, error)
+	// GetChannelSegments 获取频道已下沉到对象存储的段
+	GetChannelSegments(channelId string, channelType uint8) ([]ChannelSegment, error)
+}
+
+type EncryptionDB interface {
+	// RotateDataKeys 轮换数据密钥，已有消息在后台重新加密
+	RotateDataKeys() error
+	// EncryptionStatus 获取静态加密状态
+	EncryptionStatus() (EncryptionStatus, error)
+}
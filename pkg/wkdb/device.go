@@ -186,6 +186,32 @@ func (wk *wukongDB) UpdateDevice(d Device) error {
 	return nil
 }
 
+func (wk *wukongDB) DeleteDevices(uid string) error {
+	devices, err := wk.GetDevices(uid)
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return nil
+	}
+
+	db := wk.shardDB(uid)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	for _, d := range devices {
+		// 删除索引
+		if err = wk.deleteDeviceIndex(d, batch); err != nil {
+			return err
+		}
+		// 删除数据
+		if err = batch.DeleteRange(key.NewDeviceColumnKey(d.Id, key.MinColumnKey), key.NewDeviceColumnKey(d.Id, key.MaxColumnKey), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
 func (wk *wukongDB) SearchDevice(req DeviceSearchReq) ([]Device, error) {
 
 	iterFnc := func(devices *[]Device) func(d Device) bool {
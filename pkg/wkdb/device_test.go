@@ -147,3 +147,33 @@ func TestSearchDevices(t *testing.T) {
 	assert.Equal(t, 1, len(us))
 
 }
+
+func TestDeleteDevices(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	tn := time.Now()
+	err = d.AddDevice(wkdb.Device{Id: 1, Uid: "test", Token: "token1", DeviceFlag: 0, CreatedAt: &tn, UpdatedAt: &tn})
+	assert.NoError(t, err)
+	err = d.AddDevice(wkdb.Device{Id: 2, Uid: "test", Token: "token2", DeviceFlag: 1, CreatedAt: &tn, UpdatedAt: &tn})
+	assert.NoError(t, err)
+	err = d.AddDevice(wkdb.Device{Id: 3, Uid: "test2", Token: "token3", DeviceFlag: 1, CreatedAt: &tn, UpdatedAt: &tn})
+	assert.NoError(t, err)
+
+	err = d.DeleteDevices("test")
+	assert.NoError(t, err)
+
+	devices, err := d.GetDevices("test")
+	assert.NoError(t, err)
+	assert.Len(t, devices, 0)
+
+	devices, err = d.GetDevices("test2")
+	assert.NoError(t, err)
+	assert.Len(t, devices, 1)
+}
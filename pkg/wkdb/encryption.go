@@ -0,0 +1,656 @@
+package wkdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/kms"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+	"go.uber.org/zap"
+)
+
+// 静态加密：消息内容使用每个分区自己的数据密钥（AES-256-GCM）加密后保存，数据密钥被主密钥包装后保存在分区里。
+// 加密后的格式: shardId(4) + keyVersion(4) + nonce + 密文，消息内容的附加数据为频道hash+消息序号，
+// 避免密文被挪到其他消息上。是否加密不从内容判断：密文保存在消息的EncryptedPayload列，Payload列始终是明文
+// （开启加密前写入的数据），段在段清单里记录加密的密钥版本。后台会逐步把明文和旧版本密钥加密的内容重新加密，
+// 完成后删除不再被引用的旧数据密钥。
+
+const encryptedHeaderSize = 4 + 4
+
+var errEncryptionOff = errors.New("data is encrypted but encryption is not configured")
+
+const kmsTimeout = time.Second * 30
+
+type dataKeyring struct {
+	mu        sync.RWMutex
+	current   []uint32               // 每个分区当前的数据密钥版本
+	aeads     map[uint64]cipher.AEAD // shardId<<32 | version
+	keyCount  []int
+	reencrypt []reencryptState
+}
+
+// reencryptState 分区后台重新加密的进度，先处理本地消息再处理已下沉的段
+type reencryptState struct {
+	running  bool
+	gen      uint64 // 每次轮换+1
+	segments bool   // 是否已经在处理段
+	cursor   []byte
+	count    uint64
+	skipped  uint64 // 无法重新加密的数量，不为0时保留旧的数据密钥
+}
+
+func newDataKeyring(shardNum int) *dataKeyring {
+	return &dataKeyring{
+		current:   make([]uint32, shardNum),
+		aeads:     make(map[uint64]cipher.AEAD),
+		keyCount:  make([]int, shardNum),
+		reencrypt: make([]reencryptState, shardNum),
+	}
+}
+
+func (k *dataKeyring) currentAEAD(shardId uint32) (uint32, cipher.AEAD) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	version := k.current[shardId]
+	return version, k.aeads[uint64(shardId)<<32|uint64(version)]
+}
+
+func (k *dataKeyring) aead(shardId, version uint32) cipher.AEAD {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.aeads[uint64(shardId)<<32|uint64(version)]
+}
+
+func (k *dataKeyring) add(shardId, version uint32, aead cipher.AEAD) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.aeads[uint64(shardId)<<32|uint64(version)] = aead
+	k.keyCount[shardId]++
+	if version > k.current[shardId] {
+		k.current[shardId] = version
+	}
+}
+
+func (k *dataKeyring) remove(shardId, version uint32) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.aeads[uint64(shardId)<<32|uint64(version)]; !ok {
+		return
+	}
+	delete(k.aeads, uint64(shardId)<<32|uint64(version))
+	k.keyCount[shardId]--
+}
+
+// openKeyring 加载所有分区的数据密钥，分区没有数据密钥则创建
+func (wk *wukongDB) openKeyring() error {
+	wk.keyring = newDataKeyring(len(wk.dbs))
+	for i := range wk.dbs {
+		shardId := uint32(i)
+		dataKeys, err := wk.getDataKeys(shardId)
+		if err != nil {
+			return err
+		}
+		for _, dk := range dataKeys {
+			if err = wk.addDataKey(shardId, dk); err != nil {
+				return err
+			}
+		}
+		if len(dataKeys) == 0 {
+			if err = wk.newDataKey(shardId, 1); err != nil {
+				return err
+			}
+		}
+		// 启动后检查一遍，把开启加密前的明文和旧版本密钥加密的内容重新加密
+		wk.keyring.reencrypt[shardId].running = true
+	}
+	return nil
+}
+
+func (wk *wukongDB) getDataKeys(shardId uint32) ([]DataKey, error) {
+	iter := wk.dbs[shardId].NewIter(&pebble.IterOptions{
+		LowerBound: key.NewDataKeyLowKey(),
+		UpperBound: key.NewDataKeyHighKey(),
+	})
+	defer iter.Close()
+
+	dataKeys := make([]DataKey, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var dk DataKey
+		if err := dk.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		dataKeys = append(dataKeys, dk)
+	}
+	return dataKeys, nil
+}
+
+func (wk *wukongDB) addDataKey(shardId uint32, dk DataKey) error {
+	ctx, cancel := context.WithTimeout(wk.cancelCtx, kmsTimeout)
+	defer cancel()
+	plaintext, err := wk.opts.MasterKey.Unwrap(ctx, dk.MasterKeyId, dk.Wrapped)
+	if err != nil {
+		return fmt.Errorf("unwrap data key[shard:%d version:%d] failed: %w", shardId, dk.Version, err)
+	}
+	aead, err := kms.NewAEAD(plaintext)
+	if err != nil {
+		return err
+	}
+	wk.keyring.add(shardId, dk.Version, aead)
+	return nil
+}
+
+// newDataKey 生成新的数据密钥，用主密钥包装后保存
+func (wk *wukongDB) newDataKey(shardId uint32, version uint32) error {
+	plaintext, err := kms.GenerateKey()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(wk.cancelCtx, kmsTimeout)
+	masterKeyId, wrapped, err := wk.opts.MasterKey.Wrap(ctx, plaintext)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("wrap data key failed: %w", err)
+	}
+	dk := DataKey{
+		Version:     version,
+		MasterKeyId: masterKeyId,
+		Wrapped:     wrapped,
+		CreatedAt:   time.Now().Unix(),
+	}
+	data, err := dk.Marshal()
+	if err != nil {
+		return err
+	}
+	if err = wk.dbs[shardId].Set(key.NewDataKeyKey(version), data, wk.sync); err != nil {
+		return err
+	}
+	aead, err := kms.NewAEAD(plaintext)
+	if err != nil {
+		return err
+	}
+	wk.keyring.add(shardId, version, aead)
+	return nil
+}
+
+// RotateDataKeys 所有分区生成新版本的数据密钥，新写入的消息和段使用新密钥，已有消息和已下沉的段在后台重新加密，
+// 完成后删除旧版本的数据密钥。同时用当前主密钥重新包装旧的数据密钥（主密钥轮换后调用即可淘汰旧主密钥）。
+func (wk *wukongDB) RotateDataKeys() error {
+	if wk.keyring == nil {
+		return errors.New("encryption is not enabled")
+	}
+	wk.rotateLock.Lock()
+	defer wk.rotateLock.Unlock()
+
+	if r, ok := wk.opts.MasterKey.(kms.Reloader); ok {
+		if err := r.Reload(); err != nil {
+			return err
+		}
+	}
+
+	for i := range wk.dbs {
+		shardId := uint32(i)
+		if err := wk.rewrapDataKeys(shardId); err != nil {
+			return err
+		}
+		// 写消息时加了读锁，切换版本后不会再有用旧版本加密的消息写入，重新加密扫描一遍即可覆盖所有旧数据
+		wk.shardLocks[shardId].Lock()
+		version, _ := wk.keyring.currentAEAD(shardId)
+		err := wk.newDataKey(shardId, version+1)
+		wk.shardLocks[shardId].Unlock()
+		if err != nil {
+			return err
+		}
+		wk.keyring.mu.Lock()
+		wk.keyring.reencrypt[shardId] = reencryptState{running: true, gen: wk.keyring.reencrypt[shardId].gen + 1}
+		wk.keyring.mu.Unlock()
+	}
+	wk.Info("rotate data keys")
+	return nil
+}
+
+// rewrapDataKeys 用当前主密钥重新包装分区的数据密钥
+func (wk *wukongDB) rewrapDataKeys(shardId uint32) error {
+	ctx, cancel := context.WithTimeout(wk.cancelCtx, kmsTimeout)
+	defer cancel()
+	masterKeyId, err := wk.opts.MasterKey.CurrentKeyId(ctx)
+	if err != nil {
+		return err
+	}
+	dataKeys, err := wk.getDataKeys(shardId)
+	if err != nil {
+		return err
+	}
+	batch := wk.dbs[shardId].NewBatch()
+	defer batch.Close()
+	for _, dk := range dataKeys {
+		if dk.MasterKeyId == masterKeyId {
+			continue
+		}
+		plaintext, err := wk.opts.MasterKey.Unwrap(ctx, dk.MasterKeyId, dk.Wrapped)
+		if err != nil {
+			return fmt.Errorf("unwrap data key[shard:%d version:%d] failed: %w", shardId, dk.Version, err)
+		}
+		if dk.MasterKeyId, dk.Wrapped, err = wk.opts.MasterKey.Wrap(ctx, plaintext); err != nil {
+			return err
+		}
+		data, err := dk.Marshal()
+		if err != nil {
+			return err
+		}
+		if err = batch.Set(key.NewDataKeyKey(dk.Version), data, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+// EncryptionStatus 获取静态加密状态
+func (wk *wukongDB) EncryptionStatus() (EncryptionStatus, error) {
+	if wk.keyring == nil {
+		return EncryptionStatus{}, nil
+	}
+	ctx, cancel := context.WithTimeout(wk.cancelCtx, kmsTimeout)
+	defer cancel()
+	masterKeyId, err := wk.opts.MasterKey.CurrentKeyId(ctx)
+	if err != nil {
+		return EncryptionStatus{}, err
+	}
+	status := EncryptionStatus{
+		On:          true,
+		MasterKeyId: masterKeyId,
+		Shards:      make([]ShardEncryptionStatus, 0, len(wk.dbs)),
+	}
+	wk.keyring.mu.RLock()
+	defer wk.keyring.mu.RUnlock()
+	for i := range wk.dbs {
+		status.Shards = append(status.Shards, ShardEncryptionStatus{
+			ShardId:      uint32(i),
+			KeyVersion:   wk.keyring.current[i],
+			KeyCount:     wk.keyring.keyCount[i],
+			Reencrypting: wk.keyring.reencrypt[i].running,
+			Reencrypted:  wk.keyring.reencrypt[i].count,
+		})
+	}
+	return status, nil
+}
+
+// encryptData 使用分区当前的数据密钥加密，返回密文和使用的密钥版本
+// 未开启加密或内容为空时原样返回，版本为0
+func (wk *wukongDB) encryptData(shardId uint32, additionalData, plaintext []byte) ([]byte, uint32, error) {
+	if wk.keyring == nil || len(plaintext) == 0 {
+		return plaintext, 0, nil
+	}
+	version, aead := wk.keyring.currentAEAD(shardId)
+	if aead == nil {
+		return nil, 0, fmt.Errorf("data key[shard:%d] not found", shardId)
+	}
+	data := make([]byte, encryptedHeaderSize+aead.NonceSize(), encryptedHeaderSize+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	binary.BigEndian.PutUint32(data, shardId)
+	binary.BigEndian.PutUint32(data[4:], version)
+	nonce := data[encryptedHeaderSize:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, 0, err
+	}
+	return aead.Seal(data, nonce, plaintext, additionalData), version, nil
+}
+
+// decryptData 解密encryptData加密的内容
+func (wk *wukongDB) decryptData(additionalData, data []byte) ([]byte, error) {
+	if wk.keyring == nil {
+		return nil, errEncryptionOff
+	}
+	if len(data) < encryptedHeaderSize {
+		return nil, errors.New("encrypted data too short")
+	}
+	shardId, version := encryptedKeyVersion(data)
+	aead := wk.keyring.aead(shardId, version)
+	if aead == nil {
+		return nil, fmt.Errorf("data key[shard:%d version:%d] not found", shardId, version)
+	}
+	if len(data) < encryptedHeaderSize+aead.NonceSize() {
+		return nil, errors.New("encrypted data too short")
+	}
+	nonce := data[encryptedHeaderSize : encryptedHeaderSize+aead.NonceSize()]
+	return aead.Open(nil, nonce, data[encryptedHeaderSize+aead.NonceSize():], additionalData)
+}
+
+func encryptedKeyVersion(data []byte) (shardId uint32, version uint32) {
+	if len(data) < encryptedHeaderSize {
+		return 0, 0
+	}
+	return binary.BigEndian.Uint32(data), binary.BigEndian.Uint32(data[4:])
+}
+
+// decodePayload 解密消息EncryptedPayload列的内容，key为列的key（附加数据为其中的频道hash+消息序号）
+func (wk *wukongDB) decodePayload(columnKey, value []byte) ([]byte, error) {
+	return wk.decryptData(columnKey[4:20], value)
+}
+
+func (wk *wukongDB) reencryptLoop() {
+	defer wk.reencryptWg.Done()
+	tk := time.NewTicker(wk.opts.ReencryptInterval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-tk.C:
+			for i := range wk.dbs {
+				if wk.cancelCtx.Err() != nil {
+					return
+				}
+				if err := wk.reencryptShard(uint32(i)); err != nil {
+					wk.Error("reencrypt shard failed", zap.Error(err), zap.Int("shardId", i))
+				}
+			}
+		case <-wk.cancelCtx.Done():
+			return
+		}
+	}
+}
+
+type staleColumn struct {
+	key   []byte
+	value []byte
+	plain bool // Payload列的明文
+}
+
+// reencryptShard 将分区中明文或旧版本密钥加密的消息内容用当前密钥重新加密，每次最多处理ReencryptBatchSize条
+// 本地消息处理完后再重新加密已下沉的段，全部完成后删除旧版本的数据密钥
+func (wk *wukongDB) reencryptShard(shardId uint32) error {
+	wk.keyring.mu.RLock()
+	state := wk.keyring.reencrypt[shardId]
+	wk.keyring.mu.RUnlock()
+	if !state.running {
+		return nil
+	}
+
+	currentVersion, _ := wk.keyring.currentAEAD(shardId)
+	var (
+		count      uint64
+		skipped    uint64
+		nextCursor []byte
+		err        error
+	)
+	if state.segments {
+		count, skipped, nextCursor, err = wk.reencryptSegments(shardId, currentVersion, state.cursor)
+	} else {
+		count, skipped, nextCursor, err = wk.reencryptPayloads(shardId, currentVersion, state.cursor)
+	}
+	if err != nil {
+		return err
+	}
+
+	wk.keyring.mu.Lock()
+	st := &wk.keyring.reencrypt[shardId]
+	if !st.running || st.gen != state.gen { // 期间发生了轮换，从头开始
+		wk.keyring.mu.Unlock()
+		return nil
+	}
+	st.count += count
+	st.skipped += skipped
+	st.cursor = nextCursor
+	if nextCursor != nil {
+		wk.keyring.mu.Unlock()
+		return nil
+	}
+	if !st.segments {
+		st.segments = true
+		wk.keyring.mu.Unlock()
+		return nil
+	}
+	done := *st
+	wk.keyring.mu.Unlock()
+
+	if done.skipped == 0 {
+		if err = wk.deleteSupersededDataKeys(shardId, currentVersion, done.gen); err != nil {
+			return err
+		}
+	} else {
+		wk.Warn("some data can not be reencrypted, keep old data keys", zap.Uint32("shardId", shardId), zap.Uint64("skipped", done.skipped))
+	}
+
+	wk.keyring.mu.Lock()
+	defer wk.keyring.mu.Unlock()
+	st = &wk.keyring.reencrypt[shardId]
+	if st.running && st.gen == done.gen {
+		st.running = false
+		wk.Info("reencrypt shard done", zap.Uint32("shardId", shardId), zap.Uint32("keyVersion", currentVersion), zap.Uint64("count", st.count), zap.Uint64("skipped", st.skipped))
+	}
+	return nil
+}
+
+// reencryptPayloads 重新加密本地消息的内容，返回下次开始的位置，nil表示已经处理完
+func (wk *wukongDB) reencryptPayloads(shardId uint32, currentVersion uint32, cursor []byte) (uint64, uint64, []byte, error) {
+	db := wk.dbs[shardId]
+	lowerBound := key.NewMessagePrimaryKeyWithHash(0, 0)
+	if cursor != nil {
+		lowerBound = cursor
+	}
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: lowerBound,
+		UpperBound: key.NewMessagePrimaryKeyWithHash(math.MaxUint64, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	var (
+		stales     []staleColumn
+		scanned    int
+		batchSize  = wk.opts.ReencryptBatchSize
+		nextCursor []byte
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		_, columnName, err := key.ParseMessageColumnKey(iter.Key())
+		if err != nil {
+			continue
+		}
+		switch columnName {
+		case key.TableMessage.Column.Payload:
+			scanned++
+			if len(iter.Value()) > 0 {
+				stales = append(stales, staleColumn{key: bytes.Clone(iter.Key()), value: bytes.Clone(iter.Value()), plain: true})
+			}
+		case key.TableMessage.Column.EncryptedPayload:
+			scanned++
+			sid, version := encryptedKeyVersion(iter.Value())
+			if sid != shardId || version != currentVersion {
+				stales = append(stales, staleColumn{key: bytes.Clone(iter.Key()), value: bytes.Clone(iter.Value())})
+			}
+		default:
+			continue
+		}
+		// 每次处理的数量有限，避免长时间占用
+		if len(stales) >= batchSize || scanned >= batchSize*10 {
+			nextCursor = append(bytes.Clone(iter.Key()), 0)
+			break
+		}
+	}
+
+	if len(stales) == 0 {
+		return 0, 0, nextCursor, nil
+	}
+	skipped, err := wk.rewriteStalePayloads(shardId, stales)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return uint64(len(stales)) - skipped, skipped, nextCursor, nil
+}
+
+func (wk *wukongDB) rewriteStalePayloads(shardId uint32, stales []staleColumn) (uint64, error) {
+	// 加写锁，避免覆盖期间被截断或重新写入的消息
+	wk.shardLocks[shardId].Lock()
+	defer wk.shardLocks[shardId].Unlock()
+
+	db := wk.dbs[shardId]
+	batch := db.NewBatch()
+	defer batch.Close()
+	var skipped uint64
+	for _, stale := range stales {
+		value, closer, err := db.Get(stale.key)
+		if err != nil {
+			if err == pebble.ErrNotFound {
+				continue
+			}
+			return 0, err
+		}
+		changed := !bytes.Equal(value, stale.value)
+		closer.Close()
+		if changed {
+			continue
+		}
+		plaintext := stale.value
+		if !stale.plain {
+			if plaintext, err = wk.decodePayload(stale.key, stale.value); err != nil {
+				wk.Warn("decrypt payload failed, skip", zap.Error(err), zap.Uint32("shardId", shardId))
+				skipped++
+				continue
+			}
+		}
+		data, _, err := wk.encryptData(shardId, stale.key[4:20], plaintext)
+		if err != nil {
+			return 0, err
+		}
+		var primaryKey [16]byte
+		copy(primaryKey[:], stale.key[4:20])
+		if err = batch.Set(key.NewMessageColumnKeyWithPrimary(primaryKey, key.TableMessage.Column.EncryptedPayload), data, wk.noSync); err != nil {
+			return 0, err
+		}
+		if stale.plain {
+			if err = batch.Delete(stale.key, wk.noSync); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return skipped, batch.Commit(wk.sync)
+}
+
+// reencryptSegments 重新加密已下沉的段，处理的消息数量达到ReencryptBatchSize后返回下次开始的位置，nil表示已经处理完
+func (wk *wukongDB) reencryptSegments(shardId uint32, currentVersion uint32, cursor []byte) (uint64, uint64, []byte, error) {
+	db := wk.dbs[shardId]
+	lowerBound := key.NewChannelSegmentKeyWithHash(0, 0)
+	if cursor != nil {
+		lowerBound = cursor
+	}
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: lowerBound,
+		UpperBound: key.NewChannelSegmentKeyWithHash(math.MaxUint64, math.MaxUint64),
+	})
+	var (
+		stales     []ChannelSegment
+		msgCount   int
+		nextCursor []byte
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var seg ChannelSegment
+		if err := seg.Unmarshal(iter.Value()); err != nil {
+			iter.Close()
+			return 0, 0, nil, err
+		}
+		if seg.ObjectKey == "" || seg.KeyVersion == currentVersion {
+			continue
+		}
+		stales = append(stales, seg)
+		msgCount += int(seg.Count)
+		if msgCount >= wk.opts.ReencryptBatchSize {
+			nextCursor = append(bytes.Clone(iter.Key()), 0)
+			break
+		}
+	}
+	iter.Close()
+
+	var count, skipped uint64
+	for _, seg := range stales {
+		if !wk.tieredEnabled() {
+			skipped++
+			continue
+		}
+		ok, err := wk.reencryptSegment(shardId, seg)
+		if err != nil {
+			wk.Warn("reencrypt segment failed, skip", zap.Error(err), zap.String("objectKey", seg.ObjectKey))
+			skipped++
+			continue
+		}
+		if ok {
+			count += uint64(seg.Count)
+		}
+	}
+	return count, skipped, nextCursor, nil
+}
+
+// reencryptSegment 用当前的数据密钥重新加密段，段在期间被改变时跳过
+func (wk *wukongDB) reencryptSegment(shardId uint32, seg ChannelSegment) (bool, error) {
+	wk.dblock.channelSegmentLock.lock(seg.ChannelId, seg.ChannelType)
+	defer wk.dblock.channelSegmentLock.unlock(seg.ChannelId, seg.ChannelType)
+
+	value, closer, err := wk.dbs[shardId].Get(key.NewChannelSegmentKey(seg.ChannelId, seg.ChannelType, seg.StartSeq))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	var latest ChannelSegment
+	err = latest.Unmarshal(value)
+	closer.Close()
+	if err != nil {
+		return false, err
+	}
+	if latest.ObjectKey != seg.ObjectKey {
+		return false, nil
+	}
+	msgs, err := wk.loadSegmentMessages(seg)
+	if err != nil {
+		return false, err
+	}
+	return true, wk.replaceSegment(shardId, seg, msgs)
+}
+
+// deleteSupersededDataKeys 重新加密完成后删除比当前版本旧的数据密钥
+func (wk *wukongDB) deleteSupersededDataKeys(shardId uint32, currentVersion uint32, gen uint64) error {
+	// 和轮换互斥，避免轮换时重新包装的旧密钥被写回
+	wk.rotateLock.Lock()
+	defer wk.rotateLock.Unlock()
+
+	wk.keyring.mu.RLock()
+	st := wk.keyring.reencrypt[shardId]
+	wk.keyring.mu.RUnlock()
+	if st.gen != gen { // 期间又发生了轮换
+		return nil
+	}
+
+	dataKeys, err := wk.getDataKeys(shardId)
+	if err != nil {
+		return err
+	}
+	batch := wk.dbs[shardId].NewBatch()
+	defer batch.Close()
+	versions := make([]uint32, 0, len(dataKeys))
+	for _, dk := range dataKeys {
+		if dk.Version >= currentVersion {
+			continue
+		}
+		if err = batch.Delete(key.NewDataKeyKey(dk.Version), wk.noSync); err != nil {
+			return err
+		}
+		versions = append(versions, dk.Version)
+	}
+	if len(versions) == 0 {
+		return nil
+	}
+	if err = batch.Commit(wk.sync); err != nil {
+		return err
+	}
+	for _, version := range versions {
+		wk.keyring.remove(shardId, version)
+	}
+	wk.Info("delete superseded data keys", zap.Uint32("shardId", shardId), zap.Uint32s("versions", versions))
+	return nil
+}
@@ -0,0 +1,252 @@
+package wkdb_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/kms"
+	"github.com/WuKongIM/WuKongIM/pkg/objstore"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func appendTestMasterKey(t *testing.T, path string, id string) {
+	key, err := kms.GenerateKey()
+	assert.NoError(t, err)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	assert.NoError(t, err)
+	_, err = f.WriteString(id + ":" + base64.StdEncoding.EncodeToString(key) + "\n")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+}
+
+func newTestMessages(channelId string, channelType uint8, num int) []wkdb.Message {
+	messages := make([]wkdb.Message, 0, num)
+	for i := 0; i < num; i++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(100 + i),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				FromUID:     "u1",
+				Payload:     []byte(fmt.Sprintf("hello %d", i+1)),
+			},
+		})
+	}
+	return messages
+}
+
+func waitReencrypted(t *testing.T, d wkdb.DB) wkdb.EncryptionStatus {
+	var status wkdb.EncryptionStatus
+	assert.Eventually(t, func() bool {
+		var err error
+		status, err = d.EncryptionStatus()
+		assert.NoError(t, err)
+		for _, shard := range status.Shards {
+			if shard.Reencrypting {
+				return false
+			}
+		}
+		return true
+	}, time.Second*5, time.Millisecond*10)
+	return status
+}
+
+func TestEncryptionAtRest(t *testing.T) {
+	dir := t.TempDir()
+	masterKeyFile := filepath.Join(t.TempDir(), "master.key")
+	appendTestMasterKey(t, masterKeyFile, "k1")
+	mk, err := kms.NewFileMasterKey(masterKeyFile)
+	assert.NoError(t, err)
+	channelId := "channel"
+	channelType := uint8(2)
+	num := 20
+
+	// 开启加密前写入的明文消息
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1)))
+	assert.NoError(t, d.Open())
+	err = d.AppendMessages(channelId, channelType, newTestMessages(channelId, channelType, num/2))
+	assert.NoError(t, err)
+	assert.NoError(t, d.Close())
+
+	open := func() wkdb.DB {
+		d := wkdb.NewWukongDB(wkdb.NewOptions(
+			wkdb.WithDir(dir),
+			wkdb.WithShardNum(1),
+			wkdb.WithMasterKey(mk),
+			wkdb.WithReencryptInterval(time.Millisecond*10),
+			wkdb.WithReencryptBatchSize(3),
+		))
+		assert.NoError(t, d.Open())
+		return d
+	}
+
+	d = open()
+	err = d.AppendMessages(channelId, channelType, newTestMessages(channelId, channelType, num)[num/2:])
+	assert.NoError(t, err)
+
+	// 后台把明文重新加密
+	status := waitReencrypted(t, d)
+	assert.True(t, status.On)
+	assert.Equal(t, "k1", status.MasterKeyId)
+	assert.Equal(t, uint32(1), status.Shards[0].KeyVersion)
+	assert.Equal(t, uint64(num/2), status.Shards[0].Reencrypted)
+
+	// 轮换后已有消息用新密钥重新加密，完成后删除旧版本的数据密钥
+	err = d.RotateDataKeys()
+	assert.NoError(t, err)
+	status = waitReencrypted(t, d)
+	assert.Equal(t, uint32(2), status.Shards[0].KeyVersion)
+	assert.Equal(t, 1, status.Shards[0].KeyCount)
+	assert.Equal(t, uint64(num), status.Shards[0].Reencrypted)
+
+	msgs, err := d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, num)
+	for i, m := range msgs {
+		assert.Equal(t, []byte(fmt.Sprintf("hello %d", i+1)), m.Payload)
+	}
+
+	// 轮换主密钥：追加新的主密钥后轮换，旧的数据密钥用新主密钥重新包装
+	appendTestMasterKey(t, masterKeyFile, "k2")
+	err = d.RotateDataKeys()
+	assert.NoError(t, err)
+	status = waitReencrypted(t, d)
+	assert.Equal(t, "k2", status.MasterKeyId)
+	assert.Equal(t, uint32(3), status.Shards[0].KeyVersion)
+
+	// 搜索透明解密
+	msgs, err = d.SearchMessages(wkdb.MessageSearchReq{ChannelId: channelId, ChannelType: channelType, Payload: []byte("hello 15"), Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 1)
+	assert.NoError(t, d.Close())
+
+	// 没有主密钥无法读取
+	d = wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1)))
+	assert.NoError(t, d.Open())
+	_, err = d.LoadMsg(channelId, channelType, 1)
+	assert.Error(t, err)
+	assert.NoError(t, d.Close())
+
+	// 重新打开后仍然可用，且不再依赖旧的主密钥
+	assert.NoError(t, os.WriteFile(masterKeyFile, nil, 0600))
+	appendTestMasterKey(t, masterKeyFile, "k2")
+	d = open()
+	msg, err := d.LoadMsg(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello 1"), msg.Payload)
+	assert.NoError(t, d.Close())
+}
+
+func TestEncryptionPayloadWithEnvelopePrefix(t *testing.T) {
+	dir := t.TempDir()
+	masterKeyFile := filepath.Join(t.TempDir(), "master.key")
+	appendTestMasterKey(t, masterKeyFile, "k1")
+	mk, err := kms.NewFileMasterKey(masterKeyFile)
+	assert.NoError(t, err)
+	channelId := "channel"
+	channelType := uint8(2)
+
+	// 客户端发送的内容和加密数据的格式相同，也只能被当作明文
+	payload := append([]byte{0x00, 0xEE, 'W', 'K', 0, 0, 0, 0, 0, 0, 0, 1}, []byte("not encrypted")...)
+	messages := newTestMessages(channelId, channelType, 2)
+	messages[0].Payload = payload
+
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1)))
+	assert.NoError(t, d.Open())
+	err = d.AppendMessages(channelId, channelType, messages[:1])
+	assert.NoError(t, err)
+	msgs, err := d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 1)
+	assert.Equal(t, payload, msgs[0].Payload)
+	assert.NoError(t, d.Close())
+
+	d = wkdb.NewWukongDB(wkdb.NewOptions(
+		wkdb.WithDir(dir),
+		wkdb.WithShardNum(1),
+		wkdb.WithMasterKey(mk),
+		wkdb.WithReencryptInterval(time.Millisecond*10),
+	))
+	assert.NoError(t, d.Open())
+	defer func() {
+		assert.NoError(t, d.Close())
+	}()
+	err = d.AppendMessages(channelId, channelType, messages[1:])
+	assert.NoError(t, err)
+
+	status := waitReencrypted(t, d)
+	assert.Equal(t, uint64(1), status.Shards[0].Reencrypted)
+
+	msgs, err = d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 2)
+	assert.Equal(t, payload, msgs[0].Payload)
+	assert.Equal(t, []byte("hello 2"), msgs[1].Payload)
+}
+
+func TestRotateDataKeysReencryptSegments(t *testing.T) {
+	masterKeyFile := filepath.Join(t.TempDir(), "master.key")
+	appendTestMasterKey(t, masterKeyFile, "k1")
+	mk, err := kms.NewFileMasterKey(masterKeyFile)
+	assert.NoError(t, err)
+	channelId := "channel"
+	channelType := uint8(2)
+	num := 30
+
+	d := wkdb.NewWukongDB(wkdb.NewOptions(
+		wkdb.WithDir(t.TempDir()),
+		wkdb.WithShardNum(1),
+		wkdb.WithMasterKey(mk),
+		wkdb.WithReencryptInterval(time.Millisecond*10),
+		wkdb.WithColdStore(objstore.NewFileStore(t.TempDir())),
+		wkdb.WithTieredSegmentSize(10),
+		wkdb.WithTieredKeepMessages(10),
+		wkdb.WithTieredColdAfter(0),
+		wkdb.WithTieredInterval(0),
+	))
+	assert.NoError(t, d.Open())
+	defer func() {
+		assert.NoError(t, d.Close())
+	}()
+
+	err = d.AppendMessages(channelId, channelType, newTestMessages(channelId, channelType, num))
+	assert.NoError(t, err)
+	count, err := d.OffloadChannelMessages(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	segs, err := d.GetChannelSegments(channelId, channelType)
+	assert.NoError(t, err)
+	for _, seg := range segs {
+		assert.Equal(t, uint32(1), seg.KeyVersion)
+	}
+	waitReencrypted(t, d)
+
+	err = d.RotateDataKeys()
+	assert.NoError(t, err)
+	status := waitReencrypted(t, d)
+	assert.Equal(t, uint32(2), status.Shards[0].KeyVersion)
+	assert.Equal(t, 1, status.Shards[0].KeyCount)
+	assert.Equal(t, uint64(num), status.Shards[0].Reencrypted)
+
+	newSegs, err := d.GetChannelSegments(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Len(t, newSegs, len(segs))
+	for i, seg := range newSegs {
+		assert.Equal(t, uint32(2), seg.KeyVersion)
+		assert.NotEqual(t, segs[i].ObjectKey, seg.ObjectKey)
+	}
+
+	msgs, err := d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, num)
+	for i, m := range msgs {
+		assert.Equal(t, []byte(fmt.Sprintf("hello %d", i+1)), m.Payload)
+	}
+}
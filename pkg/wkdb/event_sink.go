@@ -0,0 +1,76 @@
+package wkdb
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+// AppendEventSinkEvents 追加待投递的事件，事件id需要递增
+func (wk *wukongDB) AppendEventSinkEvents(events []SinkEvent) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	for _, event := range events {
+		data, err := event.Marshal()
+		if err != nil {
+			return err
+		}
+		if err := batch.Set(key.NewEventSinkQueueKey(event.Id), data, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+// GetEventSinkEvents 获取待投递的事件 startId表示从此id之后开始获取（不包含startId）
+func (wk *wukongDB) GetEventSinkEvents(startId uint64, limit int) ([]SinkEvent, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewEventSinkQueueKey(startId + 1),
+		UpperBound: key.NewEventSinkQueueKey(math.MaxUint64),
+	})
+	defer iter.Close()
+
+	events := make([]SinkEvent, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+		var event SinkEvent
+		if err := event.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		event.Data = append([]byte(nil), event.Data...)
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// RemoveEventSinkEvents 移除id小于等于endId的事件
+func (wk *wukongDB) RemoveEventSinkEvents(endId uint64) error {
+	return wk.defaultShardDB().DeleteRange(key.NewEventSinkQueueKey(0), key.NewEventSinkQueueKey(endId+1), wk.sync)
+}
+
+// GetEventSinkCheckpoint 获取投递进度（最后一个已确认的事件id）
+func (wk *wukongDB) GetEventSinkCheckpoint(sinkName string) (uint64, error) {
+	value, closer, err := wk.defaultShardDB().Get(key.NewEventSinkCheckpointKey(sinkName))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	if len(value) < 8 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+// SetEventSinkCheckpoint 保存投递进度
+func (wk *wukongDB) SetEventSinkCheckpoint(sinkName string, eventId uint64) error {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, eventId)
+	return wk.defaultShardDB().Set(key.NewEventSinkCheckpointKey(sinkName), value, wk.sync)
+}
@@ -0,0 +1,79 @@
+package wkdb_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventSinkEvents(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	events := make([]wkdb.SinkEvent, 0)
+	for i := 1; i <= 10; i++ {
+		events = append(events, wkdb.SinkEvent{
+			Id:        uint64(i),
+			Type:      "msg.notify",
+			Key:       "ch1-2",
+			Timestamp: int64(i),
+			Data:      []byte(fmt.Sprintf(`{"seq":%d}`, i)),
+		})
+	}
+	err = d.AppendEventSinkEvents(events)
+	assert.NoError(t, err)
+
+	result, err := d.GetEventSinkEvents(0, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, events[0], result[0])
+
+	result, err = d.GetEventSinkEvents(4, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(result))
+	assert.Equal(t, uint64(5), result[0].Id)
+
+	err = d.RemoveEventSinkEvents(8)
+	assert.NoError(t, err)
+
+	result, err = d.GetEventSinkEvents(0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, uint64(9), result[0].Id)
+}
+
+func TestEventSinkCheckpoint(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	checkpoint, err := d.GetEventSinkCheckpoint("kafka")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), checkpoint)
+
+	err = d.SetEventSinkCheckpoint("kafka", 100)
+	assert.NoError(t, err)
+	err = d.SetEventSinkCheckpoint("nats", 50)
+	assert.NoError(t, err)
+
+	checkpoint, err = d.GetEventSinkCheckpoint("kafka")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), checkpoint)
+
+	checkpoint, err = d.GetEventSinkCheckpoint("nats")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(50), checkpoint)
+}
@@ -40,8 +40,11 @@ func NewMessageColumnKeyWithPrimary(primary [16]byte, columnName [2]byte) []byte
 }
 
 func NewMessagePrimaryKey(channelId string, channelType uint8, messageSeq uint64) []byte {
+	return NewMessagePrimaryKeyWithHash(channelIdToNum(channelId, channelType), messageSeq)
+}
+
+func NewMessagePrimaryKeyWithHash(channelHash uint64, messageSeq uint64) []byte {
 	key := make([]byte, 20)
-	channelHash := channelIdToNum(channelId, channelType)
 	key[0] = TableMessage.Id[0]
 	key[1] = TableMessage.Id[1]
 	key[2] = dataTypeTable
@@ -107,15 +110,27 @@ func NewMessageSearchHighKeWith(channelId string, channelType uint8, messageSeq
 }
 
 func NewChannelLastMessageSeqKey(channelId string, channelType uint8) []byte {
+	return newChannelLastMessageSeqKey(channelIdToNum(channelId, channelType))
+}
+
+func newChannelLastMessageSeqKey(channelHash uint64) []byte {
 	key := make([]byte, 12)
 	key[0] = TableMessage.Id[0]
 	key[1] = TableMessage.Id[1]
 	key[2] = dataTypeOther
 	key[3] = 0
-	channelHash := channelIdToNum(channelId, channelType)
 	binary.BigEndian.PutUint64(key[4:], channelHash)
 	return key
+}
 
+// ParseChannelLastMessageSeqKey 解析出频道hash
+func ParseChannelLastMessageSeqKey(key []byte) (channelHash uint64, err error) {
+	if len(key) != 12 {
+		err = fmt.Errorf("channelLastMessageSeq: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	channelHash = binary.BigEndian.Uint64(key[4:])
+	return
 }
 
 func ParseMessageColumnKey(key []byte) (messageSeq uint64, columnName [2]byte, err error) {
@@ -900,3 +915,246 @@ func NewSystemUidColumnKey(id uint64, columnName [2]byte) []byte {
 	key[13] = columnName[1]
 	return key
 }
+
+// ---------------------- webhook ----------------------
+
+func NewWebhookRetryKey(messageId uint64) []byte {
+	key := make([]byte, TableWebhookRetry.Size)
+	key[0] = TableWebhookRetry.Id[0]
+	key[1] = TableWebhookRetry.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], messageId)
+	return key
+}
+
+func NewWebhookDeadLetterKey(id uint64) []byte {
+	key := make([]byte, TableWebhookDeadLetter.Size)
+	key[0] = TableWebhookDeadLetter.Id[0]
+	key[1] = TableWebhookDeadLetter.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	return key
+}
+
+func ParseWebhookDeadLetterKey(key []byte) (id uint64, err error) {
+	if len(key) != TableWebhookDeadLetter.Size {
+		err = fmt.Errorf("webhookDeadLetter: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	id = binary.BigEndian.Uint64(key[4:])
+	return
+}
+
+// ---------------------- event sink ----------------------
+
+func NewEventSinkQueueKey(eventId uint64) []byte {
+	key := make([]byte, TableEventSinkQueue.Size)
+	key[0] = TableEventSinkQueue.Id[0]
+	key[1] = TableEventSinkQueue.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], eventId)
+	return key
+}
+
+func NewEventSinkCheckpointKey(sinkName string) []byte {
+	key := make([]byte, TableEventSinkCheckpoint.Size)
+	key[0] = TableEventSinkCheckpoint.Id[0]
+	key[1] = TableEventSinkCheckpoint.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(sinkName))
+	return key
+}
+
+// ---------------------- conversation version ----------------------
+
+func NewConversationVersionKey(uid string) []byte {
+	key := make([]byte, TableConversationVersion.Size)
+	key[0] = TableConversationVersion.Id[0]
+	key[1] = TableConversationVersion.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	return key
+}
+
+func NewConversationTombstoneKey(uid string, version uint64) []byte {
+	key := make([]byte, TableConversationTombstone.Size)
+	key[0] = TableConversationTombstone.Id[0]
+	key[1] = TableConversationTombstone.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	binary.BigEndian.PutUint64(key[12:], version)
+	return key
+}
+
+func NewConversationTombstoneFloorKey(uid string) []byte {
+	key := make([]byte, TableConversationTombstoneFloor.Size)
+	key[0] = TableConversationTombstoneFloor.Id[0]
+	key[1] = TableConversationTombstoneFloor.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	return key
+}
+
+// ---------------------- reaction ----------------------
+
+func NewReactionKey(channelId string, channelType uint8, messageSeq uint64, uid string, emoji string) []byte {
+	return newReactionKey(channelIdToNum(channelId, channelType), messageSeq, HashWithString(uid), HashWithString(emoji))
+}
+
+// NewReactionMessageLowKey 消息的所有回应的起始key
+func NewReactionMessageLowKey(channelId string, channelType uint8, messageSeq uint64) []byte {
+	return newReactionKey(channelIdToNum(channelId, channelType), messageSeq, 0, 0)
+}
+
+// NewReactionMessageHighKey 消息的所有回应的结束key
+func NewReactionMessageHighKey(channelId string, channelType uint8, messageSeq uint64) []byte {
+	return newReactionKey(channelIdToNum(channelId, channelType), messageSeq, math.MaxUint64, math.MaxUint64)
+}
+
+func newReactionKey(channelHash uint64, messageSeq uint64, uidHash uint64, emojiHash uint64) []byte {
+	key := make([]byte, TableReaction.Size)
+	key[0] = TableReaction.Id[0]
+	key[1] = TableReaction.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	binary.BigEndian.PutUint64(key[20:], uidHash)
+	binary.BigEndian.PutUint64(key[28:], emojiHash)
+	return key
+}
+
+func NewReactionVersionIndexKey(channelId string, channelType uint8, version uint64) []byte {
+	key := make([]byte, TableReactionVersionIndex.Size)
+	key[0] = TableReactionVersionIndex.Id[0]
+	key[1] = TableReactionVersionIndex.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[12:], version)
+	return key
+}
+
+func NewReactionVersionKey(channelId string, channelType uint8) []byte {
+	key := make([]byte, TableReactionVersion.Size)
+	key[0] = TableReactionVersion.Id[0]
+	key[1] = TableReactionVersion.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	return key
+}
+
+// NewReactionUserLowKey 用户对消息的所有回应的起始key
+func NewReactionUserLowKey(channelId string, channelType uint8, messageSeq uint64, uid string) []byte {
+	return newReactionKey(channelIdToNum(channelId, channelType), messageSeq, HashWithString(uid), 0)
+}
+
+// NewReactionUserHighKey 用户对消息的所有回应的结束key
+func NewReactionUserHighKey(channelId string, channelType uint8, messageSeq uint64, uid string) []byte {
+	return newReactionKey(channelIdToNum(channelId, channelType), messageSeq, HashWithString(uid), math.MaxUint64)
+}
+
+func NewReactionSummaryKey(channelId string, channelType uint8, messageSeq uint64) []byte {
+	key := make([]byte, TableReactionSummary.Size)
+	key[0] = TableReactionSummary.Id[0]
+	key[1] = TableReactionSummary.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	return key
+}
+
+// ---------------------- mirror ----------------------
+
+func NewMirrorCheckpointKey(name string) []byte {
+	key := make([]byte, TableMirrorCheckpoint.Size)
+	key[0] = TableMirrorCheckpoint.Id[0]
+	key[1] = TableMirrorCheckpoint.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(name))
+	return key
+}
+
+func NewMirrorChannelKey(channelId string, channelType uint8) []byte {
+	return newMirrorChannelKey(channelIdToNum(channelId, channelType))
+}
+
+// NewMirrorChannelLowKey 等待镜像的频道的起始key
+func NewMirrorChannelLowKey() []byte {
+	return newMirrorChannelKey(0)
+}
+
+// NewMirrorChannelHighKey 等待镜像的频道的结束key
+func NewMirrorChannelHighKey() []byte {
+	return newMirrorChannelKey(math.MaxUint64)
+}
+
+func newMirrorChannelKey(channelHash uint64) []byte {
+	key := make([]byte, TableMirrorChannel.Size)
+	key[0] = TableMirrorChannel.Id[0]
+	key[1] = TableMirrorChannel.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	return key
+}
+
+// ---------------------- channel segment ----------------------
+
+func NewChannelSegmentKey(channelId string, channelType uint8, startSeq uint64) []byte {
+	return NewChannelSegmentKeyWithHash(channelIdToNum(channelId, channelType), startSeq)
+}
+
+// NewChannelSegmentKeyWithHash 通过频道hash生成冷数据段的key
+func NewChannelSegmentKeyWithHash(channelHash uint64, startSeq uint64) []byte {
+	key := make([]byte, TableChannelSegment.Size)
+	key[0] = TableChannelSegment.Id[0]
+	key[1] = TableChannelSegment.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], startSeq)
+	return key
+}
+
+// NewChannelLastMessageSeqLowKey 频道最大消息序号的起始key
+func NewChannelLastMessageSeqLowKey() []byte {
+	return newChannelLastMessageSeqKey(0)
+}
+
+// NewChannelLastMessageSeqHighKey 频道最大消息序号的结束key
+func NewChannelLastMessageSeqHighKey() []byte {
+	return newChannelLastMessageSeqKey(math.MaxUint64)
+}
+
+// ---------------------- data key ----------------------
+
+func NewDataKeyKey(version uint32) []byte {
+	key := make([]byte, TableDataKey.Size)
+	key[0] = TableDataKey.Id[0]
+	key[1] = TableDataKey.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint32(key[4:], version)
+	return key
+}
+
+// NewDataKeyLowKey 数据密钥的起始key
+func NewDataKeyLowKey() []byte {
+	return NewDataKeyKey(0)
+}
+
+// NewDataKeyHighKey 数据密钥的结束key
+func NewDataKeyHighKey() []byte {
+	return NewDataKeyKey(math.MaxUint32)
+}
@@ -26,19 +26,20 @@ var TableMessage = struct {
 	IndexSize       int
 	SecondIndexSize int
 	Column          struct {
-		Header      [2]byte
-		Setting     [2]byte
-		Expire      [2]byte
-		MessageId   [2]byte
-		MessageSeq  [2]byte
-		ClientMsgNo [2]byte
-		Timestamp   [2]byte
-		ChannelId   [2]byte
-		ChannelType [2]byte
-		Topic       [2]byte
-		FromUid     [2]byte
-		Payload     [2]byte
-		Term        [2]byte
+		Header           [2]byte
+		Setting          [2]byte
+		Expire           [2]byte
+		MessageId        [2]byte
+		MessageSeq       [2]byte
+		ClientMsgNo      [2]byte
+		Timestamp        [2]byte
+		ChannelId        [2]byte
+		ChannelType      [2]byte
+		Topic            [2]byte
+		FromUid          [2]byte
+		Payload          [2]byte
+		Term             [2]byte
+		EncryptedPayload [2]byte
 	}
 	Index struct {
 		MessageId [2]byte
@@ -55,33 +56,35 @@ var TableMessage = struct {
 	IndexSize:       2 + 2 + 2 + 8,      // tableId + dataType + indexName + columnHash
 	SecondIndexSize: 2 + 2 + 2 + 8 + 16, // tableId + dataType + secondIndexName + columnValue + primaryKey
 	Column: struct {
-		Header      [2]byte
-		Setting     [2]byte
-		Expire      [2]byte
-		MessageId   [2]byte
-		MessageSeq  [2]byte
-		ClientMsgNo [2]byte
-		Timestamp   [2]byte
-		ChannelId   [2]byte
-		ChannelType [2]byte
-		Topic       [2]byte
-		FromUid     [2]byte
-		Payload     [2]byte
-		Term        [2]byte
+		Header           [2]byte
+		Setting          [2]byte
+		Expire           [2]byte
+		MessageId        [2]byte
+		MessageSeq       [2]byte
+		ClientMsgNo      [2]byte
+		Timestamp        [2]byte
+		ChannelId        [2]byte
+		ChannelType      [2]byte
+		Topic            [2]byte
+		FromUid          [2]byte
+		Payload          [2]byte
+		Term             [2]byte
+		EncryptedPayload [2]byte
 	}{
-		Header:      [2]byte{0x01, 0x01},
-		Setting:     [2]byte{0x01, 0x02},
-		Expire:      [2]byte{0x01, 0x03},
-		MessageId:   [2]byte{0x01, 0x04},
-		MessageSeq:  [2]byte{0x01, 0x05},
-		ClientMsgNo: [2]byte{0x01, 0x06},
-		Timestamp:   [2]byte{0x01, 0x07},
-		ChannelId:   [2]byte{0x01, 0x08},
-		ChannelType: [2]byte{0x01, 0x09},
-		Topic:       [2]byte{0x01, 0x0A},
-		FromUid:     [2]byte{0x01, 0x0B},
-		Payload:     [2]byte{0x01, 0x0C},
-		Term:        [2]byte{0x01, 0x0D},
+		Header:           [2]byte{0x01, 0x01},
+		Setting:          [2]byte{0x01, 0x02},
+		Expire:           [2]byte{0x01, 0x03},
+		MessageId:        [2]byte{0x01, 0x04},
+		MessageSeq:       [2]byte{0x01, 0x05},
+		ClientMsgNo:      [2]byte{0x01, 0x06},
+		Timestamp:        [2]byte{0x01, 0x07},
+		ChannelId:        [2]byte{0x01, 0x08},
+		ChannelType:      [2]byte{0x01, 0x09},
+		Topic:            [2]byte{0x01, 0x0A},
+		FromUid:          [2]byte{0x01, 0x0B},
+		Payload:          [2]byte{0x01, 0x0C},
+		Term:             [2]byte{0x01, 0x0D},
+		EncryptedPayload: [2]byte{0x01, 0x0E}, // 开启静态加密时消息内容保存在这一列（不写Payload列）
 	},
 	Index: struct {
 		MessageId [2]byte
@@ -313,6 +316,8 @@ var TableChannelInfo = struct {
 		DenylistCount   [2]byte // 黑名单数量
 		CreatedAt       [2]byte
 		UpdatedAt       [2]byte
+		ReplicaCount    [2]byte // 副本数量
+		AckMode         [2]byte // 副本确认方式
 	}
 	Index struct {
 		Channel [2]byte
@@ -343,6 +348,8 @@ var TableChannelInfo = struct {
 		DenylistCount   [2]byte
 		CreatedAt       [2]byte
 		UpdatedAt       [2]byte
+		ReplicaCount    [2]byte
+		AckMode         [2]byte
 	}{
 		Id:              [2]byte{0x06, 0x01},
 		ChannelId:       [2]byte{0x06, 0x02},
@@ -355,6 +362,8 @@ var TableChannelInfo = struct {
 		DenylistCount:   [2]byte{0x06, 0x09},
 		CreatedAt:       [2]byte{0x06, 0x0A},
 		UpdatedAt:       [2]byte{0x06, 0x0B},
+		ReplicaCount:    [2]byte{0x06, 0x0C},
+		AckMode:         [2]byte{0x06, 0x0D},
 	},
 	Index: struct {
 		Channel [2]byte
@@ -490,6 +499,9 @@ var TableConversation = struct {
 		ReadedToMsgSeq [2]byte
 		CreatedAt      [2]byte
 		UpdatedAt      [2]byte
+		Version        [2]byte
+		Extra          [2]byte
+		Mention        [2]byte
 	}
 	Index struct {
 		Channel [2]byte
@@ -498,6 +510,7 @@ var TableConversation = struct {
 		Type      [2]byte
 		CreatedAt [2]byte
 		UpdatedAt [2]byte
+		Version   [2]byte
 	}
 }{
 	Id:              [2]byte{0x09, 0x01},
@@ -513,6 +526,9 @@ var TableConversation = struct {
 		ReadedToMsgSeq [2]byte
 		CreatedAt      [2]byte
 		UpdatedAt      [2]byte
+		Version        [2]byte
+		Extra          [2]byte
+		Mention        [2]byte
 	}{
 		Uid:            [2]byte{0x09, 0x01},
 		ChannelId:      [2]byte{0x09, 0x02},
@@ -522,6 +538,9 @@ var TableConversation = struct {
 		ReadedToMsgSeq: [2]byte{0x09, 0x06},
 		CreatedAt:      [2]byte{0x09, 0x07},
 		UpdatedAt:      [2]byte{0x09, 0x08},
+		Version:        [2]byte{0x09, 0x09},
+		Extra:          [2]byte{0x09, 0x0A},
+		Mention:        [2]byte{0x09, 0x0B},
 	},
 	Index: struct {
 		Channel [2]byte
@@ -532,10 +551,12 @@ var TableConversation = struct {
 		Type      [2]byte
 		CreatedAt [2]byte
 		UpdatedAt [2]byte
+		Version   [2]byte
 	}{
 		Type:      [2]byte{0x09, 0x01},
 		CreatedAt: [2]byte{0x09, 0x02},
 		UpdatedAt: [2]byte{0x09, 0x03},
+		Version:   [2]byte{0x09, 0x04},
 	},
 }
 
@@ -583,6 +604,7 @@ var TableChannelClusterConfig = struct {
 		Version         [2]byte
 		CreatedAt       [2]byte
 		UpdatedAt       [2]byte
+		AckMode         [2]byte
 	}
 }{
 	Id:              [2]byte{0x0B, 0x01},
@@ -623,6 +645,7 @@ var TableChannelClusterConfig = struct {
 		Version         [2]byte
 		CreatedAt       [2]byte
 		UpdatedAt       [2]byte
+		AckMode         [2]byte
 	}{
 		ChannelId:       [2]byte{0x0B, 0x01},
 		ChannelType:     [2]byte{0x0B, 0x02},
@@ -638,6 +661,7 @@ var TableChannelClusterConfig = struct {
 		Version:         [2]byte{0x0B, 0x0C},
 		CreatedAt:       [2]byte{0x0B, 0x0D},
 		UpdatedAt:       [2]byte{0x0B, 0x0E},
+		AckMode:         [2]byte{0x0B, 0x0F},
 	},
 }
 
@@ -722,3 +746,153 @@ var TableSystemUid = struct {
 		Uid: [2]byte{0x10, 0x01},
 	},
 }
+
+// ======================== webhook重试次数 ========================
+
+var TableWebhookRetry = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x11, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType  + messageId
+}
+
+// ======================== webhook死信 ========================
+
+var TableWebhookDeadLetter = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x12, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType  + primaryKey
+}
+
+// ======================== 事件投递队列 ========================
+
+var TableEventSinkQueue = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x13, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType  + eventId
+}
+
+// ======================== 事件投递进度 ========================
+
+var TableEventSinkCheckpoint = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x14, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType  + sinkNameHash
+}
+
+// ======================== 用户会话版本号 ========================
+
+var TableConversationVersion = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x15, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType  + uid hash
+}
+
+// ======================== 已删除的会话 ========================
+
+var TableConversationTombstone = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x16, 0x01},
+	Size: 2 + 2 + 8 + 8, // tableId + dataType  + uid hash + version
+}
+
+// ======================== 已删除会话的清理位置 ========================
+
+var TableConversationTombstoneFloor = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1E, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType  + uid hash
+}
+
+// ======================== 消息回应 ========================
+
+var TableReaction = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x17, 0x01},
+	Size: 2 + 2 + 8 + 8 + 8 + 8, // tableId + dataType  + channel hash + messageSeq + uid hash + emoji hash
+}
+
+// ======================== 消息回应版本索引 ========================
+
+var TableReactionVersionIndex = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x18, 0x01},
+	Size: 2 + 2 + 8 + 8, // tableId + dataType  + channel hash + version
+}
+
+// ======================== 频道消息回应版本号 ========================
+
+var TableReactionVersion = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x19, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType  + channel hash
+}
+
+// ======================== 消息回应汇总 ========================
+
+var TableReactionSummary = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1F, 0x01},
+	Size: 2 + 2 + 8 + 8, // tableId + dataType  + channel hash + messageSeq
+}
+
+// ======================== 跨集群镜像进度 ========================
+
+var TableMirrorCheckpoint = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1A, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType  + name hash
+}
+
+// ======================== 等待镜像的频道 ========================
+
+var TableMirrorChannel = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1B, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType  + channel hash
+}
+
+// ======================== 频道冷数据段 ========================
+
+var TableChannelSegment = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1C, 0x01},
+	Size: 2 + 2 + 8 + 8, // tableId + dataType  + channel hash + startSeq
+}
+
+// ======================== 消息加密的数据密钥 ========================
+
+var TableDataKey = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1D, 0x01},
+	Size: 2 + 2 + 4, // tableId + dataType  + version
+}
@@ -16,6 +16,8 @@ type dblock struct {
 	userLock               *userLock
 	addOrUpdateChannelLock *addOrUpdateChannelLock
 	conversationLock       *conversationLock
+	reactionLock           *reactionLock
+	channelSegmentLock     *channelSegmentLock
 }
 
 func newDBLock() *dblock {
@@ -28,6 +30,8 @@ func newDBLock() *dblock {
 		totalLock:              newTotalLock(),
 		addOrUpdateChannelLock: newAddOrUpdateChannelLock(),
 		conversationLock:       newConversationLock(),
+		reactionLock:           newReactionLock(),
+		channelSegmentLock:     newChannelSegmentLock(),
 	}
 
 }
@@ -40,6 +44,8 @@ func (d *dblock) start() {
 	d.userLock.StartCleanLoop()
 	d.addOrUpdateChannelLock.StartCleanLoop()
 	d.conversationLock.StartCleanLoop()
+	d.reactionLock.StartCleanLoop()
+	d.channelSegmentLock.StartCleanLoop()
 }
 
 func (d *dblock) stop() {
@@ -50,6 +56,8 @@ func (d *dblock) stop() {
 	d.userLock.StopCleanLoop()
 	d.addOrUpdateChannelLock.StopCleanLoop()
 	d.conversationLock.StopCleanLoop()
+	d.reactionLock.StopCleanLoop()
+	d.channelSegmentLock.StopCleanLoop()
 }
 
 type channelClusterConfigLock struct {
@@ -248,3 +256,43 @@ func (c *conversationLock) lock(uid string) {
 func (c *conversationLock) unlock(uid string) {
 	c.Unlock(uid)
 }
+
+type reactionLock struct {
+	*keylock.KeyLock
+}
+
+func newReactionLock() *reactionLock {
+	return &reactionLock{
+		keylock.NewKeyLock(),
+	}
+}
+
+func (r *reactionLock) lock(channelId string, channelType uint8) {
+	key := channelId + strconv.FormatInt(int64(channelType), 10)
+	r.Lock(key)
+}
+
+func (r *reactionLock) unlock(channelId string, channelType uint8) {
+	key := channelId + strconv.FormatInt(int64(channelType), 10)
+	r.Unlock(key)
+}
+
+type channelSegmentLock struct {
+	*keylock.KeyLock
+}
+
+func newChannelSegmentLock() *channelSegmentLock {
+	return &channelSegmentLock{
+		keylock.NewKeyLock(),
+	}
+}
+
+func (c *channelSegmentLock) lock(channelId string, channelType uint8) {
+	key := channelId + strconv.FormatInt(int64(channelType), 10)
+	c.Lock(key)
+}
+
+func (c *channelSegmentLock) unlock(channelId string, channelType uint8) {
+	key := channelId + strconv.FormatInt(int64(channelType), 10)
+	c.Unlock(key)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/cockroachdb/pebble"
 	"go.uber.org/zap"
@@ -29,7 +30,11 @@ func (wk *wukongDB) AppendMessages(channelId string, channelType uint8, msgs []M
 		}()
 	}
 
-	db := wk.channelDb(channelId, channelType)
+	shardId := wk.channelDbIndex(channelId, channelType)
+	wk.shardLocks[shardId].RLock()
+	defer wk.shardLocks[shardId].RUnlock()
+
+	db := wk.shardDBById(shardId)
 	batch := db.NewBatch()
 	defer batch.Close()
 	for _, msg := range msgs {
@@ -41,6 +46,11 @@ func (wk *wukongDB) AppendMessages(channelId string, channelType uint8, msgs []M
 			return err
 		}
 	}
+	if wk.opts.MirrorOn && len(msgs) > 0 {
+		if err := wk.setMirrorChannel(channelId, channelType, uint64(msgs[len(msgs)-1].MessageSeq), batch); err != nil {
+			return err
+		}
+	}
 
 	// 消息总数量+1
 	// err := wk.IncMessageCount(len(msgs))
@@ -90,8 +100,7 @@ func (wk *wukongDB) AppendMessagesBatch(reqs []AppendMessagesReq) error {
 
 	if len(dbMap) == 1 { // 如果只有一条消息 则不需要开启协程
 		for shardId, reqs := range dbMap {
-			db := wk.shardDBById(shardId)
-			err := wk.writeMessagesBatch(db, reqs)
+			err := wk.writeMessagesBatch(shardId, reqs)
 			if err != nil {
 				return err
 			}
@@ -105,8 +114,7 @@ func (wk *wukongDB) AppendMessagesBatch(reqs []AppendMessagesReq) error {
 		for shardId, reqs := range dbMap {
 			requestGroup.Go(func(sid uint32, rqs []AppendMessagesReq) func() error {
 				return func() error {
-					db := wk.shardDBById(sid)
-					return wk.writeMessagesBatch(db, rqs)
+					return wk.writeMessagesBatch(sid, rqs)
 				}
 			}(shardId, reqs))
 
@@ -127,8 +135,11 @@ func (wk *wukongDB) AppendMessagesBatch(reqs []AppendMessagesReq) error {
 
 }
 
-func (wk *wukongDB) writeMessagesBatch(db *pebble.DB, reqs []AppendMessagesReq) error {
-	batch := db.NewBatch()
+func (wk *wukongDB) writeMessagesBatch(shardId uint32, reqs []AppendMessagesReq) error {
+	wk.shardLocks[shardId].RLock()
+	defer wk.shardLocks[shardId].RUnlock()
+
+	batch := wk.shardDBById(shardId).NewBatch()
 	defer batch.Close()
 	for _, req := range reqs {
 		lastMsg := req.Messages[len(req.Messages)-1]
@@ -141,6 +152,11 @@ func (wk *wukongDB) writeMessagesBatch(db *pebble.DB, reqs []AppendMessagesReq)
 		if err != nil {
 			return err
 		}
+		if wk.opts.MirrorOn {
+			if err = wk.setMirrorChannel(req.ChannelId, req.ChannelType, uint64(lastMsg.MessageSeq), batch); err != nil {
+				return err
+			}
+		}
 	}
 	if err := batch.Commit(wk.sync); err != nil {
 		return err
@@ -182,6 +198,9 @@ func (wk *wukongDB) GetMessage(messageId uint64) (Message, error) {
 			return EmptyMessage, err
 		}
 		if IsEmptyMessage(msg) {
+			if wk.tieredEnabled() { // 本地没有，可能已下沉到对象存储
+				return wk.getColdMessage(db, wk.endian.Uint64(arr[:8]), wk.endian.Uint64(arr[8:]))
+			}
 			return EmptyMessage, ErrNotFound
 		}
 		return msg, nil
@@ -234,6 +253,48 @@ func (wk *wukongDB) LoadPrevRangeMsgs(channelId string, channelType uint8, start
 		maxSeq = lastSeq + 1
 	}
 
+	return wk.loadRangeMsgs(channelId, channelType, minSeq, maxSeq, limit)
+}
+
+// loadRangeMsgs 获取[minSeq, maxSeq)内的消息，已下沉的部分从对象存储获取
+func (wk *wukongDB) loadRangeMsgs(channelId string, channelType uint8, minSeq, maxSeq uint64, limit int) ([]Message, error) {
+	var msgs []Message
+	err := wk.readWithColdEnd(channelId, channelType, func(coldEnd uint64) error {
+		msgs = make([]Message, 0)
+		localMinSeq := minSeq
+		localLimit := limit
+		if coldEnd >= minSeq {
+			coldMsgs, err := wk.loadColdRangeMsgs(channelId, channelType, minSeq, min(maxSeq, coldEnd+1), limit)
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, coldMsgs...)
+			localMinSeq = coldEnd + 1
+			if limit > 0 {
+				localLimit = limit - len(msgs)
+				if localLimit <= 0 {
+					return nil
+				}
+			}
+		}
+		if localMinSeq >= maxSeq {
+			return nil
+		}
+		localMsgs, err := wk.loadLocalRangeMsgs(channelId, channelType, localMinSeq, maxSeq, localLimit)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, localMsgs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// loadLocalRangeMsgs 获取本地[minSeq, maxSeq)内的消息
+func (wk *wukongDB) loadLocalRangeMsgs(channelId string, channelType uint8, minSeq, maxSeq uint64, limit int) ([]Message, error) {
 	db := wk.channelDb(channelId, channelType)
 
 	iter := db.NewIter(&pebble.IterOptions{
@@ -243,7 +304,7 @@ func (wk *wukongDB) LoadPrevRangeMsgs(channelId string, channelType uint8, start
 	defer iter.Close()
 
 	msgs := make([]Message, 0)
-	err = wk.iteratorChannelMessages(iter, limit, func(m Message) bool {
+	err := wk.iteratorChannelMessages(iter, limit, func(m Message) bool {
 		msgs = append(msgs, m)
 		return true
 	})
@@ -270,29 +331,28 @@ func (wk *wukongDB) LoadNextRangeMsgs(channelId string, channelType uint8, start
 		maxSeq = lastSeq + 1
 	}
 
-	db := wk.channelDb(channelId, channelType)
-
-	iter := db.NewIter(&pebble.IterOptions{
-		LowerBound: key.NewMessagePrimaryKey(channelId, channelType, minSeq),
-		UpperBound: key.NewMessagePrimaryKey(channelId, channelType, maxSeq),
-	})
-	defer iter.Close()
-
-	msgs := make([]Message, 0)
-
-	err = wk.iteratorChannelMessages(iter, limit, func(m Message) bool {
-		msgs = append(msgs, m)
-		return true
-	})
-	if err != nil {
-		return nil, err
-	}
-	return msgs, nil
+	return wk.loadRangeMsgs(channelId, channelType, minSeq, maxSeq, limit)
 
 }
 
 func (wk *wukongDB) LoadMsg(channelId string, channelType uint8, seq uint64) (Message, error) {
+	if wk.tieredEnabled() {
+		var msg Message
+		err := wk.readWithColdEnd(channelId, channelType, func(coldEnd uint64) error {
+			var err error
+			if seq <= coldEnd {
+				msg, err = wk.getColdMessage(wk.channelDb(channelId, channelType), key.ChannelIdToNum(channelId, channelType), seq)
+			} else {
+				msg, err = wk.loadLocalMsg(channelId, channelType, seq)
+			}
+			return err
+		})
+		return msg, err
+	}
+	return wk.loadLocalMsg(channelId, channelType, seq)
+}
 
+func (wk *wukongDB) loadLocalMsg(channelId string, channelType uint8, seq uint64) (Message, error) {
 	db := wk.channelDb(channelId, channelType)
 
 	iter := db.NewIter(&pebble.IterOptions{
@@ -356,6 +416,57 @@ func (wk *wukongDB) LoadNextRangeMsgsForSize(channelId string, channelType uint8
 	if endMessageSeq == 0 {
 		maxSeq = math.MaxUint64
 	}
+	if !wk.tieredEnabled() {
+		return wk.loadLocalRangeMsgsForSize(channelId, channelType, minSeq, maxSeq, limitSize)
+	}
+
+	var msgs []Message
+	err := wk.readWithColdEnd(channelId, channelType, func(coldEnd uint64) error {
+		msgs = make([]Message, 0)
+		localMinSeq := minSeq
+		localLimitSize := limitSize
+		if coldEnd >= minSeq {
+			var (
+				size uint64
+				full bool
+			)
+			err := wk.iterColdMessages(channelId, channelType, minSeq, min(maxSeq, coldEnd+1), func(m Message) bool {
+				msgs = append(msgs, m)
+				size += uint64(m.Size())
+				if limitSize != 0 && size >= limitSize {
+					full = true
+					return false
+				}
+				return true
+			})
+			if err != nil {
+				return err
+			}
+			if full {
+				return nil
+			}
+			localMinSeq = coldEnd + 1
+			if limitSize != 0 {
+				localLimitSize = limitSize - size
+			}
+		}
+		if localMinSeq >= maxSeq {
+			return nil
+		}
+		localMsgs, err := wk.loadLocalRangeMsgsForSize(channelId, channelType, localMinSeq, maxSeq, localLimitSize)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, localMsgs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func (wk *wukongDB) loadLocalRangeMsgsForSize(channelId string, channelType uint8, minSeq, maxSeq uint64, limitSize uint64) ([]Message, error) {
 	db := wk.channelDb(channelId, channelType)
 
 	iter := db.NewIter(&pebble.IterOptions{
@@ -379,7 +490,16 @@ func (wk *wukongDB) TruncateLogTo(channelId string, channelType uint8, messageSe
 		}()
 	}
 
-	db := wk.channelDb(channelId, channelType)
+	if wk.tieredEnabled() {
+		wk.dblock.channelSegmentLock.lock(channelId, channelType)
+		defer wk.dblock.channelSegmentLock.unlock(channelId, channelType)
+	}
+
+	shardId := wk.channelDbIndex(channelId, channelType)
+	wk.shardLocks[shardId].RLock()
+	defer wk.shardLocks[shardId].RUnlock()
+
+	db := wk.shardDBById(shardId)
 	err := db.DeleteRange(key.NewMessagePrimaryKey(channelId, channelType, messageSeq), key.NewMessagePrimaryKey(channelId, channelType, math.MaxUint64), wk.noSync)
 	if err != nil {
 		return err
@@ -387,6 +507,15 @@ func (wk *wukongDB) TruncateLogTo(channelId string, channelType uint8, messageSe
 	batch := db.NewBatch()
 	defer batch.Close()
 
+	// 截断点在已下沉的消息内
+	var removedSegs []ChannelSegment
+	if wk.tieredEnabled() {
+		removedSegs, err = wk.truncateColdTo(channelId, channelType, messageSeq, batch)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = batch.DeleteRange(key.NewMessagePrimaryKey(channelId, channelType, messageSeq), key.NewMessagePrimaryKey(channelId, channelType, math.MaxUint64), wk.noSync)
 	if err != nil {
 		return err
@@ -396,7 +525,13 @@ func (wk *wukongDB) TruncateLogTo(channelId string, channelType uint8, messageSe
 		return err
 	}
 
-	return batch.Commit(wk.sync)
+	if err = batch.Commit(wk.sync); err != nil {
+		return err
+	}
+	if len(removedSegs) > 0 {
+		wk.deleteSegmentObjects(removedSegs)
+	}
+	return nil
 }
 
 func min(x, y uint64) uint64 {
@@ -517,21 +652,13 @@ func (wk *wukongDB) searchMessageByIndex(req MessageSearchReq, db *pebble.DB, it
 			continue
 		}
 
-		iter := db.NewIter(&pebble.IterOptions{
-			LowerBound: key.NewMessageColumnKeyWithPrimary(primaryBytes, key.MinColumnKey),
-			UpperBound: key.NewMessageColumnKeyWithPrimary(primaryBytes, key.MaxColumnKey),
-		})
-
-		defer iter.Close()
-
-		var msg Message
-		err = wk.iteratorChannelMessages(iter, 0, func(m Message) bool {
-			msg = m
-			return false
-		})
+		msg, _, err := wk.getMessageByPrimaryKey(db, primaryBytes)
 		if err != nil {
 			return false, err
 		}
+		if IsEmptyMessage(msg) {
+			continue
+		}
 		if iterFnc != nil {
 			if !iterFnc(msg) {
 				break
@@ -683,15 +810,13 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 
 			for ; iter.Valid(); iterStepFnc() {
 				copy(pkey[:], iter.Value())
-				resultIter := db.NewIter(&pebble.IterOptions{
-					LowerBound: key.NewMessageColumnKeyWithPrimary(pkey, key.MinColumnKey),
-					UpperBound: key.NewMessageColumnKeyWithPrimary(pkey, key.MaxColumnKey),
-				})
-				defer resultIter.Close()
-				err = wk.iteratorChannelMessages(resultIter, 0, fnc)
+				msg, _, err := wk.getMessageByPrimaryKey(db, pkey)
 				if err != nil {
 					return nil, err
 				}
+				if !IsEmptyMessage(msg) {
+					fnc(msg)
+				}
 				if len(msgs) >= req.Limit {
 					break
 				}
@@ -720,6 +845,148 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 	return allMsgs, nil
 }
 
+func (wk *wukongDB) GetMessagesByFromUid(fromUid string) ([]Message, error) {
+	msgs := make([]Message, 0)
+	for _, db := range wk.dbs {
+		err := wk.iterateMessagesByFromUid(fromUid, db, func(m Message, _ [16]byte, _ bool) bool {
+			msgs = append(msgs, m)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return msgs, nil
+}
+
+func (wk *wukongDB) EraseMessagesByFromUid(fromUid string) (int, error) {
+	count := 0
+	for i, db := range wk.dbs {
+		batch := db.NewBatch()
+		shardCount := 0
+		var setErr error
+		coldSeqs := make(map[string]map[uint64]struct{}) // 已下沉的消息，按频道分组后重写所在的段
+		err := wk.iterateMessagesByFromUid(fromUid, db, func(m Message, primaryKey [16]byte, cold bool) bool {
+			if cold {
+				channelKey := wkutil.ChannelToKey(m.ChannelID, m.ChannelType)
+				if coldSeqs[channelKey] == nil {
+					coldSeqs[channelKey] = make(map[uint64]struct{})
+				}
+				coldSeqs[channelKey][uint64(m.MessageSeq)] = struct{}{}
+				return true
+			}
+			// 清空消息内容和发送者，保留消息序号，避免影响频道日志的连续性
+			if setErr = batch.Set(key.NewMessageColumnKeyWithPrimary(primaryKey, key.TableMessage.Column.Payload), []byte{}, wk.noSync); setErr != nil {
+				return false
+			}
+			if setErr = batch.Delete(key.NewMessageColumnKeyWithPrimary(primaryKey, key.TableMessage.Column.EncryptedPayload), wk.noSync); setErr != nil {
+				return false
+			}
+			if setErr = batch.Set(key.NewMessageColumnKeyWithPrimary(primaryKey, key.TableMessage.Column.FromUid), []byte{}, wk.noSync); setErr != nil {
+				return false
+			}
+			if setErr = batch.Delete(key.NewMessageSecondIndexFromUidKey(fromUid, primaryKey), wk.noSync); setErr != nil {
+				return false
+			}
+			shardCount++
+			return true
+		})
+		if err == nil {
+			err = setErr
+		}
+		if err != nil {
+			batch.Close()
+			wk.Error("erase messages failed", zap.Error(err), zap.String("fromUid", fromUid), zap.Int("shard", i))
+			return count, err
+		}
+		wk.shardLocks[i].RLock()
+		err = batch.Commit(wk.sync)
+		wk.shardLocks[i].RUnlock()
+		batch.Close()
+		if err != nil {
+			return count, err
+		}
+		count += shardCount
+
+		// 段重写成功后再删除发送者索引，失败时可以重新执行
+		for channelKey, seqs := range coldSeqs {
+			channelId, channelType := wkutil.ChannelFromlKey(channelKey)
+			erased, err := wk.eraseColdMessages(channelId, channelType, seqs)
+			count += erased
+			if err != nil {
+				wk.Error("erase cold messages failed", zap.Error(err), zap.String("fromUid", fromUid), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+				return count, err
+			}
+			channelHash := key.ChannelIdToNum(channelId, channelType)
+			for seq := range seqs {
+				var primaryKey [16]byte
+				wk.endian.PutUint64(primaryKey[:], channelHash)
+				wk.endian.PutUint64(primaryKey[8:], seq)
+				if err = db.Delete(key.NewMessageSecondIndexFromUidKey(fromUid, primaryKey), wk.noSync); err != nil {
+					return count, err
+				}
+			}
+		}
+	}
+	return count, nil
+}
+
+// 通过发送者索引遍历消息
+func (wk *wukongDB) iterateMessagesByFromUid(fromUid string, db *pebble.DB, iterFnc func(m Message, primaryKey [16]byte, cold bool) bool) error {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageSecondIndexFromUidKey(fromUid, minMessagePrimaryKey),
+		UpperBound: key.NewMessageSecondIndexFromUidKey(fromUid, maxMessagePrimaryKey),
+	})
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		primaryKey, err := key.ParseMessageSecondIndexKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		msg, cold, err := wk.getMessageByPrimaryKey(db, primaryKey)
+		if err != nil {
+			return err
+		}
+		if IsEmptyMessage(msg) || msg.FromUID != fromUid { // uid的hash可能冲突
+			continue
+		}
+		if !iterFnc(msg, primaryKey, cold) {
+			break
+		}
+	}
+	return nil
+}
+
+// getMessageByPrimaryKey 通过主键获取消息，本地没有时从已下沉的段获取，cold表示消息来自段
+// 消息不存在（例如索引指向的消息已被截断）时返回空消息
+func (wk *wukongDB) getMessageByPrimaryKey(db *pebble.DB, primaryKey [16]byte) (Message, bool, error) {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageColumnKeyWithPrimary(primaryKey, key.MinColumnKey),
+		UpperBound: key.NewMessageColumnKeyWithPrimary(primaryKey, key.MaxColumnKey),
+	})
+	var msg Message
+	err := wk.iteratorChannelMessages(iter, 0, func(m Message) bool {
+		msg = m
+		return false
+	})
+	iter.Close()
+	if err != nil {
+		return EmptyMessage, false, err
+	}
+	if !IsEmptyMessage(msg) || !wk.tieredEnabled() {
+		return msg, false, nil
+	}
+	msg, err = wk.getColdMessage(db, wk.endian.Uint64(primaryKey[:8]), wk.endian.Uint64(primaryKey[8:]))
+	if err != nil {
+		if err == ErrNotFound {
+			return EmptyMessage, false, nil
+		}
+		return EmptyMessage, false, err
+	}
+	return msg, true, nil
+}
+
 func (wk *wukongDB) setChannelLastMessageSeq(channelId string, channelType uint8, seq uint64, w pebble.Writer, o *pebble.WriteOptions) error {
 	data := make([]byte, 16)
 	wk.endian.PutUint64(data, seq)
@@ -809,8 +1076,12 @@ func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limi
 			preMessage.FromUID = string(iter.Value())
 		case key.TableMessage.Column.Payload:
 			// 这里必须复制一份，否则会被pebble覆盖
-			var payload = make([]byte, len(iter.Value()))
-			copy(payload, iter.Value())
+			preMessage.Payload = bytes.Clone(iter.Value())
+		case key.TableMessage.Column.EncryptedPayload:
+			payload, err := wk.decodePayload(iter.Key(), iter.Value())
+			if err != nil {
+				return err
+			}
 			preMessage.Payload = payload
 		case key.TableMessage.Column.Term:
 			preMessage.Term = wk.endian.Uint64(iter.Value())
@@ -887,8 +1158,12 @@ func (wk *wukongDB) parseChannelMessagesWithLimitSize(iter *pebble.Iterator, lim
 			preMessage.RecvPacket.FromUID = string(iter.Value())
 		case key.TableMessage.Column.Payload:
 			// 这里必须复制一份，否则会被pebble覆盖
-			var payload = make([]byte, len(iter.Value()))
-			copy(payload, iter.Value())
+			preMessage.Payload = bytes.Clone(iter.Value())
+		case key.TableMessage.Column.EncryptedPayload:
+			payload, err := wk.decodePayload(iter.Key(), iter.Value())
+			if err != nil {
+				return nil, err
+			}
 			preMessage.Payload = payload
 		case key.TableMessage.Column.Term:
 			preMessage.Term = wk.endian.Uint64(iter.Value())
@@ -974,10 +1249,27 @@ func (wk *wukongDB) writeMessage(channelId string, channelType uint8, msg Messag
 		return err
 	}
 
-	// payload
-	if err = w.Set(key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), key.TableMessage.Column.Payload), msg.Payload, wk.noSync); err != nil {
+	var primaryValue = [16]byte{}
+	wk.endian.PutUint64(primaryValue[:], key.ChannelIdToNum(channelId, channelType))
+	wk.endian.PutUint64(primaryValue[8:], uint64(msg.MessageSeq))
+
+	// payload（开启静态加密时加密后保存到EncryptedPayload列）
+	payload, keyVersion, err := wk.encryptData(wk.channelDbIndex(channelId, channelType), primaryValue[:], msg.Payload)
+	if err != nil {
+		return err
+	}
+	payloadColumn, staleColumn := key.TableMessage.Column.Payload, key.TableMessage.Column.EncryptedPayload
+	if keyVersion != 0 {
+		payloadColumn, staleColumn = staleColumn, payloadColumn
+	}
+	if err = w.Set(key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), payloadColumn), payload, wk.noSync); err != nil {
 		return err
 	}
+	if wk.keyring != nil { // 同一条消息只能有一列内容
+		if err = w.Delete(key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), staleColumn), wk.noSync); err != nil {
+			return err
+		}
+	}
 
 	// term
 	termBytes := make([]byte, 8)
@@ -986,10 +1278,6 @@ func (wk *wukongDB) writeMessage(channelId string, channelType uint8, msg Messag
 		return err
 	}
 
-	var primaryValue = [16]byte{}
-	wk.endian.PutUint64(primaryValue[:], key.ChannelIdToNum(channelId, channelType))
-	wk.endian.PutUint64(primaryValue[8:], uint64(msg.MessageSeq))
-
 	// index fromUid
 	if err = w.Set(key.NewMessageSecondIndexFromUidKey(msg.FromUID, primaryValue), nil, wk.noSync); err != nil {
 		return err
@@ -38,7 +38,7 @@ func (wk *wukongDB) RemoveMessagesOfNotifyQueue(messageIDs []int64) error {
 	defer batch.Close()
 
 	for _, messageID := range messageIDs {
-		if err := batch.Delete(key.NewMessageNotifyQueueKey(uint64(messageID)), wk.sync); err != nil {
+		if err := wk.removeMessageOfNotifyQueue(messageID, batch); err != nil {
 			return err
 		}
 	}
@@ -57,6 +57,9 @@ func (wk *wukongDB) parseMessageOfNotifyQueue(iter *pebble.Iterator, limit int)
 
 	msgs := make([]Message, 0, limit)
 	for iter.First(); iter.Valid(); iter.Next() {
+		if limit > 0 && len(msgs) >= limit {
+			break
+		}
 		value := iter.Value()
 		// 解析消息
 		var msg Message
@@ -210,3 +210,46 @@ func TestSearchMessages(t *testing.T) {
 	assert.Equal(t, 10, len(resultMessages))
 
 }
+
+func TestEraseMessagesByFromUid(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+	messages := []wkdb.Message{
+		{RecvPacket: wkproto.RecvPacket{MessageID: 1, ChannelID: channelId, ChannelType: channelType, MessageSeq: 1, FromUID: "u1", Payload: []byte("hello1")}},
+		{RecvPacket: wkproto.RecvPacket{MessageID: 2, ChannelID: channelId, ChannelType: channelType, MessageSeq: 2, FromUID: "u2", Payload: []byte("hello2")}},
+		{RecvPacket: wkproto.RecvPacket{MessageID: 3, ChannelID: channelId, ChannelType: channelType, MessageSeq: 3, FromUID: "u1", Payload: []byte("hello3")}},
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	msgs, err := d.GetMessagesByFromUid("u1")
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 2)
+
+	count, err := d.EraseMessagesByFromUid("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	msgs, err = d.GetMessagesByFromUid("u1")
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 0)
+
+	m, err := d.LoadMsg(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "", m.FromUID)
+	assert.Len(t, m.Payload, 0)
+
+	m, err = d.LoadMsg(channelId, channelType, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "u2", m.FromUID)
+	assert.Equal(t, []byte("hello2"), m.Payload)
+}
@@ -0,0 +1,179 @@
+package wkdb
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+// GetMirrorCheckpoint 获取镜像进度
+func (wk *wukongDB) GetMirrorCheckpoint(name string) (uint64, error) {
+	value, closer, err := wk.defaultShardDB().Get(key.NewMirrorCheckpointKey(name))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	if len(value) < 8 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+// SetMirrorCheckpoint 保存镜像进度
+func (wk *wukongDB) SetMirrorCheckpoint(name string, value uint64) error {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, value)
+	return wk.defaultShardDB().Set(key.NewMirrorCheckpointKey(name), data, wk.sync)
+}
+
+// MarkMirrorChannel 标记频道有新消息需要镜像，messageSeq为需要镜像到的消息序号（只会变大）
+func (wk *wukongDB) MarkMirrorChannel(channelId string, channelType uint8, messageSeq uint64) error {
+	return wk.MarkMirrorChannels([]MirrorChannel{{ChannelId: channelId, ChannelType: channelType, MessageSeq: messageSeq}})
+}
+
+// MarkMirrorChannels 批量标记频道有新消息需要镜像
+// 标记和频道的消息保存在同一个分区，开启镜像时追加消息会在同一个批次里更新标记
+func (wk *wukongDB) MarkMirrorChannels(channels []MirrorChannel) error {
+	if len(channels) == 0 {
+		return nil
+	}
+	shardChannels := make(map[uint32][]MirrorChannel)
+	for _, ch := range channels {
+		shardId := wk.channelDbIndex(ch.ChannelId, ch.ChannelType)
+		shardChannels[shardId] = append(shardChannels[shardId], ch)
+	}
+	for shardId, chs := range shardChannels {
+		if err := wk.markMirrorChannels(shardId, chs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wk *wukongDB) markMirrorChannels(shardId uint32, channels []MirrorChannel) error {
+	// 加写锁，防止和追加消息时的标记交叉写入
+	wk.shardLocks[shardId].Lock()
+	defer wk.shardLocks[shardId].Unlock()
+
+	db := wk.shardDBById(shardId)
+	batch := db.NewBatch()
+	defer batch.Close()
+	for _, ch := range channels {
+		old, err := wk.getMirrorChannel(ch.ChannelId, ch.ChannelType)
+		if err != nil {
+			return err
+		}
+		if old.ChannelId != "" && old.MessageSeq >= ch.MessageSeq {
+			continue
+		}
+		if err = wk.setMirrorChannel(ch.ChannelId, ch.ChannelType, ch.MessageSeq, batch); err != nil {
+			return err
+		}
+	}
+	if batch.Empty() {
+		return nil
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) setMirrorChannel(channelId string, channelType uint8, messageSeq uint64, w pebble.Writer) error {
+	ch := MirrorChannel{ChannelId: channelId, ChannelType: channelType, MessageSeq: messageSeq}
+	data, err := ch.Marshal()
+	if err != nil {
+		return err
+	}
+	return w.Set(key.NewMirrorChannelKey(channelId, channelType), data, wk.noSync)
+}
+
+// GetMirrorChannels 获取等待镜像的频道，从指定频道之后（不包含）开始获取，startChannelId为空表示从头开始
+func (wk *wukongDB) GetMirrorChannels(startChannelId string, startChannelType uint8, limit int) ([]MirrorChannel, error) {
+	var (
+		startShardId uint32
+		startKey     []byte
+	)
+	if startChannelId != "" {
+		startShardId = wk.channelDbIndex(startChannelId, startChannelType)
+		startKey = key.NewMirrorChannelKey(startChannelId, startChannelType)
+	}
+	channels := make([]MirrorChannel, 0)
+	for shardId := startShardId; shardId < uint32(len(wk.dbs)); shardId++ {
+		var afterKey []byte
+		if shardId == startShardId {
+			afterKey = startKey
+		}
+		var err error
+		channels, err = wk.getMirrorChannels(shardId, afterKey, limit, channels)
+		if err != nil {
+			return nil, err
+		}
+		if limit > 0 && len(channels) >= limit {
+			break
+		}
+	}
+	return channels, nil
+}
+
+func (wk *wukongDB) getMirrorChannels(shardId uint32, afterKey []byte, limit int, channels []MirrorChannel) ([]MirrorChannel, error) {
+	lowKey := key.NewMirrorChannelLowKey()
+	if afterKey != nil {
+		lowKey = afterKey
+	}
+	iter := wk.shardDBById(shardId).NewIter(&pebble.IterOptions{
+		LowerBound: lowKey,
+		UpperBound: key.NewMirrorChannelHighKey(),
+	})
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		if limit > 0 && len(channels) >= limit {
+			break
+		}
+		if afterKey != nil && bytes.Equal(iter.Key(), afterKey) {
+			continue
+		}
+		var ch MirrorChannel
+		if err := ch.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		channels = append(channels, ch)
+	}
+	return channels, nil
+}
+
+// RemoveMirrorChannel 频道已镜像到messageSeq，如果期间没有新的标记则移除
+func (wk *wukongDB) RemoveMirrorChannel(channelId string, channelType uint8, messageSeq uint64) error {
+	// 加写锁，检查和删除期间不会追加新的消息
+	shardId := wk.channelDbIndex(channelId, channelType)
+	wk.shardLocks[shardId].Lock()
+	defer wk.shardLocks[shardId].Unlock()
+
+	old, err := wk.getMirrorChannel(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	if old.ChannelId == "" || old.MessageSeq > messageSeq {
+		return nil
+	}
+	return wk.shardDBById(shardId).Delete(key.NewMirrorChannelKey(channelId, channelType), wk.sync)
+}
+
+func (wk *wukongDB) getMirrorChannel(channelId string, channelType uint8) (MirrorChannel, error) {
+	value, closer, err := wk.channelDb(channelId, channelType).Get(key.NewMirrorChannelKey(channelId, channelType))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return MirrorChannel{}, nil
+		}
+		return MirrorChannel{}, err
+	}
+	defer closer.Close()
+	var ch MirrorChannel
+	if err := ch.Unmarshal(value); err != nil {
+		return MirrorChannel{}, err
+	}
+	return ch, nil
+}
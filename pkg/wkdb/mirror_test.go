@@ -0,0 +1,169 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirrorCheckpoint(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	checkpoint, err := d.GetMirrorCheckpoint("slot:1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), checkpoint)
+
+	err = d.SetMirrorCheckpoint("slot:1", 100)
+	assert.NoError(t, err)
+
+	checkpoint, err = d.GetMirrorCheckpoint("slot:1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), checkpoint)
+
+	checkpoint, err = d.GetMirrorCheckpoint("slot:2")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), checkpoint)
+}
+
+func TestMirrorChannels(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.MarkMirrorChannel("ch1", 2, 10)
+	assert.NoError(t, err)
+	err = d.MarkMirrorChannel("ch2", 1, 5)
+	assert.NoError(t, err)
+	err = d.MarkMirrorChannel("ch1", 2, 8) // 序号不会变小
+	assert.NoError(t, err)
+
+	channels, err := d.GetMirrorChannels("", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(channels))
+	for _, ch := range channels {
+		if ch.ChannelId == "ch1" {
+			assert.Equal(t, uint8(2), ch.ChannelType)
+			assert.Equal(t, uint64(10), ch.MessageSeq)
+		}
+	}
+
+	channels, err = d.GetMirrorChannels("", 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(channels))
+
+	// 从上一页的最后一个频道之后继续获取
+	next, err := d.GetMirrorChannels(channels[0].ChannelId, channels[0].ChannelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(next))
+	assert.NotEqual(t, channels[0].ChannelId, next[0].ChannelId)
+	next, err = d.GetMirrorChannels(next[0].ChannelId, next[0].ChannelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(next))
+
+	// 镜像期间有新的标记，不移除
+	err = d.RemoveMirrorChannel("ch1", 2, 9)
+	assert.NoError(t, err)
+	channels, err = d.GetMirrorChannels("", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(channels))
+
+	err = d.RemoveMirrorChannel("ch1", 2, 10)
+	assert.NoError(t, err)
+	channels, err = d.GetMirrorChannels("", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(channels))
+	assert.Equal(t, "ch2", channels[0].ChannelId)
+}
+
+func TestMarkMirrorChannels(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.MarkMirrorChannel("ch1", 2, 10)
+	assert.NoError(t, err)
+
+	err = d.MarkMirrorChannels([]wkdb.MirrorChannel{
+		{ChannelId: "ch1", ChannelType: 2, MessageSeq: 8}, // 序号不会变小
+		{ChannelId: "ch2", ChannelType: 1, MessageSeq: 5},
+		{ChannelId: "ch3", ChannelType: 2, MessageSeq: 3},
+	})
+	assert.NoError(t, err)
+
+	channels, err := d.GetMirrorChannels("", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(channels))
+	for _, ch := range channels {
+		switch ch.ChannelId {
+		case "ch1":
+			assert.Equal(t, uint64(10), ch.MessageSeq)
+		case "ch2":
+			assert.Equal(t, uint64(5), ch.MessageSeq)
+		case "ch3":
+			assert.Equal(t, uint64(3), ch.MessageSeq)
+		}
+	}
+}
+
+func TestAppendMessagesMarkMirrorChannel(t *testing.T) {
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(t.TempDir()), wkdb.WithShardNum(2), wkdb.WithMirrorOn()))
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	// 追加消息时在同一个批次里标记频道
+	err = d.AppendMessages("ch1", 2, []wkdb.Message{
+		{RecvPacket: wkproto.RecvPacket{MessageID: 1, MessageSeq: 1, ChannelID: "ch1", ChannelType: 2}},
+		{RecvPacket: wkproto.RecvPacket{MessageID: 2, MessageSeq: 2, ChannelID: "ch1", ChannelType: 2}},
+	})
+	assert.NoError(t, err)
+	err = d.AppendMessages("ch2", 1, []wkdb.Message{
+		{RecvPacket: wkproto.RecvPacket{MessageID: 3, MessageSeq: 5, ChannelID: "ch2", ChannelType: 1}},
+	})
+	assert.NoError(t, err)
+
+	channels, err := d.GetMirrorChannels("", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(channels))
+	for _, ch := range channels {
+		switch ch.ChannelId {
+		case "ch1":
+			assert.Equal(t, uint64(2), ch.MessageSeq)
+		case "ch2":
+			assert.Equal(t, uint64(5), ch.MessageSeq)
+		}
+	}
+
+	err = d.RemoveMirrorChannel("ch1", 2, 2)
+	assert.NoError(t, err)
+	err = d.AppendMessages("ch1", 2, []wkdb.Message{
+		{RecvPacket: wkproto.RecvPacket{MessageID: 4, MessageSeq: 3, ChannelID: "ch1", ChannelType: 2}},
+	})
+	assert.NoError(t, err)
+	channels, err = d.GetMirrorChannels("", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(channels))
+}
@@ -111,21 +111,45 @@ type User struct {
 
 var EmptyChannelInfo = ChannelInfo{}
 
+// ChannelAckMode 频道消息提交需要的副本确认方式
+type ChannelAckMode uint8
+
+const (
+	ChannelAckModeDefault  ChannelAckMode = iota // 使用集群默认配置
+	ChannelAckModeNone                           // 领导写入即提交
+	ChannelAckModeMajority                       // 大多数副本确认后提交
+	ChannelAckModeAll                            // 所有副本确认后提交
+)
+
+func (a ChannelAckMode) String() string {
+	switch a {
+	case ChannelAckModeNone:
+		return "none"
+	case ChannelAckModeMajority:
+		return "majority"
+	case ChannelAckModeAll:
+		return "all"
+	}
+	return "default"
+}
+
 type ChannelInfo struct {
-	Id              uint64     `json:"id,omitempty"`               // ID
-	ChannelId       string     `json:"channel_id,omitempty"`       // 频道ID
-	ChannelType     uint8      `json:"channel_type,omitempty"`     // 频道类型
-	Ban             bool       `json:"ban,omitempty"`              // 是否被封
-	Large           bool       `json:"large,omitempty"`            // 是否是超大群
-	Disband         bool       `json:"disband,omitempty"`          // 是否解散
-	SubscriberCount int        `json:"subscriber_count,omitempty"` // 订阅者数量
-	DenylistCount   int        `json:"denylist_count,omitempty"`   // 黑名单数量
-	AllowlistCount  int        `json:"allowlist_count,omitempty"`  // 白名单数量
-	LastMsgSeq      uint64     `json:"last_msg_seq,omitempty"`     // 最新消息序号
-	LastMsgTime     uint64     `json:"last_msg_time,omitempty"`    // 最后一次消息时间
-	Webhook         string     `json:"webhook,omitempty"`          // webhook地址
-	CreatedAt       *time.Time `json:"created_at,omitempty"`       // 创建时间
-	UpdatedAt       *time.Time `json:"updated_at,omitempty"`       // 更新时间
+	Id              uint64         `json:"id,omitempty"`               // ID
+	ChannelId       string         `json:"channel_id,omitempty"`       // 频道ID
+	ChannelType     uint8          `json:"channel_type,omitempty"`     // 频道类型
+	Ban             bool           `json:"ban,omitempty"`              // 是否被封
+	Large           bool           `json:"large,omitempty"`            // 是否是超大群
+	Disband         bool           `json:"disband,omitempty"`          // 是否解散
+	SubscriberCount int            `json:"subscriber_count,omitempty"` // 订阅者数量
+	DenylistCount   int            `json:"denylist_count,omitempty"`   // 黑名单数量
+	AllowlistCount  int            `json:"allowlist_count,omitempty"`  // 白名单数量
+	LastMsgSeq      uint64         `json:"last_msg_seq,omitempty"`     // 最新消息序号
+	LastMsgTime     uint64         `json:"last_msg_time,omitempty"`    // 最后一次消息时间
+	Webhook         string         `json:"webhook,omitempty"`          // webhook地址
+	ReplicaCount    uint16         `json:"replica_count,omitempty"`    // 副本数量，0表示使用集群默认配置
+	AckMode         ChannelAckMode `json:"ack_mode,omitempty"`         // 副本确认方式
+	CreatedAt       *time.Time     `json:"created_at,omitempty"`       // 创建时间
+	UpdatedAt       *time.Time     `json:"updated_at,omitempty"`       // 更新时间
 }
 
 func NewChannelInfo(channelId string, channelType uint8) ChannelInfo {
@@ -281,13 +305,16 @@ const (
 
 // Conversation Conversation
 type Conversation struct {
-	Id           uint64           `json:"id,omitempty"`
-	Uid          string           `json:"uid,omitempty"`               // 用户uid
-	Type         ConversationType `json:"type,omitempty"`              // 会话类型
-	ChannelId    string           `json:"channel_id,omitempty"`        // 频道id
-	ChannelType  uint8            `json:"channel_type,omitempty"`      // 频道类型
-	UnreadCount  uint32           `json:"unread_count,omitempty"`      // 未读消息数量（这个可以用户自己设置）
-	ReadToMsgSeq uint64           `json:"readed_to_msg_seq,omitempty"` // 已经读至的消息序号
+	Id           uint64               `json:"id,omitempty"`
+	Uid          string               `json:"uid,omitempty"`               // 用户uid
+	Type         ConversationType     `json:"type,omitempty"`              // 会话类型
+	ChannelId    string               `json:"channel_id,omitempty"`        // 频道id
+	ChannelType  uint8                `json:"channel_type,omitempty"`      // 频道类型
+	UnreadCount  uint32               `json:"unread_count,omitempty"`      // 未读消息数量（这个可以用户自己设置）
+	ReadToMsgSeq uint64               `json:"readed_to_msg_seq,omitempty"` // 已经读至的消息序号
+	Version      uint64               `json:"version,omitempty"`           // 版本号，用户的会话每次变更（包括删除）都会递增，写入时自动生成
+	Extra        *ConversationExtra   `json:"extra,omitempty"`             // 扩展属性（置顶、免打扰、草稿等），为nil时更新会话不修改扩展属性
+	Mention      *ConversationMention `json:"mention,omitempty"`           // 未读的@提醒，为nil时更新会话不修改
 
 	CreatedAt *time.Time `json:"created_at,omitempty"` // 创建时间
 	UpdatedAt *time.Time `json:"updated_at,omitempty"` // 更新时间
@@ -315,6 +342,29 @@ func (c *Conversation) Marshal() ([]byte, error) {
 	} else {
 		enc.WriteUint64(0)
 	}
+	enc.WriteUint64(c.Version)
+
+	if c.Extra != nil {
+		enc.WriteUint8(1)
+		extraData, err := c.Extra.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		enc.WriteBinary(extraData)
+	} else {
+		enc.WriteUint8(0)
+	}
+
+	if c.Mention != nil {
+		enc.WriteUint8(1)
+		mentionData, err := c.Mention.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		enc.WriteBinary(mentionData)
+	} else {
+		enc.WriteUint8(0)
+	}
 
 	return enc.Bytes(), nil
 }
@@ -369,6 +419,180 @@ func (c *Conversation) Unmarshal(data []byte) error {
 		c.UpdatedAt = &ct
 	}
 
+	if dec.Len() > 0 { // 兼容旧数据
+		if c.Version, err = dec.Uint64(); err != nil {
+			return err
+		}
+	}
+
+	if dec.Len() > 0 {
+		var hasExtra uint8
+		if hasExtra, err = dec.Uint8(); err != nil {
+			return err
+		}
+		if hasExtra == 1 {
+			var extraData []byte
+			if extraData, err = dec.Binary(); err != nil {
+				return err
+			}
+			c.Extra = &ConversationExtra{}
+			if err = c.Extra.Unmarshal(extraData); err != nil {
+				return err
+			}
+		}
+	}
+
+	if dec.Len() > 0 {
+		var hasMention uint8
+		if hasMention, err = dec.Uint8(); err != nil {
+			return err
+		}
+		if hasMention == 1 {
+			var mentionData []byte
+			if mentionData, err = dec.Binary(); err != nil {
+				return err
+			}
+			c.Mention = &ConversationMention{}
+			if err = c.Mention.Unmarshal(mentionData); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ConversationExtra 会话的扩展属性，用户的多个设备之间同步
+type ConversationExtra struct {
+	Pin     bool   `json:"pin,omitempty"`     // 是否置顶
+	Mute    bool   `json:"mute,omitempty"`    // 是否免打扰（免打扰的会话不推送离线，不计入角标）
+	Draft   string `json:"draft,omitempty"`   // 草稿
+	Custom  string `json:"custom,omitempty"`  // 业务自定义的扩展数据（比如标签）
+	Version uint64 `json:"version,omitempty"` // 扩展属性最后一次修改时会话的版本号，写入时自动生成
+}
+
+func (c *ConversationExtra) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint8(wkutil.BoolToUint8(c.Pin))
+	enc.WriteUint8(wkutil.BoolToUint8(c.Mute))
+	enc.WriteString(c.Draft)
+	enc.WriteString(c.Custom)
+	enc.WriteUint64(c.Version)
+	return enc.Bytes(), nil
+}
+
+func (c *ConversationExtra) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	var pin uint8
+	if pin, err = dec.Uint8(); err != nil {
+		return err
+	}
+	var mute uint8
+	if mute, err = dec.Uint8(); err != nil {
+		return err
+	}
+	c.Pin = wkutil.Uint8ToBool(pin)
+	c.Mute = wkutil.Uint8ToBool(mute)
+	if c.Draft, err = dec.String(); err != nil {
+		return err
+	}
+	if c.Custom, err = dec.String(); err != nil {
+		return err
+	}
+	if c.Version, err = dec.Uint64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ConversationMention 会话里未读的@提醒
+type ConversationMention struct {
+	Count  uint32 `json:"count,omitempty"`   // 未读的@数量
+	MsgSeq uint64 `json:"msg_seq,omitempty"` // 最后一条@我的消息序号
+	All    bool   `json:"all,omitempty"`     // 最后一条@是否是@所有人
+}
+
+// Merge 合并后面产生的@提醒
+func (c *ConversationMention) Merge(o *ConversationMention) *ConversationMention {
+	if c == nil {
+		return o
+	}
+	if o == nil {
+		return c
+	}
+	merged := &ConversationMention{
+		Count:  c.Count + o.Count,
+		MsgSeq: c.MsgSeq,
+		All:    c.All,
+	}
+	if o.MsgSeq >= c.MsgSeq {
+		merged.MsgSeq = o.MsgSeq
+		merged.All = o.All
+	}
+	return merged
+}
+
+func (c *ConversationMention) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(c.Count)
+	enc.WriteUint64(c.MsgSeq)
+	enc.WriteUint8(wkutil.BoolToUint8(c.All))
+	return enc.Bytes(), nil
+}
+
+func (c *ConversationMention) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if c.Count, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if c.MsgSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	var all uint8
+	if all, err = dec.Uint8(); err != nil {
+		return err
+	}
+	c.All = wkutil.Uint8ToBool(all)
+	return nil
+}
+
+// ConversationTombstone 已删除会话的记录，增量同步时告知客户端删除
+type ConversationTombstone struct {
+	Uid         string `json:"uid"`
+	ChannelId   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	Version     uint64 `json:"version"` // 删除时的版本号
+}
+
+func (c *ConversationTombstone) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(c.Uid)
+	enc.WriteString(c.ChannelId)
+	enc.WriteUint8(c.ChannelType)
+	enc.WriteUint64(c.Version)
+	return enc.Bytes(), nil
+}
+
+func (c *ConversationTombstone) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if c.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	if c.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if c.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if c.Version, err = dec.Uint64(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -430,6 +654,7 @@ type ChannelClusterConfig struct {
 	ChannelId       string               `json:"channel_id,omitempty"`        // 频道ID
 	ChannelType     uint8                `json:"channel_type,omitempty"`      // 频道类型
 	ReplicaMaxCount uint16               `json:"replica_max_count,omitempty"` // 副本最大数量
+	AckMode         ChannelAckMode       `json:"ack_mode,omitempty"`          // 副本确认方式
 	Replicas        []uint64             `json:"replicas,omitempty"`          // 副本节点ID集合
 	Learners        []uint64             `json:"learners,omitempty"`          // 学习者节点ID集合
 	LeaderId        uint64               `json:"learder_id,omitempty"`        // 领导者ID
@@ -450,6 +675,7 @@ func (c *ChannelClusterConfig) Clone() ChannelClusterConfig {
 		ChannelId:       c.ChannelId,
 		ChannelType:     c.ChannelType,
 		ReplicaMaxCount: c.ReplicaMaxCount,
+		AckMode:         c.AckMode,
 		Replicas:        c.Replicas,
 		Learners:        c.Learners,
 		LeaderId:        c.LeaderId,
@@ -477,6 +703,9 @@ func (c *ChannelClusterConfig) Equal(cfg ChannelClusterConfig) bool {
 	if c.ReplicaMaxCount != cfg.ReplicaMaxCount {
 		return false
 	}
+	if c.AckMode != cfg.AckMode {
+		return false
+	}
 	if len(c.Replicas) != len(cfg.Replicas) {
 		return false
 	}
@@ -515,7 +744,7 @@ func (c *ChannelClusterConfig) Equal(cfg ChannelClusterConfig) bool {
 }
 
 func (c *ChannelClusterConfig) Marshal() ([]byte, error) {
-	c.version = 1
+	c.version = 2
 	enc := wkproto.NewEncoder()
 	defer enc.End()
 	enc.WriteUint16(c.version)
@@ -550,6 +779,7 @@ func (c *ChannelClusterConfig) Marshal() ([]byte, error) {
 	} else {
 		enc.WriteUint64(0)
 	}
+	enc.WriteUint8(uint8(c.AckMode))
 	return enc.Bytes(), nil
 }
 
@@ -636,6 +866,14 @@ func (c *ChannelClusterConfig) Unmarshal(data []byte) error {
 		c.UpdatedAt = &ct
 	}
 
+	if c.version > 1 {
+		var ackMode uint8
+		if ackMode, err = dec.Uint8(); err != nil {
+			return err
+		}
+		c.AckMode = ChannelAckMode(ackMode)
+	}
+
 	return nil
 }
 
@@ -725,6 +963,15 @@ type Channel struct {
 	ChannelType uint8  `json:"channel_type,omitempty"`
 }
 
+// MemberOfChannel 用户在频道内的成员关系
+type MemberOfChannel struct {
+	ChannelId   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	Subscriber  bool   `json:"subscriber,omitempty"` // 是否是订阅者
+	Denylist    bool   `json:"denylist,omitempty"`   // 是否在黑名单内
+	Allowlist   bool   `json:"allowlist,omitempty"`  // 是否在白名单内
+}
+
 type Member struct {
 	Id        uint64     `json:"id"`
 	Uid       string     `json:"uid"`
@@ -787,3 +1034,483 @@ func (m *Member) Unmarshal(data []byte) error {
 	}
 	return nil
 }
+
+// WebhookDeadLetter 重试多次仍然投递失败的webhook事件
+type WebhookDeadLetter struct {
+	Id         uint64
+	Event      string  // 事件类型
+	MessageIds []int64 // 事件包含的消息id（消息通知事件才有）
+	RetryCount int     // 已重试次数
+	Error      string  // 最后一次失败的原因
+	CreatedAt  time.Time
+	Data       []byte // 事件数据
+}
+
+func (w *WebhookDeadLetter) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(w.Id)
+	enc.WriteString(w.Event)
+	enc.WriteUint32(uint32(len(w.MessageIds)))
+	for _, messageId := range w.MessageIds {
+		enc.WriteInt64(messageId)
+	}
+	enc.WriteUint32(uint32(w.RetryCount))
+	errStr := w.Error
+	if len(errStr) > 1024 {
+		errStr = errStr[:1024]
+	}
+	enc.WriteString(errStr)
+	enc.WriteInt64(w.CreatedAt.UnixNano())
+	enc.WriteBytes(w.Data)
+	return enc.Bytes(), nil
+}
+
+func (w *WebhookDeadLetter) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if w.Id, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if w.Event, err = dec.String(); err != nil {
+		return err
+	}
+	var count uint32
+	if count, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if count > 0 {
+		w.MessageIds = make([]int64, 0, count)
+		for i := 0; i < int(count); i++ {
+			messageId, err := dec.Int64()
+			if err != nil {
+				return err
+			}
+			w.MessageIds = append(w.MessageIds, messageId)
+		}
+	}
+	var retryCount uint32
+	if retryCount, err = dec.Uint32(); err != nil {
+		return err
+	}
+	w.RetryCount = int(retryCount)
+	if w.Error, err = dec.String(); err != nil {
+		return err
+	}
+	var createdAt int64
+	if createdAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	w.CreatedAt = time.Unix(0, createdAt)
+	if w.Data, err = dec.BinaryAll(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SinkEvent 等待投递到消息中间件的事件
+type SinkEvent struct {
+	Id        uint64
+	Type      string // 事件类型
+	Key       string // 分区键
+	Timestamp int64  // 事件产生时间（毫秒）
+	Data      []byte // 事件数据
+}
+
+func (s *SinkEvent) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(s.Id)
+	enc.WriteString(s.Type)
+	enc.WriteString(s.Key)
+	enc.WriteInt64(s.Timestamp)
+	enc.WriteBytes(s.Data)
+	return enc.Bytes(), nil
+}
+
+func (s *SinkEvent) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if s.Id, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if s.Type, err = dec.String(); err != nil {
+		return err
+	}
+	if s.Key, err = dec.String(); err != nil {
+		return err
+	}
+	if s.Timestamp, err = dec.Int64(); err != nil {
+		return err
+	}
+	if s.Data, err = dec.BinaryAll(); err != nil {
+		return err
+	}
+	return nil
+}
+
+var EmptyReaction = Reaction{}
+
+// Reaction 用户对消息的回应
+type Reaction struct {
+	ChannelId   string     `json:"channel_id"`
+	ChannelType uint8      `json:"channel_type"`
+	MessageId   uint64     `json:"message_id"`
+	MessageSeq  uint64     `json:"message_seq"`
+	Uid         string     `json:"uid"`
+	Emoji       string     `json:"emoji"`
+	IsDeleted   bool       `json:"is_deleted"` // 是否已取消回应
+	Version     uint64     `json:"version"`    // 频道内的回应版本号
+	CreatedAt   *time.Time `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
+
+func (r *Reaction) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(r.ChannelId)
+	enc.WriteUint8(r.ChannelType)
+	enc.WriteUint64(r.MessageId)
+	enc.WriteUint64(r.MessageSeq)
+	enc.WriteString(r.Uid)
+	enc.WriteString(r.Emoji)
+	if r.IsDeleted {
+		enc.WriteUint8(1)
+	} else {
+		enc.WriteUint8(0)
+	}
+	enc.WriteUint64(r.Version)
+	var createdAt, updatedAt int64
+	if r.CreatedAt != nil {
+		createdAt = r.CreatedAt.UnixNano()
+	}
+	if r.UpdatedAt != nil {
+		updatedAt = r.UpdatedAt.UnixNano()
+	}
+	enc.WriteInt64(createdAt)
+	enc.WriteInt64(updatedAt)
+	return enc.Bytes(), nil
+}
+
+func (r *Reaction) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if r.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if r.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if r.MessageId, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if r.MessageSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if r.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	if r.Emoji, err = dec.String(); err != nil {
+		return err
+	}
+	var isDeleted uint8
+	if isDeleted, err = dec.Uint8(); err != nil {
+		return err
+	}
+	r.IsDeleted = isDeleted == 1
+	if r.Version, err = dec.Uint64(); err != nil {
+		return err
+	}
+	var createdAt, updatedAt int64
+	if createdAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	if updatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	if createdAt > 0 {
+		t := time.Unix(0, createdAt)
+		r.CreatedAt = &t
+	}
+	if updatedAt > 0 {
+		t := time.Unix(0, updatedAt)
+		r.UpdatedAt = &t
+	}
+	return nil
+}
+
+type ReactionSet []Reaction
+
+func (r ReactionSet) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(r)))
+	for _, v := range r {
+		data, err := v.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		enc.WriteBinary(data)
+	}
+	return enc.Bytes(), nil
+}
+
+func (r *ReactionSet) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(count); i++ {
+		data, err := dec.Binary()
+		if err != nil {
+			return err
+		}
+		var reaction Reaction
+		if err := reaction.Unmarshal(data); err != nil {
+			return err
+		}
+		*r = append(*r, reaction)
+	}
+	return nil
+}
+
+var EmptyReactionSummary = ReactionSummary{}
+
+// ReactionSummaryUidCount 回应汇总里每个表情保留的最近回应用户数
+const ReactionSummaryUidCount = 10
+
+// ReactionSummary 消息回应的汇总，随回应变更一起维护，查询时不需要遍历每个用户的回应
+type ReactionSummary struct {
+	ChannelId   string
+	ChannelType uint8
+	MessageId   uint64
+	MessageSeq  uint64
+	Emojis      []ReactionEmojiSummary // 按表情首次回应的时间排序
+}
+
+// ReactionEmojiSummary 消息某个表情的回应汇总
+type ReactionEmojiSummary struct {
+	Emoji     string
+	Count     uint32
+	Uids      []string // 最近回应的用户，最新的在前面，最多ReactionSummaryUidCount个
+	CreatedAt int64    // 首次回应的时间
+}
+
+func (r *ReactionSummary) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(r.ChannelId)
+	enc.WriteUint8(r.ChannelType)
+	enc.WriteUint64(r.MessageId)
+	enc.WriteUint64(r.MessageSeq)
+	enc.WriteUint32(uint32(len(r.Emojis)))
+	for _, emoji := range r.Emojis {
+		enc.WriteString(emoji.Emoji)
+		enc.WriteUint32(emoji.Count)
+		enc.WriteInt64(emoji.CreatedAt)
+		enc.WriteUint32(uint32(len(emoji.Uids)))
+		for _, uid := range emoji.Uids {
+			enc.WriteString(uid)
+		}
+	}
+	return enc.Bytes(), nil
+}
+
+func (r *ReactionSummary) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if r.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if r.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if r.MessageId, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if r.MessageSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	var count uint32
+	if count, err = dec.Uint32(); err != nil {
+		return err
+	}
+	r.Emojis = make([]ReactionEmojiSummary, 0, count)
+	for i := 0; i < int(count); i++ {
+		var emoji ReactionEmojiSummary
+		if emoji.Emoji, err = dec.String(); err != nil {
+			return err
+		}
+		if emoji.Count, err = dec.Uint32(); err != nil {
+			return err
+		}
+		if emoji.CreatedAt, err = dec.Int64(); err != nil {
+			return err
+		}
+		var uidCount uint32
+		if uidCount, err = dec.Uint32(); err != nil {
+			return err
+		}
+		emoji.Uids = make([]string, 0, uidCount)
+		for j := 0; j < int(uidCount); j++ {
+			uid, err := dec.String()
+			if err != nil {
+				return err
+			}
+			emoji.Uids = append(emoji.Uids, uid)
+		}
+		r.Emojis = append(r.Emojis, emoji)
+	}
+	return nil
+}
+
+// MirrorChannel 等待镜像到备集群的频道
+type MirrorChannel struct {
+	ChannelId   string
+	ChannelType uint8
+	MessageSeq  uint64 // 需要镜像到的消息序号
+}
+
+func (m *MirrorChannel) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(m.ChannelId)
+	enc.WriteUint8(m.ChannelType)
+	enc.WriteUint64(m.MessageSeq)
+	return enc.Bytes(), nil
+}
+
+func (m *MirrorChannel) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if m.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if m.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if m.MessageSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ChannelSegment 频道已下沉到对象存储的消息段
+type ChannelSegment struct {
+	ChannelId   string
+	ChannelType uint8
+	StartSeq    uint64 // 段内第一条消息的序号
+	EndSeq      uint64 // 段内最后一条消息的序号（包含）
+	Count       uint32 // 段内消息数量（序号可能不连续）
+	Size        uint64 // 压缩后的大小
+	Checksum    uint32 // 压缩后数据的crc32
+	ObjectKey   string // 对象存储中的key，为空表示此段没有消息
+	CreatedAt   int64  // 下沉时间（unix秒）
+	KeyVersion  uint32 // 加密段使用的数据密钥版本，0表示没有加密
+}
+
+func (c *ChannelSegment) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(c.ChannelId)
+	enc.WriteUint8(c.ChannelType)
+	enc.WriteUint64(c.StartSeq)
+	enc.WriteUint64(c.EndSeq)
+	enc.WriteUint32(c.Count)
+	enc.WriteUint64(c.Size)
+	enc.WriteUint32(c.Checksum)
+	enc.WriteString(c.ObjectKey)
+	enc.WriteInt64(c.CreatedAt)
+	enc.WriteUint32(c.KeyVersion)
+	return enc.Bytes(), nil
+}
+
+func (c *ChannelSegment) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if c.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if c.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if c.StartSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if c.EndSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if c.Count, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if c.Size, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if c.Checksum, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if c.ObjectKey, err = dec.String(); err != nil {
+		return err
+	}
+	if c.CreatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	if dec.Len() > 0 {
+		if c.KeyVersion, err = dec.Uint32(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DataKey 加密消息内容的数据密钥（被主密钥包装后保存）
+type DataKey struct {
+	Version     uint32 // 密钥版本，每次轮换+1
+	MasterKeyId string // 包装使用的主密钥id
+	Wrapped     []byte // 被主密钥包装后的数据密钥
+	CreatedAt   int64  // 创建时间（unix秒）
+}
+
+func (d *DataKey) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(d.Version)
+	enc.WriteString(d.MasterKeyId)
+	enc.WriteBinary(d.Wrapped)
+	enc.WriteInt64(d.CreatedAt)
+	return enc.Bytes(), nil
+}
+
+func (d *DataKey) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if d.Version, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if d.MasterKeyId, err = dec.String(); err != nil {
+		return err
+	}
+	if d.Wrapped, err = dec.Binary(); err != nil {
+		return err
+	}
+	if d.CreatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// EncryptionStatus 静态加密状态
+type EncryptionStatus struct {
+	On          bool                    `json:"on"`
+	MasterKeyId string                  `json:"master_key_id"` // 当前主密钥id
+	Shards      []ShardEncryptionStatus `json:"shards"`
+}
+
+type ShardEncryptionStatus struct {
+	ShardId      uint32 `json:"shard_id"`
+	KeyVersion   uint32 `json:"key_version"`  // 当前数据密钥版本
+	KeyCount     int    `json:"key_count"`    // 剩余的数据密钥数量（旧版本在重新加密完成后删除）
+	Reencrypting bool   `json:"reencrypting"` // 是否在后台重新加密
+	Reencrypted  uint64 `json:"reencrypted"`  // 本轮已重新加密的消息数量
+}
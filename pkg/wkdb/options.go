@@ -1,25 +1,62 @@
 package wkdb
 
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/kms"
+	"github.com/WuKongIM/WuKongIM/pkg/objstore"
+)
+
 type Options struct {
 	NodeId            uint64
 	DataDir           string
 	ConversationLimit int // 最近会话查询数量限制
-	SlotCount         int // 槽位数量
+	// 每个用户保留最近多少个版本内的已删除会话记录，更早的记录会被清理，落后太多的客户端需要全量同步
+	ConversationTombstoneRetain uint64
+	SlotCount                   int // 槽位数量
 	// 耗时配置开启
 	EnableCost   bool
 	ShardNum     int               // 数据库分区数量，一但设置就不能修改
 	IsCmdChannel func(string) bool // 是否是cmd频道
 	MemTableSize int
+
+	// 分层存储（冷数据下沉到对象存储）
+	ColdStore          objstore.Store // 冷数据对象存储，为nil表示不开启
+	TieredSegmentSize  int            // 每个段包含的消息序号数量
+	TieredKeepMessages uint64         // 每个频道最新的多少条消息始终保留在本地
+	TieredColdAfter    time.Duration  // 消息超过多久才下沉，0表示不限制
+	TieredCacheSize    int            // 本地缓存的段数量
+	TieredInterval     time.Duration  // 下沉检查间隔，0表示不自动下沉
+	TieredTimeout      time.Duration  // 对象存储请求超时时间
+
+	// 静态加密（消息内容加密后保存）
+	MasterKey          kms.MasterKey // 包装数据密钥的主密钥，为nil表示不加密
+	ReencryptInterval  time.Duration // 后台重新加密的间隔
+	ReencryptBatchSize int           // 每个分区每次重新加密的消息数量
+
+	// 跨集群镜像（主集群），追加消息时在同一个批次里标记频道等待镜像
+	MirrorOn bool
 }
 
 func NewOptions(opt ...Option) *Options {
 	o := &Options{
-		DataDir:           "./data",
-		ConversationLimit: 10000,
-		SlotCount:         128,
-		EnableCost:        true,
-		ShardNum:          8,
-		MemTableSize:      16 * 1024 * 1024,
+		DataDir:                     "./data",
+		ConversationLimit:           10000,
+		ConversationTombstoneRetain: 10000,
+		SlotCount:                   128,
+		EnableCost:                  true,
+		ShardNum:                    8,
+		MemTableSize:                16 * 1024 * 1024,
+
+		TieredSegmentSize:  10000,
+		TieredKeepMessages: 100000,
+		TieredColdAfter:    time.Hour * 24 * 30,
+		TieredCacheSize:    32,
+		TieredInterval:     time.Minute * 10,
+		TieredTimeout:      time.Second * 30,
+
+		ReencryptInterval:  time.Second * 10,
+		ReencryptBatchSize: 1000,
 	}
 	for _, f := range opt {
 		f(o)
@@ -53,6 +90,12 @@ func WithConversationLimit(limit int) Option {
 	}
 }
 
+func WithConversationTombstoneRetain(retain uint64) Option {
+	return func(o *Options) {
+		o.ConversationTombstoneRetain = retain
+	}
+}
+
 func WithEnableCost() Option {
 	return func(o *Options) {
 		o.EnableCost = true
@@ -76,3 +119,72 @@ func WithMemTableSize(size int) Option {
 		o.MemTableSize = size
 	}
 }
+
+// WithColdStore 开启分层存储，较旧的消息会下沉到对象存储
+func WithColdStore(store objstore.Store) Option {
+	return func(o *Options) {
+		o.ColdStore = store
+	}
+}
+
+func WithTieredSegmentSize(size int) Option {
+	return func(o *Options) {
+		o.TieredSegmentSize = size
+	}
+}
+
+func WithTieredKeepMessages(keep uint64) Option {
+	return func(o *Options) {
+		o.TieredKeepMessages = keep
+	}
+}
+
+func WithTieredColdAfter(d time.Duration) Option {
+	return func(o *Options) {
+		o.TieredColdAfter = d
+	}
+}
+
+func WithTieredCacheSize(size int) Option {
+	return func(o *Options) {
+		o.TieredCacheSize = size
+	}
+}
+
+func WithTieredInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.TieredInterval = interval
+	}
+}
+
+func WithTieredTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.TieredTimeout = timeout
+	}
+}
+
+// WithMasterKey 开启消息内容的静态加密
+func WithMasterKey(masterKey kms.MasterKey) Option {
+	return func(o *Options) {
+		o.MasterKey = masterKey
+	}
+}
+
+func WithReencryptInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.ReencryptInterval = interval
+	}
+}
+
+func WithReencryptBatchSize(size int) Option {
+	return func(o *Options) {
+		o.ReencryptBatchSize = size
+	}
+}
+
+// WithMirrorOn 追加消息时标记频道等待镜像到备集群
+func WithMirrorOn() Option {
+	return func(o *Options) {
+		o.MirrorOn = true
+	}
+}
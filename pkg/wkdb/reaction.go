@@ -0,0 +1,372 @@
+package wkdb
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+	"go.uber.org/zap"
+)
+
+func (wk *wukongDB) AddOrUpdateReactions(channelId string, channelType uint8, reactions []Reaction) error {
+	if len(reactions) == 0 {
+		return nil
+	}
+
+	wk.dblock.reactionLock.lock(channelId, channelType)
+	defer wk.dblock.reactionLock.unlock(channelId, channelType)
+
+	if wk.opts.EnableCost {
+		start := time.Now()
+		defer func() {
+			end := time.Since(start)
+			if end > time.Millisecond*500 {
+				wk.Info("AddOrUpdateReactions cost too long", zap.Duration("cost", end), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Int("reactions", len(reactions)))
+			}
+		}()
+	}
+
+	db := wk.channelDb(channelId, channelType)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	version, err := wk.GetReactionVersion(channelId, channelType)
+	if err != nil {
+		return err
+	}
+
+	written := make(map[string]Reaction)             // 同一批次里可能多次修改同一个回应
+	summaries := make(map[uint64]*ReactionSummary)   // 本批次修改过的消息回应汇总
+	rebuilds := make(map[uint64]map[string]struct{}) // 需要从用户回应里重建最近回应用户的表情
+	for _, reaction := range reactions {
+		reactionKey := key.NewReactionKey(channelId, channelType, reaction.MessageSeq, reaction.Uid, reaction.Emoji)
+
+		oldReaction, ok := written[string(reactionKey)]
+		if !ok {
+			oldReaction, err = wk.getReactionByKey(db, reactionKey)
+			if err != nil && err != ErrNotFound {
+				return err
+			}
+		}
+		exist := oldReaction.Version > 0
+		if exist {
+			// 删除旧的版本索引
+			if err = batch.Delete(key.NewReactionVersionIndexKey(channelId, channelType, oldReaction.Version), wk.noSync); err != nil {
+				return err
+			}
+			if oldReaction.CreatedAt != nil && !oldReaction.IsDeleted {
+				reaction.CreatedAt = oldReaction.CreatedAt // 更新时不更新创建时间
+			}
+		}
+		if !exist && reaction.IsDeleted { // 不存在的回应不需要取消
+			continue
+		}
+
+		version++
+		reaction.ChannelId = channelId
+		reaction.ChannelType = channelType
+		reaction.Version = version
+
+		data, err := reaction.Marshal()
+		if err != nil {
+			return err
+		}
+		if err = batch.Set(reactionKey, data, wk.noSync); err != nil {
+			return err
+		}
+		if err = batch.Set(key.NewReactionVersionIndexKey(channelId, channelType, version), reactionKey, wk.noSync); err != nil {
+			return err
+		}
+		written[string(reactionKey)] = reaction
+
+		// 更新消息的回应汇总
+		wasActive := exist && !oldReaction.IsDeleted
+		if wasActive == !reaction.IsDeleted {
+			continue
+		}
+		summary, ok := summaries[reaction.MessageSeq]
+		if !ok {
+			loaded, err := wk.getReactionSummary(db, channelId, channelType, reaction.MessageSeq)
+			if err != nil && err != ErrNotFound {
+				return err
+			}
+			loaded.ChannelId = channelId
+			loaded.ChannelType = channelType
+			loaded.MessageSeq = reaction.MessageSeq
+			summary = &loaded
+			summaries[reaction.MessageSeq] = summary
+		}
+		if reaction.MessageId != 0 {
+			summary.MessageId = reaction.MessageId
+		}
+		if reaction.IsDeleted {
+			if summary.removeReaction(reaction) {
+				if rebuilds[reaction.MessageSeq] == nil {
+					rebuilds[reaction.MessageSeq] = make(map[string]struct{})
+				}
+				rebuilds[reaction.MessageSeq][reaction.Emoji] = struct{}{}
+			}
+		} else {
+			summary.addReaction(reaction)
+		}
+	}
+
+	for messageSeq, summary := range summaries {
+		for emoji := range rebuilds[messageSeq] {
+			if err = wk.rebuildReactionSummaryUids(db, summary, emoji, written); err != nil {
+				return err
+			}
+		}
+		summaryKey := key.NewReactionSummaryKey(channelId, channelType, messageSeq)
+		if len(summary.Emojis) == 0 {
+			if err = batch.Delete(summaryKey, wk.noSync); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := summary.Marshal()
+		if err != nil {
+			return err
+		}
+		if err = batch.Set(summaryKey, data, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	versionBytes := make([]byte, 8)
+	wk.endian.PutUint64(versionBytes, version)
+	if err = batch.Set(key.NewReactionVersionKey(channelId, channelType), versionBytes, wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+// GetReactions 获取消息的所有回应（不包含已取消的）
+func (wk *wukongDB) GetReactions(channelId string, channelType uint8, messageSeq uint64) ([]Reaction, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewReactionMessageLowKey(channelId, channelType, messageSeq),
+		UpperBound: key.NewReactionMessageHighKey(channelId, channelType, messageSeq),
+	})
+	defer iter.Close()
+
+	reactions := make([]Reaction, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var reaction Reaction
+		if err := reaction.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		if reaction.IsDeleted {
+			continue
+		}
+		reactions = append(reactions, reaction)
+	}
+	return reactions, nil
+}
+
+// GetReactionSummary 获取消息的回应汇总
+func (wk *wukongDB) GetReactionSummary(channelId string, channelType uint8, messageSeq uint64) (ReactionSummary, error) {
+	return wk.getReactionSummary(wk.channelDb(channelId, channelType), channelId, channelType, messageSeq)
+}
+
+// GetUserReactions 获取用户对消息的回应（不包含已取消的）
+func (wk *wukongDB) GetUserReactions(channelId string, channelType uint8, messageSeq uint64, uid string) ([]Reaction, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewReactionUserLowKey(channelId, channelType, messageSeq, uid),
+		UpperBound: key.NewReactionUserHighKey(channelId, channelType, messageSeq, uid),
+	})
+	defer iter.Close()
+
+	reactions := make([]Reaction, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var reaction Reaction
+		if err := reaction.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		if reaction.IsDeleted || reaction.Uid != uid {
+			continue
+		}
+		reactions = append(reactions, reaction)
+	}
+	return reactions, nil
+}
+
+// GetReactionsByVersion 获取版本号大于version的回应变更（包含已取消的），按版本号升序
+func (wk *wukongDB) GetReactionsByVersion(channelId string, channelType uint8, version uint64, limit int) ([]Reaction, error) {
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewReactionVersionIndexKey(channelId, channelType, version+1),
+		UpperBound: key.NewReactionVersionIndexKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	reactions := make([]Reaction, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		reaction, err := wk.getReactionByKey(db, iter.Value())
+		if err != nil {
+			if err == ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		reactions = append(reactions, reaction)
+		if limit > 0 && len(reactions) >= limit {
+			break
+		}
+	}
+	return reactions, nil
+}
+
+// GetReactionVersion 获取频道消息回应的当前版本号
+func (wk *wukongDB) GetReactionVersion(channelId string, channelType uint8) (uint64, error) {
+	value, closer, err := wk.channelDb(channelId, channelType).Get(key.NewReactionVersionKey(channelId, channelType))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	if len(value) < 8 {
+		return 0, nil
+	}
+	return wk.endian.Uint64(value), nil
+}
+
+func (wk *wukongDB) getReactionByKey(db *pebble.DB, reactionKey []byte) (Reaction, error) {
+	value, closer, err := db.Get(reactionKey)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return EmptyReaction, ErrNotFound
+		}
+		return EmptyReaction, err
+	}
+	defer closer.Close()
+	var reaction Reaction
+	if err := reaction.Unmarshal(value); err != nil {
+		return EmptyReaction, err
+	}
+	return reaction, nil
+}
+
+func (wk *wukongDB) getReactionSummary(db *pebble.DB, channelId string, channelType uint8, messageSeq uint64) (ReactionSummary, error) {
+	value, closer, err := db.Get(key.NewReactionSummaryKey(channelId, channelType, messageSeq))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return EmptyReactionSummary, ErrNotFound
+		}
+		return EmptyReactionSummary, err
+	}
+	defer closer.Close()
+	var summary ReactionSummary
+	if err := summary.Unmarshal(value); err != nil {
+		return EmptyReactionSummary, err
+	}
+	return summary, nil
+}
+
+// rebuildReactionSummaryUids 最近回应的用户被取消后，从用户的回应里重新取最近回应的用户
+func (wk *wukongDB) rebuildReactionSummaryUids(db *pebble.DB, summary *ReactionSummary, emoji string, written map[string]Reaction) error {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewReactionMessageLowKey(summary.ChannelId, summary.ChannelType, summary.MessageSeq),
+		UpperBound: key.NewReactionMessageHighKey(summary.ChannelId, summary.ChannelType, summary.MessageSeq),
+	})
+	defer iter.Close()
+
+	active := make([]Reaction, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		if _, ok := written[string(iter.Key())]; ok { // 以本批次写入的为准
+			continue
+		}
+		var reaction Reaction
+		if err := reaction.Unmarshal(iter.Value()); err != nil {
+			return err
+		}
+		if !reaction.IsDeleted && reaction.Emoji == emoji {
+			active = append(active, reaction)
+		}
+	}
+	for _, reaction := range written {
+		if reaction.MessageSeq == summary.MessageSeq && !reaction.IsDeleted && reaction.Emoji == emoji {
+			active = append(active, reaction)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return reactionCreatedAt(active[i]) > reactionCreatedAt(active[j])
+	})
+
+	for i, emojiSummary := range summary.Emojis {
+		if emojiSummary.Emoji != emoji {
+			continue
+		}
+		uids := make([]string, 0, ReactionSummaryUidCount)
+		for _, reaction := range active {
+			if len(uids) >= ReactionSummaryUidCount {
+				break
+			}
+			uids = append(uids, reaction.Uid)
+		}
+		summary.Emojis[i].Uids = uids
+		summary.Emojis[i].Count = uint32(len(active))
+	}
+	return nil
+}
+
+// addReaction 汇总里增加一个回应
+func (r *ReactionSummary) addReaction(reaction Reaction) {
+	for i, emoji := range r.Emojis {
+		if emoji.Emoji != reaction.Emoji {
+			continue
+		}
+		uids := make([]string, 0, ReactionSummaryUidCount)
+		uids = append(uids, reaction.Uid)
+		for _, uid := range emoji.Uids {
+			if uid != reaction.Uid && len(uids) < ReactionSummaryUidCount {
+				uids = append(uids, uid)
+			}
+		}
+		r.Emojis[i].Count++
+		r.Emojis[i].Uids = uids
+		return
+	}
+	r.Emojis = append(r.Emojis, ReactionEmojiSummary{
+		Emoji:     reaction.Emoji,
+		Count:     1,
+		Uids:      []string{reaction.Uid},
+		CreatedAt: reactionCreatedAt(reaction),
+	})
+}
+
+// removeReaction 汇总里去掉一个回应，返回是否需要重建最近回应的用户
+func (r *ReactionSummary) removeReaction(reaction Reaction) bool {
+	for i, emoji := range r.Emojis {
+		if emoji.Emoji != reaction.Emoji {
+			continue
+		}
+		if emoji.Count <= 1 {
+			r.Emojis = append(r.Emojis[:i], r.Emojis[i+1:]...)
+			return false
+		}
+		r.Emojis[i].Count--
+		uids := make([]string, 0, len(emoji.Uids))
+		for _, uid := range emoji.Uids {
+			if uid != reaction.Uid {
+				uids = append(uids, uid)
+			}
+		}
+		r.Emojis[i].Uids = uids
+		return len(uids) < len(emoji.Uids) && int(r.Emojis[i].Count) > len(uids)
+	}
+	return false
+}
+
+func reactionCreatedAt(reaction Reaction) int64 {
+	if reaction.CreatedAt != nil {
+		return reaction.CreatedAt.UnixNano()
+	}
+	if reaction.UpdatedAt != nil {
+		return reaction.UpdatedAt.UnixNano()
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+package wkdb_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddOrUpdateReactions(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "g1"
+	channelType := uint8(2)
+	now := time.Now()
+	err = d.AddOrUpdateReactions(channelId, channelType, []wkdb.Reaction{
+		{MessageId: 1001, MessageSeq: 1, Uid: "u1", Emoji: "👍", CreatedAt: &now, UpdatedAt: &now},
+		{MessageId: 1001, MessageSeq: 1, Uid: "u2", Emoji: "👍", CreatedAt: &now, UpdatedAt: &now},
+		{MessageId: 1002, MessageSeq: 2, Uid: "u1", Emoji: "😄", CreatedAt: &now, UpdatedAt: &now},
+	})
+	assert.NoError(t, err)
+
+	reactions, err := d.GetReactions(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(reactions))
+
+	version, err := d.GetReactionVersion(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), version)
+
+	// 取消回应
+	err = d.AddOrUpdateReactions(channelId, channelType, []wkdb.Reaction{
+		{MessageId: 1001, MessageSeq: 1, Uid: "u2", Emoji: "👍", IsDeleted: true, UpdatedAt: &now},
+		{MessageId: 1001, MessageSeq: 1, Uid: "u3", Emoji: "👍", IsDeleted: true, UpdatedAt: &now}, // 不存在的回应忽略
+	})
+	assert.NoError(t, err)
+
+	reactions, err = d.GetReactions(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(reactions))
+	assert.Equal(t, "u1", reactions[0].Uid)
+
+	version, err = d.GetReactionVersion(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), version)
+
+	changes, err := d.GetReactionsByVersion(channelId, channelType, 2, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(changes))
+	assert.Equal(t, "😄", changes[0].Emoji)
+	assert.Equal(t, "u2", changes[1].Uid)
+	assert.True(t, changes[1].IsDeleted)
+	assert.Equal(t, uint64(4), changes[1].Version)
+}
+
+func TestReactionSummary(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "g1"
+	channelType := uint8(2)
+	start := time.Now()
+	reactions := make([]wkdb.Reaction, 0)
+	for i := 0; i < wkdb.ReactionSummaryUidCount+2; i++ {
+		createdAt := start.Add(time.Duration(i) * time.Second)
+		reactions = append(reactions, wkdb.Reaction{MessageId: 1001, MessageSeq: 1, Uid: fmt.Sprintf("u%d", i), Emoji: "👍", CreatedAt: &createdAt, UpdatedAt: &createdAt})
+	}
+	later := start.Add(time.Hour)
+	reactions = append(reactions, wkdb.Reaction{MessageId: 1001, MessageSeq: 1, Uid: "u0", Emoji: "😄", CreatedAt: &later, UpdatedAt: &later})
+	err = d.AddOrUpdateReactions(channelId, channelType, reactions)
+	assert.NoError(t, err)
+
+	summary, err := d.GetReactionSummary(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1001), summary.MessageId)
+	assert.Equal(t, 2, len(summary.Emojis))
+	assert.Equal(t, "👍", summary.Emojis[0].Emoji)
+	assert.Equal(t, uint32(wkdb.ReactionSummaryUidCount+2), summary.Emojis[0].Count)
+	assert.Equal(t, wkdb.ReactionSummaryUidCount, len(summary.Emojis[0].Uids))
+	assert.Equal(t, "u11", summary.Emojis[0].Uids[0])
+	assert.Equal(t, "😄", summary.Emojis[1].Emoji)
+	assert.Equal(t, uint32(1), summary.Emojis[1].Count)
+
+	// 取消最近回应的用户后，从用户回应里补齐最近回应的用户
+	now := time.Now()
+	err = d.AddOrUpdateReactions(channelId, channelType, []wkdb.Reaction{
+		{MessageId: 1001, MessageSeq: 1, Uid: "u11", Emoji: "👍", IsDeleted: true, UpdatedAt: &now},
+	})
+	assert.NoError(t, err)
+
+	summary, err = d.GetReactionSummary(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(wkdb.ReactionSummaryUidCount+1), summary.Emojis[0].Count)
+	assert.Equal(t, wkdb.ReactionSummaryUidCount, len(summary.Emojis[0].Uids))
+	assert.Equal(t, "u10", summary.Emojis[0].Uids[0])
+	assert.Equal(t, "u1", summary.Emojis[0].Uids[wkdb.ReactionSummaryUidCount-1])
+
+	userReactions, err := d.GetUserReactions(channelId, channelType, 1, "u0")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(userReactions))
+
+	// 取消表情的所有回应后表情从汇总里移除
+	err = d.AddOrUpdateReactions(channelId, channelType, []wkdb.Reaction{
+		{MessageId: 1001, MessageSeq: 1, Uid: "u0", Emoji: "😄", IsDeleted: true, UpdatedAt: &now},
+	})
+	assert.NoError(t, err)
+
+	summary, err = d.GetReactionSummary(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(summary.Emojis))
+
+	_, err = d.GetReactionSummary(channelId, channelType, 2)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+}
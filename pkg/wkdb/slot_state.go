@@ -0,0 +1,143 @@
+package wkdb
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/cockroachdb/pebble"
+)
+
+// slot状态校验的数据类型前缀，防止不同表的数据计算出相同的校验和
+const (
+	slotStateUser         = 'u'
+	slotStateChannel      = 'c'
+	slotStateSubscriber   = 's'
+	slotStateDenylist     = 'd'
+	slotStateAllowlist    = 'a'
+	slotStateConversation = 'v'
+)
+
+// slotStateHash 按行累加校验和，结果和遍历顺序无关（各节点的数据库分区数量可能不同）
+type slotStateHash uint32
+
+func (h *slotStateHash) add(data []byte) {
+	*h += slotStateHash(crc32.ChecksumIEEE(data))
+}
+
+// SlotStateChecksum 计算槽内已应用的用户、频道、频道成员和最近会话的校验和
+// 只包含通过槽日志复制的字段，本地统计和时间字段不参与计算；需要扫描全表，只用于一致性校验
+func (wk *wukongDB) SlotStateChecksum(slotId uint32, slotOf func(v string) uint32) (uint32, error) {
+	var h slotStateHash
+
+	// 用户
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewUserColumnKey(0, key.MinColumnKey),
+			UpperBound: key.NewUserColumnKey(math.MaxUint64, key.MaxColumnKey),
+		})
+		err := wk.iteratorUser(iter, func(u User) bool {
+			if slotOf(u.Uid) == slotId {
+				h.add(append([]byte{slotStateUser}, u.Uid...))
+			}
+			return true
+		})
+		iter.Close()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// 频道
+	var channels []ChannelInfo
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewChannelInfoColumnKey(0, key.MinColumnKey),
+			UpperBound: key.NewChannelInfoColumnKey(math.MaxUint64, key.MaxColumnKey),
+		})
+		err := wk.iterChannelInfo(iter, func(channelInfo ChannelInfo) bool {
+			if slotOf(channelInfo.ChannelId) == slotId {
+				channels = append(channels, channelInfo)
+			}
+			return true
+		})
+		iter.Close()
+		if err != nil {
+			return 0, err
+		}
+	}
+	for _, channelInfo := range channels {
+		h.add(channelInfoStateBytes(channelInfo))
+
+		// 频道成员（订阅者、黑名单、白名单）和频道在同一个槽
+		members, err := wk.GetSubscribers(channelInfo.ChannelId, channelInfo.ChannelType)
+		if err != nil {
+			return 0, err
+		}
+		addMemberStates(&h, slotStateSubscriber, channelInfo, members)
+		if members, err = wk.GetDenylist(channelInfo.ChannelId, channelInfo.ChannelType); err != nil {
+			return 0, err
+		}
+		addMemberStates(&h, slotStateDenylist, channelInfo, members)
+		if members, err = wk.GetAllowlist(channelInfo.ChannelId, channelInfo.ChannelType); err != nil {
+			return 0, err
+		}
+		addMemberStates(&h, slotStateAllowlist, channelInfo, members)
+	}
+
+	// 最近会话
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewConversationUidHashKey(0),
+			UpperBound: key.NewConversationUidHashKey(math.MaxUint64),
+		})
+		err := wk.iterateConversation(iter, func(conversation Conversation) bool {
+			if slotOf(conversation.Uid) == slotId {
+				h.add(conversationStateBytes(conversation))
+			}
+			return true
+		})
+		iter.Close()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return uint32(h), nil
+}
+
+func channelInfoStateBytes(channelInfo ChannelInfo) []byte {
+	data := make([]byte, 0, len(channelInfo.ChannelId)+16)
+	data = append(data, slotStateChannel)
+	data = append(data, channelInfo.ChannelId...)
+	data = append(data, channelInfo.ChannelType)
+	data = append(data, uint8(wkutil.BoolToInt(channelInfo.Ban)), uint8(wkutil.BoolToInt(channelInfo.Large)), uint8(wkutil.BoolToInt(channelInfo.Disband)))
+	data = binary.BigEndian.AppendUint16(data, channelInfo.ReplicaCount)
+	data = append(data, uint8(channelInfo.AckMode))
+	return data
+}
+
+func addMemberStates(h *slotStateHash, tp byte, channelInfo ChannelInfo, members []Member) {
+	for _, member := range members {
+		data := make([]byte, 0, len(channelInfo.ChannelId)+len(member.Uid)+3)
+		data = append(data, tp)
+		data = append(data, channelInfo.ChannelId...)
+		data = append(data, channelInfo.ChannelType, 0)
+		data = append(data, member.Uid...)
+		h.add(data)
+	}
+}
+
+func conversationStateBytes(conversation Conversation) []byte {
+	data := make([]byte, 0, len(conversation.Uid)+len(conversation.ChannelId)+32)
+	data = append(data, slotStateConversation)
+	data = append(data, conversation.Uid...)
+	data = append(data, 0)
+	data = append(data, conversation.ChannelId...)
+	data = append(data, conversation.ChannelType, uint8(conversation.Type))
+	data = binary.BigEndian.AppendUint32(data, conversation.UnreadCount)
+	data = binary.BigEndian.AppendUint64(data, conversation.ReadToMsgSeq)
+	data = binary.BigEndian.AppendUint64(data, conversation.Version)
+	return data
+}
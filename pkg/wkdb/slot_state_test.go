@@ -0,0 +1,88 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlotStateChecksum(t *testing.T) {
+	// 分区数量不同的两个副本写入相同的数据，校验和相同
+	d1 := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(t.TempDir()), wkdb.WithShardNum(1)))
+	d2 := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(t.TempDir()), wkdb.WithShardNum(4)))
+	for _, d := range []wkdb.DB{d1, d2} {
+		err := d.Open()
+		assert.NoError(t, err)
+		defer func(d wkdb.DB) {
+			err := d.Close()
+			assert.NoError(t, err)
+		}(d)
+	}
+
+	// u开头的uid和频道在槽1，其他在槽2
+	slotOf := func(v string) uint32 {
+		if len(v) > 0 && v[0] == 'u' {
+			return 1
+		}
+		return 2
+	}
+
+	apply := func(d wkdb.DB) {
+		err := d.AddUser(wkdb.User{Uid: "u1"})
+		assert.NoError(t, err)
+		err = d.AddUser(wkdb.User{Uid: "x1"})
+		assert.NoError(t, err)
+		_, err = d.AddChannel(wkdb.ChannelInfo{ChannelId: "ug1", ChannelType: 2, Large: true})
+		assert.NoError(t, err)
+		err = d.AddSubscribers("ug1", 2, []wkdb.Member{{Uid: "u1"}, {Uid: "x1"}})
+		assert.NoError(t, err)
+		err = d.AddOrUpdateConversations("u1", []wkdb.Conversation{{Uid: "u1", ChannelId: "ug1", ChannelType: 2, UnreadCount: 3}})
+		assert.NoError(t, err)
+	}
+	apply(d1)
+	apply(d2)
+
+	sum1, err := d1.SlotStateChecksum(1, slotOf)
+	assert.NoError(t, err)
+	sum2, err := d2.SlotStateChecksum(1, slotOf)
+	assert.NoError(t, err)
+	assert.Equal(t, sum1, sum2)
+
+	// 其他槽的数据不影响校验和
+	err = d2.AddUser(wkdb.User{Uid: "x2"})
+	assert.NoError(t, err)
+	sum2, err = d2.SlotStateChecksum(1, slotOf)
+	assert.NoError(t, err)
+	assert.Equal(t, sum1, sum2)
+
+	// 用户、订阅者、会话不一致时校验和不同
+	err = d2.AddUser(wkdb.User{Uid: "u2"})
+	assert.NoError(t, err)
+	sum2, err = d2.SlotStateChecksum(1, slotOf)
+	assert.NoError(t, err)
+	assert.NotEqual(t, sum1, sum2)
+	err = d1.AddUser(wkdb.User{Uid: "u2"})
+	assert.NoError(t, err)
+	sum1, err = d1.SlotStateChecksum(1, slotOf)
+	assert.NoError(t, err)
+	assert.Equal(t, sum1, sum2)
+
+	err = d2.RemoveSubscribers("ug1", 2, []string{"x1"})
+	assert.NoError(t, err)
+	sum2, err = d2.SlotStateChecksum(1, slotOf)
+	assert.NoError(t, err)
+	assert.NotEqual(t, sum1, sum2)
+
+	err = d1.RemoveSubscribers("ug1", 2, []string{"x1"})
+	assert.NoError(t, err)
+	sum1, err = d1.SlotStateChecksum(1, slotOf)
+	assert.NoError(t, err)
+	assert.Equal(t, sum1, sum2)
+
+	err = d1.AddOrUpdateConversations("u1", []wkdb.Conversation{{Uid: "u1", ChannelId: "ug1", ChannelType: 2, UnreadCount: 0}})
+	assert.NoError(t, err)
+	sum1, err = d1.SlotStateChecksum(1, slotOf)
+	assert.NoError(t, err)
+	assert.NotEqual(t, sum1, sum2)
+}
@@ -0,0 +1,648 @@
+package wkdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
+	"github.com/golang/snappy"
+	"go.uber.org/zap"
+)
+
+// 分层存储：频道中较旧的消息按序号切成不可变的段，压缩后上传到对象存储，然后删除本地副本。
+// 段清单（TableChannelSegment）保存在频道所在的分区里，读取消息时对于已下沉的序号透明的从对象存储获取。
+// 按发送者/clientMsgNo/时间戳的搜索索引在下沉后仍然保留在本地，通过索引找到的已下沉消息从段内读取，
+// 删除用户消息（EraseMessagesByFromUid）时会重写包含该用户消息的段。
+// 注意：段的对象key包含节点ID，频道的每个副本各自下沉并上传自己的段，对象存储中每个段会有副本数量份拷贝。
+
+var segmentMagic = []byte("WKSG")
+
+const segmentVersion uint8 = 1
+
+func (wk *wukongDB) tieredEnabled() bool {
+	return wk.opts.ColdStore != nil
+}
+
+// OffloadChannelMessages 将频道较旧的消息下沉到对象存储，返回本次下沉的段数量
+func (wk *wukongDB) OffloadChannelMessages(channelId string, channelType uint8) (int, error) {
+	if !wk.tieredEnabled() {
+		return 0, nil
+	}
+	wk.dblock.channelSegmentLock.lock(channelId, channelType)
+	defer wk.dblock.channelSegmentLock.unlock(channelId, channelType)
+
+	lastSeq, _, err := wk.GetChannelLastMessageSeq(channelId, channelType)
+	if err != nil {
+		return 0, err
+	}
+	coldEnd, err := wk.coldEndSeq(channelId, channelType)
+	if err != nil {
+		return 0, err
+	}
+
+	segmentSize := uint64(wk.opts.TieredSegmentSize)
+	count := 0
+	for {
+		startSeq := coldEnd + 1
+		endSeq := startSeq + segmentSize - 1
+		// 最新的TieredKeepMessages条消息始终保留在本地
+		if endSeq+wk.opts.TieredKeepMessages > lastSeq {
+			break
+		}
+		msgs, err := wk.loadLocalRangeMsgs(channelId, channelType, startSeq, endSeq+1, 0)
+		if err != nil {
+			return count, err
+		}
+		if wk.opts.TieredColdAfter > 0 && len(msgs) > 0 {
+			lastTime := time.Unix(int64(msgs[len(msgs)-1].Timestamp), 0)
+			if time.Since(lastTime) < wk.opts.TieredColdAfter {
+				break
+			}
+		}
+		if err = wk.offloadSegment(channelId, channelType, startSeq, endSeq, msgs); err != nil {
+			return count, err
+		}
+		coldEnd = endSeq
+		count++
+	}
+	return count, nil
+}
+
+func (wk *wukongDB) offloadSegment(channelId string, channelType uint8, startSeq, endSeq uint64, msgs []Message) error {
+	seg := ChannelSegment{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		StartSeq:    startSeq,
+		EndSeq:      endSeq,
+		Count:       uint32(len(msgs)),
+		CreatedAt:   time.Now().Unix(),
+	}
+	shardId := wk.channelDbIndex(channelId, channelType)
+	db := wk.shardDBById(shardId)
+
+	// 先写段清单，读取方会优先从对象存储读取，再删除本地数据
+	wk.rotateLock.RLock()
+	err := wk.uploadAndSaveSegment(shardId, &seg, msgs, fmt.Sprintf("messages/%d/%016x/%020d-%020d.seg", wk.opts.NodeId, key.ChannelIdToNum(channelId, channelType), startSeq, endSeq))
+	wk.rotateLock.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	batch := db.NewBatch()
+	defer batch.Close()
+	if err = batch.DeleteRange(key.NewMessagePrimaryKey(channelId, channelType, startSeq), key.NewMessagePrimaryKey(channelId, channelType, endSeq+1), wk.noSync); err != nil {
+		return err
+	}
+	// 保留messageId和搜索索引，通过段清单可以找到已下沉的消息
+	wk.shardLocks[shardId].RLock()
+	err = batch.Commit(wk.sync)
+	wk.shardLocks[shardId].RUnlock()
+	if err != nil {
+		return err
+	}
+	wk.Info("offload channel messages", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("startSeq", startSeq), zap.Uint64("endSeq", endSeq), zap.Uint32("count", seg.Count), zap.Uint64("size", seg.Size))
+	return nil
+}
+
+// uploadAndSaveSegment 编码段内的消息上传到objectKey，然后保存段清单，没有消息的段只保存段清单
+// 调用方需要持有rotateLock的读锁，保证加密使用的数据密钥版本在段清单写入前不会被淘汰
+func (wk *wukongDB) uploadAndSaveSegment(shardId uint32, seg *ChannelSegment, msgs []Message, objectKey string) error {
+	seg.ObjectKey = ""
+	seg.Size, seg.Checksum, seg.KeyVersion = 0, 0, 0
+	if len(msgs) > 0 {
+		seg.ObjectKey = objectKey
+		// 开启静态加密时段也加密后再上传
+		data, version, err := wk.encryptData(shardId, []byte(seg.ObjectKey), encodeSegment(msgs))
+		if err != nil {
+			return err
+		}
+		seg.Size = uint64(len(data))
+		seg.Checksum = crc32.ChecksumIEEE(data)
+		seg.KeyVersion = version
+
+		ctx, cancel := context.WithTimeout(wk.cancelCtx, wk.opts.TieredTimeout)
+		err = wk.opts.ColdStore.Put(ctx, seg.ObjectKey, data)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	segData, err := seg.Marshal()
+	if err != nil {
+		return err
+	}
+	return wk.shardDBById(shardId).Set(key.NewChannelSegmentKey(seg.ChannelId, seg.ChannelType, seg.StartSeq), segData, wk.sync)
+}
+
+// replaceSegment 把段的内容替换为msgs，上传到新的对象key后更新段清单，再删除旧的对象（调用方需要持有段锁）
+func (wk *wukongDB) replaceSegment(shardId uint32, seg ChannelSegment, msgs []Message) error {
+	oldSeg := seg
+	objectKey := fmt.Sprintf("messages/%d/%016x/%020d-%020d-%d.seg", wk.opts.NodeId, key.ChannelIdToNum(seg.ChannelId, seg.ChannelType), seg.StartSeq, seg.EndSeq, time.Now().UnixNano())
+	wk.rotateLock.RLock()
+	err := wk.uploadAndSaveSegment(shardId, &seg, msgs, objectKey)
+	wk.rotateLock.RUnlock()
+	if err != nil {
+		return err
+	}
+	// 段清单已经指向新的对象，旧对象可以删除
+	wk.deleteSegmentObjects([]ChannelSegment{oldSeg})
+	return nil
+}
+
+// eraseColdMessages 清空已下沉消息的内容和发送者，包含这些消息的段重写后上传到新的对象key，返回清空的消息数量
+func (wk *wukongDB) eraseColdMessages(channelId string, channelType uint8, seqs map[uint64]struct{}) (int, error) {
+	if len(seqs) == 0 {
+		return 0, nil
+	}
+	wk.dblock.channelSegmentLock.lock(channelId, channelType)
+	defer wk.dblock.channelSegmentLock.unlock(channelId, channelType)
+
+	var (
+		minSeq uint64 = math.MaxUint64
+		maxSeq uint64
+	)
+	for seq := range seqs {
+		minSeq = min(minSeq, seq)
+		maxSeq = max(maxSeq, seq)
+	}
+	shardId := wk.channelDbIndex(channelId, channelType)
+	db := wk.shardDBById(shardId)
+
+	var segs []ChannelSegment
+	err := wk.iterChannelSegments(db, key.ChannelIdToNum(channelId, channelType), minSeq, maxSeq+1, func(seg ChannelSegment) (bool, error) {
+		segs = append(segs, seg)
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, seg := range segs {
+		msgs, err := wk.loadSegmentMessages(seg)
+		if err != nil {
+			return count, err
+		}
+		newMsgs := make([]Message, 0, len(msgs))
+		erased := 0
+		for _, m := range msgs {
+			if _, ok := seqs[uint64(m.MessageSeq)]; ok {
+				m.Payload = []byte{}
+				m.FromUID = ""
+				erased++
+			}
+			newMsgs = append(newMsgs, m)
+		}
+		if erased == 0 {
+			continue
+		}
+		if err = wk.replaceSegment(shardId, seg, newMsgs); err != nil {
+			return count, err
+		}
+		count += erased
+	}
+	return count, nil
+}
+
+// GetChannelSegments 获取频道已下沉到对象存储的段
+func (wk *wukongDB) GetChannelSegments(channelId string, channelType uint8) ([]ChannelSegment, error) {
+	segs := make([]ChannelSegment, 0)
+	err := wk.iterChannelSegments(wk.channelDb(channelId, channelType), key.ChannelIdToNum(channelId, channelType), 0, math.MaxUint64, func(seg ChannelSegment) (bool, error) {
+		segs = append(segs, seg)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return segs, nil
+}
+
+// iterChannelSegments 按序遍历和[minSeq, maxSeq)有交集的段
+func (wk *wukongDB) iterChannelSegments(db *pebble.DB, channelHash uint64, minSeq, maxSeq uint64, fnc func(seg ChannelSegment) (bool, error)) error {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewChannelSegmentKeyWithHash(channelHash, 0),
+		UpperBound: key.NewChannelSegmentKeyWithHash(channelHash, maxSeq),
+	})
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		var seg ChannelSegment
+		if err := seg.Unmarshal(iter.Value()); err != nil {
+			return err
+		}
+		if seg.EndSeq < minSeq {
+			continue
+		}
+		next, err := fnc(seg)
+		if err != nil {
+			return err
+		}
+		if !next {
+			break
+		}
+	}
+	return nil
+}
+
+func (wk *wukongDB) lastChannelSegment(db *pebble.DB, channelHash uint64) (ChannelSegment, error) {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewChannelSegmentKeyWithHash(channelHash, 0),
+		UpperBound: key.NewChannelSegmentKeyWithHash(channelHash, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	var seg ChannelSegment
+	if !iter.Last() {
+		return seg, nil
+	}
+	if err := seg.Unmarshal(iter.Value()); err != nil {
+		return seg, err
+	}
+	return seg, nil
+}
+
+// coldEndSeq 频道已下沉到对象存储的最大消息序号，0表示没有下沉
+func (wk *wukongDB) coldEndSeq(channelId string, channelType uint8) (uint64, error) {
+	if !wk.tieredEnabled() {
+		return 0, nil
+	}
+	seg, err := wk.lastChannelSegment(wk.channelDb(channelId, channelType), key.ChannelIdToNum(channelId, channelType))
+	if err != nil {
+		return 0, err
+	}
+	return seg.EndSeq, nil
+}
+
+// readWithColdEnd 按已下沉的序号读取，如果读取期间有新的段下沉或被截断则重新读取
+func (wk *wukongDB) readWithColdEnd(channelId string, channelType uint8, read func(coldEnd uint64) error) error {
+	coldEnd, err := wk.coldEndSeq(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	for {
+		if err = read(coldEnd); err != nil {
+			return err
+		}
+		if !wk.tieredEnabled() {
+			return nil
+		}
+		newColdEnd, err := wk.coldEndSeq(channelId, channelType)
+		if err != nil {
+			return err
+		}
+		if newColdEnd == coldEnd {
+			return nil
+		}
+		coldEnd = newColdEnd
+	}
+}
+
+// iterColdMessages 按序遍历对象存储中序号在[minSeq, maxSeq)内的消息
+func (wk *wukongDB) iterColdMessages(channelId string, channelType uint8, minSeq, maxSeq uint64, fnc func(m Message) bool) error {
+	db := wk.channelDb(channelId, channelType)
+	return wk.iterChannelSegments(db, key.ChannelIdToNum(channelId, channelType), minSeq, maxSeq, func(seg ChannelSegment) (bool, error) {
+		msgs, err := wk.loadSegmentMessages(seg)
+		if err != nil {
+			return false, err
+		}
+		for _, m := range msgs {
+			seq := uint64(m.MessageSeq)
+			if seq < minSeq {
+				continue
+			}
+			if seq >= maxSeq {
+				return false, nil
+			}
+			if !fnc(m) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+func (wk *wukongDB) loadColdRangeMsgs(channelId string, channelType uint8, minSeq, maxSeq uint64, limit int) ([]Message, error) {
+	msgs := make([]Message, 0)
+	err := wk.iterColdMessages(channelId, channelType, minSeq, maxSeq, func(m Message) bool {
+		msgs = append(msgs, m)
+		return limit == 0 || len(msgs) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// loadSegmentMessages 获取段内的消息，优先从本地缓存获取
+func (wk *wukongDB) loadSegmentMessages(seg ChannelSegment) ([]Message, error) {
+	if seg.ObjectKey == "" {
+		return nil, nil
+	}
+	if msgs, ok := wk.segmentCache.Get(seg.ObjectKey); ok {
+		return msgs, nil
+	}
+
+	ctx, cancel := context.WithTimeout(wk.cancelCtx, wk.opts.TieredTimeout)
+	data, err := wk.opts.ColdStore.Get(ctx, seg.ObjectKey)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("load segment[%s] failed: %w", seg.ObjectKey, err)
+	}
+	if crc32.ChecksumIEEE(data) != seg.Checksum {
+		return nil, fmt.Errorf("segment[%s] checksum mismatch", seg.ObjectKey)
+	}
+	if seg.KeyVersion != 0 {
+		if data, err = wk.decryptData([]byte(seg.ObjectKey), data); err != nil {
+			return nil, fmt.Errorf("decrypt segment[%s] failed: %w", seg.ObjectKey, err)
+		}
+	}
+	msgs, err := decodeSegment(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode segment[%s] failed: %w", seg.ObjectKey, err)
+	}
+	wk.segmentCache.Add(seg.ObjectKey, msgs)
+	return msgs, nil
+}
+
+// getColdMessage 通过频道hash和序号获取已下沉的消息
+func (wk *wukongDB) getColdMessage(db *pebble.DB, channelHash uint64, seq uint64) (Message, error) {
+	var msg Message
+	err := wk.iterChannelSegments(db, channelHash, seq, seq+1, func(seg ChannelSegment) (bool, error) {
+		msgs, err := wk.loadSegmentMessages(seg)
+		if err != nil {
+			return false, err
+		}
+		for _, m := range msgs {
+			if uint64(m.MessageSeq) == seq {
+				msg = m
+				break
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return EmptyMessage, err
+	}
+	if IsEmptyMessage(msg) {
+		return EmptyMessage, ErrNotFound
+	}
+	return msg, nil
+}
+
+// truncateColdTo 截断已下沉的消息，跨越截断点的段会把保留的部分写回本地，返回被移除的段
+func (wk *wukongDB) truncateColdTo(channelId string, channelType uint8, messageSeq uint64, batch *pebble.Batch) ([]ChannelSegment, error) {
+	db := wk.channelDb(channelId, channelType)
+	var removed []ChannelSegment
+	err := wk.iterChannelSegments(db, key.ChannelIdToNum(channelId, channelType), messageSeq, math.MaxUint64, func(seg ChannelSegment) (bool, error) {
+		if seg.StartSeq < messageSeq {
+			msgs, err := wk.loadSegmentMessages(seg)
+			if err != nil {
+				return false, err
+			}
+			for _, m := range msgs {
+				if uint64(m.MessageSeq) >= messageSeq {
+					break
+				}
+				if err = wk.writeMessage(channelId, channelType, m, batch); err != nil {
+					return false, err
+				}
+			}
+		}
+		if err := batch.Delete(key.NewChannelSegmentKey(channelId, channelType, seg.StartSeq), wk.noSync); err != nil {
+			return false, err
+		}
+		removed = append(removed, seg)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// deleteSegmentObjects 删除对象存储中的段
+func (wk *wukongDB) deleteSegmentObjects(segs []ChannelSegment) {
+	for _, seg := range segs {
+		if seg.ObjectKey == "" {
+			continue
+		}
+		wk.segmentCache.Remove(seg.ObjectKey)
+		ctx, cancel := context.WithTimeout(wk.cancelCtx, wk.opts.TieredTimeout)
+		err := wk.opts.ColdStore.Delete(ctx, seg.ObjectKey)
+		cancel()
+		if err != nil {
+			// 对象删除失败只会残留无用的对象，不影响正确性
+			wk.Warn("delete segment object failed", zap.Error(err), zap.String("objectKey", seg.ObjectKey))
+		}
+	}
+}
+
+func (wk *wukongDB) tieredLoop() {
+	defer wk.tieredWg.Done()
+	tk := time.NewTicker(wk.opts.TieredInterval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-tk.C:
+			wk.offloadAll()
+		case <-wk.cancelCtx.Done():
+			return
+		}
+	}
+}
+
+// offloadAll 遍历所有频道，下沉满足条件的消息
+func (wk *wukongDB) offloadAll() {
+	minLastSeq := uint64(wk.opts.TieredSegmentSize) + wk.opts.TieredKeepMessages
+	for _, db := range wk.dbs {
+		channelHashes, err := wk.offloadCandidates(db, minLastSeq)
+		if err != nil {
+			wk.Error("get offload candidates failed", zap.Error(err))
+			continue
+		}
+		for _, channelHash := range channelHashes {
+			if wk.cancelCtx.Err() != nil {
+				return
+			}
+			channelId, channelType, err := wk.channelOfHash(db, channelHash)
+			if err != nil {
+				wk.Error("get channel of hash failed", zap.Error(err), zap.Uint64("channelHash", channelHash))
+				continue
+			}
+			if channelId == "" {
+				continue
+			}
+			if _, err = wk.OffloadChannelMessages(channelId, channelType); err != nil {
+				wk.Error("offload channel messages failed", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+			}
+		}
+	}
+}
+
+// offloadCandidates 最大消息序号达到下沉条件的频道
+func (wk *wukongDB) offloadCandidates(db *pebble.DB, minLastSeq uint64) ([]uint64, error) {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewChannelLastMessageSeqLowKey(),
+		UpperBound: key.NewChannelLastMessageSeqHighKey(),
+	})
+	defer iter.Close()
+
+	var channelHashes []uint64
+	for iter.First(); iter.Valid(); iter.Next() {
+		if len(iter.Value()) < 8 || wk.endian.Uint64(iter.Value()) < minLastSeq {
+			continue
+		}
+		channelHash, err := key.ParseChannelLastMessageSeqKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		channelHashes = append(channelHashes, channelHash)
+	}
+	return channelHashes, nil
+}
+
+// channelOfHash 通过频道本地最早的一条消息获取频道id
+func (wk *wukongDB) channelOfHash(db *pebble.DB, channelHash uint64) (string, uint8, error) {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessagePrimaryKeyWithHash(channelHash, 0),
+		UpperBound: key.NewMessagePrimaryKeyWithHash(channelHash, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	var (
+		channelId   string
+		channelType uint8
+		found       bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		_, columnName, err := key.ParseMessageColumnKey(iter.Key())
+		if err != nil {
+			return "", 0, err
+		}
+		switch columnName {
+		case key.TableMessage.Column.ChannelId:
+			channelId = string(iter.Value())
+		case key.TableMessage.Column.ChannelType:
+			if len(iter.Value()) > 0 {
+				channelType = iter.Value()[0]
+				found = true
+			}
+		}
+		if channelId != "" && found {
+			break
+		}
+	}
+	return channelId, channelType, nil
+}
+
+// encodeSegment 段格式: magic(4) + version(1) + snappy(消息数量 + 消息...)
+func encodeSegment(msgs []Message) []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(msgs)))
+	for _, m := range msgs {
+		enc.WriteUint8(wkproto.ToFixHeaderUint8(m.RecvPacket.Framer))
+		enc.WriteUint8(m.Setting.Uint8())
+		enc.WriteUint32(m.Expire)
+		enc.WriteInt64(m.MessageID)
+		enc.WriteUint32(m.MessageSeq)
+		enc.WriteString(m.ClientMsgNo)
+		enc.WriteInt32(m.Timestamp)
+		enc.WriteString(m.ChannelID)
+		enc.WriteUint8(m.ChannelType)
+		enc.WriteString(m.Topic)
+		enc.WriteString(m.FromUID)
+		enc.WriteUint32(uint32(len(m.Payload)))
+		enc.WriteBytes(m.Payload)
+		enc.WriteUint64(m.Term)
+	}
+	compressed := snappy.Encode(nil, enc.Bytes())
+
+	data := make([]byte, 0, len(segmentMagic)+1+len(compressed))
+	data = append(data, segmentMagic...)
+	data = append(data, segmentVersion)
+	data = append(data, compressed...)
+	return data
+}
+
+func decodeSegment(data []byte) ([]Message, error) {
+	if len(data) < len(segmentMagic)+1 || string(data[:len(segmentMagic)]) != string(segmentMagic) {
+		return nil, errors.New("invalid segment magic")
+	}
+	if data[len(segmentMagic)] != segmentVersion {
+		return nil, fmt.Errorf("unsupported segment version[%d]", data[len(segmentMagic)])
+	}
+	raw, err := snappy.Decode(nil, data[len(segmentMagic)+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	dec := wkproto.NewDecoder(raw)
+	count, err := dec.Uint32()
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]Message, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var (
+			m          Message
+			header     uint8
+			setting    uint8
+			payloadLen uint32
+		)
+		if header, err = dec.Uint8(); err != nil {
+			return nil, err
+		}
+		m.Framer = wkproto.FramerFromUint8(header)
+		if setting, err = dec.Uint8(); err != nil {
+			return nil, err
+		}
+		m.Setting = wkproto.Setting(setting)
+		if m.Expire, err = dec.Uint32(); err != nil {
+			return nil, err
+		}
+		if m.MessageID, err = dec.Int64(); err != nil {
+			return nil, err
+		}
+		if m.MessageSeq, err = dec.Uint32(); err != nil {
+			return nil, err
+		}
+		if m.ClientMsgNo, err = dec.String(); err != nil {
+			return nil, err
+		}
+		if m.Timestamp, err = dec.Int32(); err != nil {
+			return nil, err
+		}
+		if m.ChannelID, err = dec.String(); err != nil {
+			return nil, err
+		}
+		if m.ChannelType, err = dec.Uint8(); err != nil {
+			return nil, err
+		}
+		if m.Topic, err = dec.String(); err != nil {
+			return nil, err
+		}
+		if m.FromUID, err = dec.String(); err != nil {
+			return nil, err
+		}
+		if payloadLen, err = dec.Uint32(); err != nil {
+			return nil, err
+		}
+		payload, err := dec.Bytes(int(payloadLen))
+		if err != nil {
+			return nil, err
+		}
+		m.Payload = payload
+		if m.Term, err = dec.Uint64(); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
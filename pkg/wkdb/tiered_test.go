@@ -0,0 +1,192 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/objstore"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTieredTestDB(t testing.TB) wkdb.DB {
+	return wkdb.NewWukongDB(wkdb.NewOptions(
+		wkdb.WithDir(t.TempDir()),
+		wkdb.WithShardNum(1),
+		wkdb.WithColdStore(objstore.NewFileStore(t.TempDir())),
+		wkdb.WithTieredSegmentSize(10),
+		wkdb.WithTieredKeepMessages(20),
+		wkdb.WithTieredColdAfter(0),
+		wkdb.WithTieredInterval(0),
+	))
+}
+
+func TestOffloadChannelMessages(t *testing.T) {
+	d := newTieredTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+	num := 55
+
+	messages := make([]wkdb.Message, 0, num)
+	for i := 0; i < num; i++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(1000 + i),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				FromUID:     "u1",
+				Payload:     []byte("hello"),
+			},
+			Term: 1,
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	// 55条消息，保留最新的20条，只有1-30可以下沉
+	count, err := d.OffloadChannelMessages(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	segs, err := d.GetChannelSegments(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Len(t, segs, 3)
+	assert.Equal(t, uint64(21), segs[2].StartSeq)
+	assert.Equal(t, uint64(30), segs[2].EndSeq)
+	assert.Equal(t, uint32(10), segs[2].Count)
+
+	msgs, err := d.LoadPrevRangeMsgs(channelId, channelType, uint64(num), 0, num)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, num)
+	for i, m := range msgs {
+		assert.Equal(t, uint32(i+1), m.MessageSeq)
+		assert.Equal(t, []byte("hello"), m.Payload)
+	}
+
+	msgs, err = d.LoadNextRangeMsgs(channelId, channelType, 5, 0, 30)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 30)
+	assert.Equal(t, uint32(5), msgs[0].MessageSeq)
+	assert.Equal(t, uint32(34), msgs[29].MessageSeq)
+
+	msgs, err = d.LoadNextRangeMsgsForSize(channelId, channelType, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, num)
+
+	msg, err := d.LoadMsg(channelId, channelType, 15)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1014), msg.MessageID)
+	assert.Equal(t, uint64(1), msg.Term)
+
+	msg, err = d.GetMessage(1002)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), msg.MessageSeq)
+
+	// 截断到已下沉的消息内，保留的部分写回本地
+	err = d.TruncateLogTo(channelId, channelType, 16)
+	assert.NoError(t, err)
+
+	segs, err = d.GetChannelSegments(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Len(t, segs, 1)
+
+	msgs, err = d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 15)
+	assert.Equal(t, uint32(15), msgs[14].MessageSeq)
+}
+
+func TestEraseOffloadedMessagesByFromUid(t *testing.T) {
+	d := newTieredTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+	num := 40
+
+	messages := make([]wkdb.Message, 0, num)
+	for i := 0; i < num; i++ {
+		fromUid := "u1"
+		if i%2 == 1 {
+			fromUid = "u2"
+		}
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(1000 + i),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				FromUID:     fromUid,
+				Payload:     []byte("hello"),
+			},
+			Term: 1,
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	// 1-20下沉，21-40保留在本地
+	count, err := d.OffloadChannelMessages(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	segs, err := d.GetChannelSegments(channelId, channelType)
+	assert.NoError(t, err)
+
+	// 已下沉的消息仍然可以通过发送者找到
+	msgs, err := d.GetMessagesByFromUid("u2")
+	assert.NoError(t, err)
+	assert.Len(t, msgs, num/2)
+
+	msgs, err = d.SearchMessages(wkdb.MessageSearchReq{FromUid: "u2", Limit: num})
+	assert.NoError(t, err)
+	assert.Len(t, msgs, num/2)
+
+	erased, err := d.EraseMessagesByFromUid("u2")
+	assert.NoError(t, err)
+	assert.Equal(t, num/2, erased)
+
+	msgs, err = d.GetMessagesByFromUid("u2")
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 0)
+
+	// 段重写到新的对象
+	newSegs, err := d.GetChannelSegments(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Len(t, newSegs, len(segs))
+	for i, seg := range newSegs {
+		assert.NotEqual(t, segs[i].ObjectKey, seg.ObjectKey)
+		assert.Equal(t, segs[i].Count, seg.Count)
+	}
+
+	msgs, err = d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, num)
+	for _, m := range msgs {
+		if m.MessageSeq%2 == 0 {
+			assert.Equal(t, "", m.FromUID)
+			assert.Equal(t, 0, len(m.Payload))
+		} else {
+			assert.Equal(t, "u1", m.FromUID)
+			assert.Equal(t, []byte("hello"), m.Payload)
+		}
+	}
+
+	msgs, err = d.GetMessagesByFromUid("u1")
+	assert.NoError(t, err)
+	assert.Len(t, msgs, num/2)
+}
@@ -237,6 +237,36 @@ func (wk *wukongDB) UpdateUser(u User) error {
 	return batch.Commit(wk.sync)
 }
 
+func (wk *wukongDB) DeleteUser(uid string) error {
+	oldUser, err := wk.GetUser(uid)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	if IsEmptyUser(oldUser) {
+		return nil
+	}
+
+	db := wk.shardDB(uid)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	// 删除索引
+	err = wk.deleteUserIndex(oldUser, batch)
+	if err != nil {
+		return err
+	}
+
+	// 删除数据
+	err = batch.DeleteRange(key.NewUserColumnKey(oldUser.Id, key.MinColumnKey), key.NewUserColumnKey(oldUser.Id, key.MaxColumnKey), wk.noSync)
+	if err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
 // func (wk *wukongDB) incUserDeviceCount(uid string, count int, db *pebble.DB) error {
 
 // 	wk.dblock.userLock.Lock(uid)
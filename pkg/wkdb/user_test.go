@@ -125,3 +125,31 @@ func TestExistUser(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, exist)
 }
+
+func TestDeleteUser(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	tn := time.Now()
+	u := wkdb.User{
+		Uid:       "test",
+		CreatedAt: &tn,
+		UpdatedAt: &tn,
+	}
+
+	err = d.AddUser(u)
+	assert.NoError(t, err)
+
+	err = d.DeleteUser(u.Uid)
+	assert.NoError(t, err)
+
+	exist, err := d.ExistUser(u.Uid)
+	assert.NoError(t, err)
+	assert.False(t, exist)
+}
@@ -0,0 +1,154 @@
+package wkdb
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+// IncMessageNotifyRetryCount 增加通知队列消息的重试次数，返回增加后的次数
+func (wk *wukongDB) IncMessageNotifyRetryCount(messageIDs []int64) (map[int64]int, error) {
+	db := wk.defaultShardDB()
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	counts := make(map[int64]int, len(messageIDs))
+	for _, messageID := range messageIDs {
+		count, err := wk.getMessageNotifyRetryCount(messageID)
+		if err != nil {
+			return nil, err
+		}
+		count++
+		counts[messageID] = count
+
+		value := make([]byte, 4)
+		binary.BigEndian.PutUint32(value, uint32(count))
+		if err := batch.Set(key.NewWebhookRetryKey(uint64(messageID)), value, wk.noSync); err != nil {
+			return nil, err
+		}
+	}
+	if err := batch.Commit(wk.sync); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// GetMessageNotifyRetryCount 获取通知队列消息的重试次数
+func (wk *wukongDB) GetMessageNotifyRetryCount(messageID int64) (int, error) {
+	return wk.getMessageNotifyRetryCount(messageID)
+}
+
+// MoveMessagesOfNotifyQueueToDeadLetter 将通知队列的消息移到死信里
+func (wk *wukongDB) MoveMessagesOfNotifyQueueToDeadLetter(messageIDs []int64, deadLetter WebhookDeadLetter) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	for _, messageID := range messageIDs {
+		if err := wk.removeMessageOfNotifyQueue(messageID, batch); err != nil {
+			return err
+		}
+	}
+	if err := wk.writeWebhookDeadLetter(deadLetter, batch); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+// AddWebhookDeadLetter 添加死信
+func (wk *wukongDB) AddWebhookDeadLetter(deadLetter WebhookDeadLetter) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	if err := wk.writeWebhookDeadLetter(deadLetter, batch); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+// GetWebhookDeadLetters 获取死信列表 startId表示从此id之后开始获取（不包含startId）
+func (wk *wukongDB) GetWebhookDeadLetters(startId uint64, limit int) ([]WebhookDeadLetter, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewWebhookDeadLetterKey(startId + 1),
+		UpperBound: key.NewWebhookDeadLetterKey(math.MaxUint64),
+	})
+	defer iter.Close()
+
+	deadLetters := make([]WebhookDeadLetter, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		if limit > 0 && len(deadLetters) >= limit {
+			break
+		}
+		var deadLetter WebhookDeadLetter
+		if err := deadLetter.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	return deadLetters, nil
+}
+
+// GetWebhookDeadLetter 获取指定id的死信
+func (wk *wukongDB) GetWebhookDeadLetter(id uint64) (WebhookDeadLetter, error) {
+	value, closer, err := wk.defaultShardDB().Get(key.NewWebhookDeadLetterKey(id))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return WebhookDeadLetter{}, ErrNotFound
+		}
+		return WebhookDeadLetter{}, err
+	}
+	defer closer.Close()
+
+	var deadLetter WebhookDeadLetter
+	if err := deadLetter.Unmarshal(value); err != nil {
+		return WebhookDeadLetter{}, err
+	}
+	deadLetter.Data = append([]byte(nil), deadLetter.Data...)
+	return deadLetter, nil
+}
+
+// RemoveWebhookDeadLetters 移除死信
+func (wk *wukongDB) RemoveWebhookDeadLetters(ids []uint64) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	for _, id := range ids {
+		if err := batch.Delete(key.NewWebhookDeadLetterKey(id), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+// RemoveAllWebhookDeadLetters 清空死信
+func (wk *wukongDB) RemoveAllWebhookDeadLetters() error {
+	return wk.defaultShardDB().DeleteRange(key.NewWebhookDeadLetterKey(0), key.NewWebhookDeadLetterKey(math.MaxUint64), wk.sync)
+}
+
+func (wk *wukongDB) getMessageNotifyRetryCount(messageID int64) (int, error) {
+	value, closer, err := wk.defaultShardDB().Get(key.NewWebhookRetryKey(uint64(messageID)))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	if len(value) < 4 {
+		return 0, nil
+	}
+	return int(binary.BigEndian.Uint32(value)), nil
+}
+
+func (wk *wukongDB) removeMessageOfNotifyQueue(messageID int64, w *pebble.Batch) error {
+	if err := w.Delete(key.NewMessageNotifyQueueKey(uint64(messageID)), wk.noSync); err != nil {
+		return err
+	}
+	return w.Delete(key.NewWebhookRetryKey(uint64(messageID)), wk.noSync)
+}
+
+func (wk *wukongDB) writeWebhookDeadLetter(deadLetter WebhookDeadLetter, w *pebble.Batch) error {
+	data, err := deadLetter.Marshal()
+	if err != nil {
+		return err
+	}
+	return w.Set(key.NewWebhookDeadLetterKey(deadLetter.Id), data, wk.noSync)
+}
@@ -0,0 +1,136 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncMessageNotifyRetryCount(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	counts, err := d.IncMessageNotifyRetryCount([]int64{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, counts[1])
+	assert.Equal(t, 1, counts[2])
+
+	counts, err = d.IncMessageNotifyRetryCount([]int64{1})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, counts[1])
+
+	// 移除通知队列的消息时，重试次数也会被移除
+	err = d.RemoveMessagesOfNotifyQueue([]int64{1})
+	assert.NoError(t, err)
+
+	count, err := d.GetMessageNotifyRetryCount(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = d.GetMessageNotifyRetryCount(2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestMoveMessagesOfNotifyQueueToDeadLetter(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.AppendMessageOfNotifyQueue([]wkdb.Message{
+		{RecvPacket: wkproto.RecvPacket{MessageID: 1, ChannelID: "channel1", ChannelType: 1}},
+		{RecvPacket: wkproto.RecvPacket{MessageID: 2, ChannelID: "channel2", ChannelType: 2}},
+	})
+	assert.NoError(t, err)
+
+	_, err = d.IncMessageNotifyRetryCount([]int64{1})
+	assert.NoError(t, err)
+
+	deadLetter := wkdb.WebhookDeadLetter{
+		Id:         100,
+		Event:      "msg.notify",
+		MessageIds: []int64{1},
+		RetryCount: 5,
+		Error:      "timeout",
+		CreatedAt:  time.Now(),
+		Data:       []byte(`[{"message_id":1}]`),
+	}
+	err = d.MoveMessagesOfNotifyQueueToDeadLetter([]int64{1}, deadLetter)
+	assert.NoError(t, err)
+
+	msgs, err := d.GetMessagesOfNotifyQueue(10)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 1)
+	assert.Equal(t, int64(2), msgs[0].MessageID)
+
+	count, err := d.GetMessageNotifyRetryCount(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	d2, err := d.GetWebhookDeadLetter(100)
+	assert.NoError(t, err)
+	assert.Equal(t, deadLetter.Event, d2.Event)
+	assert.Equal(t, deadLetter.MessageIds, d2.MessageIds)
+	assert.Equal(t, deadLetter.RetryCount, d2.RetryCount)
+	assert.Equal(t, deadLetter.Error, d2.Error)
+	assert.Equal(t, deadLetter.CreatedAt.UnixNano(), d2.CreatedAt.UnixNano())
+	assert.Equal(t, deadLetter.Data, d2.Data)
+}
+
+func TestGetWebhookDeadLetters(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	for i := uint64(1); i <= 5; i++ {
+		err = d.AddWebhookDeadLetter(wkdb.WebhookDeadLetter{
+			Id:        i,
+			Event:     "msg.notify",
+			CreatedAt: time.Now(),
+			Data:      []byte("[]"),
+		})
+		assert.NoError(t, err)
+	}
+
+	deadLetters, err := d.GetWebhookDeadLetters(0, 3)
+	assert.NoError(t, err)
+	assert.Len(t, deadLetters, 3)
+	assert.Equal(t, uint64(1), deadLetters[0].Id)
+
+	deadLetters, err = d.GetWebhookDeadLetters(3, 10)
+	assert.NoError(t, err)
+	assert.Len(t, deadLetters, 2)
+	assert.Equal(t, uint64(4), deadLetters[0].Id)
+
+	err = d.RemoveWebhookDeadLetters([]uint64{4})
+	assert.NoError(t, err)
+
+	_, err = d.GetWebhookDeadLetter(4)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	err = d.RemoveAllWebhookDeadLetters()
+	assert.NoError(t, err)
+
+	deadLetters, err = d.GetWebhookDeadLetters(0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, deadLetters, 0)
+}
@@ -7,6 +7,7 @@ import (
 	"hash"
 	"hash/fnv"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
@@ -14,6 +15,7 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	"github.com/bwmarrin/snowflake"
 	"github.com/cockroachdb/pebble"
+	lru "github.com/hashicorp/golang-lru/v2"
 	"go.uber.org/zap"
 )
 
@@ -32,6 +34,14 @@ type wukongDB struct {
 	cancelCtx    context.Context
 	cancelFunc   context.CancelFunc
 
+	segmentCache *lru.Cache[string, []Message] // 已下沉的段缓存
+	tieredWg     sync.WaitGroup
+
+	keyring     *dataKeyring   // 数据密钥，为nil表示不加密
+	shardLocks  []sync.RWMutex // 写消息加读锁，后台重新加密和修改镜像标记加写锁
+	rotateLock  sync.RWMutex   // 轮换数据密钥加写锁，上传加密的段加读锁
+	reencryptWg sync.WaitGroup
+
 	h hash.Hash32
 }
 
@@ -41,6 +51,13 @@ func NewWukongDB(opts *Options) DB {
 		panic(err)
 	}
 	cancelCtx, cancelFunc := context.WithCancel(context.Background())
+	var segmentCache *lru.Cache[string, []Message]
+	if opts.ColdStore != nil {
+		segmentCache, err = lru.New[string, []Message](max(opts.TieredCacheSize, 1))
+		if err != nil {
+			panic(err)
+		}
+	}
 	return &wukongDB{
 		opts:         opts,
 		shardNum:     uint32(opts.ShardNum),
@@ -55,8 +72,10 @@ func NewWukongDB(opts *Options) DB {
 		noSync: &pebble.WriteOptions{
 			Sync: false,
 		},
-		Log:    wklog.NewWKLog("wukongDB"),
-		dblock: newDBLock(),
+		Log:          wklog.NewWKLog("wukongDB"),
+		dblock:       newDBLock(),
+		segmentCache: segmentCache,
+		shardLocks:   make([]sync.RWMutex, opts.ShardNum),
 	}
 }
 
@@ -106,13 +125,30 @@ func (wk *wukongDB) Open() error {
 		wk.dbs = append(wk.dbs, db)
 	}
 
+	if wk.opts.MasterKey != nil {
+		if err := wk.openKeyring(); err != nil {
+			return err
+		}
+		if wk.opts.ReencryptInterval > 0 {
+			wk.reencryptWg.Add(1)
+			go wk.reencryptLoop()
+		}
+	}
+
 	go wk.collectMetricsLoop()
 
+	if wk.tieredEnabled() && wk.opts.TieredInterval > 0 {
+		wk.tieredWg.Add(1)
+		go wk.tieredLoop()
+	}
+
 	return nil
 }
 
 func (wk *wukongDB) Close() error {
 	wk.cancelFunc()
+	wk.tieredWg.Wait()
+	wk.reencryptWg.Wait()
 	for _, db := range wk.dbs {
 		if err := db.Close(); err != nil {
 			wk.Error("close db error", zap.Error(err))
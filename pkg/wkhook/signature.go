@@ -0,0 +1,88 @@
+package wkhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webhook签名相关的请求头（grpc通过metadata传递，key为小写）
+const (
+	HeaderSignature = "X-WuKongIM-Signature" // 签名 格式：t=时间戳,v1=签名[,v1=签名]
+	HeaderEventId   = "X-WuKongIM-Event-Id"  // 事件id
+	HeaderTimestamp = "X-WuKongIM-Timestamp" // 签名时间戳（秒）
+)
+
+var (
+	ErrInvalidSignatureHeader = errors.New("wkhook: invalid signature header")
+	ErrSignatureExpired       = errors.New("wkhook: signature expired")
+	ErrSignatureMismatch      = errors.New("wkhook: signature mismatch")
+)
+
+// Sign 使用密钥对事件签名，签名内容为 时间戳.事件id.事件数据
+func Sign(secret string, timestamp int64, eventId string, data []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write([]byte(eventId))
+	mac.Write([]byte("."))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHeader 生成签名头，密钥轮换期间会用每个密钥各签一次，接收方匹配任意一个即可
+func SignatureHeader(secrets []string, timestamp int64, eventId string, data []byte) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("t=%d", timestamp))
+	for _, secret := range secrets {
+		b.WriteString(",v1=")
+		b.WriteString(Sign(secret, timestamp, eventId, data))
+	}
+	return b.String()
+}
+
+// Verify 接收方校验签名，secrets为接收方当前有效的密钥，tolerance为允许的时间偏差（0表示不校验时间）
+func Verify(secrets []string, header string, eventId string, data []byte, tolerance time.Duration) error {
+	var (
+		timestamp  int64
+		signatures []string
+		err        error
+	)
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			return ErrInvalidSignatureHeader
+		}
+		switch kv[0] {
+		case "t":
+			if timestamp, err = strconv.ParseInt(kv[1], 10, 64); err != nil {
+				return ErrInvalidSignatureHeader
+			}
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrInvalidSignatureHeader
+	}
+	if tolerance > 0 {
+		diff := time.Since(time.Unix(timestamp, 0))
+		if diff > tolerance || diff < -tolerance {
+			return ErrSignatureExpired
+		}
+	}
+	for _, secret := range secrets {
+		expected := Sign(secret, timestamp, eventId, data)
+		for _, signature := range signatures {
+			if hmac.Equal([]byte(expected), []byte(signature)) {
+				return nil
+			}
+		}
+	}
+	return ErrSignatureMismatch
+}
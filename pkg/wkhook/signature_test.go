@@ -0,0 +1,28 @@
+package wkhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	data := []byte(`[{"message_id":1}]`)
+	now := time.Now().Unix()
+
+	header := SignatureHeader([]string{"new", "old"}, now, "1001", data)
+
+	// 轮换期间接收方只有其中一个密钥也能校验通过
+	assert.NoError(t, Verify([]string{"old"}, header, "1001", data, time.Minute))
+	assert.NoError(t, Verify([]string{"new"}, header, "1001", data, time.Minute))
+
+	assert.Equal(t, ErrSignatureMismatch, Verify([]string{"other"}, header, "1001", data, time.Minute))
+	assert.Equal(t, ErrSignatureMismatch, Verify([]string{"new"}, header, "1002", data, time.Minute))
+	assert.Equal(t, ErrSignatureMismatch, Verify([]string{"new"}, header, "1001", []byte("[]"), time.Minute))
+
+	expired := SignatureHeader([]string{"new"}, now-3600, "1001", data)
+	assert.Equal(t, ErrSignatureExpired, Verify([]string{"new"}, expired, "1001", data, time.Minute))
+
+	assert.Equal(t, ErrInvalidSignatureHeader, Verify([]string{"new"}, "abc", "1001", data, time.Minute))
+}
@@ -124,6 +124,12 @@ func (c *Context) ForwardWithBody(url string, body []byte) {
 		return
 	}
 
+	// 带上目标节点返回的自定义头（比如返回数据的节点信息）
+	for key, values := range resp.Headers {
+		if strings.HasPrefix(key, "X-") && len(values) > 0 {
+			c.Writer.Header().Set(key, values[0])
+		}
+	}
 	c.Writer.WriteHeader(resp.StatusCode)
 	c.Writer.Header().Set("Content-Type", "application/json; charset=utf-8")
 	_, _ = c.Writer.Write([]byte(resp.Body))
@@ -34,6 +34,8 @@ type Options struct {
 	SocketSendBuffer int
 	// TCPKeepAlive sets up a duration for (SO_KEEPALIVE) socket option.
 	TCPKeepAlive time.Duration
+	// WSCompression websocket permessage-deflate compression options
+	WSCompression WSCompressionOptions
 
 	Event struct {
 		OnReadBytes  func(n int) // 读到的字节大小
@@ -49,6 +51,7 @@ func NewOptions() *Options {
 		ReadBufferSize:     1024 * 32,
 		MaxWriteBufferSize: 1024 * 1024 * 50,
 		MaxReadBufferSize:  1024 * 1024 * 50,
+		WSCompression:      NewWSCompressionOptions(),
 	}
 }
 
@@ -120,6 +123,13 @@ func WithTCPKeepAlive(v time.Duration) Option {
 	}
 }
 
+// WithWSCompression set websocket permessage-deflate compression options
+func WithWSCompression(v WSCompressionOptions) Option {
+	return func(opts *Options) {
+		opts.WSCompression = v
+	}
+}
+
 func WithOnReadBytes(f func(n int)) Option {
 	return func(opts *Options) {
 		opts.Event.OnReadBytes = f
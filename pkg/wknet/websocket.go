@@ -33,6 +33,7 @@ func CreateWSSConn(id int64, connFd NetFd, localAddr, remoteAddr net.Addr, eg *E
 type WSConn struct {
 	*DefaultConn
 	upgraded         bool
+	deflate          *wsDeflate    // permessage-deflate（没有协商时为nil）
 	tmpInboundBuffer InboundBuffer // inboundBuffer InboundBuffer
 }
 
@@ -67,6 +68,9 @@ func (w *WSConn) ReadToInboundBuffer() (int, error) {
 func (w *WSConn) WriteServerBinary(data []byte) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	if w.deflate != nil {
+		return w.deflate.writeServerBinary(w.outboundBuffer, data)
+	}
 	return wsutil.WriteServerBinary(w.outboundBuffer, data)
 }
 
@@ -133,7 +137,11 @@ func (w *WSConn) decode() ([]wsutil.Message, error) {
 		tmpReader.Reset(buff)
 		remLen := tmpReader.Len()
 		for tmpReader.Len() > 0 {
-			messages, err = wsutil.ReadClientMessage(tmpReader, messages)
+			if w.deflate != nil {
+				messages, err = w.deflate.readClientMessage(tmpReader, messages)
+			} else {
+				messages, err = wsutil.ReadClientMessage(tmpReader, messages)
+			}
 			if err != nil {
 				w.Warn("read client message error", zap.Error(err))
 				break
@@ -155,7 +163,9 @@ func (w *WSConn) upgrade() error {
 	}
 	tmpReader := bytes.NewReader(buff)
 	tmpWriter := bytes.NewBuffer(nil)
-	_, err = ws.Upgrade(&readWrite{
+	var deflate *wsDeflate
+	upgrader := newWSUpgrader(&w.eg.options.WSCompression, &deflate)
+	_, err = upgrader.Upgrade(&readWrite{
 		Reader: tmpReader,
 		Writer: tmpWriter,
 	})
@@ -196,6 +206,7 @@ func (w *WSConn) upgrade() error {
 	}
 
 	w.DiscardFromTemp(len(buff) - tmpReader.Len())
+	w.deflate = deflate
 	w.upgraded = true
 	return nil
 }
@@ -257,6 +268,7 @@ type readWrite struct {
 type WSSConn struct {
 	*TLSConn
 	upgraded bool
+	deflate  *wsDeflate // permessage-deflate（没有协商时为nil）
 
 	wsTmpInboundBuffer InboundBuffer // inboundBuffer InboundBuffer
 }
@@ -340,7 +352,9 @@ func (w *WSSConn) upgrade() error {
 
 	tmpReader := bytes.NewReader(buff)
 	tmpWriter := bytes.NewBuffer(nil)
-	_, err = ws.Upgrade(&readWrite{
+	var deflate *wsDeflate
+	upgrader := newWSUpgrader(&w.d.eg.options.WSCompression, &deflate)
+	_, err = upgrader.Upgrade(&readWrite{
 		Reader: tmpReader,
 		Writer: tmpWriter,
 	})
@@ -358,6 +372,7 @@ func (w *WSSConn) upgrade() error {
 
 	w.discardFromWSTemp(len(buff) - tmpReader.Len())
 
+	w.deflate = deflate
 	w.upgraded = true
 
 	return nil
@@ -397,6 +412,7 @@ func (w *WSSConn) unpacketWSData() error {
 
 func (w *WSSConn) Close() error {
 	w.upgraded = false
+	w.deflate = nil
 	_ = w.wsTmpInboundBuffer.Release()
 	return w.TLSConn.Close()
 }
@@ -404,6 +420,9 @@ func (w *WSSConn) Close() error {
 func (w *WSSConn) WriteServerBinary(data []byte) error {
 	w.d.mu.Lock()
 	defer w.d.mu.Unlock()
+	if w.deflate != nil {
+		return w.deflate.writeServerBinary(w.TLSConn, data)
+	}
 	return wsutil.WriteServerBinary(w.TLSConn, data)
 }
 
@@ -437,7 +456,11 @@ func (w *WSSConn) decode() ([]wsutil.Message, error) {
 		tmpReader.Reset(buff)
 		remLen := tmpReader.Len()
 		for tmpReader.Len() > 0 {
-			messages, err = wsutil.ReadClientMessage(tmpReader, messages)
+			if w.deflate != nil {
+				messages, err = w.deflate.readClientMessage(tmpReader, messages)
+			} else {
+				messages, err = wsutil.ReadClientMessage(tmpReader, messages)
+			}
 			if err != nil {
 				w.d.Warn("read client message error", zap.Error(err))
 				break
@@ -0,0 +1,275 @@
+package wknet
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gobwas/httphead"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsflate"
+	"github.com/gobwas/ws/wsutil"
+)
+
+var ErrWSMessageTooLarge = errors.New("websocket: decompressed message too large")
+
+// WSCompressionOptions websocket permessage-deflate（RFC 7692）压缩配置
+type WSCompressionOptions struct {
+	On                      bool // 是否开启压缩（客户端也需要支持permessage-deflate）
+	Level                   int  // 压缩级别 flate.HuffmanOnly(-2) ~ flate.BestCompression(9)
+	MinSize                 int  // 小于此大小的消息不压缩
+	ServerNoContextTakeover bool // 服务端不保持压缩上下文（每条消息独立压缩）
+	ClientNoContextTakeover bool // 要求客户端不保持压缩上下文
+	MaxMemory               int  // 每个连接压缩上下文的最大内存，超过时对应方向不保持上下文（服务端保持上下文需要大于flateWriterMemory，客户端需要再加32KB窗口）
+	MaxMessageSize          int  // 解压后单条消息的最大大小
+}
+
+func NewWSCompressionOptions() WSCompressionOptions {
+	return WSCompressionOptions{
+		On:             false,
+		Level:          flate.BestSpeed,
+		MinSize:        256,
+		MaxMemory:      1024 * 1024, // BestSpeed下服务端和客户端都能保持上下文
+		MaxMessageSize: 1024 * 1024 * 4,
+	}
+}
+
+// flateWriterMemory 不同压缩级别下一个flate.Writer大概占用的内存
+func flateWriterMemory(level int) int {
+	switch level {
+	case flate.HuffmanOnly:
+		return 320 * 1024
+	case flate.BestSpeed:
+		return 480 * 1024
+	default:
+		return 1024 * 1024
+	}
+}
+
+var (
+	flateWriterPools sync.Map // level -> *sync.Pool
+	flateReaderPool  = sync.Pool{
+		New: func() interface{} {
+			return flate.NewReader(nil)
+		},
+	}
+	// 压缩数据后追加的尾部，让flate reader读到一个空的最终块后返回EOF
+	deflateReadTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+)
+
+func flateWriterPool(level int) *sync.Pool {
+	if p, ok := flateWriterPools.Load(level); ok {
+		return p.(*sync.Pool)
+	}
+	p, _ := flateWriterPools.LoadOrStore(level, &sync.Pool{
+		New: func() interface{} {
+			w, _ := flate.NewWriter(nil, level)
+			return w
+		},
+	})
+	return p.(*sync.Pool)
+}
+
+// wsDeflate 一个连接协商后的permessage-deflate状态
+type wsDeflate struct {
+	opts   *WSCompressionOptions
+	params wsflate.Parameters // 协商结果
+
+	fw   *flate.Writer // 服务端保持上下文时使用的压缩器
+	wbuf bytes.Buffer
+	dict []byte // 客户端保持上下文时，已解压数据的最后一个窗口
+}
+
+// negotiateWSDeflate 协商permessage-deflate，返回nil表示不接受这个offer
+func negotiateWSDeflate(offer httphead.Option, opts *WSCompressionOptions) (httphead.Option, *wsDeflate) {
+	if !bytes.Equal(offer.Name, wsflate.ExtensionNameBytes) {
+		return httphead.Option{}, nil
+	}
+	var p wsflate.Parameters
+	if err := p.Parse(offer); err != nil {
+		return httphead.Option{}, nil
+	}
+	// compress/flate固定使用32KB的窗口，不能满足客户端更小的窗口要求
+	if p.ServerMaxWindowBits.Defined() && p.ServerMaxWindowBits.Bytes() < wsflate.MaxLZ77WindowSize {
+		return httphead.Option{}, nil
+	}
+	accept := wsflate.Parameters{
+		ServerNoContextTakeover: p.ServerNoContextTakeover || opts.ServerNoContextTakeover,
+		ClientNoContextTakeover: p.ClientNoContextTakeover || opts.ClientNoContextTakeover,
+	}
+
+	// 按内存上限决定是否保持上下文
+	mem := 0
+	if !accept.ServerNoContextTakeover {
+		if flateWriterMemory(opts.Level) > opts.MaxMemory {
+			accept.ServerNoContextTakeover = true
+		} else {
+			mem += flateWriterMemory(opts.Level)
+		}
+	}
+	if !accept.ClientNoContextTakeover && mem+wsflate.MaxLZ77WindowSize > opts.MaxMemory {
+		accept.ClientNoContextTakeover = true
+	}
+
+	d := &wsDeflate{
+		opts:   opts,
+		params: accept,
+	}
+	return accept.Option(), d
+}
+
+// compress 压缩一条消息，返回的数据在下次调用前有效
+func (d *wsDeflate) compress(p []byte) ([]byte, error) {
+	var fw *flate.Writer
+	d.wbuf.Reset()
+	if d.params.ServerNoContextTakeover {
+		pool := flateWriterPool(d.opts.Level)
+		fw = pool.Get().(*flate.Writer)
+		fw.Reset(&d.wbuf)
+		defer pool.Put(fw)
+	} else {
+		if d.fw == nil {
+			var err error
+			if d.fw, err = flate.NewWriter(&d.wbuf, d.opts.Level); err != nil {
+				return nil, err
+			}
+		}
+		fw = d.fw
+	}
+	if _, err := fw.Write(p); err != nil {
+		return nil, err
+	}
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+	out := d.wbuf.Bytes()
+	// 去掉flush产生的尾部 0x00 0x00 0xff 0xff
+	if len(out) >= 4 {
+		out = out[:len(out)-4]
+	}
+	return out, nil
+}
+
+// decompress 解压一条消息
+func (d *wsDeflate) decompress(p []byte) ([]byte, error) {
+	fr := flateReaderPool.Get().(io.ReadCloser)
+	defer flateReaderPool.Put(fr)
+
+	var dict []byte
+	if !d.params.ClientNoContextTakeover {
+		dict = d.dict
+	}
+	if err := fr.(flate.Resetter).Reset(io.MultiReader(bytes.NewReader(p), bytes.NewReader(deflateReadTail)), dict); err != nil {
+		return nil, err
+	}
+	var src io.Reader = fr
+	if d.opts.MaxMessageSize > 0 {
+		src = io.LimitReader(fr, int64(d.opts.MaxMessageSize)+1)
+	}
+	var out bytes.Buffer
+	if _, err := out.ReadFrom(src); err != nil {
+		return nil, err
+	}
+	if d.opts.MaxMessageSize > 0 && out.Len() > d.opts.MaxMessageSize {
+		return nil, ErrWSMessageTooLarge
+	}
+	data := out.Bytes()
+	if !d.params.ClientNoContextTakeover {
+		d.dict = appendWindow(d.dict, data)
+	}
+	return data, nil
+}
+
+// appendWindow 追加数据并只保留最后一个窗口大小的数据
+func appendWindow(window, data []byte) []byte {
+	if len(data) >= wsflate.MaxLZ77WindowSize {
+		window = append(window[:0], data[len(data)-wsflate.MaxLZ77WindowSize:]...)
+		return window
+	}
+	if over := len(window) + len(data) - wsflate.MaxLZ77WindowSize; over > 0 {
+		window = append(window[:0], window[over:]...)
+	}
+	return append(window, data...)
+}
+
+// writeServerBinary 写二进制消息，大于MinSize的消息压缩后发送
+func (d *wsDeflate) writeServerBinary(w io.Writer, data []byte) error {
+	if len(data) < d.opts.MinSize {
+		return wsutil.WriteServerBinary(w, data)
+	}
+	payload, err := d.compress(data)
+	if err != nil {
+		return err
+	}
+	frame := ws.NewBinaryFrame(payload)
+	frame.Header, err = wsflate.SetBit(frame.Header)
+	if err != nil {
+		return err
+	}
+	return ws.WriteFrame(w, frame)
+}
+
+// readClientMessage 读取客户端的一条消息，压缩的消息会被解压
+func (d *wsDeflate) readClientMessage(r io.Reader, m []wsutil.Message) ([]wsutil.Message, error) {
+	var state wsflate.MessageState
+	rd := wsutil.Reader{
+		Source:     r,
+		State:      ws.StateServerSide | ws.StateExtended,
+		Extensions: []wsutil.RecvExtension{&state},
+		OnIntermediate: func(hdr ws.Header, src io.Reader) error {
+			bts, err := io.ReadAll(src)
+			if err != nil {
+				return err
+			}
+			m = append(m, wsutil.Message{OpCode: hdr.OpCode, Payload: bts})
+			return nil
+		},
+	}
+	h, err := rd.NextFrame()
+	if err != nil {
+		return m, err
+	}
+	var p []byte
+	if h.Fin {
+		p = make([]byte, h.Length)
+		_, err = io.ReadFull(&rd, p)
+	} else {
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(&rd)
+		p = buf.Bytes()
+	}
+	if err != nil {
+		return m, err
+	}
+	if state.IsCompressed() {
+		if p, err = d.decompress(p); err != nil {
+			return m, err
+		}
+	}
+	if h.OpCode == ws.OpText && !utf8.Valid(p) {
+		return m, wsutil.ErrInvalidUTF8
+	}
+	return append(m, wsutil.Message{OpCode: h.OpCode, Payload: p}), nil
+}
+
+// newWSUpgrader 创建ws升级器，开启压缩时协商permessage-deflate，协商成功后deflate不为nil
+func newWSUpgrader(opts *WSCompressionOptions, deflate **wsDeflate) ws.Upgrader {
+	u := ws.Upgrader{}
+	if !opts.On {
+		return u
+	}
+	u.Negotiate = func(opt httphead.Option) (httphead.Option, error) {
+		if *deflate != nil { // 客户端按优先级给出多个offer，只接受第一个
+			return httphead.Option{}, nil
+		}
+		accept, d := negotiateWSDeflate(opt, opts)
+		if d != nil {
+			*deflate = d
+		}
+		return accept, nil
+	}
+	return u
+}
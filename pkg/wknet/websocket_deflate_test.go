@@ -0,0 +1,182 @@
+package wknet
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gobwas/httphead"
+	"github.com/gobwas/ws/wsflate"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebsocketCompression(t *testing.T) {
+	opts := NewWSCompressionOptions()
+	opts.On = true
+	opts.MinSize = 0
+	e := NewEngine(WithWSAddr("ws://0.0.0.0:0"), WithWSCompression(opts))
+	err := e.Start()
+	assert.NoError(t, err)
+	defer e.Stop()
+
+	payload := []byte(strings.Repeat(`{"uid":"test","content":"hello"}`, 100))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	e.OnData(func(conn Conn) error {
+		data, err := conn.Peek(-1)
+		assert.NoError(t, err)
+		if len(data) < len(payload) {
+			return nil
+		}
+		assert.Equal(t, payload, data)
+		_, _ = conn.Discard(len(data))
+
+		// 回写给客户端
+		err = conn.(IWSConn).WriteServerBinary(data)
+		assert.NoError(t, err)
+		return conn.WakeWrite()
+	})
+
+	u := url.URL{Scheme: "ws", Host: e.WSRealListenAddr().String(), Path: "/"}
+	dialer := websocket.Dialer{EnableCompression: true}
+	c, resp, err := dialer.Dial(u.String(), nil)
+	assert.NoError(t, err)
+	defer c.Close()
+	assert.Contains(t, resp.Header.Get("Sec-WebSocket-Extensions"), wsflate.ExtensionName)
+
+	go func() {
+		_, data, err := c.ReadMessage()
+		assert.NoError(t, err)
+		assert.Equal(t, payload, data)
+		wg.Done()
+	}()
+
+	err = c.WriteMessage(websocket.BinaryMessage, payload)
+	assert.NoError(t, err)
+
+	wg.Wait()
+}
+
+func TestWSDeflateContextTakeover(t *testing.T) {
+	opts := NewWSCompressionOptions()
+	opts.MaxMemory = 1024 * 1024 * 2
+
+	_, server := negotiateWSDeflate(deflateOffer(""), &opts)
+	assert.NotNil(t, server)
+	assert.False(t, server.params.ServerNoContextTakeover)
+	assert.False(t, server.params.ClientNoContextTakeover)
+
+	// 接收端使用相同的上下文设置来解压服务端压缩的数据
+	receiver := &wsDeflate{opts: &opts, params: server.params}
+	sizes := make([]int, 0)
+	for i := 0; i < 5; i++ {
+		msg := []byte(strings.Repeat(fmt.Sprintf(`{"seq":%d,"content":"hello world"}`, i%2), 50))
+		compressed, err := server.compress(msg)
+		assert.NoError(t, err)
+		sizes = append(sizes, len(compressed))
+		data, err := receiver.decompress(append([]byte(nil), compressed...))
+		assert.NoError(t, err)
+		assert.Equal(t, msg, data)
+	}
+	// 保持上下文时，重复的消息压缩后更小
+	assert.Less(t, sizes[2], sizes[0])
+}
+
+func TestNegotiateWSDeflate(t *testing.T) {
+	opts := NewWSCompressionOptions()
+
+	// 默认内存上限两个方向都保持上下文
+	_, d := negotiateWSDeflate(deflateOffer(""), &opts)
+	assert.NotNil(t, d)
+	assert.False(t, d.params.ServerNoContextTakeover)
+	assert.False(t, d.params.ClientNoContextTakeover)
+
+	// 内存上限只够客户端上下文
+	clientOnly := opts
+	clientOnly.MaxMemory = 1024 * 256
+	_, d = negotiateWSDeflate(deflateOffer(""), &clientOnly)
+	assert.NotNil(t, d)
+	assert.True(t, d.params.ServerNoContextTakeover)
+	assert.False(t, d.params.ClientNoContextTakeover)
+
+	// 内存上限太小，两个方向都不保持上下文
+	small := opts
+	small.MaxMemory = 1024
+	_, d = negotiateWSDeflate(deflateOffer(""), &small)
+	assert.NotNil(t, d)
+	assert.True(t, d.params.ServerNoContextTakeover)
+	assert.True(t, d.params.ClientNoContextTakeover)
+
+	// 不支持更小的服务端窗口
+	_, d = negotiateWSDeflate(deflateOffer("server_max_window_bits=10"), &opts)
+	assert.Nil(t, d)
+
+	// 超过最大消息大小
+	opts.MaxMessageSize = 10
+	_, d = negotiateWSDeflate(deflateOffer("client_no_context_takeover"), &opts)
+	assert.NotNil(t, d)
+	compressed, err := d.compress(bytes.Repeat([]byte("a"), 100))
+	assert.NoError(t, err)
+	_, err = d.decompress(append([]byte(nil), compressed...))
+	assert.Equal(t, ErrWSMessageTooLarge, err)
+}
+
+func deflateOffer(params string) httphead.Option {
+	header := wsflate.ExtensionName
+	if params != "" {
+		header += "; " + params
+	}
+	opts, _ := httphead.ParseOptions([]byte(header), nil)
+	return opts[0]
+}
+
+// 不同压缩级别的CPU开销和节省的字节数
+func BenchmarkWSDeflateCompress(b *testing.B) {
+	payload := []byte(strings.Repeat(`{"message_id":"1234567890","from_uid":"user1","channel_id":"group1","channel_type":2,"payload":"aGVsbG8gd29ybGQ="}`, 10))
+	for _, level := range []int{flate.HuffmanOnly, flate.BestSpeed, flate.DefaultCompression, flate.BestCompression} {
+		for _, takeover := range []bool{false, true} {
+			b.Run(fmt.Sprintf("level=%d/takeover=%v", level, takeover), func(b *testing.B) {
+				opts := NewWSCompressionOptions()
+				opts.Level = level
+				d := &wsDeflate{opts: &opts, params: wsflate.Parameters{ServerNoContextTakeover: !takeover}}
+				var compressedSize int
+				b.ReportAllocs()
+				b.SetBytes(int64(len(payload)))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					out, err := d.compress(payload)
+					if err != nil {
+						b.Fatal(err)
+					}
+					compressedSize += len(out)
+				}
+				b.ReportMetric(float64(len(payload)*b.N-compressedSize)/float64(b.N), "saved-bytes/op")
+			})
+		}
+	}
+}
+
+func BenchmarkWSDeflateDecompress(b *testing.B) {
+	payload := []byte(strings.Repeat(`{"message_id":"1234567890","from_uid":"user1","channel_id":"group1","channel_type":2,"payload":"aGVsbG8gd29ybGQ="}`, 10))
+	opts := NewWSCompressionOptions()
+	d := &wsDeflate{opts: &opts, params: wsflate.Parameters{ServerNoContextTakeover: true, ClientNoContextTakeover: true}}
+	compressed, err := d.compress(payload)
+	if err != nil {
+		b.Fatal(err)
+	}
+	compressed = append([]byte(nil), compressed...)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := d.decompress(compressed); err != nil {
+			b.Fatal(err)
+		}
+	}
+}